@@ -0,0 +1,149 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package cache implements 'agent cache'.
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/pkg/persistentcache"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+// cliParams are the command-line arguments for this subcommand
+type cliParams struct {
+	*command.GlobalParams
+
+	namespace   string
+	prefix      string
+	expiredOnly bool
+	all         bool
+}
+
+// Commands returns a slice of subcommands for the 'agent' command.
+func Commands(globalParams *command.GlobalParams) []*cobra.Command {
+	cliParams := &cliParams{
+		GlobalParams: globalParams,
+	}
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and clear the persistent cache of the Agent",
+		Long:  ``,
+	}
+	cacheCmd.PersistentFlags().StringVarP(&cliParams.namespace, "namespace", "n", "", "only consider the entries of this namespace")
+	cacheCmd.PersistentFlags().StringVarP(&cliParams.prefix, "prefix", "p", "", "only consider the entries whose key starts with this prefix")
+	cacheCmd.PersistentFlags().BoolVar(&cliParams.expiredOnly, "expired", false, "only consider the expired entries")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the entries of the persistent cache",
+		Long:  ``,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return fxutil.OneShot(list,
+				fx.Supply(cliParams),
+				fx.Supply(command.GetDefaultCoreBundleParams(cliParams.GlobalParams)),
+				core.Bundle(),
+			)
+		},
+	}
+	purgeCmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Remove entries from the persistent cache",
+		Long:  ``,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if err := checkPurgeFilter(cliParams); err != nil {
+				return err
+			}
+			return fxutil.OneShot(purge,
+				fx.Supply(cliParams),
+				fx.Supply(command.GetDefaultCoreBundleParams(cliParams.GlobalParams)),
+				core.Bundle(),
+			)
+		},
+	}
+	purgeCmd.Flags().BoolVar(&cliParams.all, "all", false, "allow removing every entry of the persistent cache when no filter is given")
+	cacheCmd.AddCommand(listCmd, purgeCmd)
+
+	return []*cobra.Command{cacheCmd}
+}
+
+func list(_ config.Component, cliParams *cliParams) error {
+	entries, err := persistentcache.List()
+	if err != nil {
+		return fmt.Errorf("could not list the persistent cache: %w", err)
+	}
+	printEntries(os.Stdout, filter(entries, cliParams, time.Now()), time.Now())
+	return nil
+}
+
+func purge(_ config.Component, cliParams *cliParams) error {
+	purged, err := persistentcache.Purge(cliParams.namespace, cliParams.prefix, cliParams.expiredOnly)
+	if err != nil {
+		return fmt.Errorf("could not purge the persistent cache: %w", err)
+	}
+	fmt.Printf("Removed %d entries from the persistent cache\n", len(purged))
+	return nil
+}
+
+// checkPurgeFilter refuses to purge the whole cache, which holds the state of the checks, unless --all is set.
+func checkPurgeFilter(cliParams *cliParams) error {
+	if cliParams.namespace == "" && cliParams.prefix == "" && !cliParams.expiredOnly && !cliParams.all {
+		return errors.New("no filter given, use --all to remove every entry of the persistent cache, including the state of the checks")
+	}
+	return nil
+}
+
+// filter returns the entries selected by the command-line arguments, sorted by namespace and key.
+func filter(entries []persistentcache.Entry, cliParams *cliParams, now time.Time) []persistentcache.Entry {
+	var selected []persistentcache.Entry
+	for _, e := range entries {
+		if cliParams.namespace != "" && e.Namespace != cliParams.namespace {
+			continue
+		}
+		if !strings.HasPrefix(e.Key, cliParams.prefix) {
+			continue
+		}
+		if cliParams.expiredOnly && !e.Expired(now) {
+			continue
+		}
+		selected = append(selected, e)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if selected[i].Namespace != selected[j].Namespace {
+			return selected[i].Namespace < selected[j].Namespace
+		}
+		return selected[i].Key < selected[j].Key
+	})
+	return selected
+}
+
+func printEntries(w io.Writer, entries []persistentcache.Entry, now time.Time) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tKEY\tSIZE\tLAST ACCESS\tEXPIRES")
+	for _, e := range entries {
+		expires := "never"
+		if e.Expired(now) {
+			expires = "expired"
+		} else if !e.Expires.IsZero() {
+			expires = e.Expires.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", e.Namespace, e.Key, e.Size, e.LastAccess.Format(time.RFC3339), expires)
+	}
+	tw.Flush()
+}
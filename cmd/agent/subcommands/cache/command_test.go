@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package cache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/pkg/persistentcache"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestListCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"cache", "list", "--namespace", "snmp", "--prefix", "device"},
+		list,
+		func(cliParams *cliParams, _ core.BundleParams) {
+			require.Equal(t, "snmp", cliParams.namespace)
+			require.Equal(t, "device", cliParams.prefix)
+			require.False(t, cliParams.expiredOnly)
+		})
+}
+
+func TestPurgeCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"cache", "purge", "--expired"},
+		purge,
+		func(cliParams *cliParams, _ core.BundleParams) {
+			require.Empty(t, cliParams.namespace)
+			require.True(t, cliParams.expiredOnly)
+		})
+}
+
+func TestPurgeCommandAll(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"cache", "purge", "--all"},
+		purge,
+		func(cliParams *cliParams, _ core.BundleParams) {
+			require.True(t, cliParams.all)
+		})
+}
+
+func TestCheckPurgeFilter(t *testing.T) {
+	assert.Error(t, checkPurgeFilter(&cliParams{}))
+	assert.NoError(t, checkPurgeFilter(&cliParams{all: true}))
+	assert.NoError(t, checkPurgeFilter(&cliParams{namespace: "snmp"}))
+	assert.NoError(t, checkPurgeFilter(&cliParams{prefix: "device"}))
+	assert.NoError(t, checkPurgeFilter(&cliParams{expiredOnly: true}))
+}
+
+func TestFilterAndPrint(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	entries := []persistentcache.Entry{
+		{Namespace: "snmp", Key: "device_2", Size: 3, LastAccess: now, Expires: now.Add(-time.Minute)},
+		{Namespace: "snmp", Key: "device_1", Size: 12, LastAccess: now},
+		{Namespace: "snmp", Key: "profile", Size: 1, LastAccess: now},
+		{Namespace: "other", Key: "device_3", Size: 1, LastAccess: now},
+	}
+
+	selected := filter(entries, &cliParams{namespace: "snmp", prefix: "device"}, now)
+	require.Len(t, selected, 2)
+	assert.Equal(t, "device_1", selected[0].Key)
+	assert.Equal(t, "device_2", selected[1].Key)
+
+	expired := filter(entries, &cliParams{expiredOnly: true}, now)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "device_2", expired[0].Key)
+
+	var b bytes.Buffer
+	printEntries(&b, selected, now)
+	assert.Equal(t, `NAMESPACE  KEY       SIZE  LAST ACCESS           EXPIRES
+snmp       device_1  12    2024-01-01T12:00:00Z  never
+snmp       device_2  3     2024-01-01T12:00:00Z  expired
+`, b.String())
+}
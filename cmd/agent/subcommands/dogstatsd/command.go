@@ -78,6 +78,26 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 		},
 	})
 
+	limitsFlags := limitsFlags{}
+
+	limitsCmd := &cobra.Command{
+		Use:   "context-limits",
+		Short: "Display the metrics, origins and tag keys hitting the dogstatsd context limiter",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return fxutil.OneShot(contextLimits,
+				fx.Supply(&limitsFlags),
+				fx.Supply(core.BundleParams{
+					ConfigParams: cconfig.NewAgentParams(globalParams.ConfFilePath, cconfig.WithExtraConfFiles(globalParams.ExtraConfFilePath), cconfig.WithFleetPoliciesDirPath(globalParams.FleetPoliciesDirPath)),
+					LogParams:    log.ForOneShot(command.LoggerName, topFlags.logLevelDefaultOff.Value(), true)}),
+				core.Bundle(),
+			)
+		},
+	}
+	limitsCmd.Flags().IntVarP(&limitsFlags.top, "top", "n", 10, "number of metrics, origins and tag keys to show")
+	limitsCmd.Flags().BoolVarP(&limitsFlags.jsonOutput, "json", "j", false, "print out raw json")
+
+	c.AddCommand(limitsCmd)
+
 	return []*cobra.Command{c}
 }
 
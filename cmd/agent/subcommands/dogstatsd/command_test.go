@@ -6,11 +6,15 @@
 package dogstatsd
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 )
 
@@ -31,4 +35,29 @@ func TestCommand(t *testing.T) {
 			assert.Equal(t, 1, f.nmetrics)
 			assert.Equal(t, 2, f.ntags)
 		})
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd", "context-limits", "-n", "5", "--json"},
+		contextLimits,
+		func(f *limitsFlags) {
+			assert.Equal(t, 5, f.top)
+			assert.True(t, f.jsonOutput)
+		})
+}
+
+func TestPrintContextLimits(t *testing.T) {
+	var stats aggregator.ContextLimiterStats
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"metric_limit": 100,
+		"policy": "drop",
+		"metrics": [{"name": "my.metric", "contexts": 100, "dropped": 42}],
+		"origins": [{"name": "", "contexts": 100}]
+	}`), &stats))
+
+	var out strings.Builder
+	printContextLimits(&out, stats)
+
+	assert.Contains(t, out.String(), "Limits: 100 contexts per metric, no limit per origin, no limit per tag key")
+	assert.Regexp(t, `100 +42 +0 +my.metric`, out.String())
+	assert.Contains(t, out.String(), "(no origin)")
 }
@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	cconfig "github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+)
+
+type limitsFlags struct {
+	top        int
+	jsonOutput bool
+}
+
+func contextLimits(config cconfig.Component, flags *limitsFlags, _ log.Component) error {
+	c := util.GetClient(false)
+	addr, err := pkgconfigsetup.GetIPCAddress(pkgconfigsetup.Datadog())
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("https://%v:%v/agent/dogstatsd-context-limiter?top=%d", addr, config.GetInt("cmd_port"), flags.top)
+
+	if err := util.SetAuthToken(config); err != nil {
+		return err
+	}
+
+	body, err := util.DoGet(c, url, util.LeaveConnectionOpen)
+	if err != nil {
+		var errMap = make(map[string]string)
+		json.Unmarshal(body, &errMap) //nolint:errcheck
+		if e, found := errMap["error"]; found {
+			return errors.New(e)
+		}
+		return fmt.Errorf("could not reach agent: %v, make sure the agent is running", err)
+	}
+
+	if flags.jsonOutput {
+		fmt.Println(string(body))
+		return nil
+	}
+
+	var stats aggregator.ContextLimiterStats
+	if err := json.Unmarshal(body, &stats); err != nil {
+		return err
+	}
+
+	printContextLimits(os.Stdout, stats)
+	return nil
+}
+
+func formatLimit(limit int, unit string) string {
+	if limit == 0 {
+		return "no limit"
+	}
+	return fmt.Sprintf("%d %s", limit, unit)
+}
+
+func printContextLimits(out io.Writer, stats aggregator.ContextLimiterStats) {
+	fmt.Fprintf(out, "Policy: %s\n", stats.Policy)
+	fmt.Fprintf(out, "Limits: %s per metric, %s per origin, %s per tag key\n",
+		formatLimit(stats.MetricLimit, "contexts"), formatLimit(stats.OriginLimit, "contexts"), formatLimit(stats.TagValueLimit, "values"))
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(out, "\nTop metrics")
+	fmt.Fprintln(w, "Contexts\tDropped\tCollapsed\tMetric name")
+	for _, m := range stats.Metrics {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\n", m.Contexts, m.Dropped, m.Collapsed, m.Name)
+	}
+	w.Flush()
+
+	fmt.Fprintln(out, "\nTop origins")
+	fmt.Fprintln(w, "Contexts\tDropped\tCollapsed\tOrigin")
+	for _, o := range stats.Origins {
+		name := o.Name
+		if name == "" {
+			name = "(no origin)"
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\n", o.Contexts, o.Dropped, o.Collapsed, name)
+	}
+	w.Flush()
+
+	fmt.Fprintln(out, "\nTop tag keys")
+	fmt.Fprintln(w, "Values\tDropped\tCollapsed\tMetric name\tTag key")
+	for _, t := range stats.Tags {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%s\n", t.Values, t.Dropped, t.Collapsed, t.Metric, t.Key)
+	}
+	w.Flush()
+}
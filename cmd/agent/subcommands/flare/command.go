@@ -10,11 +10,16 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"net"
 	"net/url"
 	"os"
 	"path"
+	"slices"
 	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/fatih/color"
@@ -85,6 +90,9 @@ type cliParams struct {
 	withStreamLogs       time.Duration
 	logLevelDefaultOff   command.LogLevelDefaultOff
 	providerTimeout      time.Duration
+	onlyProviders        []string
+	excludedProviders    []string
+	preview              bool
 }
 
 // Commands returns a slice of subcommands for the 'agent' command.
@@ -178,6 +186,9 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	flareCmd.Flags().IntVarP(&cliParams.profileBlockingRate, "profile-blocking-rate", "", 10000, "Set the fraction of goroutine blocking events that are reported in the blocking profile")
 	flareCmd.Flags().DurationVarP(&cliParams.withStreamLogs, "with-stream-logs", "L", 0*time.Second, "Add stream-logs data to the flare. It will collect logs for the amount of seconds passed to the flag")
 	flareCmd.Flags().DurationVarP(&cliParams.providerTimeout, "provider-timeout", "t", 0*time.Second, "Timeout to run each flare provider in seconds. This is not a global timeout for the flare creation process.")
+	flareCmd.Flags().StringSliceVarP(&cliParams.onlyProviders, "only", "", nil, "Only add the data of these flare providers to the flare (ex: --only logs,config,status)")
+	flareCmd.Flags().StringSliceVarP(&cliParams.excludedProviders, "exclude", "", nil, "Don't add the data of these flare providers to the flare (ex: --exclude profiles)")
+	flareCmd.Flags().BoolVarP(&cliParams.preview, "preview", "", false, "List the files of the flare with their size and the number of values scrubbed from them, and ask for confirmation before sending it")
 	flareCmd.SetArgs([]string{"caseID"})
 
 	return []*cobra.Command{flareCmd}
@@ -266,11 +277,16 @@ func makeFlare(flareComp flare.Component,
 		}
 	}
 
+	flareArgs := flaretypes.FlareArgs{
+		OnlyProviders:     cliParams.onlyProviders,
+		ExcludedProviders: cliParams.excludedProviders,
+	}
+
 	var filePath string
 	if cliParams.forceLocal {
-		filePath, err = createArchive(flareComp, profile, cliParams.providerTimeout, nil)
+		filePath, err = createArchive(flareComp, profile, flareArgs, cliParams.providerTimeout, nil)
 	} else {
-		filePath, err = requestArchive(flareComp, profile, cliParams.providerTimeout)
+		filePath, err = requestArchive(flareComp, profile, flareArgs, cliParams.providerTimeout)
 	}
 
 	if err != nil {
@@ -283,8 +299,18 @@ func makeFlare(flareComp flare.Component,
 		return err
 	}
 
+	if cliParams.preview {
+		manifest, err := helpers.ReadFlareManifest(filePath)
+		if err != nil {
+			fmt.Fprintln(color.Output, color.RedString(fmt.Sprintf("Could not read the manifest of the flare: %s", err)))
+			return err
+		}
+		printManifest(color.Output, manifest)
+	}
+
 	fmt.Fprintf(color.Output, "%s is going to be uploaded to Datadog\n", color.YellowString(filePath))
-	if !cliParams.autoconfirm {
+	// a preview always requires a confirmation, the point being to review the flare before it is sent
+	if !cliParams.autoconfirm || cliParams.preview {
 		confirmation := input.AskForConfirmation("Are you sure you want to upload a flare? [y/N]")
 		if !confirmation {
 			fmt.Fprintf(color.Output, "Aborting. (You can still use %s)\n", color.YellowString(filePath))
@@ -300,13 +326,13 @@ func makeFlare(flareComp flare.Component,
 	return nil
 }
 
-func requestArchive(flareComp flare.Component, pdata flaretypes.ProfileData, providerTimeout time.Duration) (string, error) {
+func requestArchive(flareComp flare.Component, pdata flaretypes.ProfileData, flareArgs flaretypes.FlareArgs, providerTimeout time.Duration) (string, error) {
 	fmt.Fprintln(color.Output, color.BlueString("Asking the agent to build the flare archive."))
 	c := util.GetClient(false) // FIX: get certificates right then make this true
 	ipcAddress, err := pkgconfigsetup.GetIPCAddress(pkgconfigsetup.Datadog())
 	if err != nil {
 		fmt.Fprintln(color.Output, color.RedString(fmt.Sprintf("Error getting IPC address for the agent: %s", err)))
-		return createArchive(flareComp, pdata, providerTimeout, err)
+		return createArchive(flareComp, pdata, flareArgs, providerTimeout, err)
 	}
 
 	cmdport := pkgconfigsetup.Datadog().GetInt("cmd_port")
@@ -315,18 +341,24 @@ func requestArchive(flareComp flare.Component, pdata flaretypes.ProfileData, pro
 		Host:   net.JoinHostPort(ipcAddress, strconv.Itoa(cmdport)),
 		Path:   "/agent/flare",
 	}
+	q := url.Query()
 	if providerTimeout > 0 {
-		q := url.Query()
 		q.Set("provider_timeout", strconv.FormatInt(int64(providerTimeout), 10))
-		url.RawQuery = q.Encode()
 	}
+	if len(flareArgs.OnlyProviders) > 0 {
+		q.Set("only", strings.Join(flareArgs.OnlyProviders, ","))
+	}
+	if len(flareArgs.ExcludedProviders) > 0 {
+		q.Set("exclude", strings.Join(flareArgs.ExcludedProviders, ","))
+	}
+	url.RawQuery = q.Encode()
 
 	urlstr := url.String()
 
 	// Set session token
 	if err = util.SetAuthToken(pkgconfigsetup.Datadog()); err != nil {
 		fmt.Fprintln(color.Output, color.RedString(fmt.Sprintf("Error: %s", err)))
-		return createArchive(flareComp, pdata, providerTimeout, err)
+		return createArchive(flareComp, pdata, flareArgs, providerTimeout, err)
 	}
 
 	p, err := json.Marshal(pdata)
@@ -344,15 +376,15 @@ func requestArchive(flareComp flare.Component, pdata flaretypes.ProfileData, pro
 			fmt.Fprintln(color.Output, color.RedString("The agent was unable to make the flare. (is it running?)"))
 			err = fmt.Errorf("Error getting flare from running agent: %w", err)
 		}
-		return createArchive(flareComp, pdata, providerTimeout, err)
+		return createArchive(flareComp, pdata, flareArgs, providerTimeout, err)
 	}
 
 	return string(r), nil
 }
 
-func createArchive(flareComp flare.Component, pdata flaretypes.ProfileData, providerTimeout time.Duration, ipcError error) (string, error) {
+func createArchive(flareComp flare.Component, pdata flaretypes.ProfileData, flareArgs flaretypes.FlareArgs, providerTimeout time.Duration, ipcError error) (string, error) {
 	fmt.Fprintln(color.Output, color.YellowString("Initiating flare locally."))
-	filePath, err := flareComp.Create(pdata, providerTimeout, ipcError)
+	filePath, err := flareComp.CreateWithArgs(flareArgs, providerTimeout, ipcError, pdata)
 	if err != nil {
 		fmt.Printf("The flare zipfile failed to be created: %s\n", err)
 		return "", err
@@ -360,3 +392,26 @@ func createArchive(flareComp flare.Component, pdata flaretypes.ProfileData, prov
 
 	return filePath, nil
 }
+
+// printManifest prints the files of a flare with their size and the number of values scrubbed from them
+func printManifest(w io.Writer, manifest *helpers.FlareManifest) {
+	var totalSize int64
+	var totalScrubbed int
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSIZE\tSCRUBBED")
+	for _, file := range manifest.Files {
+		rules := slices.Sorted(maps.Keys(file.Scrubbed))
+		scrubbed := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			scrubbed = append(scrubbed, fmt.Sprintf("%s: %d", rule, file.Scrubbed[rule]))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", file.Path, file.Size, strings.Join(scrubbed, ", "))
+
+		totalSize += file.Size
+		totalScrubbed += file.ScrubbedTotal()
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d files, %d bytes, %d values scrubbed\n", len(manifest.Files), totalSize, totalScrubbed)
+}
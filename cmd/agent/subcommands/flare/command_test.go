@@ -6,6 +6,7 @@
 package flare
 
 import (
+	"bytes"
 	"maps"
 	"net"
 	"net/http/httptest"
@@ -20,6 +21,7 @@ import (
 	"github.com/DataDog/datadog-agent/cmd/agent/command"
 	"github.com/DataDog/datadog-agent/comp/core"
 	configComponent "github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/core/flare/helpers"
 	flaretypes "github.com/DataDog/datadog-agent/comp/core/flare/types"
 	profiler "github.com/DataDog/datadog-agent/comp/core/profiler/def"
 	profilerfx "github.com/DataDog/datadog-agent/comp/core/profiler/fx"
@@ -295,3 +297,34 @@ func (c *commandTestSuite) TestCommand() {
 			require.Equal(t, true, secretParams.Enabled)
 		})
 }
+
+func (c *commandTestSuite) TestCommandProviderSelection() {
+	t := c.T()
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"flare", "1234", "--only", "logs,config", "--exclude", "profiles", "--preview"},
+		makeFlare,
+		func(cliParams *cliParams, _ core.BundleParams, _ secrets.Params) {
+			require.Equal(t, []string{"logs", "config"}, cliParams.onlyProviders)
+			require.Equal(t, []string{"profiles"}, cliParams.excludedProviders)
+			require.True(t, cliParams.preview)
+		})
+}
+
+func TestPrintManifest(t *testing.T) {
+	var out bytes.Buffer
+	printManifest(&out, &helpers.FlareManifest{
+		Files: []helpers.ManifestFile{
+			{Path: "etc/datadog.yaml", Size: 120, Scrubbed: map[string]int{"token": 1, "api_key": 2}},
+			{Path: "status.log", Size: 3000},
+		},
+	})
+
+	expected := `FILE              SIZE  SCRUBBED
+etc/datadog.yaml  120   api_key: 2, token: 1
+status.log        3000  
+
+2 files, 3120 bytes, 3 values scrubbed
+`
+	require.Equal(t, expected, out.String())
+}
@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package flarereceive implements 'agent flare-receive'.
+package flarereceive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+// cliParams are the command-line arguments for this subcommand
+type cliParams struct {
+	*command.GlobalParams
+
+	address string
+	dir     string
+	token   string
+	noToken bool
+	maxSize int64
+	tlsCert string
+	tlsKey  string
+}
+
+// Commands returns a slice of subcommands for the 'agent' command.
+func Commands(globalParams *command.GlobalParams) []*cobra.Command {
+	cliParams := &cliParams{
+		GlobalParams: globalParams,
+	}
+
+	flareReceiveCmd := &cobra.Command{
+		Use:   "flare-receive",
+		Short: "Receive flares sent by Agents configured with 'flare_destination'",
+		Long: `Run an HTTP server implementing the flare upload API, storing the received flares in a local directory.
+
+Point the 'flare_destination' setting of the Agents at this server to collect their flares without sending them to Datadog.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if err := checkParams(cliParams); err != nil {
+				return err
+			}
+			return fxutil.OneShot(receiveFlares,
+				fx.Supply(cliParams),
+				fx.Supply(core.BundleParams{
+					ConfigParams: config.NewAgentParams(globalParams.ConfFilePath, config.WithExtraConfFiles(globalParams.ExtraConfFilePath), config.WithFleetPoliciesDirPath(globalParams.FleetPoliciesDirPath)),
+					LogParams:    log.ForOneShot(command.LoggerName, "info", true)}),
+				core.Bundle(),
+			)
+		},
+	}
+	flareReceiveCmd.Flags().StringVarP(&cliParams.address, "address", "a", ":8443", "Address to listen on")
+	flareReceiveCmd.Flags().StringVarP(&cliParams.dir, "dir", "d", ".", "Directory in which received flares are stored")
+	flareReceiveCmd.Flags().StringVarP(&cliParams.token, "token", "t", "", "Bearer token the Agents must send, matching their 'flare_destination_token' setting")
+	flareReceiveCmd.Flags().BoolVarP(&cliParams.noToken, "no-token", "", false, "Accept the flares of any client, without requiring a token")
+	flareReceiveCmd.Flags().Int64VarP(&cliParams.maxSize, "max-size", "", 100, "Maximum size of a received flare, in MB")
+	flareReceiveCmd.Flags().StringVarP(&cliParams.tlsCert, "tls-cert", "", "", "Path to the TLS certificate of the server")
+	flareReceiveCmd.Flags().StringVarP(&cliParams.tlsKey, "tls-key", "", "", "Path to the TLS private key of the server")
+
+	return []*cobra.Command{flareReceiveCmd}
+}
+
+// checkParams validates the command-line arguments: a token is required unless --no-token is set
+func checkParams(cliParams *cliParams) error {
+	if cliParams.token == "" && !cliParams.noToken {
+		return errors.New("a --token is required, set --no-token to accept the flares of any client")
+	}
+	if cliParams.token != "" && cliParams.noToken {
+		return errors.New("--token and --no-token are mutually exclusive")
+	}
+	if cliParams.maxSize <= 0 {
+		return errors.New("--max-size must be positive")
+	}
+	if (cliParams.tlsCert == "") != (cliParams.tlsKey == "") {
+		return errors.New("--tls-cert and --tls-key must be set together")
+	}
+	return nil
+}
+
+func receiveFlares(log log.Component, cliParams *cliParams) error {
+	if err := os.MkdirAll(cliParams.dir, 0700); err != nil {
+		return fmt.Errorf("could not create the flare directory: %w", err)
+	}
+
+	server := &http.Server{
+		Addr:              cliParams.address,
+		Handler:           newFlareHandler(log, cliParams.dir, cliParams.token, cliParams.maxSize<<20),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		server.Shutdown(ctx) //nolint:errcheck
+	}()
+
+	if cliParams.token == "" {
+		log.Warn("No token required, any client will be able to upload flares")
+	}
+	log.Infof("Receiving flares on %s, storing them in %s", cliParams.address, cliParams.dir)
+
+	var err error
+	if cliParams.tlsCert != "" {
+		err = server.ListenAndServeTLS(cliParams.tlsCert, cliParams.tlsKey)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package flarereceive
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/core/flare/helpers"
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"flare-receive", "--address", "127.0.0.1:9443", "--dir", "/tmp/flares", "--token", "s3cr3t"},
+		receiveFlares,
+		func(cliParams *cliParams, _ core.BundleParams) {
+			require.Equal(t, "127.0.0.1:9443", cliParams.address)
+			require.Equal(t, "/tmp/flares", cliParams.dir)
+			require.Equal(t, "s3cr3t", cliParams.token)
+			require.Equal(t, int64(100), cliParams.maxSize)
+		})
+}
+
+func TestCheckParams(t *testing.T) {
+	for name, tc := range map[string]struct {
+		params  cliParams
+		invalid bool
+	}{
+		"token":              {params: cliParams{token: "s3cr3t", maxSize: 100}},
+		"no token":           {params: cliParams{noToken: true, maxSize: 100}},
+		"missing token":      {params: cliParams{maxSize: 100}, invalid: true},
+		"token and no token": {params: cliParams{token: "s3cr3t", noToken: true, maxSize: 100}, invalid: true},
+		"invalid max size":   {params: cliParams{token: "s3cr3t"}, invalid: true},
+		"tls cert only":      {params: cliParams{token: "s3cr3t", maxSize: 100, tlsCert: "server.crt"}, invalid: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := checkParams(&tc.params)
+			if tc.invalid {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestReceiveFlare(t *testing.T) {
+	dir := t.TempDir()
+	ts := httptest.NewServer(newFlareHandler(logmock.New(t), dir, "s3cr3t", 1<<20))
+	defer ts.Close()
+
+	archivePath := filepath.Join(t.TempDir(), "datadog-agent-flare.zip")
+	require.NoError(t, os.WriteFile(archivePath, []byte("flare content"), 0600))
+
+	cfg := config.NewMock(t)
+	cfg.SetWithoutSource("flare_destination", ts.URL)
+	cfg.SetWithoutSource("flare_destination_token", "s3cr3t")
+
+	res, err := helpers.SendTo(cfg, archivePath, "12345", "dev@datadoghq.com", "", "", helpers.NewLocalFlareSource())
+	require.NoError(t, err)
+	assert.Contains(t, res, "12345")
+
+	flares, err := filepath.Glob(filepath.Join(dir, "*_datadog-agent-flare.zip"))
+	require.NoError(t, err)
+	require.Len(t, flares, 1)
+
+	content, err := os.ReadFile(flares[0])
+	require.NoError(t, err)
+	assert.Equal(t, "flare content", string(content))
+
+	content, err = os.ReadFile(flares[0] + ".json")
+	require.NoError(t, err)
+	var metadata flareMetadata
+	require.NoError(t, json.Unmarshal(content, &metadata))
+	assert.Equal(t, "12345", metadata.CaseID)
+	assert.Equal(t, "dev@datadoghq.com", metadata.Email)
+	assert.Equal(t, "local", metadata.Source)
+	assert.Equal(t, int64(len("flare content")), metadata.Size)
+	assert.True(t, strings.Contains(filepath.Base(flares[0]), "_"+sanitize(metadata.Hostname)+"_"))
+
+	// no temporary file is left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestReceiveFlareErrors(t *testing.T) {
+	ts := httptest.NewServer(newFlareHandler(logmock.New(t), t.TempDir(), "s3cr3t", 16))
+	defer ts.Close()
+
+	t.Run("invalid token", func(t *testing.T) {
+		req, err := http.NewRequest("HEAD", ts.URL+flareURLPath, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer wrong")
+		r, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		r.Body.Close()
+		assert.Equal(t, http.StatusForbidden, r.StatusCode)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "datadog-agent-flare.zip")
+		require.NoError(t, os.WriteFile(archivePath, []byte(strings.Repeat("flare content", 10)), 0600))
+
+		cfg := config.NewMock(t)
+		cfg.SetWithoutSource("flare_destination", ts.URL)
+		cfg.SetWithoutSource("flare_destination_token", "s3cr3t")
+
+		_, err := helpers.SendTo(cfg, archivePath, "", "", "", "", helpers.NewLocalFlareSource())
+		assert.Error(t, err)
+	})
+
+	t.Run("not multipart", func(t *testing.T) {
+		req, err := http.NewRequest("POST", ts.URL+flareURLPath, strings.NewReader("flare"))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer s3cr3t")
+		r, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		r.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "my-host.example.com", sanitize("my-host.example.com"))
+	assert.Equal(t, ".._.._etc_passwd", sanitize("../../etc/passwd"))
+	assert.Equal(t, "unknown", sanitize(""))
+	assert.Equal(t, "unknown", sanitize(".."))
+}
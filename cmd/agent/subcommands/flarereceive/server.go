@@ -0,0 +1,224 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package flarereceive
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+)
+
+const (
+	flareURLPath = "/support/flare"
+	// maxFieldSize is the maximum size of the other fields of the form
+	maxFieldSize = 1 << 10
+)
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// flareMetadata is stored next to each received flare
+type flareMetadata struct {
+	CaseID       string    `json:"case_id,omitempty"`
+	Email        string    `json:"email,omitempty"`
+	Source       string    `json:"source,omitempty"`
+	RCTaskUUID   string    `json:"rc_task_uuid,omitempty"`
+	AgentVersion string    `json:"agent_version,omitempty"`
+	Hostname     string    `json:"hostname,omitempty"`
+	FileName     string    `json:"file_name"`
+	Size         int64     `json:"size"`
+	ReceivedAt   time.Time `json:"received_at"`
+	RemoteAddr   string    `json:"remote_addr"`
+}
+
+// flareResponse mimics the response of the Datadog flare endpoint
+type flareResponse struct {
+	CaseID int    `json:"case_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type flareHandler struct {
+	log   log.Component
+	dir   string
+	token string
+	// maxSize is the maximum size of an uploaded flare, in bytes
+	maxSize int64
+}
+
+// newFlareHandler returns a handler implementing the flare upload API on '/support/flare[/<case id>]', storing
+// the received flares in dir. An empty token accepts the flares of any client.
+func newFlareHandler(log log.Component, dir, token string, maxSize int64) http.Handler {
+	h := &flareHandler{log: log, dir: dir, token: token, maxSize: maxSize}
+	mux := http.NewServeMux()
+	mux.Handle(flareURLPath, h)
+	mux.Handle(flareURLPath+"/", h)
+	return mux
+}
+
+func (h *flareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+h.token)) != 1 {
+		h.respond(w, http.StatusForbidden, flareResponse{Error: "invalid token"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead:
+		// the Agent resolves the upload URL with a HEAD request first
+		w.WriteHeader(http.StatusOK)
+	case http.MethodPost:
+		h.receive(w, r)
+	default:
+		w.Header().Set("Allow", "HEAD, POST")
+		h.respond(w, http.StatusMethodNotAllowed, flareResponse{Error: "method not allowed"})
+	}
+}
+
+func (h *flareHandler) receive(w http.ResponseWriter, r *http.Request) {
+	metadata := flareMetadata{
+		CaseID:     strings.Trim(strings.TrimPrefix(r.URL.Path, flareURLPath), "/"),
+		ReceivedAt: time.Now().UTC(),
+		RemoteAddr: r.RemoteAddr,
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		h.respond(w, http.StatusBadRequest, flareResponse{Error: err.Error()})
+		return
+	}
+
+	// the flare is written to a temporary file until we know the hostname, which is sent after it
+	var tmpPath string
+	defer func() {
+		if tmpPath != "" {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			h.respondError(w, err)
+			return
+		}
+
+		if part.FormName() == "flare_file" {
+			if tmpPath != "" {
+				h.respond(w, http.StatusBadRequest, flareResponse{Error: "more than one flare_file"})
+				return
+			}
+			metadata.FileName = part.FileName()
+			tmpPath, metadata.Size, err = h.writeTemp(part)
+			if err != nil {
+				h.respondError(w, err)
+				return
+			}
+			continue
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFieldSize))
+		if err != nil {
+			h.respondError(w, err)
+			return
+		}
+		switch part.FormName() {
+		case "case_id":
+			metadata.CaseID = string(value)
+		case "email":
+			metadata.Email = string(value)
+		case "source":
+			metadata.Source = string(value)
+		case "rc_task_uuid":
+			metadata.RCTaskUUID = string(value)
+		case "agent_version":
+			metadata.AgentVersion = string(value)
+		case "hostname":
+			metadata.Hostname = string(value)
+		}
+	}
+
+	if tmpPath == "" {
+		h.respond(w, http.StatusBadRequest, flareResponse{Error: "missing flare_file"})
+		return
+	}
+
+	name := fmt.Sprintf("%s_%s_%s",
+		metadata.ReceivedAt.Format("20060102T150405.000Z"),
+		sanitize(metadata.Hostname),
+		sanitize(filepath.Base(metadata.FileName)),
+	)
+	flarePath := filepath.Join(h.dir, name)
+	if err := os.Rename(tmpPath, flarePath); err != nil {
+		h.respondError(w, err)
+		return
+	}
+	tmpPath = ""
+
+	content, err := json.MarshalIndent(metadata, "", "  ")
+	if err == nil {
+		err = os.WriteFile(flarePath+".json", content, 0600)
+	}
+	if err != nil {
+		h.log.Warnf("Could not write the metadata of flare %s: %s", flarePath, err)
+	}
+
+	h.log.Infof("Received flare %s from %s (%d bytes)", flarePath, metadata.Hostname, metadata.Size)
+	caseID, _ := strconv.Atoi(metadata.CaseID)
+	h.respond(w, http.StatusOK, flareResponse{CaseID: caseID})
+}
+
+func (h *flareHandler) writeTemp(r io.Reader) (string, int64, error) {
+	f, err := os.CreateTemp(h.dir, ".flare-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	size, err := io.Copy(f, r)
+	if err != nil {
+		os.Remove(f.Name())
+		return "", 0, err
+	}
+	return f.Name(), size, nil
+}
+
+func (h *flareHandler) respondError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		h.respond(w, http.StatusRequestEntityTooLarge, flareResponse{Error: fmt.Sprintf("flare larger than %d bytes", maxBytesErr.Limit)})
+		return
+	}
+	h.log.Errorf("Could not receive flare: %s", err)
+	h.respond(w, http.StatusInternalServerError, flareResponse{Error: err.Error()})
+}
+
+func (h *flareHandler) respond(w http.ResponseWriter, status int, response flareResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response) //nolint:errcheck
+}
+
+// sanitize makes a value sent by a client safe to use in a file name
+func sanitize(value string) string {
+	value = unsafeChars.ReplaceAllString(value, "_")
+	if value == "" || value == "." || value == ".." {
+		return "unknown"
+	}
+	return value
+}
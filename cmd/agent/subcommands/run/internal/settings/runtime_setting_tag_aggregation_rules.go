@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// TagAggregationRulesRuntimeSetting wraps operations to change the tag aggregation rules of the aggregator at runtime.
+type TagAggregationRulesRuntimeSetting struct {
+	ConfigKey string
+}
+
+// NewTagAggregationRulesRuntimeSetting creates a new instance of TagAggregationRulesRuntimeSetting
+func NewTagAggregationRulesRuntimeSetting() *TagAggregationRulesRuntimeSetting {
+	return &TagAggregationRulesRuntimeSetting{
+		ConfigKey: "tag_aggregation_rules",
+	}
+}
+
+// Description returns the runtime setting's description
+func (t *TagAggregationRulesRuntimeSetting) Description() string {
+	return "Set/get the rules dropping tags from metrics before they are aggregated, as a JSON list of rules"
+}
+
+// Hidden returns whether or not this setting is hidden from the list of runtime settings
+func (t *TagAggregationRulesRuntimeSetting) Hidden() bool {
+	return false
+}
+
+// Name returns the name of the runtime setting
+func (t *TagAggregationRulesRuntimeSetting) Name() string {
+	return t.ConfigKey
+}
+
+// Get returns the current value of the runtime setting
+func (t *TagAggregationRulesRuntimeSetting) Get(config config.Component) (interface{}, error) {
+	rules := config.Get(t.ConfigKey)
+	if rules == nil {
+		return "[]", nil
+	}
+	if s, ok := rules.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Set changes the value of the runtime setting; expected to be a JSON list of rules
+func (t *TagAggregationRulesRuntimeSetting) Set(config config.Component, v interface{}, source model.Source) error {
+	rules, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("%s: expected a JSON list of rules, got %T", t.ConfigKey, v)
+	}
+	if err := aggregator.ValidateTagAggregationRules(rules); err != nil {
+		return fmt.Errorf("%s: %v", t.ConfigKey, err)
+	}
+
+	config.Set(t.ConfigKey, rules, source)
+	return nil
+}
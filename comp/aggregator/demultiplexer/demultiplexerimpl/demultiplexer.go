@@ -117,6 +117,9 @@ func createAgentDemultiplexerOptions(config config.Component, params Params) agg
 	if params.useDogstatsdNoAggregationPipelineConfig {
 		options.EnableNoAggregationPipeline = config.GetBool("dogstatsd_no_aggregation_pipeline")
 	}
+	options.UseDogstatsdContextLimiter = config.GetBool("dogstatsd_context_limiter.enabled")
+	options.EnablePrometheusRemoteWrite = config.GetBool("prometheus_remote_write.enabled")
+	options.EnableOpenMetricsEndpoint = config.GetBool("aggregator_openmetrics_endpoint.enabled")
 
 	// Override FlushInterval only if flushInterval is set by the user
 	if v, ok := params.flushInterval.Get(); ok {
@@ -3,15 +3,18 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2023-present Datadog, Inc.
 
-// Package demultiplexerendpointimpl component provides the /dogstatsd-contexts-dump API endpoint that can register via Fx value groups.
+// Package demultiplexerendpointimpl component provides the /dogstatsd-contexts-dump and /dogstatsd-context-limiter API endpoints that can register via Fx value groups.
 package demultiplexerendpointimpl
 
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 
 	"github.com/DataDog/zstd"
 
@@ -37,7 +40,8 @@ type demultiplexerEndpoint struct {
 
 // Provides defines the output of the demultiplexerendpoint component
 type Provides struct {
-	Endpoint api.AgentEndpointProvider
+	Endpoint        api.AgentEndpointProvider
+	LimiterEndpoint api.AgentEndpointProvider
 }
 
 // NewComponent creates a new demultiplexerendpoint component
@@ -49,10 +53,40 @@ func NewComponent(reqs Requires) Provides {
 	}
 
 	return Provides{
-		Endpoint: api.NewAgentEndpointProvider(endpoint.dumpDogstatsdContexts, "/dogstatsd-contexts-dump", "POST"),
+		Endpoint:        api.NewAgentEndpointProvider(endpoint.dumpDogstatsdContexts, "/dogstatsd-contexts-dump", "POST"),
+		LimiterEndpoint: api.NewAgentEndpointProvider(endpoint.dogstatsdContextLimiter, "/dogstatsd-context-limiter", "GET"),
 	}
 }
 
+// defaultLimiterTop is the default number of offenders returned by /dogstatsd-context-limiter
+const defaultLimiterTop = 10
+
+func (demuxendpoint demultiplexerEndpoint) dogstatsdContextLimiter(w http.ResponseWriter, r *http.Request) {
+	top := defaultLimiterTop
+	if v := r.URL.Query().Get("top"); v != "" {
+		var err error
+		if top, err = strconv.Atoi(v); err != nil || top < 0 {
+			httputils.SetJSONError(w, fmt.Errorf("invalid top parameter %q", v), 400)
+			return
+		}
+	}
+
+	stats, enabled := demuxendpoint.demux.DogstatsdContextLimiterStats(top)
+	if !enabled {
+		httputils.SetJSONError(w, errors.New("the dogstatsd context limiter is not enabled, set dogstatsd_context_limiter.enabled to enable it"), 404)
+		return
+	}
+
+	resp, err := json.Marshal(stats)
+	if err != nil {
+		httputils.SetJSONError(w, demuxendpoint.log.Errorf("Failed to serialize response: %v", err), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
 func (demuxendpoint demultiplexerEndpoint) dumpDogstatsdContexts(w http.ResponseWriter, _ *http.Request) {
 	path, err := demuxendpoint.writeDogstatsdContexts()
 	if err != nil {
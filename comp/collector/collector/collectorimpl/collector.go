@@ -114,7 +114,7 @@ func newProvides(deps dependencies) provides {
 		StatusProvider:   status.NewInformationProvider(collectorStatus.Provider{}),
 		MetadataProvider: agentCheckMetadata,
 		APIGetPyStatus:   api.NewAgentEndpointProvider(getPythonStatus, "/py/status", "GET"),
-		FlareProvider:    flaretypes.NewProvider(c.fillFlare),
+		FlareProvider:    flaretypes.NewNamedProvider("checks", c.fillFlare),
 	}
 }
 
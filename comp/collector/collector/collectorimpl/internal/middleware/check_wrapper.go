@@ -25,6 +25,8 @@ type CheckWrapper struct {
 	senderManager sender.SenderManager
 
 	inner check.Check
+	// runTimeout is the run_timeout of the check instance, resolved once it's configured
+	runTimeout time.Duration
 	// done is true when the check was cancelled and must not run.
 	done bool
 	// Locked while check is running.
@@ -36,6 +38,7 @@ func NewCheckWrapper(inner check.Check, senderManager sender.SenderManager) *Che
 	return &CheckWrapper{
 		inner:         inner,
 		senderManager: senderManager,
+		runTimeout:    check.RunTimeout(inner),
 	}
 }
 
@@ -55,6 +58,11 @@ func (c *CheckWrapper) Cancel() {
 	go c.destroySender()
 }
 
+// CancelRun implements check.RunCanceler, interrupting the current run without descheduling the check
+func (c *CheckWrapper) CancelRun() {
+	check.CancelRun(c.inner)
+}
+
 func (c *CheckWrapper) destroySender() {
 	// Done must happen before Wait
 	c.runM.Lock()
@@ -86,6 +94,11 @@ func (c *CheckWrapper) Interval() time.Duration {
 	return c.inner.Interval()
 }
 
+// RunTimeout implements check.RunTimeouter
+func (c *CheckWrapper) RunTimeout() time.Duration {
+	return c.runTimeout
+}
+
 // ID implements Check#ID
 func (c *CheckWrapper) ID() checkid.ID {
 	return c.inner.ID()
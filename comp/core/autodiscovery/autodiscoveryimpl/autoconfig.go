@@ -129,7 +129,7 @@ func newProvides(deps dependencies) provides {
 		StatusProvider: status.NewInformationProvider(autodiscoveryStatus.GetProvider(c)),
 
 		Endpoint:      api.NewAgentEndpointProvider(c.(*AutoConfig).writeConfigCheck, "/config-check", "GET"),
-		FlareProvider: flaretypes.NewProvider(c.(*AutoConfig).fillFlare),
+		FlareProvider: flaretypes.NewNamedProvider("autodiscovery", c.(*AutoConfig).fillFlare),
 	}
 }
 
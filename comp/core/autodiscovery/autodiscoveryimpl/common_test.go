@@ -88,6 +88,11 @@ func (s *dummyService) GetExtraConfig(_ string) (string, error) {
 	return "", nil
 }
 
+// GetWorkloadMetadata isn't supported
+func (s *dummyService) GetWorkloadMetadata() (*listeners.WorkloadMetadata, error) {
+	return nil, listeners.ErrNotSupported
+}
+
 // FilterTemplates calls filterTemplates, if not nil
 func (s *dummyService) FilterTemplates(configs map[string]integration.Config) {
 	if s.filterTemplates != nil {
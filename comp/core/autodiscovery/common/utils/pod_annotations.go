@@ -55,12 +55,13 @@ func ExtractTemplatesFromAnnotations(entityName string, annotations map[string]s
 // (ad.datadoghq.com/redis.checks) JSON string into []integration.Config.
 func parseChecksJSON(adIdentifier string, checksJSON string) ([]integration.Config, error) {
 	var namedChecks map[string]struct {
-		Name                    string          `json:"name"`
-		InitConfig              json.RawMessage `json:"init_config"`
-		Instances               []interface{}   `json:"instances"`
-		Logs                    json.RawMessage `json:"logs"`
-		IgnoreAutodiscoveryTags bool            `json:"ignore_autodiscovery_tags"`
-		CheckTagCardinality     string          `json:"check_tag_cardinality"`
+		Name                    string                            `json:"name"`
+		InitConfig              json.RawMessage                   `json:"init_config"`
+		Instances               []interface{}                     `json:"instances"`
+		Logs                    json.RawMessage                   `json:"logs"`
+		IgnoreAutodiscoveryTags bool                              `json:"ignore_autodiscovery_tags"`
+		CheckTagCardinality     string                            `json:"check_tag_cardinality"`
+		Placement               *integration.PlacementConstraints `json:"placement"`
 	}
 
 	err := json.Unmarshal([]byte(checksJSON), &namedChecks)
@@ -86,6 +87,7 @@ func parseChecksJSON(adIdentifier string, checksJSON string) ([]integration.Conf
 		}
 
 		c.CheckTagCardinality = config.CheckTagCardinality
+		c.Placement = config.Placement
 
 		if len(config.Logs) > 0 {
 			c.LogsConfig = integration.Data(config.Logs)
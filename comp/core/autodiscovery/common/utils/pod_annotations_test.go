@@ -382,6 +382,32 @@ func TestExtractTemplatesFromAnnotations(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "v2 annotations with placement",
+			annotations: map[string]string{
+				"ad.datadoghq.com/foobar.checks": `{
+					"apache": {
+						"instances": [
+							{"apache_status_url":"http://%%host%%/server-status?auto2"}
+						],
+						"placement": {"node_selector": {"zone": "a"}, "anti_affinity_group": "apache"}
+					}
+				}`,
+			},
+			adIdentifier: "foobar",
+			output: []integration.Config{
+				{
+					Name:          "apache",
+					Instances:     []integration.Data{integration.Data(`{"apache_status_url":"http://%%host%%/server-status?auto2"}`)},
+					InitConfig:    integration.Data("{}"),
+					ADIdentifiers: []string{adID},
+					Placement: &integration.PlacementConstraints{
+						NodeSelector:      map[string]string{"zone": "a"},
+						AntiAffinityGroup: "apache",
+					},
+				},
+			},
+		},
 		{
 			name: "v2 annotations with adv1 ignore_ad_tags",
 			annotations: map[string]string{
@@ -25,6 +25,7 @@ const (
 // The second returned value is true if more than one instance is found
 func buildInstances(pc *types.PrometheusCheck, annotations map[string]string, namespacedName string) ([]integration.Data, bool) {
 	openmetricsVersion := pkgconfigsetup.Datadog().GetInt("prometheus_scrape.version")
+	loader := pkgconfigsetup.Datadog().GetString("prometheus_scrape.loader")
 
 	instances := []integration.Data{}
 	for k, v := range pc.AD.KubeAnnotations.Incl {
@@ -32,6 +33,9 @@ func buildInstances(pc *types.PrometheusCheck, annotations map[string]string, na
 			log.Debugf("'%s' matched the annotation '%s=%s' to schedule an openmetrics check", namespacedName, k, v)
 			for _, instance := range pc.Instances {
 				instanceValues := *instance
+				if instanceValues.Loader == "" {
+					instanceValues.Loader = loader
+				}
 				if instanceValues.PrometheusURL == "" && instanceValues.OpenMetricsEndpoint == "" {
 					switch openmetricsVersion {
 					case 1:
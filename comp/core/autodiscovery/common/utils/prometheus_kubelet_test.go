@@ -24,6 +24,7 @@ func TestConfigsForPod(t *testing.T) {
 		name    string
 		check   *types.PrometheusCheck
 		version int
+		loader  string
 		pod     *kubelet.Pod
 		want    []integration.Config
 		matched bool
@@ -98,6 +99,42 @@ func TestConfigsForPod(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "go check selected with prometheus_scrape.loader",
+			check:   types.DefaultPrometheusCheck,
+			version: 2,
+			loader:  "core",
+			pod: &kubelet.Pod{
+				Metadata: kubelet.PodMetadata{
+					Name:        "foo-pod",
+					Annotations: map[string]string{"prometheus.io/scrape": "true"},
+				},
+				Status: kubelet.Status{
+					Containers: []kubelet.ContainerStatus{
+						{
+							Name: "foo-ctr",
+							ID:   "foo-ctr-id",
+						},
+					},
+					AllContainers: []kubelet.ContainerStatus{
+						{
+							Name: "foo-ctr",
+							ID:   "foo-ctr-id",
+						},
+					},
+				},
+			},
+			want: []integration.Config{
+				{
+					Name:          "openmetrics",
+					InitConfig:    integration.Data("{}"),
+					Instances:     []integration.Data{integration.Data(`{"namespace":"","metrics":[".*"],"openmetrics_endpoint":"http://%%host%%:%%port%%/metrics","loader":"core"}`)},
+					Provider:      names.PrometheusPods,
+					Source:        "prometheus_pods:foo-ctr-id",
+					ADIdentifiers: []string{"foo-ctr-id"},
+				},
+			},
+		},
 		{
 			name: "custom openmetrics_endpoint",
 			check: &types.PrometheusCheck{
@@ -516,6 +553,7 @@ func TestConfigsForPod(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			pkgconfigsetup.Datadog().SetWithoutSource("prometheus_scrape.version", tt.version)
+			pkgconfigsetup.Datadog().SetWithoutSource("prometheus_scrape.loader", tt.loader)
 			tt.check.Init(tt.version)
 			assert.ElementsMatch(t, tt.want, ConfigsForPod(tt.check, tt.pod))
 		})
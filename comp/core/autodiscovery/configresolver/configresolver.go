@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
@@ -23,6 +24,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/listeners"
 	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/providers/names"
 	apiutil "github.com/DataDog/datadog-agent/pkg/api/util"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -39,6 +41,14 @@ var templateVariables = map[string]variableGetter{
 	"env":      getEnvvar,
 	"extra":    getAdditionalTplVariables,
 	"kube":     getAdditionalTplVariables,
+
+	"label":      getLabel,
+	"annotation": getAnnotation,
+	"image":      getImage,
+	"container":  getContainer,
+	"pod":        getPod,
+	"namespace":  getNamespace,
+	"file":       getFile,
 }
 
 // NoServiceError represents an error that indicates that there's a problem with a service
@@ -631,3 +641,128 @@ func getEnvvar(_ context.Context, envVar string, svc listeners.Service) (string,
 	}
 	return value, nil
 }
+
+// getWorkloadMetadata returns the metadata of the container or pod of the
+// service, for the given template variable
+func getWorkloadMetadata(tplVar string, svc listeners.Service) (*listeners.WorkloadMetadata, error) {
+	if svc == nil {
+		return nil, NewNoServiceError(fmt.Sprintf("No service. %%%%%s%%%% is not allowed", tplVar))
+	}
+	metadata, err := svc.GetWorkloadMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("%%%%%s%%%% is only supported for containers and pods, skipping service %s", tplVar, svc.GetServiceID())
+	}
+	return metadata, nil
+}
+
+// getLabel returns a label of the container, or of the pod for pod services
+func getLabel(_ context.Context, key string, svc listeners.Service) (string, error) {
+	return getMetadataValue("label", key, svc, func(m *listeners.WorkloadMetadata) map[string]string { return m.Labels })
+}
+
+// getAnnotation returns an annotation of the pod of the service
+func getAnnotation(_ context.Context, key string, svc listeners.Service) (string, error) {
+	return getMetadataValue("annotation", key, svc, func(m *listeners.WorkloadMetadata) map[string]string { return m.Annotations })
+}
+
+func getMetadataValue(kind string, key string, svc listeners.Service, values func(*listeners.WorkloadMetadata) map[string]string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("%s name is missing in %%%%%s_<name>%%%%", kind, kind)
+	}
+	metadata, err := getWorkloadMetadata(kind+"_"+key, svc)
+	if err != nil {
+		return "", err
+	}
+	value, found := values(metadata)[key]
+	if !found {
+		return "", fmt.Errorf("%s %q not found, cannot resolve %%%%%s_%s%%%% for service %s", kind, key, kind, key, svc.GetServiceID())
+	}
+	return value, nil
+}
+
+// getImage returns the short name or the tag of the image of the container
+func getImage(_ context.Context, key string, svc listeners.Service) (string, error) {
+	var value func(*listeners.WorkloadMetadata) string
+	switch key {
+	case "name":
+		value = func(m *listeners.WorkloadMetadata) string { return m.ImageName }
+	case "tag":
+		value = func(m *listeners.WorkloadMetadata) string { return m.ImageTag }
+	default:
+		return "", fmt.Errorf("invalid %%%%image_%s%%%% tag, expected %%%%image_name%%%% or %%%%image_tag%%%%", key)
+	}
+	return getMetadataField("image_"+key, svc, value)
+}
+
+// getContainer returns the name of the container
+func getContainer(_ context.Context, key string, svc listeners.Service) (string, error) {
+	if key != "name" {
+		return "", fmt.Errorf("invalid %%%%container_%s%%%% tag, expected %%%%container_name%%%%", key)
+	}
+	return getMetadataField("container_name", svc, func(m *listeners.WorkloadMetadata) string { return m.ContainerName })
+}
+
+// getPod returns the name of the pod
+func getPod(_ context.Context, key string, svc listeners.Service) (string, error) {
+	if key != "name" {
+		return "", fmt.Errorf("invalid %%%%pod_%s%%%% tag, expected %%%%pod_name%%%%", key)
+	}
+	return getMetadataField("pod_name", svc, func(m *listeners.WorkloadMetadata) string { return m.PodName })
+}
+
+// getNamespace returns the namespace of the pod
+func getNamespace(_ context.Context, key string, svc listeners.Service) (string, error) {
+	if key != "" {
+		return "", fmt.Errorf("invalid %%%%namespace_%s%%%% tag, expected %%%%namespace%%%%", key)
+	}
+	return getMetadataField("namespace", svc, func(m *listeners.WorkloadMetadata) string { return m.Namespace })
+}
+
+func getMetadataField(tplVar string, svc listeners.Service, value func(*listeners.WorkloadMetadata) string) (string, error) {
+	metadata, err := getWorkloadMetadata(tplVar, svc)
+	if err != nil {
+		return "", err
+	}
+	if v := value(metadata); v != "" {
+		return v, nil
+	}
+	return "", fmt.Errorf("%%%%%s%%%% is not available for service %s", tplVar, svc.GetServiceID())
+}
+
+// getFile returns the content of a file, which must be located in one of the
+// directories of ad_template_file_allowed_dirs, without its trailing whitespaces
+func getFile(_ context.Context, path string, svc listeners.Service) (string, error) {
+	if path == "" {
+		return "", errors.New("file path is missing in %%file_<path>%%")
+	}
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve %%%%file_%s%%%%: %s", path, err)
+	}
+	if !filepath.IsAbs(resolvedPath) || !isFileAllowed(resolvedPath) {
+		return "", fmt.Errorf("cannot resolve %%%%file_%s%%%%: the file is not located in ad_template_file_allowed_dirs", path)
+	}
+	content, err := os.ReadFile(resolvedPath)
+	if err != nil {
+		if svc != nil {
+			return "", fmt.Errorf("cannot resolve %%%%file_%s%%%% for service %s: %s", path, svc.GetServiceID(), err)
+		}
+		return "", fmt.Errorf("cannot resolve %%%%file_%s%%%%: %s", path, err)
+	}
+	return strings.TrimRight(string(content), " \t\r\n"), nil
+}
+
+// isFileAllowed returns whether the path is located in one of the directories
+// the template variables can read files from
+func isFileAllowed(path string) bool {
+	for _, dir := range pkgconfigsetup.Datadog().GetStringSlice("ad_template_file_allowed_dirs") {
+		dir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -9,14 +9,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/listeners"
 	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/providers/names"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
 	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	// we need some valid check in the catalog to run tests
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system"
@@ -30,6 +33,7 @@ type dummyService struct {
 	Pid           int
 	Hostname      string
 	ExtraConfig   map[string]string
+	Metadata      *listeners.WorkloadMetadata
 }
 
 // Equal returns whether the two dummyService are equal
@@ -94,6 +98,14 @@ func (s *dummyService) GetExtraConfig(key string) (string, error) {
 	return s.ExtraConfig[key], nil
 }
 
+// GetWorkloadMetadata returns the dummy workload metadata
+func (s *dummyService) GetWorkloadMetadata() (*listeners.WorkloadMetadata, error) {
+	if s.Metadata == nil {
+		return nil, listeners.ErrNotSupported
+	}
+	return s.Metadata, nil
+}
+
 // FilterConfigs does nothing.
 func (s *dummyService) FilterTemplates(map[string]integration.Config) {
 }
@@ -806,6 +818,107 @@ func newFakeContainerPorts() []listeners.ContainerPort {
 	}
 }
 
+func TestResolveWorkloadMetadata(t *testing.T) {
+	allowedDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(allowedDir, "password"), []byte("s3cr3t\n"), 0600))
+	otherDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(otherDir, "token"), []byte("token"), 0600))
+
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("ad_template_file_allowed_dirs", []string{allowedDir})
+
+	svc := &dummyService{
+		ID:            "a5901276aed1",
+		ADIdentifiers: []string{"redis"},
+		Metadata: &listeners.WorkloadMetadata{
+			Labels:        map[string]string{"app.kubernetes.io/version": "7.2", "team": "cache"},
+			Annotations:   map[string]string{"redis.io/db": "3"},
+			ContainerName: "redis-primary",
+			ImageName:     "redis",
+			ImageTag:      "7.2.4",
+			PodName:       "redis-0",
+			Namespace:     "storage",
+		},
+	}
+
+	testCases := []struct {
+		testName    string
+		svc         listeners.Service
+		instance    string
+		out         string
+		errorString string
+	}{
+		{
+			testName: "labels and annotations",
+			svc:      svc,
+			instance: "version: '%%label_app.kubernetes.io/version%%'\ndb: %%annotation_redis.io/db%%\nteam: %%label_team%%",
+			out:      "db: 3\ntags:\n- foo:bar\nteam: cache\nversion: \"7.2\"\n",
+		},
+		{
+			testName: "names",
+			svc:      svc,
+			instance: "name: '%%namespace%%/%%pod_name%%/%%container_name%%'\nimage: '%%image_name%%:%%image_tag%%'",
+			out:      "image: redis:7.2.4\nname: storage/redis-0/redis-primary\ntags:\n- foo:bar\n",
+		},
+		{
+			testName: "file",
+			svc:      svc,
+			instance: "password: %%file_" + filepath.Join(allowedDir, "password") + "%%",
+			out:      "password: s3cr3t\ntags:\n- foo:bar\n",
+		},
+		{
+			testName:    "file out of the allowed directories",
+			svc:         svc,
+			instance:    "password: %%file_" + filepath.Join(otherDir, "token") + "%%",
+			errorString: "cannot resolve %%file_" + filepath.Join(otherDir, "token") + "%%: the file is not located in ad_template_file_allowed_dirs",
+		},
+		{
+			testName:    "missing label",
+			svc:         svc,
+			instance:    "team: %%label_owner%%",
+			errorString: "label \"owner\" not found, cannot resolve %%label_owner%% for service a5901276aed1",
+		},
+		{
+			testName:    "invalid image variable",
+			svc:         svc,
+			instance:    "image: %%image_digest%%",
+			errorString: "invalid %%image_digest%% tag, expected %%image_name%% or %%image_tag%%",
+		},
+		{
+			testName: "no pod",
+			svc: &dummyService{
+				ID:       "a5901276aed1",
+				Metadata: &listeners.WorkloadMetadata{ContainerName: "redis"},
+			},
+			instance:    "namespace: %%namespace%%",
+			errorString: "%%namespace%% is not available for service a5901276aed1",
+		},
+		{
+			testName:    "not a container",
+			svc:         &dummyService{ID: "a5901276aed1"},
+			instance:    "name: %%container_name%%",
+			errorString: "%%container_name%% is only supported for containers and pods, skipping service a5901276aed1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			tpl := integration.Config{
+				Name:          "redisdb",
+				ADIdentifiers: []string{"redis"},
+				Instances:     []integration.Data{integration.Data(tc.instance)},
+			}
+			cfg, err := Resolve(tpl, tc.svc)
+			if tc.errorString != "" {
+				assert.EqualError(t, err, tc.errorString)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.out, string(cfg.Instances[0]))
+		})
+	}
+}
+
 func BenchmarkResolve(b *testing.B) {
 	// Prepare envvars for test
 	b.Setenv("test_envvar_key", "test_value")
@@ -7,6 +7,7 @@
 package integration
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strconv"
@@ -88,6 +89,10 @@ type Config struct {
 	// ClusterCheck is cluster-check configuration flag
 	ClusterCheck bool `json:"cluster_check"` // (include in digest: false)
 
+	// Placement holds the constraints on the runners a cluster check can be
+	// dispatched to (optional)
+	Placement *PlacementConstraints `json:"placement,omitempty"` // (include in digest: true, when set)
+
 	// NodeName is node name in case of an endpoint check backed by a pod
 	NodeName string `json:"node_name"` // (include in digest: true)
 
@@ -118,6 +123,12 @@ type CommonInstanceConfig struct {
 	Name                  string   `yaml:"name"`
 	Namespace             string   `yaml:"namespace"`
 	NoIndex               bool     `yaml:"no_index"`
+	// Schedule is a cron expression of the times the check runs at, instead of every collection interval
+	Schedule string `yaml:"schedule"`
+	// ScheduleJitter is the maximum random delay, in seconds, added to the times of the schedule
+	ScheduleJitter int `yaml:"schedule_jitter"`
+	// RunTimeout is the maximum duration, in seconds, of a run of the check before it's cancelled
+	RunTimeout int `yaml:"run_timeout"`
 }
 
 // CommonGlobalConfig holds the reserved fields for the yaml init_config data
@@ -132,6 +143,22 @@ type AdvancedADIdentifier struct {
 	KubeEndpoints KubeNamespacedName `yaml:"kube_endpoints,omitempty"`
 }
 
+// PlacementConstraints restricts the cluster check runners a cluster check
+// can be dispatched to.
+type PlacementConstraints struct {
+	// NodeSelector restricts the check to the runners having all these labels
+	NodeSelector map[string]string `json:"node_selector,omitempty" yaml:"node_selector,omitempty"`
+	// AntiAffinityGroup prevents two checks of the same group from being
+	// dispatched to the same runner
+	AntiAffinityGroup string `json:"anti_affinity_group,omitempty" yaml:"anti_affinity_group,omitempty"`
+	// TopologySpreadKey is a runner label, such as "zone", across the values of
+	// which the checks of the same spread group are evenly spread
+	TopologySpreadKey string `json:"topology_spread_key,omitempty" yaml:"topology_spread_key,omitempty"`
+	// SpreadGroup is the group of checks spread across TopologySpreadKey,
+	// defaults to the name of the check
+	SpreadGroup string `json:"spread_group,omitempty" yaml:"spread_group,omitempty"`
+}
+
 // KubeNamespacedName identifies a kubernetes object.
 type KubeNamespacedName struct {
 	Name      string `yaml:"name"`
@@ -418,6 +445,11 @@ func (c *Config) IntDigest() uint64 {
 	_, _ = h.Write([]byte(c.LogsConfig))
 	_, _ = h.Write([]byte(c.ServiceID))
 	_, _ = h.Write([]byte(strconv.FormatBool(c.IgnoreAutodiscoveryTags)))
+	if c.Placement != nil {
+		// only hashed when set to keep the digest of the other configs unchanged
+		placement, _ := json.Marshal(c.Placement)
+		_, _ = h.Write(placement)
+	}
 
 	return h.Sum64()
 }
@@ -482,6 +514,7 @@ func (c *Config) Dump(multiline bool) string {
 	fmt.Fprintf(&b, ws("ServiceID: %#v,"), c.ServiceID)
 	fmt.Fprintf(&b, ws("TaggerEntity: %#v,"), c.TaggerEntity)
 	fmt.Fprintf(&b, ws("ClusterCheck: %t,"), c.ClusterCheck)
+	fmt.Fprintf(&b, ws("Placement: %+v,"), c.Placement)
 	fmt.Fprintf(&b, ws("NodeName: %#v,"), c.NodeName)
 	fmt.Fprintf(&b, ws("Source: %s,"), c.Source)
 	fmt.Fprintf(&b, ws("IgnoreAutodiscoveryTags: %t,"), c.IgnoreAutodiscoveryTags)
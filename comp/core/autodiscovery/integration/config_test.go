@@ -245,6 +245,23 @@ func TestDigest(t *testing.T) {
 
 	// assert the ClusterCheck field is not taken into account
 	assert.NotEqual(t, simpleConfig.Digest(), simpleIngoreADTagsConfig.Digest())
+
+	simplePlacementConfig := &Config{
+		Name:       "foo",
+		InitConfig: Data(""),
+		Placement:  &PlacementConstraints{NodeSelector: map[string]string{"zone": "eu-west-1a"}},
+	}
+	otherPlacementConfig := &Config{
+		Name:       "foo",
+		InitConfig: Data(""),
+		Placement:  &PlacementConstraints{NodeSelector: map[string]string{"zone": "eu-west-1b"}},
+	}
+
+	// assert placement constraints are taken into account
+	assert.NotEqual(t, simpleConfig.Digest(), simplePlacementConfig.Digest())
+	assert.NotEqual(t, simplePlacementConfig.Digest(), otherPlacementConfig.Digest())
+	// but not in the fast digest, used to build check IDs
+	assert.Equal(t, simpleConfig.FastDigest(), simplePlacementConfig.FastDigest())
 }
 
 func TestGetNameForInstance(t *testing.T) {
@@ -267,6 +267,11 @@ func (s *CloudFoundryService) GetExtraConfig(key string) (string, error) {
 	return "", ErrNotSupported
 }
 
+// GetWorkloadMetadata is not supported
+func (s *CloudFoundryService) GetWorkloadMetadata() (*WorkloadMetadata, error) {
+	return nil, ErrNotSupported
+}
+
 // FilterTemplates does nothing.
 func (s *CloudFoundryService) FilterTemplates(map[string]integration.Config) {
 }
@@ -123,10 +123,19 @@ func (l *ContainerListener) createContainerService(entity workloadmeta.Entity) {
 		ports:    ports,
 		pid:      container.PID,
 		hostname: container.Hostname,
-		tagger:   l.tagger,
+		metadata: &WorkloadMetadata{
+			Labels:        container.Labels,
+			ContainerName: container.Name,
+			ImageName:     containerImg.ShortName,
+			ImageTag:      containerImg.Tag,
+		},
+		tagger: l.tagger,
 	}
 
 	if pod != nil {
+		svc.metadata.Annotations = pod.Annotations
+		svc.metadata.PodName = pod.Name
+		svc.metadata.Namespace = pod.Namespace
 		svc.hosts = map[string]string{"pod": pod.IP}
 		svc.ready = pod.Ready
 
@@ -185,6 +185,10 @@ func TestCreateContainerService(t *testing.T) {
 						hosts: map[string]string{},
 						ports: []ContainerPort{},
 						ready: true,
+						metadata: &WorkloadMetadata{
+							ContainerName: containerName,
+							ImageName:     "foobar",
+						},
 					},
 				},
 			},
@@ -222,6 +226,10 @@ func TestCreateContainerService(t *testing.T) {
 						hosts: map[string]string{},
 						ports: []ContainerPort{},
 						ready: true,
+						metadata: &WorkloadMetadata{
+							ContainerName: containerName,
+							ImageName:     "foobar",
+						},
 					},
 				},
 			},
@@ -250,6 +258,10 @@ func TestCreateContainerService(t *testing.T) {
 							},
 						},
 						ready: true,
+						metadata: &WorkloadMetadata{
+							ContainerName: containerName,
+							ImageName:     "foobar",
+						},
 					},
 				},
 			},
@@ -271,6 +283,14 @@ func TestCreateContainerService(t *testing.T) {
 						hosts: map[string]string{"pod": pod.IP},
 						ports: []ContainerPort{},
 						ready: pod.Ready,
+						metadata: &WorkloadMetadata{
+							Labels:        kubernetesContainer.Labels,
+							Annotations:   pod.Annotations,
+							ContainerName: kubernetesContainer.Name,
+							ImageName:     "foobar",
+							PodName:       podName,
+							Namespace:     podNamespace,
+						},
 					},
 				},
 			},
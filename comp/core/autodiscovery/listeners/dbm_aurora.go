@@ -284,6 +284,11 @@ func (d *DBMAuroraService) GetExtraConfig(key string) (string, error) {
 	return "", ErrNotSupported
 }
 
+// GetWorkloadMetadata is not supported
+func (d *DBMAuroraService) GetWorkloadMetadata() (*WorkloadMetadata, error) {
+	return nil, ErrNotSupported
+}
+
 // FilterTemplates does nothing.
 func (d *DBMAuroraService) FilterTemplates(map[string]integration.Config) {
 }
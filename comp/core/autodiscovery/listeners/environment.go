@@ -143,6 +143,11 @@ func (s *EnvironmentService) GetExtraConfig(_ string) (string, error) {
 	return "", ErrNotSupported
 }
 
+// GetWorkloadMetadata is not supported
+func (s *EnvironmentService) GetWorkloadMetadata() (*WorkloadMetadata, error) {
+	return nil, ErrNotSupported
+}
+
 // FilterTemplates does nothing.
 //
 //nolint:revive // TODO(CINT) Fix revive linter
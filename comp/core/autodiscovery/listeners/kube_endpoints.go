@@ -519,6 +519,11 @@ func (s *KubeEndpointService) GetExtraConfig(key string) (string, error) {
 	return "", ErrNotSupported
 }
 
+// GetWorkloadMetadata is not supported
+func (s *KubeEndpointService) GetWorkloadMetadata() (*WorkloadMetadata, error) {
+	return nil, ErrNotSupported
+}
+
 // FilterTemplates does nothing.
 func (s *KubeEndpointService) FilterTemplates(map[string]integration.Config) {
 }
@@ -400,6 +400,11 @@ func (s *KubeServiceService) GetExtraConfig(key string) (string, error) {
 	return "", ErrNotSupported
 }
 
+// GetWorkloadMetadata is not supported
+func (s *KubeServiceService) GetWorkloadMetadata() (*WorkloadMetadata, error) {
+	return nil, ErrNotSupported
+}
+
 // FilterTemplates does nothing.
 func (s *KubeServiceService) FilterTemplates(map[string]integration.Config) {
 }
@@ -101,7 +101,13 @@ func (l *KubeletListener) createPodService(
 		hosts:         map[string]string{"pod": pod.IP},
 		ports:         ports,
 		ready:         true,
-		tagger:        l.tagger,
+		metadata: &WorkloadMetadata{
+			Labels:      pod.Labels,
+			Annotations: pod.Annotations,
+			PodName:     pod.Name,
+			Namespace:   pod.Namespace,
+		},
+		tagger: l.tagger,
 	}
 
 	svcID := buildSvcID(pod.GetID())
@@ -168,6 +174,15 @@ func (l *KubeletListener) createContainerService(
 			"pod_uid":   pod.ID,
 		},
 		hosts: map[string]string{"pod": pod.IP},
+		metadata: &WorkloadMetadata{
+			Labels:        container.Labels,
+			Annotations:   pod.Annotations,
+			ContainerName: containerName,
+			ImageName:     containerImg.ShortName,
+			ImageTag:      containerImg.Tag,
+			PodName:       pod.Name,
+			Namespace:     pod.Namespace,
+		},
 
 		// Exclude non-running containers (including init containers)
 		// from metrics collection but keep them for collecting logs.
@@ -86,6 +86,10 @@ func TestKubeletCreatePodService(t *testing.T) {
 						hosts: map[string]string{
 							"pod": "127.0.0.1",
 						},
+						metadata: &WorkloadMetadata{
+							PodName:   podName,
+							Namespace: podNamespace,
+						},
 						ready:  true,
 						tagger: taggerComponent,
 					},
@@ -262,6 +266,12 @@ func TestKubeletCreateContainerService(t *testing.T) {
 							"pod_name":  podName,
 							"pod_uid":   podID,
 						},
+						metadata: &WorkloadMetadata{
+							ContainerName: containerName,
+							ImageName:     "foobar",
+							PodName:       podName,
+							Namespace:     podNamespace,
+						},
 						tagger: taggerComponent,
 					},
 				},
@@ -295,6 +305,12 @@ func TestKubeletCreateContainerService(t *testing.T) {
 							"pod_name":  podName,
 							"pod_uid":   podID,
 						},
+						metadata: &WorkloadMetadata{
+							ContainerName: containerName,
+							ImageName:     "foobar",
+							PodName:       podName,
+							Namespace:     podNamespace,
+						},
 						tagger: taggerComponent,
 					},
 				},
@@ -350,6 +366,12 @@ func TestKubeletCreateContainerService(t *testing.T) {
 							"pod_name":  podName,
 							"pod_uid":   podID,
 						},
+						metadata: &WorkloadMetadata{
+							ContainerName: containerName,
+							ImageName:     "foobar",
+							PodName:       podName,
+							Namespace:     podNamespace,
+						},
 						tagger: taggerComponent,
 					},
 				},
@@ -391,6 +413,12 @@ func TestKubeletCreateContainerService(t *testing.T) {
 							"pod_name":  podName,
 							"pod_uid":   podID,
 						},
+						metadata: &WorkloadMetadata{
+							ContainerName: containerName,
+							ImageName:     "foobar",
+							PodName:       podName,
+							Namespace:     podNamespace,
+						},
 						tagger: taggerComponent,
 					},
 				},
@@ -425,6 +453,13 @@ func TestKubeletCreateContainerService(t *testing.T) {
 							"pod_name":  podName,
 							"pod_uid":   podID,
 						},
+						metadata: &WorkloadMetadata{
+							Annotations:   podWithAnnotations.Annotations,
+							ContainerName: containerName,
+							ImageName:     "foobar",
+							PodName:       podName,
+							Namespace:     podNamespace,
+						},
 						tagger: taggerComponent,
 					},
 				},
@@ -470,6 +505,13 @@ func TestKubeletCreateContainerService(t *testing.T) {
 							"pod_name":  podName,
 							"pod_uid":   podID,
 						},
+						metadata: &WorkloadMetadata{
+							Annotations:   podWithMetricsExcludeAnnotation.Annotations,
+							ContainerName: containerName,
+							ImageName:     "foobar",
+							PodName:       podName,
+							Namespace:     podNamespace,
+						},
 						metricsExcluded: true,
 						tagger:          taggerComponent,
 					},
@@ -505,6 +547,13 @@ func TestKubeletCreateContainerService(t *testing.T) {
 							"pod_name":  podName,
 							"pod_uid":   podID,
 						},
+						metadata: &WorkloadMetadata{
+							Annotations:   podWithLogsExcludeAnnotation.Annotations,
+							ContainerName: containerName,
+							ImageName:     "foobar",
+							PodName:       podName,
+							Namespace:     podNamespace,
+						},
 						logsExcluded: true,
 						tagger:       taggerComponent,
 					},
@@ -35,6 +35,7 @@ type service struct {
 	ready           bool
 	checkNames      []string
 	extraConfig     map[string]string
+	metadata        *WorkloadMetadata
 	metricsExcluded bool
 	logsExcluded    bool
 	tagger          tagger.Component
@@ -55,6 +56,7 @@ func (s *service) Equal(o Service) bool {
 		reflect.DeepEqual(s.ports, s2.ports) &&
 		reflect.DeepEqual(s.adIdentifiers, s2.adIdentifiers) &&
 		reflect.DeepEqual(s.checkNames, s2.checkNames) &&
+		reflect.DeepEqual(s.metadata, s2.metadata) &&
 		s.hostname == s2.hostname &&
 		s.pid == s2.pid &&
 		s.ready == s2.ready
@@ -215,3 +217,12 @@ func (s *service) GetExtraConfig(key string) (string, error) {
 
 	return result, nil
 }
+
+// GetWorkloadMetadata returns the labels, annotations and names of the
+// container or pod of the service.
+func (s *service) GetWorkloadMetadata() (*WorkloadMetadata, error) {
+	if s.metadata == nil {
+		return nil, ErrNotSupported
+	}
+	return s.metadata, nil
+}
@@ -500,6 +500,11 @@ func (s *SNMPService) GetExtraConfig(key string) (string, error) {
 	return "", ErrNotSupported
 }
 
+// GetWorkloadMetadata is not supported
+func (s *SNMPService) GetWorkloadMetadata() (*WorkloadMetadata, error) {
+	return nil, ErrNotSupported
+}
+
 // FilterTemplates does nothing.
 //
 //nolint:revive // TODO(NDM) Fix revive linter
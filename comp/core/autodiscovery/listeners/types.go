@@ -40,6 +40,7 @@ type Service interface {
 	IsReady(context.Context) bool                                // is the service ready
 	HasFilter(containers.FilterType) bool                        // whether the service is excluded by metrics or logs exclusion config
 	GetExtraConfig(string) (string, error)                       // Extra configuration values
+	GetWorkloadMetadata() (*WorkloadMetadata, error)             // labels, annotations and names of the workload
 
 	// FilterTemplates filters the templates which will be resolved against
 	// this service, in a map keyed by template digest.
@@ -51,6 +52,20 @@ type Service interface {
 	FilterTemplates(map[string]integration.Config)
 }
 
+// WorkloadMetadata holds the metadata of the workloadmeta entity behind a
+// Service, used to resolve the template variables of the configs.
+type WorkloadMetadata struct {
+	// Labels are the labels of the container, or of the pod for pod services
+	Labels map[string]string
+	// Annotations are the annotations of the pod, if any
+	Annotations   map[string]string
+	ContainerName string
+	ImageName     string
+	ImageTag      string
+	PodName       string
+	Namespace     string
+}
+
 // ServiceListener monitors running services and triggers check (un)scheduling
 //
 // It holds a cache of running services, listens to new/killed services and
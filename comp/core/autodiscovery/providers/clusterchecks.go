@@ -9,6 +9,8 @@ package providers
 import (
 	"context"
 	"errors"
+	"maps"
+	"sync"
 	"time"
 
 	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
@@ -37,6 +39,10 @@ type ClusterChecksConfigProvider struct {
 	lastChange       int64
 	identifier       string
 	flushedConfigs   bool
+
+	// labels are the labels of the runner, nil until they're resolved
+	labelsLock sync.Mutex
+	labels     map[string]string
 }
 
 // NewClusterChecksConfigProvider returns a new ConfigProvider collecting
@@ -115,6 +121,7 @@ func (c *ClusterChecksConfigProvider) IsUpToDate(ctx context.Context) (bool, err
 
 	status := types.NodeStatus{
 		LastChange: c.lastChange,
+		Labels:     c.runnerLabels(),
 	}
 
 	reply, err := c.dcaClient.PostClusterCheckStatus(ctx, c.identifier, status)
@@ -213,12 +220,42 @@ func (c *ClusterChecksConfigProvider) postHeartbeat(ctx context.Context) error {
 
 	status := types.NodeStatus{
 		LastChange: types.ExtraHeartbeatLastChangeValue,
+		Labels:     c.runnerLabels(),
 	}
 
 	_, err := c.dcaClient.PostClusterCheckStatus(ctx, c.identifier, status)
 	return err
 }
 
+// runnerLabels returns the labels matched by the placement constraints of the cluster checks: the labels
+// of the Kubernetes node of the runner, overridden by the clc_runner_labels setting. The node labels are
+// queried from the cluster-agent, again on the next call when it fails.
+func (c *ClusterChecksConfigProvider) runnerLabels() map[string]string {
+	c.labelsLock.Lock()
+	defer c.labelsLock.Unlock()
+
+	if c.labels != nil {
+		return c.labels
+	}
+
+	labels := map[string]string{}
+	if nodeName := pkgconfigsetup.Datadog().GetString("kubernetes_kubelet_nodename"); nodeName != "" {
+		if c.dcaClient == nil {
+			return nil
+		}
+		nodeLabels, err := c.dcaClient.GetNodeLabels(nodeName)
+		if err != nil {
+			log.Warnf("Unable to get the labels of node %s from the Cluster Agent, will retry: %s", nodeName, err)
+			return nil
+		}
+		maps.Copy(labels, nodeLabels)
+	}
+	maps.Copy(labels, pkgconfigsetup.Datadog().GetStringMapString("clc_runner_labels"))
+
+	c.labels = labels
+	return c.labels
+}
+
 // GetConfigErrors is not implemented for the ClusterChecksConfigProvider
 func (c *ClusterChecksConfigProvider) GetConfigErrors() map[string]ErrorMsgSet {
 	return make(map[string]ErrorMsgSet)
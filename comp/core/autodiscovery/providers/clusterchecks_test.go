@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package providers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/util/clusteragent"
+)
+
+type fakeNodeLabelsDCAClient struct {
+	clusteragent.DCAClientInterface
+	labels map[string]string
+	err    error
+	calls  int
+}
+
+func (c *fakeNodeLabelsDCAClient) GetNodeLabels(nodeName string) (map[string]string, error) {
+	c.calls++
+	if nodeName != "node-1" {
+		return nil, errors.New("node not found")
+	}
+	return c.labels, c.err
+}
+
+func TestClusterChecksRunnerLabels(t *testing.T) {
+	t.Run("static labels", func(t *testing.T) {
+		mockConfig := configmock.New(t)
+		mockConfig.SetWithoutSource("clc_runner_labels", map[string]string{"zone": "eu-west-1a"})
+
+		c := &ClusterChecksConfigProvider{}
+		assert.Equal(t, map[string]string{"zone": "eu-west-1a"}, c.runnerLabels())
+	})
+
+	t.Run("node labels overridden by static labels", func(t *testing.T) {
+		mockConfig := configmock.New(t)
+		mockConfig.SetWithoutSource("kubernetes_kubelet_nodename", "node-1")
+		mockConfig.SetWithoutSource("clc_runner_labels", map[string]string{"zone": "eu-west-1a"})
+
+		client := &fakeNodeLabelsDCAClient{labels: map[string]string{"zone": "eu-west-1b", "pool": "checks"}}
+		c := &ClusterChecksConfigProvider{dcaClient: client}
+		assert.Equal(t, map[string]string{"zone": "eu-west-1a", "pool": "checks"}, c.runnerLabels())
+
+		// the labels are resolved once
+		c.runnerLabels()
+		assert.Equal(t, 1, client.calls)
+	})
+
+	t.Run("node labels retried on error", func(t *testing.T) {
+		mockConfig := configmock.New(t)
+		mockConfig.SetWithoutSource("kubernetes_kubelet_nodename", "node-1")
+
+		client := &fakeNodeLabelsDCAClient{err: errors.New("unavailable")}
+		c := &ClusterChecksConfigProvider{dcaClient: client}
+		assert.Nil(t, c.runnerLabels())
+
+		client.labels = map[string]string{"pool": "checks"}
+		client.err = nil
+		assert.Equal(t, map[string]string{"pool": "checks"}, c.runnerLabels())
+		assert.Equal(t, 2, client.calls)
+	})
+}
@@ -29,6 +29,7 @@ type configFormat struct {
 	ADIdentifiers           []string                           `yaml:"ad_identifiers"`
 	AdvancedADIdentifiers   []integration.AdvancedADIdentifier `yaml:"advanced_ad_identifiers"`
 	ClusterCheck            bool                               `yaml:"cluster_check"`
+	Placement               *integration.PlacementConstraints  `yaml:"placement"`
 	InitConfig              interface{}                        `yaml:"init_config"`
 	MetricConfig            interface{}                        `yaml:"jmx_metrics"`
 	LogsConfig              interface{}                        `yaml:"logs"`
@@ -425,8 +426,9 @@ func GetIntegrationConfigFromFile(name, fpath string) (integration.Config, error
 	conf.ADIdentifiers = cf.ADIdentifiers
 	conf.AdvancedADIdentifiers = cf.AdvancedADIdentifiers
 
-	// Copy cluster_check status
+	// Copy cluster_check status and placement constraints
 	conf.ClusterCheck = cf.ClusterCheck
+	conf.Placement = cf.Placement
 
 	// Copy ignore_autodiscovery_tags parameter
 	conf.IgnoreAutodiscoveryTags = cf.IgnoreAutodiscoveryTags
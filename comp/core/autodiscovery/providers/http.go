@@ -0,0 +1,360 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/providers/names"
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/telemetry"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	defaultHTTPProviderTimeout = 10 * time.Second
+	// maxHTTPProviderResponseSize caps the size of the list of configs returned by the endpoint
+	maxHTTPProviderResponseSize = 10 * 1024 * 1024
+)
+
+// HTTPFetchStatus is the outcome of the fetches of the HTTPConfigProvider, displayed in the
+// autodiscovery section of `agent status`
+type HTTPFetchStatus struct {
+	URL            string `json:"url"`
+	LastFetch      int64  `json:"last_fetch"`   // Unix timestamp of the last fetch
+	LastSuccess    int64  `json:"last_success"` // Unix timestamp of the last successful fetch
+	LastStatusCode int    `json:"last_status_code"`
+	LastError      string `json:"last_error"`
+	ETag           string `json:"etag"`
+	Configs        int    `json:"configs"`
+}
+
+var (
+	httpFetchStatusMu sync.RWMutex
+	httpFetchStatus   *HTTPFetchStatus
+)
+
+// GetHTTPFetchStatus returns the fetch status of the HTTPConfigProvider, or nil if it's not running
+func GetHTTPFetchStatus() *HTTPFetchStatus {
+	httpFetchStatusMu.RLock()
+	defer httpFetchStatusMu.RUnlock()
+
+	if httpFetchStatus == nil {
+		return nil
+	}
+	status := *httpFetchStatus
+	return &status
+}
+
+func setHTTPFetchStatus(status HTTPFetchStatus) {
+	httpFetchStatusMu.Lock()
+	defer httpFetchStatusMu.Unlock()
+	httpFetchStatus = &status
+}
+
+// httpConfigFormat is the format of each config returned by the endpoint: the one of a
+// configuration file, with the name of the check
+type httpConfigFormat struct {
+	Name                    string                             `yaml:"name"`
+	ADIdentifiers           []string                           `yaml:"ad_identifiers"`
+	AdvancedADIdentifiers   []integration.AdvancedADIdentifier `yaml:"advanced_ad_identifiers"`
+	ClusterCheck            bool                               `yaml:"cluster_check"`
+	Placement               *integration.PlacementConstraints  `yaml:"placement"`
+	InitConfig              interface{}                        `yaml:"init_config"`
+	LogsConfig              interface{}                        `yaml:"logs"`
+	Instances               []integration.RawMap               `yaml:"instances"`
+	IgnoreAutodiscoveryTags bool                               `yaml:"ignore_autodiscovery_tags"`
+	CheckTagCardinality     string                             `yaml:"check_tag_cardinality"`
+}
+
+// HTTPConfigProvider implements the ConfigProvider interface.
+// It periodically fetches a JSON or YAML list of configs from an HTTP endpoint,
+// using the ETag of the last response to only download the list when it changed.
+type HTTPConfigProvider struct {
+	client *http.Client
+	url    string
+	token  string
+
+	mu           sync.Mutex
+	etag         string
+	configs      []integration.Config
+	configErrors map[string]ErrorMsgSet
+	// modified is true when the configs were fetched by IsUpToDate and are not collected yet
+	modified bool
+	status   HTTPFetchStatus
+}
+
+// NewHTTPConfigProvider creates a new HTTPConfigProvider polling providerConfig.TemplateURL
+func NewHTTPConfigProvider(providerConfig *pkgconfigsetup.ConfigurationProviders, _ *telemetry.Store) (ConfigProvider, error) {
+	if providerConfig == nil || providerConfig.TemplateURL == "" {
+		return nil, errors.New("the http config provider requires a template_url")
+	}
+
+	u, err := url.Parse(providerConfig.TemplateURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template_url %q: %w", providerConfig.TemplateURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid template_url %q: the scheme must be http or https", providerConfig.TemplateURL)
+	}
+
+	tlsConfig, err := buildHTTPProviderTLSConfig(providerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultHTTPProviderTimeout
+	if providerConfig.TimeoutSeconds > 0 {
+		timeout = time.Duration(providerConfig.TimeoutSeconds) * time.Second
+	}
+
+	p := &HTTPConfigProvider{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+		url:          providerConfig.TemplateURL,
+		token:        providerConfig.Token,
+		configErrors: make(map[string]ErrorMsgSet),
+		status:       HTTPFetchStatus{URL: u.Redacted()},
+	}
+	setHTTPFetchStatus(p.status)
+
+	return p, nil
+}
+
+// buildHTTPProviderTLSConfig returns the TLS configuration trusting ca_file, and presenting
+// cert_file and key_file as client certificate for mutual TLS
+func buildHTTPProviderTLSConfig(providerConfig *pkgconfigsetup.ConfigurationProviders) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if providerConfig.CAFile != "" {
+		caCert, err := os.ReadFile(providerConfig.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificate found in ca_file %s", providerConfig.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if providerConfig.CertFile != "" || providerConfig.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(providerConfig.CertFile, providerConfig.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// String returns a string representation of the HTTPConfigProvider
+func (p *HTTPConfigProvider) String() string {
+	return names.HTTP
+}
+
+// Collect returns the configs of the last response of the endpoint, fetching them
+// if IsUpToDate didn't already
+func (p *HTTPConfigProvider) Collect(ctx context.Context) ([]integration.Config, error) {
+	p.mu.Lock()
+	modified := p.modified
+	p.modified = false
+	p.mu.Unlock()
+
+	if !modified {
+		if _, err := p.fetch(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]integration.Config(nil), p.configs...), nil
+}
+
+// IsUpToDate fetches the list of configs, and returns false when it changed since the last fetch
+func (p *HTTPConfigProvider) IsUpToDate(ctx context.Context) (bool, error) {
+	modified, err := p.fetch(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.modified = p.modified || modified
+	return !p.modified, nil
+}
+
+// GetConfigErrors returns the errors of the configs of the last response that couldn't be parsed
+func (p *HTTPConfigProvider) GetConfigErrors() map[string]ErrorMsgSet {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	errors := make(map[string]ErrorMsgSet, len(p.configErrors))
+	for entity, errset := range p.configErrors {
+		errors[entity] = errset
+	}
+	return errors
+}
+
+// fetch gets the list of configs from the endpoint, and returns whether it changed since
+// the last fetch. The configs of the last successful fetch are kept on errors.
+func (p *HTTPConfigProvider) fetch(ctx context.Context) (bool, error) {
+	p.mu.Lock()
+	etag := p.etag
+	p.mu.Unlock()
+
+	statusCode, newETag, body, err := p.get(ctx, etag)
+	if err == nil && statusCode == http.StatusOK {
+		var configs []integration.Config
+		var configErrors map[string]ErrorMsgSet
+		configs, configErrors, err = p.parseConfigs(body)
+		if err == nil {
+			p.mu.Lock()
+			p.etag = newETag
+			p.configs = configs
+			p.configErrors = configErrors
+			p.mu.Unlock()
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now().Unix()
+	p.status.LastFetch = now
+	p.status.LastStatusCode = statusCode
+	if err != nil {
+		p.status.LastError = err.Error()
+		setHTTPFetchStatus(p.status)
+		return false, err
+	}
+	p.status.LastSuccess = now
+	p.status.LastError = ""
+	p.status.ETag = p.etag
+	p.status.Configs = len(p.configs)
+	setHTTPFetchStatus(p.status)
+
+	return statusCode == http.StatusOK, nil
+}
+
+// get sends a GET request conditional on etag, and returns the body of the response
+// when it's a 200
+func (p *HTTPConfigProvider) get(ctx context.Context, etag string) (int, string, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	req.Header.Set("Accept", "application/json, application/yaml")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return resp.StatusCode, etag, nil, nil
+	case http.StatusOK:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPProviderResponseSize+1))
+		if err != nil {
+			return resp.StatusCode, "", nil, fmt.Errorf("unable to read the response: %w", err)
+		}
+		if len(body) > maxHTTPProviderResponseSize {
+			return resp.StatusCode, "", nil, fmt.Errorf("the response exceeds %d bytes", maxHTTPProviderResponseSize)
+		}
+		return resp.StatusCode, resp.Header.Get("ETag"), body, nil
+	default:
+		return resp.StatusCode, "", nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
+
+// parseConfigs parses a JSON or YAML list of configs. Invalid configs are skipped and
+// returned as config errors, indexed by their position in the list.
+func (p *HTTPConfigProvider) parseConfigs(body []byte) ([]integration.Config, map[string]ErrorMsgSet, error) {
+	// JSON being a subset of YAML, both are parsed by the YAML parser
+	var entries []httpConfigFormat
+	if err := yaml.Unmarshal(body, &entries); err != nil {
+		return nil, nil, fmt.Errorf("unable to parse the list of configs: %w", err)
+	}
+
+	configs := make([]integration.Config, 0, len(entries))
+	configErrors := make(map[string]ErrorMsgSet)
+	for i, entry := range entries {
+		config, err := p.toIntegrationConfig(entry)
+		if err != nil {
+			id := fmt.Sprintf("%s[%d]", p.status.URL, i)
+			log.Warnf("Ignoring config %s from the http config provider: %s", id, err)
+			configErrors[id] = ErrorMsgSet{err.Error(): struct{}{}}
+			continue
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, configErrors, nil
+}
+
+func (p *HTTPConfigProvider) toIntegrationConfig(entry httpConfigFormat) (integration.Config, error) {
+	config := integration.Config{
+		Name:                    entry.Name,
+		ADIdentifiers:           entry.ADIdentifiers,
+		AdvancedADIdentifiers:   entry.AdvancedADIdentifiers,
+		ClusterCheck:            entry.ClusterCheck,
+		Placement:               entry.Placement,
+		IgnoreAutodiscoveryTags: entry.IgnoreAutodiscoveryTags,
+		CheckTagCardinality:     entry.CheckTagCardinality,
+		Source:                  "http:" + p.status.URL,
+	}
+
+	if config.Name == "" {
+		return config, errors.New("the config has no name")
+	}
+	if entry.LogsConfig == nil && len(entry.Instances) == 0 {
+		return config, fmt.Errorf("the config of %s contains no valid instances", config.Name)
+	}
+
+	// the entry was already parsed, marshalling it back can't fail
+	if entry.InitConfig != nil {
+		config.InitConfig, _ = yaml.Marshal(entry.InitConfig)
+	}
+	for _, instance := range entry.Instances {
+		rawInstance, _ := yaml.Marshal(instance)
+		config.Instances = append(config.Instances, rawInstance)
+	}
+	if entry.LogsConfig != nil {
+		config.LogsConfig, _ = yaml.Marshal(map[string]interface{}{"logs": entry.LogsConfig})
+	}
+
+	return config, nil
+}
@@ -0,0 +1,179 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+)
+
+// configServer serves a list of configs with its version as ETag
+type configServer struct {
+	mu         sync.Mutex
+	body       string
+	etag       string
+	statusCode int
+	requests   []*http.Request
+}
+
+func (s *configServer) set(body, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body = body
+	s.etag = etag
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+
+	if s.statusCode != 0 {
+		w.WriteHeader(s.statusCode)
+		return
+	}
+	if r.Header.Get("If-None-Match") == s.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", s.etag)
+	w.Write([]byte(s.body))
+}
+
+func (s *configServer) lastRequest() *http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[len(s.requests)-1]
+}
+
+func newTestHTTPProvider(t *testing.T, url string) *HTTPConfigProvider {
+	provider, err := NewHTTPConfigProvider(&pkgconfigsetup.ConfigurationProviders{
+		Name:        "http",
+		TemplateURL: url,
+		Token:       "s3cr3t",
+	}, nil)
+	require.NoError(t, err)
+	return provider.(*HTTPConfigProvider)
+}
+
+func TestNewHTTPConfigProvider(t *testing.T) {
+	_, err := NewHTTPConfigProvider(&pkgconfigsetup.ConfigurationProviders{}, nil)
+	assert.EqualError(t, err, "the http config provider requires a template_url")
+
+	_, err = NewHTTPConfigProvider(&pkgconfigsetup.ConfigurationProviders{TemplateURL: "ftp://example.com/configs"}, nil)
+	assert.EqualError(t, err, `invalid template_url "ftp://example.com/configs": the scheme must be http or https`)
+
+	_, err = NewHTTPConfigProvider(&pkgconfigsetup.ConfigurationProviders{
+		TemplateURL: "https://example.com/configs",
+		CAFile:      "/does/not/exist.pem",
+	}, nil)
+	assert.ErrorContains(t, err, "unable to read ca_file")
+}
+
+func TestHTTPConfigProviderCollect(t *testing.T) {
+	server := &configServer{}
+	server.set(`[{"name": "http_check", "init_config": {}, "instances": [{"url": "http://foo"}]}]`, `"v1"`)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	provider := newTestHTTPProvider(t, ts.URL)
+	ctx := context.Background()
+
+	configs, err := provider.Collect(ctx)
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "http_check", configs[0].Name)
+	assert.Equal(t, []integration.Data{integration.Data("url: http://foo\n")}, configs[0].Instances)
+	assert.Equal(t, integration.Data("{}\n"), configs[0].InitConfig)
+	assert.Equal(t, "http:"+ts.URL, configs[0].Source)
+	assert.Equal(t, "Bearer s3cr3t", server.lastRequest().Header.Get("Authorization"))
+
+	upToDate, err := provider.IsUpToDate(ctx)
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+	assert.Equal(t, `"v1"`, server.lastRequest().Header.Get("If-None-Match"))
+
+	server.set(`
+- name: redisdb
+  ad_identifiers: [redis]
+  instances:
+    - host: "%%host%%"
+      port: 6379
+  logs:
+    - type: file
+      path: /var/log/redis.log
+`, `"v2"`)
+
+	upToDate, err = provider.IsUpToDate(ctx)
+	require.NoError(t, err)
+	assert.False(t, upToDate)
+
+	requests := len(server.requests)
+	configs, err = provider.Collect(ctx)
+	require.NoError(t, err)
+	assert.Len(t, server.requests, requests, "Collect should use the configs fetched by IsUpToDate")
+	require.Len(t, configs, 1)
+	assert.Equal(t, "redisdb", configs[0].Name)
+	assert.Equal(t, []string{"redis"}, configs[0].ADIdentifiers)
+	assert.Equal(t, integration.Data("logs:\n- path: /var/log/redis.log\n  type: file\n"), configs[0].LogsConfig)
+
+	status := GetHTTPFetchStatus()
+	require.NotNil(t, status)
+	assert.Equal(t, ts.URL, status.URL)
+	assert.Equal(t, http.StatusOK, status.LastStatusCode)
+	assert.Equal(t, `"v2"`, status.ETag)
+	assert.Equal(t, 1, status.Configs)
+	assert.Empty(t, status.LastError)
+	assert.NotZero(t, status.LastSuccess)
+}
+
+func TestHTTPConfigProviderErrors(t *testing.T) {
+	server := &configServer{}
+	server.set(`[{"name": "http_check", "instances": [{"url": "http://foo"}]}, {"instances": [{}]}, {"name": "empty"}]`, `"v1"`)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	provider := newTestHTTPProvider(t, ts.URL)
+	ctx := context.Background()
+
+	configs, err := provider.Collect(ctx)
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, map[string]ErrorMsgSet{
+		ts.URL + "[1]": {"the config has no name": struct{}{}},
+		ts.URL + "[2]": {"the config of empty contains no valid instances": struct{}{}},
+	}, provider.GetConfigErrors())
+
+	// the configs of the last successful fetch are kept when the endpoint fails
+	server.mu.Lock()
+	server.statusCode = http.StatusInternalServerError
+	server.mu.Unlock()
+
+	_, err = provider.IsUpToDate(ctx)
+	assert.EqualError(t, err, "unexpected status code 500")
+	status := GetHTTPFetchStatus()
+	assert.Equal(t, http.StatusInternalServerError, status.LastStatusCode)
+	assert.Equal(t, "unexpected status code 500", status.LastError)
+	assert.Equal(t, 1, status.Configs)
+
+	server.mu.Lock()
+	server.statusCode = 0
+	server.mu.Unlock()
+	server.set(`{"not": "a list"}`, `"v2"`)
+
+	_, err = provider.IsUpToDate(ctx)
+	assert.ErrorContains(t, err, "unable to parse the list of configs")
+	assert.Len(t, provider.configs, 1)
+}
@@ -11,8 +11,12 @@ import (
 	"io"
 
 	"github.com/DataDog/datadog-agent/comp/core/autodiscovery"
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/providers"
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/providers/names"
 	"github.com/DataDog/datadog-agent/comp/core/status"
 	"github.com/DataDog/datadog-agent/pkg/config/env"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
 	"github.com/DataDog/datadog-agent/pkg/util/containers"
 )
 
@@ -21,6 +25,9 @@ func populateStatus(ac autodiscovery.Component, stats map[string]interface{}) {
 	stats["adEnabledFeatures"] = env.GetDetectedFeatures()
 	stats["adConfigErrors"] = ac.GetAutodiscoveryErrors()
 	stats["filterErrors"] = containers.GetFilterErrors()
+	if httpStatus := providers.GetHTTPFetchStatus(); httpStatus != nil {
+		stats["httpProviderStatus"] = httpStatus
+	}
 }
 
 //go:embed status_templates
@@ -31,15 +38,35 @@ type Provider struct {
 	ac autodiscovery.Component
 }
 
-// GetProvider if agent is running in a container environment returns status.Provider otherwise returns nil
+// GetProvider if agent is running in a container environment or uses the http config provider returns status.Provider otherwise returns nil
 func GetProvider(acComp autodiscovery.Component) status.Provider {
-	if env.IsContainerized() {
+	if env.IsContainerized() || isHTTPProviderConfigured() {
 		return Provider{ac: acComp}
 	}
 
 	return nil
 }
 
+// isHTTPProviderConfigured returns whether the http config provider is configured, its fetch
+// status being displayed in the autodiscovery section
+func isHTTPProviderConfigured() bool {
+	var configProviders []pkgconfigsetup.ConfigurationProviders
+	if err := structure.UnmarshalKey(pkgconfigsetup.Datadog(), "config_providers", &configProviders); err != nil {
+		return false
+	}
+	for _, cp := range configProviders {
+		if cp.Name == names.HTTPRegisterName {
+			return true
+		}
+	}
+	for _, name := range pkgconfigsetup.Datadog().GetStringSlice("extra_config_providers") {
+		if name == names.HTTPRegisterName {
+			return true
+		}
+	}
+	return false
+}
+
 func (p Provider) getStatusInfo() map[string]interface{} {
 	stats := make(map[string]interface{})
 
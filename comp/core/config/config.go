@@ -82,7 +82,7 @@ func newComponent(deps dependencies) (provides, error) {
 	c, err := newConfig(deps)
 	return provides{
 		Comp:          c,
-		FlareProvider: flaretypes.NewProvider(c.fillFlare),
+		FlareProvider: flaretypes.NewNamedProvider("config", c.fillFlare),
 	}, err
 }
 
@@ -26,7 +26,8 @@ import "time"
 //
 // Everytime a file is copied to the flare the original permissions and ownership of the file is recorded (Unix only).
 //
-// There are reserved path in the flare: "permissions.log" and "flare-creationg.log" (both at the root of the flare).
+// There are reserved path in the flare: "permissions.log", "flare-creationg.log" and "flare_manifest.json" (all at the
+// root of the flare).
 // Note as well that the flare does nothing to prevent files to be overwritten by different calls. It's up to the caller
 // to make sure the path used in the flare doesn't clash with other modules.
 type FlareBuilder interface {
@@ -155,4 +156,6 @@ type FlareArgs struct {
 	ProfileDuration      time.Duration // Add performance profiling data to the flare. It will collect a heap profile and a CPU profile for the amount of seconds passed to the flag, with a minimum of 30s
 	ProfileMutexFraction int           // Set the fraction of mutex contention events that are reported in the mutex profile
 	ProfileBlockingRate  int           // Set the fraction of goroutine blocking events that are reported in the blocking profile
+	OnlyProviders        []string      // Only run the flare providers with these names. All the providers are run when empty
+	ExcludedProviders    []string      // Skip the flare providers with these names
 }
@@ -9,11 +9,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"go.uber.org/fx"
@@ -38,6 +41,9 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/option"
 )
 
+// profilesProviderName is the name selecting the profiles passed when creating a flare
+const profilesProviderName = "profiles"
+
 // FlareBuilderFactory creates an instance of FlareBuilder
 type flareBuilderFactory func(localFlare bool, flareArgs types.FlareArgs) (types.FlareBuilder, error)
 
@@ -94,8 +100,8 @@ func newFlare(deps dependencies) provides {
 	)
 	f.providers = append(
 		f.providers,
-		types.NewFiller(f.collectLogsFiles),
-		types.NewFiller(f.collectConfigFiles),
+		types.NewNamedFiller("logs", f.collectLogsFiles),
+		types.NewNamedFiller("config", f.collectConfigFiles),
 	)
 
 	return provides{
@@ -141,7 +147,7 @@ func (f *flare) onAgentTaskEvent(taskType rcclienttypes.TaskType, task rcclientt
 		f.log.Infof("Unrecognized value passed via enable_streamlogs, creating flare without streamlogs enabled: %q", streamlogs)
 	}
 
-	filePath, err := f.CreateWithArgs(flareArgs, 0, nil)
+	filePath, err := f.CreateWithArgs(flareArgs, 0, nil, nil)
 	if err != nil {
 		return true, err
 	}
@@ -180,13 +186,18 @@ func (f *flare) createAndReturnFlarePath(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	flareArgs := types.FlareArgs{
+		OnlyProviders:     splitProviderNames(r.URL.Query().Get("only")),
+		ExcludedProviders: splitProviderNames(r.URL.Query().Get("exclude")),
+	}
+
 	// Reset the `server_timeout` deadline for this connection as creating a flare can take some time
 	conn := apiutils.GetConnection(r)
 	_ = conn.SetDeadline(time.Time{})
 
 	var filePath string
 	f.log.Infof("Making a flare")
-	filePath, err := f.Create(profile, providerTimeout, nil)
+	filePath, err := f.CreateWithArgs(flareArgs, providerTimeout, nil, profile)
 
 	if err != nil || filePath == "" {
 		if err != nil {
@@ -213,11 +224,11 @@ func (f *flare) Create(pdata types.ProfileData, providerTimeout time.Duration, i
 	return f.create(types.FlareArgs{}, providerTimeout, ipcError, pdata)
 }
 
-// Create creates a new flare and returns the path to the final archive file.
+// CreateWithArgs creates a new flare with the given arguments and returns the path to the final archive file.
 //
 // If providerTimeout is 0 or negative, the timeout from the configuration will be used.
-func (f *flare) CreateWithArgs(flareArgs types.FlareArgs, providerTimeout time.Duration, ipcError error) (string, error) {
-	return f.create(flareArgs, providerTimeout, ipcError, types.ProfileData{})
+func (f *flare) CreateWithArgs(flareArgs types.FlareArgs, providerTimeout time.Duration, ipcError error, pdata types.ProfileData) (string, error) {
+	return f.create(flareArgs, providerTimeout, ipcError, pdata)
 }
 
 func (f *flare) create(flareArgs types.FlareArgs, providerTimeout time.Duration, ipcError error, pdata types.ProfileData) (string, error) {
@@ -225,6 +236,10 @@ func (f *flare) create(flareArgs types.FlareArgs, providerTimeout time.Duration,
 		providerTimeout = f.config.GetDuration("flare_provider_timeout")
 	}
 
+	if err := f.checkProviderNames(flareArgs); err != nil {
+		return "", err
+	}
+
 	fb, err := fbFactory(f.params.local, flareArgs)
 	if err != nil {
 		return "", err
@@ -241,8 +256,12 @@ func (f *flare) create(flareArgs types.FlareArgs, providerTimeout time.Duration,
 		fb.AddFile("local", []byte(msg)) //nolint:errcheck
 	}
 
-	for name, data := range pdata {
-		fb.AddFileWithoutScrubbing(filepath.Join("profiles", name), data) //nolint:errcheck
+	if shouldRunProvider(profilesProviderName, flareArgs) {
+		for name, data := range pdata {
+			fb.AddFileWithoutScrubbing(filepath.Join("profiles", name), data) //nolint:errcheck
+		}
+	} else if len(pdata) > 0 {
+		_ = fb.Logf("Skipping the profiles, not selected for this flare")
 	}
 
 	f.runProviders(fb, providerTimeout)
@@ -254,10 +273,17 @@ func (f *flare) runProviders(fb types.FlareBuilder, providerTimeout time.Duratio
 	timer := time.NewTimer(providerTimeout)
 	defer timer.Stop()
 
+	flareArgs := fb.GetFlareArgs()
 	for _, p := range f.providers {
+		providerName := runtime.FuncForPC(reflect.ValueOf(p.Callback).Pointer()).Name()
+		if !shouldRunProvider(p.Name, flareArgs) {
+			f.log.Debugf("Skipping flare provider %s (%s), not selected for this flare", providerName, p.Name)
+			_ = fb.Logf("Skipping flare provider %s (%s), not selected for this flare", providerName, p.Name)
+			continue
+		}
+
 		timeout := max(providerTimeout, p.Timeout(fb))
 		timer.Reset(timeout)
-		providerName := runtime.FuncForPC(reflect.ValueOf(p.Callback).Pointer()).Name()
 		f.log.Infof("Running flare provider %s with timeout %s", providerName, timeout)
 		_ = fb.Logf("Running flare provider %s with timeout %s", providerName, timeout)
 
@@ -290,3 +316,46 @@ func (f *flare) runProviders(fb types.FlareBuilder, providerTimeout time.Duratio
 
 	f.log.Info("All flare providers have been run, creating archive...")
 }
+
+// checkProviderNames returns an error if a provider selected or excluded by the flare arguments doesn't exist
+func (f *flare) checkProviderNames(flareArgs types.FlareArgs) error {
+	known := map[string]struct{}{profilesProviderName: {}}
+	for _, p := range f.providers {
+		if p.Name != "" {
+			known[p.Name] = struct{}{}
+		}
+	}
+
+	var unknown []string
+	for _, name := range slices.Concat(flareArgs.OnlyProviders, flareArgs.ExcludedProviders) {
+		if _, ok := known[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	names := slices.Sorted(maps.Keys(known))
+	return fmt.Errorf("unknown flare providers: %s (available providers: %s)", strings.Join(unknown, ", "), strings.Join(names, ", "))
+}
+
+// shouldRunProvider returns true if the provider named name is selected by the flare arguments. Unnamed providers
+// only run when no provider is explicitly selected.
+func shouldRunProvider(name string, flareArgs types.FlareArgs) bool {
+	if slices.Contains(flareArgs.ExcludedProviders, name) {
+		return false
+	}
+	return len(flareArgs.OnlyProviders) == 0 || slices.Contains(flareArgs.OnlyProviders, name)
+}
+
+// splitProviderNames parses a comma separated list of provider names
+func splitProviderNames(names string) []string {
+	var providers []string
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			providers = append(providers, name)
+		}
+	}
+	return providers
+}
@@ -155,6 +155,52 @@ func TestRunProviders(t *testing.T) {
 	assert.False(t, secondDone.Load())
 }
 
+func TestCreateWithSelectedProviders(t *testing.T) {
+	defer setupMockBuilder(t)()
+
+	var ran []string
+	newProvider := func(name string) *types.FlareFiller {
+		return types.NewNamedFiller(name, func(_ types.FlareBuilder) error {
+			ran = append(ran, name)
+			return nil
+		})
+	}
+
+	flare := getFlare(t, map[string]interface{}{})
+	flare.providers = []*types.FlareFiller{
+		newProvider("logs"),
+		newProvider("config"),
+		newProvider("status"),
+		newProvider("config"),
+		types.NewFiller(func(_ types.FlareBuilder) error {
+			ran = append(ran, "unnamed")
+			return nil
+		}),
+	}
+
+	// the mocked builder can't save the archive
+	_, _ = flare.CreateWithArgs(types.FlareArgs{}, 0, nil, nil)
+	assert.Equal(t, []string{"logs", "config", "status", "config", "unnamed"}, ran)
+
+	ran = nil
+	_, _ = flare.CreateWithArgs(types.FlareArgs{OnlyProviders: []string{"config", "status"}}, 0, nil, nil)
+	assert.Equal(t, []string{"config", "status", "config"}, ran)
+
+	ran = nil
+	_, _ = flare.CreateWithArgs(types.FlareArgs{ExcludedProviders: []string{"config", "profiles"}}, 0, nil, nil)
+	assert.Equal(t, []string{"logs", "status", "unnamed"}, ran)
+
+	ran = nil
+	_, err := flare.CreateWithArgs(types.FlareArgs{OnlyProviders: []string{"logs", "foo"}, ExcludedProviders: []string{"bar"}}, 0, nil, nil)
+	assert.EqualError(t, err, "unknown flare providers: foo, bar (available providers: config, logs, profiles, status)")
+	assert.Empty(t, ran)
+}
+
+func TestSplitProviderNames(t *testing.T) {
+	assert.Nil(t, splitProviderNames(""))
+	assert.Equal(t, []string{"logs", "config"}, splitProviderNames("logs, config,"))
+}
+
 func TestAgentTaskFlareProfilingArgs(t *testing.T) {
 	defer setupMockBuilder(t)()
 
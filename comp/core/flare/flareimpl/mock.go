@@ -49,6 +49,11 @@ func (fc *MockFlare) Create(_ flaretypes.ProfileData, _ time.Duration, _ error)
 	return "a string", nil
 }
 
+// CreateWithArgs mocks the flare create with args function
+func (fc *MockFlare) CreateWithArgs(_ flaretypes.FlareArgs, _ time.Duration, _ error, _ flaretypes.ProfileData) (string, error) {
+	return "a string", nil
+}
+
 // Send mocks the flare send function
 func (fc *MockFlare) Send(_ string, _ string, _ string, _ helpers.FlareSource) (string, error) {
 	return "a string", nil
@@ -36,6 +36,7 @@ func newBuilder(root string, hostname string, localFlare bool, flareArgs types.F
 		permsInfos: permissionsInfos{},
 		isLocal:    localFlare,
 		flareArgs:  flareArgs,
+		scrubbed:   map[string]map[string]int{},
 	}
 
 	fb.flareDir = filepath.Join(fb.tmpDir, hostname)
@@ -45,6 +46,13 @@ func newBuilder(root string, hostname string, localFlare bool, flareArgs types.F
 
 	fb.scrubber = scrubber.New()
 	scrubber.AddDefaultReplacers(fb.scrubber)
+	fb.scrubber.SetOnMatch(func(repl scrubber.Replacer, count int) {
+		name := repl.Name
+		if name == "" {
+			name = "unnamed"
+		}
+		fb.scrubMatches[name] += count
+	})
 
 	// The default scrubber doesn't deal with api keys of other services, for
 	// example powerDNS which has an "api_key" field in its YAML configuration.
@@ -58,11 +66,20 @@ func newBuilder(root string, hostname string, localFlare bool, flareArgs types.F
 	// replacer for api_key.
 	otherAPIKeysRx := regexp.MustCompile(`api_key\s*:\s*[a-zA-Z0-9\\\/\^\]\[\(\){}!|%:;"~><=#@$_\-\+]{2,}`)
 	fb.scrubber.AddReplacer(scrubber.SingleLine, scrubber.Replacer{
+		Name:  "other_api_key",
 		Regex: otherAPIKeysRx,
 		ReplFunc: func(_ []byte) []byte {
 			return []byte("api_key: \"********\"")
 		},
 	})
+	// the S3 flare destination credentials are not covered by the default replacers
+	fb.scrubber.AddReplacer(scrubber.SingleLine, scrubber.Replacer{
+		Name:         "credentials",
+		Regex:        regexp.MustCompile(`(\s*secret_access_key\s*:).+`),
+		YAMLKeyRegex: regexp.MustCompile(`^secret_access_key$`),
+		Hints:        []string{"secret_access_key"},
+		Repl:         []byte(`$1 "********"`),
+	})
 
 	logPath, err := fb.PrepareFilePath("flare_creation.log")
 	if err != nil {
@@ -125,6 +142,11 @@ type builder struct {
 
 	// specialized scrubber for flare content
 	scrubber *scrubber.Scrubber
+	// scrubLock serializes the scrubbing, so that scrubMatches counts the matches of a single file
+	scrubLock    sync.Mutex
+	scrubMatches map[string]int
+	// scrubbed stores the number of values scrubbed by rule, for each file of the flare
+	scrubbed map[string]map[string]int
 
 	logFile *os.File
 }
@@ -157,6 +179,13 @@ func (fb *builder) Save() (string, error) {
 	defer fb.Unlock()
 	fb.isClosed = true
 
+	manifest, err := fb.buildManifest()
+	if err != nil {
+		log.Errorf("error building the flare manifest: %s", err)
+	} else if err := os.WriteFile(filepath.Join(fb.flareDir, ManifestFileName), manifest, filePerm); err != nil {
+		log.Errorf("error writing the flare manifest: %s", err)
+	}
+
 	archiveName := getArchiveName()
 	archiveTmpPath := filepath.Join(fb.tmpDir, archiveName)
 	archiveFinalPath := filepath.Join(os.TempDir(), archiveName)
@@ -164,7 +193,7 @@ func (fb *builder) Save() (string, error) {
 	// We first create the archive in our fb.tmpDir directory which is only readable by the current user (and
 	// SYSTEM/ADMIN on Windows). Then we retrict the archive permissions before moving it to the system temporary
 	// directory. This prevents other users from being able to read local flares.
-	err := archive.Zip([]string{fb.flareDir}, archiveTmpPath)
+	err = archive.Zip([]string{fb.flareDir}, archiveTmpPath)
 	if err != nil {
 		return "", err
 	}
@@ -223,12 +252,7 @@ func (fb *builder) addFile(shouldScrub bool, destFile string, content []byte) er
 		var err error
 
 		// We use the YAML scrubber when needed. This handles nested keys, list, maps and such.
-		if strings.Contains(destFile, ".yaml") {
-			content, err = fb.scrubber.ScrubYaml(content)
-		} else {
-			content, err = fb.scrubber.ScrubBytes(content)
-		}
-
+		content, err = fb.scrub(destFile, strings.Contains(destFile, ".yaml"), content)
 		if err != nil {
 			return fb.logError("error scrubbing content for '%s': %s", destFile, err)
 		}
@@ -252,6 +276,30 @@ func (fb *builder) addFile(shouldScrub bool, destFile string, content []byte) er
 	return nil
 }
 
+// scrub scrubs the content of a file and records the number of values scrubbed by rule for the manifest
+func (fb *builder) scrub(destFile string, isYaml bool, content []byte) ([]byte, error) {
+	fb.scrubLock.Lock()
+	defer fb.scrubLock.Unlock()
+
+	fb.scrubMatches = map[string]int{}
+	var err error
+	if isYaml {
+		content, err = fb.scrubber.ScrubYaml(content)
+	} else {
+		content, err = fb.scrubber.ScrubBytes(content)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fb.scrubMatches) > 0 {
+		fb.Lock()
+		fb.scrubbed[manifestKey(destFile)] = fb.scrubMatches
+		fb.Unlock()
+	}
+	return content, nil
+}
+
 func (fb *builder) AddFile(destFile string, content []byte) error {
 	return fb.addFile(true, destFile, content)
 }
@@ -284,11 +332,7 @@ func (fb *builder) copyFileTo(shouldScrub bool, srcFile string, destFile string)
 		var err error
 
 		// We use the YAML scrubber when needed. This handles nested keys, list, maps and such.
-		if strings.Contains(srcFile, ".yaml") || strings.Contains(destFile, ".yaml") {
-			content, err = fb.scrubber.ScrubYaml(content)
-		} else {
-			content, err = fb.scrubber.ScrubBytes(content)
-		}
+		content, err = fb.scrub(destFile, strings.Contains(srcFile, ".yaml") || strings.Contains(destFile, ".yaml"), content)
 		if err != nil {
 			return fb.logError("error scrubbing content for file '%s': %s", destFile, err)
 		}
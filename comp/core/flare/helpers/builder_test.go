@@ -103,6 +103,38 @@ func TestSave(t *testing.T) {
 	assert.FileExists(t, filepath.Join(tmpDir, hostname, "test/depth1/depth2/test4"))
 }
 
+func TestSaveManifest(t *testing.T) {
+	fb := getNewBuilder(t)
+
+	fb.AddFile("status.log", []byte("api_key=123456789006789009\npassword: foo"))
+	fb.AddFile("etc/datadog.yaml", []byte("api_key: 123456789006789009\ntoken: bar\nsite: datadoghq.com"))
+	fb.AddFileWithoutScrubbing("profiles/heap.pprof", []byte("password: foo"))
+
+	archivePath, err := fb.Save()
+	require.NoError(t, err)
+	defer os.RemoveAll(archivePath)
+
+	manifest, err := ReadFlareManifest(archivePath)
+	require.NoError(t, err)
+
+	files := map[string]ManifestFile{}
+	for _, f := range manifest.Files {
+		files[f.Path] = f
+	}
+	require.Contains(t, files, "status.log")
+	require.Contains(t, files, "etc/datadog.yaml")
+	require.Contains(t, files, "profiles/heap.pprof")
+	assert.Contains(t, files, "flare_creation.log")
+	assert.Contains(t, files, "permissions.log")
+	assert.NotContains(t, files, ManifestFileName)
+
+	assert.Equal(t, map[string]int{"api_key": 1, "password": 1}, files["status.log"].Scrubbed)
+	assert.Equal(t, map[string]int{"api_key": 1, "token": 1}, files["etc/datadog.yaml"].Scrubbed)
+	assert.Equal(t, 2, files["etc/datadog.yaml"].ScrubbedTotal())
+	assert.Empty(t, files["profiles/heap.pprof"].Scrubbed)
+	assert.Equal(t, int64(len("password: foo")), files["profiles/heap.pprof"].Size)
+}
+
 func TestAddFileFromFunc(t *testing.T) {
 	fb := getNewBuilder(t)
 	defer fb.clean()
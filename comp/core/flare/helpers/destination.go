@@ -0,0 +1,176 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package helpers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/util/scrubber"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+// sendToDestination sends a flare to the custom destination set in 'flare_destination' instead of Datadog.
+//
+// Two kinds of destinations are supported:
+//   - 'http://' and 'https://' URLs, to which the flare is posted the same way as to Datadog (see 'agent flare-receive')
+//   - 's3://bucket/prefix' URLs, to which the flare is uploaded as '<prefix>/<hostname>/<archive name>'
+func sendToDestination(cfg pkgconfigmodel.Reader, client *http.Client, destination, archivePath, caseID, email, hostname string, source FlareSource) (string, error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return "", fmt.Errorf("invalid flare_destination %q: %w", scrubber.ScrubLine(destination), err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return sendToHTTPDestination(cfg, client, strings.TrimSuffix(destination, "/"), archivePath, caseID, email, hostname, source)
+	case "s3":
+		if u.Host == "" {
+			return "", fmt.Errorf("invalid flare_destination %q: missing bucket name", destination)
+		}
+		return sendToS3Destination(cfg, client, u.Host, strings.Trim(u.Path, "/"), archivePath, caseID, email, hostname, source)
+	default:
+		return "", fmt.Errorf("invalid flare_destination %q: unsupported scheme %q, expected http, https or s3", scrubber.ScrubLine(destination), u.Scheme)
+	}
+}
+
+func sendToHTTPDestination(cfg pkgconfigmodel.Reader, client *http.Client, baseURL, archivePath, caseID, email, hostname string, source FlareSource) (string, error) {
+	headers := http.Header{}
+	if token := cfg.GetString("flare_destination_token"); token != "" {
+		headers.Set("Authorization", "Bearer "+token)
+	}
+
+	url, err := resolveFlarePOSTURL(mkURL(baseURL, caseID), client, headers)
+	if err != nil {
+		return "", err
+	}
+
+	r, err := readAndPostFlareFile(archivePath, caseID, email, hostname, url, source, client, headers)
+	if err != nil {
+		return "", err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode == http.StatusUnauthorized || r.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("HTTP %s: the flare destination rejected the request, make sure 'flare_destination_token' is valid", r.Status)
+	}
+	return analyzeResponse(r, "")
+}
+
+func sendToS3Destination(cfg pkgconfigmodel.Reader, client *http.Client, bucket, prefix, archivePath, caseID, email, hostname string, source FlareSource) (string, error) {
+	region := cfg.GetString("flare_destination_s3.region")
+	endpoint := strings.TrimSuffix(cfg.GetString("flare_destination_s3.endpoint"), "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+
+	key := strings.Trim(prefix+"/"+hostname+"/"+filepath.Base(archivePath), "/")
+	// path-style addressing is the one supported by every S3-compatible store
+	objectURL := endpoint + "/" + url.PathEscape(bucket) + "/" + escapeObjectKey(key)
+
+	payloadHash, err := sha256File(archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	request, err := http.NewRequest("PUT", objectURL, file)
+	if err != nil {
+		return "", err
+	}
+	request.ContentLength = stat.Size()
+	request.Header.Set("Content-Type", "application/octet-stream")
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	agentFullVersion, _ := version.Agent()
+	metadata := map[string]string{
+		"case-id":       caseID,
+		"email":         email,
+		"source":        source.sourceType,
+		"rc-task-uuid":  source.rcTaskUUID,
+		"agent-version": agentFullVersion.String(),
+		"hostname":      hostname,
+	}
+	for name, value := range metadata {
+		if value != "" {
+			request.Header.Set("X-Amz-Meta-"+name, value)
+		}
+	}
+
+	// without credentials the request is sent unsigned, for buckets accepting anonymous uploads
+	if accessKeyID := cfg.GetString("flare_destination_s3.access_key_id"); accessKeyID != "" {
+		credentials := aws.Credentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: cfg.GetString("flare_destination_s3.secret_access_key"),
+			SessionToken:    cfg.GetString("flare_destination_s3.session_token"),
+		}
+		if credentials.SessionToken != "" {
+			request.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+		}
+		// the object key is already escaped, S3 expects it not to be escaped a second time
+		signer := v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true })
+		if err := signer.SignHTTP(context.TODO(), credentials, request, payloadHash, "s3", region, time.Now()); err != nil {
+			return "", fmt.Errorf("could not sign the flare upload request: %w", err)
+		}
+	}
+
+	r, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		sample, _ := io.ReadAll(io.LimitReader(r.Body, 512))
+		return "", fmt.Errorf("HTTP %s: could not upload the flare to s3://%s/%s\nServer returned:\n%s", r.Status, bucket, key, sample)
+	}
+	return fmt.Sprintf("Your logs were successfully uploaded to s3://%s/%s", bucket, key), nil
+}
+
+// escapeObjectKey escapes each segment of an S3 object key
+func escapeObjectKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
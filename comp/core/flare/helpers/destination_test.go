@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package helpers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+)
+
+func TestSendToHTTPDestination(t *testing.T) {
+	var lastRequest *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("DD-API-KEY"))
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/flares/support/flare/12345" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == "POST" {
+			lastRequest = r
+			assert.NoError(t, r.ParseMultipartForm(1000000))
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"case_id": 12345}`)
+		}
+	}))
+	defer ts.Close()
+
+	cfg := config.NewMock(t)
+	cfg.SetWithoutSource("flare_destination", ts.URL+"/flares/")
+
+	t.Run("ok", func(t *testing.T) {
+		cfg.SetWithoutSource("flare_destination_token", "s3cr3t")
+
+		res, err := SendTo(cfg, "./test/blank.zip", "12345", "dev@datadoghq.com", "abcdef", "https://unused.example.com", FlareSource{})
+		require.NoError(t, err)
+		assert.Contains(t, res, "12345")
+		assert.Equal(t, "dev@datadoghq.com", lastRequest.FormValue("email"))
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		cfg.SetWithoutSource("flare_destination_token", "s3cr3t")
+		cfg.SetWithoutSource("flare_encryption.passphrase", "correct horse battery staple")
+		defer cfg.SetWithoutSource("flare_encryption.passphrase", "")
+
+		archivePath := filepath.Join(t.TempDir(), "datadog-agent-flare.zip")
+		require.NoError(t, os.WriteFile(archivePath, []byte("flare content"), 0600))
+
+		_, err := SendTo(cfg, archivePath, "12345", "dev@datadoghq.com", "abcdef", "https://unused.example.com", FlareSource{})
+		require.NoError(t, err)
+
+		// the encrypted archive is removed once sent, the plain one is kept
+		assert.FileExists(t, archivePath)
+		assert.NoFileExists(t, archivePath+EncryptedFlareExtension)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		cfg.SetWithoutSource("flare_destination_token", "wrong")
+
+		_, err := SendTo(cfg, "./test/blank.zip", "12345", "dev@datadoghq.com", "abcdef", "https://unused.example.com", FlareSource{})
+		assert.Error(t, err)
+	})
+}
+
+func TestSendToS3Destination(t *testing.T) {
+	var body []byte
+	var lastRequest *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	cfg := config.NewMock(t)
+	cfg.SetWithoutSource("flare_destination", "s3://flares/agents/")
+	cfg.SetWithoutSource("flare_destination_s3.endpoint", ts.URL)
+	cfg.SetWithoutSource("flare_destination_s3.region", "eu-west-3")
+	cfg.SetWithoutSource("flare_destination_s3.access_key_id", "AKIDEXAMPLE")
+	cfg.SetWithoutSource("flare_destination_s3.secret_access_key", "wJalrXUtnFEMI")
+
+	res, err := SendTo(cfg, "./test/blank.zip", "12345", "dev@datadoghq.com", "abcdef", "https://unused.example.com", NewLocalFlareSource())
+	require.NoError(t, err)
+	assert.Contains(t, res, "s3://flares/agents/")
+
+	require.NotNil(t, lastRequest)
+	assert.Equal(t, "PUT", lastRequest.Method)
+	assert.True(t, strings.HasPrefix(lastRequest.URL.Path, "/flares/agents/"))
+	assert.True(t, strings.HasSuffix(lastRequest.URL.Path, "/blank.zip"))
+	assert.Equal(t, "12345", lastRequest.Header.Get("X-Amz-Meta-Case-Id"))
+	assert.Equal(t, "local", lastRequest.Header.Get("X-Amz-Meta-Source"))
+	assert.True(t, strings.HasPrefix(lastRequest.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+	assert.Contains(t, lastRequest.Header.Get("Authorization"), "/eu-west-3/s3/aws4_request")
+
+	expected, err := os.ReadFile("./test/blank.zip")
+	require.NoError(t, err)
+	assert.Equal(t, expected, body)
+}
+
+func TestSendToInvalidDestination(t *testing.T) {
+	cfg := config.NewMock(t)
+
+	cfg.SetWithoutSource("flare_destination", "ftp://flares.example.com")
+	_, err := SendTo(cfg, "./test/blank.zip", "", "", "abcdef", "https://unused.example.com", FlareSource{})
+	assert.ErrorContains(t, err, "unsupported scheme")
+
+	cfg.SetWithoutSource("flare_destination", "s3:///prefix")
+	_, err = SendTo(cfg, "./test/blank.zip", "", "", "abcdef", "https://unused.example.com", FlareSource{})
+	assert.ErrorContains(t, err, "missing bucket name")
+}
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// EncryptedFlareExtension is appended to the name of encrypted flare archives
+const EncryptedFlareExtension = ".pgp"
+
+// encryptFlare encrypts the flare archive according to the 'flare_encryption' configuration. It returns the path of
+// the encrypted archive, or archivePath itself when encryption is disabled.
+//
+// Archives are encrypted in the OpenPGP format, so they can be decrypted offline with standard tools such as 'gpg'.
+func encryptFlare(cfg pkgconfigmodel.Reader, archivePath string) (string, error) {
+	passphrase := cfg.GetString("flare_encryption.passphrase")
+	publicKeyFile := cfg.GetString("flare_encryption.public_key_file")
+
+	switch {
+	case passphrase != "" && publicKeyFile != "":
+		return "", errors.New("'flare_encryption.passphrase' and 'flare_encryption.public_key_file' are mutually exclusive")
+	case passphrase != "":
+		return encryptFile(archivePath, func(w io.Writer, hints *openpgp.FileHints) (io.WriteCloser, error) {
+			return openpgp.SymmetricallyEncrypt(w, []byte(passphrase), hints, nil)
+		})
+	case publicKeyFile != "":
+		recipients, err := readPublicKeys(publicKeyFile)
+		if err != nil {
+			return "", err
+		}
+		return encryptFile(archivePath, func(w io.Writer, hints *openpgp.FileHints) (io.WriteCloser, error) {
+			return openpgp.Encrypt(w, recipients, nil, hints, nil)
+		})
+	default:
+		return archivePath, nil
+	}
+}
+
+// readPublicKeys reads an armored OpenPGP key ring
+func readPublicKeys(path string) (openpgp.EntityList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read flare encryption public key: %w", err)
+	}
+	defer f.Close()
+
+	keys, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flare encryption public key %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+func encryptFile(archivePath string, encrypt func(io.Writer, *openpgp.FileHints) (io.WriteCloser, error)) (string, error) {
+	in, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	encryptedPath := archivePath + EncryptedFlareExtension
+	out, err := os.OpenFile(encryptedPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	err = func() error {
+		defer out.Close()
+
+		plaintext, err := encrypt(out, &openpgp.FileHints{IsBinary: true, FileName: filepath.Base(archivePath)})
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(plaintext, in); err != nil {
+			plaintext.Close()
+			return err
+		}
+		return plaintext.Close()
+	}()
+	if err != nil {
+		os.Remove(encryptedPath)
+		return "", fmt.Errorf("could not encrypt flare: %w", err)
+	}
+	return encryptedPath, nil
+}
@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package helpers
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+)
+
+func writeTestArchive(t *testing.T) (string, []byte) {
+	content := []byte("not really a zip file")
+	archivePath := filepath.Join(t.TempDir(), "datadog-agent-flare.zip")
+	require.NoError(t, os.WriteFile(archivePath, content, 0600))
+	return archivePath, content
+}
+
+func decrypt(t *testing.T, path string, keyring openpgp.KeyRing, prompt openpgp.PromptFunction) []byte {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	md, err := openpgp.ReadMessage(f, keyring, prompt, nil)
+	require.NoError(t, err)
+	content, err := io.ReadAll(md.UnverifiedBody)
+	require.NoError(t, err)
+	return content
+}
+
+func TestEncryptFlareDisabled(t *testing.T) {
+	cfg := config.NewMock(t)
+	archivePath, _ := writeTestArchive(t)
+
+	path, err := encryptFlare(cfg, archivePath)
+	require.NoError(t, err)
+	assert.Equal(t, archivePath, path)
+}
+
+func TestEncryptFlarePassphrase(t *testing.T) {
+	cfg := config.NewMock(t)
+	cfg.SetWithoutSource("flare_encryption.passphrase", "correct horse battery staple")
+	archivePath, content := writeTestArchive(t)
+
+	path, err := encryptFlare(cfg, archivePath)
+	require.NoError(t, err)
+	assert.Equal(t, archivePath+EncryptedFlareExtension, path)
+
+	prompt := func(_ []openpgp.Key, symmetric bool) ([]byte, error) {
+		assert.True(t, symmetric)
+		return []byte("correct horse battery staple"), nil
+	}
+	assert.Equal(t, content, decrypt(t, path, nil, prompt))
+}
+
+func TestEncryptFlarePublicKey(t *testing.T) {
+	entity, err := openpgp.NewEntity("support", "", "support@example.com", nil)
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "recipients.asc")
+	f, err := os.Create(keyFile)
+	require.NoError(t, err)
+	w, err := armor.Encode(f, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	cfg := config.NewMock(t)
+	cfg.SetWithoutSource("flare_encryption.public_key_file", keyFile)
+	archivePath, content := writeTestArchive(t)
+
+	path, err := encryptFlare(cfg, archivePath)
+	require.NoError(t, err)
+	assert.Equal(t, content, decrypt(t, path, openpgp.EntityList{entity}, nil))
+}
+
+func TestEncryptFlareErrors(t *testing.T) {
+	archivePath, _ := writeTestArchive(t)
+
+	t.Run("both modes", func(t *testing.T) {
+		cfg := config.NewMock(t)
+		cfg.SetWithoutSource("flare_encryption.passphrase", "secret")
+		cfg.SetWithoutSource("flare_encryption.public_key_file", "/some/key.asc")
+
+		_, err := encryptFlare(cfg, archivePath)
+		assert.ErrorContains(t, err, "mutually exclusive")
+	})
+
+	t.Run("invalid public key", func(t *testing.T) {
+		keyFile := filepath.Join(t.TempDir(), "recipients.asc")
+		require.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0600))
+		cfg := config.NewMock(t)
+		cfg.SetWithoutSource("flare_encryption.public_key_file", keyFile)
+
+		_, err := encryptFlare(cfg, archivePath)
+		assert.ErrorContains(t, err, "invalid flare encryption public key")
+		assert.NoFileExists(t, archivePath+EncryptedFlareExtension)
+	})
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package helpers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// ManifestFileName is the name of the manifest at the root of a flare
+const ManifestFileName = "flare_manifest.json"
+
+// FlareManifest lists the files of a flare archive along with what was scrubbed from them
+type FlareManifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes a file of a flare archive
+type ManifestFile struct {
+	// Path is the path of the file, relative to the flare root
+	Path string `json:"path"`
+	// Size is the size of the file in bytes
+	Size int64 `json:"size"`
+	// Scrubbed is the number of values scrubbed from the file, by scrubbing rule
+	Scrubbed map[string]int `json:"scrubbed,omitempty"`
+}
+
+// ScrubbedTotal returns the number of values scrubbed from the file, all rules included
+func (f ManifestFile) ScrubbedTotal() int {
+	total := 0
+	for _, count := range f.Scrubbed {
+		total += count
+	}
+	return total
+}
+
+// manifestKey returns the key of a file of the flare in the manifest
+func manifestKey(destFile string) string {
+	return path.Clean(filepath.ToSlash(destFile))
+}
+
+// buildManifest lists the files of the flare directory. It must be called while holding the builder lock.
+func (fb *builder) buildManifest() ([]byte, error) {
+	manifest := FlareManifest{Files: []ManifestFile{}}
+	err := filepath.Walk(fb.flareDir, func(src string, f os.FileInfo, err error) error {
+		if err != nil || f.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(fb.flareDir, src)
+		if err != nil {
+			return nil
+		}
+		key := manifestKey(rel)
+		manifest.Files = append(manifest.Files, ManifestFile{
+			Path:     key,
+			Size:     f.Size(),
+			Scrubbed: fb.scrubbed[key],
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+	return json.MarshalIndent(manifest, "", "  ")
+}
+
+// ReadFlareManifest returns the manifest of a flare archive
+func ReadFlareManifest(archivePath string) (*FlareManifest, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		// the files of the flare are in a directory named after the host
+		if path.Base(f.Name) != ManifestFileName || path.Dir(path.Dir(f.Name)) != "." {
+			continue
+		}
+
+		content, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer content.Close()
+
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return nil, err
+		}
+		var manifest FlareManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("invalid flare manifest: %w", err)
+		}
+		return &manifest, nil
+	}
+	return nil, fmt.Errorf("no %s in %s", ManifestFileName, archivePath)
+}
@@ -113,7 +113,7 @@ func getFlareReader(multipartBoundary, archivePath, caseID, email, hostname stri
 	return bodyReader
 }
 
-func readAndPostFlareFile(archivePath, caseID, email, hostname, url string, source FlareSource, client *http.Client, apiKey string) (*http.Response, error) {
+func readAndPostFlareFile(archivePath, caseID, email, hostname, url string, source FlareSource, client *http.Client, headers http.Header) (*http.Response, error) {
 	// Having resolved the POST URL, we do not expect to see further redirects, so do not
 	// handle them.
 	client.CheckRedirect = func(_ *http.Request, _ []*http.Request) error {
@@ -124,7 +124,7 @@ func readAndPostFlareFile(archivePath, caseID, email, hostname, url string, sour
 	if err != nil {
 		return nil, err
 	}
-	request.Header.Add("DD-API-KEY", apiKey)
+	request.Header = headers.Clone()
 
 	// We need to set the Content-Type header here, but we still haven't created the writer
 	// to obtain it from. Here we create one which only purpose is to give us a proper
@@ -199,12 +199,12 @@ func analyzeResponse(r *http.Response, apiKey string) (string, error) {
 // Resolve a flare URL to the URL at which a POST should be made.  This uses a HEAD request
 // to follow any redirects, avoiding the problematic behavior of a POST that results in a
 // redirect (and often in an early termination of the connection).
-func resolveFlarePOSTURL(url string, client *http.Client, apiKey string) (string, error) {
+func resolveFlarePOSTURL(url string, client *http.Client, headers http.Header) (string, error) {
 	request, err := http.NewRequest("HEAD", url, nil)
 	if err != nil {
 		return "", err
 	}
-	request.Header.Add("DD-API-KEY", apiKey)
+	request.Header = headers.Clone()
 
 	r, err := client.Do(request)
 	if err != nil {
@@ -230,7 +230,8 @@ func mkURL(baseURL string, caseID string) string {
 	return url
 }
 
-// SendTo sends a flare file to the backend. This is part of the "helpers" package while all the code is moved to
+// SendTo sends a flare file to the backend, or to 'flare_destination' when it is set. The archive is encrypted first
+// when 'flare_encryption' is configured. This is part of the "helpers" package while all the code is moved to
 // components. When possible use the "Send" method of the "flare" component instead.
 func SendTo(cfg pkgconfigmodel.Reader, archivePath, caseID, email, apiKey, url string, source FlareSource) (string, error) {
 	hostname, err := hostnameUtil.Get(context.TODO())
@@ -238,8 +239,15 @@ func SendTo(cfg pkgconfigmodel.Reader, archivePath, caseID, email, apiKey, url s
 		hostname = "unknown"
 	}
 
-	apiKey = configUtils.SanitizeAPIKey(apiKey)
-	baseURL, _ := configUtils.AddAgentVersionToDomain(url, "flare")
+	encryptedPath, err := encryptFlare(cfg, archivePath)
+	if err != nil {
+		return "", err
+	}
+	if encryptedPath != archivePath {
+		// only the plain archive is kept once the flare is sent
+		defer os.Remove(encryptedPath)
+		archivePath = encryptedPath
+	}
 
 	transport := httputils.CreateHTTPTransport(cfg)
 	client := &http.Client{
@@ -247,14 +255,23 @@ func SendTo(cfg pkgconfigmodel.Reader, archivePath, caseID, email, apiKey, url s
 		Timeout:   httpTimeout,
 	}
 
+	if destination := cfg.GetString("flare_destination"); destination != "" {
+		return sendToDestination(cfg, client, destination, archivePath, caseID, email, hostname, source)
+	}
+
+	apiKey = configUtils.SanitizeAPIKey(apiKey)
+	baseURL, _ := configUtils.AddAgentVersionToDomain(url, "flare")
+	headers := http.Header{}
+	headers.Set("DD-API-KEY", apiKey)
+
 	url = mkURL(baseURL, caseID)
 
-	url, err = resolveFlarePOSTURL(url, client, apiKey)
+	url, err = resolveFlarePOSTURL(url, client, headers)
 	if err != nil {
 		return "", err
 	}
 
-	r, err := readAndPostFlareFile(archivePath, caseID, email, hostname, url, source, client, apiKey)
+	r, err := readAndPostFlareFile(archivePath, caseID, email, hostname, url, source, client, headers)
 	if err != nil {
 		return "", err
 	}
@@ -40,6 +40,9 @@ type FlareTimeout func(fb FlareBuilder) time.Duration
 type FlareFiller struct {
 	Callback FlareCallback
 	Timeout  FlareTimeout
+	// Name is used to select the providers run when creating a flare (ex: `agent flare --only logs`). Several
+	// providers can share the same name.
+	Name string
 }
 
 // Provider is provided by other components to register themselves to provide flare data.
@@ -55,11 +58,23 @@ func NewFiller(callback FlareCallback) *FlareFiller {
 	return &FlareFiller{Callback: callback, Timeout: func(FlareBuilder) time.Duration { return 0 }}
 }
 
+// NewNamedFiller wraps the callback with the default FlareProvider configuration and a name.
+func NewNamedFiller(name string, callback FlareCallback) *FlareFiller {
+	filler := NewFiller(callback)
+	filler.Name = name
+	return filler
+}
+
 // NewProvider returns a new Provider to be called when a flare is created
 func NewProvider(callback FlareCallback) Provider {
 	return Provider{FlareFiller: NewFiller(callback)}
 }
 
+// NewNamedProvider returns a new Provider, identified by name, to be called when a flare is created
+func NewNamedProvider(name string, callback FlareCallback) Provider {
+	return Provider{FlareFiller: NewNamedFiller(name, callback)}
+}
+
 // NewProviderWithTimeout returns a new Provider to be called when a flare is created
 func NewProviderWithTimeout(callback FlareCallback, timeout FlareTimeout) Provider {
 	return Provider{
@@ -69,3 +84,10 @@ func NewProviderWithTimeout(callback FlareCallback, timeout FlareTimeout) Provid
 		},
 	}
 }
+
+// NewNamedProviderWithTimeout returns a new Provider, identified by name, to be called when a flare is created
+func NewNamedProviderWithTimeout(name string, callback FlareCallback, timeout FlareTimeout) Provider {
+	provider := NewProviderWithTimeout(callback, timeout)
+	provider.FlareFiller.Name = name
+	return provider
+}
@@ -47,7 +47,7 @@ func fillFlare(fb flaretypes.FlareBuilder) error {
 // NewComponent creates a new lsof component
 func NewComponent(Requires) (Provides, error) {
 	provides := Provides{
-		FlareProvider: flaretypes.NewProvider(fillFlare),
+		FlareProvider: flaretypes.NewNamedProvider("lsof", fillFlare),
 	}
 	return provides, nil
 }
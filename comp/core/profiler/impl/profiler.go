@@ -332,6 +332,6 @@ func NewComponent(req Requires) (Provides, error) {
 	}
 	return Provides{
 		Comp:          p,
-		FlareProvider: flaretypes.NewProviderWithTimeout(p.fillFlare, p.timeout),
+		FlareProvider: flaretypes.NewNamedProviderWithTimeout("profiles", p.fillFlare, p.timeout),
 	}, nil
 }
@@ -53,7 +53,7 @@ func NewComponent(reqs Requires) Provides {
 
 	return Provides{
 		Comp:          ra,
-		FlareProvider: flaretypes.NewProvider(ra.fillFlare),
+		FlareProvider: flaretypes.NewNamedProvider("remote-agents", ra.fillFlare),
 		Status:        status.NewInformationProvider(remoteagentregistryStatus.GetProvider(ra)),
 	}
 }
@@ -3,7 +3,7 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
 
-// Package secrets decodes secret values by invoking the configured executable command
+// Package secrets decodes secret values by invoking the configured executable command or built-in backends
 package secrets
 
 import (
@@ -23,6 +23,12 @@ type ConfigParams struct {
 	RemoveLinebreak  bool
 	RunPath          string
 	AuditFileMaxSize int
+	// Backends enables the built-in secret backends, by backend type ("file", "directory", "vault"
+	// or "kubernetes"), with their settings. Handles prefixed by the type of an enabled backend, such
+	// as 'ENC[vault:secret/data/db#password]', are resolved by it instead of the Command.
+	Backends map[string]interface{}
+	// CacheTTL is the number of seconds resolved secrets are cached for, 0 caching them until refreshed
+	CacheTTL int
 }
 
 // Component is the component type.
@@ -0,0 +1,208 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package secretsimpl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Types of the built-in secret backends, used as prefix of their handles.
+// Example: 'ENC[vault:secret/data/db#password]' is resolved by the vault backend.
+const (
+	fileBackendType       = "file"
+	directoryBackendType  = "directory"
+	vaultBackendType      = "vault"
+	kubernetesBackendType = "kubernetes"
+)
+
+// builtinBackend resolves secrets in-process, without calling the secret_backend_command
+type builtinBackend interface {
+	// fetch returns the value of the secret referenced by ref, the part of the handle following the backend type
+	fetch(ctx context.Context, ref string) (string, error)
+	// settings returns the configuration of the backend to display in debug information. It must not contain
+	// any credentials.
+	settings() map[string]string
+}
+
+// newBuiltinBackends builds the built-in backends enabled in the configuration, which is a map of
+// backend types to their settings.
+func newBuiltinBackends(config map[string]interface{}) (map[string]builtinBackend, error) {
+	backends := make(map[string]builtinBackend, len(config))
+	for backendType, rawSettings := range config {
+		var (
+			backend builtinBackend
+			err     error
+		)
+		switch backendType {
+		case fileBackendType:
+			var settings fileBackendSettings
+			if err = decodeBackendSettings(rawSettings, &settings); err == nil {
+				backend, err = newFileBackend(settings)
+			}
+		case directoryBackendType:
+			var settings directoryBackendSettings
+			if err = decodeBackendSettings(rawSettings, &settings); err == nil {
+				backend, err = newDirectoryBackend(settings)
+			}
+		case vaultBackendType:
+			var settings vaultBackendSettings
+			if err = decodeBackendSettings(rawSettings, &settings); err == nil {
+				backend, err = newVaultBackend(settings)
+			}
+		case kubernetesBackendType:
+			var settings kubernetesBackendSettings
+			if err = decodeBackendSettings(rawSettings, &settings); err == nil {
+				backend, err = newKubernetesBackend(settings)
+			}
+		default:
+			err = fmt.Errorf("unknown secret backend type, expected one of %s, %s, %s or %s",
+				fileBackendType, directoryBackendType, vaultBackendType, kubernetesBackendType)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid '%s' secret backend: %s", backendType, err)
+		}
+		backends[backendType] = backend
+	}
+	return backends, nil
+}
+
+// decodeBackendSettings decodes the settings of a backend, as read from the configuration, into
+// the given struct by going through YAML.
+func decodeBackendSettings(raw interface{}, settings interface{}) error {
+	if raw == nil {
+		return nil
+	}
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return yaml.UnmarshalStrict(data, settings)
+}
+
+// splitBuiltinHandle returns the backend type and the reference of a handle resolved by a built-in backend.
+// Handles of backends that are not enabled are left to the secret_backend_command.
+func (r *secretResolver) splitBuiltinHandle(handle string) (string, string, bool) {
+	backendType, ref, ok := strings.Cut(handle, ":")
+	if !ok {
+		return "", "", false
+	}
+	if _, ok := r.builtinBackends[backendType]; !ok {
+		return "", "", false
+	}
+	return backendType, ref, true
+}
+
+// splitFieldRef splits a reference of the form 'path#field'. The field is empty when not specified.
+func splitFieldRef(ref string) (string, string) {
+	path, field, _ := strings.Cut(ref, "#")
+	return path, field
+}
+
+// fetchBuiltinSecrets resolves handles with the built-in backends
+func (r *secretResolver) fetchBuiltinSecrets(handles []string) (map[string]string, error) {
+	res := make(map[string]string, len(handles))
+	for _, handle := range handles {
+		backendType, ref, _ := r.splitBuiltinHandle(handle)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.backendTimeout)*time.Second)
+		start := time.Now()
+		value, err := r.builtinBackends[backendType].fetch(ctx, ref)
+		elapsed := time.Since(start)
+		cancel()
+
+		if err != nil {
+			r.tlmSecretBackendElapsed.Add(float64(elapsed.Milliseconds()), backendType, "error")
+			r.tlmSecretResolveError.Inc("error", handle)
+			return nil, fmt.Errorf("an error occurred while resolving '%s': %s", handle, err)
+		}
+		r.tlmSecretBackendElapsed.Add(float64(elapsed.Milliseconds()), backendType, "0")
+		log.Debugf("secret '%s' resolved by the %s backend in %s", handle, backendType, elapsed)
+
+		if r.removeTrailingLinebreak {
+			value = strings.TrimRight(value, "\r\n")
+		}
+		if value == "" {
+			r.tlmSecretResolveError.Inc("empty", handle)
+			return nil, fmt.Errorf("resolved secret for '%s' is empty", handle)
+		}
+		res[handle] = value
+	}
+	return res, nil
+}
+
+// fetchSecrets resolves handles with the built-in backends they reference, and the remaining ones
+// with the secret_backend_command.
+func (r *secretResolver) fetchSecrets(handles []string) (map[string]string, error) {
+	if r.fetchHookFunc != nil {
+		// hook used only for tests
+		return r.fetchHookFunc(handles)
+	}
+
+	var builtinHandles, commandHandles []string
+	for _, handle := range handles {
+		if _, _, ok := r.splitBuiltinHandle(handle); ok {
+			builtinHandles = append(builtinHandles, handle)
+		} else {
+			commandHandles = append(commandHandles, handle)
+		}
+	}
+
+	res := make(map[string]string, len(handles))
+	if len(commandHandles) != 0 {
+		if r.backendCommand == "" {
+			return nil, fmt.Errorf("no secret_backend_command set to resolve '%s'", strings.Join(commandHandles, "', '"))
+		}
+		commandSecrets, err := r.fetchSecret(commandHandles)
+		if err != nil {
+			return nil, err
+		}
+		for handle, value := range commandSecrets {
+			res[handle] = value
+		}
+	}
+	if len(builtinHandles) != 0 {
+		builtinSecrets, err := r.fetchBuiltinSecrets(builtinHandles)
+		if err != nil {
+			return nil, err
+		}
+		for handle, value := range builtinSecrets {
+			res[handle] = value
+		}
+	}
+	return res, nil
+}
+
+type builtinBackendInfo struct {
+	Type     string
+	Settings [][]string
+}
+
+// builtinBackendsInfo returns the configuration of the built-in backends, sorted by type
+func (r *secretResolver) builtinBackendsInfo() []builtinBackendInfo {
+	infos := make([]builtinBackendInfo, 0, len(r.builtinBackends))
+	for backendType, backend := range r.builtinBackends {
+		info := builtinBackendInfo{Type: backendType}
+		for name, value := range backend.settings() {
+			info.Settings = append(info.Settings, []string{name, value})
+		}
+		sort.Slice(info.Settings, func(i, j int) bool {
+			return info.Settings[i][0] < info.Settings[j][0]
+		})
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Type < infos[j].Type
+	})
+	return infos
+}
@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package secretsimpl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+type fileBackendSettings struct {
+	// Path of a JSON or YAML document holding the secrets
+	Path string `yaml:"path"`
+}
+
+// fileBackend resolves handles of the form 'file:key' or 'file:key#field' from a JSON or YAML document
+type fileBackend struct {
+	path string
+}
+
+func newFileBackend(settings fileBackendSettings) (*fileBackend, error) {
+	if settings.Path == "" {
+		return nil, errors.New("missing 'path' setting")
+	}
+	return &fileBackend{path: settings.Path}, nil
+}
+
+func (b *fileBackend) fetch(_ context.Context, ref string) (string, error) {
+	key, field := splitFieldRef(ref)
+	if key == "" {
+		return "", errors.New("missing key")
+	}
+	// the file is read every time, so that refreshes pick up its changes
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return "", err
+	}
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return "", fmt.Errorf("could not parse '%s': %s", b.path, err)
+	}
+	value, ok := document[key]
+	if !ok {
+		return "", fmt.Errorf("key '%s' not found in '%s'", key, b.path)
+	}
+	return secretValue(value, field)
+}
+
+func (b *fileBackend) settings() map[string]string {
+	return map[string]string{"path": b.path}
+}
+
+type directoryBackendSettings struct {
+	// Path of a directory holding one secret per file, such as mounted Docker or Kubernetes secrets
+	Path string `yaml:"path"`
+}
+
+// directoryBackend resolves handles of the form 'directory:name' with the content of the file
+// of that name, or 'directory:name#field' with a field of the JSON or YAML document it holds.
+type directoryBackend struct {
+	path string
+}
+
+func newDirectoryBackend(settings directoryBackendSettings) (*directoryBackend, error) {
+	if settings.Path == "" {
+		return nil, errors.New("missing 'path' setting")
+	}
+	return &directoryBackend{path: settings.Path}, nil
+}
+
+func (b *directoryBackend) fetch(_ context.Context, ref string) (string, error) {
+	name, field := splitFieldRef(ref)
+	// secrets can only be read from the directory
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("'%s' is not a file name relative to '%s'", name, b.path)
+	}
+	data, err := os.ReadFile(filepath.Join(b.path, name))
+	if err != nil {
+		return "", err
+	}
+	if field == "" {
+		// mounted secrets are often written with a trailing line break
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return "", fmt.Errorf("could not parse '%s': %s", name, err)
+	}
+	return secretValue(document, field)
+}
+
+func (b *directoryBackend) settings() map[string]string {
+	return map[string]string{"path": b.path}
+}
+
+// secretValue returns value, or its given field when it is not empty, as a string
+func secretValue(value interface{}, field string) (string, error) {
+	if field != "" {
+		var fieldValue interface{}
+		var ok bool
+		switch v := value.(type) {
+		case map[string]interface{}:
+			fieldValue, ok = v[field]
+		case map[interface{}]interface{}:
+			fieldValue, ok = v[field]
+		default:
+			return "", fmt.Errorf("cannot read field '%s' of a value that is not an object", field)
+		}
+		if !ok {
+			return "", fmt.Errorf("field '%s' not found", field)
+		}
+		value = fieldValue
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int, int64, uint64, float64, bool:
+		return fmt.Sprint(v), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("value of type %T is not a string", value)
+	}
+}
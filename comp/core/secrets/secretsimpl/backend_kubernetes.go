@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package secretsimpl
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// kubernetesResponseMaxSize bounds the size of the Secrets read from the API server, which are limited to 1MiB
+	kubernetesResponseMaxSize = 2 * 1024 * 1024
+)
+
+type kubernetesBackendSettings struct {
+	// APIServer is the URL of the Kubernetes API server, defaults to the in-cluster address
+	APIServer string `yaml:"api_server"`
+	// Namespace of the Secrets referenced without namespace, defaults to the namespace of the Agent pod
+	Namespace string `yaml:"namespace"`
+	// TokenFile and CAFile default to the files of the service account of the Agent pod
+	TokenFile string `yaml:"token_file"`
+	CAFile    string `yaml:"ca_file"`
+}
+
+// kubernetesBackend resolves handles of the form 'kubernetes:[namespace/]name#key' with the keys of
+// Kubernetes Secrets, read from the API server with the service account of the Agent.
+type kubernetesBackend struct {
+	cfg    kubernetesBackendSettings
+	client *http.Client
+}
+
+func newKubernetesBackend(settings kubernetesBackendSettings) (*kubernetesBackend, error) {
+	if settings.APIServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("missing 'api_server' setting outside of a Kubernetes cluster")
+		}
+		settings.APIServer = "https://" + net.JoinHostPort(host, port)
+	}
+	settings.APIServer = strings.TrimRight(settings.APIServer, "/")
+	if settings.TokenFile == "" {
+		settings.TokenFile = kubernetesServiceAccountDir + "/token"
+	}
+	if settings.CAFile == "" {
+		settings.CAFile = kubernetesServiceAccountDir + "/ca.crt"
+	}
+	if settings.Namespace == "" {
+		if namespace, err := os.ReadFile(kubernetesServiceAccountDir + "/namespace"); err == nil {
+			settings.Namespace = strings.TrimSpace(string(namespace))
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if pem, err := os.ReadFile(settings.CAFile); err == nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in '%s'", settings.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return &kubernetesBackend{
+		cfg:    settings,
+		client: &http.Client{Transport: transport},
+	}, nil
+}
+
+func (b *kubernetesBackend) fetch(ctx context.Context, ref string) (string, error) {
+	path, key := splitFieldRef(ref)
+	if key == "" {
+		return "", errors.New("missing key, expected a reference of the form '[namespace/]name#key'")
+	}
+	namespace, name, ok := strings.Cut(path, "/")
+	if !ok {
+		namespace, name = b.cfg.Namespace, path
+	}
+	if namespace == "" || name == "" {
+		return "", fmt.Errorf("invalid Secret reference '%s', expected '[namespace/]name'", path)
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v1/namespaces/%s/secrets/%s", b.cfg.APIServer, url.PathEscape(namespace), url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	// the token is read every time since projected service account tokens are rotated
+	token, err := os.ReadFile(b.cfg.TokenFile)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, kubernetesResponseMaxSize))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var status struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &status) == nil && status.Message != "" {
+			return "", fmt.Errorf("could not get Secret %s/%s: %s", namespace, name, status.Message)
+		}
+		return "", fmt.Errorf("could not get Secret %s/%s: API server responded with status %d", namespace, name, resp.StatusCode)
+	}
+
+	var secret struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(data, &secret); err != nil {
+		return "", fmt.Errorf("could not parse Secret %s/%s: %s", namespace, name, err)
+	}
+	encoded, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key '%s' not found in Secret %s/%s", key, namespace, name)
+	}
+	value, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("could not decode key '%s' of Secret %s/%s: %s", key, namespace, name, err)
+	}
+	return string(value), nil
+}
+
+func (b *kubernetesBackend) settings() map[string]string {
+	return map[string]string{
+		"api_server": b.cfg.APIServer,
+		"namespace":  b.cfg.Namespace,
+		"token_file": b.cfg.TokenFile,
+		"ca_file":    b.cfg.CAFile,
+	}
+}
@@ -0,0 +1,334 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package secretsimpl
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	"github.com/DataDog/datadog-agent/comp/core/telemetry"
+	nooptelemetry "github.com/DataDog/datadog-agent/comp/core/telemetry/noopsimpl"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestNewBuiltinBackends(t *testing.T) {
+	backends, err := newBuiltinBackends(map[string]interface{}{
+		"file":      map[string]interface{}{"path": "/etc/secrets.yaml"},
+		"directory": map[interface{}]interface{}{"path": "/run/secrets"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, backends, 2)
+	assert.IsType(t, &fileBackend{}, backends[fileBackendType])
+	assert.IsType(t, &directoryBackend{}, backends[directoryBackendType])
+
+	_, err = newBuiltinBackends(map[string]interface{}{"aws": nil})
+	assert.ErrorContains(t, err, "invalid 'aws' secret backend: unknown secret backend type")
+
+	_, err = newBuiltinBackends(map[string]interface{}{"file": map[string]interface{}{"pth": "/etc/secrets.yaml"}})
+	assert.ErrorContains(t, err, "invalid 'file' secret backend")
+
+	_, err = newBuiltinBackends(map[string]interface{}{"vault": map[string]interface{}{"address": "http://vault:8200", "auth_method": "ldap"}})
+	assert.ErrorContains(t, err, "unknown auth_method 'ldap'")
+}
+
+func TestFileBackend(t *testing.T) {
+	path := writeTestFile(t, t.TempDir(), "secrets.json", `{"api_key": "abcdef", "port": 5432, "db": {"user": "datadog", "password": "p4ss"}}`)
+	backend, err := newFileBackend(fileBackendSettings{Path: path})
+	require.NoError(t, err)
+
+	tests := []struct {
+		ref      string
+		expected string
+		err      string
+	}{
+		{ref: "api_key", expected: "abcdef"},
+		{ref: "port", expected: "5432"},
+		{ref: "db#password", expected: "p4ss"},
+		{ref: "db", err: "is not a string"},
+		{ref: "db#missing", err: "field 'missing' not found"},
+		{ref: "api_key#field", err: "not an object"},
+		{ref: "missing", err: "key 'missing' not found"},
+	}
+	for _, test := range tests {
+		t.Run(test.ref, func(t *testing.T) {
+			value, err := backend.fetch(context.Background(), test.ref)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, value)
+		})
+	}
+}
+
+func TestDirectoryBackend(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "api_key", "abcdef\n")
+	writeTestFile(t, dir, "db.yaml", "user: datadog\npassword: p4ss\n")
+	writeTestFile(t, filepath.Dir(dir), "outside", "secret")
+	backend, err := newDirectoryBackend(directoryBackendSettings{Path: dir})
+	require.NoError(t, err)
+
+	value, err := backend.fetch(context.Background(), "api_key")
+	require.NoError(t, err)
+	assert.Equal(t, "abcdef", value)
+
+	value, err = backend.fetch(context.Background(), "db.yaml#password")
+	require.NoError(t, err)
+	assert.Equal(t, "p4ss", value)
+
+	_, err = backend.fetch(context.Background(), "../outside")
+	assert.ErrorContains(t, err, "is not a file name relative to")
+	_, err = backend.fetch(context.Background(), "missing")
+	assert.Error(t, err)
+}
+
+func newTestVault(t *testing.T, token string) (*httptest.Server, *int) {
+	logins := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/approle/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["role_id"] != "role" || body["secret_id"] != "s3cr3t" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors": ["invalid role or secret ID"]}`))
+			return
+		}
+		logins++
+		w.Write([]byte(`{"auth": {"client_token": "` + token + `", "lease_duration": 3600}}`))
+	})
+	secretsHandler := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Vault-Token") != token {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"errors": ["permission denied"]}`))
+				return
+			}
+			w.Write([]byte(body))
+		}
+	}
+	mux.HandleFunc("/v1/kv/db", secretsHandler(`{"data": {"password": "v1-pass"}}`))
+	mux.HandleFunc("/v1/secret/data/db", secretsHandler(`{"data": {"data": {"password": "v2-pass", "value": "v2-value"}, "metadata": {"version": 3}}}`))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &logins
+}
+
+func TestVaultBackendToken(t *testing.T) {
+	server, _ := newTestVault(t, "root-token")
+	tokenFile := writeTestFile(t, t.TempDir(), "token", "root-token\n")
+	backend, err := newVaultBackend(vaultBackendSettings{Address: server.URL + "/", TokenFile: tokenFile})
+	require.NoError(t, err)
+
+	value, err := backend.fetch(context.Background(), "kv/db#password")
+	require.NoError(t, err)
+	assert.Equal(t, "v1-pass", value)
+
+	value, err = backend.fetch(context.Background(), "secret/data/db#password")
+	require.NoError(t, err)
+	assert.Equal(t, "v2-pass", value)
+
+	value, err = backend.fetch(context.Background(), "secret/data/db")
+	require.NoError(t, err)
+	assert.Equal(t, "v2-value", value)
+
+	_, err = backend.fetch(context.Background(), "secret/data/missing#password")
+	assert.ErrorContains(t, err, "vault responded with status 404")
+
+	backend, err = newVaultBackend(vaultBackendSettings{Address: server.URL, Token: "wrong-token"})
+	require.NoError(t, err)
+	_, err = backend.fetch(context.Background(), "kv/db#password")
+	assert.ErrorContains(t, err, "vault responded with status 403: permission denied")
+}
+
+func TestVaultBackendAppRole(t *testing.T) {
+	server, logins := newTestVault(t, "approle-token")
+	backend, err := newVaultBackend(vaultBackendSettings{Address: server.URL, AuthMethod: "approle", RoleID: "role", SecretID: "s3cr3t"})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		value, err := backend.fetch(context.Background(), "secret/data/db#password")
+		require.NoError(t, err)
+		assert.Equal(t, "v2-pass", value)
+	}
+	// the token is reused until it expires
+	assert.Equal(t, 1, *logins)
+
+	// a revoked token is replaced
+	backend.token = "revoked-token"
+	_, err = backend.fetch(context.Background(), "secret/data/db#password")
+	require.NoError(t, err)
+	assert.Equal(t, 2, *logins)
+
+	backend, err = newVaultBackend(vaultBackendSettings{Address: server.URL, AuthMethod: "approle", RoleID: "role", SecretID: "wrong"})
+	require.NoError(t, err)
+	_, err = backend.fetch(context.Background(), "secret/data/db#password")
+	assert.ErrorContains(t, err, "could not log in with the approle auth method: vault responded with status 400: invalid role or secret ID")
+}
+
+func TestKubernetesBackend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sa-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/namespaces/datadog/secrets/db", "/api/v1/namespaces/other/secrets/db":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"password": base64.StdEncoding.EncodeToString([]byte("k8s-pass"))},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind": "Status", "message": "secrets \"missing\" not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	tokenFile := writeTestFile(t, t.TempDir(), "token", "sa-token")
+	backend, err := newKubernetesBackend(kubernetesBackendSettings{
+		APIServer: server.URL,
+		Namespace: "datadog",
+		TokenFile: tokenFile,
+		CAFile:    filepath.Join(t.TempDir(), "missing.crt"),
+	})
+	require.NoError(t, err)
+
+	value, err := backend.fetch(context.Background(), "db#password")
+	require.NoError(t, err)
+	assert.Equal(t, "k8s-pass", value)
+
+	value, err = backend.fetch(context.Background(), "other/db#password")
+	require.NoError(t, err)
+	assert.Equal(t, "k8s-pass", value)
+
+	_, err = backend.fetch(context.Background(), "db#user")
+	assert.ErrorContains(t, err, "key 'user' not found in Secret datadog/db")
+	_, err = backend.fetch(context.Background(), "missing#password")
+	assert.ErrorContains(t, err, `could not get Secret datadog/missing: secrets "missing" not found`)
+	_, err = backend.fetch(context.Background(), "db")
+	assert.ErrorContains(t, err, "missing key")
+}
+
+func TestResolveWithBuiltinBackends(t *testing.T) {
+	tel := fxutil.Test[telemetry.Component](t, nooptelemetry.Module())
+	resolver := newEnabledSecretResolver(tel)
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "secrets.yaml", "db:\n  password: file-pass\n")
+	writeTestFile(t, dir, "api_key", "dir-key\n")
+	resolver.Configure(secrets.ConfigParams{
+		Command: "some_command",
+		Backends: map[string]interface{}{
+			"file":      map[string]interface{}{"path": path},
+			"directory": map[string]interface{}{"path": dir},
+		},
+	})
+
+	var commandHandles []string
+	resolver.commandHookFunc = func(payload string) ([]byte, error) {
+		var input struct {
+			Secrets []string `json:"secrets"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(payload), &input))
+		commandHandles = append(commandHandles, input.Secrets...)
+		return []byte(`{"other:pass": {"value": "command-pass"}}`), nil
+	}
+
+	resolved, err := resolver.Resolve([]byte(`
+password: ENC[file:db#password]
+api_key: ENC[directory:api_key]
+other: ENC[other:pass]
+`), "test")
+	require.NoError(t, err)
+	assert.Equal(t, "api_key: dir-key\nother: command-pass\npassword: file-pass\n", string(resolved))
+	// handles of backends that aren't enabled are still resolved by the command
+	assert.Equal(t, []string{"other:pass"}, commandHandles)
+
+	var buffer bytes.Buffer
+	resolver.GetDebugInfo(&buffer)
+	assert.Contains(t, buffer.String(), "=== Built-in secret backends ===\n- 'directory':\n\tpath: "+dir+"\n- 'file':\n\tpath: "+path+"\n\n=== Secrets stats ===\nNumber of secrets resolved: 3\n")
+}
+
+func TestResolveBuiltinBackendsWithoutCommand(t *testing.T) {
+	tel := fxutil.Test[telemetry.Component](t, nooptelemetry.Module())
+	resolver := newEnabledSecretResolver(tel)
+	resolver.Configure(secrets.ConfigParams{
+		Backends: map[string]interface{}{"directory": map[string]interface{}{"path": t.TempDir()}},
+	})
+
+	_, err := resolver.Resolve([]byte("password: ENC[pass1]\n"), "test")
+	assert.EqualError(t, err, "no secret_backend_command set to resolve 'pass1'")
+}
+
+func TestResolveInvalidBuiltinBackends(t *testing.T) {
+	tel := fxutil.Test[telemetry.Component](t, nooptelemetry.Module())
+	resolver := newEnabledSecretResolver(tel)
+	resolver.Configure(secrets.ConfigParams{
+		Backends: map[string]interface{}{"file": nil},
+	})
+
+	_, err := resolver.Resolve([]byte("password: ENC[file:pass1]\n"), "test")
+	assert.EqualError(t, err, "invalid built-in secret backends configuration: invalid 'file' secret backend: missing 'path' setting")
+
+	var buffer bytes.Buffer
+	resolver.GetDebugInfo(&buffer)
+	assert.Contains(t, buffer.String(), "=== Built-in secret backends ===\nError: invalid 'file' secret backend: missing 'path' setting\n")
+}
+
+func TestResolveCacheTTL(t *testing.T) {
+	tel := fxutil.Test[telemetry.Component](t, nooptelemetry.Module())
+	resolver := newEnabledSecretResolver(tel)
+	clk := clock.NewMock()
+	resolver.clock = clk
+	dir := t.TempDir()
+	writeTestFile(t, dir, "password", "pass1")
+	resolver.Configure(secrets.ConfigParams{
+		Backends: map[string]interface{}{"directory": map[string]interface{}{"path": dir}},
+		CacheTTL: 60,
+	})
+
+	var changes []any
+	resolver.SubscribeToChanges(func(_, _ string, _ []string, _, newValue any) {
+		changes = append(changes, newValue)
+	})
+	conf := []byte("password: ENC[directory:password]\n")
+
+	resolved, err := resolver.Resolve(conf, "test")
+	require.NoError(t, err)
+	assert.Equal(t, "password: pass1\n", string(resolved))
+
+	// the cached value is used until it expires
+	writeTestFile(t, dir, "password", "pass2")
+	clk.Add(59 * time.Second)
+	resolved, err = resolver.Resolve(conf, "test")
+	require.NoError(t, err)
+	assert.Equal(t, "password: pass1\n", string(resolved))
+
+	clk.Add(time.Second)
+	resolved, err = resolver.Resolve(conf, "test")
+	require.NoError(t, err)
+	assert.Equal(t, "password: pass2\n", string(resolved))
+	assert.Equal(t, []any{"pass1", "pass1", "pass2"}, changes)
+}
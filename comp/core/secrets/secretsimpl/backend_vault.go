@@ -0,0 +1,296 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package secretsimpl
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	vaultAuthToken   = "token"
+	vaultAuthAppRole = "approle"
+
+	// vaultResponseMaxSize bounds the size of the responses read from Vault
+	vaultResponseMaxSize = 1024 * 1024
+)
+
+type vaultBackendSettings struct {
+	// Address of the Vault server, defaults to the VAULT_ADDR environment variable
+	Address string `yaml:"address"`
+	// Namespace of the secrets, for Vault Enterprise
+	Namespace string `yaml:"namespace"`
+	// CAFile holds the certificate authorities trusted to verify the Vault server certificate
+	CAFile string `yaml:"ca_file"`
+	// AuthMethod is either "token" (default) or "approle"
+	AuthMethod string `yaml:"auth_method"`
+	// Token, or the file holding it, for the token auth method. Defaults to the VAULT_TOKEN
+	// environment variable.
+	Token     string `yaml:"token"`
+	TokenFile string `yaml:"token_file"`
+	// RoleID, SecretID or the file holding it, and the mount path, for the AppRole auth method
+	RoleID       string `yaml:"role_id"`
+	SecretID     string `yaml:"secret_id"`
+	SecretIDFile string `yaml:"secret_id_file"`
+	AppRoleMount string `yaml:"approle_mount"`
+}
+
+// vaultBackend resolves handles of the form 'vault:path#field' with the secrets of HashiCorp Vault
+// KV v1 and v2 engines. The path is the API path of the secret, such as 'secret/data/db' for a KV v2
+// engine mounted at 'secret'. The field defaults to 'value'.
+type vaultBackend struct {
+	cfg    vaultBackendSettings
+	client *http.Client
+
+	// token obtained with the AppRole auth method, and its expiration
+	tokenLock   sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+func newVaultBackend(settings vaultBackendSettings) (*vaultBackend, error) {
+	if settings.Address == "" {
+		settings.Address = os.Getenv("VAULT_ADDR")
+	}
+	if settings.Address == "" {
+		return nil, errors.New("missing 'address' setting")
+	}
+	settings.Address = strings.TrimRight(settings.Address, "/")
+	if settings.AuthMethod == "" {
+		settings.AuthMethod = vaultAuthToken
+	}
+	switch settings.AuthMethod {
+	case vaultAuthToken:
+		if settings.Token == "" && settings.TokenFile == "" {
+			settings.Token = os.Getenv("VAULT_TOKEN")
+		}
+		if settings.Token == "" && settings.TokenFile == "" {
+			return nil, errors.New("the token auth method requires a 'token' or 'token_file' setting")
+		}
+	case vaultAuthAppRole:
+		if settings.RoleID == "" || (settings.SecretID == "" && settings.SecretIDFile == "") {
+			return nil, errors.New("the approle auth method requires 'role_id' and 'secret_id' or 'secret_id_file' settings")
+		}
+		if settings.AppRoleMount == "" {
+			settings.AppRoleMount = vaultAuthAppRole
+		}
+	default:
+		return nil, fmt.Errorf("unknown auth_method '%s', expected '%s' or '%s'", settings.AuthMethod, vaultAuthToken, vaultAuthAppRole)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if settings.CAFile != "" {
+		pem, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in '%s'", settings.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &vaultBackend{
+		cfg:    settings,
+		client: &http.Client{Transport: transport},
+	}, nil
+}
+
+type vaultError struct {
+	status int
+	errors []string
+}
+
+func (e *vaultError) Error() string {
+	if len(e.errors) == 0 {
+		return fmt.Sprintf("vault responded with status %d", e.status)
+	}
+	return fmt.Sprintf("vault responded with status %d: %s", e.status, strings.Join(e.errors, ", "))
+}
+
+func (b *vaultBackend) fetch(ctx context.Context, ref string) (string, error) {
+	path, field := splitFieldRef(ref)
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", errors.New("missing secret path")
+	}
+	if field == "" {
+		field = "value"
+	}
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	err := b.authenticatedRequest(ctx, http.MethodGet, "/v1/"+path, &response)
+	if err != nil {
+		return "", err
+	}
+	data := response.Data
+	// KV v2 engines nest the secret and its metadata in the data of the response
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+	if data == nil {
+		return "", fmt.Errorf("secret '%s' not found", path)
+	}
+	return secretValue(data, field)
+}
+
+// authenticatedRequest sends a request with a token, logging in again once when an AppRole token was revoked
+func (b *vaultBackend) authenticatedRequest(ctx context.Context, method, path string, response interface{}) error {
+	token, err := b.getToken(ctx)
+	if err != nil {
+		return err
+	}
+	err = b.request(ctx, method, path, token, nil, response)
+	var verr *vaultError
+	if b.cfg.AuthMethod == vaultAuthAppRole && errors.As(err, &verr) && verr.status == http.StatusForbidden {
+		b.resetToken(token)
+		if token, err = b.getToken(ctx); err != nil {
+			return err
+		}
+		err = b.request(ctx, method, path, token, nil, response)
+	}
+	return err
+}
+
+func (b *vaultBackend) getToken(ctx context.Context) (string, error) {
+	switch b.cfg.AuthMethod {
+	case vaultAuthToken:
+		if b.cfg.TokenFile == "" {
+			return b.cfg.Token, nil
+		}
+		// the file is read every time since tokens may be renewed by an agent such as Vault Agent
+		token, err := os.ReadFile(b.cfg.TokenFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(token)), nil
+	default:
+		return b.appRoleToken(ctx)
+	}
+}
+
+func (b *vaultBackend) appRoleToken(ctx context.Context) (string, error) {
+	b.tokenLock.Lock()
+	defer b.tokenLock.Unlock()
+	if b.token != "" && (b.tokenExpiry.IsZero() || time.Now().Before(b.tokenExpiry)) {
+		return b.token, nil
+	}
+
+	secretID := b.cfg.SecretID
+	if b.cfg.SecretIDFile != "" {
+		data, err := os.ReadFile(b.cfg.SecretIDFile)
+		if err != nil {
+			return "", err
+		}
+		secretID = strings.TrimSpace(string(data))
+	}
+	body, err := json.Marshal(map[string]string{"role_id": b.cfg.RoleID, "secret_id": secretID})
+	if err != nil {
+		return "", err
+	}
+	var response struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	path := "/v1/auth/" + strings.Trim(b.cfg.AppRoleMount, "/") + "/login"
+	if err := b.request(ctx, http.MethodPost, path, "", body, &response); err != nil {
+		return "", fmt.Errorf("could not log in with the approle auth method: %w", err)
+	}
+	if response.Auth.ClientToken == "" {
+		return "", errors.New("could not log in with the approle auth method: no token returned")
+	}
+	b.token = response.Auth.ClientToken
+	b.tokenExpiry = time.Time{}
+	if response.Auth.LeaseDuration > 0 {
+		// renew the token before it expires
+		lease := time.Duration(response.Auth.LeaseDuration) * time.Second
+		b.tokenExpiry = time.Now().Add(lease * 9 / 10)
+	}
+	return b.token, nil
+}
+
+// resetToken drops the AppRole token, unless it was already replaced
+func (b *vaultBackend) resetToken(token string) {
+	b.tokenLock.Lock()
+	defer b.tokenLock.Unlock()
+	if b.token == token {
+		b.token = ""
+	}
+}
+
+func (b *vaultBackend) request(ctx context.Context, method, path, token string, body []byte, response interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, b.cfg.Address+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if b.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", b.cfg.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, vaultResponseMaxSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		verr := &vaultError{status: resp.StatusCode}
+		var errResponse struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &errResponse) == nil {
+			verr.errors = errResponse.Errors
+		}
+		return verr
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("could not parse the vault response: %s", err)
+	}
+	return nil
+}
+
+func (b *vaultBackend) settings() map[string]string {
+	settings := map[string]string{
+		"address":     b.cfg.Address,
+		"auth_method": b.cfg.AuthMethod,
+	}
+	if b.cfg.Namespace != "" {
+		settings["namespace"] = b.cfg.Namespace
+	}
+	if b.cfg.TokenFile != "" {
+		settings["token_file"] = b.cfg.TokenFile
+	}
+	if b.cfg.AuthMethod == vaultAuthAppRole {
+		settings["approle_mount"] = b.cfg.AppRoleMount
+	}
+	return settings
+}
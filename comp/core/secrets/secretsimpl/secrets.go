@@ -24,6 +24,7 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"go.uber.org/fx"
 	"golang.org/x/exp/maps"
 	yaml "gopkg.in/yaml.v2"
@@ -76,6 +77,10 @@ type secretResolver struct {
 	enabled bool
 	lock    sync.Mutex
 	cache   map[string]string
+	// cacheExpiry holds when cached secrets must be fetched again, when a cache TTL is set
+	cacheExpiry map[string]time.Time
+	cacheTTL    time.Duration
+	clock       clock.Clock
 
 	// list of handles and where they were found
 	origin handleToContext
@@ -87,6 +92,9 @@ type secretResolver struct {
 	removeTrailingLinebreak bool
 	// responseMaxSize defines max size of the JSON output from a secrets reader backend
 	responseMaxSize int
+	// built-in backends resolving the handles prefixed by their type, and their configuration error
+	builtinBackends    map[string]builtinBackend
+	builtinBackendsErr error
 	// refresh secrets at a regular interval
 	refreshInterval time.Duration
 	ticker          *time.Ticker
@@ -113,6 +121,8 @@ var _ secrets.Component = (*secretResolver)(nil)
 func newEnabledSecretResolver(telemetry telemetry.Component) *secretResolver {
 	return &secretResolver{
 		cache:                   make(map[string]string),
+		cacheExpiry:             make(map[string]time.Time),
+		clock:                   clock.New(),
 		origin:                  make(handleToContext),
 		enabled:                 true,
 		tlmSecretBackendElapsed: telemetry.NewGauge("secret_backend", "elapsed_ms", []string{"command", "exit_code"}, "Elapsed time of secret backend invocation"),
@@ -126,7 +136,7 @@ func newSecretResolverProvider(deps dependencies) provides {
 	resolver.enabled = deps.Params.Enabled
 	return provides{
 		Comp:            resolver,
-		FlareProvider:   flaretypes.NewProvider(resolver.fillFlare),
+		FlareProvider:   flaretypes.NewNamedProvider("secrets", resolver.fillFlare),
 		InfoEndpoint:    api.NewAgentEndpointProvider(resolver.writeDebugInfo, "/secrets", "GET"),
 		RefreshEndpoint: api.NewAgentEndpointProvider(resolver.handleRefresh, "/secret/refresh", "GET"),
 	}
@@ -221,6 +231,11 @@ func (r *secretResolver) Configure(params secrets.ConfigParams) {
 	if r.commandAllowGroupExec {
 		log.Warnf("Agent configuration relax permissions constraint on the secret backend cmd, Group can read and exec")
 	}
+	r.cacheTTL = time.Duration(params.CacheTTL) * time.Second
+	r.builtinBackends, r.builtinBackendsErr = newBuiltinBackends(params.Backends)
+	if r.builtinBackendsErr != nil {
+		log.Errorf("Could not configure the built-in secret backends: %s", r.builtinBackendsErr)
+	}
 	r.auditFilename = filepath.Join(params.RunPath, auditFileBasename)
 	r.auditFileMaxSize = params.AuditFileMaxSize
 	if r.auditFileMaxSize == 0 {
@@ -228,6 +243,17 @@ func (r *secretResolver) Configure(params secrets.ConfigParams) {
 	}
 }
 
+// isConfigured returns whether a secret_backend_command or built-in backends are configured
+func (r *secretResolver) isConfigured() bool {
+	return r.backendCommand != "" || len(r.builtinBackends) != 0 || r.builtinBackendsErr != nil
+}
+
+// isCacheExpired returns whether the cached value of a handle outlived the cache TTL
+func (r *secretResolver) isCacheExpired(handle string) bool {
+	expiry, ok := r.cacheExpiry[handle]
+	return ok && !r.clock.Now().Before(expiry)
+}
+
 func isEnc(str string) (bool, string) {
 	// trimming space and tabs
 	str = strings.Trim(str, " 	")
@@ -261,8 +287,8 @@ func (r *secretResolver) SubscribeToChanges(cb secrets.SecretChangeCallback) {
 	r.subscriptions = append(r.subscriptions, cb)
 }
 
-// Resolve replaces all encoded secrets in data by executing "secret_backend_command" once if all secrets aren't
-// present in the cache.
+// Resolve replaces all encoded secrets in data by executing "secret_backend_command" once, and querying the
+// built-in backends, if all secrets aren't present in the cache.
 func (r *secretResolver) Resolve(data []byte, origin string) ([]byte, error) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
@@ -271,7 +297,7 @@ func (r *secretResolver) Resolve(data []byte, origin string) ([]byte, error) {
 		log.Infof("Agent secrets is disabled by caller")
 		return nil, nil
 	}
-	if data == nil || r.backendCommand == "" {
+	if data == nil || !r.isConfigured() {
 		return data, nil
 	}
 
@@ -288,8 +314,8 @@ func (r *secretResolver) Resolve(data []byte, origin string) ([]byte, error) {
 	w := &walker{
 		resolver: func(path []string, value string) (string, error) {
 			if ok, handle := isEnc(value); ok {
-				// Check if we already know this secret
-				if secretValue, ok := r.cache[handle]; ok {
+				// Check if we already know this secret, and its cached value hasn't expired
+				if secretValue, ok := r.cache[handle]; ok && !r.isCacheExpired(handle) {
 					log.Debugf("Secret '%s' was retrieved from cache", handle)
 					// keep track of place where a handle was found
 					r.registerSecretOrigin(handle, origin, path)
@@ -322,14 +348,10 @@ func (r *secretResolver) Resolve(data []byte, origin string) ([]byte, error) {
 
 	// check if any new secrets need to be fetch
 	if len(newHandles) != 0 {
-		var secretResponse map[string]string
-		var err error
-		if r.fetchHookFunc != nil {
-			// hook used only for tests
-			secretResponse, err = r.fetchHookFunc(newHandles)
-		} else {
-			secretResponse, err = r.fetchSecret(newHandles)
+		if r.builtinBackendsErr != nil {
+			return nil, fmt.Errorf("invalid built-in secret backends configuration: %s", r.builtinBackendsErr)
 		}
+		secretResponse, err := r.fetchSecrets(newHandles)
 		if err != nil {
 			return nil, err
 		}
@@ -453,6 +475,9 @@ func (r *secretResolver) processSecretResponse(secretResponse map[string]string,
 	// add results to the cache
 	for handle, secretValue := range secretResponse {
 		r.cache[handle] = secretValue
+		if r.cacheTTL > 0 {
+			r.cacheExpiry[handle] = r.clock.Now().Add(r.cacheTTL)
+		}
 	}
 	// return info about the handles sorted by their name
 	sort.Slice(handleInfoList, func(i, j int) bool {
@@ -483,14 +508,7 @@ func (r *secretResolver) Refresh() (string, error) {
 
 	log.Infof("Refreshing secrets for %d handles", len(newHandles))
 
-	var secretResponse map[string]string
-	var err error
-	if r.fetchHookFunc != nil {
-		// hook used only for tests
-		secretResponse, err = r.fetchHookFunc(newHandles)
-	} else {
-		secretResponse, err = r.fetchSecret(newHandles)
-	}
+	secretResponse, err := r.fetchSecrets(newHandles)
 	if err != nil {
 		return "", err
 	}
@@ -576,6 +594,8 @@ type secretInfo struct {
 	ExecutablePermissions        string
 	ExecutablePermissionsDetails interface{}
 	ExecutablePermissionsError   string
+	Backends                     []builtinBackendInfo
+	BackendsError                string
 	Handles                      map[string][][]string
 }
 
@@ -605,8 +625,8 @@ func (r *secretResolver) GetDebugInfo(w io.Writer) {
 		fmt.Fprintf(w, "Agent secrets is disabled by caller")
 		return
 	}
-	if r.backendCommand == "" {
-		fmt.Fprintf(w, "No secret_backend_command set: secrets feature is not enabled")
+	if !r.isConfigured() {
+		fmt.Fprintf(w, "No secret_backend_command or secret_backend_config set: secrets feature is not enabled")
 		return
 	}
 
@@ -623,22 +643,29 @@ func (r *secretResolver) GetDebugInfo(w io.Writer) {
 		return
 	}
 
-	err = checkRights(r.backendCommand, r.commandAllowGroupExec)
-
-	permissions := "OK, the executable has the correct permissions"
-	if err != nil {
-		permissions = fmt.Sprintf("error: %s", err)
-	}
-
-	details, err := r.getExecutablePermissions()
 	info := secretInfo{
-		Executable:                   r.backendCommand,
-		ExecutablePermissions:        permissions,
-		ExecutablePermissionsDetails: details,
-		Handles:                      map[string][][]string{},
+		Backends: r.builtinBackendsInfo(),
+		Handles:  map[string][][]string{},
 	}
-	if err != nil {
-		info.ExecutablePermissionsError = err.Error()
+	if r.builtinBackendsErr != nil {
+		info.BackendsError = r.builtinBackendsErr.Error()
+	}
+
+	if r.backendCommand != "" {
+		err = checkRights(r.backendCommand, r.commandAllowGroupExec)
+
+		permissions := "OK, the executable has the correct permissions"
+		if err != nil {
+			permissions = fmt.Sprintf("error: %s", err)
+		}
+
+		details, err := r.getExecutablePermissions()
+		info.Executable = r.backendCommand
+		info.ExecutablePermissions = permissions
+		info.ExecutablePermissionsDetails = details
+		if err != nil {
+			info.ExecutablePermissionsError = err.Error()
+		}
 	}
 
 	// we sort handles so the output is consistent and testable
@@ -134,7 +134,7 @@ func newStatus(deps dependencies) provides {
 
 	return provides{
 		Comp:          c,
-		FlareProvider: flaretypes.NewProvider(c.fillFlare),
+		FlareProvider: flaretypes.NewNamedProvider("status", c.fillFlare),
 		APIGetStatus: api.NewAgentEndpointProvider(
 			func(w http.ResponseWriter, r *http.Request) { c.getStatus(w, r, "") },
 			"/status",
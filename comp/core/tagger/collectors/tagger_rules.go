@@ -0,0 +1,564 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package collectors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/core/tagger/common"
+	"github.com/DataDog/datadog-agent/comp/core/tagger/taglist"
+	"github.com/DataDog/datadog-agent/comp/core/tagger/types"
+	"github.com/DataDog/datadog-agent/comp/core/workloadmeta/collectors/util"
+	workloadmeta "github.com/DataDog/datadog-agent/comp/core/workloadmeta/def"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	taggerRulesConfigKey = "tagger_rules"
+
+	// captures of the regular expressions of the conditions are referenced with this prefix
+	captureFieldPrefix = "match."
+)
+
+// TaggerRuleConfig is the configuration of a rule deriving tags from the fields
+// of the workloadmeta entities, as set in `tagger_rules`
+type TaggerRuleConfig struct {
+	Name string `mapstructure:"name" json:"name"`
+	// Kind is the kind of entities the rule applies to: container or kubernetes_pod
+	Kind string `mapstructure:"kind" json:"kind"`
+	// Match lists the conditions an entity must meet for the rule to apply
+	Match []TaggerRuleCondition `mapstructure:"match" json:"match"`
+	// Tags lists the `name:value` tags added by the rule. Values can reference
+	// fields and regex captures, e.g. `team:{labels.team|lower}`
+	Tags []string `mapstructure:"tags" json:"tags"`
+	// Cardinality is the cardinality of the tags: low (default), orchestrator or high
+	Cardinality string `mapstructure:"cardinality" json:"cardinality"`
+}
+
+// TaggerRuleCondition is a regular expression that a field of the entity must match
+type TaggerRuleCondition struct {
+	Field string `mapstructure:"field" json:"field"`
+	Regex string `mapstructure:"regex" json:"regex"`
+}
+
+// fieldGetter returns the value of a field of an entity, and whether it's set
+type fieldGetter func(field string) (string, bool)
+
+var (
+	ruleFieldsByKind = map[workloadmeta.Kind]map[string]struct{}{
+		workloadmeta.KindContainer: {
+			"id":               {},
+			"name":             {},
+			"runtime":          {},
+			"image.id":         {},
+			"image.name":       {},
+			"image.short_name": {},
+			"image.tag":        {},
+		},
+		workloadmeta.KindKubernetesPod: {
+			"id":             {},
+			"name":           {},
+			"namespace":      {},
+			"phase":          {},
+			"qos_class":      {},
+			"priority_class": {},
+			"runtime_class":  {},
+			"owner.kind":     {},
+			"owner.name":     {},
+			"deployment":     {},
+		},
+	}
+
+	ruleFieldPrefixesByKind = map[workloadmeta.Kind][]string{
+		workloadmeta.KindContainer:     {"labels.", "env."},
+		workloadmeta.KindKubernetesPod: {"labels.", "annotations.", "deployment.labels.", "deployment.annotations."},
+	}
+
+	ruleFunctions = map[string]func(string) string{
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"trim":      strings.TrimSpace,
+		"normalize": normalizeTagValue,
+	}
+
+	invalidTagValueChars = regexp.MustCompile(`[^a-z0-9_\-./:]+`)
+	repeatedUnderscores  = regexp.MustCompile(`__+`)
+)
+
+// taggerRule is a parsed TaggerRuleConfig
+type taggerRule struct {
+	name       string
+	kind       workloadmeta.Kind
+	conditions []ruleCondition
+	tags       []ruleTag
+	add        func(*taglist.TagList, string, string)
+}
+
+type ruleCondition struct {
+	field string
+	regex *regexp.Regexp
+}
+
+type ruleTag struct {
+	name  string
+	parts []ruleValuePart
+}
+
+// ruleValuePart is either a literal, or a reference to a field or capture with functions applied to it
+type ruleValuePart struct {
+	literal   string
+	field     string
+	functions []func(string) string
+}
+
+// ValidateTaggerRules returns an error if the JSON list of rules isn't valid
+func ValidateTaggerRules(rules string) error {
+	var configs []TaggerRuleConfig
+	if err := json.Unmarshal([]byte(rules), &configs); err != nil {
+		return fmt.Errorf("invalid tagger rules: %w", err)
+	}
+
+	var errs []error
+	for i, cfg := range configs {
+		if _, err := newTaggerRule(cfg); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadTaggerRules reads `tagger_rules`, either as a list or a JSON string, and
+// returns the valid rules. Invalid rules are logged and skipped.
+func loadTaggerRules(cfg config.Component) []*taggerRule {
+	var configs []TaggerRuleConfig
+	if raw, ok := cfg.Get(taggerRulesConfigKey).(string); ok {
+		if raw != "" {
+			if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+				log.Errorf("failed to parse %s: %v", taggerRulesConfigKey, err)
+				return nil
+			}
+		}
+	} else if err := structure.UnmarshalKey(cfg, taggerRulesConfigKey, &configs); err != nil {
+		log.Errorf("failed to parse %s: %v", taggerRulesConfigKey, err)
+		return nil
+	}
+
+	rules := make([]*taggerRule, 0, len(configs))
+	for i, ruleConfig := range configs {
+		rule, err := newTaggerRule(ruleConfig)
+		if err != nil {
+			log.Errorf("ignoring tagger rule %d %q: %v", i, ruleConfig.Name, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func newTaggerRule(cfg TaggerRuleConfig) (*taggerRule, error) {
+	rule := &taggerRule{
+		name: cfg.Name,
+		kind: workloadmeta.Kind(cfg.Kind),
+	}
+
+	if _, ok := ruleFieldsByKind[rule.kind]; !ok {
+		return nil, fmt.Errorf("unsupported kind %q, expected %s or %s", cfg.Kind, workloadmeta.KindContainer, workloadmeta.KindKubernetesPod)
+	}
+
+	switch strings.ToLower(cfg.Cardinality) {
+	case "", "low":
+		rule.add = (*taglist.TagList).AddLow
+	case "orchestrator", "orch":
+		rule.add = (*taglist.TagList).AddOrchestrator
+	case "high":
+		rule.add = (*taglist.TagList).AddHigh
+	default:
+		return nil, fmt.Errorf("unsupported cardinality %q, expected low, orchestrator or high", cfg.Cardinality)
+	}
+
+	captures := make(map[string]struct{})
+	for _, condition := range cfg.Match {
+		if !isRuleField(rule.kind, condition.Field) {
+			return nil, fmt.Errorf("unknown field %q for kind %s", condition.Field, rule.kind)
+		}
+		regex, err := regexp.Compile(condition.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex for field %s: %w", condition.Field, err)
+		}
+		for _, name := range regex.SubexpNames() {
+			if name != "" {
+				captures[name] = struct{}{}
+			}
+		}
+		rule.conditions = append(rule.conditions, ruleCondition{field: condition.Field, regex: regex})
+	}
+
+	if len(cfg.Tags) == 0 {
+		return nil, errors.New("the rule has no tags")
+	}
+	for _, tag := range cfg.Tags {
+		parsed, err := parseRuleTag(rule.kind, tag, captures)
+		if err != nil {
+			return nil, err
+		}
+		rule.tags = append(rule.tags, parsed)
+	}
+
+	return rule, nil
+}
+
+// parseRuleTag parses a `name:value` tag, where value can contain `{field|function}` references
+func parseRuleTag(kind workloadmeta.Kind, tag string, captures map[string]struct{}) (ruleTag, error) {
+	name, value, found := strings.Cut(tag, ":")
+	if !found || name == "" {
+		return ruleTag{}, fmt.Errorf("invalid tag %q, expected name:value", tag)
+	}
+
+	parsed := ruleTag{name: name}
+	for value != "" {
+		start := strings.IndexByte(value, '{')
+		if start < 0 {
+			parsed.parts = append(parsed.parts, ruleValuePart{literal: value})
+			break
+		}
+		if start > 0 {
+			parsed.parts = append(parsed.parts, ruleValuePart{literal: value[:start]})
+		}
+		end := strings.IndexByte(value[start:], '}')
+		if end < 0 {
+			return ruleTag{}, fmt.Errorf("invalid tag %q: unclosed {", tag)
+		}
+
+		expr := strings.Split(value[start+1:start+end], "|")
+		part := ruleValuePart{field: strings.TrimSpace(expr[0])}
+		if capture, ok := strings.CutPrefix(part.field, captureFieldPrefix); ok {
+			if _, ok := captures[capture]; !ok {
+				return ruleTag{}, fmt.Errorf("invalid tag %q: no condition captures %q", tag, capture)
+			}
+		} else if !isRuleField(kind, part.field) {
+			return ruleTag{}, fmt.Errorf("invalid tag %q: unknown field %q for kind %s", tag, part.field, kind)
+		}
+		for _, fn := range expr[1:] {
+			function, ok := ruleFunctions[strings.TrimSpace(fn)]
+			if !ok {
+				return ruleTag{}, fmt.Errorf("invalid tag %q: unknown function %q", tag, strings.TrimSpace(fn))
+			}
+			part.functions = append(part.functions, function)
+		}
+		parsed.parts = append(parsed.parts, part)
+
+		value = value[start+end+1:]
+	}
+
+	return parsed, nil
+}
+
+func isRuleField(kind workloadmeta.Kind, field string) bool {
+	if _, ok := ruleFieldsByKind[kind][field]; ok {
+		return true
+	}
+	for _, prefix := range ruleFieldPrefixesByKind[kind] {
+		if strings.HasPrefix(field, prefix) && len(field) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply adds the tags of the rule to tagList if the entity meets its conditions.
+// A tag referencing a field that isn't set is skipped.
+func (r *taggerRule) apply(fields fieldGetter, tagList *taglist.TagList) {
+	captures := make(map[string]string)
+	for _, condition := range r.conditions {
+		value, ok := fields(condition.field)
+		if !ok {
+			return
+		}
+		match := condition.regex.FindStringSubmatch(value)
+		if match == nil {
+			return
+		}
+		for i, name := range condition.regex.SubexpNames() {
+			if name != "" {
+				captures[name] = match[i]
+			}
+		}
+	}
+
+	for _, tag := range r.tags {
+		if value, ok := tag.render(fields, captures); ok {
+			r.add(tagList, tag.name, value)
+		}
+	}
+}
+
+func (t ruleTag) render(fields fieldGetter, captures map[string]string) (string, bool) {
+	var value strings.Builder
+	for _, part := range t.parts {
+		if part.field == "" {
+			value.WriteString(part.literal)
+			continue
+		}
+
+		var fieldValue string
+		var ok bool
+		if capture, isCapture := strings.CutPrefix(part.field, captureFieldPrefix); isCapture {
+			fieldValue, ok = captures[capture]
+		} else {
+			fieldValue, ok = fields(part.field)
+		}
+		if !ok {
+			return "", false
+		}
+		for _, fn := range part.functions {
+			fieldValue = fn(fieldValue)
+		}
+		value.WriteString(fieldValue)
+	}
+	return value.String(), true
+}
+
+// normalizeTagValue lowercases the value and replaces the characters that are
+// not valid in a tag with underscores
+func normalizeTagValue(value string) string {
+	value = invalidTagValueChars.ReplaceAllString(strings.ToLower(value), "_")
+	return strings.Trim(repeatedUnderscores.ReplaceAllString(value, "_"), "_")
+}
+
+func containerRuleFields(container *workloadmeta.Container) fieldGetter {
+	return func(field string) (string, bool) {
+		switch field {
+		case "id":
+			return container.ID, true
+		case "name":
+			return container.Name, container.Name != ""
+		case "runtime":
+			return string(container.Runtime), container.Runtime != ""
+		case "image.id":
+			return container.Image.ID, container.Image.ID != ""
+		case "image.name":
+			return container.Image.Name, container.Image.Name != ""
+		case "image.short_name":
+			return container.Image.ShortName, container.Image.ShortName != ""
+		case "image.tag":
+			return container.Image.Tag, container.Image.Tag != ""
+		}
+		if label, ok := strings.CutPrefix(field, "labels."); ok {
+			value, found := container.Labels[label]
+			return value, found
+		}
+		if env, ok := strings.CutPrefix(field, "env."); ok {
+			value, found := container.EnvVars[env]
+			return value, found
+		}
+		return "", false
+	}
+}
+
+func (c *WorkloadMetaCollector) podRuleFields(pod *workloadmeta.KubernetesPod) fieldGetter {
+	var deployment *workloadmeta.EntityMeta
+	deploymentName := podDeploymentName(pod)
+	deploymentLoaded := false
+
+	getDeployment := func() *workloadmeta.EntityMeta {
+		if !deploymentLoaded {
+			deploymentLoaded = true
+			deployment = c.getDeploymentMeta(pod.Namespace, deploymentName)
+		}
+		return deployment
+	}
+
+	return func(field string) (string, bool) {
+		switch field {
+		case "id":
+			return pod.ID, true
+		case "name":
+			return pod.Name, pod.Name != ""
+		case "namespace":
+			return pod.Namespace, pod.Namespace != ""
+		case "phase":
+			return pod.Phase, pod.Phase != ""
+		case "qos_class":
+			return pod.QOSClass, pod.QOSClass != ""
+		case "priority_class":
+			return pod.PriorityClass, pod.PriorityClass != ""
+		case "runtime_class":
+			return pod.RuntimeClass, pod.RuntimeClass != ""
+		case "deployment":
+			return deploymentName, deploymentName != ""
+		case "owner.kind", "owner.name":
+			if len(pod.Owners) == 0 {
+				return "", false
+			}
+			if field == "owner.kind" {
+				return pod.Owners[0].Kind, true
+			}
+			return pod.Owners[0].Name, true
+		}
+		if label, ok := strings.CutPrefix(field, "deployment.labels."); ok {
+			if meta := getDeployment(); meta != nil {
+				value, found := meta.Labels[label]
+				return value, found
+			}
+			return "", false
+		}
+		if annotation, ok := strings.CutPrefix(field, "deployment.annotations."); ok {
+			if meta := getDeployment(); meta != nil {
+				value, found := meta.Annotations[annotation]
+				return value, found
+			}
+			return "", false
+		}
+		if label, ok := strings.CutPrefix(field, "labels."); ok {
+			value, found := pod.Labels[label]
+			return value, found
+		}
+		if annotation, ok := strings.CutPrefix(field, "annotations."); ok {
+			value, found := pod.Annotations[annotation]
+			return value, found
+		}
+		return "", false
+	}
+}
+
+// podDeploymentName returns the name of the deployment owning the pod through its replicaset
+func podDeploymentName(pod *workloadmeta.KubernetesPod) string {
+	for _, owner := range pod.Owners {
+		switch owner.Kind {
+		case kubernetes.DeploymentKind:
+			return owner.Name
+		case kubernetes.ReplicaSetKind:
+			if deployment := kubernetes.ParseDeploymentForReplicaSet(owner.Name); deployment != "" {
+				return deployment
+			}
+		}
+	}
+	return ""
+}
+
+// getDeploymentMeta returns the labels and annotations of a deployment, from the deployment
+// entities of the cluster agent, or the deployments metadata collected by the node agent
+func (c *WorkloadMetaCollector) getDeploymentMeta(namespace, name string) *workloadmeta.EntityMeta {
+	if name == "" || c.store == nil {
+		return nil
+	}
+	if deployment, err := c.store.GetKubernetesDeployment(namespace + "/" + name); err == nil {
+		return &deployment.EntityMeta
+	}
+	if metadata, err := c.store.GetKubernetesMetadata(util.GenerateKubeMetadataEntityID("apps", "deployments", namespace, name)); err == nil {
+		return &metadata.EntityMeta
+	}
+	return nil
+}
+
+func buildTaggerRulesSource(kind workloadmeta.Kind) string {
+	return fmt.Sprintf("%s-rules-%s", workloadmetaCollectorName, string(kind))
+}
+
+// handleTaggerRules returns the tags that the rules of kind derive from the fields
+// of an entity, for each of the tagger entities
+func (c *WorkloadMetaCollector) handleTaggerRules(kind workloadmeta.Kind, fields fieldGetter, entityIDs []types.EntityID) []*types.TagInfo {
+	if !c.taggerRulesEnabled {
+		return nil
+	}
+
+	tagList := taglist.NewTagList()
+	for _, rule := range c.taggerRules {
+		if rule.kind == kind {
+			rule.apply(fields, tagList)
+		}
+	}
+	low, orch, high, standard := tagList.Compute()
+
+	source := buildTaggerRulesSource(kind)
+	tagInfos := make([]*types.TagInfo, 0, len(entityIDs))
+	for _, entityID := range entityIDs {
+		if len(low)+len(orch)+len(high) == 0 {
+			// no rule applies (anymore), remove the tags previously added by rules
+			tagInfos = append(tagInfos, &types.TagInfo{
+				Source:       source,
+				EntityID:     entityID,
+				DeleteEntity: true,
+			})
+			continue
+		}
+		tagInfos = append(tagInfos, &types.TagInfo{
+			Source:               source,
+			EntityID:             entityID,
+			HighCardTags:         high,
+			OrchestratorCardTags: orch,
+			LowCardTags:          low,
+			StandardTags:         standard,
+		})
+	}
+	return tagInfos
+}
+
+// handleDeleteTaggerRules removes the tags that the rules of kind added to the entities
+func (c *WorkloadMetaCollector) handleDeleteTaggerRules(kind workloadmeta.Kind, entityIDs map[types.EntityID]struct{}) []*types.TagInfo {
+	if _, ok := ruleFieldsByKind[kind]; !ok || !c.taggerRulesEnabled {
+		return nil
+	}
+
+	source := buildTaggerRulesSource(kind)
+	tagInfos := make([]*types.TagInfo, 0, len(entityIDs))
+	for entityID := range entityIDs {
+		tagInfos = append(tagInfos, &types.TagInfo{
+			Source:       source,
+			EntityID:     entityID,
+			DeleteEntity: true,
+		})
+	}
+	return tagInfos
+}
+
+// reloadTaggerRules loads the rules again, and re-tags the containers and pods
+// so that the tags of the new rules replace the ones of the previous rules
+func (c *WorkloadMetaCollector) reloadTaggerRules() {
+	c.setTaggerRules(loadTaggerRules(c.config))
+	if !c.taggerRulesEnabled {
+		return
+	}
+	log.Infof("tagger rules reloaded, %d rules are active", len(c.taggerRules))
+
+	var events []workloadmeta.Event
+	pods := make(map[string]struct{})
+	for _, container := range c.store.ListContainers() {
+		events = append(events, workloadmeta.Event{Type: workloadmeta.EventTypeSet, Entity: container})
+		if container.Owner != nil && container.Owner.Kind == workloadmeta.KindKubernetesPod {
+			pods[container.Owner.ID] = struct{}{}
+		}
+	}
+	for podID := range pods {
+		if pod, err := c.store.GetKubernetesPod(podID); err == nil {
+			events = append(events, workloadmeta.Event{Type: workloadmeta.EventTypeSet, Entity: pod})
+		}
+	}
+
+	c.processEvents(workloadmeta.EventBundle{Events: events})
+}
+
+func (c *WorkloadMetaCollector) setTaggerRules(rules []*taggerRule) {
+	c.taggerRules = rules
+	// once rules were set, the tags of the rules must be removed when they no longer apply
+	c.taggerRulesEnabled = c.taggerRulesEnabled || len(rules) > 0
+}
+
+// taggerEntityIDs returns the tagger entity IDs of the workloadmeta entities
+func taggerEntityIDs(entityIDs ...workloadmeta.EntityID) []types.EntityID {
+	ids := make([]types.EntityID, 0, len(entityIDs))
+	for _, entityID := range entityIDs {
+		ids = append(ids, common.BuildTaggerEntityID(entityID))
+	}
+	return ids
+}
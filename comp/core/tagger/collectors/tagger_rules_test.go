@@ -0,0 +1,361 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package collectors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	"github.com/DataDog/datadog-agent/comp/core/tagger/types"
+	workloadmeta "github.com/DataDog/datadog-agent/comp/core/workloadmeta/def"
+	workloadmetafxmock "github.com/DataDog/datadog-agent/comp/core/workloadmeta/fx-mock"
+	workloadmetamock "github.com/DataDog/datadog-agent/comp/core/workloadmeta/mock"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestValidateTaggerRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		err   string
+	}{
+		{
+			name:  "valid",
+			rules: `[{"kind": "container", "match": [{"field": "image.name", "regex": "^(?P<team>[a-z]+)/"}], "tags": ["team:{match.team|upper}"]}]`,
+		},
+		{
+			name:  "empty",
+			rules: `[]`,
+		},
+		{
+			name:  "not json",
+			rules: `{`,
+			err:   "invalid tagger rules: unexpected end of JSON input",
+		},
+		{
+			name:  "unknown kind",
+			rules: `[{"kind": "process", "tags": ["a:b"]}]`,
+			err:   `rule 0: unsupported kind "process", expected container or kubernetes_pod`,
+		},
+		{
+			name:  "unknown cardinality",
+			rules: `[{"kind": "container", "tags": ["a:b"], "cardinality": "medium"}]`,
+			err:   `rule 0: unsupported cardinality "medium", expected low, orchestrator or high`,
+		},
+		{
+			name:  "unknown field",
+			rules: `[{"kind": "container", "match": [{"field": "namespace", "regex": "."}], "tags": ["a:b"]}]`,
+			err:   `rule 0: unknown field "namespace" for kind container`,
+		},
+		{
+			name:  "invalid regex",
+			rules: `[{"kind": "container", "match": [{"field": "name", "regex": "("}], "tags": ["a:b"]}]`,
+			err:   "rule 0: invalid regex for field name: error parsing regexp: missing closing ): `(`",
+		},
+		{
+			name:  "no tags",
+			rules: `[{"kind": "kubernetes_pod"}]`,
+			err:   "rule 0: the rule has no tags",
+		},
+		{
+			name:  "unknown capture",
+			rules: `[{"kind": "container", "tags": ["team:{match.team}"]}]`,
+			err:   `rule 0: invalid tag "team:{match.team}": no condition captures "team"`,
+		},
+		{
+			name:  "unknown function",
+			rules: `[{"kind": "kubernetes_pod", "tags": ["team:{labels.team|reverse}"]}]`,
+			err:   `rule 0: invalid tag "team:{labels.team|reverse}": unknown function "reverse"`,
+		},
+		{
+			name:  "several invalid rules",
+			rules: `[{"kind": "container", "tags": ["team"]}, {"kind": "container", "tags": ["a:b"]}, {"kind": "container", "tags": ["team:{labels.team"]}]`,
+			err:   "rule 0: invalid tag \"team\", expected name:value\nrule 2: invalid tag \"team:{labels.team\": unclosed {",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTaggerRules(tt.rules)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoadTaggerRules(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		cfg := configmock.NewFromYAML(t, `
+tagger_rules:
+  - kind: container
+    tags: ["team:{labels.team}"]
+`)
+		assert.Len(t, loadTaggerRules(cfg), 1)
+
+		// rules set at runtime are JSON strings
+		cfg.Set("tagger_rules", `[]`, model.SourceAgentRuntime)
+		assert.Empty(t, loadTaggerRules(cfg))
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TAGGER_RULES", `[{"kind": "kubernetes_pod", "tags": ["a:b"]}, {"kind": "container", "tags": ["c:d"]}]`)
+		assert.Len(t, loadTaggerRules(configmock.New(t)), 2)
+	})
+}
+
+func TestNormalizeTagValue(t *testing.T) {
+	assert.Equal(t, "team_a", normalizeTagValue("Team A"))
+	assert.Equal(t, "foo_bar", normalizeTagValue("__Foo@@Bar!"))
+	assert.Equal(t, "my-app/v1.2:x", normalizeTagValue("My-App/v1.2:x"))
+}
+
+func newTaggerRulesCollector(t *testing.T, rules string, store workloadmetamock.Mock) (*WorkloadMetaCollector, model.Config) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("tagger_rules", rules)
+	return NewWorkloadMetaCollector(context.Background(), cfg, store, nil), cfg
+}
+
+func TestHandleContainerTaggerRules(t *testing.T) {
+	const rules = `[
+		{
+			"name": "team from registry",
+			"kind": "container",
+			"match": [{"field": "image.name", "regex": "^registry\\.example\\.com/(?P<team>[^/]+)/"}],
+			"tags": ["team:{match.team|lower}", "owner:{labels.com.example.owner|normalize}", "missing:{labels.missing}"]
+		},
+		{
+			"kind": "container",
+			"match": [{"field": "env.DEPLOY_ENV", "regex": "prod"}],
+			"tags": ["instance:{name}-{image.tag}"],
+			"cardinality": "high"
+		}
+	]`
+
+	collector, _ := newTaggerRulesCollector(t, rules, nil)
+	entityID := types.NewEntityID(types.ContainerID, "foobar")
+
+	container := &workloadmeta.Container{
+		EntityID: workloadmeta.EntityID{
+			Kind: workloadmeta.KindContainer,
+			ID:   "foobar",
+		},
+		EntityMeta: workloadmeta.EntityMeta{
+			Name:   "api",
+			Labels: map[string]string{"com.example.owner": "Jane Doe"},
+		},
+		Image: workloadmeta.ContainerImage{
+			Name: "registry.example.com/Payments/api",
+			Tag:  "1.2.3",
+		},
+		EnvVars: map[string]string{"DEPLOY_ENV": "staging"},
+	}
+
+	actual := collector.handleTaggerRules(workloadmeta.KindContainer, containerRuleFields(container), []types.EntityID{entityID})
+	assertTagInfoListEqual(t, []*types.TagInfo{
+		{
+			Source:               buildTaggerRulesSource(workloadmeta.KindContainer),
+			EntityID:             entityID,
+			HighCardTags:         []string{},
+			OrchestratorCardTags: []string{},
+			LowCardTags:          []string{"team:payments", "owner:jane_doe"},
+			StandardTags:         []string{},
+		},
+	}, actual)
+
+	container.EnvVars["DEPLOY_ENV"] = "prod"
+	container.Image.Name = "docker.io/library/api"
+	actual = collector.handleTaggerRules(workloadmeta.KindContainer, containerRuleFields(container), []types.EntityID{entityID})
+	assertTagInfoListEqual(t, []*types.TagInfo{
+		{
+			Source:               buildTaggerRulesSource(workloadmeta.KindContainer),
+			EntityID:             entityID,
+			HighCardTags:         []string{"instance:api-1.2.3"},
+			OrchestratorCardTags: []string{},
+			LowCardTags:          []string{},
+			StandardTags:         []string{},
+		},
+	}, actual)
+
+	container.EnvVars["DEPLOY_ENV"] = "dev"
+	actual = collector.handleTaggerRules(workloadmeta.KindContainer, containerRuleFields(container), []types.EntityID{entityID})
+	assertTagInfoListEqual(t, []*types.TagInfo{
+		{
+			Source:       buildTaggerRulesSource(workloadmeta.KindContainer),
+			EntityID:     entityID,
+			DeleteEntity: true,
+		},
+	}, actual)
+}
+
+func TestHandleKubePodTaggerRules(t *testing.T) {
+	const rules = `[
+		{
+			"kind": "kubernetes_pod",
+			"tags": ["owner:{deployment.annotations.example.com/owner}", "app:{deployment}"]
+		}
+	]`
+
+	store := fxutil.Test[workloadmetamock.Mock](t, fx.Options(
+		fx.Provide(func() log.Component { return logmock.New(t) }),
+		config.MockModule(),
+		workloadmetafxmock.MockModule(workloadmeta.NewParams()),
+	))
+	store.Set(&workloadmeta.KubernetesDeployment{
+		EntityID: workloadmeta.EntityID{
+			Kind: workloadmeta.KindKubernetesDeployment,
+			ID:   "default/checkout",
+		},
+		EntityMeta: workloadmeta.EntityMeta{
+			Name:        "checkout",
+			Namespace:   "default",
+			Annotations: map[string]string{"example.com/owner": "team-checkout"},
+		},
+	})
+	store.Set(&workloadmeta.Container{
+		EntityID: workloadmeta.EntityID{
+			Kind: workloadmeta.KindContainer,
+			ID:   "foobar",
+		},
+		EntityMeta: workloadmeta.EntityMeta{
+			Name: "checkout",
+		},
+	})
+
+	collector, _ := newTaggerRulesCollector(t, rules, store)
+
+	pod := &workloadmeta.KubernetesPod{
+		EntityID: workloadmeta.EntityID{
+			Kind: workloadmeta.KindKubernetesPod,
+			ID:   "pod-uid",
+		},
+		EntityMeta: workloadmeta.EntityMeta{
+			Name:      "checkout-6d4cf56db6-abcde",
+			Namespace: "default",
+		},
+		Owners: []workloadmeta.KubernetesPodOwner{
+			{
+				Kind: "ReplicaSet",
+				Name: "checkout-6d4cf56db6",
+			},
+		},
+		Containers: []workloadmeta.OrchestratorContainer{
+			{
+				ID:   "foobar",
+				Name: "checkout",
+			},
+		},
+	}
+
+	actual := collector.handleKubePod(workloadmeta.Event{
+		Type:   workloadmeta.EventTypeSet,
+		Entity: pod,
+	})
+
+	source := buildTaggerRulesSource(workloadmeta.KindKubernetesPod)
+	var ruleTagInfos []*types.TagInfo
+	for _, tagInfo := range actual {
+		if tagInfo.Source == source {
+			ruleTagInfos = append(ruleTagInfos, tagInfo)
+		}
+	}
+
+	expectedTags := []string{"owner:team-checkout", "app:checkout"}
+	assertTagInfoListEqual(t, []*types.TagInfo{
+		{
+			Source:               source,
+			EntityID:             types.NewEntityID(types.KubernetesPodUID, "pod-uid"),
+			HighCardTags:         []string{},
+			OrchestratorCardTags: []string{},
+			LowCardTags:          expectedTags,
+			StandardTags:         []string{},
+		},
+		{
+			Source:               source,
+			EntityID:             types.NewEntityID(types.ContainerID, "foobar"),
+			HighCardTags:         []string{},
+			OrchestratorCardTags: []string{},
+			LowCardTags:          expectedTags,
+			StandardTags:         []string{},
+		},
+	}, ruleTagInfos)
+}
+
+func TestTaggerRulesDisabled(t *testing.T) {
+	collector, _ := newTaggerRulesCollector(t, "[]", nil)
+	container := &workloadmeta.Container{
+		EntityID: workloadmeta.EntityID{
+			Kind: workloadmeta.KindContainer,
+			ID:   "foobar",
+		},
+	}
+
+	assert.Nil(t, collector.handleTaggerRules(workloadmeta.KindContainer, containerRuleFields(container), taggerEntityIDs(container.EntityID)))
+	assert.Nil(t, collector.handleDeleteTaggerRules(workloadmeta.KindContainer, map[types.EntityID]struct{}{
+		types.NewEntityID(types.ContainerID, "foobar"): {},
+	}))
+}
+
+func TestReloadTaggerRules(t *testing.T) {
+	store := fxutil.Test[workloadmetamock.Mock](t, fx.Options(
+		fx.Provide(func() log.Component { return logmock.New(t) }),
+		config.MockModule(),
+		workloadmetafxmock.MockModule(workloadmeta.NewParams()),
+	))
+	store.Set(&workloadmeta.Container{
+		EntityID: workloadmeta.EntityID{
+			Kind: workloadmeta.KindContainer,
+			ID:   "foobar",
+		},
+		EntityMeta: workloadmeta.EntityMeta{
+			Name: "api",
+		},
+	})
+
+	collectorCh := make(chan []*types.TagInfo, 10)
+	cfg := configmock.New(t)
+	collector := NewWorkloadMetaCollector(context.Background(), cfg, store, &fakeProcessor{collectorCh})
+	require.Empty(t, collector.taggerRules)
+
+	cfg.SetWithoutSource("tagger_rules", `[{"kind": "container", "tags": ["service:{name}"]}]`)
+	<-collector.taggerRulesUpdates
+	collector.reloadTaggerRules()
+
+	source := buildTaggerRulesSource(workloadmeta.KindContainer)
+	entityID := types.NewEntityID(types.ContainerID, "foobar")
+	findRulesTagInfo := func(tagInfos []*types.TagInfo) *types.TagInfo {
+		for _, tagInfo := range tagInfos {
+			if tagInfo.Source == source && tagInfo.EntityID == entityID {
+				return tagInfo
+			}
+		}
+		return nil
+	}
+
+	tagInfo := findRulesTagInfo(<-collectorCh)
+	require.NotNil(t, tagInfo)
+	assert.Equal(t, []string{"service:api"}, tagInfo.LowCardTags)
+
+	// removing the rules removes the tags they added
+	cfg.SetWithoutSource("tagger_rules", "[]")
+	<-collector.taggerRulesUpdates
+	collector.reloadTaggerRules()
+
+	tagInfo = findRulesTagInfo(<-collectorCh)
+	require.NotNil(t, tagInfo)
+	assert.True(t, tagInfo.DeleteEntity)
+}
@@ -171,6 +171,7 @@ func (c *WorkloadMetaCollector) processEvents(evBundle workloadmeta.EventBundle)
 			// left
 			source := buildTaggerSource(entityID)
 			tagInfos = append(tagInfos, c.handleDeleteChildren(source, unseen)...)
+			tagInfos = append(tagInfos, c.handleDeleteTaggerRules(entityID.Kind, unseen)...)
 
 		case workloadmeta.EventTypeUnset:
 			tagInfos = append(tagInfos, c.handleDelete(ev)...)
@@ -245,7 +246,7 @@ func (c *WorkloadMetaCollector) handleContainer(ev workloadmeta.Event) []*types.
 	}
 
 	low, orch, high, standard := tagList.Compute()
-	return []*types.TagInfo{
+	tagInfos := []*types.TagInfo{
 		{
 			Source:               containerSource,
 			EntityID:             common.BuildTaggerEntityID(container.EntityID),
@@ -255,6 +256,8 @@ func (c *WorkloadMetaCollector) handleContainer(ev workloadmeta.Event) []*types.
 			StandardTags:         standard,
 		},
 	}
+
+	return append(tagInfos, c.handleTaggerRules(workloadmeta.KindContainer, containerRuleFields(container), taggerEntityIDs(container.EntityID))...)
 }
 
 func (c *WorkloadMetaCollector) handleContainerImage(ev workloadmeta.Event) []*types.TagInfo {
@@ -440,6 +443,9 @@ func (c *WorkloadMetaCollector) handleKubePod(ev workloadmeta.Event) []*types.Ta
 
 	c.extractTagsFromPodLabels(pod, tagList)
 
+	// the tags of the pod rules apply to the pod and its containers
+	ruleEntityIDs := taggerEntityIDs(pod.EntityID)
+
 	for _, podContainer := range pod.GetAllContainers() {
 		cTagInfo, err := c.extractTagsFromPodContainer(pod, podContainer, tagList.Copy())
 		if err != nil {
@@ -448,9 +454,10 @@ func (c *WorkloadMetaCollector) handleKubePod(ev workloadmeta.Event) []*types.Ta
 		}
 
 		tagInfos = append(tagInfos, cTagInfo)
+		ruleEntityIDs = append(ruleEntityIDs, cTagInfo.EntityID)
 	}
 
-	return tagInfos
+	return append(tagInfos, c.handleTaggerRules(workloadmeta.KindKubernetesPod, c.podRuleFields(pod), ruleEntityIDs)...)
 }
 
 func (c *WorkloadMetaCollector) handleECSTask(ev workloadmeta.Event) []*types.TagInfo {
@@ -804,6 +811,12 @@ func (c *WorkloadMetaCollector) handleDelete(ev workloadmeta.Event) []*types.Tag
 	})
 	tagInfos = append(tagInfos, c.handleDeleteChildren(source, children)...)
 
+	ruleEntityIDs := map[types.EntityID]struct{}{taggerEntityID: {}}
+	for childEntityID := range children {
+		ruleEntityIDs[childEntityID] = struct{}{}
+	}
+	tagInfos = append(tagInfos, c.handleDeleteTaggerRules(entityID.Kind, ruleEntityIDs)...)
+
 	delete(c.children, taggerEntityID)
 
 	return tagInfos
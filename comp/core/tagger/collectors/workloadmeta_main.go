@@ -67,6 +67,11 @@ type WorkloadMetaCollector struct {
 
 	collectEC2ResourceTags            bool
 	collectPersistentVolumeClaimsTags bool
+
+	config             config.Component
+	taggerRules        []*taggerRule
+	taggerRulesEnabled bool
+	taggerRulesUpdates chan struct{}
 }
 
 func (c *WorkloadMetaCollector) initContainerMetaAsTags(labelsAsTags, envAsTags map[string]string) {
@@ -162,6 +167,9 @@ func (c *WorkloadMetaCollector) stream(ctx context.Context) {
 
 			c.processEvents(evBundle)
 
+		case <-c.taggerRulesUpdates:
+			c.reloadTaggerRules()
+
 		case <-health.C:
 
 		case <-ctx.Done():
@@ -180,6 +188,8 @@ func NewWorkloadMetaCollector(_ context.Context, cfg config.Component, store wor
 		children:                          make(map[types.EntityID]map[types.EntityID]struct{}),
 		collectEC2ResourceTags:            cfg.GetBool("ecs_collect_resource_tags_ec2"),
 		collectPersistentVolumeClaimsTags: cfg.GetBool("kubernetes_persistent_volume_claims_as_tags"),
+		config:                            cfg,
+		taggerRulesUpdates:                make(chan struct{}, 1),
 	}
 
 	containerLabelsAsTags := mergeMaps(
@@ -197,6 +207,18 @@ func NewWorkloadMetaCollector(_ context.Context, cfg config.Component, store wor
 	metadataAsTags := configutils.GetMetadataAsTags(cfg)
 	c.initK8sResourcesMetaAsTags(metadataAsTags.GetResourcesLabelsAsTags(), metadataAsTags.GetResourcesAnnotationsAsTags())
 
+	// tag-derivation rules, reloaded when the setting is updated at runtime
+	c.setTaggerRules(loadTaggerRules(cfg))
+	cfg.OnUpdate(func(setting string, _, _ any) {
+		if setting != taggerRulesConfigKey {
+			return
+		}
+		select {
+		case c.taggerRulesUpdates <- struct{}{}:
+		default:
+		}
+	})
+
 	return c
 }
 
@@ -227,4 +249,7 @@ func init() {
 	CollectorPriorities[taskSource] = types.NodeOrchestrator
 	CollectorPriorities[containerSource] = types.NodeRuntime
 	CollectorPriorities[containerImageSource] = types.NodeRuntime
+	// tags derived by the tagger rules of the user take precedence
+	CollectorPriorities[buildTaggerRulesSource(workloadmeta.KindContainer)] = types.ClusterOrchestrator
+	CollectorPriorities[buildTaggerRulesSource(workloadmeta.KindKubernetesPod)] = types.ClusterOrchestrator
 }
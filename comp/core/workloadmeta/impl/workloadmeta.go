@@ -112,7 +112,7 @@ func NewWorkloadMeta(deps Dependencies) Provider {
 
 	return Provider{
 		Comp:          wm,
-		FlareProvider: flaretypes.NewProvider(wm.sbomFlareProvider),
+		FlareProvider: flaretypes.NewNamedProvider("workloadmeta", wm.sbomFlareProvider),
 		Endpoint:      api.NewAgentEndpointProvider(wm.writeResponse, "/workload-list", "GET"),
 	}
 }
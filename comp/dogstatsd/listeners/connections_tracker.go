@@ -51,6 +51,8 @@ func (t *ConnectionTracker) Start() {
 
 // Track tracks a connection.
 func (t *ConnectionTracker) Track(conn net.Conn) {
+	// the connection is counted before being handled, for Stop to wait for it
+	t.activeConnections.Inc()
 	t.connToTrack <- conn
 }
 
@@ -70,9 +72,9 @@ func (t *ConnectionTracker) HandleConnections() {
 			if requestStop {
 				//Close it immediately if we are shutting down.
 				conn.Close()
+				t.activeConnections.Dec()
 			} else {
 				t.connections[conn] = struct{}{}
-				t.activeConnections.Inc()
 			}
 		case conn := <-t.connToClose:
 			if err := conn.Close(); err != nil {
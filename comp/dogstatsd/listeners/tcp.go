@@ -0,0 +1,315 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/def"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	tcpExpvars             = expvar.NewMap("dogstatsd-tcp")
+	tcpPacketReadingErrors = expvar.Int{}
+	tcpPackets             = expvar.Int{}
+	tcpBytes               = expvar.Int{}
+	tcpRejectedConnections = expvar.Int{}
+)
+
+func init() {
+	tcpExpvars.Set("PacketReadingErrors", &tcpPacketReadingErrors)
+	tcpExpvars.Set("Packets", &tcpPackets)
+	tcpExpvars.Set("Bytes", &tcpBytes)
+	tcpExpvars.Set("RejectedConnections", &tcpRejectedConnections)
+}
+
+const (
+	transportTCP = "tcp"
+	transportTLS = "tls"
+
+	tlsHandshakeTimeout = 10 * time.Second
+)
+
+// TCPListener implements the StatsdListener interface for newline-delimited
+// messages over TCP, optionally secured with TLS. The messages of all the
+// connections are assembled in the same packets.
+// Origin detection is not implemented for TCP.
+type TCPListener struct {
+	listener        net.Listener
+	transport       string
+	connTracker     *ConnectionTracker
+	maxConnections  int32
+	connections     *atomic.Int32
+	packetsBuffer   *packets.Buffer
+	packetAssembler *packets.Assembler
+	bufferSize      int
+	trafficCapture  replay.Component // Currently ignored
+	listenWg        sync.WaitGroup
+	connectionsWg   sync.WaitGroup
+
+	telemetryWithListenerID bool
+	telemetryStore          *TelemetryStore
+}
+
+// NewTCPListener returns an idle TCP Statsd listener
+func NewTCPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, capture replay.Component, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*TCPListener, error) {
+	listener, err := net.Listen("tcp", tcpListenAddress(cfg, cfg.GetString("dogstatsd_tcp_port")))
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+	return newTCPListener(listener, transportTCP, packetOut, sharedPacketPoolManager, cfg, capture, telemetryStore, packetsTelemetryStore), nil
+}
+
+// NewTLSListener returns an idle TLS Statsd listener. Clients must present a
+// certificate signed by the configured CA when one is set.
+func NewTLSListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, capture replay.Component, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*TCPListener, error) {
+	tlsConfig, err := tlsConfigFromAgentConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := tls.Listen("tcp", tcpListenAddress(cfg, cfg.GetString("dogstatsd_tls_port")), tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+	return newTCPListener(listener, transportTLS, packetOut, sharedPacketPoolManager, cfg, capture, telemetryStore, packetsTelemetryStore), nil
+}
+
+func tcpListenAddress(cfg model.Reader, port string) string {
+	if port == RandomPortName {
+		port = "0"
+	}
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		return ":" + port
+	}
+	return net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+}
+
+func tlsConfigFromAgentConfig(cfg model.Reader) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.GetString("dogstatsd_tls_cert_file"), cfg.GetString("dogstatsd_tls_key_file"))
+	if err != nil {
+		return nil, fmt.Errorf("can't load the TLS certificate: %s", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if caFile := cfg.GetString("dogstatsd_tls_client_ca_file"); caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read the TLS client CA: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in the TLS client CA %s", caFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+func newTCPListener(listener net.Listener, transport string, packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, capture replay.Component, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) *TCPListener {
+	packetsBufferSize := cfg.GetInt("dogstatsd_packet_buffer_size")
+	flushTimeout := cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout")
+
+	packetsBuffer := packets.NewBuffer(uint(packetsBufferSize), flushTimeout, packetOut, transport, packetsTelemetryStore)
+	packetAssembler := packets.NewAssembler(flushTimeout, packetsBuffer, sharedPacketPoolManager, packets.TCP)
+
+	l := &TCPListener{
+		listener:                listener,
+		transport:               transport,
+		connTracker:             NewConnectionTracker(transport, 1*time.Second),
+		maxConnections:          int32(cfg.GetInt("dogstatsd_tcp_max_connections")),
+		connections:             atomic.NewInt32(0),
+		packetsBuffer:           packetsBuffer,
+		packetAssembler:         packetAssembler,
+		bufferSize:              cfg.GetInt("dogstatsd_buffer_size"),
+		trafficCapture:          capture,
+		telemetryWithListenerID: cfg.GetBool("dogstatsd_telemetry_enabled_listener_id"),
+		telemetryStore:          telemetryStore,
+	}
+	log.Debugf("dogstatsd-%s: %s successfully initialized", transport, listener.Addr())
+	return l
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *TCPListener) LocalAddr() string {
+	return l.listener.Addr().String()
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *TCPListener) Listen() {
+	l.listenWg.Add(1)
+	go func() {
+		defer l.listenWg.Done()
+		l.listen()
+	}()
+}
+
+func (l *TCPListener) listen() {
+	l.connTracker.Start()
+	log.Infof("dogstatsd-%s: starting to listen on %s", l.transport, l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Errorf("dogstatsd-%s: error accepting connection: %v", l.transport, err)
+			}
+			return
+		}
+
+		if l.maxConnections > 0 && l.connections.Load() >= l.maxConnections {
+			log.Debugf("dogstatsd-%s: connection limit of %d reached, rejecting %s", l.transport, l.maxConnections, conn.RemoteAddr())
+			tcpRejectedConnections.Add(1)
+			l.telemetryStore.tlmTCPRejectedConnections.Inc(l.transport, "limit")
+			_ = conn.Close()
+			continue
+		}
+		l.connections.Inc()
+		l.connTracker.Track(conn)
+
+		l.connectionsWg.Add(1)
+		go func() {
+			defer l.connectionsWg.Done()
+			defer l.connections.Dec()
+			l.handleConnection(conn)
+			l.connTracker.Close(conn)
+		}()
+	}
+}
+
+// handleConnection reads the messages of a connection until it is closed. Only
+// complete messages are assembled, the messages longer than the buffer are dropped.
+func (l *TCPListener) handleConnection(conn net.Conn) {
+	listenerID := l.transport
+	if l.telemetryWithListenerID {
+		listenerID = l.transport + "-" + conn.RemoteAddr().String()
+	}
+	l.telemetryStore.tlmTCPConnections.Inc(listenerID, l.transport)
+	defer func() {
+		if l.telemetryWithListenerID {
+			l.clearTelemetry(listenerID)
+		} else {
+			l.telemetryStore.tlmTCPConnections.Dec(listenerID, l.transport)
+		}
+	}()
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		// the handshake is bounded for idle clients not to hold a connection
+		ctx, cancel := context.WithTimeout(context.Background(), tlsHandshakeTimeout)
+		err := tlsConn.HandshakeContext(ctx)
+		cancel()
+		if err != nil {
+			log.Debugf("dogstatsd-%s: TLS handshake with %s failed: %v", l.transport, conn.RemoteAddr(), err)
+			l.telemetryStore.tlmTCPRejectedConnections.Inc(l.transport, "handshake")
+			return
+		}
+	}
+	log.Debugf("dogstatsd-%s: starting to handle %s", l.transport, conn.RemoteAddr())
+
+	buffer := make([]byte, l.bufferSize)
+	start := 0
+	// discarding is set while the end of a message longer than the buffer is read
+	discarding := false
+	t1 := time.Now()
+	for {
+		n, err := conn.Read(buffer[start:])
+		t2 := time.Now()
+		l.telemetryStore.tlmListener.Observe(float64(t2.Sub(t1).Nanoseconds()), listenerID, l.transport, l.transport)
+
+		data := buffer[:start+n]
+		if discarding {
+			if i := bytes.IndexByte(data, '\n'); i >= 0 {
+				// the end of the long message is dropped
+				data = data[i+1:]
+				discarding = false
+			} else {
+				data = data[:0]
+			}
+		}
+		if last := bytes.LastIndexByte(data, '\n'); last >= 0 {
+			l.addMessages(data[:last], listenerID)
+			data = data[last+1:]
+		}
+		if len(data) == len(buffer) {
+			log.Debugf("dogstatsd-%s: message from %s longer than %d bytes, dropping it", l.transport, conn.RemoteAddr(), len(buffer))
+			l.telemetryStore.tlmTCPPackets.Inc(listenerID, l.transport, "error")
+			discarding = true
+			data = data[:0]
+		}
+		start = copy(buffer, data)
+		t1 = time.Now()
+
+		if err != nil {
+			if err == io.EOF || errors.Is(err, net.ErrClosed) || strings.HasSuffix(err.Error(), "connection reset by peer") {
+				// the last message may not be terminated
+				if !discarding {
+					l.addMessages(buffer[:start], listenerID)
+				}
+				log.Debugf("dogstatsd-%s: %s connection closed", l.transport, conn.RemoteAddr())
+			} else {
+				log.Errorf("dogstatsd-%s: error reading packet: %v", l.transport, err)
+				tcpPacketReadingErrors.Add(1)
+				l.telemetryStore.tlmTCPPackets.Inc(listenerID, l.transport, "error")
+			}
+			return
+		}
+	}
+}
+
+// addMessages adds newline-separated messages to the packets.
+func (l *TCPListener) addMessages(messages []byte, listenerID string) {
+	if len(messages) == 0 {
+		return
+	}
+	tcpPackets.Add(1)
+	tcpBytes.Add(int64(len(messages)))
+	l.telemetryStore.tlmTCPPackets.Inc(listenerID, l.transport, "ok")
+	l.telemetryStore.tlmTCPPacketsBytes.Add(float64(len(messages)), listenerID, l.transport)
+
+	// packetAssembler merges the messages of the connections and sends them when its buffer is full
+	l.packetAssembler.AddMessage(messages)
+}
+
+func (l *TCPListener) clearTelemetry(id string) {
+	// Since the listener id is volatile we need to make sure we clear the telemetry.
+	l.telemetryStore.tlmListener.Delete(id, l.transport, l.transport)
+	l.telemetryStore.tlmTCPConnections.Delete(id, l.transport)
+	l.telemetryStore.tlmTCPPackets.Delete(id, l.transport, "error")
+	l.telemetryStore.tlmTCPPackets.Delete(id, l.transport, "ok")
+	l.telemetryStore.tlmTCPPacketsBytes.Delete(id, l.transport)
+}
+
+// Stop closes the listener and the connections, and stops listening
+func (l *TCPListener) Stop() {
+	_ = l.listener.Close()
+	// no connection is tracked anymore once the accept loop has returned
+	l.listenWg.Wait()
+	l.connTracker.Stop()
+	l.connectionsWg.Wait()
+	l.packetAssembler.Close()
+	l.packetsBuffer.Close()
+}
@@ -0,0 +1,245 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/telemetry"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+)
+
+func newTestTCPListener(t *testing.T, cfg map[string]interface{}, packetChannel chan packets.Packets, newListener func(chan packets.Packets, *packets.PoolManager[packets.Packet], *listenerDeps, *TelemetryStore, *packets.TelemetryStore) (*TCPListener, error)) (*TCPListener, listenerDeps) {
+	deps := fulfillDepsWithConfig(t, cfg)
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	s, err := newListener(packetChannel, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), &deps, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	s.Listen()
+	t.Cleanup(s.Stop)
+	return s, deps
+}
+
+func tcpListenerFactory(packetChannel chan packets.Packets, poolManager *packets.PoolManager[packets.Packet], deps *listenerDeps, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*TCPListener, error) {
+	return NewTCPListener(packetChannel, poolManager, deps.Config, nil, telemetryStore, packetsTelemetryStore)
+}
+
+func tlsListenerFactory(packetChannel chan packets.Packets, poolManager *packets.PoolManager[packets.Packet], deps *listenerDeps, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*TCPListener, error) {
+	return NewTLSListener(packetChannel, poolManager, deps.Config, nil, telemetryStore, packetsTelemetryStore)
+}
+
+// receiveMessages reads the packets until the expected number of messages is received
+func receiveMessages(t *testing.T, packetChannel chan packets.Packets, expected int) []string {
+	var messages []string
+	for len(messages) < expected {
+		select {
+		case pkts := <-packetChannel:
+			for _, packet := range pkts {
+				assert.Equal(t, packets.TCP, packet.Source)
+				messages = append(messages, strings.Split(string(packet.Contents), "\n")...)
+			}
+		case <-time.After(2 * time.Second):
+			require.FailNow(t, "Timeout on receive channel", "received %v", messages)
+		}
+	}
+	return messages
+}
+
+func TestNewTCPListener(t *testing.T) {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{"dogstatsd_tcp_port": "__random__"})
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	s, err := NewTCPListener(nil, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, nil, telemetryStore, packetsTelemetryStore)
+
+	assert.NotNil(t, s)
+	assert.Nil(t, err)
+
+	s.Stop()
+}
+
+func TestTCPReceive(t *testing.T) {
+	packetChannel := make(chan packets.Packets)
+	s, deps := newTestTCPListener(t, map[string]interface{}{"dogstatsd_tcp_port": "__random__"}, packetChannel, tcpListenerFactory)
+
+	conn, err := net.Dial("tcp", s.LocalAddr())
+	require.NoError(t, err)
+	// a message can be split across writes, the last one isn't terminated
+	_, err = conn.Write([]byte("daemon:666|g|#sometag1:somevalue1\ndaemon:"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("667|g\ndaemon:668|g"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	messages := receiveMessages(t, packetChannel, 3)
+	assert.Equal(t, []string{"daemon:666|g|#sometag1:somevalue1", "daemon:667|g", "daemon:668|g"}, messages)
+
+	telemetryMock, ok := deps.Telemetry.(telemetry.Mock)
+	require.True(t, ok)
+	packetsMetrics, err := telemetryMock.GetCountMetric("dogstatsd", "tcp_packets")
+	require.NoError(t, err)
+	require.Len(t, packetsMetrics, 1)
+	assert.Equal(t, "ok", packetsMetrics[0].Tags()["state"])
+	// the messages are counted by read, the writes may be read at once
+	assert.GreaterOrEqual(t, packetsMetrics[0].Value(), float64(1))
+}
+
+func TestTCPDropLongMessages(t *testing.T) {
+	packetChannel := make(chan packets.Packets)
+	s, _ := newTestTCPListener(t, map[string]interface{}{
+		"dogstatsd_tcp_port":    "__random__",
+		"dogstatsd_buffer_size": 16,
+	}, packetChannel, tcpListenerFactory)
+
+	conn, err := net.Dial("tcp", s.LocalAddr())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("a:1|c\n" + strings.Repeat("b", 40) + ":1|c\nc:1|c\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	messages := receiveMessages(t, packetChannel, 2)
+	assert.Equal(t, []string{"a:1|c", "c:1|c"}, messages)
+}
+
+func TestTCPMaxConnections(t *testing.T) {
+	packetChannel := make(chan packets.Packets)
+	s, deps := newTestTCPListener(t, map[string]interface{}{
+		"dogstatsd_tcp_port":            "__random__",
+		"dogstatsd_tcp_max_connections": 1,
+	}, packetChannel, tcpListenerFactory)
+
+	first, err := net.Dial("tcp", s.LocalAddr())
+	require.NoError(t, err)
+	defer first.Close()
+	require.Eventually(t, func() bool { return s.connections.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	// the second connection is closed by the listener
+	second, err := net.Dial("tcp", s.LocalAddr())
+	require.NoError(t, err)
+	defer second.Close()
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = second.Read(make([]byte, 1))
+	assert.Error(t, err)
+
+	telemetryMock, ok := deps.Telemetry.(telemetry.Mock)
+	require.True(t, ok)
+	rejected, err := telemetryMock.GetCountMetric("dogstatsd", "tcp_rejected_connections")
+	require.NoError(t, err)
+	require.Len(t, rejected, 1)
+	assert.Equal(t, "limit", rejected[0].Tags()["reason"])
+
+	// the first connection is still handled
+	_, err = first.Write([]byte("a:1|c\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a:1|c"}, receiveMessages(t, packetChannel, 1))
+}
+
+// writeTestCertificate writes a self-signed certificate and its key, and returns their paths
+func writeTestCertificate(t *testing.T, dir, name string) (string, string, tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	return certFile, keyFile, cert
+}
+
+func TestTLSReceive(t *testing.T) {
+	dir := t.TempDir()
+	serverCertFile, serverKeyFile, serverCert := writeTestCertificate(t, dir, "server")
+	clientCAFile, _, clientCert := writeTestCertificate(t, dir, "client")
+	_, _, otherCert := writeTestCertificate(t, dir, "other")
+
+	packetChannel := make(chan packets.Packets)
+	s, _ := newTestTCPListener(t, map[string]interface{}{
+		"dogstatsd_tls_port":           "__random__",
+		"dogstatsd_tls_cert_file":      serverCertFile,
+		"dogstatsd_tls_key_file":       serverKeyFile,
+		"dogstatsd_tls_client_ca_file": clientCAFile,
+	}, packetChannel, tlsListenerFactory)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCert.Leaf)
+
+	t.Run("valid client certificate", func(t *testing.T) {
+		conn, err := tls.Dial("tcp", s.LocalAddr(), &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}})
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("a:1|c\nb:1|c\n"))
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+
+		assert.Equal(t, []string{"a:1|c", "b:1|c"}, receiveMessages(t, packetChannel, 2))
+	})
+
+	for name, certificates := range map[string][]tls.Certificate{
+		"no client certificate":      nil,
+		"unknown client certificate": {otherCert},
+	} {
+		t.Run(name, func(t *testing.T) {
+			conn, err := tls.Dial("tcp", s.LocalAddr(), &tls.Config{RootCAs: roots, Certificates: certificates})
+			if err == nil {
+				// with TLS 1.3 the client learns about the rejection on its first read
+				defer conn.Close()
+				require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+				_, err = conn.Read(make([]byte, 1))
+			}
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewTLSListenerInvalidCertificate(t *testing.T) {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{
+		"dogstatsd_tls_port":      "__random__",
+		"dogstatsd_tls_cert_file": filepath.Join(t.TempDir(), "missing.crt"),
+		"dogstatsd_tls_key_file":  filepath.Join(t.TempDir(), "missing.key"),
+	})
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	s, err := NewTLSListener(nil, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, nil, telemetryStore, packetsTelemetryStore)
+	assert.Nil(t, s)
+	assert.Error(t, err)
+}
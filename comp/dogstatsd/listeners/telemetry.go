@@ -23,6 +23,11 @@ type TelemetryStore struct {
 	tlmUDSOriginDetectionError telemetry.Counter
 	tlmUDSPacketsBytes         telemetry.Counter
 	tlmUDSConnections          telemetry.Gauge
+	// TCP
+	tlmTCPPackets             telemetry.Counter
+	tlmTCPPacketsBytes        telemetry.Counter
+	tlmTCPConnections         telemetry.Gauge
+	tlmTCPRejectedConnections telemetry.Counter
 
 	tlmListener telemetry.Histogram
 }
@@ -46,6 +51,14 @@ func NewTelemetryStore(buckets []float64, telemetrycomp telemetry.Component) *Te
 			[]string{"listener_id", "transport"}, "Dogstatsd UDS packets bytes"),
 		tlmUDSConnections: telemetrycomp.NewGauge("dogstatsd", "uds_connections",
 			[]string{"listener_id", "transport"}, "Dogstatsd UDS connections count"),
+		tlmTCPPackets: telemetrycomp.NewCounter("dogstatsd", "tcp_packets",
+			[]string{"listener_id", "transport", "state"}, "Dogstatsd TCP packets count"),
+		tlmTCPPacketsBytes: telemetrycomp.NewCounter("dogstatsd", "tcp_packets_bytes",
+			[]string{"listener_id", "transport"}, "Dogstatsd TCP packets bytes"),
+		tlmTCPConnections: telemetrycomp.NewGauge("dogstatsd", "tcp_connections",
+			[]string{"listener_id", "transport"}, "Dogstatsd TCP connections count"),
+		tlmTCPRejectedConnections: telemetrycomp.NewCounter("dogstatsd", "tcp_rejected_connections",
+			[]string{"transport", "reason"}, "Dogstatsd TCP connections rejected by the connection limit or the TLS handshake"),
 		tlmListener: telemetrycomp.NewHistogram(
 			"dogstatsd",
 			"listener_read_latency",
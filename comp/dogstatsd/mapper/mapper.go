@@ -11,6 +11,8 @@ package mapper
 import (
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -21,8 +23,39 @@ var (
 const (
 	matchTypeWildcard = "wildcard"
 	matchTypeRegex    = "regex"
+
+	actionMap  = "map"
+	actionDrop = "drop"
 )
 
+// Metric types that can be matched with `match_metric_type` or set with `metric_type`.
+const (
+	MetricTypeGauge        = "gauge"
+	MetricTypeCount        = "count"
+	MetricTypeHistogram    = "histogram"
+	MetricTypeDistribution = "distribution"
+	MetricTypeSet          = "set"
+	MetricTypeTiming       = "timing"
+)
+
+// matchableMetricTypes are the metric types a mapping can be restricted to.
+var matchableMetricTypes = map[string]bool{
+	MetricTypeGauge:        true,
+	MetricTypeCount:        true,
+	MetricTypeHistogram:    true,
+	MetricTypeDistribution: true,
+	MetricTypeSet:          true,
+	MetricTypeTiming:       true,
+}
+
+// overridableMetricTypes are the metric types a mapping can convert a metric to.
+var overridableMetricTypes = map[string]bool{
+	MetricTypeGauge:        true,
+	MetricTypeCount:        true,
+	MetricTypeHistogram:    true,
+	MetricTypeDistribution: true,
+}
+
 //
 // Those two structs are used to pull data from the configuration into typed struct. We currently load the data from the
 // configuration into MappingProfileConfig and then convert it to MappingProfile.
@@ -40,10 +73,15 @@ type MappingProfileConfig struct {
 
 // MetricMapping represent one mapping rule
 type MetricMappingConfig struct {
-	Match     string            `mapstructure:"match" json:"match" yaml:"match"`
-	MatchType string            `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
-	Name      string            `mapstructure:"name" json:"name" yaml:"name"`
-	Tags      map[string]string `mapstructure:"tags" json:"tags" yaml:"tags"`
+	Match           string            `mapstructure:"match" json:"match" yaml:"match"`
+	MatchType       string            `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
+	MatchMetricType string            `mapstructure:"match_metric_type" json:"match_metric_type" yaml:"match_metric_type"`
+	MatchTags       map[string]string `mapstructure:"match_tags" json:"match_tags" yaml:"match_tags"`
+	Action          string            `mapstructure:"action" json:"action" yaml:"action"`
+	Name            string            `mapstructure:"name" json:"name" yaml:"name"`
+	Tags            map[string]string `mapstructure:"tags" json:"tags" yaml:"tags"`
+	MetricType      string            `mapstructure:"metric_type" json:"metric_type" yaml:"metric_type"`
+	Buckets         []float64         `mapstructure:"buckets" json:"buckets" yaml:"buckets"`
 }
 
 // MetricMapper contains mappings and cache instance
@@ -57,22 +95,64 @@ type MappingProfile struct {
 	Name     string
 	Prefix   string
 	Mappings []*MetricMapping
+
+	// matchTagKeys are the sorted tag keys used by the mappings of the profile, they
+	// are part of the cache key since the outcome of the mapping depends on them.
+	matchTagKeys []string
+	// matchMetricType is true when at least one mapping of the profile is restricted
+	// to a metric type, the metric type is then part of the cache key.
+	matchMetricType bool
 }
 
 // MetricMapping represent one mapping rule
 type MetricMapping struct {
-	name  string
-	tags  map[string]string
-	regex *regexp.Regexp
+	pattern         string
+	matchMetricType string
+	matchTags       map[string]string
+	drop            bool
+	name            string
+	tags            map[string]string
+	metricType      string
+	buckets         []float64
+	regex           *regexp.Regexp
 }
 
 // MapResult represent the outcome of the mapping
 type MapResult struct {
-	Name    string
-	Tags    []string
+	Name string
+	Tags []string
+	// Drop is true when the metric should be discarded.
+	Drop bool
+	// MetricType is the type the metric should be converted to, empty if it should be kept as is.
+	MetricType string
+	// Buckets are the upper bounds of the buckets the values of the metric should be counted in.
+	Buckets []float64
 	matched bool
 }
 
+// DryRunResult describes which profile and mapping a metric hits, it is meant to help
+// troubleshooting mapping configurations.
+type DryRunResult struct {
+	// Profile is the name of the profile whose prefix matched the metric, empty if none did.
+	Profile string `json:"profile,omitempty"`
+	// ProfileIndex is the position of the profile in the configuration, -1 if none matched.
+	ProfileIndex int `json:"profile_index"`
+	// MappingIndex is the position of the mapping in the profile, -1 if none matched.
+	MappingIndex int `json:"mapping_index"`
+	// Match is the pattern of the mapping that matched.
+	Match string `json:"match,omitempty"`
+	// Action is either "map" or "drop" when a mapping matched.
+	Action string `json:"action,omitempty"`
+	// Name is the name the metric is mapped to.
+	Name string `json:"name,omitempty"`
+	// Tags are the tags added to the metric.
+	Tags []string `json:"tags,omitempty"`
+	// MetricType is the type the metric is converted to.
+	MetricType string `json:"metric_type,omitempty"`
+	// Buckets are the bucket upper bounds associated with the metric.
+	Buckets []float64 `json:"buckets,omitempty"`
+}
+
 // NewMetricMapper creates, validates, prepares a new MetricMapper
 func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*MetricMapper, error) {
 	profiles := make([]MappingProfile, 0, len(configProfiles))
@@ -88,26 +168,24 @@ func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*Met
 			Prefix:   configProfile.Prefix,
 			Mappings: make([]*MetricMapping, 0, len(configProfile.Mappings)),
 		}
+		tagKeys := map[string]struct{}{}
 		for i, currentMapping := range configProfile.Mappings {
-			matchType := currentMapping.MatchType
-			if matchType == "" {
-				matchType = matchTypeWildcard
-			}
-			if matchType != matchTypeWildcard && matchType != matchTypeRegex {
-				return nil, fmt.Errorf("profile: %s, mapping num %d: invalid match type, must be `wildcard` or `regex`", profile.Name, i)
-			}
-			if currentMapping.Name == "" {
-				return nil, fmt.Errorf("profile: %s, mapping num %d: name is required", profile.Name, i)
+			mapping, err := newMetricMapping(currentMapping)
+			if err != nil {
+				return nil, fmt.Errorf("profile: %s, mapping num %d: %v", profile.Name, i, err)
 			}
-			if currentMapping.Match == "" {
-				return nil, fmt.Errorf("profile: %s, mapping num %d: match is required", profile.Name, i)
+			for key := range mapping.matchTags {
+				tagKeys[key] = struct{}{}
 			}
-			regex, err := buildRegex(currentMapping.Match, matchType)
-			if err != nil {
-				return nil, err
+			if mapping.matchMetricType != "" {
+				profile.matchMetricType = true
 			}
-			profile.Mappings = append(profile.Mappings, &MetricMapping{name: currentMapping.Name, tags: currentMapping.Tags, regex: regex})
+			profile.Mappings = append(profile.Mappings, mapping)
 		}
+		for key := range tagKeys {
+			profile.matchTagKeys = append(profile.matchTagKeys, key)
+		}
+		sort.Strings(profile.matchTagKeys)
 		profiles = append(profiles, profile)
 	}
 	cache, err := newMapperCache(cacheSize)
@@ -117,6 +195,60 @@ func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*Met
 	return &MetricMapper{Profiles: profiles, cache: cache}, nil
 }
 
+func newMetricMapping(config MetricMappingConfig) (*MetricMapping, error) {
+	matchType := config.MatchType
+	if matchType == "" {
+		matchType = matchTypeWildcard
+	}
+	if matchType != matchTypeWildcard && matchType != matchTypeRegex {
+		return nil, fmt.Errorf("invalid match type, must be `wildcard` or `regex`")
+	}
+	action := config.Action
+	if action == "" {
+		action = actionMap
+	}
+	if action != actionMap && action != actionDrop {
+		return nil, fmt.Errorf("invalid action `%s`, must be `map` or `drop`", action)
+	}
+	if action == actionMap && config.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if config.Match == "" {
+		return nil, fmt.Errorf("match is required")
+	}
+	if config.MatchMetricType != "" && !matchableMetricTypes[config.MatchMetricType] {
+		return nil, fmt.Errorf("invalid match_metric_type `%s`", config.MatchMetricType)
+	}
+	if config.MetricType != "" && !overridableMetricTypes[config.MetricType] {
+		return nil, fmt.Errorf("invalid metric_type `%s`, must be one of `gauge`, `count`, `histogram` or `distribution`", config.MetricType)
+	}
+	if len(config.Buckets) > 0 {
+		if config.MetricType != MetricTypeHistogram && config.MetricType != MetricTypeDistribution {
+			return nil, fmt.Errorf("buckets can only be set when metric_type is `histogram` or `distribution`")
+		}
+		for i := 1; i < len(config.Buckets); i++ {
+			if config.Buckets[i] <= config.Buckets[i-1] {
+				return nil, fmt.Errorf("buckets must be sorted in increasing order")
+			}
+		}
+	}
+	regex, err := buildRegex(config.Match, matchType)
+	if err != nil {
+		return nil, err
+	}
+	return &MetricMapping{
+		pattern:         config.Match,
+		matchMetricType: config.MatchMetricType,
+		matchTags:       config.MatchTags,
+		drop:            action == actionDrop,
+		name:            config.Name,
+		tags:            config.Tags,
+		metricType:      config.MetricType,
+		buckets:         config.Buckets,
+		regex:           regex,
+	}, nil
+}
+
 func buildRegex(matchRe string, matchType string) (*regexp.Regexp, error) {
 	if matchType == matchTypeWildcard {
 		if !allowedWildcardMatchPattern.MatchString(matchRe) {
@@ -137,43 +269,160 @@ func buildRegex(matchRe string, matchType string) (*regexp.Regexp, error) {
 
 // Map returns a MapResult
 func (m *MetricMapper) Map(metricName string) *MapResult {
-	for _, profile := range m.Profiles {
-		if !strings.HasPrefix(metricName, profile.Prefix) && profile.Prefix != "*" {
+	return m.MapSample(metricName, "", nil)
+}
+
+// MapSample returns a MapResult for a metric of the given type and tags, nil if no mapping matched.
+// Only the first profile whose prefix matches the metric name is considered.
+func (m *MetricMapper) MapSample(metricName string, metricType string, tags []string) *MapResult {
+	for profileIndex := range m.Profiles {
+		profile := &m.Profiles[profileIndex]
+		if !profile.matchesPrefix(metricName) {
 			continue
 		}
-		result, cached := m.cache.get(metricName)
+		cacheKey := profile.cacheKey(profileIndex, metricName, metricType, tags)
+		result, cached := m.cache.get(cacheKey)
 		if cached {
 			if result.matched {
 				return result
 			}
 			return nil
 		}
-		for _, mapping := range profile.Mappings {
-			matches := mapping.regex.FindStringSubmatchIndex(metricName)
-			if len(matches) == 0 {
+		if mapping, matches := profile.find(metricName, metricType, tags); mapping != nil {
+			mapResult := mapping.result(metricName, matches)
+			m.cache.add(cacheKey, mapResult)
+			return mapResult
+		}
+		m.cache.add(cacheKey, &MapResult{matched: false})
+		return nil
+	}
+	return nil
+}
+
+// DryRun reports which profile and mapping the given metric hits, without using the cache.
+func (m *MetricMapper) DryRun(metricName string, metricType string, tags []string) DryRunResult {
+	dryRun := DryRunResult{ProfileIndex: -1, MappingIndex: -1}
+	for profileIndex := range m.Profiles {
+		profile := &m.Profiles[profileIndex]
+		if !profile.matchesPrefix(metricName) {
+			continue
+		}
+		dryRun.Profile = profile.Name
+		dryRun.ProfileIndex = profileIndex
+		for mappingIndex, mapping := range profile.Mappings {
+			matches := mapping.match(metricName, metricType, tags)
+			if matches == nil {
 				continue
 			}
-
-			name := string(mapping.regex.ExpandString(
-				[]byte{},
-				mapping.name,
-				metricName,
-				matches,
-			))
-
-			tags := make([]string, 0, len(mapping.tags))
-			for tagKey, tagValueExpr := range mapping.tags {
-				tagValue := string(mapping.regex.ExpandString([]byte{}, tagValueExpr, metricName, matches))
-				tags = append(tags, tagKey+":"+tagValue)
+			result := mapping.result(metricName, matches)
+			dryRun.MappingIndex = mappingIndex
+			dryRun.Match = mapping.pattern
+			dryRun.Action = actionMap
+			if result.Drop {
+				dryRun.Action = actionDrop
 			}
+			dryRun.Name = result.Name
+			dryRun.Tags = result.Tags
+			dryRun.MetricType = result.MetricType
+			dryRun.Buckets = result.Buckets
+			break
+		}
+		return dryRun
+	}
+	return dryRun
+}
 
-			mapResult := &MapResult{Name: name, matched: true, Tags: tags}
-			m.cache.add(metricName, mapResult)
-			return mapResult
+func (p *MappingProfile) matchesPrefix(metricName string) bool {
+	return p.Prefix == "*" || strings.HasPrefix(metricName, p.Prefix)
+}
+
+// cacheKey builds the key under which the outcome of the mapping is cached. Negative matches
+// are cached per profile as well, so they are not evaluated again for every sample.
+func (p *MappingProfile) cacheKey(profileIndex int, metricName string, metricType string, tags []string) string {
+	if !p.matchMetricType && len(p.matchTagKeys) == 0 {
+		return strconv.Itoa(profileIndex) + "|" + metricName
+	}
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(profileIndex))
+	b.WriteByte('|')
+	b.WriteString(metricName)
+	if p.matchMetricType {
+		b.WriteByte('|')
+		b.WriteString(metricType)
+	}
+	for _, key := range p.matchTagKeys {
+		b.WriteByte('|')
+		if value, ok := tagValue(tags, key); ok {
+			b.WriteString(key)
+			b.WriteByte(':')
+			b.WriteString(value)
+		}
+	}
+	return b.String()
+}
+
+// find returns the first mapping of the profile matching the metric along with the regex submatches.
+func (p *MappingProfile) find(metricName string, metricType string, tags []string) (*MetricMapping, []int) {
+	for _, mapping := range p.Mappings {
+		if matches := mapping.match(metricName, metricType, tags); matches != nil {
+			return mapping, matches
+		}
+	}
+	return nil, nil
+}
+
+// match returns the regex submatches if the metric matches the mapping, nil otherwise.
+func (mapping *MetricMapping) match(metricName string, metricType string, tags []string) []int {
+	if mapping.matchMetricType != "" && mapping.matchMetricType != metricType {
+		return nil
+	}
+	for key, expected := range mapping.matchTags {
+		value, ok := tagValue(tags, key)
+		if !ok || (expected != "" && expected != "*" && expected != value) {
+			return nil
 		}
-		mapResult := &MapResult{matched: false}
-		m.cache.add(metricName, mapResult)
+	}
+	matches := mapping.regex.FindStringSubmatchIndex(metricName)
+	if len(matches) == 0 {
 		return nil
 	}
-	return nil
+	return matches
+}
+
+func (mapping *MetricMapping) result(metricName string, matches []int) *MapResult {
+	if mapping.drop {
+		return &MapResult{Drop: true, matched: true}
+	}
+
+	name := string(mapping.regex.ExpandString(
+		[]byte{},
+		mapping.name,
+		metricName,
+		matches,
+	))
+
+	tags := make([]string, 0, len(mapping.tags))
+	for tagKey, tagValueExpr := range mapping.tags {
+		tagValue := string(mapping.regex.ExpandString([]byte{}, tagValueExpr, metricName, matches))
+		tags = append(tags, tagKey+":"+tagValue)
+	}
+
+	return &MapResult{Name: name, matched: true, Tags: tags, MetricType: mapping.metricType, Buckets: mapping.buckets}
+}
+
+// tagValue returns the value of the first tag with the given key. Tags without a value
+// are considered to have an empty one.
+func tagValue(tags []string, key string) (string, bool) {
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, key) {
+			continue
+		}
+		if len(tag) == len(key) {
+			return "", true
+		}
+		if tag[len(key)] == ':' {
+			return tag[len(key)+1:], true
+		}
+	}
+	return "", false
 }
@@ -329,6 +329,55 @@ dogstatsd_mapper_profiles:
 				{Name: "foo.bar1.duration", Tags: []string{"bar:bar", "foo:foo_name"}, matched: true},
 			},
 		},
+		{
+			name: "Drop action",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.debug.*"
+        action: drop
+      - match: "test.job.*"
+        name: "test.job"
+        tags:
+          job: "$1"
+`,
+			packets: []string{
+				"test.debug.foo",
+				"test.job.foo",
+			},
+			expectedResults: []MapResult{
+				{Drop: true, matched: true},
+				{Name: "test.job", Tags: []string{"job:foo"}, matched: true},
+			},
+		},
+		{
+			name: "Metric type override and buckets",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.request.*.latency"
+        name: "test.request.latency"
+        metric_type: distribution
+        tags:
+          endpoint: "$1"
+      - match: "test.request.*.size"
+        name: "test.request.size"
+        metric_type: histogram
+        buckets: [100, 1000, 10000]
+`,
+			packets: []string{
+				"test.request.login.latency",
+				"test.request.login.size",
+			},
+			expectedResults: []MapResult{
+				{Name: "test.request.latency", Tags: []string{"endpoint:login"}, MetricType: "distribution", matched: true},
+				{Name: "test.request.size", Tags: []string{}, MetricType: "histogram", Buckets: []float64{100, 1000, 10000}, matched: true},
+			},
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -504,6 +553,72 @@ dogstatsd_mapper_profiles:
 			},
 			expectedError: "missing prefix for profile",
 		},
+		{
+			name: "Invalid action",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.*"
+        action: rename
+        name: "test.job"
+`,
+			expectedError: "invalid action",
+		},
+		{
+			name: "Invalid metric type",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.*"
+        name: "test.job"
+        metric_type: set
+`,
+			expectedError: "invalid metric_type",
+		},
+		{
+			name: "Invalid match metric type",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.*"
+        name: "test.job"
+        match_metric_type: timer
+`,
+			expectedError: "invalid match_metric_type",
+		},
+		{
+			name: "Buckets without histogram type",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.*"
+        name: "test.job"
+        buckets: [1, 2]
+`,
+			expectedError: "buckets can only be set",
+		},
+		{
+			name: "Unsorted buckets",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.*"
+        name: "test.job"
+        metric_type: histogram
+        buckets: [2, 1]
+`,
+			expectedError: "buckets must be sorted",
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -515,6 +630,101 @@ dogstatsd_mapper_profiles:
 	}
 }
 
+func TestMapSample(t *testing.T) {
+	mapper, err := getMapper(t, `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.*.duration"
+        match_metric_type: timing
+        name: "test.duration"
+        metric_type: distribution
+        tags:
+          job: "$1"
+      - match: "test.*.count"
+        match_tags:
+          env: prod
+        name: "test.prod.count"
+      - match: "test.*.count"
+        match_tags:
+          team: "*"
+        name: "test.team.count"
+`)
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name       string
+		metricType string
+		tags       []string
+		expected   *MapResult
+	}{
+		{"test.job.duration", MetricTypeTiming, nil, &MapResult{Name: "test.duration", Tags: []string{"job:job"}, MetricType: "distribution", matched: true}},
+		{"test.job.duration", MetricTypeGauge, nil, nil},
+		{"test.job.count", MetricTypeCount, []string{"env:prod"}, &MapResult{Name: "test.prod.count", Tags: []string{}, matched: true}},
+		{"test.job.count", MetricTypeCount, []string{"env:staging"}, nil},
+		{"test.job.count", MetricTypeCount, []string{"env:staging", "team:metrics"}, &MapResult{Name: "test.team.count", Tags: []string{}, matched: true}},
+		{"test.job.count", MetricTypeCount, []string{"team"}, &MapResult{Name: "test.team.count", Tags: []string{}, matched: true}},
+		{"test.job.count", MetricTypeCount, []string{"teams:metrics"}, nil},
+	} {
+		// run twice to go through the cache
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, tt.expected, mapper.MapSample(tt.name, tt.metricType, tt.tags), "%s %s %v", tt.name, tt.metricType, tt.tags)
+		}
+	}
+}
+
+func TestMapSampleNegativeCachePerProfile(t *testing.T) {
+	mapper, err := getMapper(t, `
+dogstatsd_mapper_profiles:
+  - name: first
+    prefix: 'foo.'
+    mappings:
+      - match: "foo.*.bar"
+        name: "foo.bar"
+  - name: second
+    prefix: '*'
+    mappings:
+      - match: "*.baz"
+        name: "baz"
+`)
+	require.NoError(t, err)
+
+	assert.Nil(t, mapper.MapSample("foo.other", "", nil))
+	result, found := mapper.cache.get("0|foo.other")
+	assert.True(t, found)
+	assert.False(t, result.matched)
+
+	assert.Nil(t, mapper.MapSample("other.metric", "", nil))
+	result, found = mapper.cache.get("1|other.metric")
+	assert.True(t, found)
+	assert.False(t, result.matched)
+
+	assert.Equal(t, &MapResult{Name: "baz", Tags: []string{}, matched: true}, mapper.MapSample("other.baz", "", nil))
+}
+
+func TestDryRun(t *testing.T) {
+	mapper, err := getMapper(t, `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.debug.*"
+        action: drop
+      - match: "test.job.*"
+        name: "test.job"
+        tags:
+          job: "$1"
+`)
+	require.NoError(t, err)
+
+	assert.Equal(t, DryRunResult{ProfileIndex: -1, MappingIndex: -1}, mapper.DryRun("other.metric", "", nil))
+	assert.Equal(t, DryRunResult{Profile: "test", ProfileIndex: 0, MappingIndex: -1}, mapper.DryRun("test.other", "", nil))
+	assert.Equal(t, DryRunResult{Profile: "test", ProfileIndex: 0, MappingIndex: 0, Match: "test.debug.*", Action: "drop"}, mapper.DryRun("test.debug.foo", "", nil))
+	assert.Equal(t, DryRunResult{Profile: "test", ProfileIndex: 0, MappingIndex: 1, Match: "test.job.*", Action: "map", Name: "test.job", Tags: []string{"job:foo"}}, mapper.DryRun("test.job.foo", "", nil))
+	assert.Equal(t, 0, mapper.cache.cache.Len())
+}
+
 func getMapper(t *testing.T, configString string) (*MetricMapper, error) {
 	var profiles []MappingProfileConfig
 
@@ -21,6 +21,8 @@ const (
 	UDS
 	// NamedPipe Windows named pipe listner
 	NamedPipe
+	// TCP listener, with or without TLS
+	TCP
 )
 
 // Packet represents a statsd packet ready to process,
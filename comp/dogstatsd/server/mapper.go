@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+)
+
+const (
+	// bucketMetricSuffix is appended to the name of a mapped metric to report its bucket counts.
+	bucketMetricSuffix = ".bucket"
+	// bucketTagPrefix is the tag holding the upper bound of a bucket.
+	bucketTagPrefix = "upper_bound:"
+)
+
+// toMapperMetricType returns the name of a dogstatsd metric type as understood by the mapper.
+func toMapperMetricType(t metricType) string {
+	switch t {
+	case gaugeType:
+		return mapper.MetricTypeGauge
+	case countType:
+		return mapper.MetricTypeCount
+	case distributionType:
+		return mapper.MetricTypeDistribution
+	case histogramType:
+		return mapper.MetricTypeHistogram
+	case setType:
+		return mapper.MetricTypeSet
+	case timingType:
+		return mapper.MetricTypeTiming
+	}
+	return ""
+}
+
+// fromMapperMetricType returns the dogstatsd metric type matching a mapper metric type name.
+func fromMapperMetricType(t string) (metricType, bool) {
+	switch t {
+	case mapper.MetricTypeGauge:
+		return gaugeType, true
+	case mapper.MetricTypeCount:
+		return countType, true
+	case mapper.MetricTypeDistribution:
+		return distributionType, true
+	case mapper.MetricTypeHistogram:
+		return histogramType, true
+	case mapper.MetricTypeSet:
+		return setType, true
+	case mapper.MetricTypeTiming:
+		return timingType, true
+	}
+	return 0, false
+}
+
+// appendBucketSamples appends, for every given sample, a count sample named after it with the
+// ".bucket" suffix and tagged with the upper bound of the first bucket the value falls in.
+func appendBucketSamples(dest []metrics.MetricSample, samples []metrics.MetricSample, buckets []float64) []metrics.MetricSample {
+	for idx := range samples {
+		bucket := samples[idx]
+		upperBound := "inf"
+		for _, bound := range buckets {
+			if bucket.Value <= bound {
+				upperBound = strconv.FormatFloat(bound, 'f', -1, 64)
+				break
+			}
+		}
+		tags := make([]string, 0, len(bucket.Tags)+1)
+		tags = append(tags, bucket.Tags...)
+		bucket.Tags = append(tags, bucketTagPrefix+upperBound)
+		bucket.Name += bucketMetricSuffix
+		bucket.Mtype = metrics.CounterType
+		bucket.Value = 1
+		bucket.RawValue = ""
+		dest = append(dest, bucket)
+	}
+	return dest
+}
+
+// writeMapperDryRun reports which mapper profile and mapping the metric given in the `name`
+// query parameter hits. The metric type and tags can optionally be given with the `type`
+// and `tags` (comma separated) query parameters.
+func (s *server) writeMapperDryRun(w http.ResponseWriter, r *http.Request) {
+	if s.mapper == nil {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := json.Marshal(map[string]string{
+			"error":      "No dogstatsd mapper profile is configured",
+			"error_type": "not enabled",
+		})
+		w.WriteHeader(400)
+		w.Write(body)
+		return
+	}
+
+	query := r.URL.Query()
+	name := query.Get("name")
+	if name == "" {
+		httputils.SetJSONError(w, fmt.Errorf("missing `name` query parameter"), 400)
+		return
+	}
+
+	var mapperType string
+	if rawType := query.Get("type"); rawType != "" {
+		if t, err := parseMetricSampleMetricType([]byte(rawType)); err == nil {
+			mapperType = toMapperMetricType(t)
+		} else if _, ok := fromMapperMetricType(rawType); ok {
+			mapperType = rawType
+		} else {
+			httputils.SetJSONError(w, fmt.Errorf("invalid metric type %q", rawType), 400)
+			return
+		}
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(query.Get("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	body, err := json.Marshal(s.mapper.DryRun(name, mapperType, tags))
+	if err != nil {
+		httputils.SetJSONError(w, s.log.Errorf("Error marshalling the dogstatsd mapper dry-run result: %s", err), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
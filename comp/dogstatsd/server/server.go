@@ -53,6 +53,7 @@ var (
 	dogstatsdMetricPackets            = expvar.Int{}
 	dogstatsdPacketsLastSec           = expvar.Int{}
 	dogstatsdUnterminatedMetricErrors = expvar.Int{}
+	dogstatsdMetricMapperDropped      = expvar.Int{}
 
 	// while we try to add the origin tag in the tlmProcessed metric, we want to
 	// avoid having it growing indefinitely, hence this safeguard to limit the
@@ -84,8 +85,9 @@ type dependencies struct {
 type provides struct {
 	fx.Out
 
-	Comp          Component
-	StatsEndpoint api.AgentEndpointProvider
+	Comp           Component
+	StatsEndpoint  api.AgentEndpointProvider
+	MapperEndpoint api.AgentEndpointProvider
 }
 
 // When the internal telemetry is enabled, used to tag the origin
@@ -179,6 +181,7 @@ func initTelemetry() {
 	dogstatsdExpvars.Set("MetricParseErrors", &dogstatsdMetricParseErrors)
 	dogstatsdExpvars.Set("MetricPackets", &dogstatsdMetricPackets)
 	dogstatsdExpvars.Set("UnterminatedMetricErrors", &dogstatsdUnterminatedMetricErrors)
+	dogstatsdExpvars.Set("MetricMapperDropped", &dogstatsdMetricMapperDropped)
 }
 
 // TODO: (components) - merge with newServerCompat once NewServerlessServer is removed
@@ -193,8 +196,9 @@ func newServer(deps dependencies) provides {
 	}
 
 	return provides{
-		Comp:          s,
-		StatsEndpoint: api.NewAgentEndpointProvider(s.writeStats, "/dogstatsd-stats", "GET"),
+		Comp:           s,
+		StatsEndpoint:  api.NewAgentEndpointProvider(s.writeStats, "/dogstatsd-stats", "GET"),
+		MapperEndpoint: api.NewAgentEndpointProvider(s.writeMapperDryRun, "/dogstatsd-mapper", "GET"),
 	}
 }
 
@@ -397,6 +401,24 @@ func (s *server) start(context.Context) error {
 		}
 	}
 
+	if s.config.GetString("dogstatsd_tcp_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_tcp_port") > 0 {
+		tcpListener, err := listeners.NewTCPListener(packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("tcp error: %v", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tcpListener)
+		}
+	}
+
+	if s.config.GetString("dogstatsd_tls_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_tls_port") > 0 {
+		tlsListener, err := listeners.NewTLSListener(packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("tls error: %v", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tlsListener)
+		}
+	}
+
 	pipeName := s.config.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
 		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry, s.telemetry)
@@ -750,15 +772,29 @@ func (s *server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 		return metricSamples, err
 	}
 
+	var buckets []float64
 	if s.mapper != nil {
-		mapResult := s.mapper.Map(sample.name)
+		mapResult := s.mapper.MapSample(sample.name, toMapperMetricType(sample.metricType), sample.tags)
+		if mapResult != nil && mapResult.Drop {
+			s.log.Tracef("Dogstatsd mapper: metric %q dropped", sample.name)
+			dogstatsdMetricMapperDropped.Add(1)
+			if len(sample.values) > 0 {
+				s.sharedFloat64List.put(sample.values)
+			}
+			return metricSamples, nil
+		}
 		if mapResult != nil {
 			s.log.Tracef("Dogstatsd mapper: metric mapped from %q to %q with tags %v", sample.name, mapResult.Name, mapResult.Tags)
 			sample.name = mapResult.Name
 			sample.tags = append(sample.tags, mapResult.Tags...)
+			if t, ok := fromMapperMetricType(mapResult.MetricType); ok && sample.metricType != setType {
+				sample.metricType = t
+			}
+			buckets = mapResult.Buckets
 		}
 	}
 
+	metricSamplesStart := len(metricSamples)
 	metricSamples = enrichMetricSample(metricSamples, sample, origin, processID, listenerID, s.enrichConfig)
 
 	if len(sample.values) > 0 {
@@ -776,6 +812,10 @@ func (s *server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 		dogstatsdMetricPackets.Add(1)
 		okCnt.Inc()
 	}
+
+	if len(buckets) > 0 {
+		metricSamples = appendBucketSamples(metricSamples, metricSamples[metricSamplesStart:], buckets)
+	}
 	return metricSamples, nil
 }
 
@@ -7,6 +7,7 @@
 package server
 
 import (
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -351,6 +352,78 @@ dogstatsd_mapper_profiles:
 			expectedSamples:   nil,
 			expectedCacheSize: 999,
 		},
+		{
+			name: "Drop action",
+			config: `
+dogstatsd_port: __random__
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.debug.*"
+        action: drop
+`,
+			packets: [][]byte{
+				[]byte("test.debug.foo:666|g"),
+				[]byte("test.debug.bar:666:667|g"),
+				[]byte("test.job:666|g"),
+			},
+			expectedSamples: []*tMetricSample{
+				defaultMetric().withName("test.job").withTags(nil),
+			},
+			expectedCacheSize: 1000,
+		},
+		{
+			name: "Metric type override",
+			config: `
+dogstatsd_port: __random__
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.*.duration"
+        match_metric_type: timing
+        name: "test.duration"
+        metric_type: distribution
+        tags:
+          job: "$1"
+`,
+			packets: [][]byte{
+				[]byte("test.job.duration:666|ms"),
+				[]byte("test.job.duration:666|g"),
+			},
+			expectedSamples: []*tMetricSample{
+				defaultMetric().withName("test.duration").withType(metrics.DistributionType).withTags([]string{"job:job"}),
+				defaultMetric().withName("test.job.duration").withTags(nil),
+			},
+			expectedCacheSize: 1000,
+		},
+		{
+			name: "Buckets",
+			config: `
+dogstatsd_port: __random__
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.*.size"
+        name: "test.size"
+        metric_type: histogram
+        buckets: [100, 1000]
+        tags:
+          job: "$1"
+`,
+			packets: [][]byte{
+				[]byte("test.job.size:666:5000|h|#env:prod"),
+			},
+			expectedSamples: []*tMetricSample{
+				defaultMetric().withName("test.size").withType(metrics.HistogramType).withTags([]string{"env:prod", "job:job"}),
+				defaultMetric().withName("test.size").withType(metrics.HistogramType).withValue(5000).withTags([]string{"env:prod", "job:job"}),
+				defaultMetric().withName("test.size.bucket").withType(metrics.CounterType).withValue(1).withTags([]string{"env:prod", "job:job", "upper_bound:1000"}),
+				defaultMetric().withName("test.size.bucket").withType(metrics.CounterType).withValue(1).withTags([]string{"env:prod", "job:job", "upper_bound:inf"}),
+			},
+			expectedCacheSize: 1000,
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -367,6 +440,7 @@ dogstatsd_mapper_profiles:
 			var b batcherMock
 			s.parsePackets(&b, parser, genTestPackets(scenario.packets...), metrics.MetricSampleBatch{})
 
+			require.Len(t, b.samples, len(scenario.expectedSamples))
 			for idx, sample := range b.samples {
 				scenario.expectedSamples[idx].testMetric(t, sample)
 			}
@@ -374,6 +448,45 @@ dogstatsd_mapper_profiles:
 	}
 }
 
+func TestMapperDryRunEndpoint(t *testing.T) {
+	deps := fulfillDepsWithConfigYaml(t, `
+dogstatsd_port: __random__
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.*.duration"
+        match_metric_type: timing
+        name: "test.duration"
+        tags:
+          job: "$1"
+`)
+	s := deps.Server.(*server)
+	requireStart(t, s)
+
+	for _, tt := range []struct {
+		query        string
+		expectedCode int
+		expectedBody string
+	}{
+		{"name=test.job.duration&type=ms", 200, `{"profile":"test","profile_index":0,"mapping_index":0,"match":"test.*.duration","action":"map","name":"test.duration","tags":["job:job"]}`},
+		{"name=test.job.duration&type=timing", 200, `{"profile":"test","profile_index":0,"mapping_index":0,"match":"test.*.duration","action":"map","name":"test.duration","tags":["job:job"]}`},
+		{"name=test.job.duration&type=g", 200, `{"profile":"test","profile_index":0,"mapping_index":-1}`},
+		{"name=other.metric", 200, `{"profile_index":-1,"mapping_index":-1}`},
+		{"name=test.job.duration&type=invalid", 400, ""},
+		{"", 400, ""},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.writeMapperDryRun(w, httptest.NewRequest("GET", "/dogstatsd-mapper?"+tt.query, nil))
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestParseEventMessageTelemetry(t *testing.T) {
 	cfg := make(map[string]interface{})
 
@@ -171,7 +171,7 @@ func newLogsAgent(deps dependencies) provides {
 		return provides{
 			Comp:           option.New[agent.Component](logsAgent),
 			StatusProvider: statusComponent.NewInformationProvider(NewStatusProvider()),
-			FlareProvider:  flaretypes.NewProvider(logsAgent.flarecontroller.FillFlare),
+			FlareProvider:  flaretypes.NewNamedProvider("logs", logsAgent.flarecontroller.FillFlare),
 			RCListener:     rcListener,
 			LogsReciever:   option.New[integrations.Component](integrationsLogs),
 		}
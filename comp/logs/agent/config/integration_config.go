@@ -25,6 +25,7 @@ const (
 	IntegrationType   = "integration"
 	WindowsEventType  = "windows_event"
 	StringChannelType = "string_channel"
+	SyslogType        = "syslog"
 
 	// UTF16BE for UTF-16 Big endian encoding
 	UTF16BE string = "utf-16-be"
@@ -45,6 +46,10 @@ type LogsConfig struct {
 	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout"` // Network
 	Path        string // File, Journald
 
+	Protocol    string `mapstructure:"protocol" json:"protocol"`           // Syslog
+	TLSCertFile string `mapstructure:"tls_cert_file" json:"tls_cert_file"` // Syslog
+	TLSKeyFile  string `mapstructure:"tls_key_file" json:"tls_key_file"`   // Syslog
+
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
 	ExcludePaths []string `mapstructure:"exclude_paths" json:"exclude_paths"`   // File
 	TailingMode  string   `mapstructure:"start_position" json:"start_position"` // File
@@ -117,6 +122,12 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case UDPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+	case SyslogType:
+		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
+		fmt.Fprintf(&b, ws("Protocol: %#v,"), c.Protocol)
+		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("TLSCertFile: %#v,"), c.TLSCertFile)
+		fmt.Fprintf(&b, ws("TLSKeyFile: %#v,"), c.TLSKeyFile)
 	case FileType:
 		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
 		fmt.Fprintf(&b, ws("Encoding: %#v,"), c.Encoding)
@@ -173,6 +184,7 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Type            string            `json:"type,omitempty"`
 		Port            int               `json:"port,omitempty"`           // Network
+		Protocol        string            `json:"protocol,omitempty"`       // Syslog
 		Path            string            `json:"path,omitempty"`           // File, Journald
 		Encoding        string            `json:"encoding,omitempty"`       // File
 		ExcludePaths    []string          `json:"exclude_paths,omitempty"`  // File
@@ -186,6 +198,7 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 	}{
 		Type:            c.Type,
 		Port:            c.Port,
+		Protocol:        c.Protocol,
 		Path:            c.Path,
 		Encoding:        c.Encoding,
 		ExcludePaths:    c.ExcludePaths,
@@ -260,6 +273,10 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == SyslogType:
+		if err := c.validateSyslog(); err != nil {
+			return err
+		}
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -268,6 +285,30 @@ func (c *LogsConfig) Validate() error {
 	return CompileProcessingRules(c.ProcessingRules)
 }
 
+func (c *LogsConfig) validateSyslog() error {
+	if c.Port == 0 {
+		return fmt.Errorf("syslog source must have a port")
+	}
+	if c.Protocol != "" && c.Protocol != TCPType && c.Protocol != UDPType {
+		return fmt.Errorf("invalid protocol '%v' for syslog source, must be tcp or udp", c.Protocol)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("syslog source must have both a tls_cert_file and a tls_key_file to use TLS")
+	}
+	if c.TLSCertFile != "" && c.SyslogProtocol() != TCPType {
+		return fmt.Errorf("TLS is only supported by syslog sources using tcp")
+	}
+	return nil
+}
+
+// SyslogProtocol returns the transport protocol of a syslog source, tcp by default.
+func (c *LogsConfig) SyslogProtocol() string {
+	if c.Protocol == "" {
+		return TCPType
+	}
+	return c.Protocol
+}
+
 func (c *LogsConfig) validateTailingMode() error {
 	mode, found := TailingModeFromString(c.TailingMode)
 	if !found && c.TailingMode != "" {
@@ -71,7 +71,7 @@ func NewComponent(reqs Requires) (Provides, error) {
 	}
 
 	provides := Provides{
-		FlareProvider: flaretypes.NewProviderWithTimeout(sl.fillFlare, sl.getFlareTimeout),
+		FlareProvider: flaretypes.NewNamedProviderWithTimeout("logs", sl.fillFlare, sl.getFlareTimeout),
 	}
 	return provides, nil
 }
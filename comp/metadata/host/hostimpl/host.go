@@ -111,7 +111,7 @@ func newHostProvider(deps dependencies) provides {
 	return provides{
 		Comp:             &h,
 		MetadataProvider: runnerimpl.NewProvider(h.collect),
-		FlareProvider:    flaretypes.NewProvider(h.fillFlare),
+		FlareProvider:    flaretypes.NewNamedProvider("metadata", h.fillFlare),
 		StatusHeaderProvider: status.NewHeaderInformationProvider(StatusProvider{
 			Config: h.config,
 		}),
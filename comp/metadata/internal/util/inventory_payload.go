@@ -133,7 +133,7 @@ func CreateInventoryPayload(conf config.Component, l log.Component, s serializer
 
 // FlareProvider returns a flare providers to add the current inventory payload to each flares.
 func (i *InventoryPayload) FlareProvider() flaretypes.Provider {
-	return flaretypes.NewProvider(i.fillFlare)
+	return flaretypes.NewNamedProvider("metadata", i.fillFlare)
 }
 
 // MetadataProvider returns a metadata 'runner.Provider' for the current inventory payload (taking into account if
@@ -29,6 +29,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/metadata/runner/runnerimpl"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
+	"github.com/DataDog/datadog-agent/pkg/collector/runner/expvars"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
@@ -205,11 +206,18 @@ func (ic *inventorychecksImpl) getPayload(withConfigs bool) marshaler.JSONMarsha
 
 	if coll, isSet := ic.coll.Get(); isSet {
 		foundInCollector := map[string]struct{}{}
+		checkStats := expvars.GetCheckStats()
 
 		coll.MapOverChecks(func(checks []check.Info) {
 			for _, c := range checks {
 				cm := check.GetMetadata(c, withConfigs)
 
+				if stats, found := checkStats[checkid.IDToCheckName(c.ID())][c.ID()]; found {
+					cm["run.total_timeouts"] = stats.TotalTimeouts
+					cm["run.consecutive_failures"] = stats.ConsecutiveFailures
+					cm["run.circuit_breaker_open_until"] = stats.CircuitBreakerOpenUntil
+				}
+
 				if checkData, found := ic.data[string(c.ID())]; found {
 					for key, val := range checkData.metadata {
 						cm[key] = val
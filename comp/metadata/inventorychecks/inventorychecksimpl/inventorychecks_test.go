@@ -9,6 +9,7 @@ import (
 	"expvar"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/fx"
@@ -30,6 +31,9 @@ import (
 	logscompression "github.com/DataDog/datadog-agent/comp/serializer/logscompression/fx-mock"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
+	checkstats "github.com/DataDog/datadog-agent/pkg/collector/check/stats"
+	"github.com/DataDog/datadog-agent/pkg/collector/check/stub"
+	"github.com/DataDog/datadog-agent/pkg/collector/runner/expvars"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	serializermock "github.com/DataDog/datadog-agent/pkg/serializer/mocks"
@@ -257,6 +261,33 @@ func TestGetPayload(t *testing.T) {
 	}
 }
 
+func TestGetPayloadRunState(t *testing.T) {
+	stubCheck := &stub.StubCheck{}
+	mockColl := fxutil.Test[collector.Component](t,
+		fx.Replace(collectorimpl.MockParams{
+			ChecksInfo: []check.Info{check.MockInfo{Name: stubCheck.String(), CheckID: stubCheck.ID()}},
+		}),
+		collectorimpl.MockModule(),
+		core.MockBundle(),
+		workloadmetafxmock.MockModule(workloadmeta.NewParams()),
+	)
+	ic := getTestInventoryChecks(t, option.New[collector.Component](mockColl), option.None[logagent.Component](), nil)
+
+	// no run yet
+	p := ic.getPayloadWithConfigs().(*Payload)
+	assert.NotContains(t, p.Metadata[stubCheck.String()][0], "run.total_timeouts")
+
+	expvars.AddCheckStats(stubCheck, time.Second, &check.RunTimeoutError{Duration: time.Second}, nil, checkstats.NewSenderStats(), nil)
+	defer expvars.RemoveCheckStats(stubCheck.ID())
+
+	p = ic.getPayloadWithConfigs().(*Payload)
+	instance := p.Metadata[stubCheck.String()][0]
+	assert.Equal(t, uint64(1), instance["run.total_timeouts"])
+	assert.Equal(t, uint64(1), instance["run.consecutive_failures"])
+	// the circuit breaker is disabled by default
+	assert.Equal(t, int64(0), instance["run.circuit_breaker_open_until"])
+}
+
 func TestFlareProviderFilename(t *testing.T) {
 	ic := getTestInventoryChecks(
 		t, option.None[collector.Component](), option.Option[logagent.Component]{}, nil,
@@ -157,7 +157,7 @@ func NewComponent(reqs Requires) (Provides, error) {
 	}
 	return Provides{
 		Comp:           collector,
-		FlareProvider:  flaretypes.NewProviderWithTimeout(collector.fillFlare, timeoutCallback),
+		FlareProvider:  flaretypes.NewNamedProviderWithTimeout("otel", collector.fillFlare, timeoutCallback),
 		StatusProvider: status.NewInformationProvider(collector),
 	}, nil
 }
@@ -113,7 +113,7 @@ func newProcessAgent(deps dependencies) (provides, error) {
 		return provides{
 			Comp:           processAgentComponent,
 			StatusProvider: statusComponent.NewInformationProvider(agent.NewStatusProvider(deps.Config)),
-			FlareProvider:  flaretypes.NewProvider(processAgentComponent.flarehelper.FillFlare),
+			FlareProvider:  flaretypes.NewNamedProvider("process", processAgentComponent.flarehelper.FillFlare),
 		}, nil
 	}
 
@@ -20,11 +20,18 @@ import (
 )
 
 const (
-	defaultPort        = uint16(9162) // Standard UDP port for traps.
+	defaultPort        = uint16(9162)  // Standard UDP port for traps.
+	defaultTLSPort     = uint16(10162) // Standard TLS and DTLS port for traps (RFC 6353).
 	defaultStopTimeout = 5
 	packetsChanSize    = 100
 )
 
+// Transports of the Transport Security Model.
+const (
+	TransportTLS  = "tls"
+	TransportDTLS = "dtls"
+)
+
 // UserV3 contains the definition of one SNMPv3 user with its username and its auth
 // parameters.
 type UserV3 struct {
@@ -36,17 +43,30 @@ type UserV3 struct {
 	PrivProtocol   string `mapstructure:"privProtocol" yaml:"privProtocol"`
 }
 
+// TLSConfig contains the configuration of the TLS and DTLS transports, used by devices
+// sending traps and informs with the Transport Security Model (RFC 5591, RFC 6353).
+type TLSConfig struct {
+	Enabled    bool         `mapstructure:"enabled" yaml:"enabled"`
+	Port       uint16       `mapstructure:"port" yaml:"port"`
+	Transports []string     `mapstructure:"transports" yaml:"transports"`
+	CertFile   string       `mapstructure:"cert_file" yaml:"cert_file"`
+	KeyFile    string       `mapstructure:"key_file" yaml:"key_file"`
+	CAFile     string       `mapstructure:"ca_file" yaml:"ca_file"`
+	CertToUser []CertToUser `mapstructure:"cert_to_user" yaml:"cert_to_user"`
+}
+
 // TrapsConfig contains configuration for SNMP trap listeners.
 // YAML field tags provided for test marshalling purposes.
 type TrapsConfig struct {
-	Enabled               bool     `mapstructure:"enabled" yaml:"enabled"`
-	Port                  uint16   `mapstructure:"port" yaml:"port"`
-	Users                 []UserV3 `mapstructure:"users" yaml:"users"`
-	CommunityStrings      []string `mapstructure:"community_strings" yaml:"community_strings"`
-	BindHost              string   `mapstructure:"bind_host" yaml:"bind_host"`
-	StopTimeout           int      `mapstructure:"stop_timeout" yaml:"stop_timeout"`
-	Namespace             string   `mapstructure:"namespace" yaml:"namespace"`
-	authoritativeEngineID string   `mapstructure:"-" yaml:"-"`
+	Enabled               bool      `mapstructure:"enabled" yaml:"enabled"`
+	Port                  uint16    `mapstructure:"port" yaml:"port"`
+	Users                 []UserV3  `mapstructure:"users" yaml:"users"`
+	CommunityStrings      []string  `mapstructure:"community_strings" yaml:"community_strings"`
+	BindHost              string    `mapstructure:"bind_host" yaml:"bind_host"`
+	StopTimeout           int       `mapstructure:"stop_timeout" yaml:"stop_timeout"`
+	Namespace             string    `mapstructure:"namespace" yaml:"namespace"`
+	TLS                   TLSConfig `mapstructure:"tls" yaml:"tls"`
+	authoritativeEngineID string    `mapstructure:"-" yaml:"-"`
 }
 
 // ReadConfig builds the traps configuration from the Agent configuration.
@@ -98,6 +118,12 @@ func (c *TrapsConfig) SetDefaults(host string, namespace string) error {
 		return fmt.Errorf("invalid config: %w", err)
 	}
 
+	if c.TLS.Enabled {
+		if err := c.TLS.setDefaults(); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -106,6 +132,16 @@ func (c *TrapsConfig) Addr() string {
 	return fmt.Sprintf("%s:%d", c.BindHost, c.Port)
 }
 
+// TLSAddr returns the host:port address the TLS and DTLS transports listen on.
+func (c *TrapsConfig) TLSAddr() string {
+	return fmt.Sprintf("%s:%d", c.BindHost, c.TLS.Port)
+}
+
+// AuthoritativeEngineID returns the SNMPv3 engine ID of the Agent.
+func (c *TrapsConfig) AuthoritativeEngineID() string {
+	return c.authoritativeEngineID
+}
+
 // BuildSNMPParams returns a valid GoSNMP params structure from configuration.
 func (c *TrapsConfig) BuildSNMPParams(logger log.Component) (*gosnmp.GoSNMP, error) {
 	var snmpLogger gosnmp.Logger
@@ -7,6 +7,11 @@ package config
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"net"
 	"strings"
 	"testing"
 
@@ -290,3 +295,98 @@ func TestNamespaceSetBothGloballyAndLocally(t *testing.T) {
 
 	assert.Equal(t, "bar", config.Namespace)
 }
+
+func TestTLSDefaults(t *testing.T) {
+	c := &TrapsConfig{TLS: TLSConfig{
+		Enabled:  true,
+		CertFile: "agent.crt",
+		KeyFile:  "agent.key",
+		CAFile:   "ca.crt",
+		CertToUser: []CertToUser{
+			{Fingerprint: "SHA256:" + strings.Repeat("AB:", 31) + "AB", User: "device"},
+			{MapType: MapSANAny},
+		},
+	}}
+	require.NoError(t, c.SetDefaults("host", "default"))
+	assert.Equal(t, uint16(10162), c.TLS.Port)
+	assert.Equal(t, "0.0.0.0:10162", c.TLSAddr())
+	assert.True(t, c.TLS.HasTransport(TransportTLS))
+	assert.True(t, c.TLS.HasTransport(TransportDTLS))
+	assert.Equal(t, strings.Repeat("ab", 32), c.TLS.CertToUser[0].Fingerprint)
+	assert.Equal(t, MapSpecified, c.TLS.CertToUser[0].MapType)
+}
+
+func TestInvalidTLSConfig(t *testing.T) {
+	valid := func() TLSConfig {
+		return TLSConfig{
+			Enabled:    true,
+			CertFile:   "agent.crt",
+			KeyFile:    "agent.key",
+			CAFile:     "ca.crt",
+			CertToUser: []CertToUser{{User: "device"}},
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(*TLSConfig)
+		err    string
+	}{
+		{"transport", func(c *TLSConfig) { c.Transports = []string{"quic"} }, `unknown TLS transport "quic"`},
+		{"certificates", func(c *TLSConfig) { c.CAFile = "" }, "tls requires cert_file, key_file and ca_file"},
+		{"no mapping", func(c *TLSConfig) { c.CertToUser = nil }, "at least one cert_to_user entry"},
+		{"fingerprint", func(c *TLSConfig) { c.CertToUser[0].Fingerprint = "abcd" }, "invalid cert_to_user fingerprint"},
+		{"user", func(c *TLSConfig) { c.CertToUser[0].User = "" }, "require a user"},
+		{"map type", func(c *TLSConfig) { c.CertToUser[0].MapType = "serial" }, `unknown cert_to_user map_type "serial"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &TrapsConfig{TLS: valid()}
+			test.modify(&c.TLS)
+			err := c.SetDefaults("host", "default")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestTLSSecurityName(t *testing.T) {
+	ca := &x509.Certificate{Raw: []byte("ca")}
+	leaf := &x509.Certificate{
+		Raw:            []byte("leaf"),
+		Subject:        pkix.Name{CommonName: "router-1"},
+		EmailAddresses: []string{"Admin@Example.COM"},
+		DNSNames:       []string{"Router-1.Example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+	}
+	chains := [][]*x509.Certificate{{leaf, ca}}
+	caSum := sha256.Sum256(ca.Raw)
+	caFingerprint := hex.EncodeToString(caSum[:])
+
+	tests := []struct {
+		name     string
+		entries  []CertToUser
+		expected string
+		ok       bool
+	}{
+		{"specified", []CertToUser{{MapType: MapSpecified, User: "device"}}, "device", true},
+		{"rfc822 name", []CertToUser{{MapType: MapSANRFC822Name}}, "Admin@example.com", true},
+		{"dns name", []CertToUser{{MapType: MapSANDNSName}}, "router-1.example.com", true},
+		{"ip address", []CertToUser{{MapType: MapSANIPAddress}}, "10.0.0.1", true},
+		{"any", []CertToUser{{MapType: MapSANAny}}, "Admin@example.com", true},
+		{"common name", []CertToUser{{MapType: MapCommonName}}, "router-1", true},
+		{"issuer fingerprint", []CertToUser{{Fingerprint: caFingerprint, MapType: MapSpecified, User: "device"}}, "device", true},
+		{"first match", []CertToUser{
+			{Fingerprint: strings.Repeat("00", 32), MapType: MapSpecified, User: "other"},
+			{Fingerprint: caFingerprint, MapType: MapCommonName},
+		}, "router-1", true},
+		{"no match", []CertToUser{{Fingerprint: strings.Repeat("00", 32), MapType: MapSpecified, User: "other"}}, "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := TLSConfig{CertToUser: test.entries}
+			name, ok := c.SecurityName(chains)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, name)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Certificate to security name mapping types, following the snmpTlstmCertToTSNTable of RFC 6353.
+const (
+	// MapSpecified maps the certificate to the configured user.
+	MapSpecified = "specified"
+	// MapSANRFC822Name maps the certificate to its first email subject alternative name.
+	MapSANRFC822Name = "san_rfc822_name"
+	// MapSANDNSName maps the certificate to its first DNS subject alternative name.
+	MapSANDNSName = "san_dns_name"
+	// MapSANIPAddress maps the certificate to its first IP address subject alternative name.
+	MapSANIPAddress = "san_ip_address"
+	// MapSANAny maps the certificate to its first email, DNS or IP address subject alternative name.
+	MapSANAny = "san_any"
+	// MapCommonName maps the certificate to its subject common name.
+	MapCommonName = "common_name"
+)
+
+// CertToUser maps the certificates presented by devices over TLS and DTLS to a security name.
+type CertToUser struct {
+	// Fingerprint is the SHA-256 fingerprint, in hexadecimal, of the device certificate or of
+	// one of the certificate authorities that issued it. When empty, the entry matches all the
+	// certificates issued by the configured certificate authorities.
+	Fingerprint string `mapstructure:"fingerprint" yaml:"fingerprint"`
+	// MapType is how the security name is derived from the certificate.
+	MapType string `mapstructure:"map_type" yaml:"map_type"`
+	// User is the security name of the devices when MapType is "specified".
+	User string `mapstructure:"user" yaml:"user"`
+}
+
+func (c *TLSConfig) setDefaults() error {
+	if c.Port == 0 {
+		c.Port = defaultTLSPort
+	}
+	if len(c.Transports) == 0 {
+		c.Transports = []string{TransportTLS, TransportDTLS}
+	}
+	for _, transport := range c.Transports {
+		if transport != TransportTLS && transport != TransportDTLS {
+			return fmt.Errorf("unknown TLS transport %q, expected %q or %q", transport, TransportTLS, TransportDTLS)
+		}
+	}
+	if c.CertFile == "" || c.KeyFile == "" || c.CAFile == "" {
+		return errors.New("tls requires cert_file, key_file and ca_file")
+	}
+	if len(c.CertToUser) == 0 {
+		return errors.New("tls requires at least one cert_to_user entry")
+	}
+	for i := range c.CertToUser {
+		entry := &c.CertToUser[i]
+		entry.Fingerprint = normalizeFingerprint(entry.Fingerprint)
+		if b, err := hex.DecodeString(entry.Fingerprint); err != nil || (len(b) != 0 && len(b) != sha256.Size) {
+			return fmt.Errorf("invalid cert_to_user fingerprint %q: expected a SHA-256 hash", entry.Fingerprint)
+		}
+		if entry.MapType == "" {
+			entry.MapType = MapSpecified
+		}
+		switch entry.MapType {
+		case MapSpecified:
+			if entry.User == "" {
+				return errors.New("cert_to_user entries of type specified require a user")
+			}
+		case MapSANRFC822Name, MapSANDNSName, MapSANIPAddress, MapSANAny, MapCommonName:
+		default:
+			return fmt.Errorf("unknown cert_to_user map_type %q", entry.MapType)
+		}
+	}
+	return nil
+}
+
+// HasTransport returns whether the given TLS transport is enabled.
+func (c *TLSConfig) HasTransport(transport string) bool {
+	return c.Enabled && slices.Contains(c.Transports, transport)
+}
+
+// LoadCertificates returns the certificate of the Agent and the pool of the certificate
+// authorities trusted to issue device certificates.
+func (c *TLSConfig) LoadCertificates() (tls.Certificate, *x509.CertPool, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return cert, nil, fmt.Errorf("could not load the TLS certificate: %w", err)
+	}
+	caPEM, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return cert, nil, fmt.Errorf("could not read the TLS certificate authorities: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return cert, nil, fmt.Errorf("no certificate found in %s", c.CAFile)
+	}
+	return cert, pool, nil
+}
+
+// SecurityName returns the security name of a device from its verified certificate chains,
+// using the first matching cert_to_user entry. It returns false when no entry matches.
+func (c *TLSConfig) SecurityName(chains [][]*x509.Certificate) (string, bool) {
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return "", false
+	}
+	leaf := chains[0][0]
+	for _, entry := range c.CertToUser {
+		if entry.Fingerprint != "" && !chainsContain(chains, entry.Fingerprint) {
+			continue
+		}
+		if name := mapCertificate(leaf, entry); name != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func chainsContain(chains [][]*x509.Certificate, fingerprint string) bool {
+	for _, chain := range chains {
+		for _, cert := range chain {
+			sum := sha256.Sum256(cert.Raw)
+			if hex.EncodeToString(sum[:]) == fingerprint {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func mapCertificate(cert *x509.Certificate, entry CertToUser) string {
+	switch entry.MapType {
+	case MapSpecified:
+		return entry.User
+	case MapSANRFC822Name:
+		return firstRFC822Name(cert)
+	case MapSANDNSName:
+		return firstDNSName(cert)
+	case MapSANIPAddress:
+		return firstIPAddress(cert)
+	case MapSANAny:
+		for _, name := range []string{firstRFC822Name(cert), firstDNSName(cert), firstIPAddress(cert)} {
+			if name != "" {
+				return name
+			}
+		}
+	case MapCommonName:
+		return cert.Subject.CommonName
+	}
+	return ""
+}
+
+func firstRFC822Name(cert *x509.Certificate) string {
+	if len(cert.EmailAddresses) == 0 {
+		return ""
+	}
+	// RFC 6353: the host part of the address is converted to lowercase
+	local, host, ok := strings.Cut(cert.EmailAddresses[0], "@")
+	if !ok {
+		return cert.EmailAddresses[0]
+	}
+	return local + "@" + strings.ToLower(host)
+}
+
+func firstDNSName(cert *x509.Certificate) string {
+	if len(cert.DNSNames) == 0 {
+		return ""
+	}
+	return strings.ToLower(cert.DNSNames[0])
+}
+
+func firstIPAddress(cert *x509.Certificate) string {
+	if len(cert.IPAddresses) == 0 {
+		return ""
+	}
+	return cert.IPAddresses[0].String()
+}
+
+// normalizeFingerprint removes the separators and the hash algorithm prefix a fingerprint
+// may be written with, e.g. "SHA256:AB:CD:...".
+func normalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.TrimPrefix(strings.ToLower(fingerprint), "sha256:")
+	return strings.NewReplacer(":", "", " ", "").Replace(fingerprint)
+}
@@ -12,6 +12,8 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gosnmp/gosnmp"
@@ -28,6 +30,10 @@ import (
 	"github.com/DataDog/datadog-agent/comp/snmptraps/status"
 )
 
+// usmStatsUnknownEngineIDs is the OID of the counter reported to SNMPv3 senders discovering
+// the engine ID of the Agent (RFC 3414).
+const usmStatsUnknownEngineIDs = ".1.3.6.1.6.3.15.1.1.4.0"
+
 // Module defines the fx options for this component.
 func Module() fxutil.Module {
 	return fxutil.Component(
@@ -35,15 +41,22 @@ func Module() fxutil.Module {
 	)
 }
 
-// trapListener opens an UDP socket and put all received traps in a channel
+// trapListener opens an UDP socket, and optionally TLS and DTLS listeners, and put all
+// received traps and informs in a channel. Informs are acknowledged once validated.
 type trapListener struct {
-	config        *config.TrapsConfig
-	sender        sender.Sender
-	packets       packet.PacketsChannel
-	listener      *gosnmp.TrapListener
-	errorsChannel chan error
-	logger        log.Component
-	status        status.Component
+	config    *config.TrapsConfig
+	params    *gosnmp.GoSNMP
+	sender    sender.Sender
+	packets   packet.PacketsChannel
+	conn      *net.UDPConn
+	secure    []*secureTransport
+	logger    log.Component
+	status    status.Component
+	startTime time.Time
+
+	// unknownEngineIDs counts the SNMPv3 messages received with an unknown engine ID.
+	unknownEngineIDs atomic.Uint32
+	wg               sync.WaitGroup
 }
 
 type dependencies struct {
@@ -61,23 +74,19 @@ func newTrapListener(lc fx.Lifecycle, dep dependencies) (listener.Component, err
 		return nil, err
 	}
 	config := dep.Config.Get()
-	gosnmpListener := gosnmp.NewTrapListener()
-	gosnmpListener.Params, err = config.BuildSNMPParams(dep.Logger)
+	params, err := config.BuildSNMPParams(dep.Logger)
 	if err != nil {
 		return nil, err
 	}
-	errorsChan := make(chan error, 1)
 	trapListener := &trapListener{
-		config:        config,
-		sender:        sender,
-		packets:       make(packet.PacketsChannel, config.GetPacketChannelSize()),
-		listener:      gosnmpListener,
-		errorsChannel: errorsChan,
-		logger:        dep.Logger,
-		status:        dep.Status,
+		config:  config,
+		params:  params,
+		sender:  sender,
+		packets: make(packet.PacketsChannel, config.GetPacketChannelSize()),
+		logger:  dep.Logger,
+		status:  dep.Status,
 	}
 
-	gosnmpListener.OnNewTrap = trapListener.receiveTrap
 	if config.Enabled {
 		lc.Append(fx.Hook{
 			OnStart: func(_ context.Context) error {
@@ -99,29 +108,38 @@ func (t *trapListener) Packets() packet.PacketsChannel {
 
 // start the TrapListener instance.
 func (t *trapListener) start() error {
+	t.startTime = time.Now()
 	t.logger.Infof("Start listening for traps on %s", t.config.Addr())
-	go t.run()
-	return t.blockUntilReady()
-}
-
-func (t *trapListener) run() {
-	err := t.listener.Listen(t.config.Addr()) // blocking call
+	addr, err := net.ResolveUDPAddr("udp", t.config.Addr())
 	if err != nil {
-		t.errorsChannel <- err
+		return fmt.Errorf("error happened when listening for SNMP Traps: %s", err)
 	}
-}
-
-func (t *trapListener) blockUntilReady() error {
-	select {
-	// Wait for listener to be started and listening to traps.
-	// See: https://godoc.org/github.com/gosnmp/gosnmp#TrapListener.Listening
-	case <-t.listener.Listening():
-		return nil
-	// If the listener failed to start (eg because it couldn't bind to a socket),
-	// we'll get an error here.
-	case err := <-t.errorsChannel:
+	t.conn, err = net.ListenUDP("udp", addr)
+	if err != nil {
 		return fmt.Errorf("error happened when listening for SNMP Traps: %s", err)
 	}
+
+	if t.config.TLS.Enabled {
+		t.secure, err = t.listenSecure()
+		if err != nil {
+			t.conn.Close()
+			return fmt.Errorf("error happened when listening for SNMP Traps over TLS: %s", err)
+		}
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.serveUDP()
+	}()
+	for _, s := range t.secure {
+		t.wg.Add(1)
+		go func(s *secureTransport) {
+			defer t.wg.Done()
+			s.serve()
+		}(s)
+	}
+	return nil
 }
 
 // stop the current TrapListener instance
@@ -131,7 +149,11 @@ func (t *trapListener) stop() error {
 
 	go func() {
 		t.logger.Infof("Stop listening on %s", t.config.Addr())
-		t.listener.Close()
+		t.conn.Close()
+		for _, s := range t.secure {
+			s.close()
+		}
+		t.wg.Wait()
 		close(stopped)
 	}()
 
@@ -143,7 +165,57 @@ func (t *trapListener) stop() error {
 	return nil
 }
 
-func (t *trapListener) receiveTrap(p *gosnmp.SnmpPacket, u *net.UDPAddr) {
+func (t *trapListener) serveUDP() {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := t.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			t.logger.Debugf("Error reading from listener %s: %s", t.config.Addr(), err)
+			continue
+		}
+		msg := make([]byte, n)
+		copy(msg, buf[:n])
+		response := t.handleMessage(msg, addr)
+		if response == nil {
+			continue
+		}
+		if _, err := t.conn.WriteToUDP(response, addr); err != nil {
+			t.logger.Debugf("Could not send the response to %s on listener %s: %s", addr, t.config.Addr(), err)
+		}
+	}
+}
+
+// handleMessage processes an SNMP message received over UDP and returns the response to send back, if any.
+func (t *trapListener) handleMessage(msg []byte, addr *net.UDPAddr) []byte {
+	p, err := t.params.UnmarshalTrap(msg, false)
+	if err != nil {
+		// Senders discover the engine ID of the Agent with unauthenticated messages, which
+		// don't match any configured user.
+		if report := t.discoveryReport(msg); report != nil {
+			return report
+		}
+		t.logger.Debugf("Could not decode the packet received from %s on listener %s: %s", addr, t.config.Addr(), err)
+		return nil
+	}
+	if t.isUnknownEngineID(p) {
+		return t.engineIDReport(p)
+	}
+	if !t.receiveTrap(p, addr) || p.PDUType != gosnmp.InformRequest {
+		return nil
+	}
+	response, err := informResponse(p)
+	if err != nil {
+		t.logger.Debugf("Could not build the response to the inform from %s: %s", addr, err)
+		return nil
+	}
+	return response
+}
+
+// receiveTrap validates a decoded packet and publishes it. It reports whether the packet is valid.
+func (t *trapListener) receiveTrap(p *gosnmp.SnmpPacket, u *net.UDPAddr) bool {
 	packet := &packet.SnmpPacket{Content: p, Addr: u, Timestamp: time.Now().UnixMilli(), Namespace: t.config.Namespace}
 	tags := packet.GetTags()
 
@@ -153,16 +225,101 @@ func (t *trapListener) receiveTrap(p *gosnmp.SnmpPacket, u *net.UDPAddr) {
 		t.logger.Debugf("Invalid credentials from %s on listener %s, dropping traps", u.String(), t.config.Addr())
 		t.status.AddTrapsPacketsUnknownCommunityString(1)
 		t.sender.Count("datadog.snmp_traps.invalid_packet", 1, "", append(tags, "reason:unknown_community_string"))
-		return
+		return false
 	}
 	t.logger.Debugf("Packet received from %s on listener %s", u.String(), t.config.Addr())
 	t.status.AddTrapsPackets(1)
+	if p.PDUType == gosnmp.InformRequest {
+		t.status.AddDeviceInforms(u.IP.String(), 1)
+	} else {
+		t.status.AddDeviceTraps(u.IP.String(), 1)
+	}
 	t.packets <- packet
+	return true
+}
+
+// isUnknownEngineID returns whether p is an SNMPv3 message sent to another engine ID than the one
+// of the Agent, to which the Agent must answer with its own engine ID (RFC 3414 section 3.2.3b).
+// The Agent is only the authoritative engine of the confirmed messages, such as informs: traps
+// carry the engine ID of their sender.
+func (t *trapListener) isUnknownEngineID(p *gosnmp.SnmpPacket) bool {
+	if p.Version != gosnmp.Version3 || p.SecurityModel != gosnmp.UserSecurityModel || t.params.SecurityModel != gosnmp.UserSecurityModel {
+		return false
+	}
+	switch p.PDUType {
+	case gosnmp.Trap, gosnmp.SNMPv2Trap, gosnmp.GetResponse, gosnmp.Report:
+		return false
+	}
+	sp, ok := p.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if !ok {
+		return false
+	}
+	return sp.AuthoritativeEngineID != t.config.AuthoritativeEngineID()
+}
+
+// discoveryReport returns the report to send back when msg is an unauthenticated SNMPv3
+// message sent to discover the engine ID of the Agent.
+func (t *trapListener) discoveryReport(msg []byte) []byte {
+	if t.params.SecurityModel != gosnmp.UserSecurityModel {
+		return nil
+	}
+	discovery := &gosnmp.GoSNMP{
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{},
+		Logger:             t.params.Logger,
+	}
+	p, err := discovery.UnmarshalTrap(msg, false)
+	if err != nil || p.MsgFlags&gosnmp.AuthPriv != gosnmp.NoAuthNoPriv || !t.isUnknownEngineID(p) {
+		return nil
+	}
+	return t.engineIDReport(p)
+}
+
+// engineIDReport returns the report of the engine ID of the Agent, in response to p.
+func (t *trapListener) engineIDReport(p *gosnmp.SnmpPacket) []byte {
+	count := t.unknownEngineIDs.Add(1)
+	sp, ok := p.SecurityParameters.Copy().(*gosnmp.UsmSecurityParameters)
+	if !ok {
+		return nil
+	}
+	sp.AuthoritativeEngineID = t.config.AuthoritativeEngineID()
+	sp.AuthoritativeEngineBoots = 1
+	sp.AuthoritativeEngineTime = uint32(time.Since(t.startTime).Seconds())
+	report := *p
+	report.PDUType = gosnmp.Report
+	report.MsgFlags = gosnmp.NoAuthNoPriv
+	report.SecurityParameters = sp
+	report.Variables = []gosnmp.SnmpPDU{{Name: usmStatsUnknownEngineIDs, Type: gosnmp.Counter32, Value: uint(count)}}
+	msg, err := report.MarshalMsg()
+	if err != nil {
+		t.logger.Debugf("Could not build the engine ID report: %s", err)
+		return nil
+	}
+	return msg
+}
+
+// informResponse returns the response acknowledging the inform request p.
+func informResponse(p *gosnmp.SnmpPacket) ([]byte, error) {
+	if p.SecurityModel == tsmSecurityModel {
+		return encodeTSMResponse(p)
+	}
+	// p is published to the forwarder, the response is built from a copy
+	response := *p
+	response.PDUType = gosnmp.GetResponse
+	response.Error = gosnmp.NoError
+	response.ErrorIndex = 0
+	response.MsgFlags &^= gosnmp.Reportable
+	if p.SecurityParameters != nil {
+		response.SecurityParameters = p.SecurityParameters.Copy()
+	}
+	return response.MarshalMsg()
 }
 
 func validatePacket(p *gosnmp.SnmpPacket, c *config.TrapsConfig) error {
 	if p.Version == gosnmp.Version3 {
-		// v3 Packets are already decrypted and validated by gosnmp
+		// v3 Packets are already decrypted and validated by gosnmp, or authenticated by the
+		// TLS and DTLS transports
 		return nil
 	}
 
@@ -7,6 +7,7 @@ package listenerimpl
 
 import (
 	"errors"
+	"net"
 	"testing"
 	"time"
 
@@ -92,6 +93,58 @@ func TestServerV2(t *testing.T) {
 	assertVariables(t, packet)
 }
 
+func TestServerV2Inform(t *testing.T) {
+	serverPort, err := ndmtestutils.GetFreePort()
+	require.NoError(t, err)
+	config := &config.TrapsConfig{Port: serverPort, CommunityStrings: []string{"public"}}
+	s := listenerTestSetup(t, config)
+
+	params, err := config.BuildSNMPParams(nil)
+	require.NoError(t, err)
+	params.Community = "public"
+	params.Timeout = 1 * time.Second
+	params.Retries = 1
+	require.NoError(t, params.Connect())
+	defer params.Conn.Close()
+
+	trap := packetModule.NetSNMPExampleHeartbeatNotification
+	trap.IsInform = true
+	// SendTrap only returns the response once the inform is acknowledged
+	response, err := params.SendTrap(trap)
+	require.NoError(t, err)
+	assert.Equal(t, gosnmp.GetResponse, response.PDUType)
+
+	packet, err := receivePacket(s, defaultTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, gosnmp.InformRequest, packet.Content.PDUType)
+	assertVariables(t, packet)
+	assert.Equal(t, status.DeviceStats{Informs: 1}, s.Status.GetDeviceStats()["127.0.0.1"])
+}
+
+func TestServerV2InformBadCredentials(t *testing.T) {
+	serverPort, err := ndmtestutils.GetFreePort()
+	require.NoError(t, err)
+	config := &config.TrapsConfig{Port: serverPort, CommunityStrings: []string{"public"}}
+	s := listenerTestSetup(t, config)
+
+	params, err := config.BuildSNMPParams(nil)
+	require.NoError(t, err)
+	params.Community = "wrong-community"
+	trap := packetModule.NetSNMPExampleHeartbeatNotification
+	msg, err := (&gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: params.Community,
+		PDUType:   gosnmp.InformRequest,
+		RequestID: 1,
+		Variables: trap.Variables,
+	}).MarshalMsg()
+	require.NoError(t, err)
+
+	// Informs failing validation are not acknowledged
+	assert.Nil(t, s.Listener.(*trapListener).handleMessage(msg, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}))
+	assert.Empty(t, s.Status.GetDeviceStats())
+}
+
 func TestServerV2BadCredentials(t *testing.T) {
 	serverPort, err := ndmtestutils.GetFreePort()
 	require.NoError(t, err)
@@ -273,3 +326,56 @@ func assertNoPacketReceived(t *testing.T, listener listener.Component) {
 		break
 	}
 }
+
+func TestServerV3InformUnknownEngineID(t *testing.T) {
+	serverPort, err := ndmtestutils.GetFreePort()
+	require.NoError(t, err)
+	userV3 := config.UserV3{Username: "user", AuthKey: "password", AuthProtocol: "sha", PrivKey: "password", PrivProtocol: "aes"}
+	config := &config.TrapsConfig{Port: serverPort, Users: []config.UserV3{userV3}}
+	s := listenerTestSetup(t, config)
+
+	// an engine ID of valid length which isn't the one of the Agent
+	sp := &gosnmp.UsmSecurityParameters{
+		UserName:                 "user",
+		AuthoritativeEngineID:    "wrongengine",
+		AuthenticationPassphrase: "password",
+		AuthenticationProtocol:   gosnmp.SHA,
+		PrivacyPassphrase:        "password",
+		PrivacyProtocol:          gosnmp.AES,
+	}
+	require.NoError(t, sp.InitSecurityKeys())
+	inform := &gosnmp.SnmpPacket{
+		Version:            gosnmp.Version3,
+		MsgFlags:           gosnmp.AuthPriv | gosnmp.Reportable,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		SecurityParameters: sp,
+		PDUType:            gosnmp.InformRequest,
+		MsgID:              42,
+		RequestID:          1234,
+		Variables:          packetModule.NetSNMPExampleHeartbeatNotification.Variables,
+	}
+	require.NoError(t, sp.InitPacket(inform))
+	msg, err := inform.MarshalMsg()
+	require.NoError(t, err)
+
+	conn, err := net.Dial("udp", config.Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(msg)
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(defaultTimeout)))
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+
+	decoder := &gosnmp.GoSNMP{
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{},
+	}
+	report, err := decoder.UnmarshalTrap(buf[:n], false)
+	require.NoError(t, err)
+	assert.Equal(t, gosnmp.Report, report.PDUType)
+	assert.Equal(t, s.Config.Get().AuthoritativeEngineID(), report.SecurityParameters.(*gosnmp.UsmSecurityParameters).AuthoritativeEngineID)
+	assertNoPacketReceived(t, s.Listener)
+}
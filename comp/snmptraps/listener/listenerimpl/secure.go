@@ -0,0 +1,296 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package listenerimpl
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/pion/dtls/v2"
+
+	"github.com/DataDog/datadog-agent/comp/snmptraps/config"
+)
+
+// handshakeTimeout is the time devices have to complete the TLS and DTLS handshakes.
+const handshakeTimeout = 10 * time.Second
+
+// secureTransport receives the messages sent by devices over TLS or DTLS, using the
+// Transport Security Model (RFC 6353).
+type secureTransport struct {
+	name     string
+	listener net.Listener
+	trap     *trapListener
+	// chains returns the verified certificate chains of the device on the other end of conn.
+	chains func(conn net.Conn) ([][]*x509.Certificate, error)
+	// readMessage reads the next message sent on conn.
+	readMessage func(conn net.Conn, r *bufio.Reader) ([]byte, error)
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// listenSecure starts listening on the TLS and DTLS transports enabled in the configuration.
+func (t *trapListener) listenSecure() ([]*secureTransport, error) {
+	cert, roots, err := t.config.TLS.LoadCertificates()
+	if err != nil {
+		return nil, err
+	}
+	var transports []*secureTransport
+	closeAll := func() {
+		for _, s := range transports {
+			s.listener.Close()
+		}
+	}
+
+	if t.config.TLS.HasTransport(config.TransportTLS) {
+		l, err := tls.Listen("tcp", t.config.TLSAddr(), &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientCAs:    roots,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			MinVersion:   tls.VersionTLS12,
+		})
+		if err != nil {
+			return nil, err
+		}
+		transports = append(transports, t.newSecureTransport(config.TransportTLS, l, tlsChains, readStreamMessage))
+	}
+
+	if t.config.TLS.HasTransport(config.TransportDTLS) {
+		addr, err := net.ResolveUDPAddr("udp", t.config.TLSAddr())
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		l, err := dtls.Listen("udp", addr, &dtls.Config{
+			Certificates:         []tls.Certificate{cert},
+			ClientCAs:            roots,
+			ClientAuth:           dtls.RequireAndVerifyClientCert,
+			ExtendedMasterSecret: dtls.RequireExtendedMasterSecret,
+			ConnectContextMaker: func() (context.Context, func()) {
+				return context.WithTimeout(context.Background(), handshakeTimeout)
+			},
+		})
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		dtlsChains := func(conn net.Conn) ([][]*x509.Certificate, error) {
+			return verifyDTLSChains(conn, roots)
+		}
+		transports = append(transports, t.newSecureTransport(config.TransportDTLS, l, dtlsChains, readDatagramMessage))
+	}
+
+	for _, s := range transports {
+		t.logger.Infof("Start listening for traps over %s on %s", s.name, t.config.TLSAddr())
+	}
+	return transports, nil
+}
+
+func (t *trapListener) newSecureTransport(name string, l net.Listener, chains func(net.Conn) ([][]*x509.Certificate, error), read func(net.Conn, *bufio.Reader) ([]byte, error)) *secureTransport {
+	return &secureTransport{
+		name:        name,
+		listener:    l,
+		trap:        t,
+		chains:      chains,
+		readMessage: read,
+		conns:       make(map[net.Conn]struct{}),
+	}
+}
+
+// serve accepts connections until the transport is closed.
+func (s *secureTransport) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				s.wg.Wait()
+				return
+			}
+			// DTLS handshake failures are reported by Accept
+			s.trap.logger.Debugf("Error accepting a %s connection on %s: %s", s.name, s.trap.config.TLSAddr(), err)
+			continue
+		}
+		if !s.track(conn) {
+			conn.Close()
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			s.serveConn(conn)
+		}()
+	}
+}
+
+func (s *secureTransport) serveConn(conn net.Conn) {
+	defer conn.Close()
+	remote := toUDPAddr(conn.RemoteAddr())
+
+	chains, err := s.chains(conn)
+	if err != nil {
+		s.trap.logger.Debugf("Rejected %s connection from %s: %s", s.name, remote, err)
+		return
+	}
+	securityName, ok := s.trap.config.TLS.SecurityName(chains)
+	if !ok {
+		s.trap.logger.Debugf("Rejected %s connection from %s: the certificate does not match any cert_to_user entry", s.name, remote)
+		s.trap.status.AddTrapsPacketsUnknownCommunityString(1)
+		s.trap.sender.Count("datadog.snmp_traps.invalid_packet", 1, "", []string{
+			"snmp_version:3",
+			"device_namespace:" + s.trap.config.Namespace,
+			"snmp_device:" + remote.IP.String(),
+			"reason:unknown_certificate",
+		})
+		return
+	}
+	s.trap.logger.Debugf("Accepted %s connection from %s as %q", s.name, remote, securityName)
+
+	r := bufio.NewReader(conn)
+	for {
+		msg, err := s.readMessage(conn, r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !s.isClosed() {
+				s.trap.logger.Debugf("Error reading from the %s connection of %s: %s", s.name, remote, err)
+			}
+			return
+		}
+		p, err := decodeTSMMessage(msg)
+		if err != nil {
+			s.trap.logger.Debugf("Could not decode the packet received from %s over %s: %s", remote, s.name, err)
+			continue
+		}
+		if !s.trap.receiveTrap(p, remote) || p.PDUType != gosnmp.InformRequest {
+			continue
+		}
+		response, err := informResponse(p)
+		if err != nil {
+			s.trap.logger.Debugf("Could not build the response to the inform from %s: %s", remote, err)
+			continue
+		}
+		if _, err := conn.Write(response); err != nil {
+			s.trap.logger.Debugf("Could not send the response to %s over %s: %s", remote, s.name, err)
+			return
+		}
+	}
+}
+
+// track records an open connection, so that it is closed with the transport.
+func (s *secureTransport) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *secureTransport) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+func (s *secureTransport) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// close stops accepting connections and closes the open ones.
+func (s *secureTransport) close() {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.listener.Close()
+}
+
+// tlsChains completes the TLS handshake and returns the verified certificate chains of the device.
+func tlsChains(conn net.Conn) ([][]*x509.Certificate, error) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil, errors.New("not a TLS connection")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	return tlsConn.ConnectionState().VerifiedChains, nil
+}
+
+// verifyDTLSChains returns the certificate chains of the device, verified during the handshake
+// but only exposed in their raw form by the DTLS connection.
+func verifyDTLSChains(conn net.Conn, roots *x509.CertPool) ([][]*x509.Certificate, error) {
+	dtlsConn, ok := conn.(*dtls.Conn)
+	if !ok {
+		return nil, errors.New("not a DTLS connection")
+	}
+	raw := dtlsConn.ConnectionState().PeerCertificates
+	if len(raw) == 0 {
+		return nil, errors.New("no client certificate")
+	}
+	certs := make([]*x509.Certificate, 0, len(raw))
+	for _, der := range raw {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	return certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+}
+
+// readStreamMessage reads the next message of a TLS connection.
+func readStreamMessage(_ net.Conn, r *bufio.Reader) ([]byte, error) {
+	return readMessage(r)
+}
+
+// readDatagramMessage reads the next message of a DTLS connection, each datagram holding one message.
+func readDatagramMessage(conn net.Conn, _ *bufio.Reader) ([]byte, error) {
+	buf := make([]byte, maxMessageSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func toUDPAddr(addr net.Addr) *net.UDPAddr {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a
+	case *net.TCPAddr:
+		return &net.UDPAddr{IP: a.IP, Port: a.Port, Zone: a.Zone}
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr.String())
+	if err != nil {
+		return &net.UDPAddr{}
+	}
+	return udpAddr
+}
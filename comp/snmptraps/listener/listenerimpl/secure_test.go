@@ -0,0 +1,266 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package listenerimpl
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/pion/dtls/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/snmptraps/config"
+	packetModule "github.com/DataDog/datadog-agent/comp/snmptraps/packet"
+	ndmtestutils "github.com/DataDog/datadog-agent/pkg/networkdevice/testutils"
+)
+
+type testCertificates struct {
+	certFile, keyFile, caFile string
+	client                    tls.Certificate
+	roots                     *x509.CertPool
+}
+
+// generateCertificates creates a certificate authority, and the certificates it issues to
+// the Agent and to a device.
+func generateCertificates(t *testing.T) *testCertificates {
+	dir := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) ([]byte, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{name},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		return der, key
+	}
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600))
+		return path
+	}
+
+	serverDER, serverKey := issue(2, "localhost", x509.ExtKeyUsageServerAuth)
+	serverKeyDER, err := x509.MarshalECPrivateKey(serverKey)
+	require.NoError(t, err)
+	clientDER, clientKey := issue(3, "device.example.com", x509.ExtKeyUsageClientAuth)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	return &testCertificates{
+		certFile: writePEM("server.crt", "CERTIFICATE", serverDER),
+		keyFile:  writePEM("server.key", "EC PRIVATE KEY", serverKeyDER),
+		caFile:   writePEM("ca.crt", "CERTIFICATE", caDER),
+		client:   tls.Certificate{Certificate: [][]byte{clientDER}, PrivateKey: clientKey},
+		roots:    roots,
+	}
+}
+
+func secureTestConfig(t *testing.T, certs *testCertificates, transport string, certToUser config.CertToUser) *config.TrapsConfig {
+	port, err := ndmtestutils.GetFreePort()
+	require.NoError(t, err)
+	tlsPort, err := ndmtestutils.GetFreePort()
+	require.NoError(t, err)
+	return &config.TrapsConfig{
+		Port:      port,
+		BindHost:  "127.0.0.1",
+		Namespace: "totoro",
+		TLS: config.TLSConfig{
+			Enabled:    true,
+			Port:       tlsPort,
+			Transports: []string{transport},
+			CertFile:   certs.certFile,
+			KeyFile:    certs.keyFile,
+			CAFile:     certs.caFile,
+			CertToUser: []config.CertToUser{certToUser},
+		},
+	}
+}
+
+func testInform(t *testing.T) []byte {
+	msg, err := encodeTSMMessage(&gosnmp.SnmpPacket{
+		MsgID:     42,
+		RequestID: 1234,
+		Variables: packetModule.NetSNMPExampleHeartbeatNotification.Variables,
+	}, gosnmp.InformRequest, gosnmp.AuthPriv|gosnmp.Reportable)
+	require.NoError(t, err)
+	return msg
+}
+
+func assertInformResponse(t *testing.T, msg []byte) {
+	response, err := decodeTSMMessage(msg)
+	require.NoError(t, err)
+	assert.Equal(t, gosnmp.GetResponse, response.PDUType)
+	assert.Equal(t, uint32(42), response.MsgID)
+	assert.Equal(t, uint32(1234), response.RequestID)
+	assert.Equal(t, gosnmp.AuthPriv, response.MsgFlags)
+	assert.Len(t, response.Variables, len(packetModule.NetSNMPExampleHeartbeatNotification.Variables))
+}
+
+func TestTSMMessageRoundTrip(t *testing.T) {
+	p, err := decodeTSMMessage(testInform(t))
+	require.NoError(t, err)
+	assert.Equal(t, gosnmp.Version3, p.Version)
+	assert.Equal(t, tsmSecurityModel, p.SecurityModel)
+	assert.Equal(t, gosnmp.InformRequest, p.PDUType)
+	assert.Equal(t, gosnmp.AuthPriv|gosnmp.Reportable, p.MsgFlags)
+
+	response, err := informResponse(p)
+	require.NoError(t, err)
+	assertInformResponse(t, response)
+}
+
+func TestDecodeTSMMessageRejectsUSM(t *testing.T) {
+	p := &gosnmp.SnmpPacket{
+		Version:       gosnmp.Version3,
+		PDUType:       gosnmp.SNMPv2Trap,
+		MsgFlags:      gosnmp.NoAuthNoPriv,
+		SecurityModel: gosnmp.UserSecurityModel,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			UserName:              "user",
+			AuthoritativeEngineID: "foobarbaz",
+		},
+		Variables: packetModule.NetSNMPExampleHeartbeatNotification.Variables,
+	}
+	msg, err := p.MarshalMsg()
+	require.NoError(t, err)
+	_, err = decodeTSMMessage(msg)
+	assert.ErrorContains(t, err, "unsupported security model 3")
+}
+
+func TestReadMessage(t *testing.T) {
+	inform := testInform(t)
+	long := make([]byte, 300)
+	stream := append(append(append([]byte{}, inform...), encodeTLV(berSequence, long)...), inform[:5]...)
+	r := bufio.NewReader(bytes.NewReader(stream))
+
+	msg, err := readMessage(r)
+	require.NoError(t, err)
+	assert.Equal(t, inform, msg)
+	msg, err = readMessage(r)
+	require.NoError(t, err)
+	assert.Len(t, msg, 304)
+	_, err = readMessage(r)
+	assert.Error(t, err)
+}
+
+func TestServerTLSInform(t *testing.T) {
+	certs := generateCertificates(t)
+	config := secureTestConfig(t, certs, config.TransportTLS, config.CertToUser{MapType: config.MapSANDNSName})
+	s := listenerTestSetup(t, config)
+
+	conn, err := tls.Dial("tcp", config.TLSAddr(), &tls.Config{
+		Certificates: []tls.Certificate{certs.client},
+		RootCAs:      certs.roots,
+		ServerName:   "localhost",
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write(testInform(t))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(defaultTimeout)))
+	msg, err := readMessage(bufio.NewReader(conn))
+	require.NoError(t, err)
+	assertInformResponse(t, msg)
+
+	packet, err := receivePacket(s, defaultTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, gosnmp.Version3, packet.Content.Version)
+	assertVariables(t, packet)
+	assert.Equal(t, int64(1), s.Status.GetDeviceStats()["127.0.0.1"].Informs)
+}
+
+func TestServerDTLSInform(t *testing.T) {
+	certs := generateCertificates(t)
+	config := secureTestConfig(t, certs, config.TransportDTLS, config.CertToUser{MapType: config.MapCommonName})
+	s := listenerTestSetup(t, config)
+
+	addr, err := net.ResolveUDPAddr("udp", config.TLSAddr())
+	require.NoError(t, err)
+	conn, err := dtls.Dial("udp", addr, &dtls.Config{
+		Certificates:         []tls.Certificate{certs.client},
+		RootCAs:              certs.roots,
+		ServerName:           "localhost",
+		ExtendedMasterSecret: dtls.RequireExtendedMasterSecret,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write(testInform(t))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(defaultTimeout)))
+	buf := make([]byte, maxMessageSize)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assertInformResponse(t, buf[:n])
+
+	packet, err := receivePacket(s, defaultTimeout)
+	require.NoError(t, err)
+	assertVariables(t, packet)
+}
+
+func TestServerTLSUnknownCertificate(t *testing.T) {
+	certs := generateCertificates(t)
+	// The fingerprint matches neither the device certificate nor its certificate authority
+	config := secureTestConfig(t, certs, config.TransportTLS, config.CertToUser{
+		Fingerprint: "0000000000000000000000000000000000000000000000000000000000000000",
+		User:        "device",
+	})
+	s := listenerTestSetup(t, config)
+
+	conn, err := tls.Dial("tcp", config.TLSAddr(), &tls.Config{
+		Certificates: []tls.Certificate{certs.client},
+		RootCAs:      certs.roots,
+		ServerName:   "localhost",
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+	_, _ = conn.Write(testInform(t))
+
+	_, err = receivePacket(s, defaultTimeout)
+	require.EqualError(t, err, "invalid packet")
+	s.Sender.AssertMetric(t, "Count", "datadog.snmp_traps.invalid_packet", 1, "", []string{"snmp_version:3", "device_namespace:totoro", "snmp_device:127.0.0.1", "reason:unknown_certificate"})
+	assert.Empty(t, s.Status.GetDeviceStats())
+}
@@ -0,0 +1,269 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package listenerimpl
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// tsmSecurityModel is the identifier of the Transport Security Model (RFC 5591).
+	tsmSecurityModel = gosnmp.SnmpV3SecurityModel(4)
+	// maxMessageSize is the largest SNMP message accepted and advertised by the listener.
+	maxMessageSize = 65507
+
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berSequence    = 0x30
+)
+
+// decodeTSMMessage decodes an SNMPv3 message using the Transport Security Model. Such messages
+// are authenticated and encrypted by the transport, so their scoped PDU is sent in clear.
+func decodeTSMMessage(msg []byte) (*gosnmp.SnmpPacket, error) {
+	tag, body, _, err := readTLV(msg)
+	if err != nil {
+		return nil, err
+	}
+	if tag != berSequence {
+		return nil, errors.New("message is not a sequence")
+	}
+	fields, err := readTLVs(body, berInteger, berSequence, berOctetString, berSequence)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNMPv3 message: %w", err)
+	}
+	if version, err := decodeInteger(fields[0]); err != nil || version != int64(gosnmp.Version3) {
+		return nil, errors.New("not an SNMPv3 message")
+	}
+	header, err := readTLVs(fields[1], berInteger, berInteger, berOctetString, berInteger)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNMPv3 header: %w", err)
+	}
+	msgID, err := decodeInteger(header[0])
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := decodeInteger(header[1])
+	if err != nil {
+		return nil, err
+	}
+	if len(header[2]) != 1 {
+		return nil, errors.New("invalid SNMPv3 message flags")
+	}
+	securityModel, err := decodeInteger(header[3])
+	if err != nil {
+		return nil, err
+	}
+	if securityModel != int64(tsmSecurityModel) {
+		return nil, fmt.Errorf("unsupported security model %d, expected the transport security model", securityModel)
+	}
+
+	// The scoped PDU is made of the context engine ID, the context name and the PDU.
+	scopedPDU := fields[3]
+	context, err := readTLVs(scopedPDU, berOctetString, berOctetString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scoped PDU: %w", err)
+	}
+	pdu := remainingAfter(scopedPDU, 2)
+
+	// The PDU is decoded as part of an equivalent SNMPv2c message.
+	v2 := &gosnmp.GoSNMP{Version: gosnmp.Version2c}
+	p, err := v2.UnmarshalTrap(encodeTLV(berSequence, encodeTLV(berInteger, []byte{byte(gosnmp.Version2c)}), encodeTLV(berOctetString, nil), pdu), false)
+	if err != nil {
+		return nil, err
+	}
+	p.Version = gosnmp.Version3
+	p.MsgID = uint32(msgID)
+	p.MsgMaxSize = uint32(maxSize)
+	p.MsgFlags = gosnmp.SnmpV3MsgFlags(header[2][0])
+	p.SecurityModel = tsmSecurityModel
+	p.ContextEngineID = string(context[0])
+	p.ContextName = string(context[1])
+	return p, nil
+}
+
+// encodeTSMResponse returns the response to an inform request received with the Transport Security Model.
+func encodeTSMResponse(p *gosnmp.SnmpPacket) ([]byte, error) {
+	return encodeTSMMessage(p, gosnmp.GetResponse, p.MsgFlags&^gosnmp.Reportable)
+}
+
+// encodeTSMMessage encodes the variables of p in an SNMPv3 message of the given type, using
+// the Transport Security Model.
+func encodeTSMMessage(p *gosnmp.SnmpPacket, pduType gosnmp.PDUType, flags gosnmp.SnmpV3MsgFlags) ([]byte, error) {
+	v2 := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		PDUType:   pduType,
+		RequestID: p.RequestID,
+		Variables: p.Variables,
+	}
+	msg, err := v2.MarshalMsg()
+	if err != nil {
+		return nil, err
+	}
+	_, body, _, err := readTLV(msg)
+	if err != nil {
+		return nil, err
+	}
+	pdu := remainingAfter(body, 2)
+
+	header := concat(
+		encodeTLV(berInteger, encodeInteger(int64(p.MsgID))),
+		encodeTLV(berInteger, encodeInteger(maxMessageSize)),
+		encodeTLV(berOctetString, []byte{byte(flags)}),
+		encodeTLV(berInteger, encodeInteger(int64(tsmSecurityModel))),
+	)
+	scopedPDU := concat(
+		encodeTLV(berOctetString, []byte(p.ContextEngineID)),
+		encodeTLV(berOctetString, []byte(p.ContextName)),
+		pdu,
+	)
+	return encodeTLV(berSequence,
+		encodeTLV(berInteger, []byte{byte(gosnmp.Version3)}),
+		encodeTLV(berSequence, header),
+		encodeTLV(berOctetString, nil),
+		encodeTLV(berSequence, scopedPDU),
+	), nil
+}
+
+// readMessage reads an SNMP message from a stream transport, where messages are sent one
+// after the other (RFC 6353 section 5.1.1).
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := r.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	headerLen := 2
+	if header[1]&0x80 != 0 {
+		headerLen += int(header[1] & 0x7f)
+		if header, err = r.Peek(headerLen); err != nil {
+			return nil, err
+		}
+	}
+	length, _, err := decodeLength(header[1:])
+	if err != nil {
+		return nil, err
+	}
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds the maximum size", length)
+	}
+	msg := make([]byte, headerLen+length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// readTLV reads a BER encoded value and returns its tag, its content and the bytes following it.
+func readTLV(b []byte) (byte, []byte, []byte, error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errors.New("truncated value")
+	}
+	length, n, err := decodeLength(b[1:])
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	start := 1 + n
+	if len(b)-start < length {
+		return 0, nil, nil, errors.New("truncated value")
+	}
+	return b[0], b[start : start+length], b[start+length:], nil
+}
+
+// readTLVs reads values of the expected tags from b, and returns their content.
+func readTLVs(b []byte, tags ...byte) ([][]byte, error) {
+	values := make([][]byte, 0, len(tags))
+	for _, expected := range tags {
+		tag, value, rest, err := readTLV(b)
+		if err != nil {
+			return nil, err
+		}
+		if tag != expected {
+			return nil, fmt.Errorf("unexpected tag 0x%x, expected 0x%x", tag, expected)
+		}
+		values = append(values, value)
+		b = rest
+	}
+	return values, nil
+}
+
+// remainingAfter returns the bytes of b following its first n values.
+func remainingAfter(b []byte, n int) []byte {
+	for i := 0; i < n; i++ {
+		_, _, rest, err := readTLV(b)
+		if err != nil {
+			return nil
+		}
+		b = rest
+	}
+	return b
+}
+
+// decodeLength decodes a BER length, in short or long form, and returns the number of bytes it uses.
+func decodeLength(b []byte) (int, int, error) {
+	if len(b) == 0 {
+		return 0, 0, errors.New("truncated length")
+	}
+	if b[0]&0x80 == 0 {
+		return int(b[0]), 1, nil
+	}
+	n := int(b[0] & 0x7f)
+	if n == 0 || n > 4 || len(b) < 1+n {
+		return 0, 0, errors.New("invalid length")
+	}
+	length := 0
+	for _, c := range b[1 : 1+n] {
+		length = length<<8 | int(c)
+	}
+	if length < 0 {
+		return 0, 0, errors.New("invalid length")
+	}
+	return length, 1 + n, nil
+}
+
+func decodeInteger(b []byte) (int64, error) {
+	if len(b) == 0 || len(b) > 8 {
+		return 0, errors.New("invalid integer")
+	}
+	v := int64(int8(b[0]))
+	for _, c := range b[1:] {
+		v = v<<8 | int64(c)
+	}
+	return v, nil
+}
+
+func encodeInteger(v int64) []byte {
+	b := []byte{byte(v)}
+	for v > 0x7f || v < -0x80 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return b
+}
+
+func encodeTLV(tag byte, values ...[]byte) []byte {
+	value := concat(values...)
+	var buf bytes.Buffer
+	buf.WriteByte(tag)
+	switch l := len(value); {
+	case l < 0x80:
+		buf.WriteByte(byte(l))
+	case l <= 0xff:
+		buf.Write([]byte{0x81, byte(l)})
+	default:
+		buf.Write([]byte{0x82, byte(l >> 8), byte(l)})
+	}
+	buf.Write(value)
+	return buf.Bytes()
+}
+
+func concat(values ...[]byte) []byte {
+	return bytes.Join(values, nil)
+}
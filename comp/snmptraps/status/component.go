@@ -15,6 +15,15 @@ type Component interface {
 	GetTrapsPackets() int64
 	AddTrapsPacketsUnknownCommunityString(int64)
 	GetTrapsPacketsUnknownCommunityString() int64
+	AddDeviceTraps(device string, i int64)
+	AddDeviceInforms(device string, i int64)
+	GetDeviceStats() map[string]DeviceStats
 	SetStartError(error)
 	GetStartError() error
 }
+
+// DeviceStats counts the valid packets received from a device.
+type DeviceStats struct {
+	Traps   int64
+	Informs int64
+}
@@ -29,6 +29,7 @@ func newMock() status.Component {
 // mockManager mocks a manager using plain values (not expvars)
 type mockManager struct {
 	trapsPackets, trapsPacketsUnknownCommunityString int64
+	devices                                          map[string]status.DeviceStats
 	lock                                             sync.Mutex
 	err                                              error
 }
@@ -57,6 +58,39 @@ func (s *mockManager) GetTrapsPacketsUnknownCommunityString() int64 {
 	return s.trapsPacketsUnknownCommunityString
 }
 
+func (s *mockManager) AddDeviceTraps(device string, i int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stats := s.devices[device]
+	stats.Traps += i
+	s.setDevice(device, stats)
+}
+
+func (s *mockManager) AddDeviceInforms(device string, i int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stats := s.devices[device]
+	stats.Informs += i
+	s.setDevice(device, stats)
+}
+
+func (s *mockManager) setDevice(device string, stats status.DeviceStats) {
+	if s.devices == nil {
+		s.devices = make(map[string]status.DeviceStats)
+	}
+	s.devices[device] = stats
+}
+
+func (s *mockManager) GetDeviceStats() map[string]status.DeviceStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stats := make(map[string]status.DeviceStats, len(s.devices))
+	for device, st := range s.devices {
+		stats[device] = st
+	}
+	return stats
+}
+
 func (s *mockManager) SetStartError(err error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
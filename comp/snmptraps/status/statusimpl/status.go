@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"expvar"
 	"io"
+	"sync"
 
 	"go.uber.org/fx"
 
@@ -27,10 +28,24 @@ func Module() fxutil.Module {
 	)
 }
 
+const (
+	// maxTrackedDevices bounds the number of devices counted on their own, as the source
+	// address of the UDP packets can be spoofed
+	maxTrackedDevices = 100
+	// otherDevices is the key the packets of the devices beyond maxTrackedDevices are counted under
+	otherDevices = "other"
+)
+
 var (
 	trapsExpvars                       = expvar.NewMap("snmp_traps")
 	trapsPackets                       = expvar.Int{}
 	trapsPacketsUnknownCommunityString = expvar.Int{}
+	devicesExpvars                     = expvar.NewMap("snmp_traps_devices")
+	devicesTraps                       = expvar.Map{}
+	devicesInforms                     = expvar.Map{}
+	// trackedDevices holds the devices counted on their own
+	trackedDevices     = make(map[string]struct{})
+	trackedDevicesLock sync.Mutex
 	// startError stores the error we report to GetStatus()
 	startError error
 )
@@ -38,6 +53,8 @@ var (
 func init() {
 	trapsExpvars.Set("Packets", &trapsPackets)
 	trapsExpvars.Set("PacketsUnknownCommunityString", &trapsPacketsUnknownCommunityString)
+	devicesExpvars.Set("Traps", &devicesTraps)
+	devicesExpvars.Set("Informs", &devicesInforms)
 }
 
 // New creates a new status manager component
@@ -64,6 +81,32 @@ func (s *manager) GetTrapsPacketsUnknownCommunityString() int64 {
 	return trapsPacketsUnknownCommunityString.Value()
 }
 
+func (s *manager) AddDeviceTraps(device string, i int64) {
+	devicesTraps.Add(deviceKey(device), i)
+}
+
+func (s *manager) AddDeviceInforms(device string, i int64) {
+	devicesInforms.Add(deviceKey(device), i)
+}
+
+// deviceKey returns the key the packets of device are counted under.
+func deviceKey(device string) string {
+	trackedDevicesLock.Lock()
+	defer trackedDevicesLock.Unlock()
+	if _, ok := trackedDevices[device]; ok {
+		return device
+	}
+	if len(trackedDevices) >= maxTrackedDevices {
+		return otherDevices
+	}
+	trackedDevices[device] = struct{}{}
+	return device
+}
+
+func (s *manager) GetDeviceStats() map[string]trapsStatus.DeviceStats {
+	return getDeviceStats()
+}
+
 func (s *manager) GetStartError() error {
 	return startError
 }
@@ -131,6 +174,21 @@ func getDroppedPackets() float64 {
 	return float64(droppedPackets.Value())
 }
 
+func getDeviceStats() map[string]trapsStatus.DeviceStats {
+	stats := make(map[string]trapsStatus.DeviceStats)
+	devicesTraps.Do(func(kv expvar.KeyValue) {
+		s := stats[kv.Key]
+		s.Traps = kv.Value.(*expvar.Int).Value()
+		stats[kv.Key] = s
+	})
+	devicesInforms.Do(func(kv expvar.KeyValue) {
+		s := stats[kv.Key]
+		s.Informs = kv.Value.(*expvar.Int).Value()
+		stats[kv.Key] = s
+	})
+	return stats
+}
+
 // GetStatus returns key-value data for use in status reporting of the traps server.
 func GetStatus() map[string]interface{} {
 
@@ -143,6 +201,13 @@ func GetStatus() map[string]interface{} {
 		metrics["PacketsDropped"] = dropped
 	}
 	status["metrics"] = metrics
+	if stats := getDeviceStats(); len(stats) > 0 {
+		devices := make(map[string]map[string]int64, len(stats))
+		for device, s := range stats {
+			devices[device] = map[string]int64{"traps": s.Traps, "informs": s.Informs}
+		}
+		status["devices"] = devices
+	}
 	if startError != nil {
 		status["error"] = startError.Error()
 	}
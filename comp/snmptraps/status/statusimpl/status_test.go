@@ -8,6 +8,7 @@ package statusimpl
 import (
 	"bytes"
 	"expvar"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/comp/forwarder/eventplatform"
+	trapsStatus "github.com/DataDog/datadog-agent/comp/snmptraps/status"
 	_ "github.com/DataDog/datadog-agent/pkg/aggregator"
 )
 
@@ -100,3 +102,46 @@ func TestStatusProvider(t *testing.T) {
 		})
 	}
 }
+
+func resetDeviceStats() {
+	devicesTraps.Init()
+	devicesInforms.Init()
+	trackedDevicesLock.Lock()
+	clear(trackedDevices)
+	trackedDevicesLock.Unlock()
+}
+
+func TestDeviceStats(t *testing.T) {
+	t.Cleanup(resetDeviceStats)
+	s := New()
+	s.AddDeviceTraps("10.0.0.1", 3)
+	s.AddDeviceInforms("10.0.0.1", 2)
+	s.AddDeviceInforms("10.0.0.2", 1)
+
+	assert.Equal(t, map[string]trapsStatus.DeviceStats{
+		"10.0.0.1": {Traps: 3, Informs: 2},
+		"10.0.0.2": {Informs: 1},
+	}, s.GetDeviceStats())
+
+	b := new(bytes.Buffer)
+	require.NoError(t, Provider{}.Text(false, b))
+	output := strings.Replace(b.String(), "\r\n", "\n", -1)
+	assert.Contains(t, output, "  Devices:\n    10.0.0.1: 3 traps, 2 informs\n    10.0.0.2: 0 traps, 1 informs\n")
+}
+
+func TestDeviceStatsLimit(t *testing.T) {
+	t.Cleanup(resetDeviceStats)
+	s := New()
+	for i := 0; i < maxTrackedDevices+10; i++ {
+		s.AddDeviceTraps(fmt.Sprintf("10.0.%d.%d", i/256, i%256), 1)
+	}
+	// the devices already tracked are still counted on their own
+	s.AddDeviceInforms("10.0.0.0", 1)
+	s.AddDeviceInforms("192.168.0.1", 1)
+
+	stats := s.GetDeviceStats()
+	assert.Len(t, stats, maxTrackedDevices+1)
+	assert.Equal(t, trapsStatus.DeviceStats{Traps: 1, Informs: 1}, stats["10.0.0.0"])
+	assert.Equal(t, trapsStatus.DeviceStats{Traps: 10, Informs: 1}, stats[otherDevices])
+	assert.NotContains(t, stats, "192.168.0.1")
+}
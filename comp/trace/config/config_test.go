@@ -1038,6 +1038,43 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(t, cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_SAMPLING_RULES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `[{"service":"checkout","metrics":{"http.status_code":">=500"},"sample_rate":1},{"service":"checkout","tags":{"env":"prod-*"},"sample_rate":0.05,"max_per_second":100}]`)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, []*traceconfig.SamplingRule{
+			{Service: "checkout", Metrics: map[string]string{"http.status_code": ">=500"}, SampleRate: 1},
+			{Service: "checkout", Tags: map[string]string{"env": "prod-*"}, SampleRate: 0.05, MaxPerSecond: 100},
+		}, cfg.SamplingRules)
+	})
+
+	env = "DD_APM_TAIL_SAMPLING_POLICY_TAGS"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv("DD_APM_TAIL_SAMPLING_ENABLED", "true")
+		t.Setenv("DD_APM_TAIL_SAMPLING_POLICY_LATENCY_THRESHOLD_MS", "1500")
+		t.Setenv(env, `customer.tier:premium debug`)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.TailSamplingEnabled)
+		assert.True(t, cfg.TailSamplingErrors)
+		assert.Equal(t, 10*time.Second, cfg.TailSamplingDecisionWait)
+		assert.Equal(t, 1500*time.Millisecond, cfg.TailSamplingLatencyThreshold)
+		assert.Equal(t, []*traceconfig.Tag{{K: "customer.tier", V: "premium"}, {K: "debug"}}, cfg.TailSamplingTags)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, `important1 important2:value1`)
@@ -1693,6 +1730,34 @@ func TestLoadEnv(t *testing.T) {
 		assert.True(t, cfg.Obfuscation.Valkey.RemoveAllArgs)
 	})
 
+	env = "DD_APM_OBFUSCATION_GRAPHQL_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "false")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.False(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.enabled"))
+		assert.False(t, cfg.Obfuscation.GraphQL.Enabled)
+	})
+
+	env = "DD_APM_OBFUSCATION_GRAPHQL_QUANTIZE_RESOURCE"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.quantize_resource"))
+		assert.True(t, cfg.Obfuscation.GraphQL.QuantizeResource)
+	})
+
 	env = "DD_APM_OBFUSCATION_REMOVE_STACK_TRACES"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, "true")
@@ -311,6 +311,35 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	if core.IsSet("apm_config.probabilistic_sampler.hash_seed") {
 		c.ProbabilisticSamplerHashSeed = uint32(core.GetInt("apm_config.probabilistic_sampler.hash_seed"))
 	}
+	if k := "apm_config.sampling_rules"; core.IsSet(k) {
+		rules := make([]*config.SamplingRule, 0)
+		if err := structure.UnmarshalKey(core, k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"service\": \"glob\", \"tags\": {\"tag\": \"glob\"}, \"metrics\": {\"metric\": \">=500\"}, \"sample_rate\": 0.5}]', error: %v", k, err)
+		} else {
+			c.SamplingRules = rules
+		}
+	}
+
+	if core.IsSet("apm_config.tail_sampling.enabled") {
+		c.TailSamplingEnabled = core.GetBool("apm_config.tail_sampling.enabled")
+	}
+	if core.IsSet("apm_config.tail_sampling.decision_wait") {
+		c.TailSamplingDecisionWait = getDuration(core.GetInt("apm_config.tail_sampling.decision_wait"))
+	}
+	if core.IsSet("apm_config.tail_sampling.max_buffer_size") {
+		c.TailSamplingMaxBufferSize = core.GetInt("apm_config.tail_sampling.max_buffer_size")
+	}
+	if core.IsSet("apm_config.tail_sampling.policy.errors") {
+		c.TailSamplingErrors = core.GetBool("apm_config.tail_sampling.policy.errors")
+	}
+	if core.IsSet("apm_config.tail_sampling.policy.latency_threshold_ms") {
+		c.TailSamplingLatencyThreshold = time.Duration(core.GetInt("apm_config.tail_sampling.policy.latency_threshold_ms")) * time.Millisecond
+	}
+	if core.IsSet("apm_config.tail_sampling.policy.tags") {
+		for _, tag := range core.GetStringSlice("apm_config.tail_sampling.policy.tags") {
+			c.TailSamplingTags = append(c.TailSamplingTags, splitTag(tag))
+		}
+	}
 
 	if core.IsSet("apm_config.error_tracking_standalone.enabled") {
 		c.ErrorTrackingStandalone = core.GetBool("apm_config.error_tracking_standalone.enabled")
@@ -466,6 +495,8 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	c.Obfuscation.Redis.RemoveAllArgs = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.redis.remove_all_args")
 	c.Obfuscation.Valkey.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.valkey.enabled")
 	c.Obfuscation.Valkey.RemoveAllArgs = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.valkey.remove_all_args")
+	c.Obfuscation.GraphQL.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.enabled")
+	c.Obfuscation.GraphQL.QuantizeResource = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.quantize_resource")
 	c.Obfuscation.CreditCards.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.credit_cards.enabled")
 	c.Obfuscation.CreditCards.Luhn = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.credit_cards.luhn")
 	c.Obfuscation.CreditCards.KeepValues = pkgconfigsetup.Datadog().GetStringSlice("apm_config.obfuscation.credit_cards.keep_values")
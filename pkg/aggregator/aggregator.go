@@ -224,6 +224,7 @@ func init() {
 	tagsetTlm = newTagsetTelemetry([]uint64{90, 100})
 
 	aggregatorExpvars.Set("MetricTags", expvar.Func(expMetricTags))
+	aggregatorExpvars.Set("TagAggregationRules", expvar.Func(expTagAggregationRules))
 }
 
 // BufferedAggregator aggregates metrics in buckets for dogstatsd Metrics
@@ -192,11 +192,11 @@ func (cs *CheckSampler) commit(timestamp float64) {
 
 func (cs *CheckSampler) flush() (metrics.Series, metrics.SketchSeriesList) {
 	// series
-	series := cs.series
+	series := aggregateCheckSeries(cs.series)
 	cs.series = make([]*metrics.Serie, 0)
 
 	// sketches
-	sketches := cs.sketches
+	sketches := aggregateCheckSketches(cs.sketches)
 	cs.sketches = make(metrics.SketchSeriesList, 0)
 
 	// update sampler metrics
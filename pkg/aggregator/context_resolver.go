@@ -11,6 +11,7 @@ import (
 
 	tagger "github.com/DataDog/datadog-agent/comp/core/tagger/def"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
@@ -76,6 +77,7 @@ type contextResolver struct {
 	keyGenerator     *ckey.KeyGenerator
 	taggerBuffer     *tagset.HashingTagsAccumulator
 	metricBuffer     *tagset.HashingTagsAccumulator
+	tagAggregation   tagAggregationCache
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -101,41 +103,98 @@ func newContextResolver(tagger tagger.Component, cache *tags.Store, id string) *
 
 // trackContext returns the contextKey associated with the context of the metricSample and tracks that context
 func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, timestamp int64) ckey.ContextKey {
+	contextKey, _ := cr.trackLimitedContext(metricSampleContext, timestamp, nil)
+	return contextKey
+}
+
+// trackLimitedContext is trackContext, with the new contexts submitted to the
+// limiter when it isn't nil. It returns false when the limiter drops the context.
+func (cr *contextResolver) trackLimitedContext(metricSampleContext metrics.MetricSampleContext, timestamp int64, l *limiter.Limiter) (ckey.ContextKey, bool) {
 	metricSampleContext.GetTags(cr.taggerBuffer, cr.metricBuffer, cr.tagger.EnrichTags) // tags here are not sorted and can contain duplicates
 	defer cr.taggerBuffer.Reset()
 	defer cr.metricBuffer.Reset()
 
-	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
-
-	if entry, ok := cr.contextsByKey[contextKey]; !ok {
-		mtype := metricSampleContext.GetMetricType()
-		context := &Context{
-			Name:       metricSampleContext.GetName(),
-			taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
-			metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
-			Host:       metricSampleContext.GetHost(),
-			mtype:      mtype,
-			noIndex:    metricSampleContext.IsNoIndex(),
-			source:     metricSampleContext.GetSource(),
-		}
-		cr.contextsByKey[contextKey] = resolverEntry{
-			lastSeen: timestamp,
-			context:  context,
+	// drop the tags of the tag aggregation rules, aggregating the contexts together
+	if rule := cr.tagAggregation.ruleFor(metricSampleContext.GetName()); rule != nil && !keepsContextTags(metricSampleContext) {
+		droppedTaggerTags := rule.dropTagsFrom(cr.taggerBuffer)
+		if droppedMetricTags := rule.dropTagsFrom(cr.metricBuffer); droppedTaggerTags || droppedMetricTags {
+			rule.aggregatedSamples.Inc()
 		}
+	}
 
-		cr.seendByMtype[mtype] = true
-		cr.countsByMtype[mtype]++
-		cr.bytesByMtype[mtype] += uint64(context.SizeInBytes())
-		cr.dataBytesByMtype[mtype] += uint64(context.DataSizeInBytes())
-	} else {
+	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
+
+	if entry, ok := cr.contextsByKey[contextKey]; ok {
 		// We can't assign to a field of a struct contained in map
 		cr.contextsByKey[contextKey] = resolverEntry{
 			lastSeen: timestamp,
 			context:  entry.context,
 		}
+		return contextKey, true
 	}
 
-	return contextKey
+	if l != nil {
+		var ok bool
+		contextKey, taggerKey, metricKey, ok = cr.applyLimiter(metricSampleContext, l, contextKey, taggerKey, metricKey)
+		if !ok {
+			return contextKey, false
+		}
+		// the collapsed context may already be tracked
+		if entry, found := cr.contextsByKey[contextKey]; found {
+			cr.contextsByKey[contextKey] = resolverEntry{
+				lastSeen: timestamp,
+				context:  entry.context,
+			}
+			return contextKey, true
+		}
+	}
+
+	mtype := metricSampleContext.GetMetricType()
+	context := &Context{
+		Name:       metricSampleContext.GetName(),
+		taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
+		metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
+		Host:       metricSampleContext.GetHost(),
+		mtype:      mtype,
+		noIndex:    metricSampleContext.IsNoIndex(),
+		source:     metricSampleContext.GetSource(),
+	}
+	cr.contextsByKey[contextKey] = resolverEntry{
+		lastSeen: timestamp,
+		context:  context,
+	}
+
+	cr.seendByMtype[mtype] = true
+	cr.countsByMtype[mtype]++
+	cr.bytesByMtype[mtype] += uint64(context.SizeInBytes())
+	cr.dataBytesByMtype[mtype] += uint64(context.DataSizeInBytes())
+
+	return contextKey, true
+}
+
+// applyLimiter submits a new context to the limiter. When the limiter collapses
+// some of its tags, the metric tags buffer is updated and the keys of the
+// collapsed context are returned. It returns false when the context is dropped.
+func (cr *contextResolver) applyLimiter(metricSampleContext metrics.MetricSampleContext, l *limiter.Limiter, contextKey ckey.ContextKey, taggerKey, metricKey ckey.TagsKey) (ckey.ContextKey, ckey.TagsKey, ckey.TagsKey, bool) {
+	name := metricSampleContext.GetName()
+	origin := limiter.Origin(cr.taggerBuffer.Get())
+
+	decision := l.Track(name, origin, cr.metricBuffer.Get())
+	switch decision.Action {
+	case limiter.Drop:
+		return contextKey, taggerKey, metricKey, false
+	case limiter.Collapse:
+		collapsed := l.Collapse(cr.metricBuffer.Get(), decision.CollapseKeys)
+		cr.metricBuffer.Reset()
+		cr.metricBuffer.Append(collapsed...)
+		contextKey, taggerKey, metricKey = cr.generateContextKey(metricSampleContext)
+
+		if _, found := cr.contextsByKey[contextKey]; !found && !l.TrackCollapsed(name, origin, cr.metricBuffer.Get()) {
+			return contextKey, taggerKey, metricKey, false
+		}
+	}
+
+	return contextKey, taggerKey, metricKey, true
 }
 
 func (cr *contextResolver) get(key ckey.ContextKey) (*Context, bool) {
@@ -216,24 +275,27 @@ func (cr *contextResolver) sendOriginTelemetry(timestamp float64, series metrics
 // timestampContextResolver allows tracking and expiring contexts based on time.
 type timestampContextResolver struct {
 	resolver *contextResolver
+	// limiter caps the number of contexts, it is nil when the limiter is disabled
+	limiter *limiter.Limiter
 
 	contextExpireTime int64
 	counterExpireTime int64
 }
 
-func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id string, contextExpireTime, counterExpireTime int64) *timestampContextResolver {
+func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id string, contextExpireTime, counterExpireTime int64, limiter *limiter.Limiter) *timestampContextResolver {
 	return &timestampContextResolver{
 		resolver: newContextResolver(tagger, cache, id),
+		limiter:  limiter,
 
 		contextExpireTime: contextExpireTime,
 		counterExpireTime: counterExpireTime,
 	}
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp int64) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext, currentTimestamp)
-	return contextKey
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+// It returns false when the context is dropped by the limiter.
+func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp int64) (ckey.ContextKey, bool) {
+	return cr.resolver.trackLimitedContext(metricSampleContext, currentTimestamp, cr.limiter)
 }
 
 func (cr *timestampContextResolver) length() int {
@@ -256,6 +318,9 @@ func (cr *timestampContextResolver) expireContexts(timestamp int64) {
 			ttl = cr.counterExpireTime
 		}
 		if entry.lastSeen+ttl < timestamp {
+			if cr.limiter != nil {
+				cr.limiter.Remove(entry.context.Name, limiter.Origin(entry.context.taggerTags.Tags()), entry.context.metricTags.Tags())
+			}
 			cr.resolver.remove(ck)
 		}
 	}
@@ -15,6 +15,7 @@ import (
 
 	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/impl-noop"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
@@ -139,12 +140,12 @@ func testExpireContexts(t *testing.T, store *tags.Store) {
 		Tags:       []string{"foo"},
 		SampleRate: 1,
 	}
-	contextResolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4)
+	contextResolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4, nil)
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1, 4) // expires after 6
-	contextKey2 := contextResolver.trackContext(&mSample2, 6) // expires after 8
-	contextKey3 := contextResolver.trackContext(&mSample3, 6) // expires after 10
+	contextKey1, _ := contextResolver.trackContext(&mSample1, 4) // expires after 6
+	contextKey2, _ := contextResolver.trackContext(&mSample2, 6) // expires after 8
+	contextKey3, _ := contextResolver.trackContext(&mSample3, 6) // expires after 10
 
 	// With an expireTimestap of 3, both contexts are still valid
 	contextResolver.expireContexts(4)
@@ -231,6 +232,46 @@ func TestTagDeduplication(t *testing.T) {
 	testWithTagsStore(t, testTagDeduplication)
 }
 
+func testContextLimiter(t *testing.T, store *tags.Store) {
+	l := limiter.New(limiter.Config{MetricLimit: 2, TagValueLimit: 1, Policy: limiter.PolicyCollapse, Placeholder: "other"})
+	resolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4, l)
+
+	sample := func(tags ...string) *mockSample {
+		return &mockSample{"foo", []string{"pod_name:a"}, tags}
+	}
+
+	key1, ok := resolver.trackContext(sample("env:prod", "id:1"), 4)
+	require.True(t, ok)
+	// id is over its limit and collapsed
+	key2, ok := resolver.trackContext(sample("env:prod", "id:2"), 4)
+	require.True(t, ok)
+	assertContext(t, resolver.resolver.contextsByKey[key2].context, "foo", []string{"pod_name:a", "env:prod", "id:other"}, "noop")
+	// contexts collapsing to the same tags are aggregated together
+	key3, ok := resolver.trackContext(sample("env:prod", "id:3"), 4)
+	require.True(t, ok)
+	assert.Equal(t, key2, key3)
+	// the metric is over its limit, the collapsed context is new and dropped
+	_, ok = resolver.trackContext(sample("env:dev", "id:4"), 4)
+	assert.False(t, ok)
+	assert.Equal(t, 2, resolver.length())
+
+	// known contexts are still tracked
+	key, ok := resolver.trackContext(sample("env:prod", "id:1"), 6)
+	require.True(t, ok)
+	assert.Equal(t, key1, key)
+
+	// the expired contexts are released from the limiter
+	resolver.expireContexts(7)
+	assert.Equal(t, 1, resolver.length())
+	_, ok = resolver.trackContext(sample("env:prod", "id:5"), 7)
+	assert.True(t, ok)
+	assert.Equal(t, 2, resolver.length())
+}
+
+func TestContextLimiter(t *testing.T) {
+	testWithTagsStore(t, testContextLimiter)
+}
+
 type mockSink []*metrics.Serie
 
 func (s *mockSink) Append(ms *metrics.Serie) {
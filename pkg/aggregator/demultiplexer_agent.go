@@ -19,6 +19,9 @@ import (
 	orchestratorforwarder "github.com/DataDog/datadog-agent/comp/forwarder/orchestrator"
 	haagent "github.com/DataDog/datadog-agent/comp/haagent/def"
 	compression "github.com/DataDog/datadog-agent/comp/serializer/metricscompression/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/exposition"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/remotewrite"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
@@ -29,6 +32,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics/event"
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
 )
 
 // DemultiplexerWithAggregator is a Demultiplexer running an Aggregator.
@@ -42,6 +46,7 @@ type DemultiplexerWithAggregator interface {
 	GetEventPlatformForwarder() (eventplatform.Forwarder, error)
 	GetEventsAndServiceChecksChannels() (chan []*event.Event, chan []*servicecheck.ServiceCheck)
 	DumpDogstatsdContexts(io.Writer) error
+	DogstatsdContextLimiterStats(top int) (ContextLimiterStats, bool)
 }
 
 // AgentDemultiplexer is the demultiplexer implementation for the main Agent.
@@ -81,6 +86,9 @@ type AgentDemultiplexerOptions struct {
 
 	UseDogstatsdContextLimiter bool
 	DogstatsdMaxMetricsTags    int
+
+	EnablePrometheusRemoteWrite bool
+	EnableOpenMetricsEndpoint   bool
 }
 
 // DefaultAgentDemultiplexerOptions returns the default options to initialize an AgentDemultiplexer.
@@ -105,6 +113,9 @@ type statsd struct {
 	// the noAggregationStreamWorker is the one dealing with metrics that don't need to
 	// be aggregated/sampled.
 	noAggStreamWorker *noAggregationStreamWorker
+
+	// contextLimiter caps the number of contexts of the samplers, it is nil when disabled
+	contextLimiter *limiter.Limiter
 }
 
 type forwarders struct {
@@ -115,6 +126,23 @@ type dataOutputs struct {
 	forwarders       forwarders
 	sharedSerializer serializer.MetricSerializer
 	noAggSerializer  serializer.MetricSerializer
+	// remoteWrite also sends the flushed series and sketches to Prometheus
+	// remote-write endpoints, it is nil when disabled
+	remoteWrite *remotewrite.Writer
+	// openMetrics exposes the last flushed series and sketches on a local
+	// endpoint, it is nil when disabled
+	openMetrics *exposition.Server
+	// flushListeners are the enabled outputs receiving the flushed series and
+	// sketches next to the serializer
+	flushListeners []flushListener
+}
+
+// flushListener receives the series and sketches of a flush before they are
+// serialized, Flush being called at the end of the flush.
+type flushListener interface {
+	AddSerie(*metrics.Serie)
+	AddSketch(*metrics.SketchSeries)
+	Flush()
 }
 
 // InitAndStartAgentDemultiplexer creates a new Demultiplexer and runs what's necessary
@@ -164,6 +192,8 @@ func initAgentDemultiplexer(log log.Component,
 	// statsd samplers
 	// ---------------
 
+	initTagAggregationRules(pkgconfigsetup.Datadog())
+
 	bufferSize := pkgconfigsetup.Datadog().GetInt("aggregator_buffer_size")
 	metricSamplePool := metrics.NewMetricSamplePool(MetricSamplePoolBatchSize, utils.IsTelemetryEnabled(pkgconfigsetup.Datadog()))
 	_, statsdPipelinesCount := GetDogStatsDWorkerAndPipelineCount()
@@ -171,11 +201,23 @@ func initAgentDemultiplexer(log log.Component,
 
 	statsdWorkers := make([]*timeSamplerWorker, statsdPipelinesCount)
 
+	// the context limiter is shared by the samplers as the contexts of a metric
+	// or an origin are spread over the pipelines
+	var contextLimiter *limiter.Limiter
+	if options.UseDogstatsdContextLimiter {
+		limiterConfig, err := limiter.ConfigFromAgentConfig(pkgconfigsetup.Datadog())
+		if err != nil {
+			log.Errorf("The dogstatsd context limiter is disabled: %v", err)
+		} else {
+			contextLimiter = limiter.New(limiterConfig)
+		}
+	}
+
 	for i := 0; i < statsdPipelinesCount; i++ {
 		// the sampler
 		tagsStore := tags.NewStore(pkgconfigsetup.Datadog().GetBool("aggregator_use_tags_store"), fmt.Sprintf("timesampler #%d", i))
 
-		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, tagger, agg.hostname)
+		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, tagger, agg.hostname, contextLimiter)
 
 		// its worker (process loop + flush/serialization mechanism)
 
@@ -183,6 +225,23 @@ func initAgentDemultiplexer(log log.Component,
 			bufferSize, metricSamplePool, agg.flushAndSerializeInParallel, tagsStore)
 	}
 
+	var flushListeners []flushListener
+	var remoteWrite *remotewrite.Writer
+	if options.EnablePrometheusRemoteWrite {
+		remoteWriteConfig, err := remotewrite.ConfigFromAgentConfig(pkgconfigsetup.Datadog())
+		if err != nil {
+			log.Errorf("The Prometheus remote-write output is disabled: %v", err)
+		} else {
+			remoteWrite = remotewrite.New(remoteWriteConfig, httputils.CreateHTTPTransport(pkgconfigsetup.Datadog()))
+			flushListeners = append(flushListeners, remoteWrite)
+		}
+	}
+	var openMetrics *exposition.Server
+	if options.EnableOpenMetricsEndpoint {
+		openMetrics = exposition.New(exposition.ConfigFromAgentConfig(pkgconfigsetup.Datadog()))
+		flushListeners = append(flushListeners, openMetrics)
+	}
+
 	var noAggWorker *noAggregationStreamWorker
 	var noAggSerializer serializer.MetricSerializer
 	if options.EnableNoAggregationPipeline {
@@ -212,6 +271,9 @@ func initAgentDemultiplexer(log log.Component,
 
 			sharedSerializer: sharedSerializer,
 			noAggSerializer:  noAggSerializer,
+			remoteWrite:      remoteWrite,
+			openMetrics:      openMetrics,
+			flushListeners:   flushListeners,
 		},
 
 		hostTagProvider: NewHostTagProvider(),
@@ -223,6 +285,7 @@ func initAgentDemultiplexer(log log.Component,
 			workers:           statsdWorkers,
 			metricSamplePool:  metricSamplePool,
 			noAggStreamWorker: noAggWorker,
+			contextLimiter:    contextLimiter,
 		},
 	}
 
@@ -277,6 +340,15 @@ func (d *AgentDemultiplexer) run() {
 		d.log.Debug("Forwarders started")
 	}
 
+	if d.remoteWrite != nil {
+		d.remoteWrite.Start()
+	}
+	if d.openMetrics != nil {
+		if err := d.openMetrics.Start(); err != nil {
+			d.log.Errorf("Could not start the aggregator OpenMetrics endpoint: %v", err)
+		}
+	}
+
 	for _, w := range d.statsd.workers {
 		go w.run()
 	}
@@ -366,6 +438,16 @@ func (d *AgentDemultiplexer) Stop(flush bool) {
 		}
 	}
 
+	if d.dataOutputs.remoteWrite != nil {
+		d.dataOutputs.remoteWrite.Stop(timeout)
+		d.dataOutputs.remoteWrite = nil
+	}
+	if d.dataOutputs.openMetrics != nil {
+		d.dataOutputs.openMetrics.Stop()
+		d.dataOutputs.openMetrics = nil
+	}
+	d.dataOutputs.flushListeners = nil
+
 	// misc
 
 	d.dataOutputs.sharedSerializer = nil
@@ -412,6 +494,11 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 		series,
 		sketches,
 		func(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) {
+			if len(d.flushListeners) > 0 {
+				seriesSink = flushListenersSerieSink{SerieSink: seriesSink, listeners: d.flushListeners}
+				sketchesSink = flushListenersSketchesSink{SketchesSink: sketchesSink, listeners: d.flushListeners}
+			}
+
 			// flush DogStatsD pipelines (statsd/time samplers)
 			// ------------------------------------------------
 
@@ -461,10 +548,42 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 			}
 		})
 
+	for _, listener := range d.flushListeners {
+		listener.Flush()
+	}
+
 	addFlushTime("MainFlushTime", int64(time.Since(start)))
 	aggregatorNumberOfFlush.Add(1)
 }
 
+// flushListenersSerieSink adds the flushed series to the flush listeners
+// before appending them to the serializer sink.
+type flushListenersSerieSink struct {
+	metrics.SerieSink
+	listeners []flushListener
+}
+
+func (s flushListenersSerieSink) Append(serie *metrics.Serie) {
+	for _, listener := range s.listeners {
+		listener.AddSerie(serie)
+	}
+	s.SerieSink.Append(serie)
+}
+
+// flushListenersSketchesSink adds the flushed sketches to the flush listeners
+// before appending them to the serializer sink.
+type flushListenersSketchesSink struct {
+	metrics.SketchesSink
+	listeners []flushListener
+}
+
+func (s flushListenersSketchesSink) Append(sketch *metrics.SketchSeries) {
+	for _, listener := range s.listeners {
+		listener.AddSketch(sketch)
+	}
+	s.SketchesSink.Append(sketch)
+}
+
 // GetEventsAndServiceChecksChannels returneds underlying events and service checks channels.
 func (d *AgentDemultiplexer) GetEventsAndServiceChecksChannels() (chan []*event.Event, chan []*servicecheck.ServiceCheck) {
 	return d.aggregator.GetBufferedChannels()
@@ -563,6 +682,18 @@ func (d *AgentDemultiplexer) DumpDogstatsdContexts(dest io.Writer) error {
 	return nil
 }
 
+// ContextLimiterStats is a snapshot of the dogstatsd context limiter, listing its top offenders.
+type ContextLimiterStats = limiter.Stats
+
+// DogstatsdContextLimiterStats returns the top offenders of the dogstatsd context limiter,
+// or false when the limiter is disabled.
+func (d *AgentDemultiplexer) DogstatsdContextLimiterStats(top int) (ContextLimiterStats, bool) {
+	if d.statsd.contextLimiter == nil {
+		return ContextLimiterStats{}, false
+	}
+	return d.statsd.contextLimiter.Stats(top), true
+}
+
 // GetSender returns a sender.Sender with passed ID, properly registered with the aggregator
 // If no error is returned here, DestroySender must be called with the same ID
 // once the sender is not used anymore
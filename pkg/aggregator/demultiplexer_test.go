@@ -8,9 +8,15 @@
 package aggregator
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
 	"go.uber.org/fx"
 
 	"github.com/DataDog/datadog-agent/comp/core"
@@ -26,7 +32,9 @@ import (
 	compression "github.com/DataDog/datadog-agent/comp/serializer/metricscompression/def"
 	metricscompressionmock "github.com/DataDog/datadog-agent/comp/serializer/metricscompression/fx-mock"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 
 	"github.com/stretchr/testify/assert"
@@ -310,3 +318,86 @@ func createDemuxDepsWithOrchestratorFwd(
 		EventPlatformFwd: deps.Eventplatform,
 	}
 }
+
+func TestDemuxPrometheusRemoteWrite(t *testing.T) {
+	received := make(chan *prompb.WriteRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		data, err := snappy.Decode(nil, body)
+		if err != nil {
+			return
+		}
+		req := &prompb.WriteRequest{}
+		if req.Unmarshal(data) == nil {
+			received <- req
+		}
+	}))
+	defer server.Close()
+
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("prometheus_remote_write.endpoints", []map[string]interface{}{{"url": server.URL}})
+
+	opts := demuxTestOptions()
+	opts.EnablePrometheusRemoteWrite = true
+	deps := createDemuxDeps(t, opts, eventplatformimpl.NewDefaultParams())
+	demux := deps.Demultiplexer
+	require.NotNil(t, demux.remoteWrite)
+
+	demux.AggregateSample(metrics.MetricSample{
+		Name:       "my.gauge",
+		Value:      3,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{"env:prod"},
+		SampleRate: 1,
+	})
+	defer demux.Stop(false)
+
+	// AggregateSample is async, the demultiplexer is flushed until the sample
+	// has been processed by the time sampler
+	require.Eventually(t, func() bool {
+		// the time sampler only flushes the buckets ended before the flush
+		demux.ForceFlushToSerializer(time.Now().Add(time.Minute), true)
+		select {
+		case req := <-received:
+			for _, ts := range req.Timeseries {
+				for _, l := range ts.Labels {
+					if l.Name == "__name__" && l.Value == "my_gauge" {
+						return true
+					}
+				}
+			}
+		case <-time.After(100 * time.Millisecond):
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestDemuxOpenMetricsEndpoint(t *testing.T) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("aggregator_openmetrics_endpoint.port", 0)
+
+	opts := demuxTestOptions()
+	opts.EnableOpenMetricsEndpoint = true
+	deps := createDemuxDeps(t, opts, eventplatformimpl.NewDefaultParams())
+	demux := deps.Demultiplexer
+	require.NotNil(t, demux.openMetrics)
+
+	demux.AggregateSample(metrics.MetricSample{
+		Name:       "my.gauge",
+		Value:      3,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{"env:prod"},
+		SampleRate: 1,
+	})
+	defer demux.Stop(false)
+
+	// AggregateSample is async, the demultiplexer is flushed until the sample
+	// has been processed by the time sampler
+	require.Eventually(t, func() bool {
+		// the time sampler only flushes the buckets ended before the flush
+		demux.ForceFlushToSerializer(time.Now().Add(time.Minute), true)
+		rec := httptest.NewRecorder()
+		demux.openMetrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?prefix=my.", nil))
+		return strings.Contains(rec.Body.String(), "# TYPE my_gauge gauge\nmy_gauge{env=\"prod\"")
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,272 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package exposition exposes the series and sketches of the last flush in the
+// OpenMetrics text format, for them to be scraped locally.
+package exposition
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/remotewrite"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// ContentType is the content type of the OpenMetrics text format
+	ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+	// prefixParam is the query parameter filtering the metrics by prefix
+	prefixParam = "prefix"
+)
+
+// Config is the configuration of the OpenMetrics endpoint.
+type Config struct {
+	// Address the endpoint listens on
+	Address string
+	// MetricPrefixes limits the exposed metrics to the ones starting with one
+	// of the prefixes, all the metrics are exposed when it is empty
+	MetricPrefixes []string
+}
+
+// ConfigFromAgentConfig reads the `aggregator_openmetrics_endpoint` configuration.
+func ConfigFromAgentConfig(cfg model.Reader) Config {
+	return Config{
+		Address:        net.JoinHostPort(cfg.GetString("aggregator_openmetrics_endpoint.host"), cfg.GetString("aggregator_openmetrics_endpoint.port")),
+		MetricPrefixes: cfg.GetStringSlice("aggregator_openmetrics_endpoint.metric_prefixes"),
+	}
+}
+
+type metricType string
+
+const (
+	typeGauge   metricType = "gauge"
+	typeSummary metricType = "summary"
+)
+
+// family is the exposed metric of a series or a sketch, with the Prometheus series
+// of all its contexts.
+type family struct {
+	// source is the name of the metric in the agent, used to filter the families
+	source string
+	name   string
+	typ    metricType
+	series []prompb.TimeSeries
+}
+
+// Server exposes the series and sketches of the last flush. The series of a
+// flush are added with AddSerie and AddSketch, and exposed by Flush.
+type Server struct {
+	converter *remotewrite.Converter
+	prefixes  []string
+	server    *http.Server
+
+	mu       sync.RWMutex
+	pending  map[string]*family
+	families []*family
+}
+
+// New returns a Server for the given configuration. Start must be called for
+// the metrics to be exposed.
+func New(cfg Config) *Server {
+	s := &Server{
+		converter: remotewrite.NewConverter(nil, remotewrite.SketchSummary),
+		prefixes:  cfg.MetricPrefixes,
+		pending:   make(map[string]*family),
+	}
+	s.server = &http.Server{
+		Addr:    cfg.Address,
+		Handler: s,
+	}
+	return s
+}
+
+// Start starts listening on the configured address.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Error serving the aggregator OpenMetrics endpoint on %s: %v", s.server.Addr, err)
+		}
+	}()
+	return nil
+}
+
+// Stop stops listening.
+func (s *Server) Stop() {
+	if err := s.server.Shutdown(context.Background()); err != nil {
+		log.Errorf("Error shutting down the aggregator OpenMetrics endpoint: %v", err)
+	}
+}
+
+// AddSerie adds a series to the next exposed metrics, as a gauge.
+func (s *Server) AddSerie(serie *metrics.Serie) {
+	if !matchPrefix(serie.Name, s.prefixes) {
+		return
+	}
+	s.add(serie.Name, typeGauge, s.converter.Serie(serie))
+}
+
+// AddSketch adds a sketch to the next exposed metrics, as a summary.
+func (s *Server) AddSketch(sketch *metrics.SketchSeries) {
+	if !matchPrefix(sketch.Name, s.prefixes) {
+		return
+	}
+	s.add(sketch.Name, typeSummary, s.converter.Sketch(sketch))
+}
+
+func (s *Server) add(source string, typ metricType, series []prompb.TimeSeries) {
+	if len(series) == 0 {
+		return
+	}
+	// the first series of a summary holds its name, the last ones its sum and count
+	name := metricName(series[0].Labels)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, found := s.pending[name]
+	if !found {
+		f = &family{source: source, name: name, typ: typ}
+		s.pending[name] = f
+	}
+	f.series = append(f.series, series...)
+}
+
+// Flush replaces the exposed metrics by the ones added since the last flush.
+func (s *Server) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	families := make([]*family, 0, len(s.pending))
+	for _, f := range s.pending {
+		families = append(families, f)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+	s.families = families
+	s.pending = make(map[string]*family, len(s.pending))
+}
+
+// ServeHTTP writes the metrics of the last flush, filtered by the prefixes of
+// the `prefix` query parameters.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefixes := r.URL.Query()[prefixParam]
+
+	s.mu.RLock()
+	families := s.families
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", ContentType)
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		if matchPrefix(f.source, prefixes) {
+			writeFamily(bw, f)
+		}
+	}
+	bw.WriteString("# EOF\n")
+	if err := bw.Flush(); err != nil {
+		log.Debugf("Could not write the aggregator OpenMetrics response: %v", err)
+	}
+}
+
+func matchPrefix(name string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func metricName(labels []prompb.Label) string {
+	for _, l := range labels {
+		if l.Name == "__name__" {
+			return l.Value
+		}
+	}
+	return ""
+}
+
+// writeFamily writes the last sample of every series of a family. The series are
+// exposed with their timestamps, as the metrics of the last flush can be older
+// than the scrape.
+func writeFamily(w *bufio.Writer, f *family) {
+	w.WriteString("# TYPE ")
+	w.WriteString(f.name)
+	w.WriteByte(' ')
+	w.WriteString(string(f.typ))
+	w.WriteByte('\n')
+
+	for _, series := range f.series {
+		if len(series.Samples) == 0 {
+			continue
+		}
+		sample := series.Samples[len(series.Samples)-1]
+
+		w.WriteString(metricName(series.Labels))
+		writeLabels(w, series.Labels)
+		w.WriteByte(' ')
+		w.WriteString(formatFloat(sample.Value))
+		w.WriteByte(' ')
+		// OpenMetrics timestamps are in seconds
+		w.WriteString(strconv.FormatFloat(float64(sample.Timestamp)/1000, 'f', -1, 64))
+		w.WriteByte('\n')
+	}
+}
+
+func writeLabels(w *bufio.Writer, labels []prompb.Label) {
+	first := true
+	for _, l := range labels {
+		if l.Name == "__name__" {
+			continue
+		}
+		if first {
+			w.WriteByte('{')
+			first = false
+		} else {
+			w.WriteByte(',')
+		}
+		w.WriteString(l.Name)
+		w.WriteString(`="`)
+		labelValueReplacer.WriteString(w, l.Value) //nolint:errcheck
+		w.WriteByte('"')
+	}
+	if !first {
+		w.WriteByte('}')
+	}
+}
+
+// labelValueReplacer escapes the label values
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
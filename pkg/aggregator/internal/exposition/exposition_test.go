@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package exposition
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func scrape(t *testing.T, s *Server, query string) string {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+query, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func testSketch(values ...float64) *quantile.Sketch {
+	var agent quantile.Agent
+	for _, v := range values {
+		agent.Insert(v, 1)
+	}
+	return agent.Finish()
+}
+
+func TestServer(t *testing.T) {
+	s := New(Config{})
+	assert.Equal(t, "# EOF\n", scrape(t, s, ""))
+
+	s.AddSerie(&metrics.Serie{
+		Name:   "my.requests",
+		Host:   "myhost",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"path:/a\"b"}),
+		Points: []metrics.Point{{Ts: 10, Value: 1}, {Ts: 20, Value: 2}},
+	})
+	s.AddSerie(&metrics.Serie{
+		Name:   "my.requests",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"path:/c"}),
+		Points: []metrics.Point{{Ts: 20, Value: 3.5}},
+	})
+	s.AddSketch(&metrics.SketchSeries{
+		Name:   "my.latency",
+		Points: []metrics.SketchPoint{{Ts: 20, Sketch: testSketch(0, 0, 0)}},
+	})
+
+	// the metrics are exposed after the flush
+	assert.Equal(t, "# EOF\n", scrape(t, s, ""))
+	s.Flush()
+
+	assert.Equal(t, `# TYPE my_latency summary
+my_latency{quantile="0.5"} 0 20
+my_latency{quantile="0.9"} 0 20
+my_latency{quantile="0.95"} 0 20
+my_latency{quantile="0.99"} 0 20
+my_latency_sum 0 20
+my_latency_count 3 20
+# TYPE my_requests gauge
+my_requests{host="myhost",path="/a\"b"} 2 20
+my_requests{path="/c"} 3.5 20
+# EOF
+`, scrape(t, s, ""))
+
+	assert.Equal(t, `# TYPE my_requests gauge
+my_requests{host="myhost",path="/a\"b"} 2 20
+my_requests{path="/c"} 3.5 20
+# EOF
+`, scrape(t, s, "?prefix=my.req&prefix=other."))
+
+	// the next flush replaces the exposed metrics
+	s.Flush()
+	assert.Equal(t, "# EOF\n", scrape(t, s, ""))
+}
+
+func TestServerMetricPrefixes(t *testing.T) {
+	s := New(Config{MetricPrefixes: []string{"kept."}})
+	s.AddSerie(&metrics.Serie{Name: "kept.metric", Points: []metrics.Point{{Ts: 10, Value: 1}}})
+	s.AddSerie(&metrics.Serie{Name: "dropped.metric", Points: []metrics.Point{{Ts: 10, Value: 1}}})
+	s.Flush()
+
+	assert.Equal(t, "# TYPE kept_metric gauge\nkept_metric 1 10\n# EOF\n", scrape(t, s, ""))
+}
+
+func TestServerStart(t *testing.T) {
+	// reserve a free port for the server
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	s := New(Config{Address: address})
+	s.AddSerie(&metrics.Serie{Name: "my.metric", Points: []metrics.Point{{Ts: 10, Value: 1}}})
+	s.Flush()
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	resp, err := http.Get("http://" + address + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "# TYPE my_metric gauge\nmy_metric 1 10\n# EOF\n", string(body))
+}
+
+func TestConfigFromAgentConfig(t *testing.T) {
+	cfg := mock.NewFromYAML(t, `
+aggregator_openmetrics_endpoint:
+  enabled: true
+  port: 6000
+  metric_prefixes: [my_app.]
+`)
+	assert.Equal(t, Config{Address: "localhost:6000", MetricPrefixes: []string{"my_app."}}, ConfigFromAgentConfig(cfg))
+}
@@ -0,0 +1,380 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package limiter implements the dogstatsd context limiter, capping the number
+// of contexts tracked by the aggregator per metric name, per origin and per tag
+// key.
+package limiter
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+// Policy is what the limiter does with the contexts over the limits.
+type Policy string
+
+const (
+	// PolicyDrop drops the samples of the contexts over the limits.
+	PolicyDrop Policy = "drop"
+	// PolicyCollapse replaces the value of the offending tag of the contexts
+	// over the limits with a placeholder, aggregating them into a single context.
+	PolicyCollapse Policy = "collapse"
+)
+
+// Reason is the limit a context is over.
+type Reason string
+
+const (
+	// ReasonMetric is the limit of contexts per metric name.
+	ReasonMetric Reason = "metric"
+	// ReasonOrigin is the limit of contexts per origin.
+	ReasonOrigin Reason = "origin"
+	// ReasonTag is the limit of values per tag key.
+	ReasonTag Reason = "tag"
+)
+
+// Action is what has to be done with a new context.
+type Action int
+
+const (
+	// Allow means the context is under the limits and has been tracked.
+	Allow Action = iota
+	// Drop means the samples of the context must be dropped.
+	Drop
+	// Collapse means the values of some of the tags of the context must be
+	// replaced by the placeholder before calling TrackCollapsed.
+	Collapse
+)
+
+// Decision is the result of the evaluation of a new context by the limiter.
+type Decision struct {
+	Action Action
+	Reason Reason
+	// CollapseKeys are the keys of the tags to collapse, when Action is Collapse.
+	CollapseKeys []string
+}
+
+// Config holds the limits. A limit of zero disables it.
+type Config struct {
+	// MetricLimit is the maximum number of contexts per metric name.
+	MetricLimit int
+	// OriginLimit is the maximum number of contexts per origin. Contexts
+	// without origin are not limited.
+	OriginLimit int
+	// TagValueLimit is the maximum number of values per tag key of a metric.
+	TagValueLimit int
+	// Policy is what to do with the contexts over the limits.
+	Policy Policy
+	// Placeholder is the value given to the collapsed tags.
+	Placeholder string
+}
+
+// ConfigFromAgentConfig reads the limiter configuration from the agent
+// configuration.
+func ConfigFromAgentConfig(cfg model.Reader) (Config, error) {
+	c := Config{
+		MetricLimit:   cfg.GetInt("dogstatsd_context_limiter.metric_limit"),
+		OriginLimit:   cfg.GetInt("dogstatsd_context_limiter.origin_limit"),
+		TagValueLimit: cfg.GetInt("dogstatsd_context_limiter.tag_value_limit"),
+		Policy:        Policy(cfg.GetString("dogstatsd_context_limiter.policy")),
+		Placeholder:   cfg.GetString("dogstatsd_context_limiter.placeholder"),
+	}
+
+	if c.Policy != PolicyDrop && c.Policy != PolicyCollapse {
+		return c, fmt.Errorf("unknown dogstatsd context limiter policy %q, expected %q or %q", c.Policy, PolicyDrop, PolicyCollapse)
+	}
+	if c.Policy == PolicyCollapse && c.Placeholder == "" {
+		return c, fmt.Errorf("the dogstatsd context limiter placeholder can't be empty with the %q policy", PolicyCollapse)
+	}
+	if c.MetricLimit < 0 || c.OriginLimit < 0 || c.TagValueLimit < 0 {
+		return c, fmt.Errorf("the dogstatsd context limits can't be negative")
+	}
+
+	return c, nil
+}
+
+var (
+	tlmDropped = telemetry.NewCounter("aggregator", "dogstatsd_context_limiter_dropped",
+		[]string{"reason"}, "Number of new dogstatsd contexts dropped by the context limiter")
+	tlmCollapsed = telemetry.NewCounter("aggregator", "dogstatsd_context_limiter_collapsed",
+		[]string{"reason"}, "Number of new dogstatsd contexts collapsed by the context limiter")
+)
+
+type rejections struct {
+	dropped   uint64
+	collapsed uint64
+}
+
+func (r *rejections) add(action Action) {
+	if action == Drop {
+		r.dropped++
+	} else if action == Collapse {
+		r.collapsed++
+	}
+}
+
+type tagEntry struct {
+	rejections
+	values map[string]int // value -> number of contexts
+}
+
+type metricEntry struct {
+	rejections
+	contexts int
+	tags     map[string]*tagEntry // tag key -> values
+}
+
+type originEntry struct {
+	rejections
+	contexts int
+}
+
+// Limiter tracks the contexts per metric name, origin and tag key, and decides
+// what to do with the new contexts over the limits.
+//
+// The limiter is shared by the time samplers and is safe for concurrent use. It
+// is only involved in the creation and the expiration of contexts, not in the
+// processing of the samples of the known contexts.
+type Limiter struct {
+	mu        sync.Mutex
+	cfg       Config
+	trackTags bool
+	metrics   map[string]*metricEntry
+	origins   map[string]*originEntry
+}
+
+// New returns a new limiter.
+func New(cfg Config) *Limiter {
+	return &Limiter{
+		cfg: cfg,
+		// the values of the tags are needed to pick the tag to collapse
+		trackTags: cfg.TagValueLimit > 0 || cfg.Policy == PolicyCollapse,
+		metrics:   map[string]*metricEntry{},
+		origins:   map[string]*originEntry{},
+	}
+}
+
+// Origin returns the identifier of the origin of a context from its tagger tags.
+func Origin(taggerTags []string) string {
+	if len(taggerTags) == 0 {
+		return ""
+	}
+	tags := make([]string, len(taggerTags))
+	copy(tags, taggerTags)
+	sort.Strings(tags)
+	return strings.Join(tags, ",")
+}
+
+// Track evaluates a new context. When the context is allowed, it is tracked
+// until Remove is called.
+func (l *Limiter) Track(name, origin string, tags []string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m := l.metrics[name]
+	o := l.origins[origin]
+
+	var reason Reason
+	var tagKeys []string
+
+	if l.cfg.TagValueLimit > 0 && m != nil {
+		for _, tag := range tags {
+			key, value, found := strings.Cut(tag, ":")
+			if !found || value == l.cfg.Placeholder {
+				continue
+			}
+			t := m.tags[key]
+			if t == nil || len(t.values) < l.cfg.TagValueLimit {
+				continue
+			}
+			if _, known := t.values[value]; !known {
+				reason = ReasonTag
+				tagKeys = append(tagKeys, key)
+			}
+		}
+	}
+
+	collapseKeys := tagKeys
+	if over := l.overContextLimits(m, origin, o); over != "" {
+		reason = over
+		if key := highestCardinalityKey(m, tags, l.cfg.Placeholder); key != "" && !slices.Contains(collapseKeys, key) {
+			collapseKeys = append(slices.Clip(collapseKeys), key)
+		}
+	}
+
+	if reason == "" {
+		l.add(name, origin, tags)
+		return Decision{Action: Allow}
+	}
+
+	d := Decision{Action: Drop, Reason: reason}
+	if l.cfg.Policy == PolicyCollapse && len(collapseKeys) > 0 {
+		d = Decision{Action: Collapse, Reason: reason, CollapseKeys: collapseKeys}
+	}
+	l.reject(d, m, o, tagKeys)
+	return d
+}
+
+// TrackCollapsed evaluates a new context resulting from the collapse of the
+// tags of another one. It returns false when the context must still be
+// dropped.
+func (l *Limiter) TrackCollapsed(name, origin string, tags []string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m := l.metrics[name]
+	o := l.origins[origin]
+	if over := l.overContextLimits(m, origin, o); over != "" {
+		l.reject(Decision{Action: Drop, Reason: over}, m, o, nil)
+		return false
+	}
+
+	l.add(name, origin, tags)
+	return true
+}
+
+// Remove stops tracking a context previously allowed.
+func (l *Limiter) Remove(name, origin string, tags []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if m, found := l.metrics[name]; found {
+		m.contexts--
+		if l.trackTags {
+			for _, tag := range tags {
+				key, value, found := strings.Cut(tag, ":")
+				if !found {
+					continue
+				}
+				t := m.tags[key]
+				if t == nil {
+					continue
+				}
+				t.values[value]--
+				if t.values[value] <= 0 {
+					delete(t.values, value)
+				}
+				if len(t.values) == 0 {
+					delete(m.tags, key)
+				}
+			}
+		}
+		if m.contexts <= 0 {
+			delete(l.metrics, name)
+		}
+	}
+
+	if o, found := l.origins[origin]; found {
+		o.contexts--
+		if o.contexts <= 0 {
+			delete(l.origins, origin)
+		}
+	}
+}
+
+// Collapse returns a copy of tags where the values of the given keys are
+// replaced by the placeholder.
+func (l *Limiter) Collapse(tags []string, keys []string) []string {
+	res := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		key, _, found := strings.Cut(tag, ":")
+		if found && slices.Contains(keys, key) {
+			tag = key + ":" + l.cfg.Placeholder
+		}
+		res = append(res, tag)
+	}
+	return res
+}
+
+func (l *Limiter) overContextLimits(m *metricEntry, origin string, o *originEntry) Reason {
+	if l.cfg.MetricLimit > 0 && m != nil && m.contexts >= l.cfg.MetricLimit {
+		return ReasonMetric
+	}
+	if l.cfg.OriginLimit > 0 && origin != "" && o != nil && o.contexts >= l.cfg.OriginLimit {
+		return ReasonOrigin
+	}
+	return ""
+}
+
+func (l *Limiter) add(name, origin string, tags []string) {
+	m := l.metrics[name]
+	if m == nil {
+		m = &metricEntry{tags: map[string]*tagEntry{}}
+		l.metrics[name] = m
+	}
+	m.contexts++
+
+	if l.trackTags {
+		for _, tag := range tags {
+			key, value, found := strings.Cut(tag, ":")
+			if !found {
+				continue
+			}
+			t := m.tags[key]
+			if t == nil {
+				t = &tagEntry{values: map[string]int{}}
+				m.tags[key] = t
+			}
+			t.values[value]++
+		}
+	}
+
+	o := l.origins[origin]
+	if o == nil {
+		o = &originEntry{}
+		l.origins[origin] = o
+	}
+	o.contexts++
+}
+
+// reject records the rejection of a context, crediting the metric, the origin
+// and the tag keys over their limit.
+func (l *Limiter) reject(d Decision, m *metricEntry, o *originEntry, tagKeys []string) {
+	switch d.Action {
+	case Drop:
+		tlmDropped.Inc(string(d.Reason))
+	case Collapse:
+		tlmCollapsed.Inc(string(d.Reason))
+	}
+
+	if m != nil {
+		m.add(d.Action)
+		for _, key := range tagKeys {
+			m.tags[key].add(d.Action)
+		}
+	}
+	if o != nil {
+		o.add(d.Action)
+	}
+}
+
+// highestCardinalityKey returns the key of the tags of a context with the most
+// values among the contexts of the metric.
+func highestCardinalityKey(m *metricEntry, tags []string, placeholder string) string {
+	if m == nil {
+		return ""
+	}
+
+	var res string
+	maxValues := 0
+	for _, tag := range tags {
+		key, value, found := strings.Cut(tag, ":")
+		if !found || value == placeholder {
+			continue
+		}
+		if t := m.tags[key]; t != nil && (len(t.values) > maxValues || (len(t.values) == maxValues && key < res)) {
+			res = key
+			maxValues = len(t.values)
+		}
+	}
+	return res
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package limiter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func TestMetricLimit(t *testing.T) {
+	l := New(Config{MetricLimit: 2, Policy: PolicyDrop})
+
+	assert.Equal(t, Allow, l.Track("foo", "", []string{"id:1"}).Action)
+	assert.Equal(t, Allow, l.Track("foo", "", []string{"id:2"}).Action)
+	assert.Equal(t, Decision{Action: Drop, Reason: ReasonMetric}, l.Track("foo", "", []string{"id:3"}))
+	// other metrics are not affected
+	assert.Equal(t, Allow, l.Track("bar", "", []string{"id:3"}).Action)
+
+	// a context expiring makes room for a new one
+	l.Remove("foo", "", []string{"id:1"})
+	assert.Equal(t, Allow, l.Track("foo", "", []string{"id:3"}).Action)
+
+	stats := l.Stats(1)
+	require.Len(t, stats.Metrics, 1)
+	assert.Equal(t, EntryStats{Name: "foo", Contexts: 2, Dropped: 1}, stats.Metrics[0])
+}
+
+func TestOriginLimit(t *testing.T) {
+	l := New(Config{OriginLimit: 1, Policy: PolicyDrop})
+
+	assert.Equal(t, Allow, l.Track("foo", "pod_name:a", nil).Action)
+	assert.Equal(t, Decision{Action: Drop, Reason: ReasonOrigin}, l.Track("bar", "pod_name:a", nil))
+	assert.Equal(t, Allow, l.Track("bar", "pod_name:b", nil).Action)
+
+	// contexts without origin are not limited
+	assert.Equal(t, Allow, l.Track("foo", "", []string{"id:1"}).Action)
+	assert.Equal(t, Allow, l.Track("foo", "", []string{"id:2"}).Action)
+
+	stats := l.Stats(0)
+	assert.Equal(t, []EntryStats{
+		{Name: "pod_name:a", Contexts: 1, Dropped: 1},
+		{Name: "", Contexts: 2},
+		{Name: "pod_name:b", Contexts: 1},
+	}, stats.Origins)
+}
+
+func TestTagValueLimit(t *testing.T) {
+	l := New(Config{TagValueLimit: 2, Policy: PolicyDrop})
+
+	assert.Equal(t, Allow, l.Track("foo", "", []string{"env:prod", "id:1"}).Action)
+	assert.Equal(t, Allow, l.Track("foo", "", []string{"env:prod", "id:2"}).Action)
+	assert.Equal(t, Decision{Action: Drop, Reason: ReasonTag}, l.Track("foo", "", []string{"env:prod", "id:3"}))
+	// known values are still accepted
+	assert.Equal(t, Allow, l.Track("foo", "", []string{"env:dev", "id:1"}).Action)
+
+	stats := l.Stats(0)
+	assert.Equal(t, []TagStats{
+		{Metric: "foo", Key: "id", Values: 2, Dropped: 1},
+		{Metric: "foo", Key: "env", Values: 2},
+	}, stats.Tags)
+}
+
+func TestCollapse(t *testing.T) {
+	l := New(Config{MetricLimit: 3, TagValueLimit: 2, Policy: PolicyCollapse, Placeholder: "other"})
+
+	assert.Equal(t, Allow, l.Track("foo", "", []string{"env:prod", "id:1"}).Action)
+	assert.Equal(t, Allow, l.Track("foo", "", []string{"env:prod", "id:2"}).Action)
+
+	d := l.Track("foo", "", []string{"env:prod", "id:3"})
+	assert.Equal(t, Decision{Action: Collapse, Reason: ReasonTag, CollapseKeys: []string{"id"}}, d)
+	collapsed := l.Collapse([]string{"env:prod", "id:3"}, d.CollapseKeys)
+	assert.Equal(t, []string{"env:prod", "id:other"}, collapsed)
+	assert.True(t, l.TrackCollapsed("foo", "", collapsed))
+
+	// the metric limit is reached, the collapsed context is dropped
+	d = l.Track("foo", "", []string{"env:dev", "id:4"})
+	assert.Equal(t, Collapse, d.Action)
+	assert.Equal(t, ReasonMetric, d.Reason)
+	assert.Equal(t, []string{"id"}, d.CollapseKeys)
+	assert.False(t, l.TrackCollapsed("foo", "", l.Collapse([]string{"env:dev", "id:4"}, d.CollapseKeys)))
+
+	stats := l.Stats(0)
+	assert.Equal(t, []EntryStats{{Name: "foo", Contexts: 3, Dropped: 1, Collapsed: 2}}, stats.Metrics)
+}
+
+func TestCollapseWithoutTags(t *testing.T) {
+	l := New(Config{MetricLimit: 1, Policy: PolicyCollapse, Placeholder: "other"})
+
+	assert.Equal(t, Allow, l.Track("foo", "", nil).Action)
+	// there is no tag to collapse
+	assert.Equal(t, Decision{Action: Drop, Reason: ReasonMetric}, l.Track("foo", "", []string{"standalone"}))
+}
+
+func TestRemoveCleansUp(t *testing.T) {
+	l := New(Config{TagValueLimit: 1, Policy: PolicyDrop})
+
+	l.Track("foo", "pod_name:a", []string{"id:1"})
+	l.Remove("foo", "pod_name:a", []string{"id:1"})
+
+	assert.Empty(t, l.metrics)
+	assert.Empty(t, l.origins)
+}
+
+func TestOrigin(t *testing.T) {
+	assert.Equal(t, "", Origin(nil))
+	tags := []string{"pod_name:a", "kube_namespace:b"}
+	assert.Equal(t, "kube_namespace:b,pod_name:a", Origin(tags))
+	// the tags are not modified
+	assert.Equal(t, []string{"pod_name:a", "kube_namespace:b"}, tags)
+}
+
+func TestConfigFromAgentConfig(t *testing.T) {
+	cfg := mock.New(t)
+	cfg.SetWithoutSource("dogstatsd_context_limiter.metric_limit", 100)
+	cfg.SetWithoutSource("dogstatsd_context_limiter.policy", "collapse")
+
+	c, err := ConfigFromAgentConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, Config{MetricLimit: 100, Policy: PolicyCollapse, Placeholder: "other"}, c)
+
+	cfg.SetWithoutSource("dogstatsd_context_limiter.policy", "sample")
+	_, err = ConfigFromAgentConfig(cfg)
+	assert.Error(t, err)
+}
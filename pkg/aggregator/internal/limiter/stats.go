@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package limiter
+
+import (
+	"sort"
+)
+
+// Stats is a snapshot of the state of the limiter, listing its top offenders.
+//
+// A collapsed context still over the limits once collapsed is counted both as
+// collapsed and as dropped.
+type Stats struct {
+	MetricLimit   int    `json:"metric_limit"`
+	OriginLimit   int    `json:"origin_limit"`
+	TagValueLimit int    `json:"tag_value_limit"`
+	Policy        Policy `json:"policy"`
+
+	Metrics []EntryStats `json:"metrics"`
+	Origins []EntryStats `json:"origins"`
+	Tags    []TagStats   `json:"tags"`
+}
+
+// EntryStats are the statistics of a metric name or an origin.
+type EntryStats struct {
+	Name      string `json:"name"`
+	Contexts  int    `json:"contexts"`
+	Dropped   uint64 `json:"dropped"`
+	Collapsed uint64 `json:"collapsed"`
+}
+
+// TagStats are the statistics of a tag key of a metric.
+type TagStats struct {
+	Metric    string `json:"metric"`
+	Key       string `json:"key"`
+	Values    int    `json:"values"`
+	Dropped   uint64 `json:"dropped"`
+	Collapsed uint64 `json:"collapsed"`
+}
+
+// Stats returns the top offenders of the limiter: the metric names, origins
+// and tag keys with the most rejected contexts, then with the most contexts.
+// At most top entries are returned for each of them.
+func (l *Limiter) Stats(top int) Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := Stats{
+		MetricLimit:   l.cfg.MetricLimit,
+		OriginLimit:   l.cfg.OriginLimit,
+		TagValueLimit: l.cfg.TagValueLimit,
+		Policy:        l.cfg.Policy,
+		Metrics:       []EntryStats{},
+		Origins:       []EntryStats{},
+		Tags:          []TagStats{},
+	}
+
+	for name, m := range l.metrics {
+		s.Metrics = append(s.Metrics, EntryStats{Name: name, Contexts: m.contexts, Dropped: m.dropped, Collapsed: m.collapsed})
+		for key, t := range m.tags {
+			s.Tags = append(s.Tags, TagStats{Metric: name, Key: key, Values: len(t.values), Dropped: t.dropped, Collapsed: t.collapsed})
+		}
+	}
+	for origin, o := range l.origins {
+		s.Origins = append(s.Origins, EntryStats{Name: origin, Contexts: o.contexts, Dropped: o.dropped, Collapsed: o.collapsed})
+	}
+
+	sortEntries(s.Metrics)
+	sortEntries(s.Origins)
+	sort.Slice(s.Tags, func(i, j int) bool {
+		a, b := s.Tags[i], s.Tags[j]
+		if ra, rb := a.Dropped+a.Collapsed, b.Dropped+b.Collapsed; ra != rb {
+			return ra > rb
+		}
+		if a.Values != b.Values {
+			return a.Values > b.Values
+		}
+		if a.Metric != b.Metric {
+			return a.Metric < b.Metric
+		}
+		return a.Key < b.Key
+	})
+
+	if top > 0 {
+		s.Metrics = s.Metrics[:min(top, len(s.Metrics))]
+		s.Origins = s.Origins[:min(top, len(s.Origins))]
+		s.Tags = s.Tags[:min(top, len(s.Tags))]
+	}
+
+	return s
+}
+
+func sortEntries(entries []EntryStats) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if ra, rb := a.Dropped+a.Collapsed, b.Dropped+b.Collapsed; ra != rb {
+			return ra > rb
+		}
+		if a.Contexts != b.Contexts {
+			return a.Contexts > b.Contexts
+		}
+		return a.Name < b.Name
+	})
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/util/relabel"
+)
+
+// SketchFormat is how the sketches are converted to Prometheus series.
+type SketchFormat string
+
+const (
+	// SketchSummary converts a sketch to the series of a summary: a few quantiles, their sum and count.
+	SketchSummary SketchFormat = "summary"
+	// SketchNativeHistogram converts a sketch to a native histogram.
+	SketchNativeHistogram SketchFormat = "native_histogram"
+)
+
+// EndpointConfig is a remote-write endpoint the payloads are sent to.
+type EndpointConfig struct {
+	URL         string            `mapstructure:"url"`
+	Headers     map[string]string `mapstructure:"headers"`
+	Username    string            `mapstructure:"username"`
+	Password    string            `mapstructure:"password"`
+	BearerToken string            `mapstructure:"bearer_token"`
+}
+
+// Config is the configuration of the remote-write output.
+type Config struct {
+	Endpoints []EndpointConfig
+	// Timeout of a request to an endpoint
+	Timeout time.Duration
+	// BatchSize is the maximum number of series in a payload
+	BatchSize int
+	// QueueSize is the maximum number of payloads waiting to be sent to an endpoint
+	QueueSize int
+	// MaxRetries is the number of times a payload is retried after a recoverable error
+	MaxRetries int
+	// BackoffBase and BackoffMax bound the time between two retries, in seconds
+	BackoffBase float64
+	BackoffMax  float64
+
+	SketchFormat SketchFormat
+	// Relabel rules are applied to the labels built from the tags of every series
+	Relabel []*relabel.Rule
+}
+
+// ConfigFromAgentConfig reads the `prometheus_remote_write` configuration.
+func ConfigFromAgentConfig(cfg model.Reader) (Config, error) {
+	c := Config{
+		Timeout:      cfg.GetDuration("prometheus_remote_write.timeout") * time.Second,
+		BatchSize:    cfg.GetInt("prometheus_remote_write.batch_size"),
+		QueueSize:    cfg.GetInt("prometheus_remote_write.queue_size"),
+		MaxRetries:   cfg.GetInt("prometheus_remote_write.max_retries"),
+		BackoffBase:  cfg.GetFloat64("prometheus_remote_write.backoff_base"),
+		BackoffMax:   cfg.GetFloat64("prometheus_remote_write.backoff_max"),
+		SketchFormat: SketchFormat(cfg.GetString("prometheus_remote_write.sketch_format")),
+	}
+
+	if err := structure.UnmarshalKey(cfg, "prometheus_remote_write.endpoints", &c.Endpoints); err != nil {
+		return c, fmt.Errorf("invalid endpoints: %w", err)
+	}
+	if len(c.Endpoints) == 0 {
+		return c, errors.New("no endpoint is configured")
+	}
+	for _, endpoint := range c.Endpoints {
+		u, err := url.Parse(endpoint.URL)
+		if err != nil {
+			return c, fmt.Errorf("invalid endpoint url: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return c, fmt.Errorf("invalid endpoint url %q: the scheme must be http or https", u.Redacted())
+		}
+	}
+
+	switch c.SketchFormat {
+	case SketchSummary, SketchNativeHistogram:
+	default:
+		return c, fmt.Errorf("unknown sketch format %q, expected %s or %s", c.SketchFormat, SketchSummary, SketchNativeHistogram)
+	}
+	if c.BatchSize <= 0 {
+		return c, fmt.Errorf("batch_size must be positive, got %d", c.BatchSize)
+	}
+	if c.QueueSize <= 0 {
+		return c, fmt.Errorf("queue_size must be positive, got %d", c.QueueSize)
+	}
+	if c.BackoffBase <= 0 || c.BackoffMax < c.BackoffBase {
+		return c, fmt.Errorf("invalid backoff: backoff_base must be positive and lower than backoff_max")
+	}
+
+	var relabelConfigs []relabel.Config
+	if err := structure.UnmarshalKey(cfg, "prometheus_remote_write.relabel_configs", &relabelConfigs); err != nil {
+		return c, fmt.Errorf("invalid relabel_configs: %w", err)
+	}
+	rules, err := relabel.NewRules(relabelConfigs)
+	if err != nil {
+		return c, err
+	}
+	c.Relabel = rules
+
+	return c, nil
+}
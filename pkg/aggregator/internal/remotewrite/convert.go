@@ -0,0 +1,253 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/relabel"
+)
+
+const (
+	hostLabel     = "host"
+	quantileLabel = "quantile"
+
+	// nativeHistogramSchema is the schema of the native histograms built from
+	// sketches: the buckets grow by a factor of 2^(1/32), a bit more than the
+	// bins of the sketches, so a bucket gathers one or two bins.
+	nativeHistogramSchema = 5
+)
+
+// summaryQuantiles are the quantiles of the summaries built from sketches
+var summaryQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+// sketchMinValue is the lowest value of the default quantile configuration,
+// the lower values being in the zero bin of the sketches.
+const sketchMinValue = 1e-9
+
+// The sketches use the default quantile configuration, their bin k holds the
+// values around sketchGamma^(k-sketchBias).
+var (
+	sketchGamma = 1 + 2.0/128
+	sketchBias  = 1 - int(math.Floor(math.Log(sketchMinValue)/math.Log(sketchGamma)))
+)
+
+// Converter converts the aggregated series and sketches to Prometheus series.
+type Converter struct {
+	relabel      []*relabel.Rule
+	sketchFormat SketchFormat
+}
+
+// NewConverter returns a Converter applying the relabeling rules to the series,
+// and converting the sketches to the given format.
+func NewConverter(rules []*relabel.Rule, sketchFormat SketchFormat) *Converter {
+	return &Converter{relabel: rules, sketchFormat: sketchFormat}
+}
+
+// labels returns the sorted labels of a series, or false if it is dropped by
+// the relabeling rules. The tags are converted to labels named after their
+// keys, the values of tags with the same key being joined by a comma, and the
+// tags without values are dropped.
+func (c *Converter) labels(name, host string, tags tagset.CompositeTags, extra ...string) ([]prompb.Label, bool) {
+	labels := make(map[string]string, tags.Len()+2)
+	tags.ForEach(func(tag string) {
+		key, value, found := strings.Cut(tag, ":")
+		if !found || value == "" {
+			return
+		}
+		key = sanitizeName(key)
+		if previous, ok := labels[key]; ok {
+			value = previous + "," + value
+		}
+		labels[key] = value
+	})
+	if _, found := labels[hostLabel]; !found && host != "" {
+		labels[hostLabel] = host
+	}
+	labels[relabel.MetricNameLabel] = sanitizeName(name)
+
+	if !relabel.Apply(c.relabel, labels) {
+		return nil, false
+	}
+	if labels[relabel.MetricNameLabel] == "" {
+		return nil, false
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		labels[extra[i]] = extra[i+1]
+	}
+
+	res := make([]prompb.Label, 0, len(labels))
+	for name, value := range labels {
+		if value != "" {
+			res = append(res, prompb.Label{Name: name, Value: value})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, true
+}
+
+// Serie converts a series to a Prometheus series with the same points.
+func (c *Converter) Serie(serie *metrics.Serie) []prompb.TimeSeries {
+	labels, ok := c.labels(serie.Name, serie.Host, serie.Tags)
+	if !ok {
+		return nil
+	}
+
+	samples := make([]prompb.Sample, 0, len(serie.Points))
+	for _, p := range serie.Points {
+		samples = append(samples, prompb.Sample{Value: p.Value, Timestamp: int64(p.Ts * 1000)})
+	}
+	return []prompb.TimeSeries{{Labels: labels, Samples: samples}}
+}
+
+// Sketch converts a sketch to the series of a summary, or to a native histogram.
+// The series of a summary are the quantiles, then the sum and the count.
+func (c *Converter) Sketch(sketch *metrics.SketchSeries) []prompb.TimeSeries {
+	if c.sketchFormat == SketchNativeHistogram {
+		labels, ok := c.labels(sketch.Name, sketch.Host, sketch.Tags)
+		if !ok {
+			return nil
+		}
+		histograms := make([]prompb.Histogram, 0, len(sketch.Points))
+		for _, p := range sketch.Points {
+			histograms = append(histograms, nativeHistogram(p.Sketch, p.Ts*1000))
+		}
+		return []prompb.TimeSeries{{Labels: labels, Histograms: histograms}}
+	}
+
+	res := make([]prompb.TimeSeries, 0, len(summaryQuantiles)+2)
+	for _, q := range summaryQuantiles {
+		labels, ok := c.labels(sketch.Name, sketch.Host, sketch.Tags, quantileLabel, strconv.FormatFloat(q, 'g', -1, 64))
+		if !ok {
+			return nil
+		}
+		samples := make([]prompb.Sample, 0, len(sketch.Points))
+		for _, p := range sketch.Points {
+			samples = append(samples, prompb.Sample{Value: p.Sketch.Quantile(quantile.Default(), q), Timestamp: p.Ts * 1000})
+		}
+		res = append(res, prompb.TimeSeries{Labels: labels, Samples: samples})
+	}
+	for _, suffix := range []string{"_sum", "_count"} {
+		labels, ok := c.labels(sketch.Name+suffix, sketch.Host, sketch.Tags)
+		if !ok {
+			continue
+		}
+		samples := make([]prompb.Sample, 0, len(sketch.Points))
+		for _, p := range sketch.Points {
+			value := p.Sketch.Basic.Sum
+			if suffix == "_count" {
+				value = float64(p.Sketch.Basic.Cnt)
+			}
+			samples = append(samples, prompb.Sample{Value: value, Timestamp: p.Ts * 1000})
+		}
+		res = append(res, prompb.TimeSeries{Labels: labels, Samples: samples})
+	}
+	return res
+}
+
+// nativeHistogram approximates a sketch with a gauge native histogram, as the
+// sketch only holds the values of a flush interval.
+func nativeHistogram(sketch *quantile.Sketch, timestamp int64) prompb.Histogram {
+	positive := make(map[int32]int64)
+	negative := make(map[int32]int64)
+	var zeroCount uint64
+
+	keys, counts := sketch.Cols()
+	for i, k := range keys {
+		switch {
+		case k == 0:
+			zeroCount += uint64(counts[i])
+		case k > 0:
+			positive[bucketIndex(k)] += int64(counts[i])
+		default:
+			negative[bucketIndex(-k)] += int64(counts[i])
+		}
+	}
+
+	h := prompb.Histogram{
+		Count:         &prompb.Histogram_CountInt{CountInt: uint64(sketch.Basic.Cnt)},
+		Sum:           sketch.Basic.Sum,
+		Schema:        nativeHistogramSchema,
+		ZeroThreshold: sketchMinValue,
+		ZeroCount:     &prompb.Histogram_ZeroCountInt{ZeroCountInt: zeroCount},
+		ResetHint:     prompb.Histogram_GAUGE,
+		Timestamp:     timestamp,
+	}
+	h.PositiveSpans, h.PositiveDeltas = bucketSpans(positive)
+	h.NegativeSpans, h.NegativeDeltas = bucketSpans(negative)
+	return h
+}
+
+// bucketIndex returns the index of the native histogram bucket holding the
+// values of a positive sketch key.
+func bucketIndex(k int32) int32 {
+	if k >= math.MaxInt16 {
+		// +Inf values land in the last bucket
+		return math.MaxInt32
+	}
+	// the middle of the bin, in log2
+	log2 := (float64(int(k)-sketchBias) + 0.5) * math.Log2(sketchGamma)
+	return int32(math.Ceil(log2 * (1 << nativeHistogramSchema)))
+}
+
+// bucketSpans encodes the counts of the buckets as spans of consecutive buckets
+// and the deltas between their counts.
+func bucketSpans(buckets map[int32]int64) ([]prompb.BucketSpan, []int64) {
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+	indexes := make([]int32, 0, len(buckets))
+	for index := range buckets {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	var spans []prompb.BucketSpan
+	deltas := make([]int64, 0, len(indexes))
+	var previousIndex int32
+	var previousCount int64
+	for i, index := range indexes {
+		switch {
+		case i == 0:
+			spans = append(spans, prompb.BucketSpan{Offset: index, Length: 1})
+		case index == previousIndex+1:
+			spans[len(spans)-1].Length++
+		default:
+			spans = append(spans, prompb.BucketSpan{Offset: index - previousIndex - 1, Length: 1})
+		}
+		deltas = append(deltas, buckets[index]-previousCount)
+		previousIndex, previousCount = index, buckets[index]
+	}
+	return spans, deltas
+}
+
+// sanitizeName converts a metric name or a tag key to a valid Prometheus name.
+func sanitizeName(name string) string {
+	var b strings.Builder
+	b.Grow(len(name) + 1)
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
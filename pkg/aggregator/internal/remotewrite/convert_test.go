@@ -0,0 +1,174 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"math"
+	"testing"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/relabel"
+)
+
+func labelMap(labels []prompb.Label) map[string]string {
+	res := make(map[string]string, len(labels))
+	for _, l := range labels {
+		res[l.Name] = l.Value
+	}
+	return res
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func pow2(x float64) float64 {
+	return math.Pow(2, x)
+}
+
+func testSketch(values ...float64) *quantile.Sketch {
+	var agent quantile.Agent
+	for _, v := range values {
+		agent.Insert(v, 1)
+	}
+	return agent.Finish()
+}
+
+func TestConvertSerie(t *testing.T) {
+	c := NewConverter(nil, SketchSummary)
+	series := c.Serie(&metrics.Serie{
+		Name:   "my.app-requests",
+		Host:   "myhost",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod", "team.name:a", "team.name:b", "standalone"}),
+		MType:  metrics.APICountType,
+		Points: []metrics.Point{{Ts: 10, Value: 1}, {Ts: 20.5, Value: 2}},
+	})
+
+	require.Len(t, series, 1)
+	assert.Equal(t, []prompb.Label{
+		{Name: "__name__", Value: "my_app_requests"},
+		{Name: "env", Value: "prod"},
+		{Name: "host", Value: "myhost"},
+		{Name: "team_name", Value: "a,b"},
+	}, series[0].Labels)
+	assert.Equal(t, []prompb.Sample{{Value: 1, Timestamp: 10000}, {Value: 2, Timestamp: 20500}}, series[0].Samples)
+}
+
+func TestConvertSerieRelabel(t *testing.T) {
+	rules, err := relabel.NewRules([]relabel.Config{{
+		SourceLabels: []string{"__name__"},
+		Regex:        stringPtr("dropped_.*"),
+		Action:       "drop",
+	}, {
+		Action: "labeldrop",
+		Regex:  stringPtr("host"),
+	}})
+	require.NoError(t, err)
+	c := NewConverter(rules, SketchSummary)
+
+	assert.Empty(t, c.Serie(&metrics.Serie{Name: "dropped.metric", Points: []metrics.Point{{Ts: 10, Value: 1}}}))
+
+	series := c.Serie(&metrics.Serie{Name: "kept.metric", Host: "myhost", Points: []metrics.Point{{Ts: 10, Value: 1}}})
+	require.Len(t, series, 1)
+	assert.Equal(t, map[string]string{"__name__": "kept_metric"}, labelMap(series[0].Labels))
+}
+
+func TestConvertSketchSummary(t *testing.T) {
+	c := NewConverter(nil, SketchSummary)
+	series := c.Sketch(&metrics.SketchSeries{
+		Name:   "my.latency",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod"}),
+		Points: []metrics.SketchPoint{{Ts: 10, Sketch: testSketch(1, 2, 3, 4, 5)}},
+	})
+
+	require.Len(t, series, len(summaryQuantiles)+2)
+	byQuantile := make(map[string]float64)
+	for _, s := range series {
+		labels := labelMap(s.Labels)
+		assert.Equal(t, "prod", labels["env"])
+		require.Len(t, s.Samples, 1)
+		assert.Equal(t, int64(10000), s.Samples[0].Timestamp)
+		switch labels["__name__"] {
+		case "my_latency":
+			byQuantile[labels["quantile"]] = s.Samples[0].Value
+		case "my_latency_sum":
+			assert.Equal(t, 15.0, s.Samples[0].Value)
+		case "my_latency_count":
+			assert.Equal(t, 5.0, s.Samples[0].Value)
+		default:
+			t.Errorf("unexpected series %v", labels)
+		}
+	}
+	assert.Len(t, byQuantile, len(summaryQuantiles))
+	assert.InDelta(t, 3, byQuantile["0.5"], 0.1)
+	assert.InDelta(t, 5, byQuantile["0.99"], 0.1)
+}
+
+func TestConvertSketchNativeHistogram(t *testing.T) {
+	c := NewConverter(nil, SketchNativeHistogram)
+	series := c.Sketch(&metrics.SketchSeries{
+		Name:   "my.latency",
+		Points: []metrics.SketchPoint{{Ts: 10, Sketch: testSketch(0, 1, 1, 2, 100, -3)}},
+	})
+
+	require.Len(t, series, 1)
+	assert.Equal(t, map[string]string{"__name__": "my_latency"}, labelMap(series[0].Labels))
+	require.Len(t, series[0].Histograms, 1)
+	h := series[0].Histograms[0]
+	assert.Equal(t, int64(10000), h.Timestamp)
+	assert.Equal(t, prompb.Histogram_GAUGE, h.ResetHint)
+	assert.Equal(t, uint64(6), h.GetCountInt())
+	assert.Equal(t, 101.0, h.Sum)
+	assert.Equal(t, uint64(1), h.GetZeroCountInt())
+
+	// the buckets hold all the non-zero values
+	assert.Equal(t, int64(4), bucketsTotal(h.PositiveDeltas))
+	assert.Equal(t, int64(1), bucketsTotal(h.NegativeDeltas))
+}
+
+func bucketsTotal(deltas []int64) int64 {
+	var count, total int64
+	for _, d := range deltas {
+		count += d
+		total += count
+	}
+	return total
+}
+
+func TestBucketIndex(t *testing.T) {
+	// a native histogram bucket with schema 5 and index i holds (2^((i-1)/32), 2^(i/32)]
+	for _, v := range []float64{1e-6, 0.5, 1, 3, 1000, 1e9} {
+		keys, _ := testSketch(v).Cols()
+		require.Len(t, keys, 1)
+		index := bucketIndex(keys[0])
+		upper := pow2(float64(index) / 32)
+		lower := pow2(float64(index-1) / 32)
+		// the middle of a sketch bin is at most a bin away from the value
+		assert.InDelta(t, v, (lower+upper)/2, v*0.03, "value %v, index %d", v, index)
+	}
+}
+
+func TestBucketSpans(t *testing.T) {
+	spans, deltas := bucketSpans(map[int32]int64{3: 2, 4: 5, 8: 1})
+	assert.Equal(t, []prompb.BucketSpan{{Offset: 3, Length: 2}, {Offset: 3, Length: 1}}, spans)
+	assert.Equal(t, []int64{2, 3, -4}, deltas)
+
+	spans, deltas = bucketSpans(nil)
+	assert.Nil(t, spans)
+	assert.Nil(t, deltas)
+}
+
+func TestSanitizeName(t *testing.T) {
+	assert.Equal(t, "system_cpu_user", sanitizeName("system.cpu.user"))
+	assert.Equal(t, "_2xx_count", sanitizeName("2xx-count"))
+	assert.Equal(t, "kube_pod_name", sanitizeName("kube_pod_name"))
+}
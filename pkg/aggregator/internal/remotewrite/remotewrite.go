@@ -0,0 +1,259 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package remotewrite sends the aggregated series and sketches to Prometheus
+// remote-write endpoints, next to the Datadog intake.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+var (
+	tlmPayloads = telemetry.NewCounter("aggregator", "prometheus_remote_write_payloads",
+		[]string{"endpoint", "status"}, "Number of payloads sent, failed or dropped by the Prometheus remote-write output")
+	tlmSeries = telemetry.NewCounter("aggregator", "prometheus_remote_write_series",
+		[]string{"endpoint"}, "Number of series sent by the Prometheus remote-write output")
+)
+
+// Writer converts the series and sketches of a flush to Prometheus series, and
+// sends them to the remote-write endpoints. The series of a flush are added with
+// AddSerie and AddSketch, and sent by Flush.
+type Writer struct {
+	converter *Converter
+	batchSize int
+	endpoints []*endpoint
+
+	mu      sync.Mutex
+	pending []prompb.TimeSeries
+}
+
+// New returns a Writer sending payloads to the endpoints of the configuration
+// with the given transport. Start must be called for the payloads to be sent.
+func New(cfg Config, transport http.RoundTripper) *Writer {
+	w := &Writer{
+		converter: NewConverter(cfg.Relabel, cfg.SketchFormat),
+		batchSize: cfg.BatchSize,
+	}
+	client := &http.Client{Transport: transport, Timeout: cfg.Timeout}
+	for _, endpointConfig := range cfg.Endpoints {
+		w.endpoints = append(w.endpoints, newEndpoint(endpointConfig, cfg, client))
+	}
+	return w
+}
+
+// Start starts sending the payloads.
+func (w *Writer) Start() {
+	for _, e := range w.endpoints {
+		e.wg.Add(1)
+		go e.run()
+	}
+}
+
+// Stop stops sending the payloads, after trying to send the queued ones for at
+// most timeout. Flush must not be called after Stop.
+func (w *Writer) Stop(timeout time.Duration) {
+	for _, e := range w.endpoints {
+		close(e.queue)
+	}
+	timer := time.AfterFunc(timeout, func() {
+		for _, e := range w.endpoints {
+			e.cancel()
+		}
+	})
+	defer timer.Stop()
+	for _, e := range w.endpoints {
+		e.wg.Wait()
+	}
+}
+
+// AddSerie adds a series to the next payloads. The series isn't retained.
+func (w *Writer) AddSerie(serie *metrics.Serie) {
+	series := w.converter.Serie(serie)
+	w.mu.Lock()
+	w.pending = append(w.pending, series...)
+	w.mu.Unlock()
+}
+
+// AddSketch adds a sketch to the next payloads. The sketch isn't retained.
+func (w *Writer) AddSketch(sketch *metrics.SketchSeries) {
+	series := w.converter.Sketch(sketch)
+	w.mu.Lock()
+	w.pending = append(w.pending, series...)
+	w.mu.Unlock()
+}
+
+// Flush splits the series added since the last flush in payloads, and queues
+// them to be sent to every endpoint.
+func (w *Writer) Flush() {
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+
+	for len(pending) > 0 {
+		n := min(len(pending), w.batchSize)
+		payload, err := encode(pending[:n])
+		if err != nil {
+			log.Errorf("Could not encode a Prometheus remote-write payload: %v", err)
+		} else {
+			for _, e := range w.endpoints {
+				e.enqueue(payload, n)
+			}
+		}
+		pending = pending[n:]
+	}
+}
+
+// encode marshals a remote-write request, and compresses it with snappy.
+func encode(series []prompb.TimeSeries) ([]byte, error) {
+	req := &prompb.WriteRequest{Timeseries: series}
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, data), nil
+}
+
+type payload struct {
+	data   []byte
+	series int
+}
+
+// endpoint sends the payloads of its queue to a remote-write endpoint, one at a time.
+type endpoint struct {
+	config     EndpointConfig
+	name       string
+	client     *http.Client
+	queue      chan payload
+	maxRetries int
+	backoff    backoff.Policy
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newEndpoint(endpointConfig EndpointConfig, cfg Config, client *http.Client) *endpoint {
+	// the name identifies the endpoint in the telemetry and the logs without its credentials
+	name := endpointConfig.URL
+	if u, err := url.Parse(endpointConfig.URL); err == nil {
+		name = u.Host
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &endpoint{
+		config:     endpointConfig,
+		name:       name,
+		client:     client,
+		queue:      make(chan payload, cfg.QueueSize),
+		maxRetries: cfg.MaxRetries,
+		backoff:    backoff.NewExpBackoffPolicy(2, cfg.BackoffBase, cfg.BackoffMax, 1, false),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// enqueue queues a payload, dropping it if the queue is full.
+func (e *endpoint) enqueue(data []byte, series int) {
+	select {
+	case e.queue <- payload{data: data, series: series}:
+	default:
+		tlmPayloads.Inc(e.name, "dropped")
+		log.Warnf("The Prometheus remote-write queue of %s is full, dropping a payload of %d series", e.name, series)
+	}
+}
+
+func (e *endpoint) run() {
+	defer e.wg.Done()
+	for p := range e.queue {
+		if err := e.sendWithRetries(p); err != nil {
+			tlmPayloads.Inc(e.name, "error")
+			log.Errorf("Could not send a payload of %d series to the Prometheus remote-write endpoint %s: %v", p.series, e.name, err)
+			continue
+		}
+		tlmPayloads.Inc(e.name, "success")
+		tlmSeries.Add(float64(p.series), e.name)
+	}
+}
+
+func (e *endpoint) sendWithRetries(p payload) error {
+	for attempt := 0; ; attempt++ {
+		err := e.send(p.data)
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(*retryableError); !ok || attempt >= e.maxRetries {
+			return err
+		}
+		log.Debugf("Retrying a payload to the Prometheus remote-write endpoint %s: %v", e.name, err)
+
+		select {
+		case <-time.After(e.backoff.GetBackoffDuration(attempt + 1)):
+		case <-e.ctx.Done():
+			return err
+		}
+	}
+}
+
+// retryableError is an error after which the payload can be sent again: a
+// network error, a rate limit or a server error.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *endpoint) send(data []byte) error {
+	req, err := http.NewRequestWithContext(e.ctx, http.MethodPost, e.config.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for name, value := range e.config.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "datadog-agent/"+version.AgentVersion)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if e.config.Username != "" {
+		req.SetBasicAuth(e.config.Username, e.config.Password)
+	} else if e.config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+e.config.BearerToken)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5 {
+		return &retryableError{err: err}
+	}
+	return err
+}
@@ -0,0 +1,208 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// testServer records the remote-write requests it receives, answering with the
+// given status codes before succeeding.
+type testServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []*prompb.WriteRequest
+	headers  []http.Header
+}
+
+func newTestServer(t *testing.T, statuses ...int) *testServer {
+	s := &testServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if len(s.statuses) > 0 {
+			status := s.statuses[0]
+			s.statuses = s.statuses[1:]
+			w.WriteHeader(status)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		data, err := snappy.Decode(nil, body)
+		require.NoError(t, err)
+		req := &prompb.WriteRequest{}
+		require.NoError(t, req.Unmarshal(data))
+		s.requests = append(s.requests, req)
+		s.headers = append(s.headers, r.Header.Clone())
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) received() ([]*prompb.WriteRequest, []http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests, s.headers
+}
+
+func testConfig(endpoints ...EndpointConfig) Config {
+	return Config{
+		Endpoints:    endpoints,
+		Timeout:      time.Second,
+		BatchSize:    2,
+		QueueSize:    10,
+		MaxRetries:   2,
+		BackoffBase:  0.001,
+		BackoffMax:   0.001,
+		SketchFormat: SketchSummary,
+	}
+}
+
+func TestWriter(t *testing.T) {
+	server := newTestServer(t)
+	w := New(testConfig(EndpointConfig{
+		URL:         server.URL,
+		Headers:     map[string]string{"X-Scope-OrgID": "tenant"},
+		BearerToken: "token",
+	}), http.DefaultTransport)
+	w.Start()
+
+	for _, name := range []string{"a", "b", "c"} {
+		w.AddSerie(&metrics.Serie{Name: name, Points: []metrics.Point{{Ts: 10, Value: 1}}})
+	}
+	w.Flush()
+	w.Stop(time.Second)
+
+	requests, headers := server.received()
+	// the batch size splits the series in two payloads
+	require.Len(t, requests, 2)
+	assert.Len(t, requests[0].Timeseries, 2)
+	assert.Len(t, requests[1].Timeseries, 1)
+	assert.Equal(t, "c", requests[1].Timeseries[0].Labels[0].Value)
+
+	assert.Equal(t, "snappy", headers[0].Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", headers[0].Get("Content-Type"))
+	assert.Equal(t, "0.1.0", headers[0].Get("X-Prometheus-Remote-Write-Version"))
+	assert.Equal(t, "tenant", headers[0].Get("X-Scope-OrgID"))
+	assert.Equal(t, "Bearer token", headers[0].Get("Authorization"))
+}
+
+func TestWriterRetries(t *testing.T) {
+	retried := newTestServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	rejected := newTestServer(t, http.StatusBadRequest)
+	exhausted := newTestServer(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+
+	w := New(testConfig(
+		EndpointConfig{URL: retried.URL, Username: "user", Password: "pass"},
+		EndpointConfig{URL: rejected.URL},
+		EndpointConfig{URL: exhausted.URL},
+	), http.DefaultTransport)
+	w.Start()
+	w.AddSerie(&metrics.Serie{Name: "a", Points: []metrics.Point{{Ts: 10, Value: 1}}})
+	w.Flush()
+	w.Stop(time.Second)
+
+	requests, headers := retried.received()
+	require.Len(t, requests, 1)
+	user, pass, ok := (&http.Request{Header: headers[0]}).BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "pass", pass)
+
+	// client errors aren't retried
+	requests, _ = rejected.received()
+	assert.Empty(t, requests)
+	// the payload is dropped after max_retries retries
+	requests, _ = exhausted.received()
+	assert.Empty(t, requests)
+}
+
+func TestWriterQueueFull(t *testing.T) {
+	server := newTestServer(t)
+	cfg := testConfig(EndpointConfig{URL: server.URL})
+	cfg.QueueSize = 1
+	w := New(cfg, http.DefaultTransport)
+
+	// the endpoint isn't started, the second payload doesn't fit in the queue
+	for _, name := range []string{"a", "b", "c"} {
+		w.AddSerie(&metrics.Serie{Name: name, Points: []metrics.Point{{Ts: 10, Value: 1}}})
+	}
+	w.Flush()
+	w.Start()
+	w.Stop(time.Second)
+
+	requests, _ := server.received()
+	require.Len(t, requests, 1)
+	assert.Len(t, requests[0].Timeseries, 2)
+}
+
+func TestConfigFromAgentConfig(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		cfg := mock.NewFromYAML(t, `
+prometheus_remote_write:
+  enabled: true
+  sketch_format: native_histogram
+  endpoints:
+    - url: https://prometheus.example.com/api/v1/write
+      headers:
+        X-Scope-OrgID: tenant
+      bearer_token: token
+  relabel_configs:
+    - source_labels: [__name__]
+      regex: "system_.*"
+      action: drop
+`)
+		c, err := ConfigFromAgentConfig(cfg)
+		require.NoError(t, err)
+		assert.Equal(t, []EndpointConfig{{
+			URL:         "https://prometheus.example.com/api/v1/write",
+			Headers:     map[string]string{"X-Scope-OrgID": "tenant"},
+			BearerToken: "token",
+		}}, c.Endpoints)
+		assert.Equal(t, SketchNativeHistogram, c.SketchFormat)
+		assert.Equal(t, 10*time.Second, c.Timeout)
+		assert.Equal(t, 2000, c.BatchSize)
+		assert.Len(t, c.Relabel, 1)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_PROMETHEUS_REMOTE_WRITE_ENDPOINTS", `[{"url": "http://localhost:9090/api/v1/write"}]`)
+		c, err := ConfigFromAgentConfig(mock.New(t))
+		require.NoError(t, err)
+		require.Len(t, c.Endpoints, 1)
+		assert.Equal(t, "http://localhost:9090/api/v1/write", c.Endpoints[0].URL)
+	})
+
+	for name, yaml := range map[string]string{
+		"no endpoint":        `prometheus_remote_write: {enabled: true}`,
+		"invalid url":        `prometheus_remote_write: {endpoints: [{url: "localhost:9090"}]}`,
+		"invalid format":     `prometheus_remote_write: {sketch_format: histogram, endpoints: [{url: "http://localhost"}]}`,
+		"invalid batch":      `prometheus_remote_write: {batch_size: 0, endpoints: [{url: "http://localhost"}]}`,
+		"invalid backoff":    `prometheus_remote_write: {backoff_base: 10, backoff_max: 1, endpoints: [{url: "http://localhost"}]}`,
+		"invalid relabeling": `prometheus_remote_write: {relabel_configs: [{action: unknown}], endpoints: [{url: "http://localhost"}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ConfigFromAgentConfig(mock.NewFromYAML(t, yaml))
+			assert.Error(t, err)
+		})
+	}
+}
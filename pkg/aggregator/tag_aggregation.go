@@ -0,0 +1,405 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	tagAggregationRulesConfigKey = "tag_aggregation_rules"
+
+	// maximum number of metric names whose matching rule is cached by a context resolver
+	tagAggregationCacheSize = 4096
+)
+
+// TagAggregationRuleConfig is the configuration of a tag aggregation rule: the
+// metrics matching one of the names lose the tags with the given keys, their
+// contexts being aggregated together.
+type TagAggregationRuleConfig struct {
+	Name string `mapstructure:"name" json:"name"`
+	// MetricNames are metric names, `*` matching any sequence of characters
+	MetricNames []string `mapstructure:"metric_names" json:"metric_names"`
+	DropTags    []string `mapstructure:"drop_tags" json:"drop_tags"`
+}
+
+// tagAggregationRule is a compiled TagAggregationRuleConfig
+type tagAggregationRule struct {
+	config   TagAggregationRuleConfig
+	names    map[string]struct{}
+	patterns []*regexp.Regexp
+	dropTags map[string]struct{}
+
+	// number of samples whose tags were dropped
+	aggregatedSamples *atomic.Uint64
+}
+
+func newTagAggregationRule(cfg TagAggregationRuleConfig) (*tagAggregationRule, error) {
+	if len(cfg.MetricNames) == 0 {
+		return nil, errors.New("no metric name")
+	}
+	if len(cfg.DropTags) == 0 {
+		return nil, errors.New("no tag to drop")
+	}
+
+	rule := &tagAggregationRule{
+		config:            cfg,
+		names:             make(map[string]struct{}),
+		dropTags:          make(map[string]struct{}, len(cfg.DropTags)),
+		aggregatedSamples: atomic.NewUint64(0),
+	}
+	for _, name := range cfg.MetricNames {
+		if name == "" {
+			return nil, errors.New("empty metric name")
+		}
+		if !strings.Contains(name, "*") {
+			rule.names[name] = struct{}{}
+			continue
+		}
+		pattern := "^" + strings.ReplaceAll(regexp.QuoteMeta(name), `\*`, ".*") + "$"
+		rule.patterns = append(rule.patterns, regexp.MustCompile(pattern))
+	}
+	for _, key := range cfg.DropTags {
+		if key == "" {
+			return nil, errors.New("empty tag key")
+		}
+		rule.dropTags[key] = struct{}{}
+	}
+	return rule, nil
+}
+
+func (r *tagAggregationRule) matches(name string) bool {
+	if _, found := r.names[name]; found {
+		return true
+	}
+	for _, pattern := range r.patterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *tagAggregationRule) keepTag(tag string) bool {
+	key, _, _ := strings.Cut(tag, ":")
+	_, drop := r.dropTags[key]
+	return !drop
+}
+
+// dropTagsFrom removes the tags to drop from buf in place, and returns whether
+// some tags were removed
+func (r *tagAggregationRule) dropTagsFrom(buf *tagset.HashingTagsAccumulator) bool {
+	tags, hashes := buf.Get(), buf.Hashes()
+	n := 0
+	for i, tag := range tags {
+		if r.keepTag(tag) {
+			tags[n] = tag
+			hashes[n] = hashes[i]
+			n++
+		}
+	}
+	if n == len(tags) {
+		return false
+	}
+	buf.Truncate(n)
+	return true
+}
+
+// tagAggregationRules is an immutable set of rules. The first rule matching a
+// metric name applies.
+type tagAggregationRules struct {
+	rules []*tagAggregationRule
+}
+
+func (r *tagAggregationRules) match(name string) *tagAggregationRule {
+	for _, rule := range r.rules {
+		if rule.matches(name) {
+			return rule
+		}
+	}
+	return nil
+}
+
+var (
+	// currentTagAggregationRules are the rules applied by the samplers, reloaded
+	// when the setting is updated at runtime
+	currentTagAggregationRules atomic.Pointer[tagAggregationRules]
+	watchTagAggregationRules   sync.Once
+)
+
+// initTagAggregationRules loads the tag aggregation rules and reloads them when
+// the setting is updated at runtime
+func initTagAggregationRules(cfg model.Reader) {
+	setTagAggregationRules(loadTagAggregationRules(cfg))
+	watchTagAggregationRules.Do(func() {
+		cfg.OnUpdate(func(setting string, _, _ any) {
+			if setting == tagAggregationRulesConfigKey {
+				setTagAggregationRules(loadTagAggregationRules(cfg))
+			}
+		})
+	})
+}
+
+func setTagAggregationRules(rules []*tagAggregationRule) {
+	if len(rules) == 0 {
+		currentTagAggregationRules.Store(nil)
+		return
+	}
+	currentTagAggregationRules.Store(&tagAggregationRules{rules: rules})
+}
+
+// ValidateTagAggregationRules returns an error if the JSON list of rules isn't valid
+func ValidateTagAggregationRules(rules string) error {
+	var configs []TagAggregationRuleConfig
+	if err := json.Unmarshal([]byte(rules), &configs); err != nil {
+		return fmt.Errorf("invalid tag aggregation rules: %w", err)
+	}
+
+	var errs []error
+	for i, cfg := range configs {
+		if _, err := newTagAggregationRule(cfg); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadTagAggregationRules reads `tag_aggregation_rules`, either as a list or a
+// JSON string, and returns the valid rules. Invalid rules are logged and skipped.
+func loadTagAggregationRules(cfg model.Reader) []*tagAggregationRule {
+	var configs []TagAggregationRuleConfig
+	if raw, ok := cfg.Get(tagAggregationRulesConfigKey).(string); ok {
+		if raw != "" {
+			if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+				log.Errorf("failed to parse %s: %v", tagAggregationRulesConfigKey, err)
+				return nil
+			}
+		}
+	} else if err := structure.UnmarshalKey(cfg, tagAggregationRulesConfigKey, &configs); err != nil {
+		log.Errorf("failed to parse %s: %v", tagAggregationRulesConfigKey, err)
+		return nil
+	}
+
+	rules := make([]*tagAggregationRule, 0, len(configs))
+	for i, ruleConfig := range configs {
+		rule, err := newTagAggregationRule(ruleConfig)
+		if err != nil {
+			log.Errorf("ignoring tag aggregation rule %d %q: %v", i, ruleConfig.Name, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// tagAggregationRuleStatus is the status of a rule, as exposed in the expvars
+type tagAggregationRuleStatus struct {
+	Name              string
+	MetricNames       []string
+	DropTags          []string
+	AggregatedSamples uint64
+}
+
+func expTagAggregationRules() interface{} {
+	rules := currentTagAggregationRules.Load()
+	if rules == nil {
+		return []tagAggregationRuleStatus{}
+	}
+
+	res := make([]tagAggregationRuleStatus, 0, len(rules.rules))
+	for _, rule := range rules.rules {
+		res = append(res, tagAggregationRuleStatus{
+			Name:              rule.config.Name,
+			MetricNames:       rule.config.MetricNames,
+			DropTags:          rule.config.DropTags,
+			AggregatedSamples: rule.aggregatedSamples.Load(),
+		})
+	}
+	return res
+}
+
+// keepsContextTags returns whether the tags of a metric must be kept when its
+// context is tracked, even if a rule matches it. The value of these metrics is
+// computed from the previous samples of the same context, so their tags are
+// only dropped when flushing, see aggregateCheckSeries.
+func keepsContextTags(metricSampleContext metrics.MetricSampleContext) bool {
+	if bucket, ok := metricSampleContext.(*metrics.HistogramBucket); ok {
+		return bucket.Monotonic
+	}
+	switch metricSampleContext.GetMetricType() {
+	case metrics.RateType, metrics.MonotonicCountType:
+		return true
+	}
+	return false
+}
+
+// tagAggregationCache caches the rule matching each metric name for a context
+// resolver. It isn't thread-safe.
+type tagAggregationCache struct {
+	rules  *tagAggregationRules
+	byName map[string]*tagAggregationRule
+}
+
+// ruleFor returns the rule matching a metric name, or nil
+func (c *tagAggregationCache) ruleFor(name string) *tagAggregationRule {
+	rules := currentTagAggregationRules.Load()
+	if rules == nil {
+		return nil
+	}
+
+	if c.rules != rules || len(c.byName) >= tagAggregationCacheSize {
+		c.rules = rules
+		c.byName = make(map[string]*tagAggregationRule)
+	}
+
+	rule, found := c.byName[name]
+	if !found {
+		rule = rules.match(name)
+		c.byName[name] = rule
+	}
+	return rule
+}
+
+// aggregateCheckSeries drops the tags of the series matching a rule and sums the
+// series left with the same name, host, type and tags. The other series lost
+// these tags when their contexts were tracked, so this only applies to the rates,
+// monotonic counts and monotonic histogram buckets, see keepsContextTags.
+func aggregateCheckSeries(series []*metrics.Serie) []*metrics.Serie {
+	rules := currentTagAggregationRules.Load()
+	if rules == nil {
+		return series
+	}
+
+	res := series[:0]
+	merged := make(map[string]*metrics.Serie)
+	for _, serie := range series {
+		rule := rules.match(serie.Name)
+		if rule == nil {
+			res = append(res, serie)
+			continue
+		}
+		tags, dropped := dropCompositeTags(rule, serie.Tags)
+		if !dropped {
+			res = append(res, serie)
+			continue
+		}
+
+		serie.Tags = tags
+		key := aggregationKey(serie.Name, serie.Host, serie.MType.String(), serie.Tags)
+		if dest, found := merged[key]; found {
+			dest.Points = sumPoints(dest.Points, serie.Points)
+			continue
+		}
+		merged[key] = serie
+		res = append(res, serie)
+	}
+	return res
+}
+
+// aggregateCheckSketches drops the tags of the sketches matching a rule and
+// merges the sketches left with the same name, host and tags.
+func aggregateCheckSketches(sketches metrics.SketchSeriesList) metrics.SketchSeriesList {
+	rules := currentTagAggregationRules.Load()
+	if rules == nil {
+		return sketches
+	}
+
+	res := sketches[:0]
+	merged := make(map[string]*metrics.SketchSeries)
+	for _, sketch := range sketches {
+		rule := rules.match(sketch.Name)
+		if rule == nil {
+			res = append(res, sketch)
+			continue
+		}
+		tags, dropped := dropCompositeTags(rule, sketch.Tags)
+		if !dropped {
+			res = append(res, sketch)
+			continue
+		}
+
+		sketch.Tags = tags
+		key := aggregationKey(sketch.Name, sketch.Host, "", sketch.Tags)
+		if dest, found := merged[key]; found {
+			dest.Points = mergeSketchPoints(dest.Points, sketch.Points)
+			continue
+		}
+		merged[key] = sketch
+		res = append(res, sketch)
+	}
+	return res
+}
+
+// dropCompositeTags returns the tags kept by the rule, and whether some tags
+// were dropped
+func dropCompositeTags(rule *tagAggregationRule, tags tagset.CompositeTags) (tagset.CompositeTags, bool) {
+	kept := make([]string, 0, tags.Len())
+	tags.ForEach(func(tag string) {
+		if rule.keepTag(tag) {
+			kept = append(kept, tag)
+		}
+	})
+	if len(kept) == tags.Len() {
+		return tags, false
+	}
+	return tagset.CompositeTagsFromSlice(kept), true
+}
+
+func aggregationKey(name, host, mtype string, tags tagset.CompositeTags) string {
+	sorted := tags.UnsafeToReadOnlySliceString()
+	sorted = append([]string(nil), sorted...)
+	sort.Strings(sorted)
+	return name + "\x00" + host + "\x00" + mtype + "\x00" + strings.Join(sorted, ",")
+}
+
+func sumPoints(dest, src []metrics.Point) []metrics.Point {
+	for _, p := range src {
+		found := false
+		for i := range dest {
+			if dest[i].Ts == p.Ts {
+				dest[i].Value += p.Value
+				found = true
+				break
+			}
+		}
+		if !found {
+			dest = append(dest, p)
+		}
+	}
+	return dest
+}
+
+func mergeSketchPoints(dest, src []metrics.SketchPoint) []metrics.SketchPoint {
+	for _, p := range src {
+		found := false
+		for i := range dest {
+			if dest[i].Ts == p.Ts {
+				dest[i].Sketch.Merge(quantile.Default(), p.Sketch)
+				found = true
+				break
+			}
+		}
+		if !found {
+			dest = append(dest, p)
+		}
+	}
+	return dest
+}
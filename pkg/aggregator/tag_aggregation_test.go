@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/impl-noop"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func setTestTagAggregationRules(t *testing.T, rules string) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource(tagAggregationRulesConfigKey, rules)
+	setTagAggregationRules(loadTagAggregationRules(cfg))
+	t.Cleanup(func() { setTagAggregationRules(nil) })
+}
+
+func testTagAggregationTimeSampler(t *testing.T, store *tags.Store) {
+	setTestTagAggregationRules(t, `[{"name": "no-pod", "metric_names": ["my.*"], "drop_tags": ["pod_name"]}]`)
+	sampler := testTimeSampler(store)
+
+	for i, pod := range []string{"a", "b", "c"} {
+		sampler.sample(&metrics.MetricSample{
+			Name:       "my.count",
+			Value:      float64(i + 1),
+			Mtype:      metrics.CountType,
+			Tags:       []string{"env:prod", "pod_name:" + pod},
+			SampleRate: 1,
+		}, 12345.0)
+		sampler.sample(&metrics.MetricSample{
+			Name:       "other.count",
+			Value:      1,
+			Mtype:      metrics.CountType,
+			Tags:       []string{"pod_name:" + pod},
+			SampleRate: 1,
+		}, 12345.0)
+	}
+
+	series, _ := flushSerie(sampler, 12360.0)
+	require.Len(t, series, 4)
+
+	var aggregated []*metrics.Serie
+	for _, serie := range series {
+		if serie.Name == "my.count" {
+			aggregated = append(aggregated, serie)
+		}
+	}
+	require.Len(t, aggregated, 1)
+	assert.Equal(t, []string{"env:prod"}, aggregated[0].Tags.UnsafeToReadOnlySliceString())
+	assert.Equal(t, []metrics.Point{{Ts: 12340.0, Value: 6}}, aggregated[0].Points)
+
+	status := expTagAggregationRules().([]tagAggregationRuleStatus)
+	require.Len(t, status, 1)
+	assert.Equal(t, uint64(3), status[0].AggregatedSamples)
+}
+
+func TestTagAggregationTimeSampler(t *testing.T) {
+	testWithTagsStore(t, testTagAggregationTimeSampler)
+}
+
+func testTagAggregationCheckSampler(t *testing.T, store *tags.Store) {
+	setTestTagAggregationRules(t, `[{"name": "no-device", "metric_names": ["my.rate", "my.gauge"], "drop_tags": ["device"]}]`)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), nooptagger.NewComponent())
+
+	for _, ts := range []float64{12345.0, 12347.0} {
+		for _, device := range []string{"sda", "sdb"} {
+			checkSampler.addSample(&metrics.MetricSample{
+				Name:       "my.rate",
+				Value:      ts,
+				Mtype:      metrics.RateType,
+				Tags:       []string{"device:" + device},
+				SampleRate: 1,
+				Timestamp:  ts,
+			})
+			checkSampler.addSample(&metrics.MetricSample{
+				Name:       "my.gauge",
+				Value:      1,
+				Mtype:      metrics.GaugeType,
+				Tags:       []string{"env:prod", "device:" + device},
+				SampleRate: 1,
+				Timestamp:  ts,
+			})
+		}
+		checkSampler.commit(ts)
+	}
+	series, _ := checkSampler.flush()
+
+	var rate *metrics.Serie
+	for _, serie := range series {
+		switch serie.Name {
+		case "my.gauge":
+			assert.Equal(t, []string{"env:prod"}, serie.Tags.UnsafeToReadOnlySliceString())
+		case "my.rate":
+			require.Nil(t, rate)
+			rate = serie
+		}
+	}
+	// one gauge for each commit, and the rates of each device are computed before being summed
+	require.Len(t, series, 3)
+	require.NotNil(t, rate)
+	assert.Equal(t, 0, rate.Tags.Len())
+	assert.Equal(t, []metrics.Point{{Ts: 12347.0, Value: 2}}, rate.Points)
+}
+
+func TestTagAggregationCheckSampler(t *testing.T) {
+	testWithTagsStore(t, testTagAggregationCheckSampler)
+}
+
+func TestAggregateCheckSeries(t *testing.T) {
+	setTestTagAggregationRules(t, `[{"name": "no-device", "metric_names": ["my.count"], "drop_tags": ["device"]}]`)
+
+	series := aggregateCheckSeries([]*metrics.Serie{{
+		Name:   "my.count",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod", "device:sda"}),
+		MType:  metrics.APICountType,
+		Points: []metrics.Point{{Ts: 10, Value: 1}},
+	}, {
+		Name:   "my.count",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"device:sdb", "env:prod"}),
+		MType:  metrics.APICountType,
+		Points: []metrics.Point{{Ts: 10, Value: 2}, {Ts: 20, Value: 3}},
+	}, {
+		Name:   "other.count",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"device:sda"}),
+		MType:  metrics.APICountType,
+		Points: []metrics.Point{{Ts: 10, Value: 1}},
+	}})
+
+	require.Len(t, series, 2)
+	assert.Equal(t, []string{"env:prod"}, series[0].Tags.UnsafeToReadOnlySliceString())
+	assert.Equal(t, []metrics.Point{{Ts: 10, Value: 3}, {Ts: 20, Value: 3}}, series[0].Points)
+	assert.Equal(t, []string{"device:sda"}, series[1].Tags.UnsafeToReadOnlySliceString())
+}
+
+func TestTagAggregationRulesReload(t *testing.T) {
+	watchTagAggregationRules = sync.Once{}
+	t.Cleanup(func() {
+		watchTagAggregationRules = sync.Once{}
+		setTagAggregationRules(nil)
+	})
+
+	cfg := configmock.New(t)
+	initTagAggregationRules(cfg)
+	assert.Nil(t, currentTagAggregationRules.Load())
+
+	cfg.SetWithoutSource(tagAggregationRulesConfigKey, `[{"name": "no-pod", "metric_names": ["foo"], "drop_tags": ["pod_name"]}]`)
+	rules := currentTagAggregationRules.Load()
+	require.NotNil(t, rules)
+	assert.NotNil(t, rules.match("foo"))
+	assert.Nil(t, rules.match("foobar"))
+
+	// invalid rules are skipped
+	cfg.SetWithoutSource(tagAggregationRulesConfigKey, `[
+		{"name": "no-pod", "metric_names": ["foo*"], "drop_tags": ["pod_name"]},
+		{"name": "invalid", "metric_names": ["bar"]}
+	]`)
+	rules = currentTagAggregationRules.Load()
+	require.NotNil(t, rules)
+	assert.Len(t, rules.rules, 1)
+	assert.NotNil(t, rules.match("foobar"))
+}
+
+func TestLoadTagAggregationRules(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		cfg := configmock.NewFromYAML(t, `
+tag_aggregation_rules:
+  - name: no-pod
+    metric_names: ["foo.*"]
+    drop_tags: [pod_name]
+`)
+		assert.Len(t, loadTagAggregationRules(cfg), 1)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TAG_AGGREGATION_RULES", `[{"name": "no-pod", "metric_names": ["foo.*"], "drop_tags": ["pod_name"]}]`)
+		assert.Len(t, loadTagAggregationRules(configmock.New(t)), 1)
+	})
+}
+
+func TestValidateTagAggregationRules(t *testing.T) {
+	assert.NoError(t, ValidateTagAggregationRules(`[]`))
+	assert.NoError(t, ValidateTagAggregationRules(`[{"name": "a", "metric_names": ["foo.*"], "drop_tags": ["pod_name"]}]`))
+	assert.Error(t, ValidateTagAggregationRules(`{}`))
+	assert.Error(t, ValidateTagAggregationRules(`[{"name": "a", "drop_tags": ["pod_name"]}]`))
+	assert.Error(t, ValidateTagAggregationRules(`[{"name": "a", "metric_names": ["foo"]}]`))
+	assert.Error(t, ValidateTagAggregationRules(`[{"name": "a", "metric_names": ["foo"], "drop_tags": [""]}]`))
+}
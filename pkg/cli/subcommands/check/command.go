@@ -646,12 +646,19 @@ func runCheck(cliParams *cliParams, c check.Check, _ aggregator.Demultiplexer) *
 		times = 2
 		pause = 1000
 	}
+	runTimeout := check.RunTimeout(c)
 	for i := 0; i < times; i++ {
 		t0 := time.Now()
-		err := c.Run()
+		err := check.RunWithTimeout(c, runTimeout, nil)
 		warnings := c.GetWarnings()
 		sStats, _ := c.GetSenderStats()
 		s.Add(time.Since(t0), err, warnings, sStats, nil)
+		var timeoutErr *check.RunTimeoutError
+		if errors.As(err, &timeoutErr) {
+			// the check may still be running, it can't run again
+			color.Yellow("The check run timed out after %s, skipping the remaining runs", runTimeout)
+			return s
+		}
 		if pause > 0 && i < times-1 {
 			time.Sleep(time.Duration(pause) * time.Millisecond)
 		}
@@ -10,6 +10,8 @@ package clusterchecks
 import (
 	"math"
 	"sort"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
 )
 
 // CheckStatus represents the status of a check
@@ -38,6 +40,18 @@ func (ns RunnerStatus) utilization() float64 {
 type checksDistribution struct {
 	Checks  map[string]*CheckStatus
 	Runners map[string]*RunnerStatus
+
+	// placement holds the labels of the runners and the placement of the
+	// checks having placement constraints
+	placement *placementState
+	// constraints holds the placement constraints of the checks having some
+	constraints map[string]checkPlacementConstraints
+}
+
+// checkPlacementConstraints holds the placement constraints of a check
+type checkPlacementConstraints struct {
+	checkName   string
+	constraints *integration.PlacementConstraints
 }
 
 func newChecksDistribution(workersPerRunner map[string]int) checksDistribution {
@@ -51,17 +65,74 @@ func newChecksDistribution(workersPerRunner map[string]int) checksDistribution {
 	}
 
 	return checksDistribution{
-		Checks:  map[string]*CheckStatus{},
-		Runners: runners,
+		Checks:      map[string]*CheckStatus{},
+		Runners:     runners,
+		placement:   newPlacementState(),
+		constraints: map[string]checkPlacementConstraints{},
+	}
+}
+
+// setRunnerLabels sets the labels of a runner, matched by the placement
+// constraints of the checks
+func (distribution *checksDistribution) setRunnerLabels(runner string, labels map[string]string) {
+	distribution.placement.labels[runner] = labels
+}
+
+// setPlacementConstraints sets the placement constraints of a check. It must
+// be called before the check is added.
+func (distribution *checksDistribution) setPlacementConstraints(checkID string, checkName string, constraints *integration.PlacementConstraints) {
+	if constraints == nil {
+		return
+	}
+	distribution.constraints[checkID] = checkPlacementConstraints{
+		checkName:   checkName,
+		constraints: constraints,
 	}
 }
 
+// copyPlacementConstraints copies the runner labels and the placement
+// constraints of the checks of another distribution, but not the placement of
+// its checks.
+func (distribution *checksDistribution) copyPlacementConstraints(other checksDistribution) {
+	if other.placement != nil {
+		for runner, labels := range other.placement.labels {
+			distribution.setRunnerLabels(runner, labels)
+		}
+	}
+	for checkID, c := range other.constraints {
+		distribution.constraints[checkID] = c
+	}
+}
+
+// allowedRunners returns the runners satisfying the placement constraints of
+// a check, or nil if the check has no constraints
+func (distribution *checksDistribution) allowedRunners(checkID string) map[string]struct{} {
+	c, found := distribution.constraints[checkID]
+	if !found {
+		return nil
+	}
+
+	runners := make([]string, 0, len(distribution.Runners))
+	for runnerName := range distribution.Runners {
+		runners = append(runners, runnerName)
+	}
+	sort.Strings(runners)
+
+	candidates, _ := distribution.placement.filter(c.checkName, c.constraints, runners)
+	allowed := make(map[string]struct{}, len(candidates))
+	for _, runnerName := range candidates {
+		allowed[runnerName] = struct{}{}
+	}
+	return allowed
+}
+
 // leastBusyRunner returns the runner with the lowest utilization. If there are
 // several options, it gives preference to preferredRunner. If preferredRunner
 // is not among the runners with the lowest utilization, it gives precedence to
 // the runner with the lowest number of checks deployed. excludeRunner can be set
-// to avoid assigning a check to a specific runner.
-func (distribution *checksDistribution) leastBusyRunner(preferredRunner string, excludeRunner string) string {
+// to avoid assigning a check to a specific runner, and allowedRunners to
+// restrict the selection to some runners when it's not nil.
+func (distribution *checksDistribution) leastBusyRunner(preferredRunner string, excludeRunner string, allowedRunners map[string]struct{}) string {
 	leastBusyRunner := ""
 	minUtilization := 0.0
 	numChecksLeastBusyRunner := 0
@@ -71,6 +142,9 @@ func (distribution *checksDistribution) leastBusyRunner(preferredRunner string,
 		if runnerName == excludeRunner {
 			continue
 		}
+		if _, allowed := allowedRunners[runnerName]; allowedRunners != nil && !allowed {
+			continue
+		}
 
 		runnerUtilization := runnerStatus.utilization()
 		runnerNumChecks := runnerStatus.NumChecks
@@ -91,7 +165,7 @@ func (distribution *checksDistribution) leastBusyRunner(preferredRunner string,
 }
 
 func (distribution *checksDistribution) addToLeastBusy(checkID string, workersNeeded float64, preferredRunner string, excludeRunner string) {
-	leastBusy := distribution.leastBusyRunner(preferredRunner, excludeRunner)
+	leastBusy := distribution.leastBusyRunner(preferredRunner, excludeRunner, distribution.allowedRunners(checkID))
 	if leastBusy == "" {
 		return
 	}
@@ -105,6 +179,10 @@ func (distribution *checksDistribution) addCheck(checkID string, workersNeeded f
 		Runner:        runner,
 	}
 
+	if c, found := distribution.constraints[checkID]; found {
+		distribution.placement.add(c.checkName, c.constraints, runner)
+	}
+
 	runnerInfo, runnerExists := distribution.Runners[runner]
 	if runnerExists {
 		runnerInfo.WorkersUsed += workersNeeded
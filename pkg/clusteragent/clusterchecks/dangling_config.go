@@ -17,6 +17,7 @@ type danglingConfigWrapper struct {
 	config           integration.Config
 	timeCreated      time.Time
 	unscheduledCheck bool
+	placementError   string // why the placement constraints of the config can't be satisfied
 }
 
 // createDanglingConfig creates a new danglingConfigWrapper
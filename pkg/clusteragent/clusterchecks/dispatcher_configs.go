@@ -30,9 +30,19 @@ func (d *dispatcher) getState() (types.StateResponse, error) {
 		Warmup:   !d.store.active,
 		Dangling: makeConfigArrayFromDangling(d.store.danglingConfigs),
 	}
+	for digest, c := range d.store.danglingConfigs {
+		if c.placementError == "" {
+			continue
+		}
+		if response.PlacementErrors == nil {
+			response.PlacementErrors = map[string]string{}
+		}
+		response.PlacementErrors[digest] = c.placementError
+	}
 	for _, node := range d.store.nodes {
 		n := types.StateNodeResponse{
 			Name:    node.name,
+			Labels:  node.labels,
 			Configs: makeConfigArray(node.digestToConfig),
 		}
 		response.Nodes = append(response.Nodes, n)
@@ -127,6 +137,17 @@ func (d *dispatcher) removeConfig(digest string) {
 	}
 }
 
+// setPlacementError records why a dangling config could not be dispatched
+// because of its placement constraints
+func (d *dispatcher) setPlacementError(digest, placementError string) {
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	if c, found := d.store.danglingConfigs[digest]; found {
+		c.placementError = placementError
+	}
+}
+
 // shouldDispatchDangling returns true if there are dangling configs
 // and node registered, available for dispatching.
 func (d *dispatcher) shouldDispatchDangling() bool {
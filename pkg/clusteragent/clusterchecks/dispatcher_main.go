@@ -183,15 +183,27 @@ func (d *dispatcher) reschedule(configs []integration.Config) []string {
 
 // add stores and delegates a given configuration
 func (d *dispatcher) add(config integration.Config) bool {
-	target := d.getNodeToScheduleCheck()
-	if target == "" {
+	var target, placementError string
+	if config.Placement != nil {
+		target, placementError = d.getNodeToPlaceCheck(config)
+	} else {
+		target = d.getNodeToScheduleCheck()
+	}
+
+	if target == "" && placementError != "" {
+		log.Warnf("Cannot dispatch %s:%s, its placement constraints are not satisfiable: %s, will retry later", config.Name, config.Digest(), placementError)
+	} else if target == "" {
 		// If no node is found, store it in the danglingConfigs map for retrying later.
 		log.Warnf("No available node to dispatch %s:%s on, will retry later", config.Name, config.Digest())
 	} else {
 		log.Infof("Dispatching configuration %s:%s to node %s", config.Name, config.Digest(), target)
 	}
 
-	return d.addConfig(config, target)
+	added := d.addConfig(config, target)
+	if !added {
+		d.setPlacementError(config.Digest(), placementError)
+	}
+	return added
 }
 
 // remove deletes a given configuration
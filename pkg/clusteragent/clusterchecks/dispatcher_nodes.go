@@ -54,6 +54,7 @@ func (d *dispatcher) processNodeStatus(nodeName, clientIP string, status types.N
 	node.Lock()
 	defer node.Unlock()
 	node.heartbeat = timestampNow()
+	node.labels = status.Labels
 	// When we receive ExtraHeartbeatLastChangeValue, we only update heartbeat
 	if status.LastChange == types.ExtraHeartbeatLastChangeValue {
 		return true
@@ -129,6 +130,39 @@ func (d *dispatcher) getNodeWithLessChecks() string {
 	return selectedNode
 }
 
+// getNodeToPlaceCheck returns the node where a check with placement
+// constraints should be scheduled, or the reason why there is none. Among the
+// nodes satisfying the constraints, it picks one the same way as
+// getNodeToScheduleCheck.
+func (d *dispatcher) getNodeToPlaceCheck(config integration.Config) (string, string) {
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	if len(d.store.nodes) == 0 {
+		return "", ""
+	}
+
+	candidates, reason := placementStateFromStore(d.store, config.Digest()).filter(config.Name, config.Placement, d.store.sortedNodeNames())
+	if len(candidates) == 0 {
+		return "", reason
+	}
+
+	if d.advancedDispatching {
+		return candidates[rand.Intn(len(candidates))], ""
+	}
+
+	var selectedNode string
+	minNumChecks := 0
+	for _, name := range candidates {
+		numChecks := len(d.store.nodes[name].digestToConfig)
+		if selectedNode == "" || numChecks < minNumChecks {
+			selectedNode = name
+			minNumChecks = numChecks
+		}
+	}
+	return selectedNode, ""
+}
+
 // expireNodes iterates over nodes and removes the ones that have not
 // reported for more than the expiration duration. The configurations
 // dispatched to these nodes will be moved to the danglingConfigs map.
@@ -102,7 +102,8 @@ func (d *dispatcher) updateDiff(avg int) map[string]int {
 // A check Xi running on a node N is chosen to move to another node if it satisfies the following
 // Weight(Xi) >  Weight(Xj) (for each j != i, 0 <= j < len(weights))
 // where Weight(X) is the busyness value caused by running the check X.
-func (d *dispatcher) pickCheckToMove(nodeName string) (string, int, error) {
+// The skipped checks, which can't be moved, are not considered.
+func (d *dispatcher) pickCheckToMove(nodeName string, skipped map[string]bool) (string, int, error) {
 	d.store.RLock()
 	node, found := d.store.getNodeStore(nodeName)
 	d.store.RUnlock()
@@ -112,7 +113,7 @@ func (d *dispatcher) pickCheckToMove(nodeName string) (string, int, error) {
 		return "", -1, fmt.Errorf("node %s not found in store", nodeName)
 	}
 
-	return node.GetMostWeightedClusterCheck(busynessFunc)
+	return node.GetMostWeightedClusterCheck(busynessFunc, skipped)
 }
 
 // pickNode select the most appropriate node to receive a specific check.
@@ -137,6 +138,26 @@ func pickNode(diffMap map[string]int, sourceNode string) string {
 	return pickedNode
 }
 
+// placeableNodes returns the entries of diffMap of the nodes satisfying the
+// placement constraints of a check
+func (d *dispatcher) placeableNodes(checkID string, diffMap map[string]int) map[string]int {
+	config, digest := d.getConfigAndDigest(checkID)
+	if config.Placement == nil {
+		return diffMap
+	}
+
+	d.store.RLock()
+	state := placementStateFromStore(d.store, digest)
+	d.store.RUnlock()
+
+	candidates, _ := state.filter(config.Name, config.Placement, orderedKeys(diffMap))
+	res := make(map[string]int, len(candidates))
+	for _, node := range candidates {
+		res[node] = diffMap[node]
+	}
+	return res
+}
+
 // moveCheck moves a check by its ID from a node to another
 func (d *dispatcher) moveCheck(src, dest, checkID string) error {
 	log.Debugf("Moving %s from %s to %s", checkID, src, dest)
@@ -202,16 +223,23 @@ func (d *dispatcher) rebalanceUsingBusyness() []types.RebalanceResponse {
 	sort.Sort(weights)
 
 	for _, nodeWeight := range weights {
+		// checks that no other node can run because of their placement constraints
+		unplaceable := map[string]bool{}
 		for diffMap[nodeWeight.nodeName] > 0 {
 			// try to move checks from a node only of the node busyness is above the average
 			sourceNodeName := nodeWeight.nodeName
-			checkID, checkWeight, err := d.pickCheckToMove(sourceNodeName)
+			checkID, checkWeight, err := d.pickCheckToMove(sourceNodeName, unplaceable)
 			if err != nil {
 				log.Debugf("Cannot pick a check to move from node %s: %v", sourceNodeName, err)
 				break
 			}
 
-			destNodeName := pickNode(diffMap, sourceNodeName)
+			destNodeName := pickNode(d.placeableNodes(checkID, diffMap), sourceNodeName)
+			if destNodeName == "" {
+				log.Debugf("No node satisfies the placement constraints of check %s, won't move it", checkID)
+				unplaceable[checkID] = true
+				continue
+			}
 			sourceDiff := diffMap[sourceNodeName]
 			destDiff := diffMap[destNodeName]
 
@@ -310,6 +338,7 @@ func (d *dispatcher) rebalanceUsingUtilization(force bool) []types.RebalanceResp
 	currentChecksDistribution := d.currentDistribution()
 
 	proposedDistribution := newChecksDistribution(currentChecksDistribution.runnerWorkers())
+	proposedDistribution.copyPlacementConstraints(currentChecksDistribution)
 
 	// First all the checks that are excluded from rebalancing are added to the
 	// same runner where they are currently running.
@@ -377,6 +406,8 @@ func (d *dispatcher) currentDistribution() checksDistribution {
 	distribution := newChecksDistribution(currentWorkersPerRunner)
 
 	for nodeName, nodeStoreInfo := range d.store.nodes {
+		distribution.setRunnerLabels(nodeName, nodeStoreInfo.labels)
+
 		for checkID, stats := range nodeStoreInfo.clcRunnerStats {
 			if !stats.IsClusterCheck {
 				continue
@@ -384,6 +415,7 @@ func (d *dispatcher) currentDistribution() checksDistribution {
 
 			minCollectionInterval := defaults.DefaultCheckInterval
 			conf := d.store.digestToConfig[d.store.idToDigest[checkid.ID(checkID)]]
+			distribution.setPlacementConstraints(checkID, conf.Name, conf.Placement)
 			if len(conf.Instances) > 0 {
 				commonOptions := integration.CommonInstanceConfig{}
 				err := yaml.Unmarshal(conf.Instances[0], &commonOptions)
@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+)
+
+// placementState is the view of the runners used to evaluate the placement
+// constraints of the checks: their labels and the groups of the checks they
+// run.
+//
+// The node selector and the anti-affinity group are hard constraints: a check
+// is never dispatched to a runner that doesn't satisfy them. The topology
+// spread is a soft one: the runners of the least used topology domains are
+// preferred, but any runner can be chosen.
+type placementState struct {
+	labels       map[string]map[string]string // runner -> labels
+	antiAffinity map[string]map[string]int    // anti-affinity group -> runner -> number of checks
+	spread       map[string]map[string]int    // spread group -> runner -> number of checks
+}
+
+func newPlacementState() *placementState {
+	return &placementState{
+		labels:       map[string]map[string]string{},
+		antiAffinity: map[string]map[string]int{},
+		spread:       map[string]map[string]int{},
+	}
+}
+
+// placementStateFromStore returns the placement state of the runners known to
+// the store, leaving out the config with the given digest so that it can be
+// moved. The store lock must be held.
+func placementStateFromStore(store *clusterStore, excludeDigest string) *placementState {
+	state := newPlacementState()
+	for nodeName, node := range store.nodes {
+		node.RLock()
+		state.labels[nodeName] = node.labels
+		for digest, config := range node.digestToConfig {
+			if digest != excludeDigest {
+				state.add(config.Name, config.Placement, nodeName)
+			}
+		}
+		node.RUnlock()
+	}
+	return state
+}
+
+func spreadGroup(checkName string, constraints *integration.PlacementConstraints) string {
+	if constraints.SpreadGroup != "" {
+		return constraints.SpreadGroup
+	}
+	return checkName
+}
+
+// add records that a check runs on a runner
+func (s *placementState) add(checkName string, constraints *integration.PlacementConstraints, runner string) {
+	if constraints == nil {
+		return
+	}
+
+	if constraints.AntiAffinityGroup != "" {
+		incrementCount(s.antiAffinity, constraints.AntiAffinityGroup, runner)
+	}
+	if constraints.TopologySpreadKey != "" {
+		incrementCount(s.spread, spreadGroup(checkName, constraints), runner)
+	}
+}
+
+func incrementCount(counts map[string]map[string]int, group, runner string) {
+	if counts[group] == nil {
+		counts[group] = map[string]int{}
+	}
+	counts[group][runner]++
+}
+
+// filter returns the runners among candidates a check can be dispatched to.
+// When there is none, it returns the reason why.
+func (s *placementState) filter(checkName string, constraints *integration.PlacementConstraints, candidates []string) ([]string, string) {
+	if constraints == nil || len(candidates) == 0 {
+		return candidates, ""
+	}
+
+	if len(constraints.NodeSelector) > 0 {
+		candidates = filterRunners(candidates, func(runner string) bool {
+			return matchesSelector(s.labels[runner], constraints.NodeSelector)
+		})
+		if len(candidates) == 0 {
+			return nil, fmt.Sprintf("no runner has the labels %s", formatSelector(constraints.NodeSelector))
+		}
+	}
+
+	if group := constraints.AntiAffinityGroup; group != "" {
+		candidates = filterRunners(candidates, func(runner string) bool {
+			return s.antiAffinity[group][runner] == 0
+		})
+		if len(candidates) == 0 {
+			return nil, fmt.Sprintf("every eligible runner already runs a check of the anti-affinity group %q", group)
+		}
+	}
+
+	if key := constraints.TopologySpreadKey; key != "" {
+		candidates = s.leastUsedDomains(spreadGroup(checkName, constraints), key, candidates)
+	}
+
+	return candidates, ""
+}
+
+// leastUsedDomains returns the candidates of the topology domains running the
+// fewest checks of a spread group. Runners without the topology key label are
+// only returned if no candidate has it.
+func (s *placementState) leastUsedDomains(group, key string, candidates []string) []string {
+	domainCounts := map[string]int{}
+	for runner, labels := range s.labels {
+		if domain, found := labels[key]; found {
+			domainCounts[domain] += s.spread[group][runner]
+		}
+	}
+
+	var res []string
+	minCount := -1
+	for _, runner := range candidates {
+		domain, found := s.labels[runner][key]
+		if !found {
+			continue
+		}
+		count := domainCounts[domain]
+		if minCount == -1 || count < minCount {
+			res = nil
+			minCount = count
+		}
+		if count == minCount {
+			res = append(res, runner)
+		}
+	}
+
+	if len(res) == 0 {
+		return candidates
+	}
+	return res
+}
+
+func filterRunners(runners []string, keep func(runner string) bool) []string {
+	var res []string
+	for _, runner := range runners {
+		if keep(runner) {
+			res = append(res, runner)
+		}
+	}
+	return res
+}
+
+func matchesSelector(labels, selector map[string]string) bool {
+	for key, value := range selector {
+		if labelValue, found := labels[key]; !found || labelValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+func formatSelector(selector map[string]string) string {
+	pairs := make([]string, 0, len(selector))
+	for key, value := range selector {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,200 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/comp/core/tagger/mock"
+	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+func generatePlacedIntegration(name, host string, placement *integration.PlacementConstraints) integration.Config {
+	return integration.Config{
+		Name:         name,
+		ClusterCheck: true,
+		Instances:    []integration.Data{integration.Data(fmt.Sprintf("host: %s", host))},
+		Placement:    placement,
+	}
+}
+
+func nodeOfConfig(t *testing.T, d *dispatcher, config integration.Config) string {
+	patched, err := d.patchConfiguration(config)
+	require.NoError(t, err)
+
+	d.store.RLock()
+	defer d.store.RUnlock()
+	return d.store.digestToNode[patched.Digest()]
+}
+
+func TestPlacementNodeSelector(t *testing.T) {
+	fakeTagger := mock.SetupFakeTagger(t)
+	dispatcher := newDispatcher(fakeTagger)
+
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{Labels: map[string]string{"zone": "eu-west-1a"}})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{Labels: map[string]string{"zone": "eu-west-1b"}})
+
+	inZoneB := generatePlacedIntegration("http_check", "b", &integration.PlacementConstraints{
+		NodeSelector: map[string]string{"zone": "eu-west-1b"},
+	})
+	inZoneC := generatePlacedIntegration("http_check", "c", &integration.PlacementConstraints{
+		NodeSelector: map[string]string{"zone": "eu-west-1c"},
+	})
+	dispatcher.Schedule([]integration.Config{inZoneB, inZoneC})
+
+	assert.Equal(t, "nodeB", nodeOfConfig(t, dispatcher, inZoneB))
+	assert.Equal(t, "", nodeOfConfig(t, dispatcher, inZoneC))
+
+	// the unsatisfiable constraints are reported
+	state, err := dispatcher.getState()
+	require.NoError(t, err)
+	require.Len(t, state.Dangling, 1)
+	assert.Equal(t, map[string]string{state.Dangling[0].Digest(): "no runner has the labels zone=eu-west-1c"}, state.PlacementErrors)
+
+	// the config is dispatched once a matching runner reports
+	dispatcher.processNodeStatus("nodeC", "10.0.0.3", types.NodeStatus{Labels: map[string]string{"zone": "eu-west-1c"}})
+	scheduled := dispatcher.reschedule(dispatcher.retrieveDangling())
+	dispatcher.store.Lock()
+	dispatcher.deleteDangling(scheduled)
+	dispatcher.store.Unlock()
+
+	assert.Equal(t, "nodeC", nodeOfConfig(t, dispatcher, inZoneC))
+	state, err = dispatcher.getState()
+	require.NoError(t, err)
+	assert.Empty(t, state.PlacementErrors)
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestPlacementAntiAffinity(t *testing.T) {
+	fakeTagger := mock.SetupFakeTagger(t)
+	dispatcher := newDispatcher(fakeTagger)
+
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+
+	placement := &integration.PlacementConstraints{AntiAffinityGroup: "postgres-ha"}
+	replica1 := generatePlacedIntegration("postgres", "db1", placement)
+	replica2 := generatePlacedIntegration("postgres", "db2", placement)
+	replica3 := generatePlacedIntegration("postgres", "db3", placement)
+	dispatcher.Schedule([]integration.Config{replica1, replica2, replica3})
+
+	node1 := nodeOfConfig(t, dispatcher, replica1)
+	node2 := nodeOfConfig(t, dispatcher, replica2)
+	assert.NotEmpty(t, node1)
+	assert.NotEmpty(t, node2)
+	assert.NotEqual(t, node1, node2)
+
+	// there is no runner left for the third replica
+	assert.Equal(t, "", nodeOfConfig(t, dispatcher, replica3))
+	state, err := dispatcher.getState()
+	require.NoError(t, err)
+	assert.Len(t, state.PlacementErrors, 1)
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestPlacementTopologySpread(t *testing.T) {
+	fakeTagger := mock.SetupFakeTagger(t)
+	dispatcher := newDispatcher(fakeTagger)
+
+	zones := map[string]string{"nodeA": "a", "nodeB": "a", "nodeC": "b", "nodeD": "c"}
+	for node, zone := range zones {
+		dispatcher.processNodeStatus(node, "", types.NodeStatus{Labels: map[string]string{"zone": zone}})
+	}
+
+	placement := &integration.PlacementConstraints{TopologySpreadKey: "zone"}
+	var configs []integration.Config
+	for i := 0; i < 6; i++ {
+		configs = append(configs, generatePlacedIntegration("http_check", fmt.Sprintf("site%d", i), placement))
+	}
+	dispatcher.Schedule(configs)
+
+	checksPerZone := map[string]int{}
+	for _, config := range configs {
+		node := nodeOfConfig(t, dispatcher, config)
+		require.NotEmpty(t, node)
+		checksPerZone[zones[node]]++
+	}
+	assert.Equal(t, map[string]int{"a": 2, "b": 2, "c": 2}, checksPerZone)
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestChecksDistributionPlacement(t *testing.T) {
+	distribution := newChecksDistribution(map[string]int{
+		"runner1": 4,
+		"runner2": 4,
+		"runner3": 4,
+	})
+	distribution.setRunnerLabels("runner1", map[string]string{"zone": "a"})
+	distribution.setRunnerLabels("runner2", map[string]string{"zone": "b"})
+	distribution.setRunnerLabels("runner3", map[string]string{"zone": "b"})
+
+	// runner1 is the least busy but doesn't match the selector
+	distribution.addCheck("busy", 3, "runner2")
+	distribution.setPlacementConstraints("selected", "check", &integration.PlacementConstraints{NodeSelector: map[string]string{"zone": "b"}})
+	distribution.addToLeastBusy("selected", 1, "", "")
+	assert.Equal(t, "runner3", distribution.runnerForCheck("selected"))
+
+	// anti-affinity is enforced among the checks placed in the distribution
+	distribution.setPlacementConstraints("replica1", "check", &integration.PlacementConstraints{AntiAffinityGroup: "ha"})
+	distribution.setPlacementConstraints("replica2", "check", &integration.PlacementConstraints{AntiAffinityGroup: "ha"})
+	distribution.addCheck("replica1", 0.1, "runner1")
+	distribution.addToLeastBusy("replica2", 0.1, "runner1", "")
+	assert.NotEqual(t, "runner1", distribution.runnerForCheck("replica2"))
+	assert.NotEmpty(t, distribution.runnerForCheck("replica2"))
+
+	// the constraints are copied, but not the placement of the checks
+	proposed := newChecksDistribution(distribution.runnerWorkers())
+	proposed.copyPlacementConstraints(distribution)
+	assert.Equal(t, map[string]struct{}{"runner2": {}, "runner3": {}}, proposed.allowedRunners("selected"))
+	assert.Len(t, proposed.allowedRunners("replica2"), 3)
+	assert.Nil(t, proposed.allowedRunners("busy"))
+}
+
+func TestRebalanceSkipsUnplaceableChecks(t *testing.T) {
+	fakeTagger := mock.SetupFakeTagger(t)
+	dispatcher := newDispatcher(fakeTagger)
+	dispatcher.store.active = true
+
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{Labels: map[string]string{"zone": "eu-west-1a"}})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{Labels: map[string]string{"zone": "eu-west-1b"}})
+
+	// the busiest check of nodeA can't leave it, but the next one can
+	pinned := generatePlacedIntegration("http_check", "pinned", &integration.PlacementConstraints{
+		NodeSelector: map[string]string{"zone": "eu-west-1a"},
+	})
+	free := generatePlacedIntegration("http_check", "free", nil)
+	dispatcher.addConfig(pinned, "nodeA")
+	dispatcher.addConfig(free, "nodeA")
+
+	pinnedID := checkid.BuildID(pinned.Name, pinned.FastDigest(), pinned.Instances[0], pinned.InitConfig)
+	freeID := checkid.BuildID(free.Name, free.FastDigest(), free.Instances[0], free.InitConfig)
+	dispatcher.store.nodes["nodeA"].clcRunnerStats = types.CLCRunnersStats{
+		string(pinnedID): {MetricSamples: 3000, IsClusterCheck: true},
+		string(freeID):   {MetricSamples: 1000, IsClusterCheck: true},
+	}
+	dispatcher.store.nodes["nodeB"].clcRunnerStats = types.CLCRunnersStats{
+		"other": {MetricSamples: 100, IsClusterCheck: true},
+	}
+
+	moved := dispatcher.rebalanceUsingBusyness()
+	require.Len(t, moved, 1)
+	assert.Equal(t, string(freeID), moved[0].CheckID)
+	assert.Equal(t, "nodeA", dispatcher.store.digestToNode[pinned.Digest()])
+	assert.Equal(t, "nodeB", dispatcher.store.digestToNode[free.Digest()])
+
+	requireNotLocked(t, dispatcher.store)
+}
@@ -9,6 +9,7 @@ package clusterchecks
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
@@ -76,6 +77,16 @@ func (s *clusterStore) getOrCreateNodeStore(nodeName, clientIP string) *nodeStor
 	return node
 }
 
+// sortedNodeNames returns the names of the nodes in alphabetical order
+func (s *clusterStore) sortedNodeNames() []string {
+	names := make([]string, 0, len(s.nodes))
+	for name := range s.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // clearDangling resets the danglingConfigs map to a new empty one
 func (s *clusterStore) clearDangling() {
 	s.danglingConfigs = make(map[string]*danglingConfigWrapper)
@@ -90,6 +101,7 @@ type nodeStore struct {
 	lastConfigChange int64
 	digestToConfig   map[string]integration.Config
 	clientIP         string
+	labels           map[string]string
 	clcRunnerStats   types.CLCRunnersStats
 	busyness         int
 	workers          int
@@ -167,9 +179,9 @@ func (s *nodeStore) GetBusyness(busynessFunc func(stats types.CLCRunnerStats) in
 	return busyness
 }
 
-// GetMostWeightedClusterCheck returns the Cluster Check with the most weight on the node
+// GetMostWeightedClusterCheck returns the Cluster Check with the most weight on the node, ignoring the skipped ones
 // The nodeStore handles thread safety for this public method
-func (s *nodeStore) GetMostWeightedClusterCheck(busynessFunc func(stats types.CLCRunnerStats) int) (string, int, error) {
+func (s *nodeStore) GetMostWeightedClusterCheck(busynessFunc func(stats types.CLCRunnerStats) int, skipped map[string]bool) (string, int, error) {
 	s.RLock()
 	defer s.RUnlock()
 	if len(s.clcRunnerStats) == 0 {
@@ -181,7 +193,7 @@ func (s *nodeStore) GetMostWeightedClusterCheck(busynessFunc func(stats types.CL
 	checkWeight := 0
 	for id, stats := range s.clcRunnerStats {
 		busyness := busynessFunc(stats)
-		if (busyness > checkWeight || firstItr) && stats.IsClusterCheck {
+		if (busyness > checkWeight || firstItr) && stats.IsClusterCheck && !skipped[id] {
 			// Only consider Cluster Checks
 			checkWeight = busyness
 			checkID = id
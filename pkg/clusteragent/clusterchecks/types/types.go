@@ -19,7 +19,8 @@ const (
 
 // NodeStatus holds the status report from the node-agent
 type NodeStatus struct {
-	LastChange int64 `json:"last_change"`
+	LastChange int64             `json:"last_change"`
+	Labels     map[string]string `json:"labels,omitempty"` // Labels of the runner, matched by placement constraints
 }
 
 // StatusResponse holds the DCA response for a status report
@@ -59,11 +60,15 @@ type StateResponse struct {
 	Warmup     bool                 `json:"warmup"`
 	Nodes      []StateNodeResponse  `json:"nodes"`
 	Dangling   []integration.Config `json:"dangling"`
+	// PlacementErrors explains, by config digest, why dangling configs could
+	// not be dispatched because of their placement constraints
+	PlacementErrors map[string]string `json:"placement_errors,omitempty"`
 }
 
 // StateNodeResponse is a chunk of StateResponse
 type StateNodeResponse struct {
 	Name    string               `json:"name"`
+	Labels  map[string]string    `json:"labels,omitempty"`
 	Configs []integration.Config `json:"configs"`
 }
 
@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"time"
+
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+)
+
+// circuitBreakerConfig holds the settings of the circuit breaker skipping the runs of a check
+// after consecutive failures or timeouts
+type circuitBreakerConfig struct {
+	// failureThreshold is the number of consecutive failures opening the circuit breaker, 0 disables it
+	failureThreshold uint64
+	initialBackoff   time.Duration
+	maxBackoff       time.Duration
+}
+
+func newCircuitBreakerConfig() circuitBreakerConfig {
+	threshold := pkgconfigsetup.Datadog().GetInt("check_circuit_breaker.failure_threshold")
+	if threshold < 0 {
+		threshold = 0
+	}
+	return circuitBreakerConfig{
+		failureThreshold: uint64(threshold),
+		initialBackoff:   pkgconfigsetup.Datadog().GetDuration("check_circuit_breaker.initial_backoff"),
+		maxBackoff:       pkgconfigsetup.Datadog().GetDuration("check_circuit_breaker.max_backoff"),
+	}
+}
+
+// backoff returns how long the runs are skipped after the given number of consecutive failures,
+// doubling with every failure past the threshold, or 0 when the circuit breaker stays closed
+func (c circuitBreakerConfig) backoff(consecutiveFailures uint64) time.Duration {
+	if c.failureThreshold == 0 || consecutiveFailures < c.failureThreshold || c.initialBackoff <= 0 {
+		return 0
+	}
+	backoff := c.initialBackoff
+	for i := c.failureThreshold; i < consecutiveFailures; i++ {
+		backoff *= 2
+		if c.maxBackoff > 0 && backoff >= c.maxBackoff {
+			return c.maxBackoff
+		}
+	}
+	if c.maxBackoff > 0 && backoff > c.maxBackoff {
+		return c.maxBackoff
+	}
+	return backoff
+}
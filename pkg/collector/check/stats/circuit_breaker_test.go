@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "timed out" }
+func (timeoutError) Timeout() bool { return true }
+
+func TestCircuitBreakerBackoff(t *testing.T) {
+	c := circuitBreakerConfig{failureThreshold: 3, initialBackoff: time.Minute, maxBackoff: 10 * time.Minute}
+	for failures, expected := range map[uint64]time.Duration{
+		0:   0,
+		2:   0,
+		3:   time.Minute,
+		4:   2 * time.Minute,
+		5:   4 * time.Minute,
+		6:   8 * time.Minute,
+		7:   10 * time.Minute,
+		100: 10 * time.Minute,
+	} {
+		assert.Equal(t, expected, c.backoff(failures), "%d consecutive failures", failures)
+	}
+
+	// disabled
+	assert.Zero(t, circuitBreakerConfig{initialBackoff: time.Minute}.backoff(10))
+}
+
+func TestStatsCircuitBreaker(t *testing.T) {
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("check_circuit_breaker.failure_threshold", 2)
+	mockConfig.SetWithoutSource("check_circuit_breaker.initial_backoff", "1m")
+
+	stats := NewStats(&mockCheck{id: "circuit_breaker:1", stringVal: "circuit_breaker", interval: 15 * time.Second})
+	now := time.Now()
+
+	stats.Add(time.Second, timeoutError{}, nil, NewSenderStats(), nil)
+	assert.Equal(t, uint64(1), stats.TotalTimeouts)
+	assert.Equal(t, uint64(1), stats.ConsecutiveFailures)
+	assert.False(t, stats.IsCircuitBreakerOpen(now))
+
+	stats.Add(time.Second, errors.New("failure"), nil, NewSenderStats(), nil)
+	assert.Equal(t, uint64(1), stats.TotalTimeouts)
+	assert.Equal(t, uint64(2), stats.ConsecutiveFailures)
+	assert.True(t, stats.IsCircuitBreakerOpen(now))
+	assert.False(t, stats.IsCircuitBreakerOpen(now.Add(2*time.Minute)))
+
+	// the runs resume after the backoff, and a failure doubles it
+	stats.Add(time.Second, errors.New("failure"), nil, NewSenderStats(), nil)
+	assert.True(t, stats.IsCircuitBreakerOpen(now.Add(90*time.Second)))
+
+	stats.Add(time.Second, nil, nil, NewSenderStats(), nil)
+	assert.Zero(t, stats.ConsecutiveFailures)
+	assert.Zero(t, stats.CircuitBreakerOpenUntil)
+	assert.False(t, stats.IsCircuitBreakerOpen(now))
+}
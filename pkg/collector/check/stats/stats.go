@@ -7,6 +7,7 @@
 package stats
 
 import (
+	"errors"
 	"sync"
 	"time"
 
@@ -128,9 +129,15 @@ type Stats struct {
 	LastDelay                int64     // most recent check start time delay relative to the previous check run, in seconds
 	LastWarnings             []string  // warnings that occurred in the last run, if any
 	UpdateTimestamp          int64     // latest update to this instance, unix timestamp in seconds
+	TotalTimeouts            uint64    // runs cancelled because they exceeded the run_timeout of the instance
+	ConsecutiveFailures      uint64    // runs that failed or timed out since the last successful run
+	CircuitBreakerOpenUntil  int64     // date until which the runs are skipped after consecutive failures, unix timestamp in seconds, 0 if closed
 	m                        sync.Mutex
 	Telemetry                bool // do we want telemetry on this Check
 	HASupported              bool
+
+	// circuitBreaker holds the settings of the circuit breaker, resolved when the stats are created
+	circuitBreaker circuitBreakerConfig
 }
 
 //nolint:revive // TODO(AML) Fix revive linter
@@ -164,6 +171,7 @@ func NewStats(c StatsCheck) *Stats {
 		EventPlatformEvents:      make(map[string]int64),
 		TotalEventPlatformEvents: make(map[string]int64),
 		HASupported:              c.IsHASupported(),
+		circuitBreaker:           newCircuitBreakerConfig(),
 	}
 
 	// We are interested in a check's run state values even when they are 0 so we
@@ -210,12 +218,22 @@ func (cs *Stats) Add(t time.Duration, err error, warnings []error, metricStats S
 			tlmRuns.Inc(cs.CheckName, runCheckFailureTag)
 		}
 		cs.LastError = err.Error()
+		var timeoutErr interface{ Timeout() bool }
+		if errors.As(err, &timeoutErr) && timeoutErr.Timeout() {
+			cs.TotalTimeouts++
+		}
+		cs.ConsecutiveFailures++
+		if backoff := cs.circuitBreaker.backoff(cs.ConsecutiveFailures); backoff > 0 {
+			cs.CircuitBreakerOpenUntil = time.Now().Add(backoff).Unix()
+		}
 	} else {
 		if cs.Telemetry {
 			tlmRuns.Inc(cs.CheckName, runCheckSuccessTag)
 		}
 		cs.LastError = ""
 		cs.LastSuccessDate = time.Now().Unix()
+		cs.ConsecutiveFailures = 0
+		cs.CircuitBreakerOpenUntil = 0
 	}
 	cs.LastWarnings = []string{}
 	if len(warnings) != 0 {
@@ -270,6 +288,14 @@ func (cs *Stats) Add(t time.Duration, err error, warnings []error, metricStats S
 	}
 }
 
+// IsCircuitBreakerOpen returns whether the runs of the check are skipped at the given time, after
+// consecutive failures
+func (cs *Stats) IsCircuitBreakerOpen(now time.Time) bool {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	return cs.CircuitBreakerOpenUntil > 0 && now.Unix() < cs.CircuitBreakerOpenUntil
+}
+
 // SetStateCancelling sets the check stats to be in a cancelling state
 func (cs *Stats) SetStateCancelling() {
 	cs.m.Lock()
@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package check
+
+import (
+	"fmt"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+)
+
+// RunTimeoutError is the error returned by RunWithTimeout when a run exceeds its timeout
+type RunTimeoutError struct {
+	Duration time.Duration
+}
+
+func (e *RunTimeoutError) Error() string {
+	return fmt.Sprintf("check run timed out after %s", e.Duration)
+}
+
+// Timeout returns true, so that the error can be identified without depending on this package
+func (e *RunTimeoutError) Timeout() bool {
+	return true
+}
+
+// RunTimeout returns the run_timeout set in the instance of the check, or 0 when it's not set. The
+// checks resolving it when they're configured implement RunTimeouter, otherwise the instance is parsed.
+func RunTimeout(c Check) time.Duration {
+	if rt, ok := c.(RunTimeouter); ok {
+		return rt.RunTimeout()
+	}
+	return ParseRunTimeout(c.InstanceConfig())
+}
+
+// ParseRunTimeout returns the run_timeout set in the given instance, or 0 when it's not set
+func ParseRunTimeout(instance string) time.Duration {
+	var commonOptions integration.CommonInstanceConfig
+	if err := yaml.Unmarshal([]byte(instance), &commonOptions); err != nil || commonOptions.RunTimeout <= 0 {
+		return 0
+	}
+	return time.Duration(commonOptions.RunTimeout) * time.Second
+}
+
+// RunTimeouter is implemented by the checks resolving their run_timeout once, when they're configured
+type RunTimeouter interface {
+	RunTimeout() time.Duration
+}
+
+// RunCanceler is implemented by the checks whose Cancel doesn't only interrupt the current run, but
+// also deschedules them or releases resources the next runs need
+type RunCanceler interface {
+	CancelRun()
+}
+
+// CancelRun asks the check to interrupt its current run, with CancelRun when it implements
+// RunCanceler, and Cancel otherwise
+func CancelRun(c Check) {
+	if rc, ok := c.(RunCanceler); ok {
+		rc.CancelRun()
+		return
+	}
+	c.Cancel()
+}
+
+// RunWithTimeout runs the check, and cancels its run when it doesn't complete within timeout, in
+// which case it returns a *RunTimeoutError without waiting for the run. A timeout of 0 disables it.
+//
+// Cancelling a run can't always interrupt it: a check ignoring Cancel, or a Python check stuck in
+// code that doesn't release the GIL, keeps running in the background until it returns by itself.
+// lateDone, when not nil, is then called once the run actually completes, so that the caller
+// doesn't run the check again in the meantime.
+func RunWithTimeout(c Check, timeout time.Duration, lateDone func()) error {
+	if timeout <= 0 {
+		return c.Run()
+	}
+
+	result := make(chan error)
+	timedOut := make(chan struct{})
+	go func() {
+		err := c.Run()
+		select {
+		case result <- err:
+		case <-timedOut:
+			if lateDone != nil {
+				lateDone()
+			}
+		}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+	}
+	close(timedOut)
+
+	// Cancel may block as long as the run, for instance when it needs a lock the run holds
+	go CancelRun(c)
+	return &RunTimeoutError{Duration: timeout}
+}
@@ -0,0 +1,248 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/util/relabel"
+)
+
+const (
+	defaultTimeout         = 10 * time.Second
+	defaultBearerTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// instanceConfig holds the subset of the openmetrics v2 instance options supported by the
+// Go check, with the addition of relabel_configs and native_histograms.
+type instanceConfig struct {
+	OpenMetricsEndpoint             string            `yaml:"openmetrics_endpoint"`
+	PrometheusURL                   string            `yaml:"prometheus_url"`
+	Namespace                       string            `yaml:"namespace"`
+	RawMetricPrefix                 string            `yaml:"raw_metric_prefix"`
+	Metrics                         []interface{}     `yaml:"metrics"`
+	ExcludeMetrics                  []string          `yaml:"exclude_metrics"`
+	ExcludeLabels                   []string          `yaml:"exclude_labels"`
+	RenameLabels                    map[string]string `yaml:"rename_labels"`
+	RelabelConfigs                  []relabel.Config  `yaml:"relabel_configs"`
+	TagByEndpoint                   *bool             `yaml:"tag_by_endpoint"`
+	EnableHealthServiceCheck        *bool             `yaml:"enable_health_service_check"`
+	CollectHistogramBuckets         *bool             `yaml:"collect_histogram_buckets"`
+	HistogramBucketsAsDistributions bool              `yaml:"histogram_buckets_as_distributions"`
+	NativeHistograms                bool              `yaml:"native_histograms"`
+	Headers                         map[string]string `yaml:"headers"`
+	ExtraHeaders                    map[string]string `yaml:"extra_headers"`
+	Username                        string            `yaml:"username"`
+	Password                        string            `yaml:"password"`
+	BearerTokenAuth                 bool              `yaml:"bearer_token_auth"`
+	BearerTokenPath                 string            `yaml:"bearer_token_path"`
+	Timeout                         float64           `yaml:"timeout"`
+	TLSVerify                       *bool             `yaml:"tls_verify"`
+	TLSCACert                       string            `yaml:"tls_ca_cert"`
+	TLSCert                         string            `yaml:"tls_cert"`
+	TLSPrivateKey                   string            `yaml:"tls_private_key"`
+}
+
+// metricMatcher selects the metrics to collect, and renames some of them.
+type metricMatcher struct {
+	include []*regexp.Regexp
+	renames map[string]string
+	exclude []*regexp.Regexp
+}
+
+// config is the validated configuration of a check instance.
+type config struct {
+	endpoint         string
+	namespace        string
+	rawMetricPrefix  string
+	metrics          metricMatcher
+	excludeLabels    map[string]struct{}
+	renameLabels     map[string]string
+	relabelRules     []*relabel.Rule
+	tags             []string
+	healthCheck      bool
+	histogramBuckets bool
+	bucketsAsSketch  bool
+	nativeHistograms bool
+	headers          http.Header
+	username         string
+	password         string
+	bearerTokenPath  string
+	timeout          time.Duration
+	tlsConfig        *tls.Config
+}
+
+func parseConfig(data []byte) (*config, error) {
+	var instance instanceConfig
+	if err := yaml.Unmarshal(data, &instance); err != nil {
+		return nil, err
+	}
+
+	if instance.OpenMetricsEndpoint == "" {
+		if instance.PrometheusURL != "" {
+			return nil, errors.New("prometheus_url is only supported by the Python check, use openmetrics_endpoint instead")
+		}
+		return nil, errors.New("openmetrics_endpoint is required")
+	}
+
+	cfg := &config{
+		endpoint:         instance.OpenMetricsEndpoint,
+		namespace:        instance.Namespace,
+		rawMetricPrefix:  instance.RawMetricPrefix,
+		excludeLabels:    make(map[string]struct{}, len(instance.ExcludeLabels)),
+		renameLabels:     instance.RenameLabels,
+		healthCheck:      instance.EnableHealthServiceCheck == nil || *instance.EnableHealthServiceCheck,
+		histogramBuckets: instance.CollectHistogramBuckets == nil || *instance.CollectHistogramBuckets,
+		bucketsAsSketch:  instance.HistogramBucketsAsDistributions,
+		nativeHistograms: instance.NativeHistograms,
+		headers:          make(http.Header),
+		username:         instance.Username,
+		password:         instance.Password,
+		timeout:          defaultTimeout,
+	}
+	// the instance tags are added by the sender
+	if instance.TagByEndpoint == nil || *instance.TagByEndpoint {
+		cfg.tags = []string{"endpoint:" + cfg.endpoint}
+	}
+	if instance.Timeout > 0 {
+		cfg.timeout = time.Duration(instance.Timeout * float64(time.Second))
+	}
+	for _, label := range instance.ExcludeLabels {
+		cfg.excludeLabels[label] = struct{}{}
+	}
+	for key, value := range instance.Headers {
+		cfg.headers.Set(key, value)
+	}
+	for key, value := range instance.ExtraHeaders {
+		cfg.headers.Set(key, value)
+	}
+	if instance.BearerTokenAuth {
+		cfg.bearerTokenPath = instance.BearerTokenPath
+		if cfg.bearerTokenPath == "" {
+			cfg.bearerTokenPath = defaultBearerTokenPath
+		}
+	}
+
+	var err error
+	if cfg.metrics, err = parseMetrics(instance.Metrics, instance.ExcludeMetrics); err != nil {
+		return nil, err
+	}
+	if cfg.relabelRules, err = relabel.NewRules(instance.RelabelConfigs); err != nil {
+		return nil, err
+	}
+	if cfg.tlsConfig, err = buildTLSConfig(instance); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseMetrics parses the metrics option, whose items are either regular expressions
+// or maps of raw metric names to the names they are submitted with.
+func parseMetrics(metrics []interface{}, exclude []string) (metricMatcher, error) {
+	matcher := metricMatcher{renames: make(map[string]string)}
+	for _, item := range metrics {
+		switch m := item.(type) {
+		case string:
+			re, err := regexp.Compile("^(?:" + m + ")$")
+			if err != nil {
+				return matcher, fmt.Errorf("invalid metrics pattern %q: %w", m, err)
+			}
+			matcher.include = append(matcher.include, re)
+		case map[interface{}]interface{}:
+			for raw, name := range m {
+				rawName, ok1 := raw.(string)
+				newName, ok2 := name.(string)
+				if !ok1 || !ok2 {
+					return matcher, fmt.Errorf("invalid metrics mapping %v, only raw names mapped to new names are supported", m)
+				}
+				matcher.renames[rawName] = newName
+			}
+		default:
+			return matcher, fmt.Errorf("invalid metrics item %v", item)
+		}
+	}
+	if len(matcher.include) == 0 && len(matcher.renames) == 0 {
+		return matcher, errors.New("metrics is required, use [\".*\"] to collect all the metrics")
+	}
+	for _, pattern := range exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return matcher, fmt.Errorf("invalid exclude_metrics pattern %q: %w", pattern, err)
+		}
+		matcher.exclude = append(matcher.exclude, re)
+	}
+	return matcher, nil
+}
+
+// match returns the name a raw metric is submitted with, and false if it is not collected.
+func (m *metricMatcher) match(rawName string) (string, bool) {
+	for _, re := range m.exclude {
+		if re.MatchString(rawName) {
+			return "", false
+		}
+	}
+	if name, ok := m.renames[rawName]; ok {
+		return name, true
+	}
+	for _, re := range m.include {
+		if re.MatchString(rawName) {
+			return rawName, true
+		}
+	}
+	return "", false
+}
+
+func buildTLSConfig(instance instanceConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: instance.TLSVerify != nil && !*instance.TLSVerify,
+	}
+	if instance.TLSCACert != "" {
+		pem, err := os.ReadFile(instance.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read tls_ca_cert: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", instance.TLSCACert)
+		}
+	}
+	if instance.TLSCert != "" {
+		keyFile := instance.TLSPrivateKey
+		if keyFile == "" {
+			// the key may be bundled with the certificate
+			keyFile = instance.TLSCert
+		}
+		cert, err := tls.LoadX509KeyPair(instance.TLSCert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load tls_cert: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// metricName returns the name a metric is submitted with.
+func (c *config) metricName(name string) string {
+	if c.namespace == "" {
+		return name
+	}
+	return c.namespace + "." + name
+}
+
+// trimPrefix removes raw_metric_prefix from a raw metric name.
+func (c *config) trimPrefix(name string) string {
+	return strings.TrimPrefix(name, c.rawMetricPrefix)
+}
@@ -0,0 +1,368 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package openmetrics implements a check scraping Prometheus and OpenMetrics endpoints. It
+// implements a subset of the Python openmetrics v2 check, and is used when the Python
+// runtime is not available or when the instance sets `loader: core`.
+package openmetrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/option"
+	"github.com/DataDog/datadog-agent/pkg/util/relabel"
+)
+
+const (
+	// CheckName is the name of the check
+	CheckName = "openmetrics"
+
+	// acceptHeader prefers OpenMetrics over the Prometheus text format, as Prometheus does
+	acceptHeader = "application/openmetrics-text;version=1.0.0;q=0.5,application/openmetrics-text;version=0.0.1;q=0.4,text/plain;version=0.0.4;q=0.3,*/*;q=0.2"
+	// acceptHeaderProtobuf prefers the protobuf format, the only one exposing native histograms
+	acceptHeaderProtobuf = "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.6," + acceptHeader
+)
+
+// Check scrapes a Prometheus or OpenMetrics endpoint
+type Check struct {
+	core.CheckBase
+	config *config
+	client *http.Client
+	// lastScrape is the time of the previous successful scrape, used along with the
+	// `_created` series to detect the counters which were reset
+	lastScrape time.Time
+}
+
+// Factory creates a new check factory
+func Factory() option.Option[func() check.Check] {
+	return option.New(newCheck)
+}
+
+func newCheck() check.Check {
+	return &Check{
+		CheckBase: core.NewCheckBase(CheckName),
+	}
+}
+
+// Configure parses the check configuration and init the check
+func (c *Check) Configure(senderManager sender.SenderManager, integrationConfigDigest uint64, data integration.Data, initConfig integration.Data, source string) error {
+	cfg, err := parseConfig(data)
+	if err != nil {
+		return err
+	}
+
+	c.BuildID(integrationConfigDigest, data, initConfig)
+	if err := c.CommonConfigure(senderManager, initConfig, data, source); err != nil {
+		return err
+	}
+
+	c.config = cfg
+	c.client = &http.Client{
+		Timeout: cfg.timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: cfg.tlsConfig,
+		},
+	}
+	return nil
+}
+
+// Run scrapes the endpoint and submits its metrics
+func (c *Check) Run() error {
+	sender, err := c.GetSender()
+	if err != nil {
+		return err
+	}
+
+	scrapeTime := time.Now()
+	families, err := c.scrape()
+	serviceCheckName := c.config.metricName("openmetrics.health")
+	if err != nil {
+		if c.config.healthCheck {
+			sender.ServiceCheck(serviceCheckName, servicecheck.ServiceCheckCritical, "", c.config.tags, err.Error())
+		}
+		sender.Commit()
+		return err
+	}
+	if c.config.healthCheck {
+		sender.ServiceCheck(serviceCheckName, servicecheck.ServiceCheckOK, "", c.config.tags, "")
+	}
+
+	c.submitFamilies(sender, families)
+	c.lastScrape = scrapeTime
+	sender.Commit()
+	return nil
+}
+
+func (c *Check) scrape() ([]*dto.MetricFamily, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.config.headers.Clone()
+	if c.config.nativeHistograms {
+		req.Header.Set("Accept", acceptHeaderProtobuf)
+	} else if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", acceptHeader)
+	}
+	if c.config.username != "" {
+		req.SetBasicAuth(c.config.username, c.config.password)
+	}
+	if c.config.bearerTokenPath != "" {
+		// the token is read on every run, as it may be rotated
+		token, err := os.ReadFile(c.config.bearerTokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the bearer token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, c.config.endpoint)
+	}
+	return parseMetricFamilies(resp.Body, resp.Header.Get("Content-Type"))
+}
+
+func (c *Check) submitFamilies(sender sender.Sender, families []*dto.MetricFamily) {
+	for _, family := range families {
+		familyName := family.GetName()
+		if family.GetType() == dto.MetricType_COUNTER {
+			// counters are submitted as <name>.count
+			familyName = strings.TrimSuffix(familyName, "_total")
+		}
+
+		for _, metric := range family.Metric {
+			labels := make(map[string]string, len(metric.Label)+1)
+			for _, label := range metric.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			labels[relabel.MetricNameLabel] = familyName
+			if !relabel.Apply(c.config.relabelRules, labels) {
+				continue
+			}
+			rawName := c.config.trimPrefix(labels[relabel.MetricNameLabel])
+			delete(labels, relabel.MetricNameLabel)
+
+			name, ok := c.config.metrics.match(rawName)
+			if !ok {
+				continue
+			}
+			c.submitMetric(sender, family.GetType(), c.config.metricName(name), metric, c.buildTags(labels))
+		}
+	}
+}
+
+func (c *Check) submitMetric(sender sender.Sender, metricType dto.MetricType, name string, metric *dto.Metric, tags []string) {
+	switch metricType {
+	case dto.MetricType_GAUGE:
+		sender.Gauge(name, metric.GetGauge().GetValue(), "", tags)
+	case dto.MetricType_UNTYPED:
+		sender.Gauge(name, metric.GetUntyped().GetValue(), "", tags)
+	case dto.MetricType_COUNTER:
+		counter := metric.GetCounter()
+		flushFirstValue := c.flushFirstValue(counter.GetCreatedTimestamp().AsTime(), counter.CreatedTimestamp != nil)
+		sender.MonotonicCountWithFlushFirstValue(name+".count", counter.GetValue(), "", tags, flushFirstValue)
+	case dto.MetricType_SUMMARY:
+		summary := metric.GetSummary()
+		flushFirstValue := c.flushFirstValue(summary.GetCreatedTimestamp().AsTime(), summary.CreatedTimestamp != nil)
+		sender.MonotonicCountWithFlushFirstValue(name+".count", float64(summary.GetSampleCount()), "", tags, flushFirstValue)
+		sender.MonotonicCountWithFlushFirstValue(name+".sum", summary.GetSampleSum(), "", tags, flushFirstValue)
+		for _, quantile := range summary.Quantile {
+			if math.IsNaN(quantile.GetValue()) {
+				continue
+			}
+			sender.Gauge(name+".quantile", quantile.GetValue(), "", withTag(tags, "quantile:"+formatFloat(quantile.GetQuantile())))
+		}
+	case dto.MetricType_HISTOGRAM:
+		histogram := metric.GetHistogram()
+		flushFirstValue := c.flushFirstValue(histogram.GetCreatedTimestamp().AsTime(), histogram.CreatedTimestamp != nil)
+		sender.MonotonicCountWithFlushFirstValue(name+".count", histogramCount(histogram), "", tags, flushFirstValue)
+		sender.MonotonicCountWithFlushFirstValue(name+".sum", histogram.GetSampleSum(), "", tags, flushFirstValue)
+		if isNativeHistogram(histogram) {
+			submitNativeHistogram(sender, name, histogram, tags, flushFirstValue)
+		} else if c.config.histogramBuckets {
+			c.submitBuckets(sender, name, histogram, tags, flushFirstValue)
+		}
+	case dto.MetricType_GAUGE_HISTOGRAM:
+		histogram := metric.GetHistogram()
+		sender.Gauge(name+".gcount", histogramCount(histogram), "", tags)
+		sender.Gauge(name+".gsum", histogram.GetSampleSum(), "", tags)
+		if c.config.histogramBuckets {
+			for _, bucket := range histogram.Bucket {
+				sender.Gauge(name+".bucket", bucketCount(bucket), "", withTag(tags, "upper_bound:"+formatFloat(bucket.GetUpperBound())))
+			}
+		}
+	default:
+		log.Debugf("Unsupported metric type %s for metric %s", metricType, name)
+	}
+}
+
+// submitBuckets submits the buckets of a classic histogram, either as monotonic counts
+// or as distributions.
+func (c *Check) submitBuckets(sender sender.Sender, name string, histogram *dto.Histogram, tags []string, flushFirstValue bool) {
+	buckets := make([]*dto.Bucket, len(histogram.Bucket))
+	copy(buckets, histogram.Bucket)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].GetUpperBound() < buckets[j].GetUpperBound() })
+
+	if !c.config.bucketsAsSketch {
+		for _, bucket := range buckets {
+			sender.MonotonicCountWithFlushFirstValue(name+".bucket", bucketCount(bucket), "", withTag(tags, "upper_bound:"+formatFloat(bucket.GetUpperBound())), flushFirstValue)
+		}
+		return
+	}
+
+	lowerBound, previousCount := 0.0, 0.0
+	for i, bucket := range buckets {
+		upperBound := bucket.GetUpperBound()
+		if i == 0 && upperBound <= 0 {
+			lowerBound = upperBound
+		}
+		count := bucketCount(bucket)
+		submitBucket(sender, name, int64(count-previousCount), lowerBound, upperBound, tags, flushFirstValue)
+		lowerBound, previousCount = upperBound, count
+	}
+}
+
+// submitNativeHistogram submits the exponential buckets of a native histogram as distributions.
+func submitNativeHistogram(sender sender.Sender, name string, histogram *dto.Histogram, tags []string, flushFirstValue bool) {
+	base := math.Pow(2, math.Pow(2, -float64(histogram.GetSchema())))
+	zeroThreshold := histogram.GetZeroThreshold()
+
+	// bucket i covers (base^(i-1), base^i] and, for negative buckets, [-base^i, -base^(i-1))
+	forEachNativeBucket(histogram.NegativeSpan, histogram.NegativeDelta, histogram.NegativeCount, func(index int32, count float64) {
+		upperBound := math.Pow(base, float64(index))
+		submitBucket(sender, name, int64(count), -upperBound, -upperBound/base, tags, flushFirstValue)
+	})
+	zeroCount := float64(histogram.GetZeroCount())
+	if histogram.ZeroCountFloat != nil {
+		zeroCount = histogram.GetZeroCountFloat()
+	}
+	submitBucket(sender, name, int64(zeroCount), -zeroThreshold, zeroThreshold, tags, flushFirstValue)
+	forEachNativeBucket(histogram.PositiveSpan, histogram.PositiveDelta, histogram.PositiveCount, func(index int32, count float64) {
+		upperBound := math.Pow(base, float64(index))
+		submitBucket(sender, name, int64(count), upperBound/base, upperBound, tags, flushFirstValue)
+	})
+}
+
+// forEachNativeBucket calls fn with the index and the count of every bucket of a native
+// histogram. Integer histograms encode the counts as deltas to the previous bucket.
+func forEachNativeBucket(spans []*dto.BucketSpan, deltas []int64, counts []float64, fn func(int32, float64)) {
+	var index int32
+	var count int64
+	position := 0
+	for i, span := range spans {
+		if i == 0 {
+			index = span.GetOffset()
+		} else {
+			index += span.GetOffset()
+		}
+		for j := uint32(0); j < span.GetLength(); j++ {
+			switch {
+			case position < len(counts):
+				fn(index, counts[position])
+			case position < len(deltas):
+				count += deltas[position]
+				fn(index, float64(count))
+			default:
+				return
+			}
+			position++
+			index++
+		}
+	}
+}
+
+// submitBucket submits a bucket with its bounds as tags, so that every bucket has its own context.
+func submitBucket(sender sender.Sender, name string, count int64, lowerBound, upperBound float64, tags []string, flushFirstValue bool) {
+	bucketTags := withTag(tags, "lower_bound:"+formatFloat(lowerBound), "upper_bound:"+formatFloat(upperBound))
+	sender.HistogramBucket(name, count, lowerBound, upperBound, true, "", bucketTags, flushFirstValue)
+}
+
+// flushFirstValue returns whether the first value of a monotonic count should be submitted.
+// Series appearing after the first run are new, hence their first value is flushed, unless
+// their `_created` timestamp shows they already existed during the previous run.
+func (c *Check) flushFirstValue(created time.Time, hasCreated bool) bool {
+	if c.lastScrape.IsZero() {
+		return false
+	}
+	if hasCreated {
+		return created.After(c.lastScrape)
+	}
+	return true
+}
+
+func (c *Check) buildTags(labels map[string]string) []string {
+	tags := make([]string, 0, len(labels)+len(c.config.tags))
+	tags = append(tags, c.config.tags...)
+	for name, value := range labels {
+		if _, excluded := c.config.excludeLabels[name]; excluded {
+			continue
+		}
+		if renamed, ok := c.config.renameLabels[name]; ok {
+			name = renamed
+		}
+		tags = append(tags, name+":"+value)
+	}
+	sort.Strings(tags[len(c.config.tags):])
+	return tags
+}
+
+// withTag returns a copy of tags with extra tags appended.
+func withTag(tags []string, extra ...string) []string {
+	return append(append(make([]string, 0, len(tags)+len(extra)), tags...), extra...)
+}
+
+func isNativeHistogram(histogram *dto.Histogram) bool {
+	return histogram.GetZeroThreshold() > 0 || histogram.GetZeroCount() > 0 || histogram.GetZeroCountFloat() > 0 ||
+		len(histogram.PositiveSpan) > 0 || len(histogram.NegativeSpan) > 0
+}
+
+func histogramCount(histogram *dto.Histogram) float64 {
+	if histogram.SampleCountFloat != nil {
+		return histogram.GetSampleCountFloat()
+	}
+	return float64(histogram.GetSampleCount())
+}
+
+func bucketCount(bucket *dto.Bucket) float64 {
+	if bucket.CumulativeCountFloat != nil {
+		return bucket.GetCumulativeCountFloat()
+	}
+	return float64(bucket.GetCumulativeCount())
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,175 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+)
+
+func newTestCheck(t *testing.T, instance string) (*Check, *mocksender.MockSender) {
+	c := newCheck().(*Check)
+	senderManager := mocksender.CreateDefaultDemultiplexer()
+	require.NoError(t, c.Configure(senderManager, integration.FakeConfigHash, []byte(instance), nil, "test"))
+
+	sender := mocksender.NewMockSenderWithSenderManager(c.ID(), senderManager)
+	sender.SetupAcceptAll()
+	return c, sender
+}
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("Accept"), openMetricsContentType)
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		fmt.Fprint(w, openMetricsPayload)
+	}))
+	defer server.Close()
+
+	c, sender := newTestCheck(t, fmt.Sprintf(`
+openmetrics_endpoint: %s
+namespace: app
+metrics:
+  - http_requests|latency_seconds|rpc_duration|temperature
+  - build_info: build
+exclude_labels: [code]
+rename_labels:
+  method: http_method
+relabel_configs:
+  - source_labels: [method]
+    regex: post
+    action: drop
+`, server.URL))
+
+	require.NoError(t, c.Run())
+
+	endpointTag := "endpoint:" + server.URL
+	sender.AssertServiceCheck(t, "app.openmetrics.health", servicecheck.ServiceCheckOK, "", []string{endpointTag}, "")
+	sender.AssertMonotonicCount(t, "MonotonicCountWithFlushFirstValue", "app.http_requests.count", 1027, "", []string{endpointTag, "http_method:get"}, false)
+	sender.AssertNotCalled(t, "MonotonicCountWithFlushFirstValue", "app.http_requests.count", 3.0, mock.Anything, mock.Anything, mock.Anything)
+	sender.AssertMetricNotTaggedWith(t, "MonotonicCountWithFlushFirstValue", "app.http_requests.count", []string{"code:200"})
+
+	sender.AssertMonotonicCount(t, "MonotonicCountWithFlushFirstValue", "app.latency_seconds.count", 11, "", []string{endpointTag}, false)
+	sender.AssertMonotonicCount(t, "MonotonicCountWithFlushFirstValue", "app.latency_seconds.sum", 4.5, "", []string{endpointTag}, false)
+	sender.AssertMonotonicCount(t, "MonotonicCountWithFlushFirstValue", "app.latency_seconds.bucket", 8, "", []string{endpointTag, "upper_bound:0.1"}, false)
+	sender.AssertMonotonicCount(t, "MonotonicCountWithFlushFirstValue", "app.latency_seconds.bucket", 11, "", []string{endpointTag, "upper_bound:inf"}, false)
+
+	sender.AssertMetric(t, "Gauge", "app.rpc_duration.quantile", 1.5, "", []string{endpointTag, "quantile:0.99"})
+	sender.AssertMetric(t, "Gauge", "app.build", 1, "", []string{endpointTag, "version:1.2.3"})
+	sender.AssertMetric(t, "Gauge", "app.temperature", 21.5, "", []string{endpointTag})
+	sender.AssertNotCalled(t, "Gauge", "app.untyped_metric", mock.Anything, mock.Anything, mock.Anything)
+	sender.AssertNotCalled(t, "Gauge", "app.feature", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunEndpointDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c, sender := newTestCheck(t, fmt.Sprintf("openmetrics_endpoint: %s\nmetrics: ['.*']", server.URL))
+
+	assert.Error(t, c.Run())
+	sender.AssertServiceCheck(t, "openmetrics.health", servicecheck.ServiceCheckCritical, "", []string{"endpoint:" + server.URL}, "unexpected status code 503 from "+server.URL)
+}
+
+func TestFlushFirstValue(t *testing.T) {
+	c := &Check{}
+	assert.False(t, c.flushFirstValue(time.Time{}, false))
+
+	c.lastScrape = time.Now()
+	// a series appearing after the first run is new
+	assert.True(t, c.flushFirstValue(time.Time{}, false))
+	// unless it was created before the previous run
+	assert.False(t, c.flushFirstValue(c.lastScrape.Add(-time.Minute), true))
+	// a counter created after the previous run was reset
+	assert.True(t, c.flushFirstValue(c.lastScrape.Add(time.Second), true))
+}
+
+func TestSubmitNativeHistogram(t *testing.T) {
+	sender := mocksender.NewMockSender("native")
+	sender.SetupAcceptAll()
+
+	// schema 0: the buckets are powers of 2
+	histogram := &dto.Histogram{
+		SampleCount:   proto.Uint64(6),
+		SampleSum:     proto.Float64(10),
+		Schema:        proto.Int32(0),
+		ZeroThreshold: proto.Float64(0.001),
+		ZeroCount:     proto.Uint64(1),
+		PositiveSpan:  []*dto.BucketSpan{{Offset: proto.Int32(0), Length: proto.Uint32(2)}, {Offset: proto.Int32(1), Length: proto.Uint32(1)}},
+		PositiveDelta: []int64{2, -1, 1},
+		NegativeSpan:  []*dto.BucketSpan{{Offset: proto.Int32(1), Length: proto.Uint32(1)}},
+		NegativeDelta: []int64{1},
+	}
+	require.True(t, isNativeHistogram(histogram))
+
+	submitNativeHistogram(sender, "latency", histogram, []string{"env:test"}, false)
+
+	sender.AssertHistogramBucket(t, "HistogramBucket", "latency", 1, -2, -1, true, "", []string{"env:test", "lower_bound:-2", "upper_bound:-1"}, false)
+	sender.AssertHistogramBucket(t, "HistogramBucket", "latency", 1, -0.001, 0.001, true, "", []string{"env:test", "lower_bound:-0.001", "upper_bound:0.001"}, false)
+	sender.AssertHistogramBucket(t, "HistogramBucket", "latency", 2, 0.5, 1, true, "", []string{"env:test", "lower_bound:0.5", "upper_bound:1"}, false)
+	sender.AssertHistogramBucket(t, "HistogramBucket", "latency", 1, 1, 2, true, "", []string{"env:test", "lower_bound:1", "upper_bound:2"}, false)
+	sender.AssertHistogramBucket(t, "HistogramBucket", "latency", 2, 4, 8, true, "", []string{"env:test", "lower_bound:4", "upper_bound:8"}, false)
+	sender.AssertNumberOfCalls(t, "HistogramBucket", 5)
+}
+
+func TestSubmitBucketsAsDistributions(t *testing.T) {
+	sender := mocksender.NewMockSender("buckets")
+	sender.SetupAcceptAll()
+	c := &Check{config: &config{histogramBuckets: true, bucketsAsSketch: true}}
+
+	histogram := &dto.Histogram{
+		Bucket: []*dto.Bucket{
+			{UpperBound: proto.Float64(math.Inf(1)), CumulativeCount: proto.Uint64(11)},
+			{UpperBound: proto.Float64(0.1), CumulativeCount: proto.Uint64(8)},
+			{UpperBound: proto.Float64(1), CumulativeCount: proto.Uint64(10)},
+		},
+	}
+	c.submitBuckets(sender, "latency", histogram, nil, true)
+
+	sender.AssertHistogramBucket(t, "HistogramBucket", "latency", 8, 0, 0.1, true, "", []string{"lower_bound:0", "upper_bound:0.1"}, true)
+	sender.AssertHistogramBucket(t, "HistogramBucket", "latency", 2, 0.1, 1, true, "", []string{"lower_bound:0.1", "upper_bound:1"}, true)
+	sender.AssertHistogramBucket(t, "HistogramBucket", "latency", 1, 1, math.Inf(1), true, "", []string{"lower_bound:1", "upper_bound:inf"}, true)
+}
+
+func TestConfigure(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		instance string
+		err      string
+	}{
+		{name: "missing endpoint", instance: `metrics: ['.*']`, err: "openmetrics_endpoint is required"},
+		{name: "v1 instance", instance: `{prometheus_url: "http://localhost/metrics", metrics: ['*']}`, err: "prometheus_url is only supported by the Python check"},
+		{name: "missing metrics", instance: `openmetrics_endpoint: http://localhost/metrics`, err: "metrics is required"},
+		{name: "invalid pattern", instance: `{openmetrics_endpoint: "http://localhost/metrics", metrics: ['(']}`, err: "invalid metrics pattern"},
+		{name: "invalid relabeling", instance: `{openmetrics_endpoint: "http://localhost/metrics", metrics: ['.*'], relabel_configs: [{action: foo}]}`, err: "unknown relabeling action"},
+		// instances generated by the autodiscovery are JSON
+		{name: "autodiscovery", instance: `{"namespace":"","metrics":[".*"],"openmetrics_endpoint":"http://10.0.0.1:8080/metrics"}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfig([]byte(tt.instance))
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
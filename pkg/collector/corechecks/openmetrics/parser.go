@@ -0,0 +1,509 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	openMetricsContentType = "application/openmetrics-text"
+	protobufContentType    = "application/vnd.google.protobuf"
+
+	// maxLineSize bounds the size of a single line of the text formats
+	maxLineSize = 1 << 20
+)
+
+// parseMetricFamilies decodes a scrape response according to its content type. The OpenMetrics
+// text format, the Prometheus text format and the delimited protobuf format are supported.
+func parseMetricFamilies(r io.Reader, contentType string) ([]*dto.MetricFamily, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// the Prometheus text format is the historical default
+		mediaType = "text/plain"
+	}
+
+	switch {
+	case mediaType == openMetricsContentType:
+		return parseOpenMetrics(r)
+	case mediaType == protobufContentType && params["encoding"] == "delimited":
+		return decodeAll(expfmt.NewDecoder(r, expfmt.NewFormat(expfmt.TypeProtoDelim)))
+	default:
+		return decodeAll(expfmt.NewDecoder(r, expfmt.NewFormat(expfmt.TypeTextPlain)))
+	}
+}
+
+func decodeAll(decoder expfmt.Decoder) ([]*dto.MetricFamily, error) {
+	var families []*dto.MetricFamily
+	for {
+		family := &dto.MetricFamily{}
+		if err := decoder.Decode(family); err != nil {
+			if errors.Is(err, io.EOF) {
+				return families, nil
+			}
+			return families, err
+		}
+		families = append(families, family)
+	}
+}
+
+// familySuffixes lists the sample name suffixes allowed for each OpenMetrics type.
+var familySuffixes = map[string][]string{
+	"counter":        {"_total", "_created"},
+	"histogram":      {"_bucket", "_count", "_sum", "_created"},
+	"gaugehistogram": {"_bucket", "_gcount", "_gsum"},
+	"summary":        {"", "_count", "_sum", "_created"},
+	"info":           {"_info"},
+	"stateset":       {""},
+	"gauge":          {""},
+	"unknown":        {""},
+}
+
+// omFamily is a metric family being parsed.
+type omFamily struct {
+	omType string
+	family *dto.MetricFamily
+	// series indexes the metrics of the family by their labels, leaving out the
+	// "le" and "quantile" labels which identify buckets and quantiles
+	series map[string]*dto.Metric
+}
+
+// omSample is a parsed sample line.
+type omSample struct {
+	name      string
+	labels    []*dto.LabelPair
+	value     float64
+	timestamp *int64
+	exemplar  *dto.Exemplar
+}
+
+// parseOpenMetrics parses the OpenMetrics text exposition format. Info and stateset
+// metrics are converted to gauges, as done by Prometheus.
+func parseOpenMetrics(r io.Reader) ([]*dto.MetricFamily, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var families []*omFamily
+	byName := make(map[string]*omFamily)
+	var current *omFamily
+
+	getFamily := func(name string) *omFamily {
+		if f, ok := byName[name]; ok {
+			return f
+		}
+		f := &omFamily{
+			omType: "unknown",
+			family: &dto.MetricFamily{Name: proto.String(name), Type: dto.MetricType_UNTYPED.Enum()},
+			series: make(map[string]*dto.Metric),
+		}
+		byName[name] = f
+		families = append(families, f)
+		return f
+	}
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		if line == "# EOF" {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) < 4 {
+				// comments are not allowed in OpenMetrics, but are harmless
+				continue
+			}
+			f := getFamily(fields[2])
+			switch fields[1] {
+			case "TYPE":
+				if err := f.setType(fields[3]); err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+				}
+			case "HELP":
+				f.family.Help = proto.String(unescape(fields[3]))
+			case "UNIT":
+				f.family.Unit = proto.String(fields[3])
+			}
+			current = f
+			continue
+		}
+
+		sample, err := parseSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		f, suffix := current, ""
+		if f != nil {
+			var ok bool
+			if suffix, ok = f.matchSample(sample.name); !ok {
+				f = nil
+			}
+		}
+		if f == nil {
+			// samples without metadata belong to a family of unknown type
+			f = getFamily(sample.name)
+			suffix = ""
+			current = f
+		}
+		if err := f.addSample(sample, suffix); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]*dto.MetricFamily, 0, len(families))
+	for _, f := range families {
+		if len(f.family.Metric) > 0 {
+			result = append(result, f.family)
+		}
+	}
+	return result, nil
+}
+
+func (f *omFamily) setType(omType string) error {
+	if _, ok := familySuffixes[omType]; !ok {
+		return fmt.Errorf("invalid metric type %q", omType)
+	}
+	f.omType = omType
+	switch omType {
+	case "counter":
+		f.family.Type = dto.MetricType_COUNTER.Enum()
+	case "histogram":
+		f.family.Type = dto.MetricType_HISTOGRAM.Enum()
+	case "gaugehistogram":
+		f.family.Type = dto.MetricType_GAUGE_HISTOGRAM.Enum()
+	case "summary":
+		f.family.Type = dto.MetricType_SUMMARY.Enum()
+	case "gauge", "stateset":
+		f.family.Type = dto.MetricType_GAUGE.Enum()
+	case "info":
+		f.family.Type = dto.MetricType_GAUGE.Enum()
+		f.family.Name = proto.String(f.family.GetName() + "_info")
+	default:
+		f.family.Type = dto.MetricType_UNTYPED.Enum()
+	}
+	return nil
+}
+
+// matchSample returns the suffix of a sample name if it belongs to the family.
+func (f *omFamily) matchSample(sampleName string) (string, bool) {
+	name := f.familyName()
+	if !strings.HasPrefix(sampleName, name) {
+		return "", false
+	}
+	suffix := sampleName[len(name):]
+	for _, allowed := range familySuffixes[f.omType] {
+		if suffix == allowed {
+			return suffix, true
+		}
+	}
+	return "", false
+}
+
+// familyName returns the name of the family as declared in the metadata.
+func (f *omFamily) familyName() string {
+	if f.omType == "info" {
+		return strings.TrimSuffix(f.family.GetName(), "_info")
+	}
+	return f.family.GetName()
+}
+
+func (f *omFamily) addSample(s *omSample, suffix string) error {
+	var special string
+	switch {
+	case f.omType == "histogram" || f.omType == "gaugehistogram":
+		if suffix == "_bucket" {
+			special = "le"
+		}
+	case f.omType == "summary":
+		if suffix == "" {
+			special = "quantile"
+		}
+	}
+
+	labels := make([]*dto.LabelPair, 0, len(s.labels))
+	var specialValue *string
+	for _, l := range s.labels {
+		if special != "" && l.GetName() == special {
+			specialValue = l.Value
+			continue
+		}
+		labels = append(labels, l)
+	}
+	if special != "" && specialValue == nil {
+		return fmt.Errorf("sample %s is missing the %q label", s.name, special)
+	}
+
+	key := seriesKey(labels)
+	m, ok := f.series[key]
+	if !ok {
+		m = &dto.Metric{Label: labels, TimestampMs: s.timestamp}
+		f.series[key] = m
+		f.family.Metric = append(f.family.Metric, m)
+	}
+
+	switch f.omType {
+	case "counter":
+		if m.Counter == nil {
+			m.Counter = &dto.Counter{}
+		}
+		if suffix == "_created" {
+			m.Counter.CreatedTimestamp = toTimestamp(s.value)
+		} else {
+			m.Counter.Value = proto.Float64(s.value)
+			m.Counter.Exemplar = s.exemplar
+		}
+	case "histogram", "gaugehistogram":
+		if m.Histogram == nil {
+			m.Histogram = &dto.Histogram{}
+		}
+		switch suffix {
+		case "_bucket":
+			upperBound, err := parseFloat(*specialValue)
+			if err != nil {
+				return fmt.Errorf("invalid bucket bound %q: %w", *specialValue, err)
+			}
+			m.Histogram.Bucket = append(m.Histogram.Bucket, &dto.Bucket{
+				UpperBound:      proto.Float64(upperBound),
+				CumulativeCount: proto.Uint64(uint64(s.value)),
+				Exemplar:        s.exemplar,
+			})
+		case "_count", "_gcount":
+			m.Histogram.SampleCount = proto.Uint64(uint64(s.value))
+		case "_sum", "_gsum":
+			m.Histogram.SampleSum = proto.Float64(s.value)
+		case "_created":
+			m.Histogram.CreatedTimestamp = toTimestamp(s.value)
+		}
+	case "summary":
+		if m.Summary == nil {
+			m.Summary = &dto.Summary{}
+		}
+		switch suffix {
+		case "":
+			quantile, err := parseFloat(*specialValue)
+			if err != nil {
+				return fmt.Errorf("invalid quantile %q: %w", *specialValue, err)
+			}
+			m.Summary.Quantile = append(m.Summary.Quantile, &dto.Quantile{Quantile: proto.Float64(quantile), Value: proto.Float64(s.value)})
+		case "_count":
+			m.Summary.SampleCount = proto.Uint64(uint64(s.value))
+		case "_sum":
+			m.Summary.SampleSum = proto.Float64(s.value)
+		case "_created":
+			m.Summary.CreatedTimestamp = toTimestamp(s.value)
+		}
+	case "gauge", "info", "stateset":
+		m.Gauge = &dto.Gauge{Value: proto.Float64(s.value)}
+	default:
+		m.Untyped = &dto.Untyped{Value: proto.Float64(s.value)}
+	}
+	return nil
+}
+
+func seriesKey(labels []*dto.LabelPair) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.GetName())
+		b.WriteByte(0)
+		b.WriteString(l.GetValue())
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// parseSample parses a sample line:
+// name [{labels}] value [timestamp] [# {exemplar labels} value [timestamp]]
+func parseSample(line string) (*omSample, error) {
+	end := strings.IndexAny(line, "{ ")
+	if end <= 0 {
+		return nil, fmt.Errorf("invalid sample %q", line)
+	}
+	s := &omSample{name: line[:end]}
+	rest := line[end:]
+
+	if rest[0] == '{' {
+		labels, n, err := parseLabels(rest)
+		if err != nil {
+			return nil, err
+		}
+		s.labels = labels
+		rest = rest[n:]
+	}
+
+	var exemplar string
+	if i := strings.Index(rest, " # "); i >= 0 {
+		exemplar = rest[i+3:]
+		rest = rest[:i]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid sample %q", line)
+	}
+	value, err := parseFloat(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q: %w", fields[0], err)
+	}
+	s.value = value
+	if len(fields) == 2 {
+		timestamp, err := parseFloat(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q: %w", fields[1], err)
+		}
+		// OpenMetrics timestamps are in seconds
+		ms := int64(timestamp * 1000)
+		s.timestamp = &ms
+	}
+
+	if exemplar != "" {
+		if s.exemplar, err = parseExemplar(exemplar); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// parseExemplar parses what follows the "#" of a sample line: {labels} value [timestamp]
+func parseExemplar(data string) (*dto.Exemplar, error) {
+	if data == "" || data[0] != '{' {
+		return nil, fmt.Errorf("invalid exemplar %q", data)
+	}
+	labels, n, err := parseLabels(data)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(data[n:])
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid exemplar %q", data)
+	}
+	value, err := parseFloat(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid exemplar value %q: %w", fields[0], err)
+	}
+	exemplar := &dto.Exemplar{Label: labels, Value: proto.Float64(value)}
+	if len(fields) == 2 {
+		timestamp, err := parseFloat(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid exemplar timestamp %q: %w", fields[1], err)
+		}
+		exemplar.Timestamp = toTimestamp(timestamp)
+	}
+	return exemplar, nil
+}
+
+// parseLabels parses a label set starting with "{" and returns the number of bytes consumed.
+func parseLabels(data string) ([]*dto.LabelPair, int, error) {
+	var labels []*dto.LabelPair
+	i := 1
+	for {
+		if i >= len(data) {
+			return nil, 0, fmt.Errorf("unterminated label set %q", data)
+		}
+		if data[i] == '}' {
+			return labels, i + 1, nil
+		}
+		eq := strings.IndexByte(data[i:], '=')
+		if eq <= 0 || i+eq+1 >= len(data) || data[i+eq+1] != '"' {
+			return nil, 0, fmt.Errorf("invalid label set %q", data)
+		}
+		name := data[i : i+eq]
+		i += eq + 2
+
+		var value strings.Builder
+		closed := false
+		for ; i < len(data); i++ {
+			c := data[i]
+			if c == '\\' && i+1 < len(data) {
+				i++
+				switch data[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(data[i])
+				}
+				continue
+			}
+			if c == '"' {
+				closed = true
+				i++
+				break
+			}
+			value.WriteByte(c)
+		}
+		if !closed {
+			return nil, 0, fmt.Errorf("unterminated label value in %q", data)
+		}
+		labels = append(labels, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value.String())})
+		if i < len(data) && data[i] == ',' {
+			i++
+		}
+	}
+}
+
+// unescape unescapes HELP texts.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			case '"':
+				b.WriteByte('"')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func parseFloat(s string) (float64, error) {
+	switch s {
+	case "+Inf", "Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func toTimestamp(seconds float64) *timestamppb.Timestamp {
+	sec, frac := math.Modf(seconds)
+	return timestamppb.New(time.Unix(int64(sec), int64(frac*float64(time.Second))))
+}
@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const openMetricsPayload = `# HELP http_requests Number of requests.
+# TYPE http_requests counter
+http_requests_total{method="get",code="200"} 1027 # {trace_id="KOO5S4vxi0o"} 0.67
+http_requests_created{method="get",code="200"} 1.6e9
+http_requests_total{method="post",code="500"} 3
+# TYPE latency_seconds histogram
+# UNIT latency_seconds seconds
+latency_seconds_bucket{le="0.1"} 8 # {trace_id="oHg5SJYRHA0"} 0.054 1.6e9
+latency_seconds_bucket{le="1"} 10
+latency_seconds_bucket{le="+Inf"} 11
+latency_seconds_count 11
+latency_seconds_sum 4.5
+latency_seconds_created 1.6e9
+# TYPE rpc_duration summary
+rpc_duration{quantile="0.5"} 0.2
+rpc_duration{quantile="0.99"} 1.5
+rpc_duration_count 20
+rpc_duration_sum 7
+# TYPE build info
+build_info{version="1.2.3"} 1
+# TYPE feature stateset
+feature{feature="a"} 1
+feature{feature="b"} 0
+# TYPE temperature gauge
+temperature{room="a \"quoted\" \\ value"} 21.5 1700000000.5
+untyped_metric 42
+# EOF
+`
+
+func TestParseOpenMetrics(t *testing.T) {
+	families, err := parseMetricFamilies(strings.NewReader(openMetricsPayload), "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	require.NoError(t, err)
+
+	byName := make(map[string]*dto.MetricFamily)
+	for _, f := range families {
+		byName[f.GetName()] = f
+	}
+	require.Len(t, byName, 7)
+
+	requests := byName["http_requests"]
+	require.NotNil(t, requests)
+	assert.Equal(t, dto.MetricType_COUNTER, requests.GetType())
+	assert.Equal(t, "Number of requests.", requests.GetHelp())
+	require.Len(t, requests.Metric, 2)
+	assert.Equal(t, 1027.0, requests.Metric[0].GetCounter().GetValue())
+	assert.Equal(t, int64(1.6e9), requests.Metric[0].GetCounter().GetCreatedTimestamp().GetSeconds())
+	assert.Equal(t, 0.67, requests.Metric[0].GetCounter().GetExemplar().GetValue())
+	assert.Equal(t, "KOO5S4vxi0o", requests.Metric[0].GetCounter().GetExemplar().GetLabel()[0].GetValue())
+	assert.Nil(t, requests.Metric[1].GetCounter().GetCreatedTimestamp())
+
+	latency := byName["latency_seconds"]
+	require.NotNil(t, latency)
+	assert.Equal(t, dto.MetricType_HISTOGRAM, latency.GetType())
+	assert.Equal(t, "seconds", latency.GetUnit())
+	require.Len(t, latency.Metric, 1)
+	histogram := latency.Metric[0].GetHistogram()
+	assert.Equal(t, uint64(11), histogram.GetSampleCount())
+	assert.Equal(t, 4.5, histogram.GetSampleSum())
+	require.Len(t, histogram.Bucket, 3)
+	assert.Equal(t, uint64(8), histogram.Bucket[0].GetCumulativeCount())
+	assert.Equal(t, 0.054, histogram.Bucket[0].GetExemplar().GetValue())
+	assert.NotNil(t, histogram.GetCreatedTimestamp())
+
+	summary := byName["rpc_duration"].Metric[0].GetSummary()
+	assert.Equal(t, uint64(20), summary.GetSampleCount())
+	require.Len(t, summary.Quantile, 2)
+	assert.Equal(t, 1.5, summary.Quantile[1].GetValue())
+
+	assert.Equal(t, dto.MetricType_GAUGE, byName["build_info"].GetType())
+	assert.Len(t, byName["feature"].Metric, 2)
+
+	temperature := byName["temperature"].Metric[0]
+	assert.Equal(t, `a "quoted" \ value`, temperature.Label[0].GetValue())
+	assert.Equal(t, int64(1700000000500), temperature.GetTimestampMs())
+
+	assert.Equal(t, dto.MetricType_UNTYPED, byName["untyped_metric"].GetType())
+	assert.Equal(t, 42.0, byName["untyped_metric"].Metric[0].GetUntyped().GetValue())
+}
+
+func TestParseOpenMetricsErrors(t *testing.T) {
+	for _, payload := range []string{
+		"# TYPE foo nonsense\n",
+		"foo{bar=\"baz} 1\n",
+		"foo{bar=baz} 1\n",
+		"foo not_a_number\n",
+		"foo 1 2 3\n",
+		"# TYPE foo histogram\nfoo_bucket 1\n",
+		"foo 1 # not an exemplar\n",
+	} {
+		t.Run(payload, func(t *testing.T) {
+			_, err := parseOpenMetrics(strings.NewReader(payload))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParsePrometheusText(t *testing.T) {
+	payload := `# HELP requests_total Number of requests.
+# TYPE requests_total counter
+requests_total{code="200"} 10
+# TYPE queue_size gauge
+queue_size 3
+`
+	families, err := parseMetricFamilies(strings.NewReader(payload), "text/plain; version=0.0.4")
+	require.NoError(t, err)
+	require.Len(t, families, 2)
+	assert.Equal(t, "requests_total", families[0].GetName())
+	assert.Equal(t, 10.0, families[0].Metric[0].GetCounter().GetValue())
+	assert.Equal(t, 3.0, families[1].Metric[0].GetGauge().GetValue())
+}
+
+func TestParseProtobufNativeHistogram(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:                        "native_seconds",
+		NativeHistogramBucketFactor: 1.1,
+	})
+	registry.MustRegister(histogram)
+	for _, v := range []float64{0.5, 1, 2, 2, 4} {
+		histogram.Observe(v)
+	}
+	gathered, err := registry.Gather()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	format := expfmt.NewFormat(expfmt.TypeProtoDelim)
+	encoder := expfmt.NewEncoder(&buf, format)
+	for _, f := range gathered {
+		require.NoError(t, encoder.Encode(f))
+	}
+
+	families, err := parseMetricFamilies(&buf, string(format))
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	h := families[0].Metric[0].GetHistogram()
+	assert.True(t, isNativeHistogram(h))
+	assert.Equal(t, uint64(5), h.GetSampleCount())
+}
@@ -0,0 +1,349 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+	"github.com/DataDog/datadog-agent/pkg/metrics/event"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/checkplugin"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// callTimeout bounds the duration of the calls made to the plugins, except Run
+const callTimeout = 10 * time.Second
+
+// Check is a check running in a plugin, out of the Agent process
+type Check struct {
+	corechecks.CheckBase
+	registry *registry
+	cfg      pluginConfig
+
+	// plugin is the plugin the check instance is configured in, replaced when its process exits
+	pluginLock sync.Mutex
+	plugin     *plugin
+
+	instance    integration.Data
+	initConfig  integration.Data
+	longRunning bool
+	haSupported bool
+}
+
+func newCheck(name string, registry *registry, cfg pluginConfig) *Check {
+	return &Check{
+		CheckBase: corechecks.NewCheckBase(name),
+		registry:  registry,
+		cfg:       cfg,
+	}
+}
+
+// Configure starts the plugin if it's not running, and configures the check instance in it
+func (c *Check) Configure(senderManager sender.SenderManager, integrationConfigDigest uint64, data integration.Data, initConfig integration.Data, source string) error {
+	c.BuildID(integrationConfigDigest, data, initConfig)
+	if err := c.CommonConfigure(senderManager, initConfig, data, source); err != nil {
+		return err
+	}
+	c.instance = data
+	c.initConfig = initConfig
+
+	s, err := c.GetSender()
+	if err != nil {
+		log.Errorf("failed to retrieve a sender for check %s: %s", string(c.ID()), err)
+		return err
+	}
+	s.FinalizeCheckServiceTag()
+
+	// the plugin must be released on every error from now on
+	p, err := c.registry.acquire(c.cfg)
+	if err != nil {
+		return err
+	}
+	if err := c.configurePlugin(p); err != nil {
+		c.registry.release(p)
+		return err
+	}
+	c.pluginLock.Lock()
+	c.plugin = p
+	c.pluginLock.Unlock()
+	return nil
+}
+
+// configurePlugin configures the check instance in the plugin
+func (c *Check) configurePlugin(p *plugin) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resp, err := p.client.Configure(ctx, &pb.ConfigureRequest{
+		CheckId:    string(c.ID()),
+		Name:       c.String(),
+		Instance:   c.instance,
+		InitConfig: c.initConfig,
+		Source:     c.ConfigSource(),
+	})
+	if err != nil {
+		return fmt.Errorf("the check plugin could not configure the instance: %s", status.Convert(err).Message())
+	}
+	if resp.Skip {
+		return check.ErrSkipCheckInstance
+	}
+	c.longRunning = resp.LongRunning
+	c.haSupported = resp.HaSupported
+	return nil
+}
+
+// getPlugin returns the plugin of the check instance. If the plugin process exited, it's started
+// again and the instance is configured in it.
+func (c *Check) getPlugin() (*plugin, error) {
+	c.pluginLock.Lock()
+	defer c.pluginLock.Unlock()
+
+	if c.plugin == nil {
+		return nil, errors.New("the check is not configured")
+	}
+	if !c.plugin.hasExited() {
+		return c.plugin, nil
+	}
+
+	log.Infof("Check plugin %s exited, starting it again for check %s", c.cfg, c.ID())
+	p, err := c.registry.acquire(c.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.configurePlugin(p); err != nil {
+		c.registry.release(p)
+		return nil, err
+	}
+	c.registry.release(c.plugin)
+	c.plugin = p
+	return p, nil
+}
+
+// Run runs the check instance in the plugin, and submits the data it sends
+func (c *Check) Run() error {
+	p, err := c.getPlugin()
+	if err != nil {
+		return err
+	}
+	s, err := c.GetSender()
+	if err != nil {
+		return err
+	}
+
+	err = c.run(p, s)
+	if status.Code(err) == codes.NotFound {
+		// plugins listening on a socket may have been restarted without the Agent knowing about it
+		log.Debugf("Check %s not found in plugin %s, configuring it again", c.ID(), c.cfg)
+		if err = c.configurePlugin(p); err == nil {
+			err = c.run(p, s)
+		}
+	}
+	s.Commit()
+	return err
+}
+
+func (c *Check) run(p *plugin, s sender.Sender) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := p.client.Run(ctx, &pb.RunRequest{CheckId: string(c.ID())})
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return errors.New("the check plugin ended the run without result")
+		}
+		if err != nil {
+			return err
+		}
+		if result := resp.GetResult(); result != nil {
+			for _, warning := range result.Warnings {
+				_ = c.Warn(warning)
+			}
+			if result.Error != "" {
+				return errors.New(result.Error)
+			}
+			return nil
+		}
+		submit(s, resp)
+	}
+}
+
+// submit forwards a call made by the plugin to the sender of the check
+func submit(s sender.Sender, resp *pb.RunResponse) {
+	switch payload := resp.Payload.(type) {
+	case *pb.RunResponse_Metric:
+		m := payload.Metric
+		switch m.Type {
+		case pb.MetricType_GAUGE:
+			if m.Timestamp > 0 {
+				_ = s.GaugeWithTimestamp(m.Name, m.Value, m.Hostname, m.Tags, m.Timestamp)
+			} else {
+				s.Gauge(m.Name, m.Value, m.Hostname, m.Tags)
+			}
+		case pb.MetricType_RATE:
+			s.Rate(m.Name, m.Value, m.Hostname, m.Tags)
+		case pb.MetricType_COUNT:
+			if m.Timestamp > 0 {
+				_ = s.CountWithTimestamp(m.Name, m.Value, m.Hostname, m.Tags, m.Timestamp)
+			} else {
+				s.Count(m.Name, m.Value, m.Hostname, m.Tags)
+			}
+		case pb.MetricType_MONOTONIC_COUNT:
+			s.MonotonicCountWithFlushFirstValue(m.Name, m.Value, m.Hostname, m.Tags, m.FlushFirstValue)
+		case pb.MetricType_COUNTER:
+			s.Counter(m.Name, m.Value, m.Hostname, m.Tags)
+		case pb.MetricType_HISTOGRAM:
+			s.Histogram(m.Name, m.Value, m.Hostname, m.Tags)
+		case pb.MetricType_HISTORATE:
+			s.Historate(m.Name, m.Value, m.Hostname, m.Tags)
+		case pb.MetricType_DISTRIBUTION:
+			s.Distribution(m.Name, m.Value, m.Hostname, m.Tags)
+		default:
+			log.Debugf("Ignoring metric %s of unknown type %d", m.Name, m.Type)
+		}
+	case *pb.RunResponse_ServiceCheck:
+		sc := payload.ServiceCheck
+		s.ServiceCheck(sc.Name, servicecheck.ServiceCheckStatus(sc.Status), sc.Hostname, sc.Tags, sc.Message)
+	case *pb.RunResponse_Event:
+		e := payload.Event
+		s.Event(event.Event{
+			Title:          e.Title,
+			Text:           e.Text,
+			Ts:             e.Timestamp,
+			Priority:       event.Priority(e.Priority),
+			Host:           e.Hostname,
+			Tags:           e.Tags,
+			AlertType:      event.AlertType(e.AlertType),
+			AggregationKey: e.AggregationKey,
+			SourceTypeName: e.SourceTypeName,
+			EventType:      e.EventType,
+		})
+	case *pb.RunResponse_HistogramBucket:
+		b := payload.HistogramBucket
+		s.HistogramBucket(b.Name, b.Value, b.LowerBound, b.UpperBound, b.Monotonic, b.Hostname, b.Tags, b.FlushFirstValue)
+	case *pb.RunResponse_EventPlatformEvent:
+		s.EventPlatformEvent(payload.EventPlatformEvent.RawEvent, payload.EventPlatformEvent.EventType)
+	case *pb.RunResponse_Commit:
+		s.Commit()
+	}
+}
+
+// Stop stops the check instance if it's running
+func (c *Check) Stop() {
+	c.pluginLock.Lock()
+	p := c.plugin
+	c.pluginLock.Unlock()
+	if p == nil || p.hasExited() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	if _, err := p.client.Stop(ctx, &pb.StopRequest{CheckId: string(c.ID())}); err != nil {
+		log.Warnf("Could not stop check %s in plugin %s: %s", c.ID(), c.cfg, err)
+	}
+}
+
+// CancelRun implements check.RunCanceler: the run is stopped, but the check instance stays configured
+// in the plugin for the next runs
+func (c *Check) CancelRun() {
+	c.Stop()
+}
+
+// Cancel releases the check instance in the plugin, which is stopped when no other instance uses it
+func (c *Check) Cancel() {
+	c.pluginLock.Lock()
+	p := c.plugin
+	c.plugin = nil
+	c.pluginLock.Unlock()
+	if p == nil {
+		return
+	}
+
+	if !p.hasExited() {
+		ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+		if _, err := p.client.Cancel(ctx, &pb.CancelRequest{CheckId: string(c.ID())}); err != nil {
+			log.Debugf("Could not cancel check %s in plugin %s: %s", c.ID(), c.cfg, err)
+		}
+		cancel()
+	}
+	c.registry.release(p)
+}
+
+// Interval returns 0 for long-running checks, which run until stopped
+func (c *Check) Interval() time.Duration {
+	if c.longRunning {
+		return 0
+	}
+	return c.CheckBase.Interval()
+}
+
+// Version returns the version of the plugin
+func (c *Check) Version() string {
+	c.pluginLock.Lock()
+	defer c.pluginLock.Unlock()
+	if c.plugin == nil {
+		return ""
+	}
+	return c.plugin.version
+}
+
+// Loader returns the name of the plugin loader
+func (c *Check) Loader() string {
+	return LoaderName
+}
+
+// GetDiagnoses returns the diagnoses of the check instance reported by the plugin
+func (c *Check) GetDiagnoses() ([]diagnosis.Diagnosis, error) {
+	p, err := c.getPlugin()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resp, err := p.client.Diagnose(ctx, &pb.DiagnoseRequest{CheckId: string(c.ID())})
+	if status.Code(err) == codes.Unimplemented {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	diagnoses := make([]diagnosis.Diagnosis, 0, len(resp.Diagnoses))
+	for _, d := range resp.Diagnoses {
+		diagnoses = append(diagnoses, diagnosis.Diagnosis{
+			Result:      diagnosis.Result(d.Status),
+			Name:        d.Name,
+			Diagnosis:   d.Diagnosis,
+			Category:    d.Category,
+			Description: d.Description,
+			Remediation: d.Remediation,
+			RawError:    d.RawError,
+		})
+	}
+	return diagnoses, nil
+}
+
+// IsHASupported returns whether the plugin reported the check as compatible with High Availability
+func (c *Check) IsHASupported() bool {
+	return c.haSupported
+}
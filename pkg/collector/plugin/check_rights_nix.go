@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// checkRights checks that the plugin binary at path is owned by root or by the user running
+// the Agent, and that no other user can modify it.
+func checkRights(path string) error {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return fmt.Errorf("invalid check plugin '%s': can't stat it: %s", path, err)
+	}
+
+	if stat.Uid != 0 && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("invalid check plugin '%s': it is owned by neither root nor the user running the Agent", path)
+	}
+	if stat.Mode&(syscall.S_IWGRP|syscall.S_IWOTH) != 0 {
+		return fmt.Errorf("invalid check plugin '%s': 'group' or 'others' have write permissions on it", path)
+	}
+
+	if err := syscall.Access(path, unix.X_OK); err != nil {
+		return fmt.Errorf("invalid check plugin '%s': can't execute it: %s", path, err)
+	}
+
+	return nil
+}
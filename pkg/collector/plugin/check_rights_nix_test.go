@@ -0,0 +1,33 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows && test
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRights(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugin")
+	require.NoError(t, os.WriteFile(path, nil, 0755))
+	assert.NoError(t, checkRights(path))
+
+	for _, mode := range []os.FileMode{0775, 0757} {
+		require.NoError(t, os.Chmod(path, mode))
+		assert.ErrorContains(t, checkRights(path), "write permissions", mode)
+	}
+
+	require.NoError(t, os.Chmod(path, 0644))
+	assert.ErrorContains(t, checkRights(path), "can't execute it")
+
+	assert.ErrorContains(t, checkRights(filepath.Join(t.TempDir(), "missing")), "can't stat it")
+}
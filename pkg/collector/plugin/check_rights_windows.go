@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build windows
+
+package plugin
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/DataDog/datadog-agent/pkg/util/winutil"
+)
+
+// writeRights are the access rights allowing to modify a file or its permissions
+const writeRights = windows.FILE_WRITE_DATA | windows.FILE_APPEND_DATA | windows.DELETE |
+	windows.WRITE_DAC | windows.WRITE_OWNER | windows.GENERIC_WRITE | windows.GENERIC_ALL
+
+// checkRights checks that the plugin binary at path is owned by Local System, the
+// Administrators or the user running the Agent, and that no other user can modify it.
+func checkRights(path string) error {
+	var owner *windows.SID
+	var dacl *winutil.ACL
+	var secDesc windows.Handle
+	err := winutil.GetNamedSecurityInfo(path,
+		windows.SE_FILE_OBJECT,
+		windows.OWNER_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION,
+		&owner,
+		nil,
+		&dacl,
+		nil,
+		&secDesc)
+	if err != nil {
+		return fmt.Errorf("invalid check plugin '%s': could not query its security information: %s", path, err)
+	}
+	defer windows.LocalFree(secDesc) //nolint:errcheck
+
+	trusted, err := trustedSIDs()
+	if err != nil {
+		return err
+	}
+	isTrusted := func(sid *windows.SID) bool {
+		for _, t := range trusted {
+			if windows.EqualSid(sid, t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !isTrusted(owner) {
+		return fmt.Errorf("invalid check plugin '%s': it is owned by neither LOCAL_SYSTEM, Administrators nor the user running the Agent", path)
+	}
+
+	var aclSizeInfo winutil.ACL_SIZE_INFORMATION
+	if err := winutil.GetAclInformation(dacl, &aclSizeInfo, winutil.AclSizeInformation); err != nil {
+		return fmt.Errorf("could not query ACLs for '%s': %s", path, err)
+	}
+	for i := uint32(0); i < aclSizeInfo.AceCount; i++ {
+		var pAce *winutil.ACCESS_ALLOWED_ACE
+		if err := winutil.GetAce(dacl, i, &pAce); err != nil {
+			return fmt.Errorf("could not query a ACE on '%s': %s", path, err)
+		}
+		sid := (*windows.SID)(unsafe.Pointer(&pAce.SidStart))
+		if pAce.AceType == winutil.ACCESS_ALLOWED_ACE_TYPE && pAce.AccessMask&writeRights != 0 && !isTrusted(sid) {
+			return fmt.Errorf("invalid check plugin '%s': other users/groups than LOCAL_SYSTEM, Administrators or the user running the Agent have write permissions on it", path)
+		}
+	}
+	return nil
+}
+
+// trustedSIDs returns the SIDs of Local System, the Administrators and the user running the Agent
+func trustedSIDs() ([]*windows.SID, error) {
+	localSystem, err := windows.CreateWellKnownSid(windows.WinLocalSystemSid)
+	if err != nil {
+		return nil, fmt.Errorf("could not query Local System SID: %s", err)
+	}
+	administrators, err := windows.CreateWellKnownSid(windows.WinBuiltinAdministratorsSid)
+	if err != nil {
+		return nil, fmt.Errorf("could not query Administrators SID: %s", err)
+	}
+	currentUser, err := winutil.GetSidFromUser()
+	if err != nil {
+		return nil, fmt.Errorf("could not get SID for current user: %s", err)
+	}
+	return []*windows.SID{localSystem, administrators, currentUser}, nil
+}
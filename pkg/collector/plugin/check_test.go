@@ -0,0 +1,275 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package plugin
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/providers/names"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+	"github.com/DataDog/datadog-agent/pkg/metrics/event"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/checkplugin"
+)
+
+// fakePlugin is a plugin reporting the same data on every run
+type fakePlugin struct {
+	pb.UnimplementedCheckPluginServer
+
+	m          sync.Mutex
+	configured map[string]string
+	stopped    []string
+	cancelled  []string
+}
+
+func newFakePlugin() *fakePlugin {
+	return &fakePlugin{configured: make(map[string]string)}
+}
+
+func (p *fakePlugin) Handshake(context.Context, *pb.HandshakeRequest) (*pb.HandshakeResponse, error) {
+	return &pb.HandshakeResponse{ProtocolVersion: ProtocolVersion, Version: "1.2.3"}, nil
+}
+
+func (p *fakePlugin) Configure(_ context.Context, req *pb.ConfigureRequest) (*pb.ConfigureResponse, error) {
+	switch string(req.Instance) {
+	case "skip: true":
+		return &pb.ConfigureResponse{Skip: true}, nil
+	case "invalid: true":
+		return nil, status.Error(codes.InvalidArgument, "invalid instance")
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.configured[req.CheckId] = req.Name
+	return &pb.ConfigureResponse{HaSupported: true}, nil
+}
+
+func (p *fakePlugin) Run(req *pb.RunRequest, stream pb.CheckPlugin_RunServer) error {
+	p.m.Lock()
+	_, ok := p.configured[req.CheckId]
+	p.m.Unlock()
+	if !ok {
+		return status.Error(codes.NotFound, "unknown check")
+	}
+
+	responses := []*pb.RunResponse{
+		{Payload: &pb.RunResponse_Metric{Metric: &pb.Metric{Type: pb.MetricType_GAUGE, Name: "fake.gauge", Value: 1, Tags: []string{"a:b"}}}},
+		{Payload: &pb.RunResponse_Metric{Metric: &pb.Metric{Type: pb.MetricType_MONOTONIC_COUNT, Name: "fake.count", Value: 2, Hostname: "host", FlushFirstValue: true}}},
+		{Payload: &pb.RunResponse_Metric{Metric: &pb.Metric{Type: pb.MetricType_GAUGE, Name: "fake.gauge.ts", Value: 3, Timestamp: 1700000000}}},
+		{Payload: &pb.RunResponse_HistogramBucket{HistogramBucket: &pb.HistogramBucket{Name: "fake.bucket", Value: 4, LowerBound: 0, UpperBound: 10, Monotonic: true}}},
+		{Payload: &pb.RunResponse_ServiceCheck{ServiceCheck: &pb.ServiceCheck{Name: "fake.can_connect", Status: pb.ServiceCheckStatus_CRITICAL, Message: "down"}}},
+		{Payload: &pb.RunResponse_Event{Event: &pb.Event{Title: "fake", Text: "event", Timestamp: 1700000000, AlertType: "info"}}},
+		{Payload: &pb.RunResponse_Result{Result: &pb.RunResult{Warnings: []string{"deprecated option"}}}},
+	}
+	for _, resp := range responses {
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *fakePlugin) Stop(_ context.Context, req *pb.StopRequest) (*pb.StopResponse, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.stopped = append(p.stopped, req.CheckId)
+	return &pb.StopResponse{}, nil
+}
+
+func (p *fakePlugin) Cancel(_ context.Context, req *pb.CancelRequest) (*pb.CancelResponse, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	delete(p.configured, req.CheckId)
+	p.cancelled = append(p.cancelled, req.CheckId)
+	return &pb.CancelResponse{}, nil
+}
+
+func (p *fakePlugin) Diagnose(context.Context, *pb.DiagnoseRequest) (*pb.DiagnoseResponse, error) {
+	return &pb.DiagnoseResponse{Diagnoses: []*pb.Diagnosis{{
+		Status:    pb.DiagnosisStatus_DIAGNOSIS_FAIL,
+		Name:      "connectivity",
+		Diagnosis: "cannot reach the service",
+	}}}, nil
+}
+
+// serveFakePlugin serves the fake plugin on a Unix socket until the test ends
+func serveFakePlugin(t *testing.T, p *fakePlugin) string {
+	// socket paths are limited to ~100 characters, which t.TempDir() may exceed
+	dir, err := os.MkdirTemp("", "plugin-test-")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "plugin.sock")
+
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	pb.RegisterCheckPluginServer(server, p)
+	go server.Serve(listener) //nolint:errcheck
+	t.Cleanup(server.Stop)
+	return socket
+}
+
+// TestMain runs the test binary as a plugin when it's started by the loader
+func TestMain(m *testing.M) {
+	socket := os.Getenv(socketEnvVar)
+	if socket == "" {
+		os.Exit(m.Run())
+	}
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		os.Exit(1)
+	}
+	server := grpc.NewServer()
+	pb.RegisterCheckPluginServer(server, newFakePlugin())
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt)
+		<-signals
+		server.Stop()
+	}()
+	server.Serve(listener) //nolint:errcheck
+	os.Exit(0)
+}
+
+func loadCheck(t *testing.T, loader *CheckLoader, initConfig, instance string) (*Check, *mocksender.MockSender, error) {
+	senderManager := mocksender.CreateDefaultDemultiplexer()
+	config := integration.Config{
+		Name:       "fake",
+		InitConfig: integration.Data(initConfig),
+		Instances:  []integration.Data{integration.Data(instance)},
+		Provider:   names.File,
+	}
+	c, err := loader.Load(senderManager, config, config.Instances[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	sender := mocksender.NewMockSenderWithSenderManager(c.ID(), senderManager)
+	sender.SetupAcceptAll()
+	return c.(*Check), sender, nil
+}
+
+func TestRun(t *testing.T) {
+	fake := newFakePlugin()
+	socket := serveFakePlugin(t, fake)
+	loader := &CheckLoader{registry: newRegistry(5 * time.Second)}
+
+	c, sender, err := loadCheck(t, loader, "plugin_socket: "+socket, "host: localhost")
+	require.NoError(t, err)
+	assert.Equal(t, "fake", c.String())
+	assert.Equal(t, LoaderName, c.Loader())
+	assert.Equal(t, "1.2.3", c.Version())
+	assert.True(t, c.IsHASupported())
+
+	require.NoError(t, c.Run())
+	sender.AssertMetric(t, "Gauge", "fake.gauge", 1, "", []string{"a:b"})
+	sender.AssertMonotonicCount(t, "MonotonicCountWithFlushFirstValue", "fake.count", 2, "host", nil, true)
+	sender.AssertMetricWithTimestamp(t, "GaugeWithTimestamp", "fake.gauge.ts", 3, "", nil, 1700000000)
+	sender.AssertHistogramBucket(t, "HistogramBucket", "fake.bucket", 4, 0, 10, true, "", nil, false)
+	sender.AssertServiceCheck(t, "fake.can_connect", servicecheck.ServiceCheckCritical, "", nil, "down")
+	sender.AssertEvent(t, event.Event{Title: "fake", Text: "event", Ts: 1700000000, AlertType: event.AlertTypeInfo}, 0)
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+
+	warnings := c.GetWarnings()
+	require.Len(t, warnings, 1)
+	assert.EqualError(t, warnings[0], "deprecated option")
+
+	diagnoses, err := c.GetDiagnoses()
+	require.NoError(t, err)
+	assert.Equal(t, []diagnosis.Diagnosis{{
+		Result:    diagnosis.DiagnosisFail,
+		Name:      "connectivity",
+		Diagnosis: "cannot reach the service",
+	}}, diagnoses)
+
+	c.Stop()
+	c.Cancel()
+	assert.Equal(t, []string{string(c.ID())}, fake.stopped)
+	assert.Equal(t, []string{string(c.ID())}, fake.cancelled)
+	assert.Empty(t, loader.registry.plugins)
+}
+
+func TestRunReconfigures(t *testing.T) {
+	fake := newFakePlugin()
+	socket := serveFakePlugin(t, fake)
+	loader := &CheckLoader{registry: newRegistry(5 * time.Second)}
+
+	c, sender, err := loadCheck(t, loader, "", "plugin_socket: "+socket)
+	require.NoError(t, err)
+
+	// the plugin restarted and lost the configuration of the instance
+	fake.m.Lock()
+	fake.configured = make(map[string]string)
+	fake.m.Unlock()
+
+	require.NoError(t, c.Run())
+	sender.AssertMetric(t, "Gauge", "fake.gauge", 1, "", []string{"a:b"})
+	c.Cancel()
+}
+
+func TestConfigureErrors(t *testing.T) {
+	socket := serveFakePlugin(t, newFakePlugin())
+	loader := &CheckLoader{registry: newRegistry(5 * time.Second)}
+
+	_, _, err := loadCheck(t, loader, "plugin_socket: "+socket, "skip: true")
+	assert.ErrorIs(t, err, check.ErrSkipCheckInstance)
+
+	_, _, err = loadCheck(t, loader, "plugin_socket: "+socket, "invalid: true")
+	assert.ErrorContains(t, err, "invalid instance")
+
+	// the plugin is not used by any instance
+	assert.Empty(t, loader.registry.plugins)
+}
+
+func TestPluginProcess(t *testing.T) {
+	executable, err := os.Executable()
+	require.NoError(t, err)
+	loader := &CheckLoader{
+		pluginsDir: filepath.Dir(executable),
+		registry:   newRegistry(10 * time.Second),
+	}
+	initConfig := "plugin_path: " + filepath.Base(executable)
+
+	c1, sender, err := loadCheck(t, loader, initConfig, "host: a")
+	require.NoError(t, err)
+	c2, _, err := loadCheck(t, loader, initConfig, "host: b")
+	require.NoError(t, err)
+
+	// both instances run in the same process
+	require.Len(t, loader.registry.plugins, 1)
+	p := c1.plugin
+	assert.Same(t, p, c2.plugin)
+	assert.Equal(t, 2, p.refs)
+
+	// the process is started again when it exits
+	require.NoError(t, p.cmd.Process.Kill())
+	<-p.exited
+	require.NoError(t, c1.Run())
+	sender.AssertMetric(t, "Gauge", "fake.gauge", 1, "", []string{"a:b"})
+	assert.NotSame(t, p, c1.plugin)
+
+	c1.Cancel()
+	c2.Cancel()
+	assert.Empty(t, loader.registry.plugins)
+	assert.Nil(t, c2.plugin)
+}
@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package plugin implements the loader of the checks running in plugins: binaries started by the
+// Agent, or processes listening on a Unix socket, implementing the gRPC check plugin protocol.
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/providers/names"
+	tagger "github.com/DataDog/datadog-agent/comp/core/tagger/def"
+	integrations "github.com/DataDog/datadog-agent/comp/logs/integrations/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/loaders"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/option"
+)
+
+// LoaderName is the name of the plugin loader
+const LoaderName string = "plugin"
+
+// CheckLoader is a specific loader for checks running in plugins
+type CheckLoader struct {
+	pluginsDir string
+	registry   *registry
+}
+
+// NewCheckLoader creates a loader for the checks running in plugins
+func NewCheckLoader() (*CheckLoader, error) {
+	return &CheckLoader{
+		pluginsDir: pkgconfigsetup.Datadog().GetString("check_plugins_dir"),
+		registry:   newRegistry(pkgconfigsetup.Datadog().GetDuration("check_plugins_start_timeout")),
+	}, nil
+}
+
+// Name returns the plugin loader name
+func (*CheckLoader) Name() string {
+	return LoaderName
+}
+
+// Load returns a check running in the plugin named after the check in the plugins directory, or
+// set with the plugin_path or plugin_socket options of the check configuration.
+func (pl *CheckLoader) Load(senderManager sender.SenderManager, config integration.Config, instance integration.Data) (check.Check, error) {
+	cfg, err := pl.pluginConfig(config.Name, config.Provider, config.InitConfig, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCheck(config.Name, pl.registry, cfg)
+	if err := c.Configure(senderManager, config.FastDigest(), instance, config.InitConfig, config.Source); err != nil {
+		if errors.Is(err, check.ErrSkipCheckInstance) {
+			return c, err
+		}
+		log.Errorf("plugin.loader: could not configure check %s: %s", c, err)
+		return c, fmt.Errorf("Could not configure check %s: %s", c, err)
+	}
+	return c, nil
+}
+
+// pluginConfig returns the plugin settings of the check, set in its init_config section and
+// overridden by its instance. The plugin binaries are only looked up in the plugins directory,
+// and can only be given arguments or sockets by the configuration files: the configurations
+// coming from other providers, like container labels, must not be able to run arbitrary
+// commands or to connect to arbitrary sockets.
+func (pl *CheckLoader) pluginConfig(name, provider string, initConfig, instance integration.Data) (pluginConfig, error) {
+	var cfg, instanceCfg pluginConfig
+	if err := yaml.Unmarshal(initConfig, &cfg); err != nil {
+		return cfg, err
+	}
+	if err := yaml.Unmarshal(instance, &instanceCfg); err != nil {
+		return cfg, err
+	}
+	if instanceCfg.Path != "" || instanceCfg.Socket != "" {
+		cfg = instanceCfg
+	}
+
+	if cfg.Socket != "" {
+		if provider != names.File {
+			return cfg, fmt.Errorf("plugin_socket can only be set in configuration files, not by the %s provider", provider)
+		}
+		return cfg, nil
+	}
+	if len(cfg.Args) > 0 && provider != names.File {
+		return cfg, fmt.Errorf("plugin_args can only be set in configuration files, not by the %s provider", provider)
+	}
+	if cfg.Path == "" {
+		cfg.Path = name
+		if runtime.GOOS == "windows" {
+			cfg.Path += ".exe"
+		}
+	}
+	if !filepath.IsLocal(cfg.Path) {
+		return cfg, fmt.Errorf("invalid check plugin %s: plugin_path must be relative to check_plugins_dir", cfg.Path)
+	}
+	if pl.pluginsDir == "" {
+		return cfg, fmt.Errorf("check_plugins_dir is not set, cannot locate check plugin %s", cfg.Path)
+	}
+	cfg.Path = filepath.Join(pl.pluginsDir, cfg.Path)
+	info, err := os.Stat(cfg.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, fmt.Errorf("check plugin %s not found", cfg.Path)
+		}
+		return cfg, err
+	}
+	if info.IsDir() {
+		return cfg, fmt.Errorf("check plugin %s is a directory", cfg.Path)
+	}
+	if err := checkRights(cfg.Path); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func (pl *CheckLoader) String() string {
+	return "Plugin Check Loader"
+}
+
+func init() {
+	factory := func(sender.SenderManager, option.Option[integrations.Component], tagger.Component) (check.Loader, error) {
+		return NewCheckLoader()
+	}
+
+	loaders.RegisterLoader(40, factory)
+}
@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/providers/names"
+)
+
+func TestPluginConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fake"), nil, 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "directory"), 0755))
+	loader := &CheckLoader{pluginsDir: dir}
+
+	cfg, err := loader.pluginConfig("fake", names.File, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, pluginConfig{Path: filepath.Join(dir, "fake")}, cfg)
+
+	cfg, err = loader.pluginConfig("other", names.File, integration.Data("plugin_path: fake\nplugin_args: [--verbose]"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, pluginConfig{Path: filepath.Join(dir, "fake"), Args: []string{"--verbose"}}, cfg)
+
+	cfg, err = loader.pluginConfig("other", names.File, integration.Data("plugin_path: fake"), integration.Data("plugin_socket: /run/plugin.sock"))
+	require.NoError(t, err)
+	assert.Equal(t, pluginConfig{Socket: "/run/plugin.sock"}, cfg)
+
+	_, err = loader.pluginConfig("other", names.File, nil, nil)
+	assert.ErrorContains(t, err, "not found")
+
+	_, err = loader.pluginConfig("directory", names.File, nil, nil)
+	assert.ErrorContains(t, err, "is a directory")
+}
+
+func TestPluginConfigRestrictions(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fake"), nil, 0755))
+	loader := &CheckLoader{pluginsDir: dir}
+
+	// the configurations of the other providers can select a plugin, but not its arguments
+	cfg, err := loader.pluginConfig("other", names.Container, nil, integration.Data("plugin_path: fake"))
+	require.NoError(t, err)
+	assert.Equal(t, pluginConfig{Path: filepath.Join(dir, "fake")}, cfg)
+	_, err = loader.pluginConfig("other", names.Container, nil, integration.Data("plugin_path: fake\nplugin_args: [-c, id]"))
+	assert.ErrorContains(t, err, "plugin_args can only be set in configuration files")
+	_, err = loader.pluginConfig("other", names.Kubernetes, integration.Data("plugin_path: fake\nplugin_args: [-c, id]"), nil)
+	assert.ErrorContains(t, err, "plugin_args can only be set in configuration files")
+
+	// nor connect to a socket
+	_, err = loader.pluginConfig("other", names.Container, nil, integration.Data("plugin_socket: /run/plugin.sock"))
+	assert.ErrorContains(t, err, "plugin_socket can only be set in configuration files")
+	_, err = loader.pluginConfig("other", names.Kubernetes, integration.Data("plugin_socket: /run/plugin.sock"), nil)
+	assert.ErrorContains(t, err, "plugin_socket can only be set in configuration files")
+
+	// the binaries are only looked up in the plugins directory
+	for _, path := range []string{"/bin/sh", "../fake", "sub/../../fake"} {
+		_, err = loader.pluginConfig("other", names.File, integration.Data("plugin_path: "+path), nil)
+		assert.ErrorContains(t, err, "plugin_path must be relative to check_plugins_dir", path)
+	}
+	_, err = (&CheckLoader{}).pluginConfig("fake", names.File, nil, nil)
+	assert.ErrorContains(t, err, "check_plugins_dir is not set")
+}
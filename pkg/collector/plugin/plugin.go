@@ -0,0 +1,275 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/checkplugin"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// ProtocolVersion is the version of the check plugin protocol implemented by the Agent
+	ProtocolVersion = 1
+
+	// Environment variables set for the plugin processes started by the Agent
+	socketEnvVar          = "DD_CHECK_PLUGIN_SOCKET"
+	protocolVersionEnvVar = "DD_CHECK_PLUGIN_PROTOCOL_VERSION"
+
+	// stopGracePeriod is the time given to a plugin process to exit before it is killed
+	stopGracePeriod = 5 * time.Second
+
+	// maxLogLineLength is the length from which the output of a plugin process is logged even
+	// if the line isn't complete, so that a process never writing new lines can't fill the memory
+	maxLogLineLength = 8 * 1024
+)
+
+// pluginConfig holds the settings of the check configuration locating its plugin
+type pluginConfig struct {
+	// Path of the plugin binary, defaults to the check name in the check_plugins_dir directory
+	Path string `yaml:"plugin_path"`
+	// Args are the arguments the plugin binary is started with
+	Args []string `yaml:"plugin_args"`
+	// Socket is the Unix socket of a plugin started outside of the Agent. The Agent then
+	// doesn't start any binary.
+	Socket string `yaml:"plugin_socket"`
+}
+
+// key identifies the plugins shared by the check instances
+func (c pluginConfig) key() string {
+	if c.Socket != "" {
+		return "unix://" + c.Socket
+	}
+	return strings.Join(append([]string{c.Path}, c.Args...), "\x00")
+}
+
+func (c pluginConfig) String() string {
+	if c.Socket != "" {
+		return "unix://" + c.Socket
+	}
+	return c.Path
+}
+
+// plugin is a connection to a plugin, and the process it runs in when started by the Agent
+type plugin struct {
+	cfg     pluginConfig
+	conn    *grpc.ClientConn
+	client  pb.CheckPluginClient
+	version string
+
+	cmd    *exec.Cmd
+	tmpDir string
+	// exited is closed once the plugin process exits
+	exited chan struct{}
+	// refs is the number of check instances using the plugin, protected by the lock of the registry
+	refs int
+}
+
+// hasExited returns whether the process of the plugin exited, in which case it must be started again
+func (p *plugin) hasExited() bool {
+	select {
+	case <-p.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// close closes the connection to the plugin and stops its process
+func (p *plugin) close() {
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	if p.cmd != nil && !p.hasExited() {
+		// interrupts are not supported on Windows, where the process is killed right away
+		if runtime.GOOS == "windows" || p.cmd.Process.Signal(os.Interrupt) != nil {
+			_ = p.cmd.Process.Kill()
+		}
+		select {
+		case <-p.exited:
+		case <-time.After(stopGracePeriod):
+			log.Warnf("Check plugin %s did not exit after %s, killing it", p.cfg, stopGracePeriod)
+			_ = p.cmd.Process.Kill()
+			<-p.exited
+		}
+	}
+	if p.tmpDir != "" {
+		os.RemoveAll(p.tmpDir)
+	}
+}
+
+// registry keeps track of the plugins, shared by the check instances using the same plugin
+type registry struct {
+	m            sync.Mutex
+	plugins      map[string]*plugin
+	startTimeout time.Duration
+}
+
+func newRegistry(startTimeout time.Duration) *registry {
+	return &registry{
+		plugins:      make(map[string]*plugin),
+		startTimeout: startTimeout,
+	}
+}
+
+// acquire returns the plugin for the given configuration, starting it if it's not running.
+// The plugin must be released once it's not used anymore.
+func (r *registry) acquire(cfg pluginConfig) (*plugin, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	key := cfg.key()
+	if p, ok := r.plugins[key]; ok {
+		if !p.hasExited() {
+			p.refs++
+			return p, nil
+		}
+		// the check instances still holding the previous process will acquire the new one
+		delete(r.plugins, key)
+		p.close()
+	}
+
+	p, err := r.start(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p.refs++
+	r.plugins[key] = p
+	return p, nil
+}
+
+// release releases a plugin acquired by a check instance, stopping it when it's not used anymore
+func (r *registry) release(p *plugin) {
+	r.m.Lock()
+	p.refs--
+	refs := p.refs
+	if refs == 0 && r.plugins[p.cfg.key()] == p {
+		delete(r.plugins, p.cfg.key())
+	}
+	r.m.Unlock()
+
+	if refs == 0 {
+		p.close()
+	}
+}
+
+// start starts the plugin, or connects to it when it listens on a socket, and completes the handshake
+func (r *registry) start(cfg pluginConfig) (*plugin, error) {
+	p := &plugin{
+		cfg:    cfg,
+		exited: make(chan struct{}),
+	}
+
+	socket := cfg.Socket
+	if socket == "" {
+		var err error
+		if p.tmpDir, err = os.MkdirTemp("", "dd-check-plugin-"); err != nil {
+			return nil, err
+		}
+		socket = filepath.Join(p.tmpDir, "plugin.sock")
+
+		name := filepath.Base(cfg.Path)
+		p.cmd = exec.Command(cfg.Path, cfg.Args...)
+		p.cmd.Env = append(os.Environ(),
+			socketEnvVar+"="+socket,
+			protocolVersionEnvVar+"="+strconv.Itoa(ProtocolVersion),
+		)
+		p.cmd.Stdout = &logWriter{name: name, logFunc: log.Infof}
+		p.cmd.Stderr = &logWriter{name: name, logFunc: func(format string, params ...interface{}) {
+			_ = log.Warnf(format, params...)
+		}}
+		if err := p.cmd.Start(); err != nil {
+			os.RemoveAll(p.tmpDir)
+			return nil, fmt.Errorf("could not start check plugin %s: %w", cfg, err)
+		}
+		log.Infof("Started check plugin %s with PID %d", cfg, p.cmd.Process.Pid)
+		go func() {
+			err := p.cmd.Wait()
+			if err != nil {
+				log.Warnf("Check plugin %s exited: %s", cfg, err)
+			} else {
+				log.Infof("Check plugin %s exited", cfg)
+			}
+			close(p.exited)
+		}()
+	}
+
+	conn, err := grpc.NewClient("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		p.close()
+		return nil, err
+	}
+	p.conn = conn
+	p.client = pb.NewCheckPluginClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.startTimeout)
+	defer cancel()
+	go func() {
+		// don't wait for the timeout when the process exits early
+		select {
+		case <-p.exited:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	resp, err := p.client.Handshake(ctx, &pb.HandshakeRequest{ProtocolVersion: ProtocolVersion}, grpc.WaitForReady(true))
+	if err != nil {
+		exited := p.hasExited()
+		p.close()
+		if exited {
+			return nil, fmt.Errorf("check plugin %s exited before completing the handshake", cfg)
+		}
+		return nil, fmt.Errorf("could not complete the handshake with check plugin %s: %w", cfg, err)
+	}
+	if resp.ProtocolVersion != ProtocolVersion {
+		p.close()
+		return nil, fmt.Errorf("check plugin %s implements version %d of the protocol, expected version %d", cfg, resp.ProtocolVersion, ProtocolVersion)
+	}
+	p.version = resp.Version
+	return p, nil
+}
+
+// logWriter forwards the lines written by a plugin process to the Agent logs
+type logWriter struct {
+	name    string
+	logFunc func(format string, params ...interface{})
+	buf     []byte
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		next := i + 1
+		if i < 0 || i > maxLogLineLength {
+			if len(w.buf) < maxLogLineLength {
+				break
+			}
+			// the line is too long, its beginning is logged on its own
+			i, next = maxLogLineLength, maxLogLineLength
+		}
+		if line := strings.TrimRight(string(w.buf[:i]), "\r"); line != "" {
+			w.logFunc("check plugin %s: %s", w.name, line)
+		}
+		w.buf = w.buf[next:]
+	}
+	return len(b), nil
+}
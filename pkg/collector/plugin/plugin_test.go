@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogWriter(t *testing.T) {
+	var lines []string
+	w := &logWriter{name: "fake", logFunc: func(format string, params ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, params...))
+	}}
+
+	_, _ = w.Write([]byte("first\r\nsec"))
+	_, _ = w.Write([]byte("ond\n\npartial"))
+	assert.Equal(t, []string{"check plugin fake: first", "check plugin fake: second"}, lines)
+
+	// the lines longer than the limit are logged in chunks, without waiting for their end
+	lines = nil
+	w.buf = nil
+	_, _ = w.Write([]byte(strings.Repeat("a", maxLogLineLength)))
+	_, _ = w.Write([]byte(strings.Repeat("b", maxLogLineLength+10)))
+	assert.Equal(t, []string{
+		"check plugin fake: " + strings.Repeat("a", maxLogLineLength),
+		"check plugin fake: " + strings.Repeat("b", maxLogLineLength),
+	}, lines)
+	assert.Len(t, w.buf, 10)
+	_, _ = w.Write([]byte("\n"))
+	assert.Equal(t, "check plugin fake: "+strings.Repeat("b", 10), lines[2])
+	assert.Empty(t, w.buf)
+}
@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// cronJob schedules a check at the times of a cron expression, instead of at a fixed interval.
+// Every job runs in its own goroutine.
+type cronJob struct {
+	check    check.Check
+	spec     string
+	schedule cron.Schedule
+	// jitter is the maximum random delay added to each time of the schedule, to avoid running the
+	// same check at the same time across many hosts
+	jitter  time.Duration
+	stop    chan bool // closed to stop this job, created again every time it starts
+	stopped chan bool // signals that this job has stopped
+	running bool
+
+	mu      sync.RWMutex // to protect nextRun
+	nextRun time.Time
+}
+
+// checkSchedule returns the cron job of a check when its instance sets a schedule, or nil
+func checkSchedule(c check.Check) (*cronJob, error) {
+	var commonOptions integration.CommonInstanceConfig
+	if err := yaml.Unmarshal([]byte(c.InstanceConfig()), &commonOptions); err != nil || commonOptions.Schedule == "" {
+		// invalid instances are reported when the check is configured
+		return nil, nil
+	}
+	if commonOptions.ScheduleJitter < 0 {
+		return nil, fmt.Errorf("schedule_jitter must be a positive number of seconds")
+	}
+	// ParseStandard supports the 5 fields of the cron expressions, descriptors such as '@daily',
+	// and a time zone prefix such as 'CRON_TZ=Europe/Paris'
+	schedule, err := cron.ParseStandard(commonOptions.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule '%s': %s", commonOptions.Schedule, err)
+	}
+	return &cronJob{
+		check:    c,
+		spec:     commonOptions.Schedule,
+		schedule: schedule,
+		jitter:   time.Duration(commonOptions.ScheduleJitter) * time.Second,
+		// buffered, as cancelled jobs are not waited for
+		stopped: make(chan bool, 1),
+	}, nil
+}
+
+// next returns the time of the next run after t, including the random jitter
+func (j *cronJob) next(t time.Time) time.Time {
+	next := j.schedule.Next(t)
+	if j.jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(j.jitter))))
+	}
+	return next
+}
+
+// getNextRun returns the time of the next run, zero when the job is not running
+func (j *cronJob) getNextRun() time.Time {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.nextRun
+}
+
+func (j *cronJob) setNextRun(t time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.nextRun = t
+}
+
+// run posts the check to the execution pipeline at the times of the schedule, until stop is closed.
+// Not blocking, runs in a new goroutine.
+func (j *cronJob) run(s *Scheduler, stop <-chan bool) {
+	go func() {
+		log.Debugf("Cron job of check %s is running...", j.check.ID())
+		for j.process(s, stop) {
+			// empty
+		}
+		j.setNextRun(time.Time{})
+		j.stopped <- true
+	}()
+}
+
+// process waits for the next time of the schedule and enqueues the check, and returns whether
+// the job should wait for the following time (or stop)
+func (j *cronJob) process(s *Scheduler, stop <-chan bool) bool {
+	next := j.next(time.Now())
+	if next.IsZero() {
+		// the schedule never matches, such as on February 30th
+		log.Warnf("The schedule '%s' of check %s never runs", j.spec, j.check.ID())
+		<-stop
+		return false
+	}
+	j.setNextRun(next)
+
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	select {
+	case <-stop:
+		return false
+	case <-timer.C:
+	}
+
+	if !s.IsCheckScheduled(j.check.ID()) {
+		return true
+	}
+	select {
+	// blocking, we'll be here as long as it takes
+	case s.checksPipe <- j.check:
+	case <-stop:
+		return false
+	}
+	return true
+}
+
+func (j *cronJob) stats() map[string]interface{} {
+	stats := map[string]interface{}{
+		"Schedule": j.spec,
+		"Jitter":   j.jitter / time.Second,
+	}
+	if nextRun := j.getNextRun(); !nextRun.IsZero() {
+		stats["NextRun"] = nextRun.Unix()
+	}
+	return stats
+}
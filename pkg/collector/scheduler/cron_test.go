@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
+)
+
+// FIXTURE
+type cronTestCheck struct {
+	TestCheck
+	instance string
+}
+
+func (c *cronTestCheck) InstanceConfig() string { return c.instance }
+
+// everySchedule is a schedule matching at a fixed interval, shorter than the cron expressions allow
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time { return t.Add(time.Duration(e)) }
+
+func TestCheckSchedule(t *testing.T) {
+	job, err := checkSchedule(&cronTestCheck{instance: "host: localhost"})
+	assert.NoError(t, err)
+	assert.Nil(t, job)
+
+	job, err = checkSchedule(&cronTestCheck{instance: "schedule: '0 2 * * *'\nschedule_jitter: 60"})
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, "0 2 * * *", job.spec)
+	assert.Equal(t, time.Minute, job.jitter)
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	for i := 0; i < 10; i++ {
+		next := job.next(now)
+		assert.False(t, next.Before(time.Date(2024, 3, 2, 2, 0, 0, 0, time.Local)))
+		assert.True(t, next.Before(time.Date(2024, 3, 2, 2, 1, 0, 0, time.Local)))
+	}
+
+	job, err = checkSchedule(&cronTestCheck{instance: "schedule: '@hourly'"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 13, 0, 0, 0, time.Local), job.next(now))
+
+	job, err = checkSchedule(&cronTestCheck{instance: "schedule: 'CRON_TZ=UTC 30 * * * *'"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), job.next(now.UTC()))
+
+	_, err = checkSchedule(&cronTestCheck{instance: "schedule: 'every day'"})
+	assert.ErrorContains(t, err, "invalid schedule")
+
+	_, err = checkSchedule(&cronTestCheck{instance: "schedule: '@daily'\nschedule_jitter: -1"})
+	assert.Error(t, err)
+}
+
+func TestEnterCron(t *testing.T) {
+	s := getScheduler()
+	defer s.Stop()
+
+	// invalid schedules are rejected
+	err := s.Enter(&cronTestCheck{TestCheck: TestCheck{intl: time.Second}, instance: "schedule: '61 * * * *'"})
+	assert.Error(t, err)
+	assert.Empty(t, s.cronJobs)
+
+	c := &cronTestCheck{TestCheck: TestCheck{intl: 15 * time.Second}, instance: "schedule: '* * * * *'"}
+	require.NoError(t, s.Enter(c))
+	assert.Empty(t, s.jobQueues)
+	require.Contains(t, s.cronJobs, c.ID())
+	assert.True(t, s.IsCheckScheduled(c.ID()))
+
+	job := s.cronJobs[c.ID()]
+	assert.Eventually(t, func() bool {
+		return !job.getNextRun().IsZero()
+	}, time.Second, 10*time.Millisecond)
+	assert.WithinDuration(t, time.Now(), job.getNextRun(), time.Minute)
+
+	stats := expCronJobs(s)().(map[checkid.ID]map[string]interface{})
+	assert.Equal(t, "* * * * *", stats[c.ID()]["Schedule"])
+	assert.Equal(t, job.getNextRun().Unix(), stats[c.ID()]["NextRun"])
+
+	require.NoError(t, s.Cancel(c.ID()))
+	assert.Empty(t, s.cronJobs)
+	assert.False(t, s.IsCheckScheduled(c.ID()))
+	select {
+	case <-job.stopped:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the cron job did not stop")
+	}
+}
+
+func TestCronJobRun(t *testing.T) {
+	ch := make(chan check.Check)
+	s := NewScheduler(ch)
+
+	c := &cronTestCheck{TestCheck: TestCheck{intl: 15 * time.Second}, instance: "schedule: '@daily'"}
+	require.NoError(t, s.Enter(c))
+	s.Run()
+
+	// replace the job by one running every few milliseconds
+	s.mu.Lock()
+	s.stopCronJob(s.cronJobs[c.ID()], true)
+	job := s.cronJobs[c.ID()]
+	job.schedule = everySchedule(5 * time.Millisecond)
+	s.startCronJob(job)
+	s.mu.Unlock()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case scheduled := <-ch:
+			assert.Equal(t, c.ID(), scheduled.ID())
+		case <-time.After(time.Second):
+			require.Fail(t, "the check was not scheduled")
+		}
+	}
+
+	// the job runs again when the queues are restarted
+	s.stopQueues()
+	assert.False(t, job.running)
+	s.startQueues()
+	select {
+	case scheduled := <-ch:
+		assert.Equal(t, c.ID(), scheduled.ID())
+	case <-time.After(time.Second):
+		require.Fail(t, "the check was not scheduled after the restart")
+	}
+
+	require.NoError(t, s.Stop())
+	assert.False(t, job.running)
+	assert.True(t, job.getNextRun().IsZero())
+}
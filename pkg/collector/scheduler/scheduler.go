@@ -51,10 +51,11 @@ type Scheduler struct {
 	mu               sync.Mutex                  // To protect critical sections in struct's fields
 
 	checkToQueue map[checkid.ID]*jobQueue // Keep track of what is the queue for any Check
+	cronJobs     map[checkid.ID]*cronJob  // Keep track of the checks scheduled with a cron expression
 	// To protect checkToQueue. Using mu would create a deadlock when stopping the Scheduler. 'jobQueue' is calling
 	// 'IsCheckScheduled' right when then 'Stop' function is called and mu is already lock. for this reason we have
 	// to lock: one for the Scheduler and a dedicated one for the 'IsCheckScheduled' method. This way 'jobQueue' and
-	// metadata provider can call 'IsCheckScheduled' without creating a deadlock.
+	// metadata provider can call 'IsCheckScheduled' without creating a deadlock. It also protects cronJobs.
 	checkToQueueMutex sync.RWMutex
 
 	cancelOneTime chan bool      // Used to internally communicate a cancel signal to one-time schedule goroutines
@@ -70,6 +71,7 @@ func NewScheduler(checksPipe chan<- check.Check) *Scheduler {
 		started:          make(chan bool),
 		jobQueues:        make(map[time.Duration]*jobQueue),
 		checkToQueue:     make(map[checkid.ID]*jobQueue),
+		cronJobs:         make(map[checkid.ID]*cronJob),
 		tlmTrackedChecks: make(map[checkid.ID]string),
 		running:          atomic.NewBool(false),
 		cancelOneTime:    make(chan bool),
@@ -77,7 +79,8 @@ func NewScheduler(checksPipe chan<- check.Check) *Scheduler {
 	}
 }
 
-// Enter schedules a `Check`s for execution accordingly to the `Check.Interval()` value.
+// Enter schedules a `Check`s for execution accordingly to the `Check.Interval()` value,
+// or to the cron expression set with the `schedule` option of its instance.
 // If the interval is 0, the check is supposed to run only once.
 func (s *Scheduler) Enter(check check.Check) error {
 	// enqueue immediately if this is a one-time schedule
@@ -86,30 +89,43 @@ func (s *Scheduler) Enter(check check.Check) error {
 		return nil
 	}
 
-	if check.Interval() < minAllowedInterval {
+	job, err := checkSchedule(check)
+	if err != nil {
+		return err
+	}
+	if job == nil && check.Interval() < minAllowedInterval {
 		return fmt.Errorf("schedule interval must be greater than %v or 0", minAllowedInterval)
 	}
 
-	log.Infof("Scheduling check %s with an interval of %v", check.ID(), check.Interval())
-
-	// sync when accessing `jobQueues` and `check2queue`
+	// sync when accessing `jobQueues`, `check2queue` and `cronJobs`
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.jobQueues[check.Interval()]; !ok {
-		s.jobQueues[check.Interval()] = newJobQueue(check.Interval())
-		s.startQueue(s.jobQueues[check.Interval()])
-		if check.IsTelemetryEnabled() {
-			tlmQueuesCount.Inc()
+	if job != nil {
+		log.Infof("Scheduling check %s with the schedule '%s' and a jitter of %v", check.ID(), job.spec, job.jitter)
+		s.startCronJob(job)
+
+		s.checkToQueueMutex.Lock()
+		s.cronJobs[check.ID()] = job
+		s.checkToQueueMutex.Unlock()
+	} else {
+		log.Infof("Scheduling check %s with an interval of %v", check.ID(), check.Interval())
+
+		if _, ok := s.jobQueues[check.Interval()]; !ok {
+			s.jobQueues[check.Interval()] = newJobQueue(check.Interval())
+			s.startQueue(s.jobQueues[check.Interval()])
+			if check.IsTelemetryEnabled() {
+				tlmQueuesCount.Inc()
+			}
+			schedulerQueuesCount.Add(1)
 		}
-		schedulerQueuesCount.Add(1)
-	}
-	s.jobQueues[check.Interval()].addJob(check)
+		s.jobQueues[check.Interval()].addJob(check)
 
-	// map each check to the Job Queue it was assigned to
-	s.checkToQueueMutex.Lock()
-	s.checkToQueue[check.ID()] = s.jobQueues[check.Interval()]
-	s.checkToQueueMutex.Unlock()
+		// map each check to the Job Queue it was assigned to
+		s.checkToQueueMutex.Lock()
+		s.checkToQueue[check.ID()] = s.jobQueues[check.Interval()]
+		s.checkToQueueMutex.Unlock()
+	}
 
 	schedulerChecksEntered.Add(1)
 	if check.IsTelemetryEnabled() {
@@ -118,6 +134,7 @@ func (s *Scheduler) Enter(check check.Check) error {
 		tlmChecksEntered.Inc(checkName)
 	}
 	schedulerExpvars.Set("Queues", expvar.Func(expQueues(s)))
+	schedulerExpvars.Set("CronJobs", expvar.Func(expCronJobs(s)))
 	return nil
 }
 
@@ -131,16 +148,22 @@ func (s *Scheduler) Cancel(id checkid.ID) error {
 
 	log.Infof("Unscheduling check %s", string(id))
 
-	if _, ok := s.checkToQueue[id]; !ok {
-		return nil
-	}
+	if job, ok := s.cronJobs[id]; ok {
+		// don't wait for the job to stop, as it may be waiting for the lock we hold
+		s.stopCronJob(job, false)
+		delete(s.cronJobs, id)
+	} else {
+		if _, ok := s.checkToQueue[id]; !ok {
+			return nil
+		}
 
-	// remove it from the queue
-	err := s.checkToQueue[id].removeJob(id)
-	if err != nil {
-		return fmt.Errorf("unable to remove the Job from the queue: %s", err)
+		// remove it from the queue
+		err := s.checkToQueue[id].removeJob(id)
+		if err != nil {
+			return fmt.Errorf("unable to remove the Job from the queue: %s", err)
+		}
+		delete(s.checkToQueue, id)
 	}
-	delete(s.checkToQueue, id)
 
 	schedulerChecksEntered.Add(-1)
 	if checkName, ok := s.tlmTrackedChecks[id]; ok {
@@ -148,6 +171,7 @@ func (s *Scheduler) Cancel(id checkid.ID) error {
 		tlmChecksEntered.Dec(checkName)
 	}
 	schedulerExpvars.Set("Queues", expvar.Func(expQueues(s)))
+	schedulerExpvars.Set("CronJobs", expvar.Func(expCronJobs(s)))
 	return nil
 }
 
@@ -214,6 +238,9 @@ func (s *Scheduler) IsCheckScheduled(id checkid.ID) bool {
 	s.checkToQueueMutex.RLock()
 	defer s.checkToQueueMutex.RUnlock()
 
+	if _, found := s.cronJobs[id]; found {
+		return true
+	}
 	_, found := s.checkToQueue[id]
 	return found
 }
@@ -235,6 +262,11 @@ func (s *Scheduler) stopQueues() {
 			q.running = false
 		}
 	}
+
+	log.Debugf("Stopping %v cron job(s)", len(s.cronJobs))
+	for _, job := range s.cronJobs {
+		s.stopCronJob(job, true)
+	}
 }
 
 // startQueues loads the timer for each queue
@@ -246,6 +278,9 @@ func (s *Scheduler) startQueues() {
 	for _, q := range s.jobQueues {
 		s.startQueue(q)
 	}
+	for _, job := range s.cronJobs {
+		s.startCronJob(job)
+	}
 }
 
 // startQueue starts a queue (non-blocking operation) if it's not running yet
@@ -256,6 +291,27 @@ func (s *Scheduler) startQueue(q *jobQueue) {
 	}
 }
 
+// startCronJob starts a cron job (non-blocking operation) if it's not running yet
+func (s *Scheduler) startCronJob(job *cronJob) {
+	if !job.running {
+		// the channel of the previous run is closed
+		job.stop = make(chan bool)
+		job.run(s, job.stop)
+		job.running = true
+	}
+}
+
+// stopCronJob stops a cron job if it's running, optionally waiting for it to stop
+func (s *Scheduler) stopCronJob(job *cronJob, wait bool) {
+	if job.running {
+		close(job.stop)
+		if wait {
+			<-job.stopped
+		}
+		job.running = false
+	}
+}
+
 // enqueueOnce enqueues a check once to the checksPipe.
 // Do not block, in case the runner has not started yet.
 // The queuing can be cancelled by closing the `cancelOneTime` channel.
@@ -285,3 +341,17 @@ func expQueues(s *Scheduler) func() interface{} {
 		return queues
 	}
 }
+
+// expCronJobs return a function to get the stats for the cron jobs, by check ID
+func expCronJobs(s *Scheduler) func() interface{} {
+	return func() interface{} {
+		s.checkToQueueMutex.RLock()
+		defer s.checkToQueueMutex.RUnlock()
+
+		jobs := make(map[checkid.ID]map[string]interface{}, len(s.cronJobs))
+		for id, job := range s.cronJobs {
+			jobs[id] = job.stats()
+		}
+		return jobs
+	}
+}
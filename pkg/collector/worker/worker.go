@@ -7,6 +7,7 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -146,6 +147,11 @@ func (w *Worker) Run() {
 			continue
 		}
 
+		if stats, found := expvars.CheckStats(check.ID()); found && stats.IsCircuitBreakerOpen(time.Now()) {
+			checkLogger.Debug("Check failed too many times in a row, skipping execution until its backoff elapses...")
+			continue
+		}
+
 		// Add check to tracker if it's not already running
 		if !w.checksTracker.AddCheck(check) {
 			checkLogger.Debug("Check is already running, skipping execution...")
@@ -161,8 +167,10 @@ func (w *Worker) Run() {
 
 		utilizationTracker.Started()
 
-		// Run the check
-		checkErr := check.Run()
+		// Run the check. When it times out, it's removed from the running checks once its run
+		// completes, in the background.
+		checkID := check.ID()
+		checkErr := runCheck(check, longRunning, func() { w.checksTracker.DeleteCheck(checkID) })
 
 		utilizationTracker.Finished()
 
@@ -202,7 +210,9 @@ func (w *Worker) Run() {
 		}
 
 		// Remove the check from the running list
-		w.checksTracker.DeleteCheck(check.ID())
+		if !isRunTimeout(checkErr) {
+			w.checksTracker.DeleteCheck(check.ID())
+		}
 
 		// Publish statistics about this run
 		expvars.AddRunningCheckCount(-1)
@@ -223,6 +233,22 @@ func (w *Worker) Run() {
 	log.Debugf("Runner %d, worker %d: Finished processing checks.", w.runnerID, w.ID)
 }
 
+// runCheck runs the check, and cancels its run when it exceeds the run_timeout of its instance, in
+// which case lateDone is called once the run actually completes, in the background.
+func runCheck(c check.Check, longRunning bool, lateDone func()) error {
+	var timeout time.Duration
+	if !longRunning {
+		timeout = check.RunTimeout(c)
+	}
+	return check.RunWithTimeout(c, timeout, lateDone)
+}
+
+// isRunTimeout returns whether the run was cancelled after exceeding its run_timeout
+func isRunTimeout(err error) bool {
+	var timeoutErr *check.RunTimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
 func startUtilizationUpdater(name string, ut *utilizationtracker.UtilizationTracker) {
 	expvars.SetWorkerStats(name, &expvars.WorkerStats{
 		Utilization: 0.0,
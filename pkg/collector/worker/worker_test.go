@@ -496,6 +496,96 @@ func TestWorkerStatsAddition(t *testing.T) {
 	}
 }
 
+// timeoutCheck is a check blocking until released, with a run_timeout of one second
+type timeoutCheck struct {
+	testCheck
+	cancelled *atomic.Bool
+}
+
+func (c *timeoutCheck) InstanceConfig() string { return "run_timeout: 1" }
+func (c *timeoutCheck) Cancel()                { c.cancelled.Store(true) }
+
+func TestWorkerRunTimeout(t *testing.T) {
+	expvars.Reset()
+	pkgconfigsetup.Datadog().SetWithoutSource("hostname", "myhost")
+
+	checksTracker := tracker.NewRunningChecksTracker()
+	pendingChecksChan := make(chan check.Check, 10)
+	mockShouldAddStatsFunc := func(checkid.ID) bool { return true }
+
+	release := make(chan struct{})
+	c := &timeoutCheck{
+		testCheck: testCheck{
+			t:        t,
+			id:       "timeout:123",
+			runFunc:  func(checkid.ID) { <-release },
+			runCount: atomic.NewUint64(0),
+		},
+		cancelled: atomic.NewBool(false),
+	}
+
+	// the second run is skipped, as the first one is still running after its timeout
+	pendingChecksChan <- c
+	pendingChecksChan <- c
+	close(pendingChecksChan)
+
+	worker, err := NewWorker(aggregator.NewNoOpSenderManager(), haagentmock.NewMockHaAgent(), 100, 200, pendingChecksChan, checksTracker, mockShouldAddStatsFunc)
+	require.Nil(t, err)
+
+	start := time.Now()
+	worker.Run()
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+
+	assert.Eventually(t, c.cancelled.Load, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, int(expvars.GetRunsCount()))
+	assert.Equal(t, 1, int(expvars.GetErrorsCount()))
+	stats, found := expvars.CheckStats(c.ID())
+	require.True(t, found)
+	assert.Equal(t, uint64(1), stats.TotalTimeouts)
+	assert.Equal(t, "check run timed out after 1s", stats.LastError)
+
+	_, running := checksTracker.Check(c.ID())
+	assert.True(t, running)
+
+	// the check is removed from the running checks once its run completes
+	close(release)
+	assert.Eventually(t, func() bool {
+		_, running := checksTracker.Check(c.ID())
+		return !running
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, c.RunCount())
+}
+
+func TestWorkerCircuitBreaker(t *testing.T) {
+	expvars.Reset()
+	pkgconfigsetup.Datadog().SetWithoutSource("hostname", "myhost")
+	pkgconfigsetup.Datadog().SetWithoutSource("check_circuit_breaker.failure_threshold", 2)
+	defer pkgconfigsetup.Datadog().SetWithoutSource("check_circuit_breaker.failure_threshold", 0)
+
+	checksTracker := tracker.NewRunningChecksTracker()
+	pendingChecksChan := make(chan check.Check, 10)
+	mockShouldAddStatsFunc := func(checkid.ID) bool { return true }
+
+	failingCheck := newCheck(t, "failing:123", true, nil)
+	for i := 0; i < 4; i++ {
+		pendingChecksChan <- failingCheck
+	}
+	close(pendingChecksChan)
+
+	worker, err := NewWorker(aggregator.NewNoOpSenderManager(), haagentmock.NewMockHaAgent(), 100, 200, pendingChecksChan, checksTracker, mockShouldAddStatsFunc)
+	require.Nil(t, err)
+
+	worker.Run()
+
+	// the runs are skipped after two consecutive failures
+	assert.Equal(t, 2, failingCheck.RunCount())
+	stats, found := expvars.CheckStats(failingCheck.ID())
+	require.True(t, found)
+	assert.Equal(t, uint64(2), stats.ConsecutiveFailures)
+	assert.True(t, stats.IsCircuitBreakerOpen(time.Now()))
+	assert.False(t, stats.IsCircuitBreakerOpen(time.Now().Add(time.Minute+time.Second)))
+}
+
 func TestWorkerServiceCheckSending(t *testing.T) {
 	expvars.Reset()
 	pkgconfigsetup.Datadog().SetWithoutSource("hostname", "myhost")
@@ -191,6 +191,7 @@ type ConfigurationProviders struct {
 	Token                   string `mapstructure:"token"`
 	GraceTimeSeconds        int    `mapstructure:"grace_time_seconds"`
 	DegradedDeadlineMinutes int    `mapstructure:"degraded_deadline_minutes"`
+	TimeoutSeconds          int    `mapstructure:"timeout_seconds"`
 }
 
 // Listeners helps unmarshalling `listeners` config param
@@ -356,6 +357,8 @@ func InitConfig(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("secret_backend_skip_checks", false)
 	config.BindEnvAndSetDefault("secret_backend_remove_trailing_line_break", false)
 	config.BindEnvAndSetDefault("secret_refresh_interval", 0)
+	config.BindEnvAndSetDefault("secret_backend_cache_ttl", 0)
+	config.SetKnown("secret_backend_config")
 	config.SetDefault("secret_audit_file_max_size", 0)
 
 	// IPC API server timeout
@@ -373,6 +376,17 @@ func InitConfig(config pkgconfigmodel.Setup) {
 
 	config.BindEnvAndSetDefault("flare.rc_streamlogs.duration", 60*time.Second)
 
+	// flare destination and encryption, for environments that can't send flares to Datadog
+	config.BindEnvAndSetDefault("flare_destination", "")
+	config.BindEnvAndSetDefault("flare_destination_token", "")
+	config.BindEnvAndSetDefault("flare_destination_s3.endpoint", "")
+	config.BindEnvAndSetDefault("flare_destination_s3.region", "us-east-1")
+	config.BindEnvAndSetDefault("flare_destination_s3.access_key_id", "")
+	config.BindEnvAndSetDefault("flare_destination_s3.secret_access_key", "")
+	config.BindEnvAndSetDefault("flare_destination_s3.session_token", "")
+	config.BindEnvAndSetDefault("flare_encryption.passphrase", "")
+	config.BindEnvAndSetDefault("flare_encryption.public_key_file", "")
+
 	// Docker
 	config.BindEnvAndSetDefault("docker_query_timeout", int64(5))
 	config.BindEnvAndSetDefault("docker_labels_as_tags", map[string]string{})
@@ -410,6 +424,7 @@ func InitConfig(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("prometheus_scrape.service_endpoints", false) // Enables Service Endpoints checks in the prometheus config provider
 	config.BindEnv("prometheus_scrape.checks")                                // Defines any extra prometheus/openmetrics check configurations to be handled by the prometheus config provider
 	config.BindEnvAndSetDefault("prometheus_scrape.version", 1)               // Version of the openmetrics check to be scheduled by the Prometheus auto-discovery
+	config.BindEnvAndSetDefault("prometheus_scrape.loader", "")               // Loader of the openmetrics check scheduled by the Prometheus auto-discovery, "core" selects the Go check
 
 	// Network Devices Monitoring
 	bindEnvAndSetLogsConfigKeys(config, "network_devices.metadata.")
@@ -458,6 +473,7 @@ func InitConfig(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("network_devices.snmp_traps.bind_host", "0.0.0.0")
 	config.BindEnvAndSetDefault("network_devices.snmp_traps.stop_timeout", 5) // in seconds
 	config.SetKnown("network_devices.snmp_traps.users")
+	config.SetKnown("network_devices.snmp_traps.tls")
 
 	// NetFlow
 	config.SetKnown("network_devices.netflow.listeners")
@@ -745,6 +761,7 @@ func InitConfig(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("clc_runner_server_write_timeout", 15)
 	config.BindEnvAndSetDefault("clc_runner_server_readheader_timeout", 10)
 	config.BindEnvAndSetDefault("clc_runner_remote_tagger_enabled", false)
+	config.BindEnvAndSetDefault("clc_runner_labels", map[string]string{}) // labels matched by the placement constraints of the cluster checks
 
 	// Remote tagger
 	config.BindEnvAndSetDefault("remote_tagger.max_concurrent_sync", 3)
@@ -1075,9 +1092,20 @@ func agent(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("origin_detection_unified", false)
 	config.BindEnv("env")
 	config.BindEnvAndSetDefault("tag_value_split_separator", map[string]string{})
+	// tagger_rules is a list of rules, set as a JSON string of the list through the environment
+	config.BindEnv("tagger_rules")
+	config.ParseEnvAsSlice("tagger_rules", func(in string) []interface{} {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"tagger_rules" can not be parsed: %v`, err)
+		}
+		return rules
+	})
 	config.BindEnvAndSetDefault("conf_path", ".")
 	config.BindEnvAndSetDefault("confd_path", defaultConfdPath)
 	config.BindEnvAndSetDefault("additional_checksd", defaultAdditionalChecksPath)
+	config.BindEnvAndSetDefault("check_plugins_dir", defaultCheckPluginsPath)
+	config.BindEnvAndSetDefault("check_plugins_start_timeout", 10*time.Second)
 	config.BindEnvAndSetDefault("jmx_log_file", "")
 	// If enabling log_payloads, ensure the log level is set to at least DEBUG to be able to see the logs
 	config.BindEnvAndSetDefault("log_payloads", false)
@@ -1113,6 +1141,9 @@ func agent(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("metadata_provider_stop_timeout", 30*time.Second)
 	config.BindEnvAndSetDefault("check_runners", int64(4))
 	config.BindEnvAndSetDefault("check_cancel_timeout", 500*time.Millisecond)
+	config.BindEnvAndSetDefault("check_circuit_breaker.failure_threshold", 0)
+	config.BindEnvAndSetDefault("check_circuit_breaker.initial_backoff", time.Minute)
+	config.BindEnvAndSetDefault("check_circuit_breaker.max_backoff", time.Hour)
 	config.BindEnvAndSetDefault("auth_token_file_path", "")
 	// used to override the path where the IPC cert/key files are stored/retrieved
 	config.BindEnvAndSetDefault("ipc_cert_file_path", "")
@@ -1222,6 +1253,7 @@ func autoconfig(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("container_exclude_logs", []string{})
 	config.BindEnvAndSetDefault("container_exclude_stopped_age", DefaultAuditorTTL-1) // in hours
 	config.BindEnvAndSetDefault("ad_config_poll_interval", int64(10))                 // in seconds
+	config.BindEnvAndSetDefault("ad_template_file_allowed_dirs", []string{})
 	config.BindEnvAndSetDefault("extra_listeners", []string{})
 	config.BindEnvAndSetDefault("extra_config_providers", []string{})
 	config.BindEnvAndSetDefault("ignore_autoconf", []string{})
@@ -1285,6 +1317,8 @@ func debugging(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("tracemalloc_whitelist", "") // deprecated
 	config.BindEnvAndSetDefault("tracemalloc_blacklist", "") // deprecated
 	config.BindEnvAndSetDefault("run_path", defaultRunPath)
+	// Maximum size in bytes of the entries of the persistent cache, stored in run_path; 0 means unlimited
+	config.BindEnvAndSetDefault("persistent_cache.max_size", 100*1024*1024)
 	config.BindEnv("no_proxy_nonexact_match")
 }
 
@@ -1347,6 +1381,41 @@ func aggregator(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("basic_telemetry_add_container_tags", false) // configure adding the agent container tags to the basic agent telemetry metrics (e.g. `datadog.agent.running`)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_chan_size", 200)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_buffer_size", 4000)
+	// tag_aggregation_rules is a list of rules, set as a JSON string of the list through the environment
+	config.BindEnv("tag_aggregation_rules")
+	config.ParseEnvAsSlice("tag_aggregation_rules", func(in string) []interface{} {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"tag_aggregation_rules" can not be parsed: %v`, err)
+		}
+		return rules
+	})
+
+	// Prometheus remote-write output of the aggregated metrics
+	config.BindEnvAndSetDefault("prometheus_remote_write.enabled", false)
+	config.BindEnvAndSetDefault("prometheus_remote_write.timeout", 10)
+	config.BindEnvAndSetDefault("prometheus_remote_write.batch_size", 2000)
+	config.BindEnvAndSetDefault("prometheus_remote_write.queue_size", 100)
+	config.BindEnvAndSetDefault("prometheus_remote_write.max_retries", 5)
+	config.BindEnvAndSetDefault("prometheus_remote_write.backoff_base", 1)
+	config.BindEnvAndSetDefault("prometheus_remote_write.backoff_max", 30)
+	config.BindEnvAndSetDefault("prometheus_remote_write.sketch_format", "summary")
+	for _, key := range []string{"prometheus_remote_write.endpoints", "prometheus_remote_write.relabel_configs"} {
+		config.BindEnv(key)
+		config.ParseEnvAsSlice(key, func(in string) []interface{} {
+			var values []interface{}
+			if err := json.Unmarshal([]byte(in), &values); err != nil {
+				log.Errorf(`"%s" can not be parsed: %v`, key, err)
+			}
+			return values
+		})
+	}
+
+	// local OpenMetrics endpoint exposing the last flushed metrics
+	config.BindEnvAndSetDefault("aggregator_openmetrics_endpoint.enabled", false)
+	config.BindEnvAndSetDefault("aggregator_openmetrics_endpoint.host", "localhost")
+	config.BindEnvAndSetDefault("aggregator_openmetrics_endpoint.port", 5004)
+	config.BindEnvAndSetDefault("aggregator_openmetrics_endpoint.metric_prefixes", []string{})
 }
 
 func serverless(config pkgconfigmodel.Setup) {
@@ -1414,6 +1483,12 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("dogstatsd_non_local_traffic", false)
 	config.BindEnvAndSetDefault("dogstatsd_socket", defaultStatsdSocket) // Only enabled on unix systems
 	config.BindEnvAndSetDefault("dogstatsd_stream_socket", "")           // Experimental || Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0)                 // Notice: 0 means TCP port closed
+	config.BindEnvAndSetDefault("dogstatsd_tls_port", 0)                 // Notice: 0 means TLS port closed
+	config.BindEnvAndSetDefault("dogstatsd_tls_cert_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tls_key_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tls_client_ca_file", "") // Notice: empty means client certificates aren't required
+	config.BindEnvAndSetDefault("dogstatsd_tcp_max_connections", 1000)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_autoadjust", false)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_autoadjust_strategy", "max_throughput")
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_count", 1)
@@ -1454,6 +1529,15 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	// Force the amount of dogstatsd workers (mainly used for benchmarks or some very specific use-case)
 	config.BindEnvAndSetDefault("dogstatsd_workers_count", 0)
 
+	// Cap the number of dogstatsd contexts per metric name, per origin and per tag key.
+	// A limit of 0 disables it. The policy is either "drop" or "collapse".
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.enabled", false)
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.metric_limit", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.origin_limit", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.tag_value_limit", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.policy", "drop")
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.placeholder", "other")
+
 	// To enable the following feature, GODEBUG must contain `madvdontneed=1`
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.enabled", false)
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.low_soft_limit", 0.7)
@@ -1556,6 +1640,12 @@ func logsagent(config pkgconfigmodel.Setup) {
 	// Transport protocol for log payloads
 	config.BindEnvAndSetDefault("logs_config.http_protocol", "auto")
 
+	// Store payloads that cannot be sent on disk instead of retrying them in memory
+	config.BindEnvAndSetDefault("logs_config.disk_retry.enabled", false)
+	// Defaults to <logs_config.run_path>/retry when empty
+	config.BindEnvAndSetDefault("logs_config.disk_retry.path", "")
+	config.BindEnvAndSetDefault("logs_config.disk_retry.max_size_bytes", 100*1024*1024)
+
 	bindEnvAndSetLogsConfigKeys(config, "logs_config.")
 	bindEnvAndSetLogsConfigKeys(config, "database_monitoring.samples.")
 	bindEnvAndSetLogsConfigKeys(config, "database_monitoring.activity.")
@@ -2272,9 +2362,11 @@ func ResolveSecrets(config pkgconfigmodel.Config, secretResolver secrets.Compone
 		RemoveLinebreak:  config.GetBool("secret_backend_remove_trailing_line_break"),
 		RunPath:          config.GetString("run_path"),
 		AuditFileMaxSize: config.GetInt("secret_audit_file_max_size"),
+		Backends:         config.GetStringMap("secret_backend_config"),
+		CacheTTL:         config.GetInt("secret_backend_cache_ttl"),
 	})
 
-	if config.GetString("secret_backend_command") != "" {
+	if config.GetString("secret_backend_command") != "" || len(config.GetStringMap("secret_backend_config")) != 0 {
 		// Viper doesn't expose the final location of the file it
 		// loads. Since we are searching for 'datadog.yaml' in multiple
 		// locations we let viper determine the one to use before
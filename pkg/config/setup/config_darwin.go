@@ -8,6 +8,7 @@ package setup
 const (
 	defaultConfdPath            = "/opt/datadog-agent/etc/conf.d"
 	defaultAdditionalChecksPath = "/opt/datadog-agent/etc/checks.d"
+	defaultCheckPluginsPath     = "/opt/datadog-agent/etc/plugins.d"
 	defaultRunPath              = "/opt/datadog-agent/run"
 	defaultGuiPort              = 5002
 	// DefaultUpdaterLogFile is the default updater log file
@@ -32,6 +32,7 @@ var (
 const (
 	defaultConfdPath            = "/etc/datadog-agent/conf.d"
 	defaultAdditionalChecksPath = "/etc/datadog-agent/checks.d"
+	defaultCheckPluginsPath     = "/etc/datadog-agent/plugins.d"
 	defaultGuiPort              = -1
 	// DefaultUpdaterLogFile is the default updater log file
 	DefaultUpdaterLogFile = "/var/log/datadog/updater.log"
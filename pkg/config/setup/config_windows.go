@@ -16,6 +16,7 @@ import (
 var (
 	defaultConfdPath            = "c:\\programdata\\datadog\\conf.d"
 	defaultAdditionalChecksPath = "c:\\programdata\\datadog\\checks.d"
+	defaultCheckPluginsPath     = "c:\\programdata\\datadog\\plugins.d"
 	defaultRunPath              = "c:\\programdata\\datadog\\run"
 	defaultGuiPort              = 5002
 	// DefaultUpdaterLogFile is the default updater log file
@@ -46,6 +47,7 @@ func osinit() {
 	if err == nil {
 		defaultConfdPath = filepath.Join(pd, "conf.d")
 		defaultAdditionalChecksPath = filepath.Join(pd, "checks.d")
+		defaultCheckPluginsPath = filepath.Join(pd, "plugins.d")
 		defaultRunPath = filepath.Join(pd, "run")
 		DefaultSecurityAgentLogFile = filepath.Join(pd, "logs", "security-agent.log")
 		defaultSystemProbeLogFilePath = filepath.Join(pd, "logs", "system-probe.log")
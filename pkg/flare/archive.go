@@ -55,33 +55,37 @@ func ExtraFlareProviders(diagnoseDeps diagnose.SuitesDeps) []*flaretypes.FlareFi
 	 */
 
 	providers := []*flaretypes.FlareFiller{
-		flaretypes.NewFiller(provideExtraFiles),
-		flaretypes.NewFiller(provideSystemProbe),
-		flaretypes.NewFiller(provideConfigDump),
-		flaretypes.NewFiller(provideRemoteConfig),
-		flaretypes.NewFiller(getRegistryJSON),
-		flaretypes.NewFiller(getVersionHistory),
-		flaretypes.NewFiller(getWindowsData),
-		flaretypes.NewFiller(common.GetExpVar),
-		flaretypes.NewFiller(provideInstallInfo),
-		flaretypes.NewFiller(provideAuthTokenPerm),
-		flaretypes.NewFiller(provideDiagnoses(diagnoseDeps)),
-		flaretypes.NewFiller(provideContainers(diagnoseDeps)),
+		flaretypes.NewNamedFiller("tagger", provideExtraFiles),
+		flaretypes.NewNamedFiller("system-probe", provideSystemProbe),
+		flaretypes.NewNamedFiller("config", provideConfigDump),
+		flaretypes.NewNamedFiller("remote-config", provideRemoteConfig),
+		flaretypes.NewNamedFiller("logs", getRegistryJSON),
+		flaretypes.NewNamedFiller("install-info", getVersionHistory),
+		flaretypes.NewNamedFiller("windows", getWindowsData),
+		flaretypes.NewNamedFiller("expvar", common.GetExpVar),
+		flaretypes.NewNamedFiller("install-info", provideInstallInfo),
+		flaretypes.NewNamedFiller("permissions", provideAuthTokenPerm),
+		flaretypes.NewNamedFiller("diagnose", provideDiagnoses(diagnoseDeps)),
+		flaretypes.NewNamedFiller("containers", provideContainers(diagnoseDeps)),
 	}
 
 	pprofURL := fmt.Sprintf("http://127.0.0.1:%s/debug/pprof/goroutine?debug=2",
 		pkgconfigsetup.Datadog().GetString("expvar_port"))
 	telemetryURL := fmt.Sprintf("http://127.0.0.1:%s/telemetry", pkgconfigsetup.Datadog().GetString("expvar_port"))
 
-	for filename, fromFunc := range map[string]func() ([]byte, error){
-		"envvars.log":         common.GetEnvVars,
-		"health.yaml":         getHealth,
-		"go-routine-dump.log": func() ([]byte, error) { return getHTTPCallContent(pprofURL) },
-		"telemetry.log":       func() ([]byte, error) { return getHTTPCallContent(telemetryURL) },
+	for filename, extra := range map[string]struct {
+		name     string
+		fromFunc func() ([]byte, error)
+	}{
+		"envvars.log":         {"envvars", common.GetEnvVars},
+		"health.yaml":         {"health", getHealth},
+		"go-routine-dump.log": {"expvar", func() ([]byte, error) { return getHTTPCallContent(pprofURL) }},
+		"telemetry.log":       {"telemetry", func() ([]byte, error) { return getHTTPCallContent(telemetryURL) }},
 	} {
-		providers = append(providers, flaretypes.NewFiller(
+		providers = append(providers, flaretypes.NewNamedFiller(
+			extra.name,
 			func(fb flaretypes.FlareBuilder) error {
-				fb.AddFileFromFunc(filename, fromFunc) //nolint:errcheck
+				fb.AddFileFromFunc(filename, extra.fromFunc) //nolint:errcheck
 				return nil
 			},
 		))
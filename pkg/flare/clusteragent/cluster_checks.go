@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/fatih/color"
@@ -80,6 +81,17 @@ func GetClusterChecks(w io.Writer, checkName string) error {
 		fmt.Fprintln(w, "")
 	}
 
+	// Print the reasons why placement constraints can't be satisfied
+	if len(cr.PlacementErrors) > 0 {
+		fmt.Fprintf(w, "=== %s placement constraints ===\n", color.RedString("Unsatisfiable"))
+		for _, c := range cr.Dangling {
+			if reason, found := cr.PlacementErrors[c.Digest()]; found && (checkName == "" || c.Name == checkName) {
+				fmt.Fprintf(w, "%s (%s): %s\n", color.GreenString(c.Name), c.Digest(), reason)
+			}
+		}
+		fmt.Fprintln(w, "")
+	}
+
 	// Print summary of agents
 	if len(cr.Nodes) == 0 {
 		fmt.Fprintf(w, "=== %s agent reporting ===\n", color.RedString("Zero"))
@@ -89,9 +101,9 @@ func GetClusterChecks(w io.Writer, checkName string) error {
 	fmt.Fprintf(w, "=== %d agents reporting ===\n", len(cr.Nodes))
 	sort.Slice(cr.Nodes, func(i, j int) bool { return cr.Nodes[i].Name < cr.Nodes[j].Name })
 	table := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
-	fmt.Fprintln(table, "\nName\tRunning checks")
+	fmt.Fprintln(table, "\nName\tRunning checks\tLabels")
 	for _, n := range cr.Nodes {
-		fmt.Fprintf(table, "%s\t%d\n", n.Name, len(n.Configs))
+		fmt.Fprintf(table, "%s\t%d\t%s\n", n.Name, len(n.Configs), formatLabels(n.Labels))
 	}
 	table.Flush()
 
@@ -109,6 +121,15 @@ func GetClusterChecks(w io.Writer, checkName string) error {
 	return nil
 }
 
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
 // GetEndpointsChecks dumps the endpointschecks dispatching state to the writer
 func GetEndpointsChecks(w io.Writer, checkName string) error {
 	if !endpointschecksEnabled() {
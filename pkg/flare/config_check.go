@@ -8,6 +8,7 @@ package flare
 import (
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/fatih/color"
 
@@ -156,6 +157,9 @@ func PrintClusterCheckConfig(w io.Writer, c integration.Config, checkName string
 		}
 		printContainerExclusionRulesInfo(w, &c)
 	}
+	if c.Placement != nil {
+		printPlacementConstraints(w, c.Placement)
+	}
 	if c.NodeName != "" {
 		state := fmt.Sprintf("dispatched to %s", c.NodeName)
 		fmt.Fprintf(w, "%s: %s\n", color.BlueString("State"), color.CyanString(state))
@@ -163,6 +167,30 @@ func PrintClusterCheckConfig(w io.Writer, c integration.Config, checkName string
 	fmt.Fprintln(w, "===")
 }
 
+func printPlacementConstraints(w io.Writer, p *integration.PlacementConstraints) {
+	fmt.Fprintf(w, "%s:\n", color.BlueString("Placement constraints"))
+	if len(p.NodeSelector) > 0 {
+		keys := make([]string, 0, len(p.NodeSelector))
+		for key := range p.NodeSelector {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "* node selector: %s\n", color.CyanString(key+"="+p.NodeSelector[key]))
+		}
+	}
+	if p.AntiAffinityGroup != "" {
+		fmt.Fprintf(w, "* anti-affinity group: %s\n", color.CyanString(p.AntiAffinityGroup))
+	}
+	if p.TopologySpreadKey != "" {
+		spread := p.TopologySpreadKey
+		if p.SpreadGroup != "" {
+			spread += " (group " + p.SpreadGroup + ")"
+		}
+		fmt.Fprintf(w, "* spread across: %s\n", color.CyanString(spread))
+	}
+}
+
 func printContainerExclusionRulesInfo(w io.Writer, c *integration.Config) {
 	var msg string
 	if c.IsCheckConfig() && c.MetricsExcluded {
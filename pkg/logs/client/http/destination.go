@@ -51,6 +51,13 @@ var (
 	expVarInUseMsMapKey = "inUseMs"
 )
 
+const (
+	// retryQueueCheckInterval is how often the retry queue is checked when it is not notified of new payloads.
+	retryQueueCheckInterval = 10 * time.Second
+	// minRetryQueueBackoff prevents a busy loop when the backoff policy returns no delay.
+	minRetryQueueBackoff = time.Second
+)
+
 // emptyJsonPayload is an empty payload used to check HTTP connectivity without sending logs.
 //
 //nolint:revive // TODO(AML) Fix revive linter
@@ -80,6 +87,12 @@ type Destination struct {
 	shouldRetry    bool
 	lastRetryError error
 
+	// On-disk retry
+	retryQueue       RetryQueue
+	retryQueueSignal chan struct{} // notifies the retry queue loop that a payload was stored
+	retryQueueStop   chan struct{}
+	retryQueueDone   chan struct{}
+
 	// Telemetry
 	expVars         *expvar.Map
 	destMeta        *client.DestinationMetadata
@@ -90,6 +103,8 @@ type Destination struct {
 // NewDestination returns a new Destination.
 // If `maxConcurrentBackgroundSends` > 0, then at most that many background payloads will be sent concurrently, else
 // there is no concurrency and the background sending pipeline will block while sending each payload.
+// If `retryQueue` is not nil, payloads failing with a retryable error are stored in it instead of blocking
+// the pipeline, and are sent in order once the endpoint recovers. It is only used when `shouldRetry` is true.
 // TODO: add support for SOCKS5
 func NewDestination(endpoint config.Endpoint,
 	contentType string,
@@ -98,7 +113,8 @@ func NewDestination(endpoint config.Endpoint,
 	shouldRetry bool,
 	destMeta *client.DestinationMetadata,
 	cfg pkgconfigmodel.Reader,
-	pipelineMonitor metrics.PipelineMonitor) *Destination {
+	pipelineMonitor metrics.PipelineMonitor,
+	retryQueue RetryQueue) *Destination {
 
 	return newDestination(endpoint,
 		contentType,
@@ -108,7 +124,8 @@ func NewDestination(endpoint config.Endpoint,
 		shouldRetry,
 		destMeta,
 		cfg,
-		pipelineMonitor)
+		pipelineMonitor,
+		retryQueue)
 }
 
 func newDestination(endpoint config.Endpoint,
@@ -119,7 +136,8 @@ func newDestination(endpoint config.Endpoint,
 	shouldRetry bool,
 	destMeta *client.DestinationMetadata,
 	cfg pkgconfigmodel.Reader,
-	pipelineMonitor metrics.PipelineMonitor) *Destination {
+	pipelineMonitor metrics.PipelineMonitor,
+	retryQueue RetryQueue) *Destination {
 
 	if maxConcurrentBackgroundSends <= 0 {
 		maxConcurrentBackgroundSends = 1
@@ -140,6 +158,10 @@ func newDestination(endpoint config.Endpoint,
 		metrics.DestinationExpVars.Set(destMeta.TelemetryName(), expVars)
 	}
 
+	if !shouldRetry {
+		retryQueue = nil
+	}
+
 	return &Destination{
 		host:                endpoint.Host,
 		url:                 buildURL(endpoint),
@@ -155,6 +177,8 @@ func newDestination(endpoint config.Endpoint,
 		lastRetryError:      nil,
 		retryLock:           sync.Mutex{},
 		shouldRetry:         shouldRetry,
+		retryQueue:          retryQueue,
+		retryQueueSignal:    make(chan struct{}, 1),
 		expVars:             expVars,
 		destMeta:            destMeta,
 		isMRF:               endpoint.IsMRF,
@@ -191,6 +215,11 @@ func (d *Destination) Metadata() *client.DestinationMetadata {
 // Start starts reading the input channel
 func (d *Destination) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) (stopChan <-chan struct{}) {
 	stop := make(chan struct{})
+	if d.retryQueue != nil {
+		d.retryQueueStop = make(chan struct{})
+		d.retryQueueDone = make(chan struct{})
+		go d.runRetryQueue(isRetrying)
+	}
 	go d.run(input, output, stop, isRetrying)
 	return stop
 }
@@ -216,6 +245,12 @@ func (d *Destination) run(input chan *message.Payload, output chan *message.Payl
 	// Wait for any pending concurrent sends to finish or terminate
 	d.wg.Wait()
 
+	// Stored payloads are sent on the next start
+	if d.retryQueue != nil {
+		close(d.retryQueueStop)
+		<-d.retryQueueDone
+	}
+
 	d.updateRetryState(nil, isRetrying)
 	stopChan <- struct{}{}
 }
@@ -234,6 +269,11 @@ func (d *Destination) sendConcurrent(payload *message.Payload, output chan *mess
 
 // Send sends a payload over HTTP,
 func (d *Destination) sendAndRetry(payload *message.Payload, output chan *message.Payload, isRetrying chan bool) {
+	// Payloads are queued behind the stored ones to keep them ordered.
+	if d.retryQueue != nil && d.retryQueue.Len() > 0 && d.storeForRetry(payload, output) {
+		return
+	}
+
 	for {
 
 		d.retryLock.Lock()
@@ -270,6 +310,9 @@ func (d *Destination) sendAndRetry(payload *message.Payload, output chan *messag
 
 		if d.shouldRetry {
 			if d.updateRetryState(err, isRetrying) {
+				if d.retryQueue != nil && d.storeForRetry(payload, output) {
+					return
+				}
 				continue
 			}
 		}
@@ -281,6 +324,104 @@ func (d *Destination) sendAndRetry(payload *message.Payload, output chan *messag
 	}
 }
 
+// storeForRetry stores the payload in the retry queue and hands it over to the auditor, as it is
+// now safe to move the offsets forward. It returns false if the payload could not be stored, in
+// which case the caller must keep retrying it in memory.
+func (d *Destination) storeForRetry(payload *message.Payload, output chan *message.Payload) bool {
+	if err := d.retryQueue.Store(payload); err != nil {
+		log.Warnf("%s: could not store payload in the retry queue, retrying in memory: %v", d.url, err)
+		return false
+	}
+	d.pipelineMonitor.ReportComponentEgress(payload, d.destMeta.MonitorTag())
+	// the logs are counted as sent once the stored payload is sent
+	metrics.LogsStoredForRetry.Add(int64(len(payload.Messages)))
+	metrics.TlmLogsStoredForRetry.Add(float64(len(payload.Messages)))
+	output <- payload
+
+	select {
+	case d.retryQueueSignal <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// runRetryQueue sends the stored payloads in order until the destination stops.
+func (d *Destination) runRetryQueue(isRetrying chan bool) {
+	defer close(d.retryQueueDone)
+
+	for {
+		wait, backingOff := d.flushRetryQueue(isRetrying)
+		signal := d.retryQueueSignal
+		if backingOff {
+			// new payloads must not cut the backoff short
+			signal = nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-d.retryQueueStop:
+			timer.Stop()
+			return
+		case <-signal:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// flushRetryQueue sends the stored payloads until the queue is empty or a send fails. It returns
+// how long to wait before trying again, and whether this delay is a backoff after an error.
+func (d *Destination) flushRetryQueue(isRetrying chan bool) (time.Duration, bool) {
+	for {
+		select {
+		case <-d.retryQueueStop:
+			return 0, false
+		default:
+		}
+
+		payload, err := d.retryQueue.Peek()
+		if err != nil {
+			// the payload would block the queue forever
+			log.Warnf("%s: dropping unreadable payload from the retry queue: %v", d.url, err)
+			if err := d.retryQueue.Remove(); err != nil {
+				log.Warnf("%s: could not remove payload from the retry queue: %v", d.url, err)
+				return retryQueueCheckInterval, false
+			}
+			continue
+		}
+		if payload == nil {
+			return retryQueueCheckInterval, false
+		}
+
+		err = d.unconditionalSend(payload.Payload)
+		if err == context.Canceled {
+			return retryQueueCheckInterval, false
+		}
+		if d.updateRetryState(err, isRetrying) {
+			metrics.DestinationErrors.Add(1)
+			metrics.TlmDestinationErrors.Inc()
+			log.Warnf("Could not send stored payload: %v", err)
+
+			d.retryLock.Lock()
+			nbErrors := d.nbErrors
+			d.retryLock.Unlock()
+			metrics.RetryCount.Add(1)
+			metrics.TlmRetryCount.Add(1)
+			return max(d.backoff.GetBackoffDuration(nbErrors), minRetryQueueBackoff), true
+		}
+		if err != nil {
+			// the intake rejected the payload, there is no point in sending it again
+			log.Warnf("Dropping stored payload: %v", err)
+		} else {
+			metrics.LogsSent.Add(int64(payload.MessageCount))
+			metrics.TlmLogsSent.Add(float64(payload.MessageCount))
+		}
+		if err := d.retryQueue.Remove(); err != nil {
+			log.Warnf("%s: could not remove payload from the retry queue: %v", d.url, err)
+			return retryQueueCheckInterval, false
+		}
+	}
+}
+
 func (d *Destination) unconditionalSend(payload *message.Payload) (err error) {
 	defer func() {
 		tlmSend.Inc(d.host, errorToTag(err))
@@ -460,7 +601,7 @@ func getMessageTimestamp(messages []*message.Message) int64 {
 func prepareCheckConnectivity(endpoint config.Endpoint, cfg pkgconfigmodel.Reader) (*client.DestinationsContext, *Destination) {
 	ctx := client.NewDestinationsContext()
 	// Lower the timeout to 5s because HTTP connectivity test is done synchronously during the agent bootstrap sequence
-	destination := newDestination(endpoint, JSONContentType, ctx, time.Second*5, 0, false, client.NewNoopDestinationMetadata(), cfg, metrics.NewNoopPipelineMonitor(""))
+	destination := newDestination(endpoint, JSONContentType, ctx, time.Second*5, 0, false, client.NewNoopDestinationMetadata(), cfg, metrics.NewNoopPipelineMonitor(""), nil)
 	return ctx, destination
 }
 
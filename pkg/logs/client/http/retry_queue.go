@@ -0,0 +1,403 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package http
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"expvar"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// RetryQueue durably stores payloads that could not be sent by a destination so that
+// they can be sent later on. Payloads are returned in the order they were stored.
+type RetryQueue interface {
+	// Store persists a payload. Once Store returns without error, the payload survives
+	// an agent restart.
+	Store(payload *message.Payload) error
+	// Peek returns the oldest stored payload without removing it, or nil if the queue is empty.
+	// An error means the oldest payload cannot be read, it is up to the caller to remove it.
+	Peek() (*StoredPayload, error)
+	// Remove removes the oldest stored payload.
+	Remove() error
+	// Len returns the number of stored payloads.
+	Len() int
+}
+
+// StoredPayload is a payload read back from a RetryQueue. The messages of a payload are not
+// stored, only their number.
+type StoredPayload struct {
+	*message.Payload
+	// MessageCount is the number of messages encoded in the payload.
+	MessageCount int
+}
+
+const (
+	retryFileExtension = ".retry"
+	retryTempPrefix    = "tmp-"
+
+	// retryFileMagic identifies payloads written by the disk retry queue, it must be
+	// followed by the format version.
+	retryFileMagic   = "DDLR"
+	retryFileVersion = byte(1)
+
+	// header: magic, version, created (unix nanos), unencoded size, message count, encoding length
+	retryFileHeaderSize = len(retryFileMagic) + 1 + 8 + 8 + 4 + 2
+	// trailer: CRC32 (IEEE) of everything before it
+	retryFileTrailerSize = 4
+
+	expVarRetrySizeBytesKey       = "SizeBytes"
+	expVarRetryPayloadsKey        = "Payloads"
+	expVarRetryOldestTimestampKey = "OldestEntryTimestamp"
+
+	droppedReasonQueueFull = "queue_full"
+	droppedReasonCorrupted = "corrupted"
+)
+
+var errRetryPayloadTooLarge = errors.New("payload is larger than the maximum size of the retry queue")
+
+type retryFile struct {
+	path    string
+	size    int64
+	created time.Time
+}
+
+// DiskRetryQueue is a RetryQueue storing one file per payload in a directory. Files are
+// written to a temporary file which is synced and then renamed, so that a crash never
+// leaves a partially written payload behind. When the queue is full, the oldest payloads
+// are evicted to make room for the new ones.
+type DiskRetryQueue struct {
+	mu           sync.Mutex
+	dir          string
+	maxSizeBytes int64
+	files        []retryFile
+	currentSize  int64
+	nextSeq      uint64
+
+	// Telemetry
+	name     string
+	expVars  *expvar.Map
+	size     *expvar.Int
+	payloads *expvar.Int
+	oldest   *expvar.Int
+}
+
+// NewDiskRetryQueue returns a new DiskRetryQueue storing at most maxSizeBytes in dir.
+// Payloads left in dir by a previous run are loaded and will be returned first.
+// name is used to report telemetry, reporting is disabled when it is empty.
+func NewDiskRetryQueue(dir string, maxSizeBytes int64, name string) (*DiskRetryQueue, error) {
+	if maxSizeBytes <= 0 {
+		return nil, fmt.Errorf("invalid maximum size for the retry queue: %d", maxSizeBytes)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create the retry queue directory %s: %w", dir, err)
+	}
+	q := &DiskRetryQueue{
+		dir:          dir,
+		maxSizeBytes: maxSizeBytes,
+		name:         name,
+		expVars:      &expvar.Map{},
+		size:         &expvar.Int{},
+		payloads:     &expvar.Int{},
+		oldest:       &expvar.Int{},
+	}
+	q.expVars.Set(expVarRetrySizeBytesKey, q.size)
+	q.expVars.Set(expVarRetryPayloadsKey, q.payloads)
+	q.expVars.Set(expVarRetryOldestTimestampKey, q.oldest)
+	if name != "" {
+		metrics.DiskRetryQueues.Set(name, q.expVars)
+	}
+
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	// The size limit may have been lowered since the previous run.
+	q.makeRoomFor(0)
+	q.updateTelemetry()
+	return q, nil
+}
+
+// Store implements RetryQueue.
+func (q *DiskRetryQueue) Store(payload *message.Payload) error {
+	created := time.Now()
+	data := encodeRetryPayload(payload, created)
+	size := int64(len(data))
+	if size > q.maxSizeBytes {
+		return errRetryPayloadTooLarge
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.makeRoomFor(size)
+
+	path := filepath.Join(q.dir, fmt.Sprintf("%020d%s", q.nextSeq, retryFileExtension))
+	if err := writeFileAtomically(q.dir, path, data); err != nil {
+		return err
+	}
+	q.nextSeq++
+	q.files = append(q.files, retryFile{path: path, size: size, created: created})
+	q.currentSize += size
+	q.updateTelemetry()
+	return nil
+}
+
+// Peek implements RetryQueue. Payloads which cannot be read back are dropped, so an unreadable
+// file never blocks the queue.
+func (q *DiskRetryQueue) Peek() (*StoredPayload, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.files) > 0 {
+		f := q.files[0]
+		data, err := os.ReadFile(f.path)
+		if err == nil {
+			var payload *StoredPayload
+			if payload, err = decodeRetryPayload(data); err == nil {
+				return payload, nil
+			}
+		}
+		log.Warnf("Dropping unreadable payload %s from the retry queue: %v", f.path, err)
+		q.removeOldest(droppedReasonCorrupted)
+	}
+	return nil, nil
+}
+
+// Remove implements RetryQueue.
+func (q *DiskRetryQueue) Remove() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.files) == 0 {
+		return nil
+	}
+	f := q.files[0]
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	q.files = q.files[1:]
+	q.currentSize -= f.size
+	q.updateTelemetry()
+	return nil
+}
+
+// Len implements RetryQueue.
+func (q *DiskRetryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.files)
+}
+
+// SizeBytes returns the number of bytes currently stored on disk.
+func (q *DiskRetryQueue) SizeBytes() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.currentSize
+}
+
+// load reads the payloads left by a previous run and removes leftover temporary files.
+func (q *DiskRetryQueue) load() error {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return fmt.Errorf("cannot read the retry queue directory %s: %w", q.dir, err)
+	}
+	type seqFile struct {
+		seq uint64
+		retryFile
+	}
+	var files []seqFile
+	for _, entry := range entries {
+		path := filepath.Join(q.dir, entry.Name())
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), retryTempPrefix) {
+			// interrupted write
+			if err := os.Remove(path); err != nil {
+				log.Warnf("Cannot remove temporary file %s: %v", path, err)
+			}
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), retryFileExtension), 10, 64)
+		if err != nil || !strings.HasSuffix(entry.Name(), retryFileExtension) {
+			continue
+		}
+		created, err := readRetryFileCreated(path)
+		if err != nil {
+			log.Warnf("Dropping unreadable payload %s from the retry queue: %v", path, err)
+			q.dropFile(path, 0, droppedReasonCorrupted)
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, seqFile{seq: seq, retryFile: retryFile{path: path, size: info.Size(), created: created}})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].seq < files[j].seq })
+
+	for _, f := range files {
+		q.files = append(q.files, f.retryFile)
+		q.currentSize += f.size
+		q.nextSeq = f.seq + 1
+	}
+	if len(q.files) > 0 {
+		log.Infof("Loaded %d payloads (%d bytes) from the retry queue %s", len(q.files), q.currentSize, q.dir)
+	}
+	return nil
+}
+
+// makeRoomFor evicts the oldest payloads until size bytes can be stored.
+func (q *DiskRetryQueue) makeRoomFor(size int64) {
+	for len(q.files) > 0 && q.currentSize+size > q.maxSizeBytes {
+		log.Warnf("Retry queue %s is full, dropping the oldest payload %s", q.dir, q.files[0].path)
+		q.removeOldest(droppedReasonQueueFull)
+	}
+}
+
+// removeOldest removes the oldest file and accounts for it as dropped.
+func (q *DiskRetryQueue) removeOldest(reason string) {
+	f := q.files[0]
+	q.files = q.files[1:]
+	q.currentSize -= f.size
+	q.dropFile(f.path, f.size, reason)
+	q.updateTelemetry()
+}
+
+func (q *DiskRetryQueue) dropFile(path string, size int64, reason string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Cannot remove %s from the retry queue: %v", path, err)
+	}
+	metrics.DiskRetryDroppedBytes.Add(size)
+	metrics.TlmDiskRetryDroppedBytes.Add(float64(size), q.name, reason)
+}
+
+func (q *DiskRetryQueue) updateTelemetry() {
+	q.size.Set(q.currentSize)
+	q.payloads.Set(int64(len(q.files)))
+	var oldest int64
+	var age float64
+	if len(q.files) > 0 {
+		oldest = q.files[0].created.Unix()
+		age = time.Since(q.files[0].created).Seconds()
+	}
+	q.oldest.Set(oldest)
+	if q.name != "" {
+		metrics.TlmDiskRetrySizeBytes.Set(float64(q.currentSize), q.name)
+		metrics.TlmDiskRetryPayloads.Set(float64(len(q.files)), q.name)
+		metrics.TlmDiskRetryOldestEntryAge.Set(age, q.name)
+	}
+}
+
+// writeFileAtomically writes data to path through a synced temporary file.
+func writeFileAtomically(dir, path string, data []byte) error {
+	tmp, err := os.CreateTemp(dir, retryTempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("cannot write %s: %w", path, err)
+	}
+	return nil
+}
+
+func encodeRetryPayload(payload *message.Payload, created time.Time) []byte {
+	var buf bytes.Buffer
+	buf.Grow(retryFileHeaderSize + len(payload.Encoding) + len(payload.Encoded) + retryFileTrailerSize)
+	buf.WriteString(retryFileMagic)
+	buf.WriteByte(retryFileVersion)
+	_ = binary.Write(&buf, binary.LittleEndian, created.UnixNano())
+	_ = binary.Write(&buf, binary.LittleEndian, int64(payload.UnencodedSize))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(payload.Messages)))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(len(payload.Encoding)))
+	buf.WriteString(payload.Encoding)
+	buf.Write(payload.Encoded)
+	_ = binary.Write(&buf, binary.LittleEndian, crc32.ChecksumIEEE(buf.Bytes()))
+	return buf.Bytes()
+}
+
+func decodeRetryPayload(data []byte) (*StoredPayload, error) {
+	if len(data) < retryFileHeaderSize+retryFileTrailerSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	body, trailer := data[:len(data)-retryFileTrailerSize], data[len(data)-retryFileTrailerSize:]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(trailer) {
+		return nil, errors.New("checksum mismatch")
+	}
+	if _, err := decodeRetryHeader(body); err != nil {
+		return nil, err
+	}
+	off := len(retryFileMagic) + 1 + 8
+	unencodedSize := int64(binary.LittleEndian.Uint64(body[off:]))
+	off += 8
+	messageCount := int(binary.LittleEndian.Uint32(body[off:]))
+	off += 4
+	encodingLen := int(binary.LittleEndian.Uint16(body[off:]))
+	off += 2
+	if off+encodingLen > len(body) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return &StoredPayload{
+		Payload: &message.Payload{
+			Messages:      []*message.Message{},
+			Encoding:      string(body[off : off+encodingLen]),
+			Encoded:       body[off+encodingLen:],
+			UnencodedSize: int(unencodedSize),
+		},
+		MessageCount: messageCount,
+	}, nil
+}
+
+func decodeRetryHeader(data []byte) (time.Time, error) {
+	if len(data) < len(retryFileMagic)+1+8 {
+		return time.Time{}, io.ErrUnexpectedEOF
+	}
+	if string(data[:len(retryFileMagic)]) != retryFileMagic {
+		return time.Time{}, errors.New("invalid file format")
+	}
+	if version := data[len(retryFileMagic)]; version != retryFileVersion {
+		return time.Time{}, fmt.Errorf("unsupported format version %d", version)
+	}
+	return time.Unix(0, int64(binary.LittleEndian.Uint64(data[len(retryFileMagic)+1:]))), nil
+}
+
+func readRetryFileCreated(path string) (time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	header := make([]byte, len(retryFileMagic)+1+8)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return time.Time{}, err
+	}
+	return decodeRetryHeader(header)
+}
@@ -0,0 +1,247 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package http
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+func newRetryPayload(content string) *message.Payload {
+	return &message.Payload{
+		Messages:      []*message.Message{},
+		Encoded:       []byte(content),
+		Encoding:      "gzip",
+		UnencodedSize: len(content) * 2,
+	}
+}
+
+func TestDiskRetryQueueOrder(t *testing.T) {
+	q, err := NewDiskRetryQueue(t.TempDir(), 1024, "")
+	require.NoError(t, err)
+
+	payload, err := q.Peek()
+	assert.NoError(t, err)
+	assert.Nil(t, payload)
+
+	for _, content := range []string{"a", "b", "c"} {
+		require.NoError(t, q.Store(newRetryPayload(content)))
+	}
+	assert.Equal(t, 3, q.Len())
+
+	for _, content := range []string{"a", "b", "c"} {
+		payload, err := q.Peek()
+		require.NoError(t, err)
+		assert.Equal(t, newRetryPayload(content), payload.Payload)
+		require.NoError(t, q.Remove())
+	}
+	assert.Equal(t, 0, q.Len())
+	assert.Equal(t, int64(0), q.SizeBytes())
+}
+
+func TestDiskRetryQueueReload(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewDiskRetryQueue(dir, 1024, "")
+	require.NoError(t, err)
+	require.NoError(t, q.Store(newRetryPayload("first")))
+	require.NoError(t, q.Store(newRetryPayload("second")))
+
+	// leftovers of an interrupted write are discarded
+	require.NoError(t, os.WriteFile(filepath.Join(dir, retryTempPrefix+"123"), []byte("partial"), 0600))
+
+	q, err = NewDiskRetryQueue(dir, 1024, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, q.Len())
+	require.NoError(t, q.Store(newRetryPayload("third")))
+
+	for _, content := range []string{"first", "second", "third"} {
+		payload, err := q.Peek()
+		require.NoError(t, err)
+		assert.Equal(t, content, string(payload.Encoded))
+		require.NoError(t, q.Remove())
+	}
+	_, err = os.Stat(filepath.Join(dir, retryTempPrefix+"123"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDiskRetryQueueEvictsOldest(t *testing.T) {
+	payloadSize := int64(len(encodeRetryPayload(newRetryPayload("a"), time.Now())))
+	q, err := NewDiskRetryQueue(t.TempDir(), 2*payloadSize, "")
+	require.NoError(t, err)
+
+	for _, content := range []string{"a", "b", "c"} {
+		require.NoError(t, q.Store(newRetryPayload(content)))
+	}
+	assert.Equal(t, 2, q.Len())
+	assert.Equal(t, 2*payloadSize, q.SizeBytes())
+
+	payload, err := q.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(payload.Encoded))
+
+	assert.ErrorIs(t, q.Store(newRetryPayload(strings.Repeat("x", int(2*payloadSize)))), errRetryPayloadTooLarge)
+}
+
+func TestDiskRetryQueueDropsCorruptedPayloads(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewDiskRetryQueue(dir, 1024, "")
+	require.NoError(t, err)
+	require.NoError(t, q.Store(newRetryPayload("corrupted")))
+	require.NoError(t, q.Store(newRetryPayload("valid")))
+
+	path := q.files[0].path
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xFF
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	payload, err := q.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, "valid", string(payload.Encoded))
+	assert.Equal(t, 1, q.Len())
+}
+
+func TestDiskRetryQueueDropsUnreadablePayloads(t *testing.T) {
+	q, err := NewDiskRetryQueue(t.TempDir(), 1024, "")
+	require.NoError(t, err)
+	require.NoError(t, q.Store(newRetryPayload("unreadable")))
+	require.NoError(t, q.Store(newRetryPayload("valid")))
+
+	// reading a directory fails with another error than a missing file
+	path := q.files[0].path
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, os.Mkdir(path, 0700))
+	dropped := metrics.DiskRetryDroppedBytes.Value()
+
+	payload, err := q.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, "valid", string(payload.Encoded))
+	assert.Equal(t, 1, q.Len())
+	assert.Greater(t, metrics.DiskRetryDroppedBytes.Value(), dropped)
+}
+
+func TestDiskRetryQueueMessageCount(t *testing.T) {
+	q, err := NewDiskRetryQueue(t.TempDir(), 1024, "")
+	require.NoError(t, err)
+	stored := newRetryPayload("a")
+	stored.Messages = []*message.Message{{}, {}, {}}
+	require.NoError(t, q.Store(stored))
+
+	payload, err := q.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, 3, payload.MessageCount)
+	assert.Empty(t, payload.Messages)
+}
+
+// failingRetryQueue is a RetryQueue whose first payloads can't be read.
+type failingRetryQueue struct {
+	unreadable int
+	payloads   []*StoredPayload
+}
+
+func (q *failingRetryQueue) Store(payload *message.Payload) error {
+	q.payloads = append(q.payloads, &StoredPayload{Payload: payload, MessageCount: len(payload.Messages)})
+	return nil
+}
+
+func (q *failingRetryQueue) Peek() (*StoredPayload, error) {
+	if q.unreadable > 0 {
+		return nil, errors.New("unreadable")
+	}
+	if len(q.payloads) == 0 {
+		return nil, nil
+	}
+	return q.payloads[0], nil
+}
+
+func (q *failingRetryQueue) Remove() error {
+	if q.unreadable > 0 {
+		q.unreadable--
+	} else if len(q.payloads) > 0 {
+		q.payloads = q.payloads[1:]
+	}
+	return nil
+}
+
+func (q *failingRetryQueue) Len() int {
+	return len(q.payloads)
+}
+
+func TestDestinationStoresPayloadsWhileEndpointIsDown(t *testing.T) {
+	cfg := configmock.New(t)
+	server := NewTestServer(500, cfg)
+	q, err := NewDiskRetryQueue(t.TempDir(), 1024, "")
+	require.NoError(t, err)
+	server.Destination.retryQueue = q
+
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload)
+	server.Destination.Start(input, output, nil)
+
+	// payloads are handed over to the auditor as soon as they are stored
+	for _, content := range []string{"a", "b"} {
+		input <- newRetryPayload(content)
+		<-output
+	}
+	assert.Equal(t, 2, q.Len())
+
+	server.ChangeStatus(200)
+	assert.Eventually(t, func() bool { return q.Len() == 0 }, 30*time.Second, 50*time.Millisecond)
+
+	server.Stop()
+}
+
+func TestDestinationCountsStoredLogsOnceSent(t *testing.T) {
+	cfg := configmock.New(t)
+	server := NewTestServer(500, cfg)
+	q, err := NewDiskRetryQueue(t.TempDir(), 1024, "")
+	require.NoError(t, err)
+	server.Destination.retryQueue = q
+
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload)
+	server.Destination.Start(input, output, nil)
+
+	sent, stored := metrics.LogsSent.Value(), metrics.LogsStoredForRetry.Value()
+	payload := newRetryPayload("a")
+	payload.Messages = []*message.Message{{}, {}}
+	input <- payload
+	<-output
+	assert.Equal(t, sent, metrics.LogsSent.Value())
+	assert.Equal(t, stored+2, metrics.LogsStoredForRetry.Value())
+
+	server.ChangeStatus(200)
+	assert.Eventually(t, func() bool { return q.Len() == 0 }, 30*time.Second, 50*time.Millisecond)
+	assert.Equal(t, sent+2, metrics.LogsSent.Value())
+
+	server.Stop()
+}
+
+func TestDestinationDropsUnreadableStoredPayloads(t *testing.T) {
+	cfg := configmock.New(t)
+	server := NewTestServer(200, cfg)
+	q := &failingRetryQueue{unreadable: 1}
+	require.NoError(t, q.Store(newRetryPayload("valid")))
+	server.Destination.retryQueue = q
+
+	// the payloads stored after the unreadable one are sent
+	server.Destination.flushRetryQueue(make(chan bool, 1))
+	assert.Equal(t, 0, q.unreadable)
+	assert.Equal(t, 0, q.Len())
+
+	server.Stop()
+}
@@ -0,0 +1,174 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/tailers/syslog"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/startstop"
+)
+
+// A SyslogListener receives syslog messages over TCP, TLS or UDP and delegates their
+// parsing to a syslog tailer, one per TCP connection.
+type SyslogListener struct {
+	pipelineProvider pipeline.Provider
+	source           *sources.LogSource
+	frameSize        int
+	maxMessageSize   int
+	idleTimeout      time.Duration
+	listener         net.Listener
+	packetConn       net.PacketConn
+	tailers          []*tailer.Tailer
+	mu               sync.Mutex
+	stopped          bool
+}
+
+// NewSyslogListener returns an initialized SyslogListener. UDP messages larger than frameSize
+// and TCP messages larger than logs_config.max_message_size_bytes are truncated.
+func NewSyslogListener(pipelineProvider pipeline.Provider, source *sources.LogSource, frameSize int) *SyslogListener {
+	var idleTimeout time.Duration
+	if source.Config.IdleTimeout != "" {
+		var err error
+		idleTimeout, err = time.ParseDuration(source.Config.IdleTimeout)
+		if err != nil {
+			log.Errorf("Error parsing log's idle_timeout as a duration: %s", err)
+			idleTimeout = 0
+		}
+	}
+	return &SyslogListener{
+		pipelineProvider: pipelineProvider,
+		source:           source,
+		frameSize:        frameSize,
+		maxMessageSize:   max(frameSize, pkgconfigsetup.Datadog().GetInt("logs_config.max_message_size_bytes")),
+		idleTimeout:      idleTimeout,
+	}
+}
+
+// Start starts listening for syslog messages.
+func (l *SyslogListener) Start() {
+	protocol := l.source.Config.SyslogProtocol()
+	log.Infof("Starting syslog server on %s port %d", protocol, l.source.Config.Port)
+	var err error
+	if protocol == config.UDPType {
+		err = l.startUDP()
+	} else {
+		err = l.startTCP()
+	}
+	if err != nil {
+		log.Errorf("Can't start syslog server on %s port %d: %v", protocol, l.source.Config.Port, err)
+		l.source.Status.Error(err)
+		return
+	}
+	l.source.Status.Success()
+}
+
+// Stop stops accepting new connections and stops all the tailers.
+func (l *SyslogListener) Stop() {
+	log.Infof("Stopping syslog server on port %d", l.source.Config.Port)
+	l.mu.Lock()
+	l.stopped = true
+	if l.listener != nil {
+		l.listener.Close()
+	}
+	tailers := l.tailers
+	l.tailers = nil
+	l.mu.Unlock()
+
+	stopper := startstop.NewParallelStopper()
+	for _, t := range tailers {
+		stopper.Add(t)
+	}
+	stopper.Stop()
+}
+
+func (l *SyslogListener) startUDP() error {
+	conn, err := net.ListenPacket("udp", fmt.Sprintf(":%d", l.source.Config.Port))
+	if err != nil {
+		return err
+	}
+	l.packetConn = conn
+	l.addTailer(tailer.NewTailer(l.source, conn, tailer.NewDatagramReadFunc(conn, l.frameSize), l.pipelineProvider.NextPipelineChan()))
+	return nil
+}
+
+func (l *SyslogListener) startTCP() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", l.source.Config.Port))
+	if err != nil {
+		return err
+	}
+	if l.source.Config.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(l.source.Config.TLSCertFile, l.source.Config.TLSKeyFile)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		listener = tls.NewListener(listener, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+	}
+	l.listener = listener
+	go l.accept(listener)
+	return nil
+}
+
+// accept accepts new TCP connections and creates a dedicated tailer for each.
+func (l *SyslogListener) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !isClosedConnError(err) {
+				log.Warnf("Can't accept syslog connections on port %d: %v", l.source.Config.Port, err)
+				l.source.Status.Error(err)
+			}
+			return
+		}
+		t := tailer.NewTailer(l.source, conn, tailer.NewStreamReadFunc(conn, l.maxMessageSize, l.idleTimeout), l.pipelineProvider.NextPipelineChan())
+		if !l.addTailer(t) {
+			conn.Close()
+			return
+		}
+		l.source.Status.Success()
+		go l.removeWhenDone(t)
+	}
+}
+
+// addTailer starts and tracks a tailer, unless the listener was stopped.
+func (l *SyslogListener) addTailer(t *tailer.Tailer) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopped {
+		return false
+	}
+	l.tailers = append(l.tailers, t)
+	t.Start()
+	return true
+}
+
+// removeWhenDone stops tracking a tailer once its connection is closed.
+func (l *SyslogListener) removeWhenDone(t *tailer.Tailer) {
+	<-t.Done()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, tailer := range l.tailers {
+		if tailer == t {
+			l.tailers = append(l.tailers[:i], l.tailers[i+1:]...)
+			// release the connection, the tailer already stopped reading from it
+			go t.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestSyslogTCPShouldReceiveMessages(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	processRawMessage := false
+	listener := NewSyslogListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, ProcessRawMessage: &processRawMessage}), 9000)
+	listener.Start()
+
+	conn, err := net.Dial("tcp", listener.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	fmt.Fprint(conn, "<13>Oct 11 22:14:15 host app: first\n")
+	fmt.Fprint(conn, "29 <12>1 - host app - - - second")
+	var msg *message.Message
+	msg = <-msgChan
+	assert.Equal(t, "first", string(msg.GetContent()))
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	msg = <-msgChan
+	assert.Equal(t, "second", string(msg.GetContent()))
+	assert.Equal(t, message.StatusWarning, msg.Status)
+
+	listener.Stop()
+}
+
+func TestSyslogUDPShouldReceiveMessages(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	processRawMessage := false
+	listener := NewSyslogListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Protocol: config.UDPType, ProcessRawMessage: &processRawMessage}), 9000)
+	listener.Start()
+
+	conn, err := net.Dial("udp", listener.packetConn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	fmt.Fprint(conn, "<11>1 - host app - - [meta key=\"value\"] hello")
+	msg := <-msgChan
+	assert.Equal(t, "hello", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.Contains(t, msg.Origin.Tags(nil), "key:value")
+
+	listener.Stop()
+}
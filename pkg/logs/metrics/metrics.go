@@ -70,6 +70,27 @@ var (
 		nil, "Histogram of http sender latency in ms", []float64{10, 25, 50, 75, 100, 250, 500, 1000, 10000})
 	// DestinationExpVars a map of sender utilization metrics for each http destination
 	DestinationExpVars = expvar.Map{}
+	// LogsStoredForRetry is the total number of logs stored in the on-disk retry queues to be sent later on
+	LogsStoredForRetry = expvar.Int{}
+	// TlmLogsStoredForRetry is the total number of logs stored in the on-disk retry queues to be sent later on
+	TlmLogsStoredForRetry = telemetry.NewCounter("logs", "stored_for_retry",
+		nil, "Total number of logs stored in the on-disk retry queues to be sent later on")
+	// DiskRetryQueues a map of on-disk retry queue metrics for each http destination
+	DiskRetryQueues = expvar.Map{}
+	// DiskRetryDroppedBytes is the total number of bytes dropped from the on-disk retry queues
+	DiskRetryDroppedBytes = expvar.Int{}
+	// TlmDiskRetryDroppedBytes is the total number of bytes dropped from the on-disk retry queues
+	TlmDiskRetryDroppedBytes = telemetry.NewCounter("logs", "disk_retry_dropped_bytes",
+		[]string{"destination", "reason"}, "Total number of bytes dropped from the on-disk retry queue")
+	// TlmDiskRetrySizeBytes is the current size of the on-disk retry queue in bytes
+	TlmDiskRetrySizeBytes = telemetry.NewGauge("logs", "disk_retry_size_bytes",
+		[]string{"destination"}, "Current size of the on-disk retry queue in bytes")
+	// TlmDiskRetryPayloads is the current number of payloads in the on-disk retry queue
+	TlmDiskRetryPayloads = telemetry.NewGauge("logs", "disk_retry_payloads",
+		[]string{"destination"}, "Current number of payloads in the on-disk retry queue")
+	// TlmDiskRetryOldestEntryAge is the age in seconds of the oldest payload in the on-disk retry queue
+	TlmDiskRetryOldestEntryAge = telemetry.NewGauge("logs", "disk_retry_oldest_entry_age",
+		[]string{"destination"}, "Age in seconds of the oldest payload in the on-disk retry queue")
 	// TODO: Add LogsCollected for the total number of collected logs.
 	//nolint:revive // TODO(AML) Fix revive linter
 	DestinationHttpRespByStatusAndUrl = expvar.Map{}
@@ -107,4 +128,7 @@ func init() {
 	LogsExpvars.Set("BytesMissed", &BytesMissed)
 	LogsExpvars.Set("SenderLatency", &SenderLatency)
 	LogsExpvars.Set("HttpDestinationStats", &DestinationExpVars)
+	LogsExpvars.Set("LogsStoredForRetry", &LogsStoredForRetry)
+	LogsExpvars.Set("DiskRetryQueues", &DiskRetryQueues)
+	LogsExpvars.Set("DiskRetryDroppedBytes", &DiskRetryDroppedBytes)
 }
@@ -8,7 +8,9 @@ package pipeline
 
 import (
 	"context"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
@@ -26,6 +28,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/logs/status/statusinterface"
 	compressioncommon "github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // Pipeline processes and sends messages to the backend
@@ -136,7 +139,7 @@ func getDestinations(endpoints *config.Endpoints, destinationsContext *client.De
 			if serverless {
 				reliable = append(reliable, http.NewSyncDestination(endpoint, http.JSONContentType, destinationsContext, senderDoneChan, destMeta, cfg))
 			} else {
-				reliable = append(reliable, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, true, destMeta, cfg, pipelineMonitor))
+				reliable = append(reliable, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, true, destMeta, cfg, pipelineMonitor, getRetryQueue(endpoint, destMeta, cfg)))
 			}
 		}
 		for i, endpoint := range endpoints.GetUnReliableEndpoints() {
@@ -144,7 +147,7 @@ func getDestinations(endpoints *config.Endpoints, destinationsContext *client.De
 			if serverless {
 				additionals = append(additionals, http.NewSyncDestination(endpoint, http.JSONContentType, destinationsContext, senderDoneChan, destMeta, cfg))
 			} else {
-				additionals = append(additionals, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, false, destMeta, cfg, pipelineMonitor))
+				additionals = append(additionals, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, false, destMeta, cfg, pipelineMonitor, nil))
 			}
 		}
 		return client.NewDestinations(reliable, additionals)
@@ -159,6 +162,34 @@ func getDestinations(endpoints *config.Endpoints, destinationsContext *client.De
 	return client.NewDestinations(reliable, additionals)
 }
 
+// getRetryQueue returns the on-disk retry queue of a reliable HTTP destination, or nil when it is disabled.
+// Each destination gets its own directory so that stored payloads are only sent to the endpoint they were meant for.
+func getRetryQueue(endpoint config.Endpoint, destMeta *client.DestinationMetadata, cfg pkgconfigmodel.Reader) http.RetryQueue {
+	if !cfg.GetBool("logs_config.disk_retry.enabled") {
+		return nil
+	}
+	root := cfg.GetString("logs_config.disk_retry.path")
+	if root == "" {
+		root = filepath.Join(cfg.GetString("logs_config.run_path"), "retry")
+	}
+	dir := filepath.Join(root, sanitizeDirName(destMeta.TelemetryName()+"_"+endpoint.Host))
+	queue, err := http.NewDiskRetryQueue(dir, cfg.GetInt64("logs_config.disk_retry.max_size_bytes"), destMeta.TelemetryName())
+	if err != nil {
+		log.Warnf("Could not create the retry queue for %s, retrying in memory: %v", endpoint.Host, err)
+		return nil
+	}
+	return queue
+}
+
+func sanitizeDirName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
 //nolint:revive // TODO(AML) Fix revive linter
 func getStrategy(
 	inputChan chan *message.Message,
@@ -173,6 +173,12 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 	switch c.Type {
 	case config.TCPType, config.UDPType:
 		dictionary["Port"] = c.Port
+	case config.SyslogType:
+		dictionary["Port"] = c.Port
+		dictionary["Protocol"] = c.SyslogProtocol()
+		if c.TLSCertFile != "" {
+			dictionary["TLS"] = true
+		}
 	case config.FileType:
 		dictionary["Path"] = c.Path
 		dictionary["TailingMode"] = c.TailingMode
@@ -209,9 +215,43 @@ func (b *Builder) getMetricsStatus() map[string]string {
 	metrics["RetryCount"] = fmt.Sprintf("%v", b.logsExpVars.Get("RetryCount").(*expvar.Int).Value())
 	metrics["RetryTimeSpent"] = time.Duration(b.logsExpVars.Get("RetryTimeSpent").(*expvar.Int).Value()).String()
 	metrics["EncodedBytesSent"] = fmt.Sprintf("%v", b.logsExpVars.Get("EncodedBytesSent").(*expvar.Int).Value())
+	b.addDiskRetryStatus(metrics)
 	return metrics
 }
 
+// addDiskRetryStatus aggregates the metrics of the on-disk retry queues, if any.
+func (b *Builder) addDiskRetryStatus(metrics map[string]string) {
+	queues, ok := b.logsExpVars.Get("DiskRetryQueues").(*expvar.Map)
+	if !ok {
+		return
+	}
+	var count, size, oldest int64
+	queues.Do(func(kv expvar.KeyValue) {
+		queue, ok := kv.Value.(*expvar.Map)
+		if !ok {
+			return
+		}
+		count++
+		if v, ok := queue.Get("SizeBytes").(*expvar.Int); ok {
+			size += v.Value()
+		}
+		if v, ok := queue.Get("OldestEntryTimestamp").(*expvar.Int); ok && v.Value() > 0 && (oldest == 0 || v.Value() < oldest) {
+			oldest = v.Value()
+		}
+	})
+	if count == 0 {
+		return
+	}
+	metrics["DiskRetrySizeBytes"] = fmt.Sprintf("%v", size)
+	metrics["DiskRetryDroppedBytes"] = fmt.Sprintf("%v", b.logsExpVars.Get("DiskRetryDroppedBytes").(*expvar.Int).Value())
+	metrics["LogsStoredForRetry"] = fmt.Sprintf("%v", b.logsExpVars.Get("LogsStoredForRetry").(*expvar.Int).Value())
+	age := time.Duration(0)
+	if oldest > 0 {
+		age = time.Since(time.Unix(oldest, 0)).Truncate(time.Second)
+	}
+	metrics["DiskRetryOldestEntryAge"] = age.String()
+}
+
 func (b *Builder) getProcessFileStats() map[string]uint64 {
 	stats := make(map[string]uint64)
 	fs, err := procfilestats.GetProcessFileStats()
@@ -6,6 +6,7 @@
 package status
 
 import (
+	"expvar"
 	"fmt"
 	"math"
 	"testing"
@@ -96,13 +97,13 @@ func TestStatusDeduplicateErrorsAndWarnings(t *testing.T) {
 func TestMetrics(t *testing.T) {
 	defer Clear()
 	Clear()
-	var expected = `{"BytesMissed": 0, "BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "EncodedBytesSent": 0, "Errors": "", "HttpDestinationStats": {}, "IsRunning": false, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0, "RetryCount": 0, "RetryTimeSpent": 0, "SenderLatency": 0, "Warnings": ""}`
+	var expected = `{"BytesMissed": 0, "BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "DiskRetryDroppedBytes": 0, "DiskRetryQueues": {}, "EncodedBytesSent": 0, "Errors": "", "HttpDestinationStats": {}, "IsRunning": false, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0, "LogsStoredForRetry": 0, "RetryCount": 0, "RetryTimeSpent": 0, "SenderLatency": 0, "Warnings": ""}`
 	assert.Equal(t, expected, metrics.LogsExpvars.String())
 
 	initStatus()
 	AddGlobalWarning("bar", "Unique Warning")
 	AddGlobalError("bar", "I am an error")
-	expected = `{"BytesMissed": 0, "BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "EncodedBytesSent": 0, "Errors": "I am an error", "HttpDestinationStats": {}, "IsRunning": true, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0, "RetryCount": 0, "RetryTimeSpent": 0, "SenderLatency": 0, "Warnings": "Unique Warning"}`
+	expected = `{"BytesMissed": 0, "BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "DiskRetryDroppedBytes": 0, "DiskRetryQueues": {}, "EncodedBytesSent": 0, "Errors": "I am an error", "HttpDestinationStats": {}, "IsRunning": true, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0, "LogsStoredForRetry": 0, "RetryCount": 0, "RetryTimeSpent": 0, "SenderLatency": 0, "Warnings": "Unique Warning"}`
 	assert.Equal(t, expected, metrics.LogsExpvars.String())
 }
 
@@ -139,6 +140,37 @@ func TestStatusMetrics(t *testing.T) {
 	assert.Equal(t, fmt.Sprintf("%v", math.MinInt64), status.StatusMetrics["LogsProcessed"])
 }
 
+func TestStatusMetricsDiskRetry(t *testing.T) {
+	defer Clear()
+	initStatus()
+
+	status := Get(false)
+	assert.NotContains(t, status.StatusMetrics, "DiskRetrySizeBytes")
+
+	newQueue := func(size, oldest int64) *expvar.Map {
+		queue := &expvar.Map{}
+		queue.Add("SizeBytes", size)
+		queue.Add("OldestEntryTimestamp", oldest)
+		return queue
+	}
+	metrics.DiskRetryQueues.Set("first", newQueue(100, time.Now().Add(-time.Hour).Unix()))
+	metrics.DiskRetryQueues.Set("second", newQueue(50, 0))
+	metrics.DiskRetryDroppedBytes.Set(10)
+	metrics.LogsStoredForRetry.Set(3)
+	defer func() {
+		metrics.DiskRetryQueues.Delete("first")
+		metrics.DiskRetryQueues.Delete("second")
+		metrics.DiskRetryDroppedBytes.Set(0)
+		metrics.LogsStoredForRetry.Set(0)
+	}()
+
+	status = Get(false)
+	assert.Equal(t, "150", status.StatusMetrics["DiskRetrySizeBytes"])
+	assert.Equal(t, "10", status.StatusMetrics["DiskRetryDroppedBytes"])
+	assert.Equal(t, "3", status.StatusMetrics["LogsStoredForRetry"])
+	assert.Equal(t, "1h0m0s", status.StatusMetrics["DiskRetryOldestEntryAge"])
+}
+
 func TestStatusEndpoints(t *testing.T) {
 	defer Clear()
 	initStatus()
@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// maxFrameLengthDigits bounds the length prefix of octet-counted frames.
+const maxFrameLengthDigits = 10
+
+// ReadFrame reads the next message from a stream, as described in RFC 6587. Frames
+// starting with a digit use octet counting ("LEN SP MSG"), any other frame uses
+// non-transparent framing and ends with a line feed. Messages larger than maxSize
+// are truncated to maxSize bytes.
+func ReadFrame(r *bufio.Reader, maxSize int) ([]byte, error) {
+	first, err := r.Peek(1)
+	// some senders terminate octet-counted frames with a line feed too
+	for err == nil && (first[0] == '\n' || first[0] == '\r') {
+		_, _ = r.ReadByte()
+		first, err = r.Peek(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		return readOctetCountedFrame(r, maxSize)
+	}
+	return readLine(r, maxSize)
+}
+
+func readOctetCountedFrame(r *bufio.Reader, maxSize int) ([]byte, error) {
+	var digits []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if c == ' ' {
+			break
+		}
+		if c < '0' || c > '9' || len(digits) == maxFrameLengthDigits {
+			return nil, fmt.Errorf("invalid octet count %q", append(digits, c))
+		}
+		digits = append(digits, c)
+	}
+	length, err := strconv.Atoi(string(digits))
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, min(length, maxSize))
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	if length > len(frame) {
+		if _, err := r.Discard(length - len(frame)); err != nil {
+			return nil, err
+		}
+	}
+	return frame, nil
+}
+
+func readLine(r *bufio.Reader, maxSize int) ([]byte, error) {
+	var frame []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if len(frame) < maxSize {
+			frame = append(frame, chunk[:min(len(chunk), maxSize-len(frame))]...)
+		}
+		switch err {
+		case nil:
+			return frame, nil
+		case bufio.ErrBufferFull:
+			// the line is longer than the buffer, keep reading until its end
+			continue
+		case io.EOF:
+			if len(frame) > 0 {
+				// the last message is not followed by a line feed
+				return frame, nil
+			}
+			return nil, err
+		default:
+			return nil, err
+		}
+	}
+}
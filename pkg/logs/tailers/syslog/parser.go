@@ -0,0 +1,263 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// nilValue is used by RFC 5424 for header fields and structured data that are not set.
+const nilValue = "-"
+
+// rfc3164TimestampLayout is the BSD syslog timestamp, e.g. "Oct 11 22:14:15".
+const rfc3164TimestampLayout = time.Stamp
+
+var (
+	errNoPriority      = errors.New("missing priority")
+	errInvalidPriority = errors.New("invalid priority")
+	errInvalidHeader   = errors.New("invalid header")
+	errInvalidSD       = errors.New("invalid structured data")
+)
+
+// severityStatuses maps syslog severities to log statuses.
+var severityStatuses = [...]string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// StructuredDataElement is an RFC 5424 structured data element, e.g.
+// [exampleSDID@32473 iut="3" eventSource="Application"].
+type StructuredDataElement struct {
+	ID     string
+	Params []StructuredDataParam
+}
+
+// StructuredDataParam is a name-value pair of a structured data element.
+type StructuredDataParam struct {
+	Name  string
+	Value string
+}
+
+// Message is a parsed syslog message. Header fields which were not set are empty.
+type Message struct {
+	// Version is 0 for RFC 3164 messages.
+	Version        int
+	Facility       int
+	Severity       int
+	Timestamp      string
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData []StructuredDataElement
+	Msg            []byte
+}
+
+// Status returns the log status matching the severity of the message.
+func (m *Message) Status() string {
+	return severityStatuses[m.Severity]
+}
+
+// Parse parses an RFC 5424 or an RFC 3164 message. RFC 3164 is loosely specified,
+// hence only the priority is mandatory for such messages and the fields that cannot
+// be found are left empty.
+func Parse(data []byte) (*Message, error) {
+	data = bytes.TrimRight(data, "\r\n\x00")
+	pri, rest, err := parsePriority(data)
+	if err != nil {
+		return nil, err
+	}
+	msg := &Message{
+		Facility: pri / 8,
+		Severity: pri % 8,
+	}
+	// RFC 5424 messages have a version right after the priority, followed by a space
+	if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' {
+		if sp := bytes.IndexByte(rest, ' '); sp > 0 && sp <= 3 {
+			if version, err := strconv.Atoi(string(rest[:sp])); err == nil {
+				msg.Version = version
+				return msg, parseRFC5424(msg, rest[sp+1:])
+			}
+		}
+	}
+	parseRFC3164(msg, rest)
+	return msg, nil
+}
+
+// parsePriority parses the "<PRI>" part of a message.
+func parsePriority(data []byte) (int, []byte, error) {
+	if len(data) == 0 || data[0] != '<' {
+		return 0, nil, errNoPriority
+	}
+	end := bytes.IndexByte(data, '>')
+	if end < 2 || end > 4 {
+		return 0, nil, errInvalidPriority
+	}
+	pri, err := strconv.Atoi(string(data[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, nil, errInvalidPriority
+	}
+	return pri, data[end+1:], nil
+}
+
+// parseRFC5424 parses what follows the version of an RFC 5424 message:
+// TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP STRUCTURED-DATA [SP MSG]
+func parseRFC5424(msg *Message, data []byte) error {
+	fields := make([]string, 5)
+	for i := range fields {
+		sp := bytes.IndexByte(data, ' ')
+		if sp <= 0 {
+			return errInvalidHeader
+		}
+		if field := string(data[:sp]); field != nilValue {
+			fields[i] = field
+		}
+		data = data[sp+1:]
+	}
+	msg.Timestamp, msg.Hostname, msg.AppName, msg.ProcID, msg.MsgID = fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	sd, rest, err := parseStructuredData(data)
+	if err != nil {
+		return err
+	}
+	msg.StructuredData = sd
+	if len(rest) > 0 {
+		if rest[0] != ' ' {
+			return errInvalidSD
+		}
+		rest = rest[1:]
+	}
+	msg.Msg = bytes.TrimPrefix(rest, []byte("\xEF\xBB\xBF"))
+	return nil
+}
+
+// parseStructuredData parses the STRUCTURED-DATA part of an RFC 5424 message and returns
+// what follows it.
+func parseStructuredData(data []byte) ([]StructuredDataElement, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, errInvalidSD
+	}
+	if data[0] == '-' {
+		return nil, data[1:], nil
+	}
+	var elements []StructuredDataElement
+	for len(data) > 0 && data[0] == '[' {
+		data = data[1:]
+		end := bytes.IndexAny(data, " ]")
+		if end <= 0 {
+			return nil, nil, errInvalidSD
+		}
+		element := StructuredDataElement{ID: string(data[:end])}
+		data = data[end:]
+		for len(data) > 0 && data[0] == ' ' {
+			data = data[1:]
+			eq := bytes.IndexByte(data, '=')
+			if eq <= 0 || eq+1 >= len(data) || data[eq+1] != '"' {
+				return nil, nil, errInvalidSD
+			}
+			name := string(data[:eq])
+			value, n, err := parseParamValue(data[eq+2:])
+			if err != nil {
+				return nil, nil, err
+			}
+			element.Params = append(element.Params, StructuredDataParam{Name: name, Value: value})
+			data = data[eq+2+n:]
+		}
+		if len(data) == 0 || data[0] != ']' {
+			return nil, nil, errInvalidSD
+		}
+		data = data[1:]
+		elements = append(elements, element)
+	}
+	if elements == nil {
+		return nil, nil, errInvalidSD
+	}
+	return elements, data, nil
+}
+
+// parseParamValue parses a quoted parameter value, starting right after the opening
+// quote. It returns the unescaped value and the number of bytes consumed, including
+// the closing quote.
+func parseParamValue(data []byte) (string, int, error) {
+	var value []byte
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			// only '"', '\' and ']' are escaped, other backslashes are kept as is
+			if i+1 < len(data) && (data[i+1] == '"' || data[i+1] == '\\' || data[i+1] == ']') {
+				i++
+			}
+			value = append(value, data[i])
+		case '"':
+			return string(value), i + 1, nil
+		default:
+			value = append(value, data[i])
+		}
+	}
+	return "", 0, errInvalidSD
+}
+
+// parseRFC3164 parses what follows the priority of an RFC 3164 message:
+// TIMESTAMP SP HOSTNAME SP TAG[PID]: MSG
+func parseRFC3164(msg *Message, data []byte) {
+	if len(data) >= len(rfc3164TimestampLayout) {
+		if _, err := time.Parse(rfc3164TimestampLayout, string(data[:len(rfc3164TimestampLayout)])); err == nil {
+			msg.Timestamp = string(data[:len(rfc3164TimestampLayout)])
+			data = bytes.TrimLeft(data[len(rfc3164TimestampLayout):], " ")
+		}
+	}
+	if msg.Timestamp == "" {
+		// some senders use an RFC 3339 timestamp instead
+		if sp := bytes.IndexByte(data, ' '); sp > 0 {
+			if _, err := time.Parse(time.RFC3339Nano, string(data[:sp])); err == nil {
+				msg.Timestamp = string(data[:sp])
+				data = data[sp+1:]
+			}
+		}
+	}
+
+	// the hostname is only present with a timestamp, and cannot be mistaken for a tag
+	if msg.Timestamp != "" {
+		if sp := bytes.IndexByte(data, ' '); sp > 0 && bytes.IndexAny(data[:sp], ":[") < 0 {
+			msg.Hostname = string(data[:sp])
+			data = data[sp+1:]
+		}
+	}
+
+	// TAG is made of alphanumeric characters, the content starts with any other character
+	end := 0
+	for end < len(data) && end < 48 && isTagChar(data[end]) {
+		end++
+	}
+	if end > 0 && end < len(data) && (data[end] == '[' || data[end] == ':') {
+		msg.AppName = string(data[:end])
+		rest := data[end:]
+		if rest[0] == '[' {
+			if closing := bytes.IndexByte(rest, ']'); closing > 0 {
+				msg.ProcID = string(rest[1:closing])
+				rest = rest[closing+1:]
+			}
+		}
+		rest = bytes.TrimPrefix(rest, []byte(":"))
+		data = bytes.TrimPrefix(rest, []byte(" "))
+	}
+	msg.Msg = data
+}
+
+func isTagChar(c byte) bool {
+	return c != ' ' && c != ':' && c != '[' && c > 32 && c < 127
+}
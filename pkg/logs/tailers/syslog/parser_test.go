@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	msg, err := Parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application"][meta seq="a \"quoted\" \] value"] ` + "\xEF\xBB\xBF" + "An application event log entry...\n"))
+	require.NoError(t, err)
+	assert.Equal(t, &Message{
+		Version:   1,
+		Facility:  20,
+		Severity:  5,
+		Timestamp: "2003-10-11T22:14:15.003Z",
+		Hostname:  "mymachine.example.com",
+		AppName:   "evntslog",
+		MsgID:     "ID47",
+		StructuredData: []StructuredDataElement{
+			{ID: "exampleSDID@32473", Params: []StructuredDataParam{{"iut", "3"}, {"eventSource", "Application"}}},
+			{ID: "meta", Params: []StructuredDataParam{{"seq", `a "quoted" ] value`}}},
+		},
+		Msg: []byte("An application event log entry..."),
+	}, msg)
+	assert.Equal(t, message.StatusNotice, msg.Status())
+}
+
+func TestParseRFC5424NilValues(t *testing.T) {
+	msg, err := Parse([]byte(`<34>1 - - - - - -`))
+	require.NoError(t, err)
+	assert.Equal(t, &Message{Version: 1, Facility: 4, Severity: 2, Msg: []byte{}}, msg)
+	assert.Equal(t, message.StatusCritical, msg.Status())
+
+	msg, err = Parse([]byte(`<14>1 2024-01-01T00:00:00Z host app 1234 - [origin ip="10.0.0.1"]`))
+	require.NoError(t, err)
+	assert.Equal(t, "1234", msg.ProcID)
+	assert.Empty(t, msg.Msg)
+	assert.Equal(t, []StructuredDataElement{{ID: "origin", Params: []StructuredDataParam{{"ip", "10.0.0.1"}}}}, msg.StructuredData)
+}
+
+func TestParseRFC3164(t *testing.T) {
+	for _, tt := range []struct {
+		in       string
+		expected Message
+	}{
+		{
+			in:       `<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8`,
+			expected: Message{Facility: 4, Severity: 2, Timestamp: "Oct 11 22:14:15", Hostname: "mymachine", AppName: "su", Msg: []byte("'su root' failed for lonvick on /dev/pts/8")},
+		},
+		{
+			in:       `<13>Feb  5 17:32:18 10.0.0.99 sshd[4123]: Accepted publickey for root`,
+			expected: Message{Facility: 1, Severity: 5, Timestamp: "Feb  5 17:32:18", Hostname: "10.0.0.99", AppName: "sshd", ProcID: "4123", Msg: []byte("Accepted publickey for root")},
+		},
+		{
+			in:       `<30>2024-03-01T10:00:00.123+01:00 web01 nginx[12]: GET /`,
+			expected: Message{Facility: 3, Severity: 6, Timestamp: "2024-03-01T10:00:00.123+01:00", Hostname: "web01", AppName: "nginx", ProcID: "12", Msg: []byte("GET /")},
+		},
+		{
+			in:       `<15>cron[42]: job done`,
+			expected: Message{Facility: 1, Severity: 7, AppName: "cron", ProcID: "42", Msg: []byte("job done")},
+		},
+		{
+			in:       `<11>just some text`,
+			expected: Message{Facility: 1, Severity: 3, Msg: []byte("just some text")},
+		},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			msg, err := Parse([]byte(tt.in))
+			require.NoError(t, err)
+			assert.Equal(t, &tt.expected, msg)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{
+		``,
+		`no priority`,
+		`<>1 - - - - - -`,
+		`<192>invalid facility`,
+		`<1a>bad`,
+		`<34>1 2003-10-11T22:14:15.003Z host`,
+		`<34>1 - - - - - [unterminated`,
+		`<34>1 - - - - - [id key="value]`,
+		`<34>1 - - - - - [id key=value]`,
+		`<34>1 - - - - - no structured data`,
+	} {
+		t.Run(in, func(t *testing.T) {
+			_, err := Parse([]byte(in))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("11 <13>1 - - -\n<14>line two\n15 <13>framed\nwith\r\n<15>last without lf"))
+	var frames []string
+	for {
+		frame, err := ReadFrame(r, 1024)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		frames = append(frames, string(frame))
+	}
+	assert.Equal(t, []string{"<13>1 - - -", "<14>line two\n", "<13>framed\nwith", "<15>last without lf"}, frames)
+}
+
+func TestReadFrameTruncates(t *testing.T) {
+	r := bufio.NewReaderSize(strings.NewReader("20 <13>0123456789abcdef"+strings.Repeat("x", 50)+"\n<14>next\n"), 16)
+	frame, err := ReadFrame(r, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "<13>012345", string(frame))
+
+	frame, err = ReadFrame(r, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "xxxxxxxxxx", string(frame))
+
+	frame, err = ReadFrame(r, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "<14>next\n", string(frame))
+}
+
+func TestReadFrameInvalidOctetCount(t *testing.T) {
+	_, err := ReadFrame(bufio.NewReader(strings.NewReader("12a <13>msg")), 1024)
+	assert.Error(t, err)
+}
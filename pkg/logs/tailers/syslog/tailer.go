@@ -0,0 +1,197 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package syslog implements a tailer for syslog messages received over the network.
+package syslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// ReadFunc returns the next syslog message and the address of its sender.
+type ReadFunc func() ([]byte, string, error)
+
+// Tailer reads syslog messages from a connection, parses them and forwards them
+// as structured messages.
+type Tailer struct {
+	source     *sources.LogSource
+	conn       io.Closer
+	read       ReadFunc
+	outputChan chan *message.Message
+	stop       chan struct{}
+	stopOnce   sync.Once
+	done       chan struct{}
+}
+
+// NewTailer returns a new Tailer reading messages with read. conn is closed when the tailer stops.
+func NewTailer(source *sources.LogSource, conn io.Closer, read ReadFunc, outputChan chan *message.Message) *Tailer {
+	return &Tailer{
+		source:     source,
+		conn:       conn,
+		read:       read,
+		outputChan: outputChan,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// NewStreamReadFunc returns a ReadFunc reading framed messages from a TCP or TLS connection.
+// The connection is closed if no message is received for idleTimeout, if set.
+func NewStreamReadFunc(conn net.Conn, maxSize int, idleTimeout time.Duration) ReadFunc {
+	reader := bufio.NewReader(conn)
+	return func() ([]byte, string, error) {
+		if idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(idleTimeout)) //nolint:errcheck
+		}
+		frame, err := ReadFrame(reader, maxSize)
+		return frame, conn.RemoteAddr().String(), err
+	}
+}
+
+// NewDatagramReadFunc returns a ReadFunc reading one message per UDP datagram.
+// Messages larger than maxSize are truncated.
+func NewDatagramReadFunc(conn net.PacketConn, maxSize int) ReadFunc {
+	buf := make([]byte, maxSize)
+	return func() ([]byte, string, error) {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil, "", err
+		}
+		frame := make([]byte, n)
+		copy(frame, buf[:n])
+		return frame, addr.String(), nil
+	}
+}
+
+// Start starts reading messages.
+func (t *Tailer) Start() {
+	go t.run()
+}
+
+// Stop stops the tailer and closes its connection.
+func (t *Tailer) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+		t.conn.Close()
+	})
+	<-t.done
+}
+
+// Done is closed once the tailer stopped reading, either because it was stopped or
+// because its connection was closed.
+func (t *Tailer) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *Tailer) run() {
+	defer close(t.done)
+	for {
+		frame, remoteAddr, err := t.read()
+		if err != nil {
+			select {
+			case <-t.stop:
+			default:
+				if err != io.EOF {
+					log.Warnf("Couldn't read syslog message from connection: %v", err)
+					t.source.Status.Error(err)
+				}
+			}
+			return
+		}
+		if len(frame) == 0 {
+			continue
+		}
+		t.source.RecordBytes(int64(len(frame)))
+		select {
+		case t.outputChan <- t.toMessage(frame, remoteAddr):
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// toMessage converts a raw syslog message to a log message. Messages that cannot be
+// parsed are forwarded as they are.
+func (t *Tailer) toMessage(frame []byte, remoteAddr string) *message.Message {
+	origin := message.NewOrigin(t.source)
+	var tags []string
+	if remoteAddr != "" && pkgconfigsetup.Datadog().GetBool("logs_config.use_sourcehost_tag") {
+		if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+			tags = append(tags, "source_host:"+host)
+		}
+	}
+
+	msg, err := Parse(frame)
+	if err != nil {
+		log.Debugf("Could not parse syslog message, forwarding it as is: %v", err)
+		origin.SetTags(tags)
+		return message.NewMessage(trimFrame(frame), origin, message.StatusInfo, time.Now().UnixNano())
+	}
+
+	for _, element := range msg.StructuredData {
+		for _, param := range element.Params {
+			tags = append(tags, param.Name+":"+param.Value)
+		}
+	}
+	origin.SetTags(tags)
+
+	content := getContent(msg)
+	if t.source.Config.ShouldProcessRawMessage() {
+		rendered, err := json.Marshal(content.Data)
+		if err != nil {
+			log.Error("can't marshal syslog message", err)
+			rendered = msg.Msg
+		}
+		return message.NewMessage(rendered, origin, msg.Status(), time.Now().UnixNano())
+	}
+	return message.NewStructuredMessage(&content, origin, msg.Status(), time.Now().UnixNano())
+}
+
+// getContent builds the structured content of a message: the message itself is
+// stored in "message" and the header fields in a "syslog" attribute.
+func getContent(msg *Message) message.BasicStructuredContent {
+	content := message.BasicStructuredContent{
+		Data: make(map[string]interface{}),
+	}
+	content.SetContent(msg.Msg)
+
+	attributes := map[string]interface{}{
+		"facility": msg.Facility,
+		"severity": msg.Severity,
+	}
+	for key, value := range map[string]string{
+		"timestamp": msg.Timestamp,
+		"hostname":  msg.Hostname,
+		"appname":   msg.AppName,
+		"procid":    msg.ProcID,
+		"msgid":     msg.MsgID,
+	} {
+		if value != "" {
+			attributes[key] = value
+		}
+	}
+	if msg.Version > 0 {
+		attributes["version"] = msg.Version
+	}
+	content.Data["syslog"] = attributes
+	return content
+}
+
+func trimFrame(frame []byte) []byte {
+	for len(frame) > 0 && (frame[len(frame)-1] == '\n' || frame[len(frame)-1] == '\r') {
+		frame = frame[:len(frame)-1]
+	}
+	return frame
+}
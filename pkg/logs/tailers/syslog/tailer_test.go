@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestTailerForwardsParsedMessages(t *testing.T) {
+	processRawMessage := false
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessRawMessage: &processRawMessage})
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	tailer := NewTailer(source, r, NewStreamReadFunc(r, 1024, time.Minute), msgChan)
+	tailer.Start()
+
+	go fmt.Fprint(w, "<11>1 2024-01-01T00:00:00Z web01 api 42 req [ctx env=\"prod\" team=\"core\"] request failed\n")
+	msg := <-msgChan
+	assert.Equal(t, "request failed", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.ElementsMatch(t, []string{"env:prod", "team:core"}, msg.Origin.Tags(nil))
+	rendered, err := msg.Render()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"message":"request failed","syslog":{"facility":1,"severity":3,"version":1,"timestamp":"2024-01-01T00:00:00Z","hostname":"web01","appname":"api","procid":"42","msgid":"req"}}`, string(rendered))
+
+	// messages which are not syslog are forwarded as they are
+	go fmt.Fprint(w, "not a syslog message\n")
+	msg = <-msgChan
+	assert.Equal(t, "not a syslog message", string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.Status)
+
+	tailer.Stop()
+}
+
+func TestTailerProcessesRawMessage(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	tailer := NewTailer(source, r, NewStreamReadFunc(r, 1024, 0), msgChan)
+	tailer.Start()
+
+	go fmt.Fprint(w, "29 <14>Oct 11 22:14:15 host a: b")
+	msg := <-msgChan
+	assert.JSONEq(t, `{"message":"b","syslog":{"facility":1,"severity":6,"timestamp":"Oct 11 22:14:15","hostname":"host","appname":"a"}}`, string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.Status)
+
+	w.Close()
+	<-tailer.Done()
+	tailer.Stop()
+}
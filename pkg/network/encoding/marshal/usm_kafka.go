@@ -12,8 +12,10 @@ import (
 	model "github.com/DataDog/agent-payload/v5/process"
 
 	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/network/protocols"
 	"github.com/DataDog/datadog-agent/pkg/network/protocols/kafka"
 	"github.com/DataDog/datadog-agent/pkg/network/types"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 type kafkaEncoder struct {
@@ -76,6 +78,12 @@ func (e *kafkaEncoder) encodeData(connectionData *USMConnectionData[kafka.Key, *
 							kafkaStatsBuilder.SetLatencies(func(b *bytes.Buffer) {
 								latencies.EncodeProto(b)
 							})
+						} else if withLatency := requestStat.LatencySampleCount(); withLatency > 0 && withLatency != requestStat.Count {
+							// FirstLatencySample is read as the latency of every request counted, a sketch
+							// leaves out the requests without latency, such as produce requests with acks = 0
+							kafkaStatsBuilder.SetLatencies(func(b *bytes.Buffer) {
+								encodeLatencySample(b, requestStat.FirstLatencySample, withLatency)
+							})
 						} else {
 							kafkaStatsBuilder.SetFirstLatencySample(requestStat.FirstLatencySample)
 						}
@@ -88,6 +96,22 @@ func (e *kafkaEncoder) encodeData(connectionData *USMConnectionData[kafka.Key, *
 	return staticTags
 }
 
+// encodeLatencySample encodes a sketch holding count times latency.
+func encodeLatencySample(b *bytes.Buffer, latency float64, count int) {
+	latencies := protocols.SketchesPool.Get()
+	if latencies == nil {
+		return
+	}
+	defer func() {
+		latencies.Clear()
+		protocols.SketchesPool.Put(latencies)
+	}()
+	if err := latencies.AddWithCount(latency, float64(count)); err != nil {
+		log.Debugf("could not add kafka request latency to ddsketch: %v", err)
+	}
+	latencies.EncodeProto(b)
+}
+
 func (e *kafkaEncoder) Close() {
 	if e == nil {
 		return
@@ -128,6 +128,59 @@ func (s *KafkaSuite) TestFormatKafkaStats() {
 	assert.ElementsMatch(t, out.KafkaAggregations, aggregations.KafkaAggregations)
 }
 
+func (s *KafkaSuite) TestFormatKafkaLatencies() {
+	t := s.T()
+
+	produceKey := kafka.NewKey(localhost, localhost, clientPort, serverPort, topicName, kafka.ProduceAPIKey, apiVersion1)
+	fetchKey := kafka.NewKey(localhost, localhost, clientPort, serverPort, topicName, kafka.FetchAPIKey, apiVersion2)
+
+	produceStats := kafka.NewRequestStats()
+	produceStats.AddRequest(0, 10, 0, 10.0)
+	produceStats.AddRequest(0, 10, 0, 30.0)
+	// produce requests sent with acks = 0 have no latency
+	produceStats.AddRequest(0, 5, 0, 0)
+	// a single latency sample along with acks = 0 requests
+	mixedKey := kafka.NewKey(localhost, localhost, clientPort, serverPort, "mixed-topic", kafka.ProduceAPIKey, apiVersion1)
+	mixedStats := kafka.NewRequestStats()
+	mixedStats.AddRequest(0, 4, 0, 20.0)
+	mixedStats.AddRequest(0, 6, 0, 0)
+	fetchStats := kafka.NewRequestStats()
+	fetchStats.AddRequest(0, 3, 0, 50.0)
+
+	in := map[kafka.Key]*kafka.RequestStats{produceKey: produceStats, mixedKey: mixedStats, fetchKey: fetchStats}
+	encoder := newKafkaEncoder(in)
+	t.Cleanup(encoder.Close)
+
+	aggregations := getKafkaAggregations(t, encoder, defaultConnection)
+	require.Len(t, aggregations.KafkaAggregations, 3)
+	for _, aggregation := range aggregations.KafkaAggregations {
+		stats := aggregation.StatsByErrorCode[0]
+		require.NotNil(t, stats)
+		switch aggregation.Header.RequestType {
+		case kafka.ProduceAPIKey:
+			if aggregation.Topic == "mixed-topic" {
+				assert.Equal(t, uint32(10), stats.Count)
+				assert.Zero(t, stats.FirstLatencySample)
+				sketch := unmarshalSketch(t, stats.Latencies)
+				assert.Equal(t, float64(4), sketch.GetCount())
+				verifyQuantile(t, sketch, 0.5, 20.0)
+				continue
+			}
+			assert.Equal(t, uint32(25), stats.Count)
+			sketch := unmarshalSketch(t, stats.Latencies)
+			assert.Equal(t, float64(20), sketch.GetCount())
+			verifyQuantile(t, sketch, 0.0, 10.0)
+			verifyQuantile(t, sketch, 1.0, 30.0)
+		case kafka.FetchAPIKey:
+			assert.Equal(t, uint32(3), stats.Count)
+			assert.Nil(t, stats.Latencies)
+			assert.Equal(t, 50.0, stats.FirstLatencySample)
+		default:
+			t.Fatalf("unexpected request type %d", aggregation.Header.RequestType)
+		}
+	}
+}
+
 func (s *KafkaSuite) TestKafkaIDCollisionRegression() {
 	t := s.T()
 	assert := assert.New(t)
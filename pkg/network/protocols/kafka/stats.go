@@ -62,6 +62,9 @@ type RequestStat struct {
 	// keep-alives where a short-lived TCP connection is used for a single request.
 	FirstLatencySample float64
 	StaticTags         uint64
+	// firstLatencySampleCount is the number of requests FirstLatencySample stands for. It differs from Count
+	// when some of the requests have no latency, such as produce requests sent with acks = 0.
+	firstLatencySampleCount int
 }
 
 func (r *RequestStat) initSketch() error {
@@ -91,7 +94,12 @@ func (r *RequestStats) CombineWith(newStats *RequestStats) {
 
 		if newRequests.Latencies == nil {
 			// In this case, newRequests must have only FirstLatencySample, so use it when adding the request
-			r.AddRequest(statusCode, newRequests.Count, newRequests.StaticTags, newRequests.FirstLatencySample)
+			if withLatency := newRequests.LatencySampleCount(); withLatency > 0 {
+				r.AddRequest(statusCode, withLatency, newRequests.StaticTags, newRequests.FirstLatencySample)
+			}
+			if withoutLatency := newRequests.Count - newRequests.LatencySampleCount(); withoutLatency > 0 {
+				r.AddRequest(statusCode, withoutLatency, newRequests.StaticTags, 0)
+			}
 			continue
 		}
 
@@ -107,7 +115,7 @@ func (r *RequestStats) CombineWith(newStats *RequestStats) {
 
 			// If we have a latency sample in this bucket we now add it to the DDSketch
 			if stats.FirstLatencySample != 0 {
-				err := stats.Latencies.AddWithCount(stats.FirstLatencySample, float64(stats.Count))
+				err := stats.Latencies.AddWithCount(stats.FirstLatencySample, float64(stats.LatencySampleCount()))
 				if err != nil {
 					log.Debugf("could not add kafka request latency to ddsketch: %v", err)
 				}
@@ -122,7 +130,21 @@ func (r *RequestStats) CombineWith(newStats *RequestStats) {
 	}
 }
 
-// AddRequest takes information about a Kafka transaction and adds it to the request stats
+// LatencySampleCount returns the number of requests represented by FirstLatencySample.
+func (r *RequestStat) LatencySampleCount() int {
+	if r.FirstLatencySample == 0 {
+		return 0
+	}
+	if r.firstLatencySampleCount == 0 {
+		// stats built outside of AddRequest only set Count
+		return r.Count
+	}
+	return r.firstLatencySampleCount
+}
+
+// AddRequest takes information about a Kafka transaction and adds it to the request stats.
+// Requests without a latency, such as produce requests sent with acks = 0, are counted but
+// are not part of the latency distribution.
 func (r *RequestStats) AddRequest(errorCode int32, count int, staticTags uint64, latency float64) {
 	if !isValidKafkaErrorCode(errorCode) {
 		return
@@ -132,12 +154,17 @@ func (r *RequestStats) AddRequest(errorCode int32, count int, staticTags uint64,
 		stats = &RequestStat{}
 		r.ErrorCodeToStat[errorCode] = stats
 	}
-	originalCount := stats.Count
+	firstLatencySampleCount := stats.LatencySampleCount()
 	stats.Count += count
 	stats.StaticTags |= staticTags
 
+	if latency <= 0 {
+		return
+	}
+
 	if stats.FirstLatencySample == 0 {
 		stats.FirstLatencySample = latency
+		stats.firstLatencySampleCount = count
 		return
 	}
 
@@ -148,7 +175,7 @@ func (r *RequestStats) AddRequest(errorCode int32, count int, staticTags uint64,
 		}
 
 		// Add the deferred latency sample
-		if err := stats.Latencies.AddWithCount(stats.FirstLatencySample, float64(originalCount)); err != nil {
+		if err := stats.Latencies.AddWithCount(stats.FirstLatencySample, float64(firstLatencySampleCount)); err != nil {
 			log.Debugf("could not add request latency to ddsketch: %v", err)
 		}
 	}
@@ -76,6 +76,56 @@ func TestCombineWith(t *testing.T) {
 	}
 }
 
+func TestAddRequestWithoutLatency(t *testing.T) {
+	testErrorCode := int32(0)
+	stats := NewRequestStats()
+	// produce requests sent with acks = 0 never get a response
+	stats.AddRequest(testErrorCode, 5, 1, 0)
+	stats.AddRequest(testErrorCode, 10, 1, 10.0)
+	stats.AddRequest(testErrorCode, 7, 1, 0)
+
+	s := stats.ErrorCodeToStat[testErrorCode]
+	if assert.NotNil(t, s) {
+		assert.Equal(t, 22, s.Count)
+		assert.Nil(t, s.Latencies)
+		assert.Equal(t, 10.0, s.FirstLatencySample)
+	}
+
+	stats.AddRequest(testErrorCode, 20, 1, 20.0)
+	if assert.NotNil(t, s.Latencies) {
+		assert.Equal(t, 42, s.Count)
+		assert.Equal(t, float64(30), s.Latencies.GetCount())
+
+		verifyQuantile(t, s.Latencies, 0.0, 10.0)
+		verifyQuantile(t, s.Latencies, 1.0, 20.0)
+	}
+}
+
+func TestCombineWithoutLatency(t *testing.T) {
+	testErrorCode := int32(0)
+
+	stats := NewRequestStats()
+	stats2 := NewRequestStats()
+	stats3 := NewRequestStats()
+
+	stats.AddRequest(testErrorCode, 10, 1, 10.0)
+	stats2.AddRequest(testErrorCode, 5, 1, 0)
+	stats2.AddRequest(testErrorCode, 15, 1, 15.0)
+	stats3.AddRequest(testErrorCode, 4, 1, 0)
+
+	stats.CombineWith(stats2)
+	stats.CombineWith(stats3)
+
+	s := stats.ErrorCodeToStat[testErrorCode]
+	if assert.NotNil(t, s) && assert.NotNil(t, s.Latencies) {
+		assert.Equal(t, 34, s.Count)
+		assert.Equal(t, float64(25), s.Latencies.GetCount())
+
+		verifyQuantile(t, s.Latencies, 0.0, 10.0)
+		verifyQuantile(t, s.Latencies, 1.0, 15.0)
+	}
+}
+
 func verifyQuantile(t *testing.T, sketch *ddsketch.DDSketch, q float64, expectedValue float64) {
 	val, err := sketch.GetValueAtQuantile(q)
 	assert.Nil(t, err)
@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"strings"
+)
+
+// graphQLContext specifies the syntactic construct the obfuscator is currently in.
+type graphQLContext int
+
+const (
+	// graphQLSelectionSet is a set of fields surrounded by braces.
+	graphQLSelectionSet graphQLContext = iota
+
+	// graphQLArguments is a list of field or directive arguments surrounded by parentheses.
+	graphQLArguments
+
+	// graphQLVariableDefinitions is the list of variable definitions of an operation.
+	graphQLVariableDefinitions
+
+	// graphQLListValue is a list literal surrounded by brackets.
+	graphQLListValue
+
+	// graphQLObjectValue is an input object literal surrounded by braces.
+	graphQLObjectValue
+)
+
+// ObfuscateGraphQLString obfuscates the given GraphQL document by replacing all
+// literal values (strings, numbers, booleans, nulls and enums) with "?". Variables,
+// operations, fields, aliases, fragments and directives are kept as they are and
+// white space is normalized. Comments are removed.
+func (*Obfuscator) ObfuscateGraphQLString(query string) (string, error) {
+	var (
+		t    = newGraphQLTokenizer(query)
+		w    graphQLWriter
+		ctx  []graphQLContext
+		prev [2]string // the two previously scanned tokens, most recent first
+		// expectValue reports whether the next token starts a value.
+		expectValue bool
+		// selectionDepth is the number of selection sets we are nested in.
+		selectionDepth int
+	)
+	top := func() (graphQLContext, bool) {
+		if len(ctx) == 0 {
+			return 0, false
+		}
+		return ctx[len(ctx)-1], true
+	}
+	pop := func() {
+		if len(ctx) > 0 {
+			ctx = ctx[:len(ctx)-1]
+		}
+	}
+	for {
+		typ, tok, err := t.scan()
+		if err != nil {
+			return "", err
+		}
+		if typ == graphQLTokenEOF {
+			break
+		}
+		cur, _ := top()
+		inList := len(ctx) > 0 && cur == graphQLListValue
+		switch {
+		case typ == graphQLTokenString || typ == graphQLTokenInt || typ == graphQLTokenFloat:
+			// literals are always obfuscated, regardless of where they show up
+			w.write("?")
+			expectValue = false
+		case typ == graphQLTokenName && prev[0] == "$":
+			// variable name
+			w.write(tok)
+			expectValue = false
+		case typ == graphQLTokenName && (expectValue || inList):
+			// boolean, null or enum value
+			w.write("?")
+			expectValue = false
+		case tok == "[" && (expectValue || inList):
+			ctx = append(ctx, graphQLListValue)
+			w.write(tok)
+			expectValue = false
+		case tok == "]" && inList:
+			pop()
+			w.write(tok)
+		case tok == "{" && (expectValue || inList):
+			ctx = append(ctx, graphQLObjectValue)
+			w.write(tok)
+			expectValue = false
+		case tok == "{":
+			ctx = append(ctx, graphQLSelectionSet)
+			selectionDepth++
+			w.write(tok)
+		case tok == "}":
+			if c, ok := top(); ok && c == graphQLSelectionSet {
+				selectionDepth--
+			}
+			pop()
+			w.write(tok)
+		case tok == "(":
+			if selectionDepth == 0 && prev[1] != "@" {
+				ctx = append(ctx, graphQLVariableDefinitions)
+			} else {
+				ctx = append(ctx, graphQLArguments)
+			}
+			w.write(tok)
+		case tok == ")":
+			pop()
+			w.write(tok)
+			expectValue = false
+		case tok == ":":
+			// in variable definitions, a type follows the colon
+			expectValue = cur == graphQLArguments || cur == graphQLObjectValue
+			w.write(tok)
+		case tok == "=":
+			// default value of a variable definition
+			expectValue = true
+			w.write(tok)
+		default:
+			w.write(tok)
+		}
+		prev[1], prev[0] = prev[0], tok
+	}
+	return w.String(), nil
+}
+
+// QuantizeGraphQLString returns a quantized version of a GraphQL document, made of the
+// type and the name of its first operation, e.g. "query GetUser". Anonymous operations
+// only report their type. An empty string is returned when no operation could be found.
+func (*Obfuscator) QuantizeGraphQLString(query string) string {
+	var (
+		t          = newGraphQLTokenizer(query)
+		depth      int
+		inFragment bool
+	)
+	for {
+		typ, tok, err := t.scan()
+		if err != nil || typ == graphQLTokenEOF {
+			return ""
+		}
+		switch {
+		case tok == "{" || tok == "(" || tok == "[":
+			if depth == 0 && tok == "{" && !inFragment {
+				// query shorthand, e.g. "{ user { name } }"
+				return "query"
+			}
+			depth++
+		case tok == "}" || tok == ")" || tok == "]":
+			depth--
+			if depth == 0 && tok == "}" {
+				inFragment = false
+			}
+		case depth == 0 && typ == graphQLTokenName && tok == "fragment":
+			inFragment = true
+		case depth == 0 && typ == graphQLTokenName && !inFragment && isGraphQLOperationType(tok):
+			typ, name, err := t.scan()
+			if err != nil {
+				return ""
+			}
+			if typ != graphQLTokenName {
+				return tok
+			}
+			return tok + " " + name
+		}
+	}
+}
+
+func isGraphQLOperationType(tok string) bool {
+	return tok == "query" || tok == "mutation" || tok == "subscription"
+}
+
+// graphQLWriter writes tokens separated by a single space, omitting it where it
+// would hurt readability, such as around parentheses and before colons.
+type graphQLWriter struct {
+	strings.Builder
+	last string
+}
+
+func (w *graphQLWriter) write(tok string) {
+	if w.Len() > 0 && w.needsSpace(tok) {
+		w.WriteByte(' ')
+	}
+	w.WriteString(tok)
+	w.last = tok
+}
+
+func (w *graphQLWriter) needsSpace(tok string) bool {
+	switch w.last {
+	case "(", "[", "$", "@":
+		return false
+	case "...":
+		return tok == "on"
+	}
+	switch tok {
+	case "(", ")", "]", ":", ",", "!":
+		return false
+	}
+	return true
+}
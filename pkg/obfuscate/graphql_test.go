@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateGraphQLString(t *testing.T) {
+	o := NewObfuscator(Config{})
+	for _, tt := range []struct {
+		name, in, out string
+	}{
+		{
+			"no-literals",
+			`{ user { name } }`,
+			`{ user { name } }`,
+		},
+		{
+			"whitespace",
+			"query  GetUser\n{\n\tuser {\n\t\tname\n\t\temail\n\t}\n}",
+			`query GetUser { user { name email } }`,
+		},
+		{
+			"string-argument",
+			`query { user(id: "123") { name } }`,
+			`query { user(id: ?) { name } }`,
+		},
+		{
+			"numbers",
+			`{ products(first: 10, minPrice: -1.5e3) { id } }`,
+			`{ products(first: ?, minPrice: ?) { id } }`,
+		},
+		{
+			"booleans-enums-null",
+			`{ users(active: true, role: ADMIN, manager: null) { id } }`,
+			`{ users(active: ?, role: ?, manager: ?) { id } }`,
+		},
+		{
+			"variables",
+			`query GetUser($id: ID!, $withEmail: Boolean = false) { user(id: $id) { name email @include(if: $withEmail) } }`,
+			`query GetUser($id: ID!, $withEmail: Boolean = ?) { user(id: $id) { name email @include(if: $withEmail) } }`,
+		},
+		{
+			"list-type-and-list-value",
+			`query Q($ids: [ID!]! = ["a", "b"]) { nodes(ids: [1, 2, 3], kinds: [A B]) { id } }`,
+			`query Q($ids: [ID!]! = [?, ?]) { nodes(ids: [?, ?, ?], kinds: [? ?]) { id } }`,
+		},
+		{
+			"object-value",
+			`mutation { createUser(input: {name: "John", age: 42, tags: ["x"], address: {zip: "75001"}}) { id } }`,
+			`mutation { createUser(input: { name: ?, age: ?, tags: [?], address: { zip: ? } }) { id } }`,
+		},
+		{
+			"directive-literal",
+			`{ user { name @skip(if: true) } }`,
+			`{ user { name @skip(if: ?) } }`,
+		},
+		{
+			"aliases-and-fragments",
+			`query { me: user(id: 1) { ...UserFields ... on Admin { level } } } fragment UserFields on User { name }`,
+			`query { me: user(id: ?) { ...UserFields ... on Admin { level } } } fragment UserFields on User { name }`,
+		},
+		{
+			"fragment-directive-arguments",
+			`fragment F on User @custom(flag: "secret") { name }`,
+			`fragment F on User @custom(flag: ?) { name }`,
+		},
+		{
+			"block-string",
+			`mutation { post(body: """multi
+line \""" quoted""") { id } }`,
+			`mutation { post(body: ?) { id } }`,
+		},
+		{
+			"escaped-string",
+			`{ search(q: "a \"quoted\" value") { id } }`,
+			`{ search(q: ?) { id } }`,
+		},
+		{
+			"comments",
+			"# fetch the user\n{ user(id: 1) { name # the name\n } }",
+			`{ user(id: ?) { name } }`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := o.ObfuscateGraphQLString(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestObfuscateGraphQLStringErrors(t *testing.T) {
+	o := NewObfuscator(Config{})
+	for _, in := range []string{
+		`{ user(id: "123) { name } }`,
+		`{ post(body: """never closed) { id } }`,
+		`{ user(id: 12.) { name } }`,
+		`{ user(id: 1) { name } } %`,
+		`{ ..user }`,
+	} {
+		t.Run(in, func(t *testing.T) {
+			_, err := o.ObfuscateGraphQLString(in)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestQuantizeGraphQLString(t *testing.T) {
+	o := NewObfuscator(Config{})
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{`query GetUser($id: ID!) { user(id: $id) { name } }`, "query GetUser"},
+		{`mutation CreateUser { createUser(name: "x") { id } }`, "mutation CreateUser"},
+		{`subscription { messages { text } }`, "subscription"},
+		{`{ user { name } }`, "query"},
+		{`fragment UserFields on User { name } query Q { user { ...UserFields } }`, "query Q"},
+		{`fragment UserFields on User { name }`, ""},
+		{`# comment only`, ""},
+		{`query "broken`, ""},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			assert.Equal(t, tt.out, o.QuantizeGraphQLString(tt.in))
+		})
+	}
+}
+
+func BenchmarkObfuscateGraphQLString(b *testing.B) {
+	o := NewObfuscator(Config{})
+	query := `query GetUser($id: ID!) { user(id: $id) { name friends(first: 10, orderBy: {field: NAME, direction: ASC}) { edges { node { name } } } } }`
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = o.ObfuscateGraphQLString(query)
+	}
+}
@@ -0,0 +1,209 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// graphQLTokenType specifies the lexical token type returned by the GraphQL tokenizer.
+type graphQLTokenType int
+
+const (
+	// graphQLTokenEOF signals the end of the document.
+	graphQLTokenEOF graphQLTokenType = iota
+
+	// graphQLTokenPunctuator is any of ! $ & ( ) ... : = @ [ ] { | } and the
+	// insignificant comma, which is kept to preserve the author's formatting.
+	graphQLTokenPunctuator
+
+	// graphQLTokenName is a name such as a field, type, keyword or enum value.
+	graphQLTokenName
+
+	// graphQLTokenInt is an integer literal.
+	graphQLTokenInt
+
+	// graphQLTokenFloat is a float literal.
+	graphQLTokenFloat
+
+	// graphQLTokenString is a string or block string literal.
+	graphQLTokenString
+)
+
+// String implements fmt.Stringer.
+func (t graphQLTokenType) String() string {
+	return map[graphQLTokenType]string{
+		graphQLTokenEOF:        "EOF",
+		graphQLTokenPunctuator: "punctuator",
+		graphQLTokenName:       "name",
+		graphQLTokenInt:        "int",
+		graphQLTokenFloat:      "float",
+		graphQLTokenString:     "string",
+	}[t]
+}
+
+// errGraphQLUnterminatedString is returned when a string or block string is never closed.
+var errGraphQLUnterminatedString = errors.New("unterminated string")
+
+// graphQLTokenizer splits a GraphQL document into lexical tokens as described in
+// the "Language" section of the GraphQL specification. White space, line terminators,
+// unicode BOMs and comments are ignored.
+//
+// See https://spec.graphql.org/October2021/#sec-Language.Source-Text
+type graphQLTokenizer struct {
+	data string
+	off  int
+}
+
+// newGraphQLTokenizer returns a new tokenizer for the given document.
+func newGraphQLTokenizer(data string) *graphQLTokenizer {
+	return &graphQLTokenizer{data: data}
+}
+
+// scan returns the next token along with its type. A graphQLTokenEOF token is
+// returned once the whole document was consumed.
+func (t *graphQLTokenizer) scan() (graphQLTokenType, string, error) {
+	t.skipIgnored()
+	if t.off >= len(t.data) {
+		return graphQLTokenEOF, "", nil
+	}
+	start := t.off
+	ch := t.data[t.off]
+	switch {
+	case ch == '.':
+		if t.off+2 < len(t.data) && t.data[t.off+1] == '.' && t.data[t.off+2] == '.' {
+			t.off += 3
+			return graphQLTokenPunctuator, "...", nil
+		}
+		return graphQLTokenEOF, "", fmt.Errorf("unexpected character %q at offset %d", ch, start)
+	case isGraphQLPunctuator(ch):
+		t.off++
+		return graphQLTokenPunctuator, t.data[start:t.off], nil
+	case ch == '_' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z'):
+		t.off++
+		for t.off < len(t.data) && isGraphQLNameContinue(t.data[t.off]) {
+			t.off++
+		}
+		return graphQLTokenName, t.data[start:t.off], nil
+	case ch == '-' || isDigit(rune(ch)):
+		return t.scanNumber()
+	case ch == '"':
+		return t.scanString()
+	}
+	return graphQLTokenEOF, "", fmt.Errorf("unexpected character %q at offset %d", ch, start)
+}
+
+// skipIgnored advances past white space, line terminators, comments and unicode BOMs.
+// Commas are insignificant too, but they are returned as punctuators.
+func (t *graphQLTokenizer) skipIgnored() {
+	for t.off < len(t.data) {
+		switch ch := t.data[t.off]; ch {
+		case ' ', '\t', '\n', '\r':
+			t.off++
+		case '#':
+			for t.off < len(t.data) && t.data[t.off] != '\n' && t.data[t.off] != '\r' {
+				t.off++
+			}
+		case 0xEF:
+			// unicode BOM (U+FEFF) encoded as UTF-8
+			if t.off+2 < len(t.data) && t.data[t.off+1] == 0xBB && t.data[t.off+2] == 0xBF {
+				t.off += 3
+				continue
+			}
+			return
+		default:
+			return
+		}
+	}
+}
+
+// scanNumber scans an IntValue or FloatValue.
+func (t *graphQLTokenizer) scanNumber() (graphQLTokenType, string, error) {
+	start := t.off
+	typ := graphQLTokenInt
+	if t.data[t.off] == '-' {
+		t.off++
+	}
+	if !t.scanDigits() {
+		return graphQLTokenEOF, "", fmt.Errorf("invalid number at offset %d", start)
+	}
+	if t.off < len(t.data) && t.data[t.off] == '.' {
+		typ = graphQLTokenFloat
+		t.off++
+		if !t.scanDigits() {
+			return graphQLTokenEOF, "", fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	if t.off < len(t.data) && (t.data[t.off] == 'e' || t.data[t.off] == 'E') {
+		typ = graphQLTokenFloat
+		t.off++
+		if t.off < len(t.data) && (t.data[t.off] == '+' || t.data[t.off] == '-') {
+			t.off++
+		}
+		if !t.scanDigits() {
+			return graphQLTokenEOF, "", fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	return typ, t.data[start:t.off], nil
+}
+
+// scanDigits consumes a sequence of digits and reports whether at least one was found.
+func (t *graphQLTokenizer) scanDigits() bool {
+	start := t.off
+	for t.off < len(t.data) && isDigit(rune(t.data[t.off])) {
+		t.off++
+	}
+	return t.off > start
+}
+
+// scanString scans a StringValue, which can either be a regular quoted string
+// or a triple-quoted block string.
+func (t *graphQLTokenizer) scanString() (graphQLTokenType, string, error) {
+	start := t.off
+	if t.off+2 < len(t.data) && t.data[t.off+1] == '"' && t.data[t.off+2] == '"' {
+		t.off += 3
+		for t.off < len(t.data) {
+			switch {
+			case t.data[t.off] == '\\' && t.off+3 < len(t.data) && t.data[t.off+1:t.off+4] == `"""`:
+				t.off += 4
+			case t.off+2 < len(t.data) && t.data[t.off:t.off+3] == `"""`:
+				t.off += 3
+				return graphQLTokenString, t.data[start:t.off], nil
+			default:
+				t.off++
+			}
+		}
+		return graphQLTokenEOF, "", errGraphQLUnterminatedString
+	}
+	t.off++
+	for t.off < len(t.data) {
+		switch t.data[t.off] {
+		case '\\':
+			t.off += 2
+		case '"':
+			t.off++
+			return graphQLTokenString, t.data[start:t.off], nil
+		case '\n', '\r':
+			return graphQLTokenEOF, "", errGraphQLUnterminatedString
+		default:
+			t.off++
+		}
+	}
+	return graphQLTokenEOF, "", errGraphQLUnterminatedString
+}
+
+func isGraphQLPunctuator(ch byte) bool {
+	switch ch {
+	case '!', '$', '&', '(', ')', ':', '=', '@', '[', ']', '{', '|', '}', ',':
+		return true
+	}
+	return false
+}
+
+func isGraphQLNameContinue(ch byte) bool {
+	return ch == '_' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || isDigit(rune(ch))
+}
@@ -7,59 +7,29 @@
 package persistentcache
 
 import (
-	"os"
-	"path/filepath"
-	"regexp"
 	"strings"
-
-	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 )
 
-// Invalid characters to clean up
-var invalidChars = regexp.MustCompile("[^a-zA-Z0-9_-]")
-
-// Return a file where to store the data. We split the key by ":", using the
-// first prefix as directory, if present. This is useful for integrations, which
+// Write stores data on disk in the run directory. The key is split by ":", using
+// the first prefix as namespace, if present. This is useful for integrations, which
 // use the check_id formed with $check_name:$hash
-func getFileForKey(key string) (string, error) {
-	parent := pkgconfigsetup.Datadog().GetString("run_path")
-	paths := strings.SplitN(key, ":", 2)
-	cleanedPath := invalidChars.ReplaceAllString(paths[0], "")
-	if len(paths) == 1 {
-		// If there is no colon, just return the key
-		return filepath.Join(parent, cleanedPath), nil
-	}
-	// Otherwise, create the directory with a prefix
-	err := os.MkdirAll(filepath.Join(parent, cleanedPath), 0700)
-	if err != nil {
-		return "", err
-	}
-	cleanedFile := invalidChars.ReplaceAllString(paths[1], "")
-	return filepath.Join(parent, cleanedPath, cleanedFile), nil
-}
-
-// Write stores data on disk in the run directory.
 func Write(key, value string) error {
-	path, err := getFileForKey(key)
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(path, []byte(value), 0600)
+	namespace, name := splitKey(key)
+	return NewStore(namespace).Set(name, value, 0)
 }
 
 // Read returns a value previously stored, or the empty string.
 func Read(key string) (string, error) {
-	path, err := getFileForKey(key)
-	if err != nil {
-		return "", err
-	}
-	_, err = os.Stat(path)
-	if os.IsNotExist(err) {
-		return "", nil
-	}
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
+	namespace, name := splitKey(key)
+	value, _, err := NewStore(namespace).Get(name)
+	return value, err
+}
+
+// splitKey returns the namespace and the name of a key of the form [namespace:]name.
+func splitKey(key string) (string, string) {
+	paths := strings.SplitN(key, ":", 2)
+	if len(paths) == 1 {
+		return "", paths[0]
 	}
-	return string(content), nil
+	return paths[0], paths[1]
 }
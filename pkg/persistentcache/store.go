@@ -0,0 +1,444 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2019-present Datadog, Inc.
+
+package persistentcache
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// metaSuffix is appended to the file name of an entry to name the file holding its metadata.
+// Keys can't contain dots, so the metadata files never collide with entries.
+const metaSuffix = ".meta"
+
+// ownerFile is created in the namespace directories written by the cache. The files without
+// metadata are only managed in those directories, the run path holds files of other components.
+const ownerFile = ".persistentcache"
+
+// Invalid characters to clean up
+var invalidChars = regexp.MustCompile("[^a-zA-Z0-9_-]")
+
+var (
+	// mu serializes the changes made to the cache by this process, which makes
+	// compare-and-swap writes atomic.
+	mu sync.Mutex
+	// cacheSize is the size of the entries stored in cacheRoot, computed when the
+	// size limit is first checked and kept up to date afterwards. Negative when unknown.
+	cacheSize int64 = -1
+	cacheRoot string
+)
+
+// Entry describes a value stored in the cache.
+type Entry struct {
+	Namespace string    `json:"namespace"`
+	Key       string    `json:"key"`
+	Size      int64     `json:"size"`
+	Expires   time.Time `json:"expires,omitempty"`
+	// LastAccess is the last time the entry was written or read.
+	LastAccess time.Time `json:"last_access"`
+}
+
+// Expired returns whether the entry is expired at now.
+func (e Entry) Expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires)
+}
+
+// entryMeta is the content of the metadata file of an entry.
+type entryMeta struct {
+	// Expires is the expiration date of the entry in seconds since the epoch, zero if it never expires.
+	Expires int64 `json:"expires,omitempty"`
+}
+
+// Store is a namespace of the persistent cache. Its entries are stored in a directory of the
+// run path named after the namespace, or in the run path itself for the empty namespace.
+type Store struct {
+	namespace string
+}
+
+// NewStore returns the store of the given namespace. Characters other than letters,
+// digits, "_" and "-" are removed from namespaces and keys.
+func NewStore(namespace string) *Store {
+	return &Store{namespace: invalidChars.ReplaceAllString(namespace, "")}
+}
+
+func (s *Store) dir() string {
+	return filepath.Join(pkgconfigsetup.Datadog().GetString("run_path"), s.namespace)
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.dir(), invalidChars.ReplaceAllString(key, ""))
+}
+
+// Set stores value under key. The entry expires after ttl, or never if ttl is zero.
+func (s *Store) Set(key, value string, ttl time.Duration) error {
+	mu.Lock()
+	defer mu.Unlock()
+	return s.set(key, value, ttl)
+}
+
+// CompareAndSwap stores value under key only if the current value of the entry is old,
+// the empty string standing for a missing entry. It reports whether the value was stored.
+func (s *Store) CompareAndSwap(key, old, value string, ttl time.Duration) (bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	current, _, err := s.get(key, time.Now())
+	if err != nil {
+		return false, err
+	}
+	if current != old {
+		return false, nil
+	}
+	return true, s.set(key, value, ttl)
+}
+
+// Get returns the value stored under key and whether it was found. Expired entries are removed.
+func (s *Store) Get(key string) (string, bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return s.get(key, time.Now())
+}
+
+// get reads an entry. mu must be held.
+func (s *Store) get(key string, now time.Time) (string, bool, error) {
+	path := s.path(key)
+	meta, hasMeta, err := readMeta(path)
+	if err != nil {
+		return "", false, err
+	}
+	if hasMeta && meta.Expires > 0 && now.Unix() >= meta.Expires {
+		return "", false, s.delete(key)
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	// the modification time of the metadata file tracks the last access for the eviction,
+	// or the one of the entry itself when it has no metadata
+	if hasMeta {
+		_ = os.Chtimes(path+metaSuffix, now, now)
+	} else {
+		_ = os.Chtimes(path, now, now)
+	}
+	return string(content), true, nil
+}
+
+// Delete removes the entry stored under key, if any.
+func (s *Store) Delete(key string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	return s.delete(key)
+}
+
+// delete removes an entry. mu must be held.
+func (s *Store) delete(key string) error {
+	path := s.path(key)
+	size := fileSize(path)
+	for _, p := range []string{path, path + metaSuffix} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	addSize(-size)
+	return nil
+}
+
+// List returns the entries whose key starts with prefix.
+func (s *Store) List(prefix string) ([]Entry, error) {
+	return listDir(s.dir(), s.namespace, prefix)
+}
+
+// DeletePrefix removes the entries whose key starts with prefix, and returns how many were removed.
+func (s *Store) DeletePrefix(prefix string) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	entries, err := s.List(prefix)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		if err := s.delete(e.Key); err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), nil
+}
+
+// set stores an entry. mu must be held.
+func (s *Store) set(key, value string, ttl time.Duration) error {
+	if err := os.MkdirAll(s.dir(), 0700); err != nil {
+		return err
+	}
+	if s.namespace != "" {
+		if err := markOwned(s.dir()); err != nil {
+			return err
+		}
+	}
+	path := s.path(key)
+	previous := fileSize(path)
+
+	var meta entryMeta
+	if ttl > 0 {
+		// round up so that entries never expire before their TTL
+		expires := time.Now().Add(ttl)
+		meta.Expires = expires.Unix()
+		if expires.Nanosecond() > 0 {
+			meta.Expires++
+		}
+	}
+	metaContent, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, []byte(value)); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path+metaSuffix, metaContent); err != nil {
+		return err
+	}
+
+	addSize(int64(len(value)) - previous)
+	return enforceMaxSize(time.Now())
+}
+
+// List returns the entries of all the namespaces.
+func List() ([]Entry, error) {
+	root := pkgconfigsetup.Datadog().GetString("run_path")
+	entries, err := listDir(root, "", "")
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if !f.IsDir() {
+			continue
+		}
+		namespaced, err := listDir(filepath.Join(root, f.Name()), f.Name(), "")
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, namespaced...)
+	}
+	return entries, nil
+}
+
+// Purge removes the entries matching namespace, when it is not empty, and whose key starts
+// with prefix. Only the expired entries are removed when expiredOnly is set. It returns the
+// removed entries.
+func Purge(namespace, prefix string, expiredOnly bool) ([]Entry, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	entries, err := List()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var purged []Entry
+	for _, e := range entries {
+		if namespace != "" && e.Namespace != namespace || !strings.HasPrefix(e.Key, prefix) {
+			continue
+		}
+		if expiredOnly && !e.Expired(now) {
+			continue
+		}
+		if err := NewStore(e.Namespace).delete(e.Key); err != nil {
+			return purged, err
+		}
+		purged = append(purged, e)
+	}
+	return purged, nil
+}
+
+// enforceMaxSize removes the expired entries, then the least recently used ones, when the
+// cache is larger than persistent_cache.max_size. mu must be held.
+func enforceMaxSize(now time.Time) error {
+	maxSize := pkgconfigsetup.Datadog().GetInt64("persistent_cache.max_size")
+	root := pkgconfigsetup.Datadog().GetString("run_path")
+	if maxSize <= 0 || (cacheRoot == root && cacheSize >= 0 && cacheSize <= maxSize) {
+		return nil
+	}
+	entries, err := List()
+	if err != nil {
+		return err
+	}
+	cacheRoot, cacheSize = root, 0
+	for _, e := range entries {
+		cacheSize += e.Size
+	}
+	if cacheSize <= maxSize {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if ei, ej := entries[i].Expired(now), entries[j].Expired(now); ei != ej {
+			return ei
+		}
+		return entries[i].LastAccess.Before(entries[j].LastAccess)
+	})
+	evicted := 0
+	for _, e := range entries {
+		if cacheSize <= maxSize {
+			break
+		}
+		if err := NewStore(e.Namespace).delete(e.Key); err != nil {
+			return err
+		}
+		evicted++
+	}
+	log.Debugf("Evicted %d entries from the persistent cache to stay under %d bytes", evicted, maxSize)
+	return nil
+}
+
+// addSize records a change of the size of the cache. mu must be held.
+func addSize(delta int64) {
+	if cacheRoot != pkgconfigsetup.Datadog().GetString("run_path") {
+		// the run path changed, the size is computed again on the next check
+		cacheSize = -1
+		return
+	}
+	if cacheSize >= 0 {
+		cacheSize += delta
+	}
+}
+
+// listDir returns the entries stored in dir, under the given namespace, whose key starts with prefix.
+// The files written by older versions of the agent have no metadata: in the namespace directories
+// owned by the cache, the ones named like keys are listed as entries that never expire, whose last
+// access is their modification time. Other files without metadata belong to other components.
+func listDir(dir, namespace, prefix string) ([]Entry, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		names[f.Name()] = struct{}{}
+	}
+	_, owned := names[ownerFile]
+	owned = owned && namespace != ""
+	var entries []Entry
+	for _, f := range files {
+		name := f.Name()
+		if !f.Type().IsRegular() {
+			continue
+		}
+		key := strings.TrimSuffix(name, metaSuffix)
+		if !strings.HasPrefix(key, prefix) || invalidChars.MatchString(key) {
+			continue
+		}
+		if key == name {
+			if _, hasMeta := names[name+metaSuffix]; hasMeta {
+				// listed along with its metadata
+				continue
+			}
+			if !owned {
+				continue
+			}
+			info, err := f.Info()
+			if err != nil {
+				continue
+			}
+			entries = append(entries, Entry{Namespace: namespace, Key: key, Size: info.Size(), LastAccess: info.ModTime()})
+			continue
+		}
+
+		path := filepath.Join(dir, key)
+		value, err := os.Stat(path)
+		if err != nil {
+			// the metadata of an entry removed by an older version of the agent
+			continue
+		}
+		meta, _, err := readMeta(path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		e := Entry{Namespace: namespace, Key: key, Size: value.Size(), LastAccess: info.ModTime()}
+		if meta.Expires > 0 {
+			e.Expires = time.Unix(meta.Expires, 0)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// markOwned records that the namespace directory dir is owned by the cache.
+func markOwned(dir string) error {
+	path := filepath.Join(dir, ownerFile)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	return os.WriteFile(path, nil, 0600)
+}
+
+// readMeta reads the metadata of the entry stored at path. Entries written by older
+// versions of the agent have no metadata.
+func readMeta(path string) (entryMeta, bool, error) {
+	var meta entryMeta
+	content, err := os.ReadFile(path + metaSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return meta, false, nil
+	}
+	if err != nil {
+		return meta, false, err
+	}
+	if err := json.Unmarshal(content, &meta); err != nil {
+		log.Debugf("Ignoring invalid persistent cache metadata %s: %v", path+metaSuffix, err)
+		return meta, false, nil
+	}
+	return meta, true, nil
+}
+
+// writeFileAtomic writes content to path through a temporary file, so that readers
+// never see a partially written file.
+func writeFileAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,204 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2019-present Datadog, Inc.
+
+package persistentcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func setupRunPath(t *testing.T) string {
+	testDir := t.TempDir()
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("run_path", testDir)
+	return testDir
+}
+
+func TestStoreTTL(t *testing.T) {
+	setupRunPath(t)
+	s := NewStore("snmp")
+
+	require.NoError(t, s.Set("device", "value", time.Hour))
+	value, found, err := s.Get("device")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "value", value)
+
+	require.NoError(t, s.Set("expired", "value", time.Nanosecond))
+	value, found, err = s.get("expired", time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Empty(t, value)
+	entries, err := s.List("")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "device", entries[0].Key)
+}
+
+func TestStoreLegacyEntries(t *testing.T) {
+	testDir := setupRunPath(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(testDir, "check"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "check", "abc"), []byte("legacy"), 0600))
+
+	value, err := Read("check:abc")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", value)
+
+	// files without metadata are only managed once the namespace is written by the cache
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "check", "def"), []byte("legacy"), 0600))
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(testDir, "check", "def"), past, past))
+	entries, err := List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// they are then listed as entries which never expire, and evicted like the other entries
+	configmock.New(t).SetWithoutSource("persistent_cache.max_size", 10)
+	require.NoError(t, Write("check:ghi", "new"))
+	entries, err = List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	legacy := entries[0]
+	if legacy.Key != "abc" {
+		legacy = entries[1]
+	}
+	assert.Equal(t, "check", legacy.Namespace)
+	assert.Equal(t, "abc", legacy.Key)
+	assert.Equal(t, int64(6), legacy.Size)
+	assert.True(t, legacy.Expires.IsZero())
+	entries, err = NewStore("check").List("")
+	require.NoError(t, err)
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	assert.ElementsMatch(t, []string{"abc", "ghi"}, keys)
+
+	purged, err := Purge("check", "a", false)
+	require.NoError(t, err)
+	require.Len(t, purged, 1)
+	assert.NoFileExists(t, filepath.Join(testDir, "check", "abc"))
+}
+
+func TestStoreForeignFiles(t *testing.T) {
+	testDir := setupRunPath(t)
+	configmock.New(t).SetWithoutSource("persistent_cache.max_size", 15)
+	past := time.Now().Add(-time.Minute)
+	foreign := []string{
+		filepath.Join(testDir, "registry"),
+		filepath.Join(testDir, "jmxfetch", "state"),
+		filepath.Join(testDir, "transactions_to_retry", "abc"),
+	}
+	for _, path := range foreign {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte("0123456789"), 0600))
+		require.NoError(t, os.Chtimes(path, past, past))
+	}
+
+	s := NewStore("ns")
+	require.NoError(t, s.Set("a", "0123456789", 0))
+	require.NoError(t, s.Set("b", "0123456789", 0))
+	entries, err := List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "b", entries[0].Key)
+
+	purged, err := Purge("", "", false)
+	require.NoError(t, err)
+	assert.Len(t, purged, 1)
+	for _, path := range foreign {
+		assert.FileExists(t, path)
+	}
+}
+
+func TestStoreCompareAndSwap(t *testing.T) {
+	setupRunPath(t)
+	s := NewStore("ns")
+
+	swapped, err := s.CompareAndSwap("key", "", "v1", 0)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+
+	swapped, err = s.CompareAndSwap("key", "", "v2", 0)
+	require.NoError(t, err)
+	assert.False(t, swapped)
+
+	swapped, err = s.CompareAndSwap("key", "v1", "v2", 0)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+
+	value, _, err := s.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", value)
+}
+
+func TestStoreDeletePrefix(t *testing.T) {
+	setupRunPath(t)
+	s := NewStore("ns")
+	for _, key := range []string{"device_1", "device_2", "profile_1"} {
+		require.NoError(t, s.Set(key, "value", 0))
+	}
+	require.NoError(t, NewStore("other").Set("device_3", "value", 0))
+
+	n, err := s.DeletePrefix("device")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	entries, err := List()
+	require.NoError(t, err)
+	keys := make(map[string]string)
+	for _, e := range entries {
+		keys[e.Key] = e.Namespace
+	}
+	assert.Equal(t, map[string]string{"profile_1": "ns", "device_3": "other"}, keys)
+}
+
+func TestStoreMaxSize(t *testing.T) {
+	testDir := setupRunPath(t)
+	configmock.New(t).SetWithoutSource("persistent_cache.max_size", 25)
+	s := NewStore("ns")
+
+	require.NoError(t, s.Set("a", "0123456789", 0))
+	require.NoError(t, s.Set("b", "0123456789", 0))
+	// a is read after b was written, b becomes the least recently used entry
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(testDir, "ns", "b"+metaSuffix), past, past))
+	_, _, err := s.Get("a")
+	require.NoError(t, err)
+
+	require.NoError(t, s.Set("c", "0123456789", 0))
+	entries, err := s.List("")
+	require.NoError(t, err)
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	assert.ElementsMatch(t, []string{"a", "c"}, keys)
+}
+
+func TestPurge(t *testing.T) {
+	setupRunPath(t)
+	require.NoError(t, NewStore("ns").Set("expired", "value", time.Nanosecond))
+	require.NoError(t, NewStore("ns").Set("valid", "value", time.Hour))
+	require.NoError(t, NewStore("other").Set("expired", "value", time.Nanosecond))
+	time.Sleep(time.Second)
+
+	purged, err := Purge("ns", "", true)
+	require.NoError(t, err)
+	require.Len(t, purged, 1)
+	assert.Equal(t, Entry{Namespace: "ns", Key: "expired"}, Entry{Namespace: purged[0].Namespace, Key: purged[0].Key})
+
+	purged, err = Purge("", "", false)
+	require.NoError(t, err)
+	assert.Len(t, purged, 2)
+}
@@ -44,6 +44,19 @@ func PopulateStatus(stats map[string]interface{}) {
 	json.Unmarshal(checkSchedulerStatsJSON, &checkSchedulerStats) //nolint:errcheck
 	stats["checkSchedulerStats"] = checkSchedulerStats
 
+	// checks scheduled with a cron expression, by check ID
+	checkSchedules := make(map[string]interface{})
+	if schedulerData := expvar.Get("scheduler"); schedulerData != nil {
+		var schedulerStats struct {
+			CronJobs map[string]interface{}
+		}
+		json.Unmarshal([]byte(schedulerData.String()), &schedulerStats) //nolint:errcheck
+		if schedulerStats.CronJobs != nil {
+			checkSchedules = schedulerStats.CronJobs
+		}
+	}
+	stats["checkSchedules"] = checkSchedules
+
 	pyLoaderData := expvar.Get("pyLoader")
 	if pyLoaderData != nil {
 		pyLoaderStatsJSON := []byte(pyLoaderData.String())
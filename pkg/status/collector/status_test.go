@@ -59,3 +59,71 @@ func TestRender(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderSchedule(t *testing.T) {
+	data := map[string]interface{}{
+		"runnerStats": map[string]interface{}{
+			"Checks": map[string]interface{}{
+				"inventory": map[string]interface{}{
+					"inventory:1234": map[string]interface{}{
+						"CheckID":         "inventory:1234",
+						"CheckName":       "inventory",
+						"UpdateTimestamp": float64(1700000000),
+						"LastError":       "",
+						"LastWarnings":    []interface{}{},
+					},
+				},
+			},
+		},
+		"checkSchedules": map[string]interface{}{
+			"inventory:1234": map[string]interface{}{
+				"Schedule": "0 2 * * *",
+				"Jitter":   float64(300),
+				"NextRun":  float64(1700013600),
+			},
+		},
+	}
+
+	output := new(bytes.Buffer)
+	require.NoError(t, Provider{}.TextWithData(output, data))
+	require.Contains(t, output.String(), "Schedule: 0 2 * * * (jitter: 300s)")
+	require.Contains(t, output.String(), "Next Scheduled Run: ")
+	require.NotContains(t, output.String(), "Not Scheduled")
+
+	// the schedules are not available with the agent check command
+	delete(data, "checkSchedules")
+	output.Reset()
+	require.NoError(t, Provider{}.TextWithData(output, data))
+	require.NotContains(t, output.String(), "Schedule:")
+}
+
+func TestRenderCircuitBreaker(t *testing.T) {
+	data := map[string]interface{}{
+		"runnerStats": map[string]interface{}{
+			"Checks": map[string]interface{}{
+				"postgres": map[string]interface{}{
+					"postgres:1234": map[string]interface{}{
+						"CheckID":                 "postgres:1234",
+						"CheckName":               "postgres",
+						"UpdateTimestamp":         float64(1700000000),
+						"LastError":               "check run timed out after 30s",
+						"LastWarnings":            []interface{}{},
+						"TotalTimeouts":           float64(3),
+						"ConsecutiveFailures":     float64(5),
+						"CircuitBreakerOpenUntil": float64(1700000600),
+					},
+				},
+			},
+		},
+	}
+
+	output := new(bytes.Buffer)
+	require.NoError(t, Provider{}.TextWithData(output, data))
+	require.Contains(t, output.String(), "Total Timeouts: 3")
+	require.Contains(t, output.String(), "Circuit Breaker: Open until ")
+	require.Contains(t, output.String(), "after 5 consecutive failures")
+
+	output.Reset()
+	require.NoError(t, status.RenderHTML(templatesFS, "collectorHTML.tmpl", output, data))
+	require.Contains(t, output.String(), "Total Timeouts: 3<br>")
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/remoteconfighandler"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/stats"
+	"github.com/DataDog/datadog-agent/pkg/trace/tailsampling"
 	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
 	"github.com/DataDog/datadog-agent/pkg/trace/timing"
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
@@ -52,6 +53,9 @@ const (
 	// probabilitySampling is the value for _dd.p.dm when the agent is configured to use the ProbabilitySampler.
 	probabilitySampling = "-9"
 
+	// ruleSampling is the value for _dd.p.dm when a trace is kept by a sampling rule.
+	ruleSampling = "-3"
+
 	// tagDecisionMaker specifies the sampling decision maker
 	tagDecisionMaker = "_dd.p.dm"
 )
@@ -94,6 +98,8 @@ type Agent struct {
 	RareSampler           *sampler.RareSampler
 	NoPrioritySampler     *sampler.NoPrioritySampler
 	ProbabilisticSampler  *sampler.ProbabilisticSampler
+	RuleSampler           *sampler.RuleSampler
+	TailSampler           *tailsampling.Sampler
 	SamplerMetrics        *sampler.Metrics
 	EventProcessor        *event.Processor
 	TraceWriter           TraceWriter
@@ -157,6 +163,7 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 		RareSampler:           sampler.NewRareSampler(conf),
 		NoPrioritySampler:     sampler.NewNoPrioritySampler(conf),
 		ProbabilisticSampler:  sampler.NewProbabilisticSampler(conf),
+		RuleSampler:           sampler.NewRuleSampler(conf),
 		SamplerMetrics:        sampler.NewMetrics(statsd),
 		EventProcessor:        newEventProcessor(conf, statsd),
 		StatsWriter:           statsWriter,
@@ -168,11 +175,12 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 		Statsd:                statsd,
 		Timing:                timing,
 	}
-	agnt.SamplerMetrics.Add(agnt.PrioritySampler, agnt.ErrorsSampler, agnt.NoPrioritySampler, agnt.RareSampler)
+	agnt.SamplerMetrics.Add(agnt.PrioritySampler, agnt.ErrorsSampler, agnt.NoPrioritySampler, agnt.RareSampler, agnt.RuleSampler)
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt, telemetryCollector, statsd, timing)
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf, statsd, timing)
 	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler)
 	agnt.TraceWriter = writer.NewTraceWriter(conf, agnt.PrioritySampler, agnt.ErrorsSampler, agnt.RareSampler, telemetryCollector, statsd, timing, comp)
+	agnt.TailSampler = tailsampling.NewSampler(conf, func(pkg *writer.SampledChunks) { agnt.TraceWriter.WriteChunks(pkg) }, statsd)
 	return agnt
 }
 
@@ -185,6 +193,7 @@ func (a *Agent) Run() {
 		a.Concentrator,
 		a.ClientStatsAggregator,
 		a.SamplerMetrics,
+		a.TailSampler,
 		a.EventProcessor,
 		a.OTLPReceiver,
 		a.RemoteConfigHandler,
@@ -264,6 +273,7 @@ func (a *Agent) loop() {
 	for _, stopper := range []interface{ Stop() }{
 		a.Concentrator,
 		a.ClientStatsAggregator,
+		a.TailSampler,
 		a.TraceWriter,
 		a.StatsWriter,
 		a.SamplerMetrics,
@@ -393,7 +403,17 @@ func (a *Agent) Process(p *api.Payload) {
 			statsInput.Traces = append(statsInput.Traces, *pt.Clone())
 		}
 
+		var unsampled *pb.TraceChunk
+		if a.conf.TailSamplingEnabled {
+			// The samplers may remove spans from the chunk, keep them around for the tail sampler.
+			unsampled = pt.TraceChunk.ShallowCopy()
+		}
 		keep, numEvents := a.sample(now, ts, pt)
+		if unsampled != nil && a.TailSampler.Add(now, p.TracerPayload, unsampled, pt.TraceChunk, keep) {
+			// The chunk is buffered until the whole trace is sampled.
+			p.RemoveChunk(i)
+			continue
+		}
 		if !keep && len(pt.TraceChunk.Spans) == 0 {
 			// The entire trace was dropped and no spans were kept.
 			p.RemoveChunk(i)
@@ -636,11 +656,12 @@ func (a *Agent) getAnalyzedEvents(pt *traceutil.ProcessedTrace, ts *info.TagStat
 // with the sampling rate.
 //
 // If the agent is set as Error Tracking Standalone, only the ErrorSampler is run (other samplers are bypassed).
-// Otherwise, the rare sampler is run first, catching all rare traces early. If the probabilistic sampler is
-// enabled, it is run on the trace, followed by the error sampler. Otherwise, If the trace has a
-// priority set, the sampling priority is used with the Priority Sampler. When there is no priority
-// set, the NoPrioritySampler is run. Finally, if the trace has not been sampled by the other
-// samplers, the error sampler is run.
+// Otherwise, the rare sampler is run first, catching all rare traces early. The sampling rules are then
+// applied to the traces without a user sampling decision, the first matching rule deciding whether the
+// trace is kept. If the probabilistic sampler is enabled, it is run on the trace, followed by the error
+// sampler. Otherwise, If the trace has a priority set, the sampling priority is used with the Priority
+// Sampler. When there is no priority set, the NoPrioritySampler is run. Finally, if the trace has not
+// been sampled by the other samplers, the error sampler is run.
 func (a *Agent) runSamplers(now time.Time, ts *info.TagStats, pt traceutil.ProcessedTrace) (keep bool, checkAnalyticsEvents bool) {
 	samplerName := sampler.NameUnknown
 	samplingPriority := sampler.PriorityNone
@@ -666,6 +687,17 @@ func (a *Agent) runSamplers(now time.Time, ts *info.TagStats, pt traceutil.Proce
 			samplerName = sampler.NameRare
 			return true, true
 		}
+		if !hasUserSamplingDecision(pt.TraceChunk) {
+			if keep, matched := a.runRuleSampler(pt); matched {
+				samplerName = sampler.NameRule
+				if keep || !traceContainsError(pt.TraceChunk.Spans, false) {
+					return keep, true
+				}
+				// the traces with errors dropped by a rule are still given to the error sampler
+				samplerName = sampler.NameError
+				return a.ErrorsSampler.Sample(now, pt.TraceChunk.Spans, pt.Root, pt.TracerEnv), true
+			}
+		}
 		if a.ProbabilisticSampler.Sample(pt.Root) {
 			pt.TraceChunk.Tags[tagDecisionMaker] = probabilitySampling
 			return true, true
@@ -707,6 +739,18 @@ func (a *Agent) runSamplers(now time.Time, ts *info.TagStats, pt traceutil.Proce
 		return true, true
 	}
 
+	if !hasUserSamplingDecision(pt.TraceChunk) {
+		if keep, matched := a.runRuleSampler(pt); matched {
+			samplerName = sampler.NameRule
+			if keep || !traceContainsError(pt.TraceChunk.Spans, false) {
+				return keep, true
+			}
+			// the traces with errors dropped by a rule are still given to the error sampler
+			samplerName = sampler.NameError
+			return a.ErrorsSampler.Sample(now, pt.TraceChunk.Spans, pt.Root, pt.TracerEnv), true
+		}
+	}
+
 	if hasPriority {
 		if a.PrioritySampler.Sample(now, pt.TraceChunk, pt.Root, pt.TracerEnv, pt.ClientDroppedP0sWeight) {
 			return true, true
@@ -723,6 +767,24 @@ func (a *Agent) runSamplers(now time.Time, ts *info.TagStats, pt traceutil.Proce
 	return false, true
 }
 
+// runRuleSampler applies the sampling rules to pt. matched reports whether a rule applies to the
+// trace, in which case keep is the sampling decision. Kept traces are marked as such by the rule.
+func (a *Agent) runRuleSampler(pt traceutil.ProcessedTrace) (keep bool, matched bool) {
+	keep, matched = a.RuleSampler.Sample(pt.TraceChunk, pt.Root)
+	if keep {
+		pt.TraceChunk.Priority = int32(sampler.PriorityUserKeep)
+		pt.TraceChunk.Tags[tagDecisionMaker] = ruleSampling
+	}
+	return keep, matched
+}
+
+// hasUserSamplingDecision reports whether the trace was kept or dropped by the user, in which
+// case the sampling rules don't apply to it.
+func hasUserSamplingDecision(chunk *pb.TraceChunk) bool {
+	priority, ok := sampler.GetSamplingPriority(chunk)
+	return ok && (priority == sampler.PriorityUserKeep || priority == sampler.PriorityUserDrop)
+}
+
 func traceContainsError(trace pb.Trace, considerExceptionEvents bool) bool {
 	for _, span := range trace {
 		if span.Error != 0 || (considerExceptionEvents && spanContainsExceptionSpanEvent(span)) {
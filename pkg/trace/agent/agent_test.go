@@ -1081,6 +1081,7 @@ func TestSampling(t *testing.T) {
 			ErrorsSampler:        sampler.NewErrorsSampler(cfg),
 			PrioritySampler:      sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
 			RareSampler:          sampler.NewRareSampler(cfg),
+			RuleSampler:          sampler.NewRuleSampler(cfg),
 			ProbabilisticSampler: sampler.NewProbabilisticSampler(cfg),
 			SamplerMetrics:       sampler.NewMetrics(statsd),
 			conf:                 cfg,
@@ -1850,6 +1851,7 @@ func TestSampleTrace(t *testing.T) {
 				ErrorsSampler:     sampler.NewErrorsSampler(cfg),
 				PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
 				RareSampler:       sampler.NewRareSampler(config.New()),
+				RuleSampler:       sampler.NewRuleSampler(cfg),
 				EventProcessor:    newEventProcessor(cfg, statsd),
 				SamplerMetrics:    metrics,
 				conf:              cfg,
@@ -1983,6 +1985,7 @@ func TestSample(t *testing.T) {
 			ErrorsSampler:     sampler.NewErrorsSampler(cfg),
 			PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
 			RareSampler:       sampler.NewRareSampler(config.New()),
+			RuleSampler:       sampler.NewRuleSampler(cfg),
 			EventProcessor:    newEventProcessor(cfg, statsd),
 			SamplerMetrics:    sampler.NewMetrics(statsd),
 			conf:              cfg,
@@ -2021,6 +2024,7 @@ func TestSampleManualUserDropNoAnalyticsEvents(t *testing.T) {
 		ErrorsSampler:     sampler.NewErrorsSampler(cfg),
 		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
 		RareSampler:       sampler.NewRareSampler(config.New()),
+		RuleSampler:       sampler.NewRuleSampler(cfg),
 		EventProcessor:    newEventProcessor(cfg, statsd),
 		SamplerMetrics:    sampler.NewMetrics(statsd),
 		conf:              cfg,
@@ -2030,6 +2034,145 @@ func TestSampleManualUserDropNoAnalyticsEvents(t *testing.T) {
 	assert.Empty(t, pt.Root.Metrics["_dd.analyzed"])
 }
 
+func TestSampleRules(t *testing.T) {
+	now := time.Now()
+	cfg := &config.AgentConfig{
+		TargetTPS: 5,
+		ErrorTPS:  1000,
+		Features:  make(map[string]struct{}),
+		SamplingRules: []*config.SamplingRule{
+			{Service: "checkout", Metrics: map[string]string{"http.status_code": ">=500"}, SampleRate: 1},
+			{Service: "checkout", SampleRate: 0},
+		},
+	}
+	statsd := &statsd.NoOpClient{}
+	a := &Agent{
+		NoPrioritySampler:    sampler.NewNoPrioritySampler(cfg),
+		ErrorsSampler:        sampler.NewErrorsSampler(cfg),
+		PrioritySampler:      sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
+		RareSampler:          sampler.NewRareSampler(config.New()),
+		ProbabilisticSampler: sampler.NewProbabilisticSampler(cfg),
+		RuleSampler:          sampler.NewRuleSampler(cfg),
+		EventProcessor:       newEventProcessor(cfg, statsd),
+		SamplerMetrics:       sampler.NewMetrics(statsd),
+		conf:                 cfg,
+	}
+	newTrace := func(service, status string, priority sampler.SamplingPriority) traceutil.ProcessedTrace {
+		root := &pb.Span{
+			TraceID: 1,
+			Service: service,
+			Start:   now.UnixNano(),
+			Metrics: map[string]float64{"_top_level": 1},
+			Meta:    map[string]string{"http.status_code": status},
+		}
+		pt := traceutil.ProcessedTrace{TraceChunk: testutil.TraceChunkWithSpan(root), Root: root}
+		pt.TraceChunk.Priority = int32(priority)
+		return pt
+	}
+
+	t.Run("kept by rule", func(t *testing.T) {
+		pt := newTrace("checkout", "502", sampler.PriorityAutoDrop)
+		keep, _ := a.runSamplers(now, info.NewReceiverStats().GetTagStats(info.Tags{}), pt)
+		assert.True(t, keep)
+		assert.Equal(t, ruleSampling, pt.TraceChunk.Tags[tagDecisionMaker])
+		assert.EqualValues(t, sampler.PriorityUserKeep, pt.TraceChunk.Priority)
+	})
+	t.Run("dropped by rule", func(t *testing.T) {
+		pt := newTrace("checkout", "200", sampler.PriorityAutoKeep)
+		keep, _ := a.runSamplers(now, info.NewReceiverStats().GetTagStats(info.Tags{}), pt)
+		assert.False(t, keep)
+		assert.NotContains(t, pt.TraceChunk.Tags, tagDecisionMaker)
+	})
+	t.Run("error dropped by rule", func(t *testing.T) {
+		for _, probabilistic := range []bool{false, true} {
+			cfg.ProbabilisticSamplerEnabled = probabilistic
+			pt := newTrace("checkout", "200", sampler.PriorityAutoKeep)
+			pt.Root.Error = 1
+			pt.Root.Metrics["_dd.rule_psr"] = 0.5
+			keep, _ := a.runSamplers(now, info.NewReceiverStats().GetTagStats(info.Tags{}), pt)
+			assert.True(t, keep)
+			assert.NotEqual(t, ruleSampling, pt.TraceChunk.Tags[tagDecisionMaker])
+			assert.Contains(t, pt.Root.Metrics, sampler.KeySamplingRateRule)
+			// the rate of the tracer sampling rules is kept
+			assert.Equal(t, 0.5, pt.Root.Metrics["_dd.rule_psr"])
+		}
+		cfg.ProbabilisticSamplerEnabled = false
+	})
+	t.Run("user keep wins", func(t *testing.T) {
+		pt := newTrace("checkout", "200", sampler.PriorityUserKeep)
+		keep, _ := a.runSamplers(now, info.NewReceiverStats().GetTagStats(info.Tags{}), pt)
+		assert.True(t, keep)
+	})
+	t.Run("user drop wins", func(t *testing.T) {
+		// the manual drop is only detected from the decision maker with this feature
+		cfg.Features["error_rare_sample_tracer_drop"] = struct{}{}
+		defer delete(cfg.Features, "error_rare_sample_tracer_drop")
+		pt := newTrace("checkout", "502", sampler.PriorityUserDrop)
+		keep, _ := a.runSamplers(now, info.NewReceiverStats().GetTagStats(info.Tags{}), pt)
+		assert.False(t, keep)
+		assert.NotEqual(t, ruleSampling, pt.TraceChunk.Tags[tagDecisionMaker])
+	})
+	t.Run("no matching rule", func(t *testing.T) {
+		pt := newTrace("frontend", "200", sampler.PriorityAutoKeep)
+		keep, _ := a.runSamplers(now, info.NewReceiverStats().GetTagStats(info.Tags{}), pt)
+		assert.True(t, keep)
+		assert.NotEqual(t, ruleSampling, pt.TraceChunk.Tags[tagDecisionMaker])
+	})
+	t.Run("probabilistic sampler", func(t *testing.T) {
+		cfg.ProbabilisticSamplerEnabled = true
+		defer func() { cfg.ProbabilisticSamplerEnabled = false }()
+		pt := newTrace("checkout", "200", sampler.PriorityAutoKeep)
+		keep, _ := a.runSamplers(now, info.NewReceiverStats().GetTagStats(info.Tags{}), pt)
+		assert.False(t, keep)
+
+		// the user decisions take precedence over the rules in both modes
+		for _, priority := range []sampler.SamplingPriority{sampler.PriorityUserKeep, sampler.PriorityUserDrop} {
+			pt = newTrace("checkout", "502", priority)
+			a.runSamplers(now, info.NewReceiverStats().GetTagStats(info.Tags{}), pt)
+			assert.NotEqual(t, ruleSampling, pt.TraceChunk.Tags[tagDecisionMaker])
+			assert.EqualValues(t, priority, pt.TraceChunk.Priority)
+		}
+	})
+}
+
+func TestProcessTailSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSamplingEnabled = true
+	cfg.TargetTPS = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+	tw := agnt.TraceWriter.(*mockTraceWriter)
+
+	now := time.Now()
+	newPayload := func(span *pb.Span) *api.Payload {
+		chunk := testutil.TraceChunkWithSpan(span)
+		chunk.Priority = int32(sampler.PriorityAutoDrop)
+		return &api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+			Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+		}
+	}
+
+	// the healthy part of the trace is dropped by the samplers, and buffered
+	agnt.Process(newPayload(&pb.Span{TraceID: 7, SpanID: 1, Service: "web", Name: "http.request", Resource: "GET /", Start: now.UnixNano(), Duration: 1}))
+	assert.Empty(t, tw.payloads)
+
+	// the error span received later pulls it along
+	agnt.Process(newPayload(&pb.Span{TraceID: 7, SpanID: 2, ParentID: 1, Service: "web", Name: "db.query", Resource: "SELECT", Start: now.UnixNano(), Duration: 1, Error: 1}))
+	var spans []uint64
+	for _, p := range tw.payloads {
+		for _, chunk := range p.TracerPayload.Chunks {
+			assert.False(t, chunk.DroppedTrace)
+			for _, span := range chunk.Spans {
+				spans = append(spans, span.SpanID)
+			}
+		}
+	}
+	assert.ElementsMatch(t, []uint64{1, 2}, spans)
+}
+
 func TestPartialSamplingFree(t *testing.T) {
 	cfg := &config.AgentConfig{RareSamplerEnabled: false, BucketInterval: 10 * time.Second}
 	dynConf := sampler.NewDynamicConfig()
@@ -2044,6 +2187,7 @@ func TestPartialSamplingFree(t *testing.T) {
 		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
 		EventProcessor:    newEventProcessor(cfg, statsd),
 		RareSampler:       sampler.NewRareSampler(config.New()),
+		RuleSampler:       sampler.NewRuleSampler(cfg),
 		SamplerMetrics:    sampler.NewMetrics(statsd),
 		TraceWriter:       &mockTraceWriter{},
 		conf:              cfg,
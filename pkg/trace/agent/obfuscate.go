@@ -93,6 +93,11 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 			return
 		}
 		span.Meta[tagMongoDBQuery] = o.ObfuscateMongoDBString(span.Meta[tagMongoDBQuery])
+	case "graphql":
+		if !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		transform.ObfuscateGraphQLSpan(o, span, a.conf.Obfuscation.GraphQL.QuantizeResource)
 	case "elasticsearch", "opensearch":
 		if span.Meta == nil {
 			return
@@ -170,6 +175,11 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 		}
 	case "redis", "valkey":
 		b.Resource = o.QuantizeRedisString(b.Resource)
+	case "graphql":
+		if a.conf.Obfuscation == nil || !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		b.Resource = transform.ObfuscateGraphQLResource(o, b.Resource, b.Resource, a.conf.Obfuscation.GraphQL.QuantizeResource)
 	}
 }
 
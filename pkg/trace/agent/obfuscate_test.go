@@ -296,6 +296,30 @@ func TestObfuscateConfig(t *testing.T) {
 		&config.ObfuscationConfig{},
 	))
 
+	t.Run("graphql/enabled", testConfig(
+		"graphql",
+		"graphql.source",
+		`query GetUser { user(id: "123", active: true) { name } }`,
+		`query GetUser { user(id: ?, active: ?) { name } }`,
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/non-parsable", testConfig(
+		"graphql",
+		"graphql.source",
+		`query { user(id: "123) { name } }`,
+		"Non-parsable GraphQL query",
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/disabled", testConfig(
+		"graphql",
+		"graphql.source",
+		`query GetUser { user(id: "123") { name } }`,
+		`query GetUser { user(id: "123") { name } }`,
+		&config.ObfuscationConfig{},
+	))
+
 	t.Run("creditcard", func(t *testing.T) {
 		for _, tt := range []struct {
 			k, v string
@@ -332,6 +356,57 @@ func SQLSpan(query string) *pb.Span {
 	}
 }
 
+func TestGraphQLResource(t *testing.T) {
+	document := `query GetUser { user(id: "123") { name } }`
+	for _, tt := range []struct {
+		name     string
+		resource string
+		quantize bool
+		out      string
+	}{
+		{"obfuscate", document, false, `query GetUser { user(id: ?) { name } }`},
+		{"quantize", document, true, "query GetUser"},
+		{"operation-name", "GetUser", false, "GetUser"},
+		{"quantize-from-tag", "GetUser", true, "query GetUser"},
+		{"span-name", "graphql.execute", false, "graphql.execute"},
+		{"http-route", "POST /graphql", false, "POST /graphql"},
+		{"malformed", `query GetUser { user(id: "123) { name } }`, false, "Non-parsable GraphQL query"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			agnt, stop := agentWithDefaults()
+			defer stop()
+			agnt.conf.Obfuscation = &config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{
+				Enabled:          true,
+				QuantizeResource: tt.quantize,
+			}}
+			span := &pb.Span{
+				Resource: tt.resource,
+				Type:     "graphql",
+				Meta:     map[string]string{"graphql.source": document},
+			}
+			agnt.obfuscateSpan(span)
+			assert.Equal(t, tt.out, span.Resource)
+			assert.Equal(t, `query GetUser { user(id: ?) { name } }`, span.Meta["graphql.source"])
+		})
+	}
+}
+
+func TestGraphQLStatsGroupResource(t *testing.T) {
+	for in, out := range map[string]string{
+		`query GetUser { user(id: "123") { name } }`: `query GetUser { user(id: ?) { name } }`,
+		`query GetUser { user(id: "123) { name } }`:  "Non-parsable GraphQL query",
+		"graphql.execute": "graphql.execute",
+		"POST /graphql":   "POST /graphql",
+	} {
+		agnt, stop := agentWithDefaults()
+		defer stop()
+		agnt.conf.Obfuscation = &config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{Enabled: true}}
+		b := &pb.ClientGroupedStats{Type: "graphql", Resource: in}
+		agnt.obfuscateStatsGroup(b)
+		assert.Equal(t, out, b.Resource)
+	}
+}
+
 func TestSQLResourceQuery(t *testing.T) {
 	assert := assert.New(t)
 	testCases := []*struct {
@@ -128,6 +128,10 @@ type ObfuscationConfig struct {
 	// for spans of type "memcached".
 	Memcached obfuscate.MemcachedConfig `mapstructure:"memcached"`
 
+	// GraphQL holds the configuration for obfuscating the "graphql.source" tag
+	// and the resource for spans of type "graphql".
+	GraphQL obfuscate.GraphQLConfig `mapstructure:"graphql"`
+
 	// CreditCards holds the configuration for obfuscating credit cards.
 	CreditCards obfuscate.CreditCardsConfig `mapstructure:"credit_cards"`
 
@@ -168,6 +172,7 @@ func (o *ObfuscationConfig) Export(conf *AgentConfig) obfuscate.Config {
 		Redis:                o.Redis,
 		Valkey:               o.Valkey,
 		Memcached:            o.Memcached,
+		GraphQL:              o.GraphQL,
 		CreditCard:           o.CreditCards,
 		Logger:               new(debugLogger),
 		Cache:                o.Cache,
@@ -209,6 +214,30 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// SamplingRule specifies an agent-side trace sampling rule. A rule applies to a trace chunk
+// when one of its spans matches all the conditions of the rule.
+type SamplingRule struct {
+	// Service, Name and Resource are glob patterns matched against the span service,
+	// operation name and resource. Empty patterns match everything.
+	Service  string `mapstructure:"service"`
+	Name     string `mapstructure:"name"`
+	Resource string `mapstructure:"resource"`
+
+	// Tags maps span tags to the glob patterns their values must match.
+	Tags map[string]string `mapstructure:"tags"`
+
+	// Metrics maps numeric span attributes to the range their values must be in, using the
+	// forms "500", ">=500", ">500", "<=500", "<500" or "500..599" (inclusive).
+	// Numeric values found in the span tags are also matched.
+	Metrics map[string]string `mapstructure:"metrics"`
+
+	// SampleRate is the rate (between 0 and 1) at which the matching traces are kept.
+	SampleRate float64 `mapstructure:"sample_rate"`
+
+	// MaxPerSecond limits the number of traces kept by the rule per second. Zero means no limit.
+	MaxPerSecond float64 `mapstructure:"max_per_second"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -343,6 +372,18 @@ type AgentConfig struct {
 	ProbabilisticSamplerHashSeed           uint32
 	ProbabilisticSamplerSamplingPercentage float32
 
+	// SamplingRules are evaluated in order on each trace chunk, the first matching rule
+	// makes the sampling decision.
+	SamplingRules []*SamplingRule
+
+	// Tail Sampling configuration
+	TailSamplingEnabled          bool
+	TailSamplingDecisionWait     time.Duration // how long the chunks of a trace are buffered before it is sampled
+	TailSamplingMaxBufferSize    int           // maximum size in bytes of the buffered chunks
+	TailSamplingErrors           bool          // keep the traces containing an error
+	TailSamplingLatencyThreshold time.Duration // keep the traces lasting at least this long, if not zero
+	TailSamplingTags             []*Tag        // keep the traces containing a span with one of these tags
+
 	// Error Tracking Standalone
 	ErrorTrackingStandalone bool
 
@@ -544,6 +585,10 @@ func New() *AgentConfig {
 		RareSamplerCooldownPeriod: 5 * time.Minute,
 		RareSamplerCardinality:    200,
 
+		TailSamplingDecisionWait:  10 * time.Second,
+		TailSamplingMaxBufferSize: 50 * 1024 * 1024,
+		TailSamplingErrors:        true,
+
 		ErrorTrackingStandalone: false,
 
 		ReceiverEnabled:        true,
@@ -38,6 +38,8 @@ const (
 	NameRare
 	// NameProbabilistic is the name of the probabilistic sampler.
 	NameProbabilistic
+	// NameRule is the name of the rule sampler.
+	NameRule
 )
 
 // String returns the string representation of the Name.
@@ -53,13 +55,15 @@ func (n Name) String() string {
 		return "rare"
 	case NameProbabilistic:
 		return "probabilistic"
+	case NameRule:
+		return "rule"
 	default:
 		return "unknown"
 	}
 }
 
 func (n Name) shouldAddEnvTag() bool {
-	return n == NamePriority || n == NameNoPriority || n == NameRare || n == NameError
+	return n == NamePriority || n == NameNoPriority || n == NameRare || n == NameError || n == NameRule
 }
 
 // Metrics is a structure to record metrics for the different samplers.
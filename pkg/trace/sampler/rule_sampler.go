@@ -0,0 +1,276 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+const (
+	// KeySamplingRateRule is the metric key holding the rate of the agent sampling rule which kept or dropped
+	// a trace. It differs from the key of the rate of the tracer sampling rules, which is left untouched.
+	KeySamplingRateRule = "_dd.agent_rule_sr"
+
+	// MetricsRuleMatched is the metric name for the number of traces matched by a sampling rule.
+	MetricsRuleMatched = "datadog.trace_agent.sampler.rule.matched"
+	// MetricsRuleKept is the metric name for the number of traces kept by a sampling rule.
+	MetricsRuleKept = "datadog.trace_agent.sampler.rule.kept"
+	// MetricsRuleLimited is the metric name for the number of traces dropped by the rate limit of a sampling rule.
+	MetricsRuleLimited = "datadog.trace_agent.sampler.rule.limited"
+)
+
+// RuleSampler samples traces according to the ordered sampling rules of the agent configuration.
+// The first rule matching a span of a trace chunk decides whether the chunk is kept, by sampling
+// it at the rule rate and within the rule rate limit.
+type RuleSampler struct {
+	rules []*samplingRule
+}
+
+// samplingRule is a compiled config.SamplingRule.
+type samplingRule struct {
+	tag      string
+	service  *regexp.Regexp
+	name     *regexp.Regexp
+	resource *regexp.Regexp
+	tags     map[string]*regexp.Regexp
+	metrics  map[string]numericRange
+	rate     float64
+	limiter  *rate.Limiter
+
+	matched *atomic.Int64
+	kept    *atomic.Int64
+	limited *atomic.Int64
+}
+
+// NewRuleSampler returns a RuleSampler applying the sampling rules of conf.
+// Invalid rules are logged and ignored.
+func NewRuleSampler(conf *config.AgentConfig) *RuleSampler {
+	s := &RuleSampler{}
+	for i, r := range conf.SamplingRules {
+		rule, err := newSamplingRule(r)
+		if err != nil {
+			log.Errorf("Ignoring sampling rule %d: %v", i, err)
+			continue
+		}
+		rule.tag = "rule:" + strconv.Itoa(i)
+		s.rules = append(s.rules, rule)
+	}
+	return s
+}
+
+func newSamplingRule(r *config.SamplingRule) (*samplingRule, error) {
+	if r.SampleRate < 0 || r.SampleRate > 1 {
+		return nil, fmt.Errorf("sample_rate must be between 0 and 1, got %v", r.SampleRate)
+	}
+	if r.MaxPerSecond < 0 {
+		return nil, fmt.Errorf("max_per_second must be positive, got %v", r.MaxPerSecond)
+	}
+	rule := &samplingRule{
+		service:  compileGlob(r.Service),
+		name:     compileGlob(r.Name),
+		resource: compileGlob(r.Resource),
+		tags:     make(map[string]*regexp.Regexp, len(r.Tags)),
+		metrics:  make(map[string]numericRange, len(r.Metrics)),
+		rate:     r.SampleRate,
+		limiter:  rate.NewLimiter(rate.Inf, 0),
+		matched:  atomic.NewInt64(0),
+		kept:     atomic.NewInt64(0),
+		limited:  atomic.NewInt64(0),
+	}
+	if r.MaxPerSecond > 0 {
+		rule.limiter = rate.NewLimiter(rate.Limit(r.MaxPerSecond), int(math.Ceil(r.MaxPerSecond)))
+	}
+	for k, v := range r.Tags {
+		rule.tags[k] = compileGlob(v)
+	}
+	for k, v := range r.Metrics {
+		nr, err := parseNumericRange(v)
+		if err != nil {
+			return nil, fmt.Errorf("metric %q: %v", k, err)
+		}
+		rule.metrics[k] = nr
+	}
+	return rule, nil
+}
+
+// Sample applies the sampling rules to the chunk. matched reports whether a rule applies to
+// the chunk, in which case keep is the sampling decision and the rule rate is set on root.
+func (s *RuleSampler) Sample(t *pb.TraceChunk, root *pb.Span) (keep bool, matched bool) {
+	for _, rule := range s.rules {
+		if !rule.matchChunk(t) {
+			continue
+		}
+		rule.matched.Inc()
+		setMetric(root, KeySamplingRateRule, rule.rate)
+		if !SampleByRate(root.TraceID, rule.rate) {
+			return false, true
+		}
+		if !rule.limiter.Allow() {
+			rule.limited.Inc()
+			return false, true
+		}
+		rule.kept.Inc()
+		return true, true
+	}
+	return false, false
+}
+
+// IsEnabled returns whether any sampling rule is configured.
+func (s *RuleSampler) IsEnabled() bool {
+	return len(s.rules) > 0
+}
+
+func (s *RuleSampler) report(statsd statsd.ClientInterface) {
+	for _, rule := range s.rules {
+		tags := []string{rule.tag}
+		_ = statsd.Count(MetricsRuleMatched, rule.matched.Swap(0), tags, 1)
+		_ = statsd.Count(MetricsRuleKept, rule.kept.Swap(0), tags, 1)
+		_ = statsd.Count(MetricsRuleLimited, rule.limited.Swap(0), tags, 1)
+	}
+}
+
+func (r *samplingRule) matchChunk(t *pb.TraceChunk) bool {
+	for _, span := range t.Spans {
+		if r.matchSpan(span) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *samplingRule) matchSpan(s *pb.Span) bool {
+	if !matchGlob(r.service, s.Service) || !matchGlob(r.name, s.Name) || !matchGlob(r.resource, s.Resource) {
+		return false
+	}
+	for k, glob := range r.tags {
+		v, ok := s.Meta[k]
+		if !ok {
+			m, ok := s.Metrics[k]
+			if !ok {
+				return false
+			}
+			v = strconv.FormatFloat(m, 'f', -1, 64)
+		}
+		if !glob.MatchString(v) {
+			return false
+		}
+	}
+	for k, nr := range r.metrics {
+		v, ok := s.Metrics[k]
+		if !ok {
+			meta, ok := s.Meta[k]
+			if !ok {
+				return false
+			}
+			var err error
+			if v, err = strconv.ParseFloat(meta, 64); err != nil {
+				return false
+			}
+		}
+		if !nr.contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// compileGlob compiles a glob pattern, where "*" matches any sequence of characters
+// and "?" any single character, into an anchored regular expression. It returns nil
+// for the empty and "*" patterns, which match everything.
+func compileGlob(glob string) *regexp.Regexp {
+	if glob == "" || glob == "*" {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i, part := range strings.Split(glob, "*") {
+		if i > 0 {
+			b.WriteString(".*")
+		}
+		b.WriteString(strings.ReplaceAll(regexp.QuoteMeta(part), `\?`, "."))
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func matchGlob(re *regexp.Regexp, s string) bool {
+	return re == nil || re.MatchString(s)
+}
+
+// numericRange is an interval of values, whose bounds are inclusive unless stated otherwise.
+type numericRange struct {
+	min, max                 float64
+	minExcluded, maxExcluded bool
+}
+
+// parseNumericRange parses ranges of the forms "500", ">=500", ">500", "<=500", "<500" and "500..599".
+func parseNumericRange(s string) (numericRange, error) {
+	nr := numericRange{min: math.Inf(-1), max: math.Inf(1)}
+	s = strings.TrimSpace(s)
+	var err error
+	switch {
+	case strings.HasPrefix(s, ">="):
+		nr.min, err = parseBound(s[2:])
+	case strings.HasPrefix(s, ">"):
+		nr.min, err = parseBound(s[1:])
+		nr.minExcluded = true
+	case strings.HasPrefix(s, "<="):
+		nr.max, err = parseBound(s[2:])
+	case strings.HasPrefix(s, "<"):
+		nr.max, err = parseBound(s[1:])
+		nr.maxExcluded = true
+	case strings.Contains(s, ".."):
+		lo, hi, _ := strings.Cut(s, "..")
+		if nr.min, err = parseBound(lo); err != nil {
+			return nr, err
+		}
+		if nr.max, err = parseBound(hi); err != nil {
+			return nr, err
+		}
+		if nr.min > nr.max {
+			return nr, fmt.Errorf("empty range %q", s)
+		}
+	default:
+		nr.min, err = parseBound(s)
+		nr.max = nr.min
+	}
+	return nr, err
+}
+
+func parseBound(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("missing range bound")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid range bound %q", s)
+	}
+	return v, nil
+}
+
+func (nr numericRange) contains(v float64) bool {
+	if v < nr.min || (nr.minExcluded && v == nr.min) {
+		return false
+	}
+	if v > nr.max || (nr.maxExcluded && v == nr.max) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+func TestRuleSampler(t *testing.T) {
+	conf := &config.AgentConfig{
+		SamplingRules: []*config.SamplingRule{
+			{Service: "checkout", Metrics: map[string]string{"http.status_code": ">=500"}, SampleRate: 1},
+			{Service: "checkout", Resource: "GET /health*", SampleRate: 0},
+			{Service: "check?ut", Tags: map[string]string{"env": "prod-*"}, SampleRate: 1, MaxPerSecond: 1},
+		},
+	}
+
+	t.Run("range on a tag", func(t *testing.T) {
+		s := NewRuleSampler(conf)
+		root := &pb.Span{TraceID: 1, Service: "checkout", Meta: map[string]string{"http.status_code": "503"}}
+		keep, matched := s.Sample(&pb.TraceChunk{Spans: []*pb.Span{root}}, root)
+		assert.True(t, matched)
+		assert.True(t, keep)
+		assert.Equal(t, 1.0, root.Metrics[KeySamplingRateRule])
+	})
+	t.Run("range on a child span metric", func(t *testing.T) {
+		s := NewRuleSampler(conf)
+		root := &pb.Span{TraceID: 1, Service: "frontend"}
+		child := &pb.Span{TraceID: 1, Service: "checkout", Metrics: map[string]float64{"http.status_code": 500}}
+		keep, matched := s.Sample(&pb.TraceChunk{Spans: []*pb.Span{root, child}}, root)
+		assert.True(t, matched)
+		assert.True(t, keep)
+	})
+	t.Run("drop rule", func(t *testing.T) {
+		s := NewRuleSampler(conf)
+		root := &pb.Span{TraceID: 1, Service: "checkout", Resource: "GET /healthz", Meta: map[string]string{"http.status_code": "200"}}
+		keep, matched := s.Sample(&pb.TraceChunk{Spans: []*pb.Span{root}}, root)
+		assert.True(t, matched)
+		assert.False(t, keep)
+		assert.Equal(t, 0.0, root.Metrics[KeySamplingRateRule])
+	})
+	t.Run("rate limit", func(t *testing.T) {
+		s := NewRuleSampler(conf)
+		root := &pb.Span{TraceID: 1, Service: "checkout", Meta: map[string]string{"env": "prod-eu"}}
+		chunk := &pb.TraceChunk{Spans: []*pb.Span{root}}
+		keep, matched := s.Sample(chunk, root)
+		assert.True(t, matched)
+		assert.True(t, keep)
+		keep, matched = s.Sample(chunk, root)
+		assert.True(t, matched)
+		assert.False(t, keep)
+		assert.EqualValues(t, 1, s.rules[2].limited.Load())
+	})
+	t.Run("no match", func(t *testing.T) {
+		s := NewRuleSampler(conf)
+		root := &pb.Span{TraceID: 1, Service: "checkout", Meta: map[string]string{"env": "staging"}}
+		keep, matched := s.Sample(&pb.TraceChunk{Spans: []*pb.Span{root}}, root)
+		assert.False(t, matched)
+		assert.False(t, keep)
+		assert.NotContains(t, root.Metrics, KeySamplingRateRule)
+	})
+}
+
+func TestRuleSamplerRate(t *testing.T) {
+	s := NewRuleSampler(&config.AgentConfig{
+		SamplingRules: []*config.SamplingRule{{Name: "http.request", SampleRate: 0.2}},
+	})
+	var kept int
+	for i := uint64(0); i < 10000; i++ {
+		root := &pb.Span{TraceID: i * 1000003, Name: "http.request"}
+		if keep, _ := s.Sample(&pb.TraceChunk{Spans: []*pb.Span{root}}, root); keep {
+			kept++
+		}
+	}
+	assert.InDelta(t, 2000, kept, 200)
+}
+
+func TestRuleSamplerInvalidRules(t *testing.T) {
+	s := NewRuleSampler(&config.AgentConfig{
+		SamplingRules: []*config.SamplingRule{
+			{SampleRate: 2},
+			{SampleRate: 1, MaxPerSecond: -1},
+			{SampleRate: 1, Metrics: map[string]string{"duration": ">="}},
+			{SampleRate: 1, Metrics: map[string]string{"duration": "10..1"}},
+			{Service: "web", SampleRate: 1},
+		},
+	})
+	require.Len(t, s.rules, 1)
+	assert.Equal(t, "rule:4", s.rules[0].tag)
+}
+
+func TestCompileGlob(t *testing.T) {
+	for _, tt := range []struct {
+		glob    string
+		value   string
+		matches bool
+	}{
+		{"", "anything", true},
+		{"*", "anything", true},
+		{"web", "web", true},
+		{"web", "web-api", false},
+		{"web*", "web-api", true},
+		{"*api", "web-api", true},
+		{"w?b", "wab", true},
+		{"w?b", "wb", false},
+		{"GET /users/*", "GET /users/42", true},
+		{"a.b", "axb", false},
+	} {
+		assert.Equal(t, tt.matches, matchGlob(compileGlob(tt.glob), tt.value), "%q ~ %q", tt.glob, tt.value)
+	}
+}
+
+func TestParseNumericRange(t *testing.T) {
+	for _, tt := range []struct {
+		expr     string
+		included []float64
+		excluded []float64
+	}{
+		{"500", []float64{500}, []float64{499, 501}},
+		{">=500", []float64{500, 599}, []float64{499}},
+		{">500", []float64{501}, []float64{500}},
+		{"<=1.5", []float64{1.5, -3}, []float64{1.6}},
+		{"<1.5", []float64{1.4}, []float64{1.5}},
+		{"400..499", []float64{400, 499}, []float64{399, 500}},
+	} {
+		nr, err := parseNumericRange(tt.expr)
+		require.NoError(t, err, tt.expr)
+		for _, v := range tt.included {
+			assert.True(t, nr.contains(v), "%v in %q", v, tt.expr)
+		}
+		for _, v := range tt.excluded {
+			assert.False(t, nr.contains(v), "%v not in %q", v, tt.expr)
+		}
+	}
+	for _, expr := range []string{"", ">=", "abc", "1..", "..2", "5..1"} {
+		_, err := parseNumericRange(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,354 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package tailsampling buffers the trace chunks dropped by the agent samplers until the
+// complete trace can be sampled by the tail sampling policy.
+package tailsampling
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+const (
+	// decisionInterval is the frequency at which expired traces are sampled.
+	decisionInterval = time.Second
+
+	// ReasonBufferFull is the drop reason of the traces evicted because the buffer is full.
+	ReasonBufferFull = "buffer_full"
+	// ReasonMemoryPressure is the drop reason of the traces evicted because the agent uses too much memory.
+	ReasonMemoryPressure = "memory_pressure"
+	// ReasonPolicy is the drop reason of the traces not matching the tail sampling policy.
+	ReasonPolicy = "policy"
+
+	// MetricKept is the metric name for the number of traces kept by the tail sampler.
+	MetricKept = "datadog.trace_agent.tail_sampler.kept"
+	// MetricDropped is the metric name for the number of traces dropped by the tail sampler, tagged by reason.
+	MetricDropped = "datadog.trace_agent.tail_sampler.dropped"
+	// MetricBufferSize is the metric name for the size in bytes of the buffered chunks.
+	MetricBufferSize = "datadog.trace_agent.tail_sampler.buffer_size"
+	// MetricBufferedTraces is the metric name for the number of buffered traces.
+	MetricBufferedTraces = "datadog.trace_agent.tail_sampler.buffered_traces"
+)
+
+// WriteFunc receives the chunks sampled by the tail sampler.
+type WriteFunc func(pkg *writer.SampledChunks)
+
+// Sampler buffers the chunks dropped by the agent samplers for each trace, so that a trace
+// whose spans are received across several payloads is sampled as a whole. Once the decision
+// wait is over, a trace is kept entirely when one of its chunks was kept by the agent samplers
+// or when it matches the policy: it contains an error, lasts longer than the latency threshold
+// or has a span with one of the policy tags. Otherwise, the buffered chunks are written as the
+// agent samplers left them.
+type Sampler struct {
+	conf   *config.AgentConfig
+	out    WriteFunc
+	statsd statsd.ClientInterface
+
+	// memAlloc returns the memory allocated by the agent; replaced in tests.
+	memAlloc func() uint64
+
+	mu      sync.Mutex
+	traces  map[uint64]*bufferedTrace
+	order   *list.List // buffered traces, oldest first
+	decided map[uint64]decision
+	size    int
+	kept    int64
+	dropped map[string]int64
+
+	exit chan struct{}
+	done chan struct{}
+}
+
+// bufferedTrace holds the chunks of a trace waiting for a sampling decision.
+type bufferedTrace struct {
+	id     uint64
+	expire time.Time
+	elem   *list.Element
+	chunks []bufferedChunk
+	size   int
+
+	// keep is set once a chunk of the trace is kept by the agent samplers or matches the policy.
+	keep bool
+	// pulled is set once chunks dropped by the agent samplers are written as part of the kept trace.
+	pulled     bool
+	start, end int64
+}
+
+type bufferedChunk struct {
+	// header holds the attributes of the tracer payload the chunk was received in.
+	header *pb.TracerPayload
+	// full is the chunk with all its spans, written when the trace is kept.
+	full *pb.TraceChunk
+	// sampled is the chunk as left by the agent samplers, written when the trace is dropped.
+	sampled *pb.TraceChunk
+}
+
+// decision is a recent sampling decision, applied to the chunks received after it was made.
+type decision struct {
+	keep   bool
+	expire time.Time
+}
+
+// NewSampler returns a tail Sampler writing the sampled chunks to out.
+func NewSampler(conf *config.AgentConfig, out WriteFunc, statsd statsd.ClientInterface) *Sampler {
+	info := watchdog.NewCurrentInfo()
+	return &Sampler{
+		conf:     conf,
+		out:      out,
+		statsd:   statsd,
+		memAlloc: func() uint64 { return info.Mem().Alloc },
+		traces:   make(map[uint64]*bufferedTrace),
+		order:    list.New(),
+		decided:  make(map[uint64]decision),
+		dropped:  make(map[string]int64),
+		exit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start starts sampling the buffered traces.
+func (s *Sampler) Start() {
+	if !s.conf.TailSamplingEnabled {
+		return
+	}
+	go func() {
+		defer watchdog.LogOnPanic(s.statsd)
+		s.run()
+	}()
+}
+
+func (s *Sampler) run() {
+	defer close(s.done)
+	decide := time.NewTicker(decisionInterval)
+	defer decide.Stop()
+	wd := time.NewTicker(s.conf.WatchdogInterval)
+	defer wd.Stop()
+	for {
+		select {
+		case now := <-decide.C:
+			s.decideExpired(now)
+		case <-wd.C:
+			s.watchdog()
+			s.report()
+		case <-s.exit:
+			return
+		}
+	}
+}
+
+// Stop stops the sampler and samples all the buffered traces.
+func (s *Sampler) Stop() {
+	if !s.conf.TailSamplingEnabled {
+		return
+	}
+	close(s.exit)
+	<-s.done
+	s.decideExpired(time.Now().Add(s.conf.TailSamplingDecisionWait))
+	s.report()
+}
+
+// Add records a chunk once the agent samplers ran on it. full is the chunk before sampling,
+// sampled the chunk as left by the samplers and kept their decision. tp holds the attributes
+// of the payload the chunk was received in. Add reports whether the chunk is now owned by the
+// sampler, in which case the caller must not write it.
+func (s *Sampler) Add(now time.Time, tp *pb.TracerPayload, full, sampled *pb.TraceChunk, kept bool) bool {
+	if len(full.Spans) == 0 {
+		return false
+	}
+	id := full.Spans[0].TraceID
+
+	var out []*writer.SampledChunks
+	defer func() { s.write(out) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d, ok := s.decided[id]; ok && now.Before(d.expire) {
+		// the trace was already sampled, this chunk was received too late to be buffered
+		if kept || !d.keep {
+			return false
+		}
+		out = append(out, keptChunks(bufferedChunk{header: tp.Cut(0), full: full}))
+		return true
+	}
+
+	t, ok := s.traces[id]
+	if !ok {
+		t = &bufferedTrace{id: id, expire: now.Add(s.conf.TailSamplingDecisionWait), start: full.Spans[0].Start}
+		t.elem = s.order.PushBack(t)
+		s.traces[id] = t
+	}
+	s.observe(t, full)
+	if kept {
+		t.keep = true
+	}
+	if t.keep {
+		// the chunks of a kept trace don't need to wait for the decision
+		for _, c := range t.chunks {
+			out = append(out, keptChunks(c))
+			t.pulled = true
+		}
+		s.size -= t.size
+		t.chunks, t.size = nil, 0
+		if kept {
+			return false
+		}
+		out = append(out, keptChunks(bufferedChunk{header: tp.Cut(0), full: full}))
+		t.pulled = true
+		return true
+	}
+
+	t.chunks = append(t.chunks, bufferedChunk{header: tp.Cut(0), full: full, sampled: sampled})
+	size := full.Msgsize()
+	t.size += size
+	s.size += size
+	for s.size > s.conf.TailSamplingMaxBufferSize && s.order.Len() > 0 {
+		out = append(out, s.decide(s.order.Front().Value.(*bufferedTrace), ReasonBufferFull, now)...)
+	}
+	return true
+}
+
+// observe updates the policy state of t with the spans of chunk.
+func (s *Sampler) observe(t *bufferedTrace, chunk *pb.TraceChunk) {
+	for _, span := range chunk.Spans {
+		if span.Start < t.start {
+			t.start = span.Start
+		}
+		if end := span.Start + span.Duration; end > t.end {
+			t.end = end
+		}
+		if t.keep {
+			continue
+		}
+		if s.conf.TailSamplingErrors && span.Error != 0 {
+			t.keep = true
+		}
+		for _, tag := range s.conf.TailSamplingTags {
+			if v, ok := span.Meta[tag.K]; ok && (tag.V == "" || v == tag.V) {
+				t.keep = true
+			}
+		}
+	}
+}
+
+// decideExpired samples the traces whose decision wait is over at now.
+func (s *Sampler) decideExpired(now time.Time) {
+	var out []*writer.SampledChunks
+	s.mu.Lock()
+	for e := s.order.Front(); e != nil; e = s.order.Front() {
+		t := e.Value.(*bufferedTrace)
+		if now.Before(t.expire) {
+			break
+		}
+		out = append(out, s.decide(t, ReasonPolicy, now)...)
+	}
+	for id, d := range s.decided {
+		if !now.Before(d.expire) {
+			delete(s.decided, id)
+		}
+	}
+	s.mu.Unlock()
+	s.write(out)
+}
+
+// watchdog evicts the oldest traces, until half of the buffer is freed, when the agent
+// uses more memory than allowed.
+func (s *Sampler) watchdog() {
+	if s.conf.MaxMemory <= 0 {
+		return
+	}
+	if alloc := s.memAlloc(); float64(alloc) <= s.conf.MaxMemory {
+		return
+	}
+	var out []*writer.SampledChunks
+	now := time.Now()
+	s.mu.Lock()
+	target := s.size / 2
+	for s.size > target && s.order.Len() > 0 {
+		out = append(out, s.decide(s.order.Front().Value.(*bufferedTrace), ReasonMemoryPressure, now)...)
+	}
+	s.mu.Unlock()
+	if len(out) > 0 {
+		log.Warnf("Tail sampler evicted %d chunks: memory threshold exceeded", len(out))
+	}
+	s.write(out)
+}
+
+// decide removes t from the buffer and returns the chunks to write. reason is recorded when
+// the trace is dropped. s.mu must be held.
+func (s *Sampler) decide(t *bufferedTrace, reason string, now time.Time) []*writer.SampledChunks {
+	s.order.Remove(t.elem)
+	delete(s.traces, t.id)
+	s.size -= t.size
+
+	keep := t.keep || (s.conf.TailSamplingLatencyThreshold > 0 && time.Duration(t.end-t.start) >= s.conf.TailSamplingLatencyThreshold)
+	s.decided[t.id] = decision{keep: keep, expire: now.Add(s.conf.TailSamplingDecisionWait)}
+
+	out := make([]*writer.SampledChunks, 0, len(t.chunks))
+	if keep {
+		if t.pulled || len(t.chunks) > 0 {
+			s.kept++
+		}
+		for _, c := range t.chunks {
+			out = append(out, keptChunks(c))
+		}
+		return out
+	}
+	s.dropped[reason]++
+	for _, c := range t.chunks {
+		if len(c.sampled.Spans) == 0 {
+			continue
+		}
+		c.header.Chunks = []*pb.TraceChunk{c.sampled}
+		sc := &writer.SampledChunks{TracerPayload: c.header, Size: c.sampled.Msgsize()}
+		if !c.sampled.DroppedTrace {
+			sc.SpanCount = int64(len(c.sampled.Spans))
+		}
+		out = append(out, sc)
+	}
+	return out
+}
+
+// keptChunks returns the full chunk of c, marked as kept.
+func keptChunks(c bufferedChunk) *writer.SampledChunks {
+	c.full.DroppedTrace = false
+	c.full.Priority = int32(sampler.PriorityUserKeep)
+	c.header.Chunks = []*pb.TraceChunk{c.full}
+	return &writer.SampledChunks{
+		TracerPayload: c.header,
+		Size:          c.full.Msgsize(),
+		SpanCount:     int64(len(c.full.Spans)),
+	}
+}
+
+func (s *Sampler) write(out []*writer.SampledChunks) {
+	for _, sc := range out {
+		s.out(sc)
+	}
+}
+
+func (s *Sampler) report() {
+	s.mu.Lock()
+	kept, dropped := s.kept, s.dropped
+	s.kept, s.dropped = 0, make(map[string]int64)
+	size, traces := s.size, s.order.Len()
+	s.mu.Unlock()
+
+	_ = s.statsd.Count(MetricKept, kept, nil, 1)
+	for reason, n := range dropped {
+		_ = s.statsd.Count(MetricDropped, n, []string{"reason:" + reason}, 1)
+	}
+	_ = s.statsd.Gauge(MetricBufferSize, float64(size), nil, 1)
+	_ = s.statsd.Gauge(MetricBufferedTraces, float64(traces), nil, 1)
+}
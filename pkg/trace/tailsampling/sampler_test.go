@@ -0,0 +1,225 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tailsampling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+type testWriter struct {
+	written []*writer.SampledChunks
+}
+
+func (w *testWriter) write(pkg *writer.SampledChunks) {
+	w.written = append(w.written, pkg)
+}
+
+// spans returns the IDs of the written spans, and whether they were written as part of a kept trace.
+func (w *testWriter) spans() map[uint64]bool {
+	spans := make(map[uint64]bool)
+	for _, pkg := range w.written {
+		for _, chunk := range pkg.TracerPayload.Chunks {
+			for _, span := range chunk.Spans {
+				spans[span.SpanID] = !chunk.DroppedTrace
+			}
+		}
+	}
+	return spans
+}
+
+func newTestSampler() (*Sampler, *testWriter) {
+	conf := config.New()
+	conf.TailSamplingEnabled = true
+	w := &testWriter{}
+	return NewSampler(conf, w.write, &statsd.NoOpClient{}), w
+}
+
+func newChunk(traceID uint64, spans ...*pb.Span) (full, sampled *pb.TraceChunk) {
+	for _, span := range spans {
+		span.TraceID = traceID
+	}
+	full = &pb.TraceChunk{Priority: int32(sampler.PriorityAutoDrop), Spans: spans, Tags: map[string]string{}}
+	sampled = full.ShallowCopy()
+	sampled.DroppedTrace = true
+	sampled.Spans = nil
+	return full, sampled
+}
+
+func TestSamplerKeepsTraceWithLateError(t *testing.T) {
+	s, w := newTestSampler()
+	tp := &pb.TracerPayload{Env: "prod"}
+	now := time.Now()
+
+	full, sampled := newChunk(1, &pb.Span{SpanID: 1, Start: 100, Duration: 10})
+	assert.True(t, s.Add(now, tp, full, sampled, false))
+	assert.Empty(t, w.written)
+
+	// the error is received in a later payload, it pulls the buffered chunk along
+	full, sampled = newChunk(1, &pb.Span{SpanID: 2, Start: 105, Duration: 1, Error: 1})
+	assert.True(t, s.Add(now.Add(time.Second), tp, full, sampled, false))
+	assert.Equal(t, map[uint64]bool{1: true, 2: true}, w.spans())
+	for _, pkg := range w.written {
+		assert.Equal(t, "prod", pkg.TracerPayload.Env)
+		assert.EqualValues(t, sampler.PriorityUserKeep, pkg.TracerPayload.Chunks[0].Priority)
+	}
+	assert.Zero(t, s.size)
+
+	// chunks received after the decision are kept as well
+	s.decideExpired(now.Add(time.Minute))
+	full, sampled = newChunk(1, &pb.Span{SpanID: 3})
+	assert.True(t, s.Add(now.Add(time.Minute), tp, full, sampled, false))
+	assert.True(t, w.spans()[3])
+	assert.EqualValues(t, 1, s.kept)
+}
+
+func TestSamplerKeepsTraceKeptByHeadSamplers(t *testing.T) {
+	s, w := newTestSampler()
+	tp := &pb.TracerPayload{}
+	now := time.Now()
+
+	full, sampled := newChunk(1, &pb.Span{SpanID: 1})
+	assert.True(t, s.Add(now, tp, full, sampled, false))
+
+	// chunks kept by the agent samplers are written by the caller
+	full, _ = newChunk(1, &pb.Span{SpanID: 2})
+	assert.False(t, s.Add(now, tp, full, full, true))
+	assert.Equal(t, map[uint64]bool{1: true}, w.spans())
+}
+
+func TestSamplerDropsTrace(t *testing.T) {
+	s, w := newTestSampler()
+	tp := &pb.TracerPayload{}
+	now := time.Now()
+
+	full, sampled := newChunk(1, &pb.Span{SpanID: 1}, &pb.Span{SpanID: 2, Metrics: map[string]float64{sampler.KeySpanSamplingMechanism: 8}})
+	// the single span sampled span is left by the agent samplers
+	sampled.Spans = full.Spans[1:]
+	assert.True(t, s.Add(now, tp, full, sampled, false))
+	full, sampled = newChunk(2, &pb.Span{SpanID: 3})
+	assert.True(t, s.Add(now.Add(time.Second), tp, full, sampled, false))
+
+	s.decideExpired(now.Add(s.conf.TailSamplingDecisionWait))
+	assert.Equal(t, map[uint64]bool{2: false}, w.spans())
+	assert.Len(t, s.traces, 1)
+	assert.EqualValues(t, 1, s.dropped[ReasonPolicy])
+
+	// chunks received after the decision are left to the caller
+	full, sampled = newChunk(1, &pb.Span{SpanID: 4})
+	assert.False(t, s.Add(now.Add(s.conf.TailSamplingDecisionWait), tp, full, sampled, false))
+}
+
+func TestSamplerPolicy(t *testing.T) {
+	for name, tt := range map[string]struct {
+		conf func(*config.AgentConfig)
+		span *pb.Span
+		keep bool
+	}{
+		"error": {
+			span: &pb.Span{Error: 1},
+			keep: true,
+		},
+		"errors disabled": {
+			conf: func(c *config.AgentConfig) { c.TailSamplingErrors = false },
+			span: &pb.Span{Error: 1},
+			keep: false,
+		},
+		"latency": {
+			conf: func(c *config.AgentConfig) { c.TailSamplingLatencyThreshold = time.Second },
+			span: &pb.Span{Start: 0, Duration: int64(2 * time.Second)},
+			keep: true,
+		},
+		"fast": {
+			conf: func(c *config.AgentConfig) { c.TailSamplingLatencyThreshold = time.Second },
+			span: &pb.Span{Start: 0, Duration: int64(time.Millisecond)},
+			keep: false,
+		},
+		"tag": {
+			conf: func(c *config.AgentConfig) { c.TailSamplingTags = []*config.Tag{{K: "customer.tier", V: "premium"}} },
+			span: &pb.Span{Meta: map[string]string{"customer.tier": "premium"}},
+			keep: true,
+		},
+		"tag key": {
+			conf: func(c *config.AgentConfig) { c.TailSamplingTags = []*config.Tag{{K: "debug"}} },
+			span: &pb.Span{Meta: map[string]string{"debug": "1"}},
+			keep: true,
+		},
+		"other tag value": {
+			conf: func(c *config.AgentConfig) { c.TailSamplingTags = []*config.Tag{{K: "customer.tier", V: "premium"}} },
+			span: &pb.Span{Meta: map[string]string{"customer.tier": "free"}},
+			keep: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, w := newTestSampler()
+			if tt.conf != nil {
+				tt.conf(s.conf)
+			}
+			now := time.Now()
+			tt.span.SpanID = 1
+			full, sampled := newChunk(1, tt.span)
+			require.True(t, s.Add(now, &pb.TracerPayload{}, full, sampled, false))
+			s.decideExpired(now.Add(s.conf.TailSamplingDecisionWait))
+			if tt.keep {
+				assert.Equal(t, map[uint64]bool{1: true}, w.spans())
+			} else {
+				assert.Empty(t, w.spans())
+			}
+		})
+	}
+}
+
+func TestSamplerEviction(t *testing.T) {
+	s, w := newTestSampler()
+	tp := &pb.TracerPayload{}
+	now := time.Now()
+
+	full, sampled := newChunk(1, &pb.Span{SpanID: 1, Service: "web"})
+	s.conf.TailSamplingMaxBufferSize = full.Msgsize() * 3
+	for id := uint64(1); id <= 4; id++ {
+		full, sampled = newChunk(id, &pb.Span{SpanID: id, Service: "web"})
+		assert.True(t, s.Add(now, tp, full, sampled, false))
+	}
+	// the oldest trace is evicted to stay within the budget
+	assert.Len(t, s.traces, 3)
+	assert.NotContains(t, s.traces, uint64(1))
+	assert.EqualValues(t, 1, s.dropped[ReasonBufferFull])
+
+	// memory pressure frees half of the buffer
+	s.memAlloc = func() uint64 { return uint64(s.conf.MaxMemory) + 1 }
+	s.watchdog()
+	assert.Len(t, s.traces, 1)
+	assert.Contains(t, s.traces, uint64(4))
+	assert.EqualValues(t, 2, s.dropped[ReasonMemoryPressure])
+	assert.Empty(t, w.spans())
+}
+
+func TestSamplerStop(t *testing.T) {
+	s, w := newTestSampler()
+	s.Start()
+
+	full, sampled := newChunk(1, &pb.Span{SpanID: 1, Error: 1})
+	full.Spans = append(full.Spans, &pb.Span{TraceID: 1, SpanID: 2})
+	assert.True(t, s.Add(time.Now(), &pb.TracerPayload{}, full, sampled, false))
+	full, sampled = newChunk(2, &pb.Span{SpanID: 3})
+	full.Spans[0].Error = 0
+	sampled.Spans = full.Spans
+	assert.True(t, s.Add(time.Now(), &pb.TracerPayload{}, full, sampled, false))
+
+	// buffered traces are sampled on stop
+	s.Stop()
+	assert.Empty(t, s.traces)
+	assert.Equal(t, map[uint64]bool{1: true, 2: true, 3: false}, w.spans())
+}
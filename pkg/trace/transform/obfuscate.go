@@ -6,6 +6,8 @@
 package transform
 
 import (
+	"strings"
+
 	"github.com/DataDog/datadog-agent/pkg/obfuscate"
 	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
@@ -30,11 +32,15 @@ const (
 	TagHTTPURL = "http.url"
 	// TagDBMS represents a DBMS tag
 	TagDBMS = "db.type"
+	// TagGraphQLSource represents a GraphQL document tag
+	TagGraphQLSource = "graphql.source"
 )
 
 const (
 	// TextNonParsable is the error text used when a query is non-parsable
 	TextNonParsable = "Non-parsable SQL query"
+	// TextNonParsableGraphQL is the error text used when a GraphQL document is non-parsable
+	TextNonParsableGraphQL = "Non-parsable GraphQL query"
 )
 
 // ObfuscateSQLSpan obfuscates a SQL span using pkg/obfuscate logic
@@ -80,3 +86,39 @@ func ObfuscateValkeySpan(o *obfuscate.Obfuscator, span *pb.Span, removeAllArgs b
 	}
 	span.Meta[TagValkeyRawCommand] = o.ObfuscateRedisString(span.Meta[TagValkeyRawCommand])
 }
+
+// ObfuscateGraphQLSpan obfuscates a GraphQL span using pkg/obfuscate logic. When quantizeResource
+// is set, the resource is replaced by the type and name of the operation found in the document.
+func ObfuscateGraphQLSpan(o *obfuscate.Obfuscator, span *pb.Span, quantizeResource bool) {
+	document := span.Meta[TagGraphQLSource]
+	if document != "" {
+		oq, err := o.ObfuscateGraphQLString(document)
+		if err != nil {
+			oq = TextNonParsableGraphQL
+		}
+		span.Meta[TagGraphQLSource] = oq
+	} else {
+		document = span.Resource
+	}
+	span.Resource = ObfuscateGraphQLResource(o, span.Resource, document, quantizeResource)
+}
+
+// ObfuscateGraphQLResource returns the obfuscated version of a GraphQL resource. When quantizeResource
+// is set and an operation can be found in document, its type and name are returned instead.
+// Resources which can't be GraphQL documents as they have no selection set, e.g. "graphql.execute"
+// or "POST /graphql", are returned unchanged, while documents which can't be parsed are replaced.
+func ObfuscateGraphQLResource(o *obfuscate.Obfuscator, resource, document string, quantizeResource bool) string {
+	if quantizeResource {
+		if q := o.QuantizeGraphQLString(document); q != "" {
+			return q
+		}
+	}
+	if !strings.Contains(resource, "{") {
+		return resource
+	}
+	oq, err := o.ObfuscateGraphQLString(resource)
+	if err != nil {
+		return TextNonParsableGraphQL
+	}
+	return oq
+}
@@ -0,0 +1,188 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package relabel implements the Prometheus relabeling rules
+package relabel
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MetricNameLabel is the label holding the name of a metric during relabeling, as in Prometheus.
+const MetricNameLabel = "__name__"
+
+// Relabeling actions, with the semantics of the Prometheus relabel_configs.
+const (
+	actionReplace   = "replace"
+	actionKeep      = "keep"
+	actionDrop      = "drop"
+	actionKeepEqual = "keepequal"
+	actionDropEqual = "dropequal"
+	actionHashMod   = "hashmod"
+	actionLabelMap  = "labelmap"
+	actionLabelDrop = "labeldrop"
+	actionLabelKeep = "labelkeep"
+	actionLowercase = "lowercase"
+	actionUppercase = "uppercase"
+)
+
+// Config is a relabeling rule applied to the labels of a series.
+type Config struct {
+	SourceLabels []string `yaml:"source_labels" mapstructure:"source_labels" json:"source_labels"`
+	Separator    *string  `yaml:"separator" mapstructure:"separator" json:"separator"`
+	Regex        *string  `yaml:"regex" mapstructure:"regex" json:"regex"`
+	Modulus      uint64   `yaml:"modulus" mapstructure:"modulus" json:"modulus"`
+	TargetLabel  string   `yaml:"target_label" mapstructure:"target_label" json:"target_label"`
+	Replacement  *string  `yaml:"replacement" mapstructure:"replacement" json:"replacement"`
+	Action       string   `yaml:"action" mapstructure:"action" json:"action"`
+}
+
+// Rule is a validated Config.
+type Rule struct {
+	sourceLabels []string
+	separator    string
+	regex        *regexp.Regexp
+	modulus      uint64
+	targetLabel  string
+	replacement  string
+	action       string
+}
+
+// NewRule validates a relabeling rule.
+func NewRule(cfg Config) (*Rule, error) {
+	rule := &Rule{
+		sourceLabels: cfg.SourceLabels,
+		separator:    ";",
+		modulus:      cfg.Modulus,
+		targetLabel:  cfg.TargetLabel,
+		replacement:  "$1",
+		action:       strings.ToLower(cfg.Action),
+	}
+	if cfg.Separator != nil {
+		rule.separator = *cfg.Separator
+	}
+	if cfg.Replacement != nil {
+		rule.replacement = *cfg.Replacement
+	}
+	if rule.action == "" {
+		rule.action = actionReplace
+	}
+	expr := "(.*)"
+	if cfg.Regex != nil {
+		expr = *cfg.Regex
+	}
+	regex, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid relabeling regex %q: %w", expr, err)
+	}
+	rule.regex = regex
+
+	switch rule.action {
+	case actionReplace, actionLowercase, actionUppercase, actionKeepEqual, actionDropEqual:
+		if rule.targetLabel == "" {
+			return nil, fmt.Errorf("relabeling action %q requires a target_label", rule.action)
+		}
+	case actionHashMod:
+		if rule.targetLabel == "" || rule.modulus == 0 {
+			return nil, fmt.Errorf("relabeling action %q requires a target_label and a non-zero modulus", rule.action)
+		}
+	case actionKeep, actionDrop, actionLabelMap, actionLabelDrop, actionLabelKeep:
+	default:
+		return nil, fmt.Errorf("unknown relabeling action %q", cfg.Action)
+	}
+	return rule, nil
+}
+
+// NewRules validates a list of relabeling rules.
+func NewRules(configs []Config) ([]*Rule, error) {
+	rules := make([]*Rule, 0, len(configs))
+	for _, cfg := range configs {
+		rule, err := NewRule(cfg)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Apply applies the rules to labels in order, and returns false if the series is dropped.
+// labels is modified in place.
+func Apply(rules []*Rule, labels map[string]string) bool {
+	for _, rule := range rules {
+		if !rule.apply(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Rule) apply(labels map[string]string) bool {
+	values := make([]string, 0, len(r.sourceLabels))
+	for _, name := range r.sourceLabels {
+		values = append(values, labels[name])
+	}
+	value := strings.Join(values, r.separator)
+
+	switch r.action {
+	case actionKeep:
+		return r.regex.MatchString(value)
+	case actionDrop:
+		return !r.regex.MatchString(value)
+	case actionKeepEqual:
+		return value == labels[r.targetLabel]
+	case actionDropEqual:
+		return value != labels[r.targetLabel]
+	case actionReplace:
+		indexes := r.regex.FindStringSubmatchIndex(value)
+		if indexes == nil {
+			return true
+		}
+		target := string(r.regex.ExpandString(nil, r.targetLabel, value, indexes))
+		result := string(r.regex.ExpandString(nil, r.replacement, value, indexes))
+		if target == "" {
+			return true
+		}
+		if result == "" {
+			delete(labels, target)
+		} else {
+			labels[target] = result
+		}
+	case actionLowercase:
+		labels[r.targetLabel] = strings.ToLower(value)
+	case actionUppercase:
+		labels[r.targetLabel] = strings.ToUpper(value)
+	case actionHashMod:
+		sum := md5.Sum([]byte(value))
+		labels[r.targetLabel] = fmt.Sprint(binary.BigEndian.Uint64(sum[8:]) % r.modulus)
+	case actionLabelMap:
+		mapped := make(map[string]string)
+		for name, v := range labels {
+			if r.regex.MatchString(name) {
+				mapped[r.regex.ReplaceAllString(name, r.replacement)] = v
+			}
+		}
+		for name, v := range mapped {
+			labels[name] = v
+		}
+	case actionLabelDrop:
+		for name := range labels {
+			if name != MetricNameLabel && r.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	case actionLabelKeep:
+		for name := range labels {
+			if name != MetricNameLabel && !r.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package relabel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRelabel(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		rules    string
+		labels   map[string]string
+		expected map[string]string
+	}{
+		{
+			name:     "replace",
+			rules:    `[{source_labels: [pod, container], separator: "/", regex: "(.*)/(.*)", target_label: workload, replacement: "$1-$2"}]`,
+			labels:   map[string]string{"pod": "web", "container": "nginx"},
+			expected: map[string]string{"pod": "web", "container": "nginx", "workload": "web-nginx"},
+		},
+		{
+			name:     "replace without match",
+			rules:    `[{source_labels: [pod], regex: "db.*", target_label: tier, replacement: "storage"}]`,
+			labels:   map[string]string{"pod": "web"},
+			expected: map[string]string{"pod": "web"},
+		},
+		{
+			name:     "rename the metric",
+			rules:    `[{source_labels: [__name__], regex: "go_(.*)", target_label: __name__, replacement: "golang_$1"}]`,
+			labels:   map[string]string{"__name__": "go_goroutines"},
+			expected: map[string]string{"__name__": "golang_goroutines"},
+		},
+		{
+			name:     "drop",
+			rules:    `[{source_labels: [__name__], regex: "go_.*", action: drop}]`,
+			labels:   map[string]string{"__name__": "go_goroutines"},
+			expected: nil,
+		},
+		{
+			name:     "keep",
+			rules:    `[{source_labels: [env], regex: "prod", action: keep}]`,
+			labels:   map[string]string{"env": "staging"},
+			expected: nil,
+		},
+		{
+			name:     "labelmap",
+			rules:    `[{regex: "k8s_(.*)", action: labelmap}]`,
+			labels:   map[string]string{"k8s_pod": "web"},
+			expected: map[string]string{"k8s_pod": "web", "pod": "web"},
+		},
+		{
+			name:     "labeldrop",
+			rules:    `[{regex: "k8s_.*", action: labeldrop}]`,
+			labels:   map[string]string{"__name__": "up", "k8s_pod": "web", "env": "prod"},
+			expected: map[string]string{"__name__": "up", "env": "prod"},
+		},
+		{
+			name:     "labelkeep",
+			rules:    `[{regex: "env", action: labelkeep}]`,
+			labels:   map[string]string{"__name__": "up", "k8s_pod": "web", "env": "prod"},
+			expected: map[string]string{"__name__": "up", "env": "prod"},
+		},
+		{
+			name:     "lowercase",
+			rules:    `[{source_labels: [env], target_label: env, action: lowercase}]`,
+			labels:   map[string]string{"env": "PROD"},
+			expected: map[string]string{"env": "prod"},
+		},
+		{
+			name:     "hashmod",
+			rules:    `[{source_labels: [instance], target_label: shard, modulus: 1, action: hashmod}]`,
+			labels:   map[string]string{"instance": "a"},
+			expected: map[string]string{"instance": "a", "shard": "0"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var configs []Config
+			require.NoError(t, yaml.Unmarshal([]byte(tt.rules), &configs))
+			rules, err := NewRules(configs)
+			require.NoError(t, err)
+
+			kept := Apply(rules, tt.labels)
+			if tt.expected == nil {
+				assert.False(t, kept)
+				return
+			}
+			assert.True(t, kept)
+			assert.Equal(t, tt.expected, tt.labels)
+		})
+	}
+}
+
+func TestRelabelInvalidRules(t *testing.T) {
+	regex := "("
+	for _, cfg := range []Config{
+		{Action: "unknown"},
+		{Action: "replace"},
+		{Action: "hashmod", TargetLabel: "shard"},
+		{Action: "keep", Regex: &regex},
+	} {
+		_, err := NewRule(cfg)
+		assert.Error(t, err)
+	}
+}
@@ -46,6 +46,7 @@ func init() {
 // the default replacers.
 func AddDefaultReplacers(scrubber *Scrubber) {
 	hintedAPIKeyReplacer := Replacer{
+		Name: "api_key",
 		// If hinted, mask the value regardless if it doesn't match 32-char hexadecimal string
 		Regex: regexp.MustCompile(`(api_?key=)\b[a-zA-Z0-9]+([a-zA-Z0-9]{5})\b`),
 		Hints: []string{"api_key", "apikey"},
@@ -54,6 +55,7 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 		LastUpdated: defaultVersion,
 	}
 	hintedAPPKeyReplacer := Replacer{
+		Name: "app_key",
 		// If hinted, mask the value regardless if it doesn't match 40-char hexadecimal string
 		Regex: regexp.MustCompile(`(ap(?:p|plication)_?key=)\b[a-zA-Z0-9]+([a-zA-Z0-9]{5})\b`),
 		Hints: []string{"app_key", "appkey", "application_key"},
@@ -65,6 +67,7 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 	// replacers are check one by one in order. We first try to scrub 64 bytes token, keeping the last 5 digit. If
 	// the token has a different size we scrub it entirely.
 	hintedBearerReplacer := Replacer{
+		Name:  "bearer_token",
 		Regex: regexp.MustCompile(`\bBearer [a-fA-F0-9]{59}([a-fA-F0-9]{5})\b`),
 		Hints: []string{"Bearer"},
 		Repl:  []byte(`Bearer ***********************************************************$1`),
@@ -74,6 +77,7 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 	}
 	// For this one we match any characters
 	hintedBearerInvalidReplacer := Replacer{
+		Name:  "bearer_token",
 		Regex: regexp.MustCompile(`\bBearer\s+[^*]+\b`),
 		Hints: []string{"Bearer"},
 		Repl:  []byte("Bearer " + defaultReplacement),
@@ -83,6 +87,7 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 	}
 
 	apiKeyReplacerYAML := Replacer{
+		Name:  "api_key",
 		Regex: regexp.MustCompile(`(\-|\:|,|\[|\{)(\s+)?\b[a-fA-F0-9]{27}([a-fA-F0-9]{5})\b`),
 		Repl:  []byte(`$1$2"***************************$3"`),
 
@@ -90,12 +95,14 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 		LastUpdated: parseVersion("7.39.0"),
 	}
 	apiKeyReplacer := Replacer{
+		Name:  "api_key",
 		Regex: regexp.MustCompile(`\b[a-fA-F0-9]{27}([a-fA-F0-9]{5})\b`),
 		Repl:  []byte(`***************************$1`),
 
 		LastUpdated: defaultVersion,
 	}
 	appKeyReplacerYAML := Replacer{
+		Name:  "app_key",
 		Regex: regexp.MustCompile(`(\-|\:|,|\[|\{)(\s+)?\b[a-fA-F0-9]{35}([a-fA-F0-9]{5})\b`),
 		Repl:  []byte(`$1$2"***********************************$3"`),
 
@@ -103,12 +110,14 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 		LastUpdated: parseVersion("7.39.0"),
 	}
 	appKeyReplacer := Replacer{
+		Name:  "app_key",
 		Regex: regexp.MustCompile(`\b[a-fA-F0-9]{35}([a-fA-F0-9]{5})\b`),
 		Repl:  []byte(`***********************************$1`),
 
 		LastUpdated: defaultVersion,
 	}
 	rcAppKeyReplacer := Replacer{
+		Name:  "rc_app_key",
 		Regex: regexp.MustCompile(`\bDDRCM_[A-Z0-9]+([A-Z0-9]{5})\b`),
 		Repl:  []byte(`***********************************$1`),
 
@@ -119,6 +128,7 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 	// URI Generic Syntax
 	// https://tools.ietf.org/html/rfc3986
 	uriPasswordReplacer := Replacer{
+		Name:  "uri_password",
 		Regex: regexp.MustCompile(`(?i)([a-z][a-z0-9+-.]+://|\b)([^:\s]+):([^\s|"]+)@`),
 		Repl:  []byte(`$1$2:********@`),
 
@@ -132,7 +142,9 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 		[]byte(`$1 "********"`),
 	)
 	yamlPasswordReplacer.LastUpdated = defaultVersion
+	yamlPasswordReplacer.Name = "password"
 	passwordReplacer := Replacer{
+		Name: "password",
 		// this regex has three parts:
 		// * key: case-insensitive, optionally quoted (pass | password | pswd | pwd), not anchored to match on args like --mysql_password= etc.
 		// * separator: (= or :) with optional opening quote we don't want to match as part of the password
@@ -150,19 +162,23 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 		[]byte(`$1 "********"`),
 	)
 	tokenReplacer.LastUpdated = defaultVersion
+	tokenReplacer.Name = "token"
 	snmpReplacer := matchYAMLKey(
 		`(community_string|auth[Kk]ey|priv[Kk]ey|community|authentication_key|privacy_key|Authorization|authorization)`,
 		[]string{"community_string", "authKey", "authkey", "privKey", "privkey", "community", "authentication_key", "privacy_key", "Authorization", "authorization"},
 		[]byte(`$1 "********"`),
 	)
 	snmpReplacer.LastUpdated = parseVersion("7.64.0") // https://github.com/DataDog/datadog-agent/pull/33742
+	snmpReplacer.Name = "credentials"
 	snmpMultilineReplacer := matchYAMLKeyWithListValue(
 		"(community_strings)",
 		"community_strings",
 		[]byte(`$1 "********"`),
 	)
 	snmpMultilineReplacer.LastUpdated = parseVersion("7.34.0") // https://github.com/DataDog/datadog-agent/pull/10305
+	snmpMultilineReplacer.Name = "credentials"
 	certReplacer := Replacer{
+		Name: "certificate",
 		/*
 		   Try to match as accurately as possible. RFC 7468's ABNF
 		   Backreferences are not available in go, so we cannot verify
@@ -193,6 +209,7 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 		},
 	)
 	apiKeyYaml.LastUpdated = parseVersion("7.44.0") // https://github.com/DataDog/datadog-agent/pull/15707
+	apiKeyYaml.Name = "api_key"
 
 	appKeyYaml := matchYAMLOnly(
 		`ap(?:p|plication)_?key`,
@@ -210,6 +227,7 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 		},
 	)
 	appKeyYaml.LastUpdated = parseVersion("7.44.0") // https://github.com/DataDog/datadog-agent/pull/15707
+	appKeyYaml.Name = "app_key"
 
 	scrubber.AddReplacer(SingleLine, hintedAPIKeyReplacer)
 	scrubber.AddReplacer(SingleLine, hintedAPPKeyReplacer)
@@ -411,6 +429,7 @@ func AddStrippedKeys(strippedKeys []string) {
 			strippedKeys,
 			[]byte(`$1 "********"`),
 		)
+		replacer.Name = "stripped_key"
 		// We add the new replacer to the default scrubber and to the list of dynamicReplacers so any new
 		// scubber will inherit it.
 		DefaultScrubber.AddReplacer(SingleLine, replacer)
@@ -23,6 +23,9 @@ import (
 
 // Replacer represents a replacement of sensitive information with a "clean" version.
 type Replacer struct {
+	// Name identifies the kind of sensitive information the replacer scrubs, for instance when reporting what
+	// was scrubbed. Several replacers can share the same name.
+	Name string
 	// Regex must match the sensitive information
 	Regex *regexp.Regexp
 	// YAMLKeyRegex matches the key of sensitive information in a dict/map. This is used when iterating over a
@@ -89,6 +92,9 @@ type Scrubber struct {
 	// shouldApply is a function that can be used to conditionally apply a replacer.
 	// If the function returns false, the replacer will not be applied.
 	shouldApply func(repl Replacer) bool
+
+	// onMatch is a function called with the number of values a replacer scrubbed.
+	onMatch func(repl Replacer, count int)
 }
 
 // New creates a new scrubber with no replacers installed.
@@ -121,6 +127,12 @@ func (c *Scrubber) SetShouldApply(shouldApply func(repl Replacer) bool) {
 	c.shouldApply = shouldApply
 }
 
+// SetOnMatch sets a function called every time a replacer scrubs values, with the number of values it scrubbed.
+// Matches of values that were already scrubbed are not counted.
+func (c *Scrubber) SetOnMatch(onMatch func(repl Replacer, count int)) {
+	c.onMatch = onMatch
+}
+
 // ScrubFile scrubs credentials from file given by pathname
 func (c *Scrubber) ScrubFile(filePath string) ([]byte, error) {
 	file, err := os.Open(filePath)
@@ -212,7 +224,9 @@ func (c *Scrubber) scrub(data []byte, replacers []Replacer) []byte {
 			}
 		}
 		if len(repl.Hints) == 0 || containsHint {
-			if repl.ReplFunc != nil {
+			if c.onMatch != nil {
+				data = c.replaceAndCount(repl, data)
+			} else if repl.ReplFunc != nil {
 				data = repl.Regex.ReplaceAllFunc(data, repl.ReplFunc)
 			} else {
 				data = repl.Regex.ReplaceAll(data, repl.Repl)
@@ -221,3 +235,50 @@ func (c *Scrubber) scrub(data []byte, replacers []Replacer) []byte {
 	}
 	return data
 }
+
+// replaceAndCount applies a replacer like scrub does, and reports the number of values it changed to onMatch
+func (c *Scrubber) replaceAndCount(repl Replacer, data []byte) []byte {
+	matches := repl.Regex.FindAllSubmatchIndex(data, -1)
+	if len(matches) == 0 {
+		return data
+	}
+
+	count := 0
+	last := 0
+	result := make([]byte, 0, len(data))
+	for _, match := range matches {
+		var replaced []byte
+		if repl.ReplFunc != nil {
+			replaced = repl.ReplFunc(data[match[0]:match[1]])
+		} else {
+			replaced = repl.Regex.Expand(nil, repl.Repl, data, match)
+		}
+		if !isRedacted(data[match[0]:match[1]], replaced) {
+			count++
+		}
+		result = append(result, data[last:match[0]]...)
+		result = append(result, replaced...)
+		last = match[1]
+	}
+	result = append(result, data[last:]...)
+
+	if count > 0 {
+		c.onMatch(repl, count)
+	}
+	return result
+}
+
+// isRedacted returns true if the replacement only changes the masking characters and quotes of the
+// original, meaning that the original was already scrubbed
+func isRedacted(original, replaced []byte) bool {
+	strip := func(b []byte) []byte {
+		return bytes.Map(func(r rune) rune {
+			switch r {
+			case '*', '"', '\'', ' ', '\t':
+				return -1
+			}
+			return r
+		}, b)
+	}
+	return bytes.Equal(strip(original), strip(replaced))
+}
@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"reflect"
 
 	"gopkg.in/yaml.v3"
 )
@@ -92,10 +93,14 @@ func (c *Scrubber) ScrubDataObj(data *interface{}) {
 			}
 
 			if replacer.YAMLKeyRegex.Match([]byte(key)) {
+				scrubbed := interface{}(defaultReplacement)
 				if replacer.ProcessValue != nil {
-					return true, replacer.ProcessValue(value)
+					scrubbed = replacer.ProcessValue(value)
 				}
-				return true, defaultReplacement
+				if c.onMatch != nil && !reflect.DeepEqual(scrubbed, value) {
+					c.onMatch(replacer, 1)
+				}
+				return true, scrubbed
 			}
 		}
 		return false, ""
@@ -18,6 +18,7 @@ PROTO_PKGS = {
     'languagedetection': (False, False),
     'remoteagent': (False, False),
     'autodiscovery': (False, False),
+    'checkplugin': (False, False),
 }
 
 # maybe put this in a separate function